@@ -0,0 +1,235 @@
+package lane
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type (
+	// Selects the line format of an access log lane
+	AccessLogFormat int
+
+	// An HTTP request as written to an access log
+	AccessLogEntry struct {
+		Time       time.Time // when the request was received
+		RemoteAddr string    // the client address, with or without the port
+		User       string    // the basic auth user, or ""
+		Method     string
+		URI        string
+		Proto      string
+		Status     int
+		Bytes      int64 // the size of the response body
+		Duration   time.Duration
+		Referer    string
+		UserAgent  string
+		LaneId     string // the lane of the request's context, or ""
+		JourneyId  string // the journey ID of that lane, or of the request's JourneyIdHeader
+	}
+
+	// A disk lane that writes an HTTP access log, kept apart from the
+	// application's log so that each can be shipped and rotated on its own.
+	// The file is reopened with ReopenFile() after a tool such as logrotate
+	// renames it.
+	AccessLogLane interface {
+		DiskLane
+
+		// Writes [entry] to the access log.
+		LogAccess(entry *AccessLogEntry)
+
+		// Wraps [next] to write each request it serves to the access log.
+		Middleware(next http.Handler) http.Handler
+	}
+
+	accessLogLane struct {
+		DiskLane
+		format AccessLogFormat
+	}
+
+	// Writes the message of each record as is, as the access log lane
+	// formats its own lines
+	accessLogEncoder struct{}
+
+	// Captures the status and size of a response for the access log
+	accessLogResponseWriter struct {
+		http.ResponseWriter
+		status int
+		bytes  int64
+	}
+
+	jsonAccessLogEntry struct {
+		Time       time.Time `json:"time"`
+		RemoteAddr string    `json:"remoteAddr"`
+		User       string    `json:"user,omitempty"`
+		Method     string    `json:"method"`
+		URI        string    `json:"uri"`
+		Proto      string    `json:"proto"`
+		Status     int       `json:"status"`
+		Bytes      int64     `json:"bytes"`
+		DurationMs float64   `json:"durationMs"`
+		Referer    string    `json:"referer,omitempty"`
+		UserAgent  string    `json:"userAgent,omitempty"`
+		LaneId     string    `json:"laneId,omitempty"`
+		JourneyId  string    `json:"journeyId,omitempty"`
+	}
+)
+
+const (
+	// The Common Log Format, such as
+	//
+	//	127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326
+	AccessLogCommon AccessLogFormat = iota
+	// The Combined Log Format, the Common Log Format followed by the quoted
+	// referer and user agent
+	AccessLogCombined
+	// One JSON object per line, which also has the duration and the lane and
+	// journey IDs
+	AccessLogJSON
+)
+
+// the time layout of the Common Log Format
+const clfTimeLayout = "02/Jan/2006:15:04:05 -0700"
+
+// Makes a disk lane that appends an HTTP access log to [logFile] in
+// [format]. Requests are written with LogAccess(), or by its Middleware().
+// Each entry is logged at INFO.
+func NewAccessLogLane(ctx OptionalContext, logFile string, format AccessLogFormat, opts ...LaneOption) (al AccessLogLane, err error) {
+	l, err := NewDiskLane(ctx, logFile, append(opts, WithEncoder(accessLogEncoder{}))...)
+	if err != nil {
+		return
+	}
+	al = &accessLogLane{DiskLane: l.(DiskLane), format: format}
+	return
+}
+
+func (accessLogEncoder) AppendRecord(buf []byte, rec *Record) []byte {
+	return append(append(buf, rec.Message...), '\n')
+}
+
+func (al *accessLogLane) LogAccess(entry *AccessLogEntry) {
+	al.Info(al.format.line(entry))
+}
+
+func (al *accessLogLane) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rw := &accessLogResponseWriter{ResponseWriter: w}
+		next.ServeHTTP(rw, r)
+
+		entry := AccessLogEntry{
+			Time:       start,
+			RemoteAddr: r.RemoteAddr,
+			Method:     r.Method,
+			URI:        r.RequestURI,
+			Proto:      r.Proto,
+			Status:     rw.status,
+			Bytes:      rw.bytes,
+			Duration:   time.Since(start),
+			Referer:    r.Referer(),
+			UserAgent:  r.UserAgent(),
+			JourneyId:  r.Header.Get(JourneyIdHeader),
+		}
+		if entry.URI == "" {
+			entry.URI = r.URL.RequestURI()
+		}
+		if entry.Status == 0 {
+			entry.Status = http.StatusOK
+		}
+		entry.User, _, _ = r.BasicAuth()
+		if l, found := FromContext(r.Context()); found {
+			entry.LaneId = l.LaneId()
+			if journeyId := l.JourneyId(); journeyId != "" {
+				entry.JourneyId = journeyId
+			}
+		}
+		al.LogAccess(&entry)
+	})
+}
+
+// Renders [entry] as a line of the access log, without the line break
+func (format AccessLogFormat) line(entry *AccessLogEntry) string {
+	if format == AccessLogJSON {
+		data, err := json.Marshal(jsonAccessLogEntry{
+			Time:       entry.Time,
+			RemoteAddr: entry.RemoteAddr,
+			User:       entry.User,
+			Method:     entry.Method,
+			URI:        entry.URI,
+			Proto:      entry.Proto,
+			Status:     entry.Status,
+			Bytes:      entry.Bytes,
+			DurationMs: float64(entry.Duration) / float64(time.Millisecond),
+			Referer:    entry.Referer,
+			UserAgent:  entry.UserAgent,
+			LaneId:     entry.LaneId,
+			JourneyId:  entry.JourneyId,
+		})
+		if err != nil {
+			// the entry is made of strings and numbers, so this is not expected
+			return ""
+		}
+		return string(data)
+	}
+
+	host := entry.RemoteAddr
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	size := "-"
+	if entry.Bytes > 0 {
+		size = strconv.FormatInt(entry.Bytes, 10)
+	}
+
+	buf := make([]byte, 0, 256)
+	buf = append(buf, clfField(host)...)
+	buf = append(buf, " - "...)
+	buf = append(buf, clfField(entry.User)...)
+	buf = append(buf, " ["...)
+	buf = entry.Time.AppendFormat(buf, clfTimeLayout)
+	buf = append(buf, "] "...)
+	buf = strconv.AppendQuote(buf, entry.Method+" "+entry.URI+" "+entry.Proto)
+	buf = append(buf, ' ')
+	buf = strconv.AppendInt(buf, int64(entry.Status), 10)
+	buf = append(buf, ' ')
+	buf = append(buf, size...)
+	if format == AccessLogCombined {
+		buf = append(buf, ' ')
+		buf = strconv.AppendQuote(buf, clfField(entry.Referer))
+		buf = append(buf, ' ')
+		buf = strconv.AppendQuote(buf, clfField(entry.UserAgent))
+	}
+	return string(buf)
+}
+
+// Renders an unquoted field of the Common Log Format, which is "-" when the
+// value is missing
+func clfField(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+func (rw *accessLogResponseWriter) WriteHeader(status int) {
+	if rw.status == 0 && status >= http.StatusOK {
+		// an informational response is followed by the final one
+		rw.status = status
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *accessLogResponseWriter) Write(p []byte) (n int, err error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	n, err = rw.ResponseWriter.Write(p)
+	rw.bytes += int64(n)
+	return
+}
+
+// Provides the wrapped writer to http.ResponseController
+func (rw *accessLogResponseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -0,0 +1,96 @@
+package lane
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAccessLogFormats(t *testing.T) {
+	entry := AccessLogEntry{
+		Time:       time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("", -7*3600)),
+		RemoteAddr: "127.0.0.1:50000",
+		User:       "frank",
+		Method:     "GET",
+		URI:        "/apache_pb.gif",
+		Proto:      "HTTP/1.0",
+		Status:     200,
+		Bytes:      2326,
+		Duration:   1500 * time.Microsecond,
+		Referer:    "http://www.example.com/start.html",
+		UserAgent:  `Mozilla/4.08 "quoted"`,
+		LaneId:     "lane",
+	}
+
+	common := AccessLogCommon.line(&entry)
+	if common != `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326` {
+		t.Errorf("unexpected common line %s", common)
+	}
+
+	combined := AccessLogCombined.line(&entry)
+	if combined != common+` "http://www.example.com/start.html" "Mozilla/4.08 \"quoted\""` {
+		t.Errorf("unexpected combined line %s", combined)
+	}
+
+	var decoded map[string]any
+	if err := json.Unmarshal([]byte(AccessLogJSON.line(&entry)), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded["status"] != 200.0 || decoded["durationMs"] != 1.5 || decoded["laneId"] != "lane" || decoded["journeyId"] != nil {
+		t.Errorf("unexpected json %v", decoded)
+	}
+
+	entry.User, entry.Bytes = "", 0
+	if line := AccessLogCommon.line(&entry); !strings.HasPrefix(line, "127.0.0.1 - - [") || !strings.HasSuffix(line, " 200 -") {
+		t.Errorf("unexpected line for missing values %s", line)
+	}
+}
+
+func TestAccessLogMiddleware(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	al, err := NewAccessLogLane(nil, path, AccessLogJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := NewTestingLane(nil)
+	app.SetJourneyId("trip")
+	handler := al.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("short and stout"))
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/pot?x=1", nil)
+	req = req.WithContext(app)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	al.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("unexpected access log %q", data)
+	}
+
+	var first AccessLogEntry
+	if err = json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatal(err)
+	}
+	if first.Method != "POST" || first.URI != "/pot?x=1" || first.Status != http.StatusTeapot || first.Bytes != 15 || first.LaneId != app.LaneId() || first.JourneyId != "trip" {
+		t.Errorf("unexpected entry %s", lines[0])
+	}
+	if !strings.Contains(lines[1], `"uri":"/"`) || strings.Contains(lines[1], "laneId") {
+		t.Errorf("unexpected entry %s", lines[1])
+	}
+	if app.EventCount() != 0 {
+		t.Errorf("the access log was mixed into the application log:\n%s", app.EventsToString())
+	}
+}
@@ -0,0 +1,175 @@
+package lane
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// Settings for retrying an operation with exponentially increasing delays.
+	// The zero value uses the defaults.
+	Backoff struct {
+		Initial     time.Duration // delay before the first retry, defaults to 100 milliseconds
+		Max         time.Duration // limit on a single delay, defaults to 30 seconds
+		Multiplier  float64       // growth of the delay per attempt, defaults to 2
+		Jitter      float64       // fraction of each delay that is randomized, defaults to 0.5, negative for none
+		MaxAttempts int           // attempts before giving up, or zero for no limit
+	}
+
+	// An error carrying the delay a server asked for before the next attempt
+	retryAfterError struct {
+		err   error
+		delay time.Duration
+	}
+
+	// An error that retrying won't fix
+	permanentError struct {
+		err error
+	}
+)
+
+func (b Backoff) withDefaults() Backoff {
+	if b.Initial <= 0 {
+		b.Initial = 100 * time.Millisecond
+	}
+	if b.Max <= 0 {
+		b.Max = 30 * time.Second
+	}
+	if b.Multiplier < 1 {
+		b.Multiplier = 2
+	}
+	if b.Jitter == 0 {
+		b.Jitter = 0.5
+	} else if b.Jitter < 0 {
+		b.Jitter = 0
+	} else if b.Jitter > 1 {
+		b.Jitter = 1
+	}
+	return b
+}
+
+// Provides the delay to wait after the failure of [attempt], counting from 1.
+// With jitter, the delay is randomly reduced by up to the jitter fraction, so
+// that instances that failed together don't retry together.
+func (b Backoff) Delay(attempt int) time.Duration {
+	b = b.withDefaults()
+
+	delay := float64(b.Initial)
+	for i := 1; i < attempt && delay < float64(b.Max); i++ {
+		delay *= b.Multiplier
+	}
+	delay = min(delay, float64(b.Max))
+	delay -= delay * b.Jitter * rand.Float64()
+	return time.Duration(delay)
+}
+
+// Calls [fn] until it succeeds, the attempts are used up, or [ctx] is done,
+// waiting the Delay() between attempts. An error made with RetryAfter()
+// replaces the delay with the one the server asked for, up to Max, and an
+// error made with Permanent() stops the retries. The [attempt] passed to [fn]
+// counts from 1. The last error of [fn] is returned, joined with the context
+// error if [ctx] ended the retries.
+func (b Backoff) Retry(ctx OptionalContext, fn func(attempt int) error) (err error) {
+	return b.retry(ctx, fn, nil)
+}
+
+// Worker for Retry() that calls [onRetry], if provided, before each delay
+func (b Backoff) retry(ctx OptionalContext, fn func(attempt int) error, onRetry func(attempt int, err error, delay time.Duration)) (err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	for attempt := 1; ; attempt++ {
+		if err = fn(attempt); err == nil {
+			return
+		}
+		if b.MaxAttempts > 0 && attempt >= b.MaxAttempts || IsPermanent(err) {
+			return
+		}
+
+		delay, found := RetryAfterDelay(err)
+		if found {
+			// a server can't stall the retries beyond the backoff limit
+			delay = min(delay, b.withDefaults().Max)
+		} else {
+			delay = b.Delay(attempt)
+		}
+		if onRetry != nil {
+			onRetry(attempt, err, delay)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, context.Cause(ctx))
+		}
+	}
+}
+
+// Wraps [err] with the delay a server asked for before the next attempt, such
+// as from a Retry-After header, for Backoff.Retry() to use.
+func RetryAfter(err error, delay time.Duration) error {
+	return &retryAfterError{err: err, delay: delay}
+}
+
+// Provides the delay of an error made with RetryAfter()
+func RetryAfterDelay(err error) (delay time.Duration, found bool) {
+	var rae *retryAfterError
+	if errors.As(err, &rae) {
+		return rae.delay, true
+	}
+	return
+}
+
+func (rae *retryAfterError) Error() string {
+	return rae.err.Error()
+}
+
+func (rae *retryAfterError) Unwrap() error {
+	return rae.err
+}
+
+// Wraps [err] to stop Backoff.Retry() without further attempts, such as for a
+// request the server rejected as invalid.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// Indicates whether [err] was made with Permanent()
+func IsPermanent(err error) bool {
+	var pe *permanentError
+	return errors.As(err, &pe)
+}
+
+func (pe *permanentError) Error() string {
+	return pe.err.Error()
+}
+
+func (pe *permanentError) Unwrap() error {
+	return pe.err
+}
+
+// Parses a Retry-After header value, which is either a number of seconds or
+// an HTTP date, relative to [now].
+func ParseRetryAfter(value string, now time.Time) (delay time.Duration, valid bool) {
+	value = strings.TrimSpace(value)
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	t, err := http.ParseTime(value)
+	if err != nil {
+		return
+	}
+	return max(t.Sub(now), 0), true
+}
@@ -0,0 +1,141 @@
+package lane
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	b := Backoff{Initial: 10 * time.Millisecond, Max: 50 * time.Millisecond, Jitter: -1}
+	expected := []time.Duration{10, 20, 40, 50, 50}
+	for i, want := range expected {
+		if d := b.Delay(i + 1); d != want*time.Millisecond {
+			t.Errorf("attempt %d: expected %v, got %v", i+1, want*time.Millisecond, d)
+		}
+	}
+
+	jittered := Backoff{Initial: time.Second, Jitter: 0.25}
+	for range 100 {
+		if d := jittered.Delay(1); d < 750*time.Millisecond || d > time.Second {
+			t.Fatalf("jittered delay %v out of range", d)
+		}
+	}
+}
+
+func TestBackoffRetry(t *testing.T) {
+	errFail := errors.New("fail")
+	b := Backoff{Initial: time.Millisecond, MaxAttempts: 3}
+
+	var attempts []int
+	err := b.Retry(nil, func(attempt int) error {
+		attempts = append(attempts, attempt)
+		return errFail
+	})
+	if !errors.Is(err, errFail) || len(attempts) != 3 || attempts[2] != 3 {
+		t.Errorf("unexpected result %v after attempts %v", err, attempts)
+	}
+
+	calls := 0
+	err = b.Retry(context.Background(), func(attempt int) error {
+		calls++
+		if attempt < 2 {
+			return errFail
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Errorf("unexpected result %v after %d calls", err, calls)
+	}
+}
+
+func TestBackoffRetryContext(t *testing.T) {
+	errFail := errors.New("fail")
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := Backoff{Initial: time.Hour}.Retry(ctx, func(attempt int) error {
+		return errFail
+	})
+	if !errors.Is(err, errFail) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("unexpected error %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("the retry did not stop with the context")
+	}
+}
+
+func TestBackoffRetryAfter(t *testing.T) {
+	errBusy := errors.New("busy")
+	b := Backoff{Initial: time.Hour, MaxAttempts: 2}
+
+	start := time.Now()
+	err := b.Retry(nil, func(attempt int) error {
+		return RetryAfter(errBusy, time.Millisecond)
+	})
+	if !errors.Is(err, errBusy) || err.Error() != "busy" {
+		t.Errorf("unexpected error %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("the Retry-After delay was not used")
+	}
+	if d, found := RetryAfterDelay(err); !found || d != time.Millisecond {
+		t.Errorf("unexpected delay %v", d)
+	}
+	if _, found := RetryAfterDelay(errBusy); found {
+		t.Error("unexpected delay")
+	}
+}
+
+func TestBackoffRetryAfterMax(t *testing.T) {
+	errBusy := errors.New("busy")
+	b := Backoff{Initial: time.Millisecond, Max: 5 * time.Millisecond, MaxAttempts: 2}
+
+	var delays []time.Duration
+	start := time.Now()
+	b.retry(nil, func(attempt int) error {
+		return RetryAfter(errBusy, 24*time.Hour)
+	}, func(attempt int, err error, delay time.Duration) {
+		delays = append(delays, delay)
+	})
+	if len(delays) != 1 || delays[0] != 5*time.Millisecond || time.Since(start) > time.Second {
+		t.Errorf("expected the Retry-After delay capped by Max, have %v", delays)
+	}
+}
+
+func TestBackoffPermanent(t *testing.T) {
+	errInvalid := errors.New("invalid")
+	calls := 0
+	err := Backoff{Initial: time.Hour}.Retry(nil, func(attempt int) error {
+		calls++
+		return Permanent(errInvalid)
+	})
+	if calls != 1 || !errors.Is(err, errInvalid) || !IsPermanent(err) || err.Error() != "invalid" {
+		t.Errorf("unexpected result %v after %d calls", err, calls)
+	}
+	if IsPermanent(errInvalid) {
+		t.Error("unexpected permanent error")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 4, 9, 11, 37, 37, 0, time.UTC)
+
+	if d, valid := ParseRetryAfter(" 120 ", now); !valid || d != 2*time.Minute {
+		t.Errorf("unexpected seconds %v %v", d, valid)
+	}
+	if d, valid := ParseRetryAfter(now.Add(time.Minute).Format(http.TimeFormat), now); !valid || d != time.Minute {
+		t.Errorf("unexpected date %v %v", d, valid)
+	}
+	if d, valid := ParseRetryAfter(now.Add(-time.Minute).Format(http.TimeFormat), now); !valid || d != 0 {
+		t.Errorf("unexpected past date %v %v", d, valid)
+	}
+	for _, value := range []string{"", "-1", "soon"} {
+		if _, valid := ParseRetryAfter(value, now); valid {
+			t.Errorf("%q: expected invalid", value)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package lane
+
+import (
+	"testing"
+)
+
+func BenchmarkLogLaneSuppressedTrace(b *testing.B) {
+	ll := NewLogLane(nil)
+	ll.SetLogLevel(LogLevelInfo)
+	args := []any{"suppressed", 123}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ll.Trace(args...)
+	}
+}
+
+func BenchmarkLogLaneSuppressedTracef(b *testing.B) {
+	ll := NewLogLane(nil)
+	ll.SetLogLevel(LogLevelInfo)
+	args := []any{"suppressed", 123}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ll.Tracef("%s %d", args...)
+	}
+}
+
+func BenchmarkLogLaneSuppressedTraceObject(b *testing.B) {
+	ll := NewLogLane(nil)
+	ll.SetLogLevel(LogLevelInfo)
+	obj := &testStruct{a: 1, b: 2}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ll.TraceObject("suppressed", obj)
+	}
+}
+
+func BenchmarkNullLaneTrace(b *testing.B) {
+	nl := NewNullLane(nil)
+	args := []any{"suppressed", 123}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		nl.Trace(args...)
+	}
+}
+
+func BenchmarkLogLaneSuppressedTraceWithTee(b *testing.B) {
+	ll := NewLogLane(nil)
+	ll.SetLogLevel(LogLevelInfo)
+	nl := NewNullLane(nil)
+	ll.AddTee(nl)
+	args := []any{"suppressed", 123}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ll.Trace(args...)
+	}
+}
+
+func BenchmarkLogLaneParallelWithTee(b *testing.B) {
+	ll := NewLogLane(nil)
+	ll.SetLogLevel(LogLevelInfo)
+	nl := NewNullLane(nil)
+	ll.AddTee(nl)
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			ll.Debug("teed", 123)
+		}
+	})
+}
+
+func TestSuppressedTraceAllocs(t *testing.T) {
+	ll := NewLogLane(nil)
+	ll.SetLogLevel(LogLevelInfo)
+	nl := NewNullLane(nil)
+	args := []any{"suppressed", 123}
+	obj := &testStruct{a: 1, b: 2}
+
+	// the args slice is made once, so any allocation is the lane's overhead
+	allocs := testing.AllocsPerRun(100, func() {
+		ll.Trace(args...)
+		ll.Tracef("%s %d", args...)
+		ll.Debug(args...)
+		ll.TraceObject("suppressed", obj)
+		nl.Trace(args...)
+		nl.Errorf("%s %d", args...)
+		nl.InfoObject("suppressed", obj)
+	})
+	if allocs != 0 {
+		t.Errorf("suppressed logging allocated %f times", allocs)
+	}
+}
@@ -0,0 +1,100 @@
+package lane
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type (
+	// Settings of Bootstrap(). The logging settings can be set directly, from
+	// the command line with RegisterFlags(), or from the environment.
+	BootstrapOptions struct {
+		Level         string // --log-level or LOG_LEVEL, such as "info"; "" for TRACE
+		File          string // --log-file or LOG_FILE, the path of a disk lane; "" for the standard logger
+		Format        string // --log-format or LOG_FORMAT: "text" (the default), "json" or "logfmt"
+		OpenSearchURL string // --log-opensearch-url or LOG_OPENSEARCH_URL, the URL of an OpenSearch tee
+
+		// Makes the lane of OpenSearchURL, such as the constructor of the
+		// go-lane-opensearch package, which isn't a dependency of this package
+		OpenSearch func(ctx OptionalContext, url string) (Lane, error)
+
+		Context OptionalContext         // the context of the root lane
+		Getenv  func(key string) string // reads the environment, or nil for os.Getenv
+		Options []LaneOption            // more options for the root lane
+	}
+)
+
+// Defines the --log-level, --log-file, --log-format and --log-opensearch-url
+// flags on [fs], such as flag.CommandLine, which set the options when the
+// flags are parsed.
+func (bo *BootstrapOptions) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&bo.Level, "log-level", bo.Level, "the log level: trace, debug, info, warn, error or fatal")
+	fs.StringVar(&bo.File, "log-file", bo.File, "the log file, instead of the standard error output")
+	fs.StringVar(&bo.Format, "log-format", bo.Format, "the log format: text, json or logfmt")
+	fs.StringVar(&bo.OpenSearchURL, "log-opensearch-url", bo.OpenSearchURL, "the URL of an OpenSearch server to also receive the log")
+}
+
+// Assembles a root lane from the common logging settings, so that services
+// start up the same way. A setting that is empty in [opts] is read from its
+// environment variable. The root lane is a disk lane when a log file is set,
+// or a log lane otherwise, and an OpenSearch lane is added as a tee when a
+// URL is set, with the same level. The tee is closed when the root lane is closed.
+func Bootstrap(opts BootstrapOptions) (l Lane, err error) {
+	getenv := opts.Getenv
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+	setting := func(value, key string) string {
+		if value == "" {
+			value = getenv(key)
+		}
+		return value
+	}
+
+	laneOpts := []LaneOption{WithTeeClose()}
+
+	level := LogLevelTrace
+	if levelName := setting(opts.Level, "LOG_LEVEL"); levelName != "" {
+		if level, err = ParseLogLevel(levelName); err != nil {
+			return
+		}
+		laneOpts = append(laneOpts, WithLevel(level))
+	}
+
+	switch format := setting(opts.Format, "LOG_FORMAT"); strings.ToLower(format) {
+	case "", "text":
+	case "json":
+		laneOpts = append(laneOpts, WithEncoder(JSONEncoder{}))
+	case "logfmt":
+		laneOpts = append(laneOpts, WithEncoder(LogfmtEncoder{}))
+	default:
+		err = fmt.Errorf("unsupported log format %q", format)
+		return
+	}
+
+	var tee Lane
+	if url := setting(opts.OpenSearchURL, "LOG_OPENSEARCH_URL"); url != "" {
+		if opts.OpenSearch == nil {
+			err = fmt.Errorf("log OpenSearch URL %s is set without an OpenSearch lane constructor", url)
+			return
+		}
+		if tee, err = opts.OpenSearch(opts.Context, url); err != nil {
+			return
+		}
+		tee.SetLogLevel(level)
+		laneOpts = append(laneOpts, WithTee(tee))
+	}
+
+	laneOpts = append(laneOpts, opts.Options...)
+
+	if file := setting(opts.File, "LOG_FILE"); file != "" {
+		if l, err = NewDiskLane(opts.Context, file, laneOpts...); err != nil && tee != nil {
+			tee.Close()
+		}
+		return
+	}
+	l = NewLogLane(opts.Context, laneOpts...)
+	return
+}
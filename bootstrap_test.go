@@ -0,0 +1,97 @@
+package lane
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBootstrapFlags(t *testing.T) {
+	var opts BootstrapOptions
+	fs := flag.NewFlagSet("service", flag.ContinueOnError)
+	port := fs.Int("port", 80, "")
+	opts.RegisterFlags(fs)
+
+	path := filepath.Join(t.TempDir(), "service.log")
+	if err := fs.Parse([]string{"--log-level", "warn", "--log-file=" + path, "--log-format", "json", "--port", "8080"}); err != nil {
+		t.Fatal(err)
+	}
+	opts.Getenv = func(key string) string { return "" }
+
+	l, err := Bootstrap(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Info("hidden")
+	l.Warn("shown")
+	l.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(data)
+	if *port != 8080 || strings.Contains(text, "hidden") || !strings.Contains(text, `"level":"WARN"`) || !strings.Contains(text, `"message":"shown"`) {
+		t.Errorf("unexpected log %q", text)
+	}
+}
+
+func TestBootstrapEnv(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	env := map[string]string{"LOG_LEVEL": "error", "LOG_FORMAT": "logfmt", "LOG_OPENSEARCH_URL": "http://search:9200"}
+	tl := NewTestingLane(nil)
+	var url string
+	l, err := Bootstrap(BootstrapOptions{
+		Level:  "info",
+		Getenv: func(key string) string { return env[key] },
+		OpenSearch: func(ctx OptionalContext, u string) (Lane, error) {
+			url = u
+			return tl, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Debug("hidden")
+	l.Info("shown")
+
+	if _, isLog := l.(*logLane); !isLog || url != "http://search:9200" || !tl.VerifyEventText("INFO\tshown") {
+		t.Errorf("unexpected lane %T %s:\n%s", l, url, tl.EventsToString())
+	}
+	if text := buf.String(); !strings.Contains(text, " level=info ") || !strings.HasSuffix(text, " msg=shown\n") {
+		t.Errorf("unexpected output %q", text)
+	}
+}
+
+func TestBootstrapErrors(t *testing.T) {
+	noEnv := func(key string) string { return "" }
+	if _, err := Bootstrap(BootstrapOptions{Level: "loud", Getenv: noEnv}); !errors.Is(err, ErrInvalidLogLevel) {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, err := Bootstrap(BootstrapOptions{Format: "xml", Getenv: noEnv}); err == nil {
+		t.Error("expected a format error")
+	}
+	if _, err := Bootstrap(BootstrapOptions{OpenSearchURL: "http://search:9200", Getenv: noEnv}); err == nil {
+		t.Error("expected an OpenSearch error")
+	}
+
+	tl := NewTestingLane(nil)
+	closed := false
+	tl.OnClose(func(l Lane) { closed = true })
+	_, err := Bootstrap(BootstrapOptions{
+		File:       filepath.Join(t.TempDir(), "missing", "dir", "service.log"),
+		OpenSearch: func(ctx OptionalContext, url string) (Lane, error) { return tl, nil },
+		Getenv:     func(key string) string { return map[string]string{"LOG_OPENSEARCH_URL": "http://search:9200"}[key] },
+	})
+	if err == nil || !closed {
+		t.Errorf("unexpected %v %v", err, closed)
+	}
+}
@@ -0,0 +1,90 @@
+package lane
+
+import (
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+type (
+	// Common implementation of the caller annotation setting
+	callerInfoStore struct {
+		enabled atomic.Bool
+	}
+
+	// A logging call site outside of the lane implementation
+	callSite struct {
+		file string // the full path of the source file
+		line int
+		text string // such as "main.go:42 main.run", or "" for a frame of the lane implementation
+	}
+)
+
+// program counter -> callSite
+var callerCache sync.Map
+
+// Annotates each message with the file:line and function of the code that
+// logged it. Returns the prior setting.
+func (cis *callerInfoStore) SetCallerInfo(enable bool) (prior bool) {
+	return cis.enabled.Swap(enable)
+}
+
+// Provides the caller annotation, or an empty string if it isn't enabled
+func (cis *callerInfoStore) callerInfo() string {
+	if !cis.enabled.Load() {
+		return ""
+	}
+	return findCaller()
+}
+
+// Locates the first caller outside of the lane implementation and the log
+// package, such as "main.go:42 main.run". The wrapper layers between the
+// caller and the output vary with the lane type and tees, so the stack is
+// walked instead of skipping a fixed number of frames.
+func findCaller() string {
+	return findCallSite().text
+}
+
+// Locates the first call site outside of the lane implementation and the log
+// package, or provides a zero callSite if there isn't one
+func findCallSite() callSite {
+	var pcs [32]uintptr
+	callers := pcs[:runtime.Callers(3, pcs[:])]
+	if origin := findTeeOrigin(); origin != nil {
+		// a tee on a worker goroutine
+		callers = origin.pcs
+	}
+	for _, pc := range callers {
+		site, found := callerCache.Load(pc)
+		if !found {
+			site, _ = callerCache.LoadOrStore(pc, describeCaller(pc))
+		}
+		if cs := site.(callSite); cs.text != "" {
+			return cs
+		}
+	}
+	return callSite{}
+}
+
+func describeCaller(pc uintptr) callSite {
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	if strings.HasPrefix(frame.Function, lanePackagePrefix) && !strings.HasSuffix(frame.File, "_test.go") {
+		return callSite{}
+	}
+	if strings.HasPrefix(frame.Function, "log.") || strings.HasPrefix(frame.Function, "log/slog.") {
+		return callSite{}
+	}
+
+	function := frame.Function
+	if slash := strings.LastIndexByte(function, '/'); slash >= 0 {
+		function = function[slash+1:]
+	}
+	return callSite{
+		file: frame.File,
+		line: frame.Line,
+		text: path.Base(frame.File) + ":" + strconv.Itoa(frame.Line) + " " + function,
+	}
+}
@@ -0,0 +1,104 @@
+package lane
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Provides the expected caller annotation of the line before the call
+func testCallerLine(t *testing.T) string {
+	pc, file, line, _ := runtime.Caller(1)
+	function := runtime.FuncForPC(pc).Name()
+	function = function[strings.LastIndexByte(function, '/')+1:]
+	return filepath.Base(file) + ":" + strconv.Itoa(line-1) + " " + function
+}
+
+func TestLogLaneCallerInfo(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	ll := NewLogLane(nil)
+	if ll.SetCallerInfo(true) {
+		t.Error("caller info should be off by default")
+	}
+
+	ll.Info("hello")
+	caller := testCallerLine(t)
+	if !strings.HasSuffix(buf.String(), " "+caller+": hello\n") {
+		t.Errorf("expected caller %s in %q", caller, buf.String())
+	}
+
+	// the log wrapper and derived lanes report the same caller
+	buf.Reset()
+	ll.Derive().Logger().Print("wrapped")
+	caller = testCallerLine(t)
+	if !strings.HasSuffix(buf.String(), " "+caller+": wrapped\n") {
+		t.Errorf("expected caller %s in %q", caller, buf.String())
+	}
+
+	buf.Reset()
+	ll.SetCallerInfo(false)
+	ll.Info("plain")
+	if strings.Contains(buf.String(), "caller_test.go") {
+		t.Errorf("unexpected caller in %q", buf.String())
+	}
+}
+
+func TestCallerInfoTee(t *testing.T) {
+	dl, err := NewDiskLane(nil, filepath.Join(t.TempDir(), "caller.log"), WithEncoder(JSONEncoder{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dl.Close()
+
+	tl := NewTestingLane(nil)
+	tl.SetCallerInfo(true)
+	dl.AddTee(tl)
+
+	dl.Warn("through the tee")
+	caller := testCallerLine(t)
+	events := tl.Events()
+	if len(events) != 1 || events[0].Caller != caller {
+		t.Errorf("expected caller %s, got %+v", caller, events)
+	}
+}
+
+func TestCallerInfoRecord(t *testing.T) {
+	rbl := NewRingBufferLane(nil, 4)
+	rbl.SetCallerInfo(true)
+	rbl.Error("boom")
+	caller := testCallerLine(t)
+
+	events := rbl.Events()
+	if len(events) != 1 || events[0].Caller != caller {
+		t.Errorf("expected caller %s, got %+v", caller, events)
+	}
+}
+
+func TestCallerInfoTeeTimeout(t *testing.T) {
+	ll := NewLogLane(nil, WithTeeTimeout(time.Second))
+	tl := NewTestingLane(nil)
+	tl.SetCallerInfo(true)
+	tl.EnableStackTrace(LogLevelError, true)
+	ll.AddTee(tl)
+
+	// the tee runs on a worker goroutine, but reports the logging call
+	ll.Error("on a worker")
+	caller := testCallerLine(t)
+
+	events := tl.Events()
+	if len(events) < 2 || events[0].Caller != caller {
+		t.Fatalf("expected caller %s, got %+v", caller, events)
+	}
+	if !strings.Contains(tl.EventsToString(), "TestCallerInfoTeeTimeout") {
+		t.Errorf("expected the logging call's stack in:\n%s", tl.EventsToString())
+	}
+}
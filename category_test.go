@@ -0,0 +1,83 @@
+package lane
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCategory(t *testing.T) {
+	for _, l := range []Lane{NewTestingLane(nil), NewLogLane(nil), NewNullLane(nil)} {
+		tl := NewTestingLane(nil)
+		l.AddTee(tl)
+
+		http := l.Category("http")
+		http.Info("request")
+		http.Derive().Info("derived")
+		l.Info("plain")
+
+		events := tl.Events()
+		if len(events) != 3 || events[0].Category != "http" || events[1].Category != "http" || events[2].Category != "" {
+			t.Errorf("%T: unexpected events: %+v", l, events)
+		}
+		if l.(laneInternal).LaneProps().category != "" {
+			t.Errorf("%T: the category changed the parent", l)
+		}
+	}
+}
+
+func TestCategoryPrefix(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	ll := NewLogLane(nil)
+	ll.SetJourneyId("trip")
+	ll.Category("db").Info("connected")
+
+	if !strings.Contains(buf.String(), "INFO {trip:") || !strings.Contains(buf.String(), "} [db] connected") {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+}
+
+func TestCategoryRecord(t *testing.T) {
+	ll := NewLogLane(nil).(LogLane)
+	var sink testRecordSink
+	ll.AddRecordSink(&sink)
+	ll.Category("cache").Warn("miss")
+
+	recs := sink.records
+	if len(recs) != 1 || recs[0].Category != "cache" {
+		t.Fatalf("unexpected records %+v", recs)
+	}
+
+	for _, enc := range []Encoder{JSONEncoder{}, CBOREncoder{}, LogfmtEncoder{}} {
+		data := enc.AppendRecord(nil, &recs[0])
+		if _, ok := enc.(CBOREncoder); ok {
+			rec, err := NewCBORDecoder(bytes.NewReader(data)).Decode()
+			if err != nil || rec.Category != "cache" {
+				t.Errorf("unexpected decoding %+v %v", rec, err)
+			}
+		} else if !bytes.Contains(data, []byte("cache")) {
+			t.Errorf("%T: the category is missing from %q", enc, data)
+		}
+	}
+}
+
+func TestCategoryFilter(t *testing.T) {
+	tl := NewTestingLane(nil)
+	tl.WantDescendantEvents(true)
+	fl, err := NewExprFilterLane(tl, `category == "http"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fl.Category("http").Info("kept")
+	fl.Category("db").Info("dropped")
+	fl.Info("dropped too")
+
+	if !tl.VerifyEventText("INFO\tkept") {
+		t.Errorf("unexpected events:\n%s", tl.EventsToString())
+	}
+}
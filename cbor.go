@@ -0,0 +1,264 @@
+package lane
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+type (
+	// A CBOR tagged value
+	cborTag struct {
+		num uint64
+		v   any
+	}
+)
+
+var errCborType = errors.New("unsupported cbor type")
+
+// CBOR major types
+const (
+	cborUint   = 0 << 5
+	cborNegInt = 1 << 5
+	cborBytes  = 2 << 5
+	cborText   = 3 << 5
+	cborArray  = 4 << 5
+	cborMap    = 5 << 5
+	cborTagged = 6 << 5
+)
+
+// Appends the CBOR encoding of v, which is made of nil, bool, integers,
+// float64, string, []byte, []any, []string, map[string]any and cborTag.
+// Map keys are written in sorted order.
+func appendCbor(b []byte, v any) []byte {
+	switch t := v.(type) {
+	case nil:
+		return append(b, 0xf6)
+	case bool:
+		if t {
+			return append(b, 0xf5)
+		}
+		return append(b, 0xf4)
+	case int:
+		return appendCborInt(b, int64(t))
+	case int64:
+		return appendCborInt(b, t)
+	case uint64:
+		return appendCborHead(b, cborUint, t)
+	case float64:
+		return binary.BigEndian.AppendUint64(append(b, 0xfb), math.Float64bits(t))
+	case string:
+		return append(appendCborHead(b, cborText, uint64(len(t))), t...)
+	case []byte:
+		return append(appendCborHead(b, cborBytes, uint64(len(t))), t...)
+	case []any:
+		b = appendCborHead(b, cborArray, uint64(len(t)))
+		for _, elem := range t {
+			b = appendCbor(b, elem)
+		}
+		return b
+	case []string:
+		b = appendCborHead(b, cborArray, uint64(len(t)))
+		for _, elem := range t {
+			b = appendCbor(b, elem)
+		}
+		return b
+	case map[string]any:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		b = appendCborHead(b, cborMap, uint64(len(t)))
+		for _, k := range keys {
+			b = appendCbor(b, k)
+			b = appendCbor(b, t[k])
+		}
+		return b
+	case cborTag:
+		return appendCbor(appendCborHead(b, cborTagged, t.num), t.v)
+	default:
+		panic(fmt.Sprintf("%v: %T", errCborType, v))
+	}
+}
+
+func appendCborInt(b []byte, n int64) []byte {
+	if n < 0 {
+		return appendCborHead(b, cborNegInt, uint64(-1-n))
+	}
+	return appendCborHead(b, cborUint, uint64(n))
+}
+
+// Appends the initial byte of a data item, with the argument [n] in the
+// smallest form that holds it
+func appendCborHead(b []byte, major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return append(b, major|byte(n))
+	case n <= math.MaxUint8:
+		return append(b, major|24, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, major|25), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, major|26), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, major|27), n)
+	}
+}
+
+// Reads one CBOR data item. Maps are decoded as map[string]any when their
+// keys are strings, or map[any]any otherwise. Indefinite lengths are not
+// supported.
+func readCbor(r *bufio.Reader) (v any, err error) {
+	initial, err := r.ReadByte()
+	if err != nil {
+		return
+	}
+	major := initial & 0xe0
+	info := initial & 0x1f
+
+	if major == 0xe0 {
+		return readCborSimple(r, info)
+	}
+
+	n, err := readCborArgument(r, info)
+	if err != nil {
+		return
+	}
+
+	switch major {
+	case cborUint:
+		if n > math.MaxInt64 {
+			return n, nil
+		}
+		return int64(n), nil
+	case cborNegInt:
+		if n > math.MaxInt64 {
+			return nil, errCborType
+		}
+		return -1 - int64(n), nil
+	case cborBytes:
+		return readCborBytes(r, n)
+	case cborText:
+		var raw []byte
+		raw, err = readCborBytes(r, n)
+		return string(raw), err
+	case cborArray:
+		return readCborArray(r, n)
+	case cborMap:
+		return readCborMap(r, n)
+	default: // cborTagged
+		var tagged any
+		if tagged, err = readCbor(r); err != nil {
+			return
+		}
+		return cborTag{num: n, v: tagged}, nil
+	}
+}
+
+// Reads the argument that follows the initial byte
+func readCborArgument(r *bufio.Reader, info byte) (n uint64, err error) {
+	if info < 24 {
+		return uint64(info), nil
+	}
+	if info > 27 {
+		return 0, errCborType
+	}
+	raw, err := readCborBytes(r, 1<<(info-24))
+	if err != nil {
+		return
+	}
+	for _, by := range raw {
+		n = n<<8 | uint64(by)
+	}
+	return
+}
+
+func readCborSimple(r *bufio.Reader, info byte) (v any, err error) {
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		return nil, nil
+	case 26:
+		var raw []byte
+		if raw, err = readCborBytes(r, 4); err != nil {
+			return
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(raw))), nil
+	case 27:
+		var raw []byte
+		if raw, err = readCborBytes(r, 8); err != nil {
+			return
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(raw)), nil
+	}
+	return nil, errCborType
+}
+
+func readCborBytes(r *bufio.Reader, n uint64) (raw []byte, err error) {
+	if n > math.MaxInt32 {
+		return nil, errCborType
+	}
+	raw = make([]byte, n)
+	_, err = io.ReadFull(r, raw)
+	return
+}
+
+func readCborArray(r *bufio.Reader, n uint64) (list []any, err error) {
+	list = make([]any, 0, min(n, 1024))
+	for range n {
+		var elem any
+		if elem, err = readCbor(r); err != nil {
+			return
+		}
+		list = append(list, elem)
+	}
+	return
+}
+
+func readCborMap(r *bufio.Reader, n uint64) (m any, err error) {
+	keys := make([]any, 0, min(n, 1024))
+	values := make([]any, 0, min(n, 1024))
+	allStrings := true
+	for range n {
+		var k, v any
+		if k, err = readCbor(r); err != nil {
+			return
+		}
+		if v, err = readCbor(r); err != nil {
+			return
+		}
+		switch k.(type) {
+		case string:
+		case nil, bool, int64, uint64, float64:
+			allStrings = false
+		default:
+			// a key that can't be hashed
+			return nil, errCborType
+		}
+		keys = append(keys, k)
+		values = append(values, v)
+	}
+
+	if allStrings {
+		sm := make(map[string]any, len(keys))
+		for i, k := range keys {
+			sm[k.(string)] = values[i]
+		}
+		return sm, nil
+	}
+
+	am := make(map[any]any, len(keys))
+	for i, k := range keys {
+		am[k] = values[i]
+	}
+	return am, nil
+}
@@ -0,0 +1,87 @@
+package lane
+
+import (
+	"bufio"
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestCborEncoding(t *testing.T) {
+	cases := []struct {
+		v        any
+		expected []byte
+	}{
+		{nil, []byte{0xf6}},
+		{true, []byte{0xf5}},
+		{false, []byte{0xf4}},
+		{10, []byte{0x0a}},
+		{24, []byte{0x18, 0x18}},
+		{1000, []byte{0x19, 0x03, 0xe8}},
+		{1000000, []byte{0x1a, 0x00, 0x0f, 0x42, 0x40}},
+		{-1, []byte{0x20}},
+		{-100, []byte{0x38, 0x63}},
+		{uint64(1) << 40, []byte{0x1b, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00}},
+		{1.5, []byte{0xfb, 0x3f, 0xf8, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}},
+		{"a", []byte{0x61, 'a'}},
+		{[]byte{1, 2}, []byte{0x42, 0x01, 0x02}},
+		{[]any{1, "a"}, []byte{0x82, 0x01, 0x61, 'a'}},
+		{[]string{"x"}, []byte{0x81, 0x61, 'x'}},
+		{map[string]any{"b": 2, "a": 1}, []byte{0xa2, 0x61, 'a', 0x01, 0x61, 'b', 0x02}},
+		{cborTag{num: 1, v: 1363896240}, []byte{0xc1, 0x1a, 0x51, 0x4b, 0x67, 0xb0}},
+	}
+
+	for _, c := range cases {
+		actual := appendCbor(nil, c.v)
+		if !bytes.Equal(actual, c.expected) {
+			t.Errorf("encoding %v: got %x, expected %x", c.v, actual, c.expected)
+		}
+	}
+}
+
+func TestCborUnsupportedType(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	appendCbor(nil, struct{}{})
+}
+
+func TestCborDecoding(t *testing.T) {
+	values := []any{
+		nil, true, false, int64(0), int64(24), int64(1000000), int64(-100), int64(math.MinInt64),
+		uint64(math.MaxUint64), 1.5, "text", []byte{1, 2},
+		[]any{int64(1), "a", []any{}},
+		map[string]any{"a": int64(1), "b": map[string]any{}},
+		cborTag{num: 0, v: "2024-03-04T05:06:07Z"},
+	}
+
+	var data []byte
+	for _, v := range values {
+		data = appendCbor(data, v)
+	}
+	data = append(data, 0xa1, 0x01, 0x02) // a map with an integer key
+	values = append(values, map[any]any{int64(1): int64(2)})
+
+	r := bufio.NewReader(bytes.NewReader(data))
+	for _, expected := range values {
+		actual, err := readCbor(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("got %#v, expected %#v", actual, expected)
+		}
+	}
+	if _, err := readCbor(r); err == nil {
+		t.Error("expected the end of the data")
+	}
+
+	for _, bad := range [][]byte{{0x5f}, {0x1c}, {0xf8, 0x20}, {0x62, 'a'}} {
+		if _, err := readCbor(bufio.NewReader(bytes.NewReader(bad))); err == nil {
+			t.Errorf("%x: expected an error", bad)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package lane
+
+import (
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type (
+	// Source of the time used in log timestamps
+	Clock interface {
+		Now() time.Time
+	}
+
+	systemClock struct{}
+
+	// A clock that only changes when told to, for reproducible test output
+	FixedClock struct {
+		mu  sync.Mutex
+		now time.Time
+	}
+)
+
+// The clock that reads the system time
+var SystemClock Clock = systemClock{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// Makes a clock that is frozen at time [t].
+func NewFixedClock(t time.Time) *FixedClock {
+	return &FixedClock{now: t}
+}
+
+func (fc *FixedClock) Now() time.Time {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.now
+}
+
+// Changes the frozen time.
+func (fc *FixedClock) Set(t time.Time) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.now = t
+}
+
+// Moves the frozen time forward by [d].
+func (fc *FixedClock) Advance(d time.Duration) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.now = fc.now.Add(d)
+}
+
+// Renders the timestamp the way the log package does for the given flags,
+// including the trailing space, or an empty string if no date or time flag
+// is set.
+func formatLogTime(t time.Time, flags int) string {
+	if flags&(log.Ldate|log.Ltime|log.Lmicroseconds) == 0 {
+		return ""
+	}
+
+	if flags&log.LUTC != 0 {
+		t = t.UTC()
+	}
+
+	buf := make([]byte, 0, 27)
+	if flags&log.Ldate != 0 {
+		year, month, day := t.Date()
+		buf = appendPadded(buf, year, 4)
+		buf = append(buf, '/')
+		buf = appendPadded(buf, int(month), 2)
+		buf = append(buf, '/')
+		buf = appendPadded(buf, day, 2)
+		buf = append(buf, ' ')
+	}
+	if flags&(log.Ltime|log.Lmicroseconds) != 0 {
+		hour, min, sec := t.Clock()
+		buf = appendPadded(buf, hour, 2)
+		buf = append(buf, ':')
+		buf = appendPadded(buf, min, 2)
+		buf = append(buf, ':')
+		buf = appendPadded(buf, sec, 2)
+		if flags&log.Lmicroseconds != 0 {
+			buf = append(buf, '.')
+			buf = appendPadded(buf, t.Nanosecond()/1e3, 6)
+		}
+		buf = append(buf, ' ')
+	}
+	return string(buf)
+}
+
+func appendPadded(buf []byte, n, width int) []byte {
+	s := strconv.Itoa(n)
+	for i := len(s); i < width; i++ {
+		buf = append(buf, '0')
+	}
+	return append(buf, s...)
+}
+
+// Renders the timestamp of [t] in [loc] for the given flags, as
+// formatLogTime() does, followed by the time zone when the time is included
+func formatLogTimeIn(t time.Time, flags int, loc *time.Location) string {
+	t = t.In(loc)
+	ts := formatLogTime(t, flags&^log.LUTC)
+	if flags&(log.Ltime|log.Lmicroseconds) != 0 {
+		ts += t.Format("MST") + " "
+	}
+	return ts
+}
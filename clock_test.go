@@ -0,0 +1,170 @@
+package lane
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFormatLogTime(t *testing.T) {
+	ts := time.Date(2024, 3, 4, 5, 6, 7, 891011000, time.UTC)
+
+	flagSets := []int{
+		0,
+		log.Ldate,
+		log.Ltime,
+		log.LstdFlags,
+		log.LstdFlags | log.Lmicroseconds,
+		log.Lmicroseconds | log.LUTC,
+	}
+
+	for _, flags := range flagSets {
+		var buf bytes.Buffer
+		l := log.New(&buf, "", flags)
+		l.Print("x")
+		expected := buf.String()
+
+		// the log package uses the current time; only compare the layout
+		actual := formatLogTime(ts, flags) + "x\n"
+		if len(actual) != len(expected) {
+			t.Errorf("flags %d: layout mismatch %q vs %q", flags, actual, expected)
+		}
+	}
+
+	if formatLogTime(ts, log.LstdFlags|log.Lmicroseconds|log.LUTC) != "2024/03/04 05:06:07.891011 " {
+		t.Errorf("wrong timestamp %q", formatLogTime(ts, log.LstdFlags|log.Lmicroseconds|log.LUTC))
+	}
+}
+
+func TestFixedClock(t *testing.T) {
+	ts := time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC)
+	fc := NewFixedClock(ts)
+	if !fc.Now().Equal(ts) {
+		t.Error("wrong time")
+	}
+	fc.Advance(time.Second)
+	if !fc.Now().Equal(ts.Add(time.Second)) {
+		t.Error("wrong advanced time")
+	}
+	fc.Set(ts)
+	if !fc.Now().Equal(ts) {
+		t.Error("wrong set time")
+	}
+}
+
+func TestDiskLaneGolden(t *testing.T) {
+	os.Remove("test.log")
+	defer os.Remove("test.log")
+
+	ts := time.Date(2024, 3, 4, 5, 6, 7, 0, time.Local)
+	fc := NewFixedClock(ts)
+
+	dl, err := NewDiskLane(nil, "test.log", WithClock(fc), WithLaneIdFormat(LaneIdCounter))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dl.Info("first")
+	fc.Advance(time.Minute)
+	dl2 := dl.Derive()
+	dl2.Warn("second")
+	dl2.Close()
+	dl.Close()
+
+	raw, err := os.ReadFile("test.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "2024/03/04 05:06:07 INFO {0000000001} first\n" +
+		"2024/03/04 05:07:07 WARN {0000000002} second\n"
+	if string(raw) != expected {
+		t.Errorf("golden mismatch:\n%s", string(raw))
+	}
+}
+
+func TestLogLaneClockMicroseconds(t *testing.T) {
+	ts := time.Date(2024, 3, 4, 5, 6, 7, 123456000, time.Local)
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer func() { log.SetOutput(os.Stderr) }()
+
+	ll := NewLogLane(nil, WithClock(NewFixedClock(ts)), WithIdSource(func() string { return "abc" }))
+	ll.Logger().SetFlags(log.LstdFlags | log.Lmicroseconds)
+	ll.Info("test")
+
+	if buf.String() != "2024/03/04 05:06:07.123456 INFO {abc} test\n" {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+}
+
+func TestLogLaneHeaderConcurrent(t *testing.T) {
+	ts := time.Date(2024, 3, 4, 5, 6, 7, 0, time.Local)
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	ll := NewLogLane(nil, WithClock(NewFixedClock(ts)), WithIdSource(func() string { return "abc" }))
+	ll.Logger().SetPrefix("app: ")
+	plain := NewLogLane(nil, WithIdSource(func() string { return "def" }))
+	plain.Logger().SetFlags(log.Lmsgprefix)
+	plain.Logger().SetPrefix("plain: ")
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				ll.Info("clock")
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				plain.Info("no time")
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line != "app: 2024/03/04 05:06:07 INFO {abc} clock" && line != "plain: INFO {def} no time" {
+			t.Fatalf("unexpected line %q", line)
+		}
+	}
+}
+
+func TestTimeLocation(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	ts := time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC)
+	east := time.FixedZone("EST", -5*60*60)
+	ll := NewLogLane(nil, WithClock(NewFixedClock(ts)), WithTimeLocation(east)).(LogLane)
+	var rec testRecordSink
+	ll.AddRecordSink(&rec)
+
+	ll.Info("eastern")
+	ll.SetTimeLocation(time.UTC)
+	ll.Derive().Info("utc")
+	if prior := ll.SetTimeLocation(nil); prior != time.UTC {
+		t.Errorf("unexpected prior location %v", prior)
+	}
+	ll.Info("default")
+
+	lines := strings.Split(buf.String(), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "2024/03/04 00:06:07 EST INFO ") || !strings.HasPrefix(lines[1], "2024/03/04 05:06:07 UTC INFO ") || !strings.HasPrefix(lines[2], "2024/03/04 "+ts.Local().Format("15:04:05")+" INFO ") {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+	if len(rec.records) != 3 || rec.records[0].Time.Location() != east || !rec.records[0].Time.Equal(ts) {
+		t.Errorf("unexpected records %v", rec.records)
+	}
+}
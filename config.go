@@ -0,0 +1,68 @@
+package lane
+
+type (
+	// The settings of a lane, from ConfigSnapshot(), that ApplyConfig()
+	// restores. The arrays are indexed by level.
+	LaneConfigState struct {
+		Level           LaneLogLevel
+		StackTrace      [logLevelMax]bool
+		StackTraceDepth [logLevelMax]int
+		CallerInfo      bool
+		MaxLength       int
+		LevelMaxLength  [logLevelMax]int
+		TruncationMode  TruncationMode
+		ObjectOptions   LogObjectOpt
+		LineEnding      LineEnding        // log lane types only
+		MultiLine       MultiLinePolicy   // log lane types only
+		FlagsMask       int               // log lane types only
+		Metadata        map[string]string // the tags of the lane, nil if it has none
+	}
+)
+
+// Captures the settings held by the stores the lane types have in common
+func captureConfig(l Lane, level LaneLogLevel, sts *stackTraceStore, cis *callerInfoStore, lcs *lengthConstraintStore) (state LaneConfigState) {
+	state.Level = level
+	for i := LogLevelTrace; i < logLevelMax; i++ {
+		state.StackTrace[i] = sts.stackEnabled(i)
+		state.StackTraceDepth[i] = sts.stackDepth(i)
+		state.LevelMaxLength[i] = int(lcs.levels[i].Load())
+	}
+	state.CallerInfo = cis.enabled.Load()
+	state.MaxLength = int(lcs.maxLength.Load())
+	state.TruncationMode = TruncationMode(lcs.mode.Load())
+	state.ObjectOptions = l.ObjectOptions()
+	if md := l.MetadataMap(); len(md) > 0 {
+		state.Metadata = md
+	}
+	return
+}
+
+// Changes the settings of [l] to [state] with its setters, so that a lane
+// group passes the settings to its members
+func applyConfig(l Lane, state LaneConfigState) {
+	l.SetLogLevel(state.Level)
+	for i := LogLevelTrace; i < logLevelMax; i++ {
+		l.EnableStackTraceDepth(i, state.StackTraceDepth[i])
+		l.EnableStackTrace(i, state.StackTrace[i])
+		l.SetLevelLengthConstraint(i, state.LevelMaxLength[i])
+	}
+	l.SetCallerInfo(state.CallerInfo)
+	l.SetLengthConstraint(state.MaxLength)
+	l.SetTruncationMode(state.TruncationMode)
+	l.SetObjectOptions(state.ObjectOptions)
+
+	for key := range l.MetadataMap() {
+		if _, kept := state.Metadata[key]; !kept {
+			l.DeleteMetadata(key)
+		}
+	}
+	for key, val := range state.Metadata {
+		l.SetMetadata(key, val)
+	}
+
+	if ll, ok := l.(LogLane); ok {
+		ll.SetLineEnding(state.LineEnding)
+		ll.SetMultiLinePolicy(state.MultiLine)
+		ll.SetFlagsMask(state.FlagsMask)
+	}
+}
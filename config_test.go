@@ -0,0 +1,85 @@
+package lane
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConfigSnapshotRestore(t *testing.T) {
+	for _, l := range []Lane{NewTestingLane(nil), NewLogLane(nil), NewNullLane(nil)} {
+		l.SetLogLevel(LogLevelWarn)
+		l.EnableStackTraceDepth(LogLevelError, 4)
+		l.SetLengthConstraint(100)
+		l.SetLevelLengthConstraint(LogLevelTrace, -1)
+		l.SetMetadata("service", "api")
+		state := l.ConfigSnapshot()
+
+		l.SetLogLevel(LogLevelTrace)
+		l.EnableStackTrace(LogLevelError, false)
+		l.EnableStackTrace(LogLevelDebug, true)
+		l.SetCallerInfo(true)
+		l.SetLengthConstraint(20)
+		l.SetLevelLengthConstraint(LogLevelTrace, 50)
+		l.SetTruncationMode(TruncateMiddle)
+		l.SetObjectEncoding(EncodingJSONTags)
+		l.SetMetadata("service", "worker")
+		l.SetMetadata("debug", "on")
+		if reflect.DeepEqual(l.ConfigSnapshot(), state) {
+			t.Fatalf("%T: the changes were not captured", l)
+		}
+
+		l.ApplyConfig(state)
+		if actual := l.ConfigSnapshot(); !reflect.DeepEqual(actual, state) {
+			t.Errorf("%T: got %+v, expected %+v", l, actual, state)
+		}
+		if state.Level != LogLevelWarn || !state.StackTrace[LogLevelError] || state.StackTraceDepth[LogLevelError] != 4 ||
+			state.MaxLength != 100 || state.LevelMaxLength[LogLevelTrace] != LengthUnconstrained {
+			t.Errorf("%T: unexpected state %+v", l, state)
+		}
+		if md := l.MetadataMap(); len(md) != 1 || md["service"] != "api" {
+			t.Errorf("%T: unexpected metadata %v", l, md)
+		}
+	}
+}
+
+func TestConfigSnapshotLogLane(t *testing.T) {
+	ll := NewLogLane(nil).(LogLane)
+	state := ll.ConfigSnapshot()
+	if state.LineEnding != LineEndingLF || state.FlagsMask != 0 || state.Metadata != nil {
+		t.Errorf("unexpected state %+v", state)
+	}
+
+	ll.AddCR(true)
+	ll.SetFlagsMask(3)
+	changed := ll.ConfigSnapshot()
+	if changed.LineEnding != LineEndingCRLF || changed.FlagsMask != 3 {
+		t.Errorf("unexpected state %+v", changed)
+	}
+
+	// a derived lane is reconciled with its parent's settings
+	child := ll.Derive()
+	ll.SetLogLevel(LogLevelError)
+	child.ApplyConfig(ll.ConfigSnapshot())
+	if !reflect.DeepEqual(child.ConfigSnapshot(), ll.ConfigSnapshot()) {
+		t.Errorf("the derived lane was not reconciled: %+v", child.ConfigSnapshot())
+	}
+
+	ll.ApplyConfig(state)
+	if actual := ll.ConfigSnapshot(); !reflect.DeepEqual(actual, state) {
+		t.Errorf("got %+v, expected %+v", actual, state)
+	}
+}
+
+func TestConfigApplyLaneGroup(t *testing.T) {
+	member := NewTestingLane(nil)
+	g := NewLaneGroup(nil, []Lane{member})
+
+	state := g.ConfigSnapshot()
+	state.Level = LogLevelError
+	state.Metadata = map[string]string{"k": "v"}
+	g.ApplyConfig(state)
+
+	if member.ConfigSnapshot().Level != LogLevelError || member.GetMetadata("k") != "v" {
+		t.Errorf("the member was not configured: %+v", member.ConfigSnapshot())
+	}
+}
@@ -0,0 +1,230 @@
+package lane
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+type (
+	// The JSON contents of a file watched by WatchConfig()
+	WatchedConfig struct {
+		Level string             `json:"level,omitempty"` // the root lane's log level, such as "info", or "" to leave it
+		Tees  []WatchedTeeConfig `json:"tees,omitempty"`
+	}
+
+	// A tee attached to the root lane by WatchConfig()
+	WatchedTeeConfig struct {
+		Name   string `json:"name"`             // identifies the tee across changes to the file
+		Type   string `json:"type"`             // "disk" for a disk lane, or "log" for a log lane
+		Path   string `json:"path,omitempty"`   // the log file of a disk tee
+		Level  string `json:"level,omitempty"`  // the tee's log level, or "" for TRACE
+		Filter string `json:"filter,omitempty"` // a NewExprFilterLane() expression, or "" for all messages
+	}
+
+	// The state of a WatchConfig() call
+	configWatcher struct {
+		mu      sync.Mutex
+		root    Lane
+		path    string
+		raw     []byte
+		modTime time.Time
+		tees    map[string]watchedTee
+	}
+
+	watchedTee struct {
+		config WatchedTeeConfig
+		l      Lane
+	}
+)
+
+// How often WatchConfig() checks its file for changes
+var configPollInterval = time.Second
+
+// Applies the JSON configuration file at [path] to [root], and applies it
+// again whenever the file changes, so that the log level can be adjusted
+// and tees attached or detached without restarting the service, such as:
+//
+//	{"level": "info", "tees": [{"name": "debug", "type": "disk", "path": "/tmp/debug.log",
+//		"filter": "msg =~ \"timeout\""}]}
+//
+// A tee whose configuration changes is replaced. An invalid file fails the
+// call, or is logged as an error when it changes later, leaving the prior
+// configuration in place. The file is checked every second.
+//
+// Call [stop] to end the watch and detach and close the tees. The watch also
+// ends when the root lane's context is done.
+func WatchConfig(path string, root Lane) (stop func(), err error) {
+	cw := &configWatcher{root: root, path: path, tees: map[string]watchedTee{}}
+	if _, err = cw.poll(); err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(configPollInterval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if changed, err := cw.poll(); err != nil {
+					root.Errorf("log config %s: %v", path, err)
+				} else if changed {
+					root.Infof("log config %s applied", path)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	stop = func() {
+		once.Do(func() {
+			ticker.Stop()
+			close(done)
+			cw.detachAll()
+		})
+	}
+	context.AfterFunc(root, stop)
+	return
+}
+
+// Applies the file if it changed since the last poll
+func (cw *configWatcher) poll() (changed bool, err error) {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+
+	if cw.tees == nil {
+		return // stopped
+	}
+
+	fi, err := os.Stat(cw.path)
+	if err != nil {
+		return
+	}
+	if cw.raw != nil && fi.ModTime().Equal(cw.modTime) {
+		return
+	}
+	raw, err := os.ReadFile(cw.path)
+	if err != nil {
+		return
+	}
+	cw.modTime = fi.ModTime()
+	if cw.raw != nil && bytes.Equal(raw, cw.raw) {
+		return
+	}
+	cw.raw = raw
+
+	var config WatchedConfig
+	if err = json.Unmarshal(raw, &config); err != nil {
+		return
+	}
+	if err = cw.apply(config); err != nil {
+		return
+	}
+	changed = true
+	return
+}
+
+func (cw *configWatcher) apply(config WatchedConfig) error {
+	level, found := parseLevelName(config.Level)
+	if config.Level != "" && !found {
+		return fmt.Errorf("unknown level %q", config.Level)
+	}
+
+	// make the new tees before changing anything, so that an error leaves
+	// the prior configuration in place
+	added := map[string]watchedTee{}
+	names := make([]string, 0, len(config.Tees))
+	for _, tc := range config.Tees {
+		if slices.Contains(names, tc.Name) {
+			closeWatchedTees(added)
+			return fmt.Errorf("duplicate tee name %q", tc.Name)
+		}
+		names = append(names, tc.Name)
+
+		if wt, found := cw.tees[tc.Name]; found && wt.config == tc {
+			continue
+		}
+		l, err := makeWatchedTee(tc)
+		if err != nil {
+			closeWatchedTees(added)
+			return fmt.Errorf("tee %q: %w", tc.Name, err)
+		}
+		added[tc.Name] = watchedTee{config: tc, l: l}
+	}
+
+	for name, wt := range cw.tees {
+		if _, replaced := added[name]; replaced || !slices.Contains(names, name) {
+			cw.root.RemoveTee(wt.l)
+			wt.l.Close()
+			delete(cw.tees, name)
+		}
+	}
+	for _, name := range names {
+		if wt, found := added[name]; found {
+			cw.root.AddTee(wt.l)
+			cw.tees[name] = wt
+		}
+	}
+
+	if found {
+		cw.root.SetLogLevel(level)
+	}
+	return nil
+}
+
+func makeWatchedTee(tc WatchedTeeConfig) (l Lane, err error) {
+	level := LogLevelTrace
+	if tc.Level != "" {
+		var found bool
+		if level, found = parseLevelName(tc.Level); !found {
+			return nil, fmt.Errorf("unknown level %q", tc.Level)
+		}
+	}
+
+	switch tc.Type {
+	case "disk":
+		if l, err = NewDiskLane(nil, tc.Path); err != nil {
+			return
+		}
+	case "log":
+		l = NewLogLane(nil)
+	default:
+		return nil, fmt.Errorf("unknown type %q", tc.Type)
+	}
+	l.SetLogLevel(level)
+
+	if tc.Filter != "" {
+		wrapped := l
+		if l, err = NewExprFilterLane(wrapped, tc.Filter); err != nil {
+			wrapped.Close()
+			return
+		}
+		l.SetLogLevel(level)
+	}
+	return
+}
+
+func closeWatchedTees(tees map[string]watchedTee) {
+	for _, wt := range tees {
+		wt.l.Close()
+	}
+}
+
+// Detaches and closes the tees at the end of the watch
+func (cw *configWatcher) detachAll() {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+
+	for _, wt := range cw.tees {
+		cw.root.RemoveTee(wt.l)
+		wt.l.Close()
+	}
+	cw.tees = nil
+}
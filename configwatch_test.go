@@ -0,0 +1,115 @@
+package lane
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, path, text string, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Waits for the watcher to apply the configuration
+func waitForCondition(t *testing.T, cond func() bool) {
+	t.Helper()
+	for start := time.Now(); !cond(); time.Sleep(time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("timed out")
+		}
+	}
+}
+
+func TestWatchConfig(t *testing.T) {
+	prior := configPollInterval
+	configPollInterval = time.Millisecond
+	defer func() { configPollInterval = prior }()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "lane.json")
+	diskPath := filepath.Join(dir, "debug.log")
+	modTime := time.Now().Add(-time.Hour)
+	writeConfig(t, path, `{"level": "warn"}`, modTime)
+
+	root := NewTestingLane(nil)
+	stop, err := WatchConfig(path, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+	if root.ConfigSnapshot().Level != LogLevelWarn || len(root.Tees()) != 0 {
+		t.Fatal("the initial config wasn't applied")
+	}
+
+	writeConfig(t, path, `{"level": "info", "tees": [{"name": "debug", "type": "disk", "path": "`+diskPath+`", "filter": "msg =~ timeout"}]}`, modTime.Add(time.Second))
+	waitForCondition(t, func() bool { return len(root.Tees()) == 1 })
+	if root.ConfigSnapshot().Level != LogLevelInfo {
+		t.Error("the level wasn't changed")
+	}
+
+	root.Info("request timeout")
+	root.Info("request done")
+
+	writeConfig(t, path, `{"tees": [{"name": "debug", "type": "disk", "path": "`+diskPath+`", "filter": "msg =~ timeout"}, {"name": "bad", "type": "unknown"}]}`, modTime.Add(2*time.Second))
+	waitForCondition(t, func() bool { return root.Contains(`tee "bad": unknown type "unknown"`) })
+	if len(root.Tees()) != 1 {
+		t.Error("the prior tees should remain")
+	}
+
+	writeConfig(t, path, `{}`, modTime.Add(3*time.Second))
+	waitForCondition(t, func() bool { return len(root.Tees()) == 0 })
+
+	data, err := os.ReadFile(diskPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "request timeout") || strings.Contains(string(data), "request done") {
+		t.Errorf("unexpected disk tee output %q", data)
+	}
+}
+
+func TestWatchConfigStop(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lane.json")
+	writeConfig(t, path, `{"tees": [{"name": "console", "type": "log", "level": "error"}]}`, time.Now())
+
+	root := NewNullLane(nil)
+	stop, err := WatchConfig(path, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tees := root.Tees(); len(tees) != 1 || tees[0].ConfigSnapshot().Level != LogLevelError {
+		t.Fatalf("unexpected tees %v", tees)
+	}
+	stop()
+	stop()
+	if len(root.Tees()) != 0 {
+		t.Error("the tees weren't detached")
+	}
+}
+
+func TestWatchConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	root := NewNullLane(nil)
+	if _, err := WatchConfig(filepath.Join(dir, "missing.json"), root); err == nil {
+		t.Error("expected a missing file error")
+	}
+
+	path := filepath.Join(dir, "lane.json")
+	for _, text := range []string{`{"level": "loud"}`, `{"tees": [{"name": "a", "type": "log"}, {"name": "a", "type": "log"}]}`, `{`} {
+		writeConfig(t, path, text, time.Now())
+		if _, err := WatchConfig(path, root); err == nil {
+			t.Errorf("expected an error for %s", text)
+		}
+	}
+	if len(root.Tees()) != 0 {
+		t.Error("a failed config attached tees")
+	}
+}
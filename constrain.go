@@ -0,0 +1,322 @@
+package lane
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+type (
+	// Selects which part of a long message is kept
+	TruncationMode int32
+
+	// Common implementation of the length constraint settings
+	lengthConstraintStore struct {
+		maxLength atomic.Int32
+		levels    [logLevelMax]atomic.Int32
+		mode      atomic.Int32
+	}
+
+	// Implemented by lanes that can report their length constraint, so that
+	// object logs can be shortened without breaking the JSON
+	lengthConstrainer interface {
+		lengthConstraint(level LaneLogLevel) int
+	}
+
+	// JSON value decoded with member order preserved
+	jsonNode struct {
+		raw      string // encoded scalar, or empty for containers
+		str      *string
+		isObject bool
+		isArray  bool
+		keys     []string
+		children []*jsonNode
+	}
+)
+
+const (
+	// Keeps the start of the message
+	TruncateEnd TruncationMode = iota
+	// Keeps the start and the end of the message ("start … end"), which is
+	// useful when the end holds an error code
+	TruncateMiddle
+)
+
+// Per-level length constraint that removes the limit for the level,
+// regardless of the lane-wide limit
+const LengthUnconstrained = -1
+
+var errTrailingJson = errors.New("unexpected data after JSON value")
+
+const (
+	elisionMark      = "…"
+	elidedJsonString = `"` + elisionMark + `"`
+	elidedJsonMember = elidedJsonString + ":" + elidedJsonString
+)
+
+// Set a limit on the message length, or less than 1 for no limit.
+func (lcs *lengthConstraintStore) SetLengthConstraint(maxLength int) int {
+	old := lcs.maxLength.Load()
+	if maxLength > 1 {
+		lcs.maxLength.Store(int32(maxLength))
+	} else {
+		lcs.maxLength.Store(0)
+	}
+	return int(old)
+}
+
+// Set a limit on the message length for a single level. Zero applies the
+// lane-wide limit, and LengthUnconstrained removes the limit for the level.
+func (lcs *lengthConstraintStore) SetLevelLengthConstraint(level LaneLogLevel, maxLength int) (prior int) {
+	if level < LogLevelTrace || level >= logLevelMax {
+		panic("invalid level argument")
+	}
+
+	limit := int32(0)
+	if maxLength > 1 {
+		limit = int32(maxLength)
+	} else if maxLength < 0 {
+		limit = LengthUnconstrained
+	}
+	return int(lcs.levels[level].Swap(limit))
+}
+
+// Selects which part of a long message is kept.
+func (lcs *lengthConstraintStore) SetTruncationMode(mode TruncationMode) (prior TruncationMode) {
+	return TruncationMode(lcs.mode.Swap(int32(mode)))
+}
+
+// Applies the lane-wide length constraint to the message.
+func (lcs *lengthConstraintStore) Constrain(text string) string {
+	return constrainText(text, int(lcs.maxLength.Load()), TruncationMode(lcs.mode.Load()))
+}
+
+// Applies the length constraint for the level to the message.
+func (lcs *lengthConstraintStore) constrainLevel(level LaneLogLevel, text string) string {
+	return constrainText(text, lcs.lengthConstraint(level), TruncationMode(lcs.mode.Load()))
+}
+
+func (lcs *lengthConstraintStore) lengthConstraint(level LaneLogLevel) int {
+	if level >= LogLevelTrace && level < logLevelMax {
+		switch limit := lcs.levels[level].Load(); {
+		case limit == LengthUnconstrained:
+			return 0
+		case limit > 0:
+			return int(limit)
+		}
+	}
+	return int(lcs.maxLength.Load())
+}
+
+// Shortens text to at most maxLen runes, marking the removed text with an
+// ellipsis, without splitting a multi-byte UTF-8 sequence.
+func constrainText(text string, maxLen int, mode TruncationMode) string {
+	if maxLen <= 0 || len(text) <= maxLen {
+		return text
+	}
+
+	count := utf8.RuneCountInString(text)
+	if count <= maxLen {
+		return text
+	}
+
+	keep := maxLen - 1
+	head := keep
+	if mode == TruncateMiddle {
+		head = keep - keep/2
+	}
+	tail := keep - head
+
+	return text[:runeOffset(text, head)] + elisionMark + text[runeOffset(text, count-tail):]
+}
+
+// Provides the byte offset of the rune at index n
+func runeOffset(text string, n int) int {
+	for pos := range text {
+		if n == 0 {
+			return pos
+		}
+		n--
+	}
+	return len(text)
+}
+
+// Shortens an object log line to at most maxLen runes. When possible, the
+// inner fields of the JSON are elided so that the text after the message
+// remains parseable.
+func constrainObjectText(message string, raw []byte, maxLen int) string {
+	enc := message + ": " + string(raw)
+	if maxLen <= 0 || len(enc) <= maxLen || utf8.RuneCountInString(enc) <= maxLen {
+		return enc
+	}
+
+	budget := maxLen - utf8.RuneCountInString(message) - 2
+	if budget >= utf8.RuneCountInString(elidedJsonString) {
+		node, err := parseJsonNode(raw)
+		if err == nil {
+			if elided, _ := node.elide(budget); elided != "" {
+				return message + ": " + elided
+			}
+		}
+	}
+
+	return constrainText(enc, maxLen, TruncateEnd)
+}
+
+// Decodes JSON into a tree that keeps the order of object members.
+func parseJsonNode(raw []byte) (node *jsonNode, err error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+
+	node, err = decodeJsonNode(dec)
+	if err != nil {
+		return
+	}
+	if dec.More() {
+		err = errTrailingJson
+	}
+	return
+}
+
+func decodeJsonNode(dec *json.Decoder) (node *jsonNode, err error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return
+	}
+
+	node = &jsonNode{}
+	switch t := tok.(type) {
+	case json.Delim:
+		if t == '{' {
+			node.isObject = true
+		} else {
+			node.isArray = true
+		}
+		for dec.More() {
+			if node.isObject {
+				var keyTok json.Token
+				if keyTok, err = dec.Token(); err != nil {
+					return
+				}
+				node.keys = append(node.keys, keyTok.(string))
+			}
+			var child *jsonNode
+			if child, err = decodeJsonNode(dec); err != nil {
+				return
+			}
+			node.children = append(node.children, child)
+		}
+		_, err = dec.Token() // closing delimiter
+
+	case string:
+		node.str = &t
+		node.raw = jsonString(t)
+
+	case json.Number:
+		node.raw = t.String()
+
+	default:
+		var b []byte
+		b, err = json.Marshal(t)
+		node.raw = string(b)
+	}
+	return
+}
+
+func jsonString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+// Renders the node in at most budget runes. Values that don't fit are cut
+// short and the rest of their container is replaced with "…". Returns an
+// empty string if not even the elision fits.
+func (node *jsonNode) elide(budget int) (out string, complete bool) {
+	if node.isObject || node.isArray {
+		return node.elideContainer(budget)
+	}
+
+	if utf8.RuneCountInString(node.raw) <= budget {
+		return node.raw, true
+	}
+	if budget < utf8.RuneCountInString(elidedJsonString) {
+		return
+	}
+	if node.str == nil {
+		return elidedJsonString, false
+	}
+
+	// keep as much of the string as fits
+	var sb strings.Builder
+	used := utf8.RuneCountInString(elidedJsonString)
+	for _, r := range *node.str {
+		n := utf8.RuneCountInString(jsonString(string(r))) - 2
+		if used+n > budget {
+			break
+		}
+		sb.WriteRune(r)
+		used += n
+	}
+	return jsonString(sb.String() + elisionMark), false
+}
+
+func (node *jsonNode) elideContainer(budget int) (out string, complete bool) {
+	open, close, mark := "[", "]", elidedJsonString
+	if node.isObject {
+		open, close, mark = "{", "}", elidedJsonMember
+	}
+	markLen := utf8.RuneCountInString(mark)
+
+	if budget < 2+markLen && !(len(node.children) == 0 && budget >= 2) {
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(open)
+	used := 2
+
+	for i, child := range node.children {
+		sep := ""
+		if i > 0 {
+			sep = ","
+		}
+		prefix := sep
+		if node.isObject {
+			prefix += jsonString(node.keys[i]) + ":"
+		}
+		prefixLen := utf8.RuneCountInString(prefix)
+
+		// leave room to mark the elision of the members that follow
+		avail := budget - used - prefixLen
+		if i < len(node.children)-1 {
+			avail -= 1 + markLen
+		}
+
+		text, childComplete := "", false
+		if avail > 0 {
+			text, childComplete = child.elide(avail)
+		}
+		if text == "" {
+			sb.WriteString(sep + mark)
+			sb.WriteString(close)
+			return sb.String(), false
+		}
+
+		sb.WriteString(prefix + text)
+		used += prefixLen + utf8.RuneCountInString(text)
+
+		if !childComplete {
+			if i < len(node.children)-1 {
+				sb.WriteString("," + mark)
+			}
+			sb.WriteString(close)
+			return sb.String(), false
+		}
+	}
+
+	sb.WriteString(close)
+	return sb.String(), true
+}
@@ -0,0 +1,183 @@
+package lane
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestConstrainTextRunes(t *testing.T) {
+	cases := []struct {
+		text     string
+		maxLen   int
+		expected string
+	}{
+		{"hello", 0, "hello"},
+		{"hello", 5, "hello"},
+		{"hello world", 5, "hell…"},
+		{"héllo wörld", 5, "héll…"},
+		{"日本語のテキスト", 4, "日本語…"},
+		{"日本語", 3, "日本語"},
+		{"日本語", 2, "日…"},
+	}
+
+	for _, c := range cases {
+		actual := constrainText(c.text, c.maxLen, TruncateEnd)
+		if actual != c.expected {
+			t.Errorf("constrain %q to %d: got %q, expected %q", c.text, c.maxLen, actual, c.expected)
+		}
+		if !utf8.ValidString(actual) {
+			t.Errorf("invalid utf-8 %q", actual)
+		}
+	}
+}
+
+func TestConstrainObjectText(t *testing.T) {
+	cases := []struct {
+		raw      string
+		maxLen   int
+		expected string
+	}{
+		{`{"a":1}`, 0, `obj: {"a":1}`},
+		{`{"a":1,"b":2}`, 100, `obj: {"a":1,"b":2}`},
+		{`{"a":1,"b":2,"c":3}`, 20, `obj: {"a":1,"…":"…"}`},
+		{`{"a":"the quick brown fox"}`, 20, `obj: {"a":"the qu…"}`},
+		{`[1,2,3,4,5,6,7,8,9]`, 15, `obj: [1,2,"…"]`},
+		{`{"a":1,"b":{"c":[1,2,3,4,5,6]}}`, 30, `obj: {"a":1,"b":{"c":[1,"…"]}}`},
+		{`"日本語のテキスト"`, 10, `obj: "日本…"`},
+		{`12345678901234567890`, 10, `obj: "…"`},
+		{`{"a":1}`, 6, `obj: …`},
+	}
+
+	for _, c := range cases {
+		actual := constrainObjectText("obj", []byte(c.raw), c.maxLen)
+		if actual != c.expected {
+			t.Errorf("constrain %s to %d: got %s, expected %s", c.raw, c.maxLen, actual, c.expected)
+		}
+		if c.maxLen > 0 && utf8.RuneCountInString(actual) > c.maxLen {
+			t.Errorf("constrain %s to %d: length %d", c.raw, c.maxLen, utf8.RuneCountInString(actual))
+		}
+	}
+}
+
+func TestConstrainObjectParseable(t *testing.T) {
+	tl := NewTestingLane(nil)
+	tl.SetLengthConstraint(60)
+
+	type inner struct {
+		Name  string
+		Items []int
+	}
+	obj := map[string]any{
+		"first":  inner{Name: "ünïcödé nämé that is long", Items: []int{1, 2, 3, 4, 5}},
+		"second": strings.Repeat("x", 100),
+	}
+
+	tl.InfoObject("obj", obj)
+
+	ptl := tl.(*testingLane)
+	if len(ptl.Events()) != 1 {
+		t.Fatal("expected one event")
+	}
+	msg := ptl.Events()[0].Message
+	if utf8.RuneCountInString(msg) > 60 {
+		t.Errorf("message too long: %s", msg)
+	}
+
+	text, found := strings.CutPrefix(msg, "obj: ")
+	if !found {
+		t.Fatalf("unexpected message: %s", msg)
+	}
+	var v any
+	if err := json.Unmarshal([]byte(text), &v); err != nil {
+		t.Errorf("elided json not parseable: %s: %v", text, err)
+	}
+}
+
+func TestConstrainTextMiddle(t *testing.T) {
+	cases := []struct {
+		text     string
+		maxLen   int
+		expected string
+	}{
+		{"hello", 5, "hello"},
+		{"request failed with code 503", 11, "reque…e 503"},
+		{"request failed with code 503", 10, "reque… 503"},
+		{"日本語のテキスト", 5, "日本…スト"},
+	}
+
+	for _, c := range cases {
+		actual := constrainText(c.text, c.maxLen, TruncateMiddle)
+		if actual != c.expected {
+			t.Errorf("constrain %q to %d: got %q, expected %q", c.text, c.maxLen, actual, c.expected)
+		}
+	}
+}
+
+func TestLevelLengthConstraint(t *testing.T) {
+	tl := NewTestingLane(nil)
+	tl.SetLengthConstraint(10)
+
+	if prior := tl.SetLevelLengthConstraint(LogLevelError, LengthUnconstrained); prior != 0 {
+		t.Errorf("expected initial 0, got %d", prior)
+	}
+	if prior := tl.SetLevelLengthConstraint(LogLevelTrace, 5); prior != 0 {
+		t.Errorf("expected initial 0, got %d", prior)
+	}
+
+	tl.Trace("the quick brown fox")
+	tl.Info("the quick brown fox")
+	tl.Error("the quick brown fox")
+
+	if !tl.VerifyEventText("TRACE\tthe …\nINFO\tthe quick…\nERROR\tthe quick brown fox") {
+		t.Error("wrong events")
+	}
+
+	if prior := tl.SetLevelLengthConstraint(LogLevelError, 0); prior != LengthUnconstrained {
+		t.Errorf("expected unconstrained, got %d", prior)
+	}
+	tl.Error("the quick brown fox")
+	if tl.EventsToString() != "TRACE\tthe …\nINFO\tthe quick…\nERROR\tthe quick brown fox\nERROR\tthe quick…" {
+		t.Errorf("wrong events: %s", tl.EventsToString())
+	}
+}
+
+func TestLevelLengthConstraintLogLane(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer func() { log.SetOutput(os.Stderr) }()
+
+	ll := NewLogLane(nil)
+	ll.SetLengthConstraint(10)
+	ll.SetLevelLengthConstraint(LogLevelError, LengthUnconstrained)
+	ll.SetLevelLengthConstraint(LogLevelWarn, 21)
+	ll.SetTruncationMode(TruncateMiddle)
+
+	ll.Info("request failed with code 503")
+	ll.Errorf("request failed with code %d", 503)
+	ll.WarnObject("obj", map[string]int{"a": 1, "b": 2, "c": 3})
+
+	testExpectedStdout(t, &buf, []string{
+		"reque… 503",
+		"request failed with code 503",
+		`obj: {"a":1,"…":"…"}`,
+	})
+}
+
+func TestLengthConstraintInherit(t *testing.T) {
+	tl := NewTestingLane(nil)
+	tl.SetLevelLengthConstraint(LogLevelInfo, 10)
+	tl.SetTruncationMode(TruncateMiddle)
+
+	tl2 := tl.Derive()
+	if prior := tl2.SetLevelLengthConstraint(LogLevelInfo, 0); prior != 10 {
+		t.Errorf("expected 10, got %d", prior)
+	}
+	if prior := tl2.SetTruncationMode(TruncateEnd); prior != TruncateMiddle {
+		t.Errorf("expected middle mode, got %d", prior)
+	}
+}
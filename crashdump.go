@@ -0,0 +1,94 @@
+package lane
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type (
+	// Settings of WithCrashDump()
+	CrashDumpOptions struct {
+		Dir     string         // the directory of the crash report files, created if necessary
+		Ring    RingBufferLane // a ring buffer whose recent records are included, or nil
+		Records int            // the number of recent ring buffer records, or 0 for all of them
+		Root    Lane           // the root of the lane tree in the report, or nil for the root of the fatal lane
+	}
+)
+
+// Makes the fatal messages of a log lane, and of the lanes derived from it,
+// write a crash report to a file in [opts.Dir] before the panic handler is
+// called. The report holds the time, the fatal message, the lane tree, the
+// recent ring buffer records and the stacks of all goroutines, so that a
+// postmortem is self-contained. The path is logged at ERROR, and a failure
+// to write the report is passed to the WithDiagnostics() handler. Testing
+// and null lanes ignore it.
+func WithCrashDump(opts CrashDumpOptions) LaneOption {
+	return func(o *laneOptions) {
+		o.crashDump = &opts
+	}
+}
+
+// Writes the crash report of the fatal [message] if the lane has the
+// WithCrashDump() option
+func (ll *logLane) writeCrashReport(message string) {
+	if ll.crashDump == nil {
+		return
+	}
+
+	now := ll.now()
+	props := ll.LaneProps()
+	path, err := writeCrashReport(ll.crashDump, ll.outer, props, now, message)
+	if err != nil {
+		ll.reportDiagnostic(fmt.Errorf("crash report: %w", err), Record{Time: now, LaneId: props.laneId, JourneyId: props.journeyId, Category: props.category, ParentId: props.parentId, Level: LogLevelFatal, Message: message})
+		return
+	}
+	ll.outer.Errorf("crash report written to %s", path)
+}
+
+func writeCrashReport(opts *CrashDumpOptions, l Lane, props loggingProperties, now time.Time, message string) (path string, err error) {
+	if err = os.MkdirAll(opts.Dir, 0755); err != nil {
+		return
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "crash report %s\n", now.UTC().Format(time.RFC3339Nano))
+	fmt.Fprintf(&sb, "lane: %s\n", props.laneId)
+	if props.journeyId != "" {
+		fmt.Fprintf(&sb, "journey: %s\n", props.journeyId)
+	}
+	fmt.Fprintf(&sb, "message: %s\n", message)
+
+	root := opts.Root
+	if root == nil {
+		root = l
+		for p := root.Parent(); p != nil; p = p.Parent() {
+			root = p
+		}
+	}
+	fmt.Fprintf(&sb, "\nlane tree:\n%s", DumpTree(root))
+
+	if opts.Ring != nil {
+		records := opts.Ring.Events()
+		if opts.Records > 0 && len(records) > opts.Records {
+			records = records[len(records)-opts.Records:]
+		}
+		fmt.Fprintf(&sb, "\nrecent records (%d):\n", len(records))
+		enc := TextEncoder{Flags: log.Ldate | log.Ltime | log.Lmicroseconds}
+		var buf []byte
+		for i := range records {
+			buf = enc.AppendRecord(buf[:0], &records[i])
+			sb.Write(buf)
+		}
+	}
+
+	fmt.Fprintf(&sb, "\ngoroutines:\n%s", allGoroutineStacks())
+
+	name := fmt.Sprintf("crash-%s-%s.txt", now.UTC().Format("20060102T150405.000000000"), props.laneId)
+	path = filepath.Join(opts.Dir, name)
+	err = os.WriteFile(path, []byte(sb.String()), 0644)
+	return
+}
@@ -0,0 +1,84 @@
+package lane
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCrashDump(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	dir := filepath.Join(t.TempDir(), "crashes")
+	ring := NewRingBufferLane(nil, 10)
+	ll := NewLogLane(nil, WithCrashDump(CrashDumpOptions{Dir: dir, Ring: ring, Records: 2}), WithTee(ring))
+	ll.SetJourneyId("trip")
+	ll.TrackDescendants(true)
+	ll.SetPanicHandler(func() {})
+
+	ll.Info("first")
+	child := ll.Derive()
+	child.SetName("worker")
+	child.Warn("second")
+	child.Fatalf("out of %s", "memory")
+
+	files, err := os.ReadDir(dir)
+	if err != nil || len(files) != 1 {
+		t.Fatalf("unexpected crash files %v %v", files, err)
+	}
+	path := filepath.Join(dir, files[0].Name())
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := string(data)
+
+	for _, expected := range []string{
+		"lane: " + child.LaneId() + "\njourney: trip\nmessage: out of memory\n",
+		"\nlane tree:\n" + ll.LaneId() + " ",
+		"  " + child.LaneId() + ` "worker"`,
+		"\nrecent records (2):\n",
+		"WARN {trip:" + child.LaneId()[len(child.LaneId())-10:] + "} second\n",
+		"} out of memory\n",
+		"\ngoroutines:\ngoroutine ",
+		"TestCrashDump",
+	} {
+		if !strings.Contains(report, expected) {
+			t.Errorf("the report is missing %q:\n%s", expected, report)
+		}
+	}
+	if strings.Contains(report, "} first") {
+		t.Error("the record limit wasn't applied")
+	}
+	if !strings.Contains(buf.String(), "crash report written to "+path) {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+}
+
+func TestCrashDumpFailure(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var reported error
+	ll := NewLogLane(nil, WithCrashDump(CrashDumpOptions{Dir: filepath.Join(file, "crashes")}), WithDiagnostics(func(err error, rec Record) {
+		reported = err
+	}))
+	ll.SetPanicHandler(func() {})
+	ll.FatalObject("bad state", map[string]int{"a": 1})
+
+	if reported == nil || !strings.HasPrefix(reported.Error(), "crash report: ") || errors.Unwrap(reported) == nil {
+		t.Errorf("unexpected error %v", reported)
+	}
+}
@@ -0,0 +1,149 @@
+package lane
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type (
+	// Settings for a lane that sends log records to the Datadog logs intake API
+	DatadogConfig struct {
+		APIKey     string        // the Datadog API key, required
+		Site       string        // the Datadog site, defaults to "datadoghq.com"
+		Endpoint   string        // overrides the intake URL made from the site
+		Service    string        // the service of each record, when set
+		Source     string        // the ddsource of each record, defaults to "go"
+		Hostname   string        // the host of each record, when set
+		Tags       []string      // tags of each record, such as "env:prod"
+		Gzip       bool          // compress the requests
+		BatchSize  int           // maximum records per request, defaults to 100
+		QueueSize  int           // records held while sending, defaults to 10000
+		MaxRetries int           // attempts to send a batch before dropping it, defaults to 3
+		Backoff    Backoff       // delays between the attempts to send a batch; MaxRetries sets the attempts
+		Timeout    time.Duration // per request, defaults to 10 seconds
+		Client     *http.Client  // defaults to a client with the timeout
+		Spool      *SpoolConfig  // holds undeliverable records on disk for replay, when set
+	}
+)
+
+// Makes a lane that sends its log records to the Datadog logs intake API.
+// Each record carries the level as its status, the lane ID, journey ID,
+// category, parent lane ID and caller under "lane", and the metadata as
+// attributes. Records are queued and sent in batches in the background, so
+// logging does not wait for the network. Close the lane to flush the queue.
+//
+// Batches are kept within the intake's limits of 1000 logs and 5 MB. A log
+// over 1 MB has its message shortened to fit, or is dropped if it can't.
+func NewDatadogLane(ctx OptionalContext, cfg DatadogConfig, opts ...LaneOption) (l Lane, err error) {
+	if cfg.APIKey == "" {
+		err = errors.New("datadog lane requires an API key")
+		return
+	}
+	if cfg.Site == "" {
+		cfg.Site = "datadoghq.com"
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://http-intake.logs." + cfg.Site + "/api/v2/logs"
+	}
+	if cfg.Source == "" {
+		cfg.Source = "go"
+	}
+	tags := strings.Join(cfg.Tags, ",")
+
+	l = newHTTPBatchLane(ctx, httpBatchConfig{
+		endpoint:     cfg.Endpoint,
+		headers:      map[string]string{"Content-Type": "application/json", "DD-API-KEY": cfg.APIKey},
+		gzip:         cfg.Gzip,
+		batchSize:    min(cfg.BatchSize, 1000), // the intake's limits
+		maxBodyBytes: 5 << 20,
+		maxRecBytes:  1 << 20,
+		queueSize:    cfg.QueueSize,
+		maxRetries:   cfg.MaxRetries,
+		backoff:      cfg.Backoff,
+		timeout:      cfg.Timeout,
+		client:       cfg.Client,
+		spool:        cfg.Spool,
+		encodeRecord: func(rec *Record) []byte {
+			return encodeDatadogLog(rec, &cfg, tags)
+		},
+		encodeBody: encodeJsonArray,
+	}, opts...)
+	return
+}
+
+// Encodes [rec] as a Datadog log
+func encodeDatadogLog(rec *Record, cfg *DatadogConfig, tags string) []byte {
+	entry := make(map[string]any, len(rec.Fields)+8)
+	for k, v := range rec.Fields {
+		entry[k] = v
+	}
+
+	laneAttrs := map[string]string{"id": rec.LaneId}
+	if rec.JourneyId != "" {
+		laneAttrs["journey_id"] = rec.JourneyId
+	}
+	if rec.Category != "" {
+		laneAttrs["category"] = rec.Category
+	}
+	if rec.ParentId != "" {
+		laneAttrs["parent_id"] = rec.ParentId
+	}
+	if rec.Caller != "" {
+		laneAttrs["caller"] = rec.Caller
+	}
+	entry["lane"] = laneAttrs
+
+	entry["message"] = rec.Message
+	entry["status"] = datadogStatus(rec.Level)
+	entry["timestamp"] = rec.Time.UnixMilli()
+	entry["ddsource"] = cfg.Source
+	if tags != "" {
+		entry["ddtags"] = tags
+	}
+	if cfg.Service != "" {
+		entry["service"] = cfg.Service
+	}
+	if cfg.Hostname != "" {
+		entry["hostname"] = cfg.Hostname
+	}
+	if len(rec.Stack) > 0 {
+		entry["error"] = map[string]string{"stack": strings.Join(rec.Stack, "\n")}
+	}
+
+	data, _ := json.Marshal(entry)
+	return data
+}
+
+// Makes a JSON array of the encoded [records]
+func encodeJsonArray(records [][]byte) []byte {
+	size := 2
+	for _, rec := range records {
+		size += len(rec) + 1
+	}
+	buf := append(make([]byte, 0, size), '[')
+	for i, rec := range records {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, rec...)
+	}
+	return append(buf, ']')
+}
+
+func datadogStatus(level LaneLogLevel) string {
+	switch level {
+	case LogLevelTrace, LogLevelDebug:
+		return "debug"
+	case LogLevelWarn:
+		return "warning"
+	case LogLevelError:
+		return "error"
+	case LogLevelFatal, logLevelPreFatal:
+		return "critical"
+	default:
+		return "info"
+	}
+}
@@ -0,0 +1,86 @@
+package lane
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDatadogLane(t *testing.T) {
+	tis := newTestIntakeServer()
+	defer tis.Close()
+
+	dl, err := NewDatadogLane(nil, DatadogConfig{
+		APIKey:   "key",
+		Endpoint: tis.URL + "/api/v2/logs",
+		Service:  "checkout",
+		Hostname: "web-1",
+		Tags:     []string{"env:test", "team:pay"},
+		Gzip:     true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dl.SetJourneyId("trip")
+	dl.SetMetadata("tenant", "acme")
+	dl.Info("first")
+	dl.Error("second")
+	dl.Close()
+
+	var logs []map[string]any
+	for _, req := range tis.waitRequests(t, 1) {
+		if req.path != "/api/v2/logs" || req.header.Get("DD-API-KEY") != "key" || req.header.Get("Content-Encoding") != "gzip" {
+			t.Errorf("unexpected request %+v", req)
+		}
+		var batch []map[string]any
+		if err = json.Unmarshal(req.body, &batch); err != nil {
+			t.Fatal(err)
+		}
+		logs = append(logs, batch...)
+	}
+
+	if len(logs) != 2 {
+		t.Fatalf("unexpected logs %v", logs)
+	}
+	first, second := logs[0], logs[1]
+	if first["message"] != "first" || first["status"] != "info" || second["status"] != "error" {
+		t.Errorf("unexpected logs %v", logs)
+	}
+	if first["service"] != "checkout" || first["hostname"] != "web-1" || first["ddsource"] != "go" || first["ddtags"] != "env:test,team:pay" || first["tenant"] != "acme" {
+		t.Errorf("unexpected attributes %v", first)
+	}
+	if lane, _ := first["lane"].(map[string]any); lane["journey_id"] != "trip" || lane["id"] != dl.LaneId() {
+		t.Errorf("unexpected lane attributes %v", first["lane"])
+	}
+}
+
+func TestDatadogLaneConfig(t *testing.T) {
+	if _, err := NewDatadogLane(nil, DatadogConfig{}); err == nil {
+		t.Error("expected an error without an API key")
+	}
+
+	dl, err := NewDatadogLane(nil, DatadogConfig{APIKey: "key", Site: "datadoghq.eu"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dl.Close()
+	if endpoint := dl.(*httpBatchLane).sender.cfg.endpoint; endpoint != "https://http-intake.logs.datadoghq.eu/api/v2/logs" {
+		t.Errorf("unexpected endpoint %s", endpoint)
+	}
+}
+
+func TestDatadogLog(t *testing.T) {
+	cfg := DatadogConfig{Source: "go"}
+	rec := Record{Time: time.UnixMilli(1700000000123), LaneId: "lane", Level: LogLevelStack, Fields: map[string]string{"status": "overridden"}, Stack: []string{"a", "b"}}
+
+	var entry map[string]any
+	if err := json.Unmarshal(encodeDatadogLog(&rec, &cfg, ""), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry["timestamp"] != 1700000000123.0 || entry["status"] != "info" || entry["ddtags"] != nil {
+		t.Errorf("unexpected entry %v", entry)
+	}
+	if stack, _ := entry["error"].(map[string]any); stack["stack"] != "a\nb" {
+		t.Errorf("unexpected error attribute %v", entry["error"])
+	}
+}
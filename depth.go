@@ -0,0 +1,57 @@
+package lane
+
+import (
+	"slices"
+	"strings"
+)
+
+// Limits the depth of the Derive() chains of a log lane type, such as to
+// contain a bug that derives a lane from the previous one in a loop. When
+// a lane would be derived more than [depth] levels below the root, an ERROR
+// with the derivation chain is logged, once for the lane tree. Derive() on a
+// lane at the limit that was itself made by Derive() then makes a sibling of
+// the lane instead of a child, which has the same settings and context, so
+// the chain stops growing and the lanes it replaces can be collected. Other
+// derivations, which have contexts of their own, still nest. Other lane
+// types ignore it.
+func WithMaxDepth(depth int) LaneOption {
+	return func(o *laneOptions) {
+		o.maxDepth = depth
+	}
+}
+
+func (ll *logLane) SetMaxDepth(depth int) (prior int) {
+	return int(ll.maxDepth.Swap(int32(depth)))
+}
+
+// Checks if a lane derived from the lane would be deeper than the limit of
+// SetMaxDepth(), reporting the first time that happens in the lane tree
+func (ll *logLane) exceedsMaxDepth() bool {
+	maxDepth := int(ll.maxDepth.Load())
+	if maxDepth <= 0 || ll.depth < maxDepth {
+		return false
+	}
+	if ll.depthLogged.CompareAndSwap(false, true) {
+		ll.outer.Errorf("derivation depth limit %d exceeded: %s", maxDepth, ll.derivationChain())
+	}
+	return true
+}
+
+// Makes a lane with the settings of the lane and the context of its parent
+func (ll *logLane) deriveSibling() Lane {
+	l, err := deriveLogLaneAs(ll, true, ll.parent, nil, ll.onCreateLane)
+	if err != nil {
+		l.Fatal(err)
+	}
+	return l
+}
+
+// Renders the IDs of the lane's ancestors and the lane, root first
+func (ll *logLane) derivationChain() string {
+	var ids []string
+	for l := ll; l != nil; l = l.parent {
+		ids = append(ids, trimLaneId(l.LaneId()))
+	}
+	slices.Reverse(ids)
+	return strings.Join(ids, " → ")
+}
@@ -0,0 +1,51 @@
+package lane
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMaxDepth(t *testing.T) {
+	tl := NewTestingLane(nil)
+	root := NewLogLane(nil, WithMaxDepth(2), WithTee(tl), WithLevel(LogLevelFatal))
+	root.TrackDescendants(true)
+
+	l := root
+	chain := []Lane{root}
+	for range 100 {
+		l = l.Derive()
+		chain = append(chain, l)
+	}
+
+	if depth := l.(*logLane).depth; depth != 2 {
+		t.Errorf("unexpected depth %d", depth)
+	}
+	if l.Parent() != chain[1] {
+		t.Error("the lane isn't a sibling at the limit")
+	}
+
+	events := tl.Events()
+	expected := "derivation depth limit 2 exceeded: " + trimLaneId(root.LaneId()) + " → " + trimLaneId(chain[1].LaneId()) + " → " + trimLaneId(chain[2].LaneId())
+	if len(events) != 1 || events[0].Level != "ERROR" || events[0].Message != expected {
+		t.Errorf("unexpected events:\n%s", tl.EventsToString())
+	}
+}
+
+func TestMaxDepthNests(t *testing.T) {
+	root := NewLogLane(nil)
+	if prior := root.(LogLane).SetMaxDepth(1); prior != 0 {
+		t.Errorf("unexpected prior depth %d", prior)
+	}
+
+	child, cancel := root.DeriveWithTimeout(time.Minute)
+	defer cancel()
+	grandchild := child.Derive()
+	greatGrandchild := grandchild.Derive()
+
+	if grandchild.Parent() != child || greatGrandchild.Parent() != child || greatGrandchild.Done() != child.Done() {
+		t.Error("unexpected derivation")
+	}
+	if greatGrandchild.Value(ParentLaneIdKey) != child.LaneId() {
+		t.Error("unexpected parent lane ID")
+	}
+}
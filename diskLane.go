@@ -1,50 +1,92 @@
 package lane
 
 import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
 	"log"
 	"os"
-	"syscall"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type (
+	// A lane that logs to a file
+	DiskLane interface {
+		Lane
+
+		// Closes and reopens the log file by name, for use after a tool such as logrotate
+		// renames it. Lanes derived from the same root lane switch to the new file.
+		ReopenFile() error
+
+		// Writes buffered or compressed output to the file.
+		Flush() error
+	}
+
 	diskLane struct {
 		LogLane
-		f *os.File
+		file     *diskFile
+		closed   atomic.Bool
+		alerting atomic.Bool // logging a disk space alert
+	}
+
+	// Log file shared by a disk lane and its derivations. The file is closed
+	// when the last of the lanes is closed.
+	diskFile struct {
+		mu    sync.Mutex
+		path  string
+		f     *os.File
+		gz    *gzip.Writer  // set when compressed
+		w     *bufio.Writer // set when buffered
+		out   io.Writer     // the first of w, gz or f
+		refs  int
+		done  chan struct{} // stops the periodic flush
+		space *diskSpace    // set when the free space is monitored
 	}
 )
 
-func NewDiskLane(ctx OptionalContext, logFile string) (l Lane, err error) {
+var errDiskFileClosed = errors.New("disk lane file is closed")
+
+// Makes a lane that appends to [logFile]. Use a type assertion to DiskLane
+// for ReopenFile().
+func NewDiskLane(ctx OptionalContext, logFile string, opts ...LaneOption) (l Lane, err error) {
+	lo := applyLaneOptions(opts)
 
 	createFn := func(parentLane Lane) (newLane Lane, ll LogLane, writer *log.Logger, err error) {
-		newLane, ll, writer, err = createDiskLane(logFile, parentLane)
+		newLane, ll, writer, err = createDiskLane(logFile, parentLane, lo)
 		return
 	}
 
-	return NewEmbeddedLogLane(createFn, ctx)
+	return NewEmbeddedLogLane(createFn, ctx, opts...)
+}
+
+// Makes a disk lane that writes gzip compressed output to [logFile]. The
+// compressed data is written to the file every second (or the interval of
+// the WithWriteBuffer option), and the gzip stream is completed when the
+// last lane is closed. An existing file is appended with a new gzip member,
+// which gzip readers treat as one stream.
+func NewGzipLane(ctx OptionalContext, logFile string, opts ...LaneOption) (l Lane, err error) {
+	return NewDiskLane(ctx, logFile, append(opts, withCompression())...)
 }
 
-func createDiskLane(logFile string, parentLane Lane) (newLane Lane, ll LogLane, writer *log.Logger, err error) {
+func createDiskLane(logFile string, parentLane Lane, lo *laneOptions) (newLane Lane, ll LogLane, writer *log.Logger, err error) {
 	dl := diskLane{}
 	pdl, _ := parentLane.(*diskLane)
 
 	if pdl == nil {
-		var f *os.File
-		f, err = os.OpenFile(logFile, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
-		if err != nil {
+		if dl.file, err = openDiskFile(logFile, lo); err != nil {
 			return
 		}
-
-		dl.f = f
 	} else {
-		var newFd int
-		newFd, err = syscall.Dup(int(pdl.f.Fd()))
-		if err != nil {
+		if err = pdl.file.acquire(); err != nil {
 			return
 		}
-		f2 := os.NewFile(uintptr(newFd), pdl.f.Name())
-		dl.f = f2
+		dl.file = pdl.file
 	}
-	writer = log.New(dl.f, "", 0)
+	writer = log.New(dl.file, "", 0)
 
 	ll = AllocEmbeddedLogLane()
 	dl.LogLane = ll
@@ -52,9 +94,191 @@ func createDiskLane(logFile string, parentLane Lane) (newLane Lane, ll LogLane,
 	return
 }
 
-func (dl *diskLane) Close() {
-	if dl.f != nil {
-		dl.f.Close()
+// Releases the lane's reference to the log file, closing the file if no
+// other lane derived from the same root is using it. Buffered output is
+// written either way.
+func (dl *diskLane) CloseWithContext(ctx context.Context) (err error) {
+	err = dl.LogLane.CloseWithContext(ctx)
+	if !dl.closed.Swap(true) {
+		err = errors.Join(err, dl.file.release())
+	}
+	return
+}
+
+func (dl *diskLane) ReopenFile() error {
+	return dl.file.reopen()
+}
+
+func (dl *diskLane) Flush() error {
+	return dl.file.Flush()
+}
+
+func openDiskFile(path string, lo *laneOptions) (df *diskFile, err error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return
+	}
+
+	df = &diskFile{path: path, refs: 1}
+	if lo.compress {
+		df.gz = gzip.NewWriter(f)
+	}
+	if lo.bufferSize > 0 {
+		df.w = bufio.NewWriterSize(f, lo.bufferSize)
+	}
+	df.attach(f)
+
+	if df.gz != nil || df.w != nil {
+		flushInterval := lo.flushInterval
+		if flushInterval <= 0 {
+			flushInterval = time.Second
+		}
+		df.done = make(chan struct{})
+		go df.flushPeriodically(flushInterval)
+	}
+
+	if lo.diskSpace != nil {
+		df.space = startDiskSpaceGuard(path, *lo.diskSpace)
+	}
+	return
+}
+
+// Directs the output chain to [f]
+func (df *diskFile) attach(f *os.File) {
+	df.f = f
+	df.out = f
+	if df.gz != nil {
+		df.gz.Reset(df.out)
+		df.out = df.gz
+	}
+	if df.w != nil {
+		df.w.Reset(df.out)
+		df.out = df.w
+	}
+}
+
+func (df *diskFile) flushPeriodically(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-df.done:
+			return
+		case <-ticker.C:
+			df.Flush()
+		}
+	}
+}
+
+func (df *diskFile) Write(p []byte) (n int, err error) {
+	df.mu.Lock()
+	defer df.mu.Unlock()
+
+	if df.f == nil {
+		return 0, errDiskFileClosed
+	}
+	return df.out.Write(p)
+}
+
+// Writes the buffered output to the file
+func (df *diskFile) Flush() error {
+	df.mu.Lock()
+	defer df.mu.Unlock()
+	return df.flushLocked()
+}
+
+func (df *diskFile) flushLocked() (err error) {
+	if df.f == nil {
+		return
+	}
+	if df.w != nil {
+		err = df.w.Flush()
+	}
+	if df.gz != nil {
+		err = errors.Join(err, df.gz.Flush())
+	}
+	return
+}
+
+// Flushes and closes the file, completing the gzip stream
+func (df *diskFile) closeLocked() (err error) {
+	err = df.flushLocked()
+	if df.gz != nil {
+		err = errors.Join(err, df.gz.Close())
+	}
+	err = errors.Join(err, df.f.Close())
+	df.f = nil
+	return
+}
+
+// Adds a lane's reference to the file
+func (df *diskFile) acquire() error {
+	df.mu.Lock()
+	defer df.mu.Unlock()
+
+	if df.f == nil {
+		return errDiskFileClosed
+	}
+	df.refs++
+	return nil
+}
+
+// Removes a lane's reference to the file, closing it after the last one
+func (df *diskFile) release() (err error) {
+	df.mu.Lock()
+	defer df.mu.Unlock()
+
+	df.refs--
+	if df.refs > 0 || df.f == nil {
+		return df.flushLocked()
+	}
+
+	err = df.closeLocked()
+	if df.done != nil {
+		close(df.done)
+	}
+	if df.space != nil {
+		df.space.stop()
+	}
+	return
+}
+
+func (df *diskFile) reopen() (err error) {
+	df.mu.Lock()
+	defer df.mu.Unlock()
+
+	if df.f == nil {
+		return errDiskFileClosed
+	}
+
+	f, err := os.OpenFile(df.path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		// keep writing to the prior file
+		return
+	}
+	err = df.closeLocked()
+	df.attach(f)
+	return
+}
+
+func (dl *diskLane) admits(level LaneLogLevel) bool {
+	if dl.file.space == nil {
+		return true
+	}
+	return dl.file.space.admits(dl, level)
+}
+
+func (dl *diskLane) lengthConstraint(level LaneLogLevel) int {
+	if lc, ok := dl.LogLane.(lengthConstrainer); ok {
+		return lc.lengthConstraint(level)
+	}
+	return 0
+}
+
+func (dl *diskLane) treeInfo() laneTreeInfo {
+	if tr, ok := dl.LogLane.(treeReporter); ok {
+		return tr.treeInfo()
 	}
-	dl.f = nil
+	return laneTreeInfo{}
 }
@@ -0,0 +1,137 @@
+package lane
+
+import (
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// Settings of the free space monitor of a disk lane
+	DiskSpaceGuard struct {
+		// Free bytes below which only WARN and above are written, or 0 for no
+		// threshold.
+		LowBytes uint64
+
+		// Free bytes below which nothing is written, or 0 for no threshold.
+		CriticalBytes uint64
+
+		// How often the free space is checked, or 0 for every 10 seconds.
+		CheckInterval time.Duration
+	}
+
+	diskSpaceState int32
+
+	// Free space monitor shared by a disk lane and its derivations
+	diskSpace struct {
+		cfg     DiskSpaceGuard
+		dir     string
+		free    atomic.Uint64
+		state   atomic.Int32
+		alerted atomic.Int32 // the state most recently logged
+		done    chan struct{}
+	}
+
+	// Implemented by a lane type embedding a log lane that can suppress output
+	// regardless of the log level
+	levelGate interface {
+		admits(level LaneLogLevel) bool
+	}
+)
+
+const (
+	diskSpaceOk diskSpaceState = iota
+	diskSpaceLow
+	diskSpaceCritical
+)
+
+const defaultDiskSpaceCheckInterval = 10 * time.Second
+
+// provides the free bytes of the filesystem holding a directory; replaced by tests
+var diskFreeSpace = freeSpace
+
+// Monitors the free space of the filesystem holding [path] until stopped
+func startDiskSpaceGuard(path string, cfg DiskSpaceGuard) *diskSpace {
+	ds := &diskSpace{cfg: cfg, dir: filepath.Dir(path), done: make(chan struct{})}
+	ds.check()
+
+	interval := cfg.CheckInterval
+	if interval <= 0 {
+		interval = defaultDiskSpaceCheckInterval
+	}
+	go ds.monitor(interval)
+	return ds
+}
+
+func (ds *diskSpace) monitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ds.done:
+			return
+		case <-ticker.C:
+			ds.check()
+		}
+	}
+}
+
+// Measures the free space and updates the state. The state is unchanged if
+// the free space can't be measured, such as on an unsupported platform.
+func (ds *diskSpace) check() {
+	free, err := diskFreeSpace(ds.dir)
+	if err != nil {
+		return
+	}
+	ds.free.Store(free)
+
+	state := diskSpaceOk
+	if free < ds.cfg.CriticalBytes {
+		state = diskSpaceCritical
+	} else if free < ds.cfg.LowBytes {
+		state = diskSpaceLow
+	}
+	ds.state.Store(int32(state))
+}
+
+func (ds *diskSpace) stop() {
+	close(ds.done)
+}
+
+// Checks if a message at [level] can be written, and logs the change of the
+// free space state to [dl] the first time it is observed
+func (ds *diskSpace) admits(dl *diskLane, level LaneLogLevel) bool {
+	if dl.alerting.Load() {
+		return true
+	}
+
+	state := diskSpaceState(ds.state.Load())
+	if prior := ds.alerted.Load(); prior != int32(state) && ds.alerted.CompareAndSwap(prior, int32(state)) {
+		ds.alert(dl, state)
+	}
+
+	switch state {
+	case diskSpaceLow:
+		return level >= LogLevelWarn
+	case diskSpaceCritical:
+		return false
+	}
+	return true
+}
+
+// Logs the free space state, bypassing the guard
+func (ds *diskSpace) alert(dl *diskLane, state diskSpaceState) {
+	dl.alerting.Store(true)
+	defer dl.alerting.Store(false)
+
+	free := ds.free.Load()
+	switch state {
+	case diskSpaceLow:
+		dl.Warnf("disk space low: %d bytes free for %s, writing only WARN and above", free, ds.dir)
+	case diskSpaceCritical:
+		dl.Errorf("disk space critical: %d bytes free for %s, writing stopped", free, ds.dir)
+	default:
+		dl.Infof("disk space recovered: %d bytes free for %s, writing resumed", free, ds.dir)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !(linux || darwin || freebsd)
+
+package lane
+
+import "errors"
+
+// The free space isn't measured on this platform, leaving the disk space
+// guard inactive
+func freeSpace(dir string) (uint64, error) {
+	return 0, errors.ErrUnsupported
+}
@@ -0,0 +1,97 @@
+package lane
+
+import (
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDiskSpaceGuard(t *testing.T) {
+	skipInRelease(t)
+
+	var free atomic.Uint64
+	free.Store(5000)
+	diskFreeSpace = func(dir string) (uint64, error) { return free.Load(), nil }
+	defer func() { diskFreeSpace = freeSpace }()
+
+	path := t.TempDir() + "/guarded.log"
+	l, err := NewDiskLane(nil, path, WithDiskSpaceGuard(DiskSpaceGuard{LowBytes: 1000, CriticalBytes: 100, CheckInterval: time.Hour}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	space := l.(*diskLane).file.space
+	dl := l.Derive()
+
+	dl.Info("plenty")
+
+	free.Store(500)
+	space.check()
+	dl.Info("dropped info")
+	dl.Warn("low warning")
+
+	free.Store(50)
+	space.check()
+	dl.Error("dropped error")
+	l.Error("dropped root error")
+
+	free.Store(5000)
+	space.check()
+	dl.Trace("resumed")
+
+	dl.Close()
+	l.Close()
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(string(raw)), "\n") {
+		// drop the timestamp and lane ID
+		lines = append(lines, line[strings.Index(line, "} ")+2:])
+	}
+
+	dir := space.dir
+	expected := []string{
+		"plenty",
+		"disk space low: 500 bytes free for " + dir + ", writing only WARN and above",
+		"low warning",
+		"disk space critical: 50 bytes free for " + dir + ", writing stopped",
+		"disk space recovered: 5000 bytes free for " + dir + ", writing resumed",
+		"resumed",
+	}
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected log:\n%s", raw)
+	}
+}
+
+func TestDiskSpaceGuardMonitors(t *testing.T) {
+	var checks atomic.Int32
+	diskFreeSpace = func(dir string) (uint64, error) {
+		checks.Add(1)
+		return 0, nil
+	}
+	defer func() { diskFreeSpace = freeSpace }()
+
+	l, err := NewDiskLane(nil, t.TempDir()+"/monitored.log", WithDiskSpaceGuard(DiskSpaceGuard{CriticalBytes: 1, CheckInterval: time.Millisecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	deadline := time.Now().Add(time.Minute)
+	for checks.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if checks.Load() < 3 {
+		t.Error("the free space wasn't checked periodically")
+	}
+}
+
+func TestFreeSpace(t *testing.T) {
+	if free, err := freeSpace(t.TempDir()); err == nil && free == 0 {
+		t.Error("no free space measured")
+	}
+}
@@ -0,0 +1,107 @@
+package lane
+
+import (
+	"fmt"
+	"strings"
+)
+
+func (tl *testingLane) VerifyEventsT(t TestingT, eventList []*LaneEvent) bool {
+	t.Helper()
+	return tl.verifyEventsT(t, eventList, false)
+}
+
+func (tl *testingLane) VerifyEventTextT(t TestingT, eventText string) bool {
+	t.Helper()
+	return tl.verifyEventsT(t, parseEventText(eventText), true)
+}
+
+// Worker for VerifyEventsT() and VerifyEventTextT(), which compares the IDs
+// that a descriptor sets when [withIds] is true
+func (tl *testingLane) verifyEventsT(t TestingT, eventList []*LaneEvent, withIds bool) bool {
+	t.Helper()
+
+	if tl.verifyEvents(eventList, withIds) {
+		return true
+	}
+
+	events := tl.Events()
+	captured := make([]*LaneEvent, 0, len(events))
+	for i := range events {
+		captured = append(captured, &events[i])
+	}
+
+	// the IDs are shown when the expected events check them
+	showIds := false
+	for _, e := range eventList {
+		if e.Id != "" || e.JourneyId != "" {
+			showIds = withIds
+		}
+	}
+
+	index := 0
+	for index < len(eventList) && index < len(captured) && eventList[index].matches(captured[index], withIds) {
+		index++
+	}
+	t.Errorf("events differ at index %d (expected %d events, captured %d):\n%s",
+		index, len(eventList), len(captured), diffEvents(eventList, captured, withIds, showIds))
+	return false
+}
+
+// Renders an event in the VerifyEventText() form, escaping tabs and line
+// breaks in the message
+func eventLine(e *LaneEvent, withIds bool) string {
+	message := strings.ReplaceAll(e.Message, "\t", "\\t")
+	message = strings.ReplaceAll(message, "\n", "\\n")
+	if !withIds {
+		return e.Level + "\t" + message
+	}
+
+	journeyId, laneId := e.JourneyId, e.Id
+	if journeyId == "" {
+		journeyId = "*"
+	}
+	if laneId == "" {
+		laneId = "*"
+	}
+	return e.Level + "\t{" + journeyId + ":" + laneId + "}\t" + message
+}
+
+// Makes a unified diff of the events, without hunk headers, that takes the
+// expected events to the captured events, comparing the IDs with [withIds]
+// and showing them with [showIds]
+func diffEvents(expected, captured []*LaneEvent, withIds, showIds bool) string {
+	// lcs[i][j] is the length of the longest common subsequence of
+	// expected[i:] and captured[j:]
+	lcs := make([][]int, len(expected)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(captured)+1)
+	}
+	for i := len(expected) - 1; i >= 0; i-- {
+		for j := len(captured) - 1; j >= 0; j-- {
+			if expected[i].matches(captured[j], withIds) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("--- expected\n+++ captured\n")
+	i, j := 0, 0
+	for i < len(expected) || j < len(captured) {
+		switch {
+		case i < len(expected) && j < len(captured) && expected[i].matches(captured[j], withIds):
+			fmt.Fprintf(&sb, " %s\n", eventLine(captured[j], showIds))
+			i++
+			j++
+		case j < len(captured) && (i == len(expected) || lcs[i][j+1] > lcs[i+1][j]):
+			fmt.Fprintf(&sb, "+%s\n", eventLine(captured[j], showIds))
+			j++
+		default:
+			fmt.Fprintf(&sb, "-%s\n", eventLine(expected[i], showIds))
+			i++
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,87 @@
+package lane
+
+import "testing"
+
+func TestVerifyEventTextT(t *testing.T) {
+	tl := NewTestingLane(nil)
+	tl.Info("a")
+	tl.Warn("b")
+	tl.Error("line\tbreak\nhere")
+
+	if !tl.VerifyEventTextT(t, "INFO\ta\nWARN\tb\nERROR\tline\\tbreak\\nhere") {
+		t.Error("expected a match")
+	}
+
+	var tfr testFailureRecorder
+	if tl.VerifyEventTextT(&tfr, "INFO\ta\nWARN\tc\nERROR\tline\\tbreak\\nhere\nINFO\td") {
+		t.Fatal("expected a mismatch")
+	}
+	expected := "events differ at index 1 (expected 4 events, captured 3):\n" +
+		"--- expected\n+++ captured\n" +
+		" INFO\ta\n" +
+		"-WARN\tc\n" +
+		"+WARN\tb\n" +
+		" ERROR\tline\\tbreak\\nhere\n" +
+		"-INFO\td\n"
+	if len(tfr.failures) != 1 || tfr.failures[0] != expected {
+		t.Errorf("unexpected failures %q", tfr.failures)
+	}
+}
+
+func TestVerifyEventsTEmpty(t *testing.T) {
+	tl := NewTestingLane(nil)
+	tl.Info("unexpected")
+
+	var tfr testFailureRecorder
+	if tl.VerifyEventsT(&tfr, nil) || len(tfr.failures) != 1 || tfr.failures[0] != "events differ at index 0 (expected 0 events, captured 1):\n--- expected\n+++ captured\n+INFO\tunexpected\n" {
+		t.Errorf("unexpected failures %q", tfr.failures)
+	}
+}
+
+func TestVerifyEventTextIds(t *testing.T) {
+	ll := NewLogLane(nil)
+	ll.SetJourneyId("trip")
+	tl := NewTestingLane(nil)
+	ll.AddTee(tl)
+
+	child := ll.Derive()
+	ll.Info("from the parent")
+	child.Warn("from the child")
+
+	expected := "INFO\t{trip:" + ll.LaneId() + "}\tfrom the parent\n" +
+		"WARN\t{" + child.LaneId() + "}\tfrom the child"
+	if !tl.VerifyEventTextT(t, expected) {
+		return
+	}
+	if !tl.VerifyEventText("INFO\t{trip:*}\tfrom the parent\nWARN\tfrom the child") {
+		t.Error("expected a journey match")
+	}
+	if tl.VerifyEventText("INFO\t{" + child.LaneId() + "}\tfrom the parent\nWARN\tfrom the child") {
+		t.Error("the lane ID wasn't checked")
+	}
+	if !tl.FindEventText("WARN\t{trip:" + child.LaneId() + "}\tfrom the child") {
+		t.Error("expected to find the child's event")
+	}
+
+	var tfr testFailureRecorder
+	tl.VerifyEventTextT(&tfr, "INFO\t{other:*}\tfrom the parent\nWARN\tfrom the child")
+	expectedFailure := "events differ at index 0 (expected 2 events, captured 2):\n" +
+		"--- expected\n+++ captured\n" +
+		"-INFO\t{other:*}\tfrom the parent\n" +
+		"+INFO\t{trip:" + ll.LaneId() + "}\tfrom the parent\n" +
+		" WARN\t{trip:" + child.LaneId() + "}\tfrom the child\n"
+	if len(tfr.failures) != 1 || tfr.failures[0] != expectedFailure {
+		t.Errorf("unexpected failures %q", tfr.failures)
+	}
+}
+
+func TestVerifyEventsIgnoresIds(t *testing.T) {
+	tl := NewTestingLane(nil)
+	tl.Info("hello")
+
+	// the struct form compares the level and message only, as it always has
+	events := []*LaneEvent{{Id: "other", JourneyId: "other", Level: "INFO", Message: "hello"}}
+	if !tl.VerifyEvents(events) || !tl.FindEvents(events) || !tl.VerifyEventsT(t, events) {
+		t.Error("the IDs of the expected events were compared")
+	}
+}
@@ -0,0 +1,75 @@
+package lane
+
+import (
+	"io"
+	"os/exec"
+	"path/filepath"
+)
+
+type (
+	// A command run with the output correlated to a lane, from Command()
+	Cmd struct {
+		*exec.Cmd
+		l      Lane
+		stdout io.WriteCloser
+		stderr io.WriteCloser
+	}
+)
+
+// Prepares to run the program [name] with [args], as exec.CommandContext()
+// does with the lane's context, so that the lane's cancellation kills the
+// process. The output is logged line by line to a lane derived from [l] with
+// the program's base name as its category, the standard output at INFO and
+// the standard error at ERROR. The command line is logged when the command
+// starts, and the exit code when it ends.
+//
+// Use Run(), or Start() and Wait(), of the returned command, which log the
+// start and end. The command's Output() and CombinedOutput() aren't
+// supported, as the output goes to the lane.
+func Command(l Lane, name string, args ...string) *Cmd {
+	cl := l.Category(filepath.Base(name))
+	c := &Cmd{
+		Cmd:    exec.CommandContext(l, name, args...),
+		l:      cl,
+		stdout: cl.WriterAt(LogLevelInfo),
+		stderr: cl.WriterAt(LogLevelError),
+	}
+	c.Stdout = c.stdout
+	c.Stderr = c.stderr
+	return c
+}
+
+// Starts the command, logging its command line
+func (c *Cmd) Start() error {
+	if err := c.Cmd.Start(); err != nil {
+		c.l.Errorf("can't run %s: %v", c.String(), err)
+		return err
+	}
+	c.l.Infof("running %s", c.String())
+	return nil
+}
+
+// Waits for the command to end, logging the rest of its output and the exit
+// code
+func (c *Cmd) Wait() error {
+	err := c.Cmd.Wait()
+	c.stdout.Close()
+	c.stderr.Close()
+
+	if err == nil {
+		c.l.Infof("%s exited with code 0", c.Path)
+	} else if c.ProcessState != nil {
+		c.l.Errorf("%s exited with code %d: %v", c.Path, c.ProcessState.ExitCode(), err)
+	} else {
+		c.l.Errorf("%s failed: %v", c.Path, err)
+	}
+	return err
+}
+
+// Starts the command and waits for it to end
+func (c *Cmd) Run() error {
+	if err := c.Start(); err != nil {
+		return err
+	}
+	return c.Wait()
+}
@@ -0,0 +1,80 @@
+package lane
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func testShell(t *testing.T) string {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no shell")
+	}
+	return sh
+}
+
+func TestCommand(t *testing.T) {
+	sh := testShell(t)
+	tl := NewTestingLane(nil)
+	tl.WantDescendantEvents(true)
+
+	if err := Command(tl, sh, "-c", "echo out; echo err 1>&2").Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	events := tl.Events()
+	if len(events) != 4 || events[0].Category != "sh" {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+	if !tl.FindEventText("INFO\tout") || !tl.FindEventText("ERROR\terr") || !tl.FindEventText("INFO\t"+sh+" exited with code 0") {
+		t.Errorf("unexpected events:\n%s", tl.EventsToString())
+	}
+}
+
+func TestCommandExitCode(t *testing.T) {
+	sh := testShell(t)
+	tl := NewTestingLane(nil)
+	tl.WantDescendantEvents(true)
+
+	err := Command(tl, sh, "-c", "exit 3").Run()
+	if err == nil {
+		t.Fatal("expected an exit error")
+	}
+	if !tl.Contains("exited with code 3") {
+		t.Errorf("unexpected events:\n%s", tl.EventsToString())
+	}
+}
+
+func TestCommandCanceled(t *testing.T) {
+	sh := testShell(t)
+	tl := NewTestingLane(nil)
+	tl.WantDescendantEvents(true)
+	l, cancel := tl.DeriveWithCancel()
+
+	cmd := Command(l, sh, "-c", "exec sleep 10")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	cancel()
+	if err := cmd.Wait(); err == nil || time.Since(start) > 5*time.Second {
+		t.Errorf("expected the process to be killed, err %v", err)
+	}
+	if l.Err() != context.Canceled || !tl.Contains("exited with code -1") {
+		t.Errorf("unexpected events:\n%s", tl.EventsToString())
+	}
+}
+
+func TestCommandNotFound(t *testing.T) {
+	tl := NewTestingLane(nil)
+	tl.WantDescendantEvents(true)
+
+	if err := Command(tl, "/nonexistent/program").Run(); err == nil {
+		t.Fatal("expected an error")
+	}
+	if !tl.Contains("can't run /nonexistent/program") {
+		t.Errorf("unexpected events:\n%s", tl.EventsToString())
+	}
+}
@@ -0,0 +1,103 @@
+package lane
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+)
+
+type (
+	// A lane that passes only the messages matching a filter expression to
+	// the lane it wraps
+	exprFilterLane struct {
+		LogLane
+		wrapped Lane
+		expr    filterExpr
+	}
+)
+
+// Makes a lane that logs to [wrapped] only the messages that match [expr],
+// such as `level >= warn && msg =~ "timeout" && meta.tenant == "acme"`. The
+// expression is compiled once, so filters can come from a configuration file.
+//
+// A comparison has a field on the left: level, msg, lane, journey, category,
+// or meta.<key> for a metadata value of the filter lane. The level compares
+// with a level name using ==, !=, <, <=, > or >=. The other fields compare with a
+// string using == or !=, or with a regular expression using =~ or !~.
+// Strings are double quoted with Go escapes, or back quoted; a value without
+// spaces or operator characters doesn't need quotes. Comparisons combine with
+// &&, || and !, and group with parentheses.
+//
+// The wrapped lane receives the messages with the filter lane's IDs, as a tee
+// would. A stack trace is matched at LogLevelStack and passed on at
+// LogLevelTrace, one message per line. The tees of the filter lane receive
+// all messages. Lanes derived from the filter lane wrap lanes derived from
+// [wrapped], and closing the filter lane closes the lane it wraps. The filter
+// lane's context is derived from [wrapped].
+func NewExprFilterLane(wrapped Lane, expr string, opts ...LaneOption) (l Lane, err error) {
+	filter, err := compileFilterExpr(expr)
+	if err != nil {
+		return
+	}
+
+	createFn := func(parentLane Lane) (newLane Lane, ll LogLane, writer *log.Logger, err error) {
+		target := wrapped
+		if parent, ok := parentLane.(*exprFilterLane); ok {
+			target = parent.wrapped.Derive()
+		}
+		newLane, ll, writer = createExprFilterLane(target, filter)
+		return
+	}
+
+	return NewEmbeddedLogLane(createFn, wrapped, opts...)
+}
+
+func createExprFilterLane(wrapped Lane, expr filterExpr) (newLane Lane, ll LogLane, writer *log.Logger) {
+	efl := exprFilterLane{wrapped: wrapped, expr: expr}
+	ll = AllocEmbeddedLogLane()
+	efl.LogLane = ll
+	newLane = &efl
+	writer = log.New(io.Discard, "", 0)
+	return
+}
+
+func (efl *exprFilterLane) receiveRecord(rec *Record) {
+	if efl.expr(rec) {
+		replayEvents([]RingEvent{*rec}, efl.wrapped)
+	}
+}
+
+func (efl *exprFilterLane) SetMetadata(key, val string) {
+	efl.LogLane.SetMetadata(key, val)
+	efl.wrapped.SetMetadata(key, val)
+}
+
+func (efl *exprFilterLane) DeleteMetadata(key string) {
+	efl.LogLane.DeleteMetadata(key)
+	efl.wrapped.DeleteMetadata(key)
+}
+
+func (efl *exprFilterLane) SetMetadataInheritance(mode MetadataInheritance) (prior MetadataInheritance) {
+	prior = efl.LogLane.SetMetadataInheritance(mode)
+	efl.wrapped.SetMetadataInheritance(mode)
+	return
+}
+
+func (efl *exprFilterLane) CloseWithContext(ctx context.Context) error {
+	return errors.Join(efl.LogLane.CloseWithContext(ctx), efl.wrapped.CloseWithContext(ctx))
+}
+
+func (efl *exprFilterLane) lengthConstraint(level LaneLogLevel) int {
+	if lc, ok := efl.LogLane.(lengthConstrainer); ok {
+		return lc.lengthConstraint(level)
+	}
+	return 0
+}
+
+func (efl *exprFilterLane) treeInfo() laneTreeInfo {
+	if tr, ok := efl.LogLane.(treeReporter); ok {
+		return tr.treeInfo()
+	}
+	return laneTreeInfo{}
+}
@@ -0,0 +1,130 @@
+package lane
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExprFilterLane(t *testing.T) {
+	tl := NewTestingLane(nil)
+	fl, err := NewExprFilterLane(tl, `level >= warn && msg =~ "timeout" && meta.tenant == "acme"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fl.Warn("timeout before tenant")
+	fl.SetMetadata("tenant", "acme")
+	fl.Info("info timeout")
+	fl.Warn("connect timeout")
+	fl.Errorf("read %s", "timeout")
+	fl.Error("other failure")
+
+	if !tl.VerifyEventText("WARN\tconnect timeout\nERROR\tread timeout") {
+		t.Errorf("unexpected events:\n%s", tl.EventsToString())
+	}
+	for _, e := range tl.Events() {
+		if e.Id != fl.LaneId() {
+			t.Error("expected the filter lane's ID")
+		}
+	}
+	if tl.GetMetadata("tenant") != "acme" {
+		t.Error("the metadata was not passed to the wrapped lane")
+	}
+}
+
+func TestExprFilterLaneDerive(t *testing.T) {
+	skipInRelease(t)
+
+	tl := NewTestingLane(nil)
+	tl.WantDescendantEvents(true)
+	fl, err := NewExprFilterLane(tl, `!(lane == x || journey != "trip") || level == error`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	child := fl.Derive()
+	child.Info("no journey")
+	child.Error("error passes")
+	child.SetJourneyId("trip")
+	child.Debug("on the trip")
+
+	if !tl.VerifyEventText("ERROR\terror passes\nDEBUG\ton the trip") {
+		t.Errorf("unexpected events:\n%s", tl.EventsToString())
+	}
+	if events := tl.Events(); len(events) != 2 || events[0].Id != child.LaneId() {
+		t.Error("expected the derived filter lane's ID")
+	}
+	child.Close()
+	fl.Close()
+}
+
+func TestExprFilterLaneStack(t *testing.T) {
+	tl := NewTestingLane(nil)
+	fl, err := NewExprFilterLane(tl, "level >= stack")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fl.Error("not a stack")
+	fl.LogStack("here")
+	events := tl.Events()
+	if len(events) < 3 || events[0].Message != "here" || events[0].Level != "TRACE" {
+		t.Errorf("unexpected events:\n%s", tl.EventsToString())
+	}
+}
+
+func TestCompileFilterExpr(t *testing.T) {
+	rec := Record{Level: LogLevelInfo, Message: "disk 90% full", LaneId: "abc", Fields: map[string]string{"region": "us-east-1"}}
+
+	cases := []struct {
+		expr  string
+		match bool
+	}{
+		{"level == info", true},
+		{"level < INFO", false},
+		{"level <= debug || level > warn", false},
+		{`msg !~ "\\d+%"`, false},
+		{"msg =~ `^disk`", true},
+		{"meta.region == us-east-1 && lane != xyz", true},
+		{`meta.missing == ""`, true},
+		{"!(level >= warn) && (msg =~ full)", true},
+	}
+	for _, c := range cases {
+		expr, err := compileFilterExpr(c.expr)
+		if err != nil {
+			t.Errorf("%s: %v", c.expr, err)
+			continue
+		}
+		if expr(&rec) != c.match {
+			t.Errorf("%s: expected %v", c.expr, c.match)
+		}
+	}
+}
+
+func TestCompileFilterExprErrors(t *testing.T) {
+	exprs := []string{
+		"",
+		"level >= loud",
+		"level =~ warn",
+		"msg < x",
+		"size == 1",
+		"meta. == x",
+		`msg == "open`,
+		"msg =~ `(`",
+		"(level == info",
+		"level == info)",
+		"level info",
+		"msg = x",
+		"level == info &&",
+		"meta.missing == ''",
+	}
+	for _, expr := range exprs {
+		if _, err := compileFilterExpr(expr); !errors.Is(err, errFilterSyntax) {
+			t.Errorf("%q: expected a syntax error, got %v", expr, err)
+		}
+	}
+
+	if _, err := NewExprFilterLane(NewNullLane(nil), "level >"); !errors.Is(err, errFilterSyntax) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,302 @@
+package lane
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type (
+	// A compiled filter expression
+	filterExpr func(rec *Record) bool
+
+	filterTokenKind int
+
+	filterToken struct {
+		kind filterTokenKind
+		text string
+		pos  int
+	}
+
+	filterParser struct {
+		tokens []filterToken
+		next   int
+	}
+)
+
+const (
+	filterTokenEnd filterTokenKind = iota
+	filterTokenWord
+	filterTokenString
+	filterTokenOp
+	filterTokenNot
+	filterTokenAnd
+	filterTokenOr
+	filterTokenOpen
+	filterTokenClose
+)
+
+var errFilterSyntax = errors.New("invalid filter expression")
+
+// Compiles a filter expression, with the grammar described by
+// NewExprFilterLane()
+func compileFilterExpr(text string) (expr filterExpr, err error) {
+	tokens, err := scanFilterExpr(text)
+	if err != nil {
+		return
+	}
+
+	p := filterParser{tokens: tokens}
+	if expr, err = p.parseOr(); err != nil {
+		return
+	}
+	if tok := p.peek(); tok.kind != filterTokenEnd {
+		err = p.errorAt(tok, "unexpected %q", tok.text)
+	}
+	return
+}
+
+func scanFilterExpr(text string) (tokens []filterToken, err error) {
+	pos := 0
+	for pos < len(text) {
+		c := text[pos]
+		start := pos
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			pos++
+			continue
+
+		case c == '(' || c == ')':
+			kind := filterTokenOpen
+			if c == ')' {
+				kind = filterTokenClose
+			}
+			tokens = append(tokens, filterToken{kind: kind, text: text[pos : pos+1], pos: pos})
+			pos++
+
+		case strings.HasPrefix(text[pos:], "&&"):
+			tokens = append(tokens, filterToken{kind: filterTokenAnd, text: "&&", pos: pos})
+			pos += 2
+
+		case strings.HasPrefix(text[pos:], "||"):
+			tokens = append(tokens, filterToken{kind: filterTokenOr, text: "||", pos: pos})
+			pos += 2
+
+		case c == '=' || c == '!' || c == '<' || c == '>':
+			op := text[pos : pos+1]
+			if pos+1 < len(text) && (text[pos+1] == '=' || text[pos+1] == '~') {
+				op = text[pos : pos+2]
+			}
+			switch op {
+			case "!":
+				tokens = append(tokens, filterToken{kind: filterTokenNot, text: op, pos: pos})
+			case "==", "!=", "=~", "!~", "<", "<=", ">", ">=":
+				tokens = append(tokens, filterToken{kind: filterTokenOp, text: op, pos: pos})
+			default:
+				err = fmt.Errorf("%w: unknown operator %q at offset %d", errFilterSyntax, op, pos)
+				return
+			}
+			pos += len(op)
+
+		case c == '"' || c == '`':
+			end := pos + 1
+			for end < len(text) && text[end] != c {
+				if c == '"' && text[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(text) {
+				err = fmt.Errorf("%w: unterminated string at offset %d", errFilterSyntax, start)
+				return
+			}
+			var value string
+			if value, err = strconv.Unquote(text[pos : end+1]); err != nil {
+				err = fmt.Errorf("%w: invalid string at offset %d", errFilterSyntax, start)
+				return
+			}
+			tokens = append(tokens, filterToken{kind: filterTokenString, text: value, pos: start})
+			pos = end + 1
+
+		default:
+			end := pos
+			for end < len(text) && isFilterWordChar(text[end]) {
+				end++
+			}
+			if end == pos {
+				err = fmt.Errorf("%w: unexpected %q at offset %d", errFilterSyntax, c, pos)
+				return
+			}
+			tokens = append(tokens, filterToken{kind: filterTokenWord, text: text[pos:end], pos: pos})
+			pos = end
+		}
+	}
+	tokens = append(tokens, filterToken{kind: filterTokenEnd, pos: len(text)})
+	return
+}
+
+func isFilterWordChar(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') ||
+		c == '_' || c == '.' || c == '-' || c == ':' || c == '/'
+}
+
+func (p *filterParser) peek() filterToken {
+	return p.tokens[p.next]
+}
+
+func (p *filterParser) take() filterToken {
+	tok := p.tokens[p.next]
+	if tok.kind != filterTokenEnd {
+		p.next++
+	}
+	return tok
+}
+
+func (p *filterParser) errorAt(tok filterToken, format string, args ...any) error {
+	return fmt.Errorf("%w: %s at offset %d", errFilterSyntax, fmt.Sprintf(format, args...), tok.pos)
+}
+
+func (p *filterParser) parseOr() (expr filterExpr, err error) {
+	if expr, err = p.parseAnd(); err != nil {
+		return
+	}
+	for p.peek().kind == filterTokenOr {
+		p.take()
+		var right filterExpr
+		if right, err = p.parseAnd(); err != nil {
+			return
+		}
+		left := expr
+		expr = func(rec *Record) bool { return left(rec) || right(rec) }
+	}
+	return
+}
+
+func (p *filterParser) parseAnd() (expr filterExpr, err error) {
+	if expr, err = p.parseUnary(); err != nil {
+		return
+	}
+	for p.peek().kind == filterTokenAnd {
+		p.take()
+		var right filterExpr
+		if right, err = p.parseUnary(); err != nil {
+			return
+		}
+		left := expr
+		expr = func(rec *Record) bool { return left(rec) && right(rec) }
+	}
+	return
+}
+
+func (p *filterParser) parseUnary() (expr filterExpr, err error) {
+	tok := p.peek()
+	switch tok.kind {
+	case filterTokenNot:
+		p.take()
+		var operand filterExpr
+		if operand, err = p.parseUnary(); err != nil {
+			return
+		}
+		expr = func(rec *Record) bool { return !operand(rec) }
+
+	case filterTokenOpen:
+		p.take()
+		if expr, err = p.parseOr(); err != nil {
+			return
+		}
+		if end := p.take(); end.kind != filterTokenClose {
+			err = p.errorAt(end, "expected )")
+		}
+
+	default:
+		expr, err = p.parseComparison()
+	}
+	return
+}
+
+func (p *filterParser) parseComparison() (expr filterExpr, err error) {
+	field := p.take()
+	if field.kind != filterTokenWord {
+		err = p.errorAt(field, "expected a field")
+		return
+	}
+	op := p.take()
+	if op.kind != filterTokenOp {
+		err = p.errorAt(op, "expected a comparison after %s", field.text)
+		return
+	}
+	value := p.take()
+	if value.kind != filterTokenWord && value.kind != filterTokenString {
+		err = p.errorAt(value, "expected a value after %s", op.text)
+		return
+	}
+
+	if field.text == "level" {
+		return p.levelComparison(op, value)
+	}
+
+	var get func(rec *Record) string
+	switch field.text {
+	case "msg", "message":
+		get = func(rec *Record) string { return rec.Message }
+	case "lane":
+		get = func(rec *Record) string { return rec.LaneId }
+	case "journey":
+		get = func(rec *Record) string { return rec.JourneyId }
+	case "category":
+		get = func(rec *Record) string { return rec.Category }
+	default:
+		key, found := strings.CutPrefix(field.text, "meta.")
+		if !found || key == "" {
+			err = p.errorAt(field, "unknown field %s", field.text)
+			return
+		}
+		get = func(rec *Record) string { return rec.Fields[key] }
+	}
+
+	switch op.text {
+	case "==":
+		expr = func(rec *Record) bool { return get(rec) == value.text }
+	case "!=":
+		expr = func(rec *Record) bool { return get(rec) != value.text }
+	case "=~", "!~":
+		re, reErr := regexp.Compile(value.text)
+		if reErr != nil {
+			err = p.errorAt(value, "%v", reErr)
+			return
+		}
+		want := op.text == "=~"
+		expr = func(rec *Record) bool { return re.MatchString(get(rec)) == want }
+	default:
+		err = p.errorAt(op, "%s can't compare %s", op.text, field.text)
+	}
+	return
+}
+
+func (p *filterParser) levelComparison(op, value filterToken) (expr filterExpr, err error) {
+	level, found := parseLevelName(value.text)
+	if !found {
+		err = p.errorAt(value, "unknown level %s", value.text)
+		return
+	}
+
+	switch op.text {
+	case "==":
+		expr = func(rec *Record) bool { return rec.Level == level }
+	case "!=":
+		expr = func(rec *Record) bool { return rec.Level != level }
+	case "<":
+		expr = func(rec *Record) bool { return rec.Level < level }
+	case "<=":
+		expr = func(rec *Record) bool { return rec.Level <= level }
+	case ">":
+		expr = func(rec *Record) bool { return rec.Level > level }
+	case ">=":
+		expr = func(rec *Record) bool { return rec.Level >= level }
+	default:
+		err = p.errorAt(op, "%s can't compare level", op.text)
+	}
+	return
+}
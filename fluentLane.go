@@ -0,0 +1,379 @@
+package lane
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+type (
+	// Settings for a lane that sends log events to Fluentd or Fluent Bit
+	FluentConfig struct {
+		Network     string        // "tcp" (the default) or "unix"
+		Addr        string        // defaults to "localhost:24224"
+		Tag         string        // defaults to "lane"
+		AppName     string        // added to each record as "app" when set
+		RequireAck  bool          // wait for the server to acknowledge each chunk
+		AckTimeout  time.Duration // defaults to 5 seconds
+		DialTimeout time.Duration // defaults to 5 seconds
+		BatchSize   int           // maximum records per chunk, defaults to 100
+		QueueSize   int           // records held while sending, defaults to 10000
+		MaxRetries  int           // attempts to send a chunk before dropping it, defaults to 3
+		Backoff     Backoff       // delays between the attempts to send a chunk; MaxRetries sets the attempts
+		Spool       *SpoolConfig  // holds undeliverable records on disk for replay, when set
+	}
+
+	fluentLane struct {
+		LogLane
+		sender *fluentSender
+		owner  bool
+	}
+
+	// Background delivery of records, shared by a fluent lane and its derivations
+	fluentSender struct {
+		cfg      FluentConfig
+		records  chan fluentRecord
+		done     chan struct{}
+		mu       sync.RWMutex
+		closed   bool
+		conn     net.Conn
+		reader   *bufio.Reader
+		spool    *diskSpool
+		counters laneCounters
+	}
+
+	fluentRecord struct {
+		t      time.Time
+		fields map[string]any
+	}
+
+	// An encoded forward mode message, and its ID when an ack is required
+	fluentChunk struct {
+		data []byte
+		id   string
+	}
+)
+
+var ErrFluentAck = errors.New("fluent chunk was not acknowledged")
+
+// Makes a lane that sends its log events with the Fluent forward protocol.
+// Events are queued and sent in the background, so logging does not wait for
+// the network. Close the lane to flush the queue and disconnect.
+func NewFluentLane(ctx OptionalContext, cfg FluentConfig, opts ...LaneOption) Lane {
+	sender := newFluentSender(cfg)
+
+	createFn := func(parentLane Lane) (newLane Lane, ll LogLane, writer *log.Logger, err error) {
+		newLane, ll, writer = createFluentLane(sender)
+		return
+	}
+
+	l, _ := NewEmbeddedLogLane(createFn, ctx, opts...)
+	l.(*fluentLane).owner = true
+	return l
+}
+
+func createFluentLane(sender *fluentSender) (newLane Lane, ll LogLane, writer *log.Logger) {
+	fl := fluentLane{sender: sender}
+	ll = AllocEmbeddedLogLane()
+	fl.LogLane = ll
+	newLane = &fl
+	writer = log.New(io.Discard, "", 0)
+	return
+}
+
+func (fl *fluentLane) receiveRecord(rec *Record) {
+	fields := map[string]any{
+		"level":   levelNames[rec.Level],
+		"lane_id": rec.LaneId,
+		"message": rec.Message,
+	}
+	if rec.JourneyId != "" {
+		fields["journey_id"] = rec.JourneyId
+	}
+	if rec.Caller != "" {
+		fields["caller"] = rec.Caller
+	}
+	if len(rec.Stack) > 0 {
+		stack := make([]any, 0, len(rec.Stack))
+		for _, line := range rec.Stack {
+			stack = append(stack, line)
+		}
+		fields["stack"] = stack
+	}
+	if fl.sender.cfg.AppName != "" {
+		fields["app"] = fl.sender.cfg.AppName
+	}
+
+	fl.sender.enqueue(fluentRecord{t: rec.Time, fields: fields})
+}
+
+// Provides the lane's operational counters, including those of the sender
+func (fl *fluentLane) Stats() (stats LaneStats) {
+	stats = fl.LogLane.Stats()
+	stats.addDelivery(&fl.sender.counters)
+	if fl.sender.spool != nil {
+		stats.Spool = fl.sender.spool.stats()
+	}
+	return
+}
+
+// Flushes the queued records and disconnects, if this lane started the sender.
+// Returns the context error if [ctx] is done before the records are sent.
+func (fl *fluentLane) CloseWithContext(ctx context.Context) (err error) {
+	err = fl.LogLane.CloseWithContext(ctx)
+	if fl.owner {
+		err = errors.Join(err, fl.sender.close(ctx))
+	}
+	return
+}
+
+func (fl *fluentLane) lengthConstraint(level LaneLogLevel) int {
+	if lc, ok := fl.LogLane.(lengthConstrainer); ok {
+		return lc.lengthConstraint(level)
+	}
+	return 0
+}
+
+func (fl *fluentLane) treeInfo() laneTreeInfo {
+	if tr, ok := fl.LogLane.(treeReporter); ok {
+		return tr.treeInfo()
+	}
+	return laneTreeInfo{}
+}
+
+func newFluentSender(cfg FluentConfig) *fluentSender {
+	if cfg.Network == "" {
+		cfg.Network = "tcp"
+	}
+	if cfg.Addr == "" {
+		cfg.Addr = "localhost:24224"
+	}
+	if cfg.Tag == "" {
+		cfg.Tag = "lane"
+	}
+	if cfg.AckTimeout <= 0 {
+		cfg.AckTimeout = 5 * time.Second
+	}
+	if cfg.DialTimeout <= 0 {
+		cfg.DialTimeout = 5 * time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 10000
+	}
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = 3
+	}
+	cfg.Backoff.MaxAttempts = cfg.MaxRetries
+
+	fs := &fluentSender{
+		cfg:     cfg,
+		records: make(chan fluentRecord, cfg.QueueSize),
+		done:    make(chan struct{}),
+	}
+	if cfg.Spool != nil {
+		fs.spool = newDiskSpool(*cfg.Spool)
+	}
+	go fs.run()
+	return fs
+}
+
+func (fs *fluentSender) enqueue(rec fluentRecord) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	if fs.closed {
+		return
+	}
+	select {
+	case fs.records <- rec:
+	default:
+		// the queue is full; logging must not block
+		fs.counters.dropped.Add(1)
+	}
+}
+
+func (fs *fluentSender) close(ctx context.Context) error {
+	fs.mu.Lock()
+	if !fs.closed {
+		fs.closed = true
+		close(fs.records)
+	}
+	fs.mu.Unlock()
+
+	select {
+	case <-fs.done:
+		return nil
+	case <-ctx.Done():
+		// the sender finishes in the background
+		return ctx.Err()
+	}
+}
+
+// Worker that sends the queued records in chunks until the queue is closed
+func (fs *fluentSender) run() {
+	defer close(fs.done)
+	defer fs.disconnect()
+
+	var retry <-chan time.Time
+	if fs.spool != nil {
+		defer fs.spool.close()
+		ticker := time.NewTicker(fs.spool.cfg.RetryInterval)
+		defer ticker.Stop()
+		retry = ticker.C
+	}
+
+	for {
+		var rec fluentRecord
+		var more bool
+		select {
+		case rec, more = <-fs.records:
+		case <-retry:
+			fs.replaySpool()
+			continue
+		}
+		if !more {
+			if fs.spool != nil {
+				// what can't be delivered now stays on disk for the next run
+				fs.replaySpool()
+			}
+			return
+		}
+
+		batch := [][]byte{encodeFluentEntry(rec)}
+	fill:
+		for len(batch) < fs.cfg.BatchSize {
+			select {
+			case rec, more = <-fs.records:
+				if !more {
+					break fill
+				}
+				batch = append(batch, encodeFluentEntry(rec))
+			default:
+				break fill
+			}
+		}
+
+		fs.deliver(batch)
+	}
+}
+
+// Sends a batch of encoded entries, spooling them if they can't be sent
+func (fs *fluentSender) deliver(entries [][]byte) {
+	if fs.spool != nil && fs.spool.pending() && fs.replaySpool() != nil {
+		// keep the order: new entries wait behind the spooled ones
+		fs.spool.append(entries)
+		return
+	}
+
+	if err := fs.sendWithRetry(entries); err != nil {
+		fs.counters.setError(err)
+		if fs.spool != nil {
+			fs.spool.append(entries)
+		} else {
+			// without a spool, the chunk is dropped after the retries are used up
+			fs.counters.dropped.Add(int64(len(entries)))
+		}
+	}
+}
+
+func (fs *fluentSender) sendWithRetry(entries [][]byte) error {
+	chunk := fs.encodeChunk(entries)
+	return fs.cfg.Backoff.Retry(context.Background(), func(attempt int) error {
+		err := fs.send(chunk)
+		if err != nil {
+			fs.disconnect()
+		}
+		return err
+	})
+}
+
+// Sends the spooled entries, making one attempt per chunk
+func (fs *fluentSender) replaySpool() error {
+	return fs.spool.replay(fs.cfg.BatchSize, 0, func(entries [][]byte) error {
+		err := fs.send(fs.encodeChunk(entries))
+		if err != nil {
+			fs.disconnect()
+		}
+		return err
+	})
+}
+
+// Encodes the forward mode entry [time, record]
+func encodeFluentEntry(rec fluentRecord) []byte {
+	return appendMsgpack(nil, []any{fluentEventTime(rec.t), rec.fields})
+}
+
+// Encodes the forward mode message [tag, [entry, ...], {"chunk": id}]
+func (fs *fluentSender) encodeChunk(entries [][]byte) fluentChunk {
+	option := map[string]any{"size": len(entries)}
+	var id string
+	if fs.cfg.RequireAck {
+		raw := make([]byte, 16)
+		rand.Read(raw)
+		id = base64.StdEncoding.EncodeToString(raw)
+		option["chunk"] = id
+	}
+
+	data := appendMsgpack([]byte{0x93}, fs.cfg.Tag) // an array of three
+	data = appendMsgpackLength(data, len(entries), 0x90, 15, 0, 0xdc, 0xdd)
+	for _, entry := range entries {
+		data = append(data, entry...)
+	}
+	data = appendMsgpack(data, option)
+
+	return fluentChunk{data: data, id: id}
+}
+
+func (fs *fluentSender) send(chunk fluentChunk) (err error) {
+	if fs.conn == nil {
+		if fs.conn, err = net.DialTimeout(fs.cfg.Network, fs.cfg.Addr, fs.cfg.DialTimeout); err != nil {
+			return
+		}
+		fs.reader = bufio.NewReader(fs.conn)
+	}
+
+	if _, err = fs.conn.Write(chunk.data); err != nil {
+		return
+	}
+	fs.counters.bytes.Add(int64(len(chunk.data)))
+
+	if chunk.id != "" {
+		fs.conn.SetReadDeadline(time.Now().Add(fs.cfg.AckTimeout))
+		var resp any
+		if resp, err = readMsgpack(fs.reader); err != nil {
+			return
+		}
+		if m, ok := resp.(map[string]any); !ok || m["ack"] != chunk.id {
+			err = fmt.Errorf("%w: %v", ErrFluentAck, resp)
+		}
+	}
+	return
+}
+
+func (fs *fluentSender) disconnect() {
+	if fs.conn != nil {
+		fs.conn.Close()
+		fs.conn = nil
+		fs.reader = nil
+	}
+}
+
+// Makes the Fluent EventTime extension value, which has nanosecond precision
+func fluentEventTime(t time.Time) msgpackExt {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint32(data, uint32(t.Unix()))
+	binary.BigEndian.PutUint32(data[4:], uint32(t.Nanosecond()))
+	return msgpackExt{typ: 0, data: data}
+}
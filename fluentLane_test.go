@@ -0,0 +1,223 @@
+package lane
+
+import (
+	"bufio"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+type (
+	// Minimal forward protocol server for testing
+	testFluentServer struct {
+		listener net.Listener
+		mu       sync.Mutex
+		tags     []string
+		records  []map[string]any
+		times    []msgpackExt
+		chunks   int
+		noAck    bool
+		reject   bool
+	}
+)
+
+func newTestFluentServer(t *testing.T) *testFluentServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tfs := &testFluentServer{listener: listener}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go tfs.serve(conn)
+		}
+	}()
+	return tfs
+}
+
+func (tfs *testFluentServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	tfs.mu.Lock()
+	reject := tfs.reject
+	tfs.mu.Unlock()
+	if reject {
+		return
+	}
+
+	r := bufio.NewReader(conn)
+	for {
+		msg, err := readMsgpack(r)
+		if err != nil {
+			return
+		}
+
+		parts := msg.([]any)
+		option := parts[2].(map[string]any)
+
+		tfs.mu.Lock()
+		tfs.chunks++
+		for _, entry := range parts[1].([]any) {
+			pair := entry.([]any)
+			tfs.tags = append(tfs.tags, parts[0].(string))
+			tfs.times = append(tfs.times, pair[0].(msgpackExt))
+			tfs.records = append(tfs.records, pair[1].(map[string]any))
+		}
+		noAck := tfs.noAck
+		tfs.mu.Unlock()
+
+		if chunk, found := option["chunk"]; found && !noAck {
+			conn.Write(appendMsgpack(nil, map[string]any{"ack": chunk}))
+		}
+	}
+}
+
+func (tfs *testFluentServer) waitRecords(t *testing.T, count int) []map[string]any {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		tfs.mu.Lock()
+		n := len(tfs.records)
+		tfs.mu.Unlock()
+		if n >= count {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	tfs.mu.Lock()
+	defer tfs.mu.Unlock()
+	return append([]map[string]any{}, tfs.records...)
+}
+
+func TestFluentLane(t *testing.T) {
+	tfs := newTestFluentServer(t)
+	defer tfs.listener.Close()
+
+	clock := NewFixedClock(time.Date(2024, 3, 4, 5, 6, 7, 8, time.UTC))
+	fl := NewFluentLane(nil, FluentConfig{
+		Addr:       tfs.listener.Addr().String(),
+		Tag:        "app.logs",
+		AppName:    "svc",
+		RequireAck: true,
+	}, WithClock(clock))
+
+	child := fl.Derive()
+	child.SetJourneyId("j1")
+
+	fl.Info("hello")
+	child.Errorf("failed %d", 42)
+	fl.Close()
+
+	records := tfs.waitRecords(t, 2)
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+
+	first := records[0]
+	if first["message"] != "hello" || first["level"] != "INFO" || first["app"] != "svc" || first["lane_id"] != fl.LaneId() {
+		t.Errorf("unexpected record %v", first)
+	}
+	if _, found := first["journey_id"]; found {
+		t.Error("unexpected journey id")
+	}
+
+	second := records[1]
+	if second["message"] != "failed 42" || second["level"] != "ERROR" || second["journey_id"] != "j1" || second["lane_id"] != child.LaneId() {
+		t.Errorf("unexpected record %v", second)
+	}
+
+	tfs.mu.Lock()
+	defer tfs.mu.Unlock()
+	if tfs.tags[0] != "app.logs" {
+		t.Errorf("unexpected tag %s", tfs.tags[0])
+	}
+	expectedTime := fluentEventTime(clock.Now())
+	if string(tfs.times[0].data) != string(expectedTime.data) {
+		t.Errorf("unexpected time %x", tfs.times[0].data)
+	}
+}
+
+func TestFluentLaneAckRetry(t *testing.T) {
+	tfs := newTestFluentServer(t)
+	defer tfs.listener.Close()
+	tfs.noAck = true
+
+	fl := NewFluentLane(nil, FluentConfig{
+		Addr:       tfs.listener.Addr().String(),
+		RequireAck: true,
+		AckTimeout: 20 * time.Millisecond,
+		MaxRetries: 2,
+	})
+
+	fl.Info("unacknowledged")
+	fl.Close()
+
+	// the chunk was sent once per attempt
+	tfs.mu.Lock()
+	defer tfs.mu.Unlock()
+	if tfs.chunks != 2 {
+		t.Errorf("expected 2 attempts, got %d", tfs.chunks)
+	}
+}
+
+func TestFluentLaneClosed(t *testing.T) {
+	fl := NewFluentLane(nil, FluentConfig{Addr: "127.0.0.1:1", MaxRetries: 1, DialTimeout: time.Millisecond})
+	child := fl.Derive()
+	fl.Close()
+
+	// logging after close is discarded
+	child.Info("late")
+	fl.Close()
+}
+
+func TestFluentLaneSpool(t *testing.T) {
+	tfs := newTestFluentServer(t)
+	defer tfs.listener.Close()
+	tfs.mu.Lock()
+	tfs.reject = true
+	tfs.mu.Unlock()
+
+	fl := NewFluentLane(nil, FluentConfig{
+		Addr:       tfs.listener.Addr().String(),
+		RequireAck: true,
+		MaxRetries: 1,
+		Spool:      &SpoolConfig{Dir: t.TempDir(), RetryInterval: 10 * time.Millisecond},
+	})
+	defer fl.Close()
+
+	fl.Info("while offline")
+	stats := fl.(*fluentLane).Stats()
+	for deadline := time.Now().Add(5 * time.Second); stats.Spool.Spooled == 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+		stats = fl.(*fluentLane).Stats()
+	}
+	if stats.Spool.Spooled != 1 || stats.Spool.Pending != 1 {
+		t.Fatalf("unexpected stats %+v", stats.Spool)
+	}
+
+	// the spooled record is replayed when the server accepts connections again
+	tfs.mu.Lock()
+	tfs.reject = false
+	tfs.mu.Unlock()
+
+	records := tfs.waitRecords(t, 1)
+	if len(records) != 1 || records[0]["message"] != "while offline" {
+		t.Fatalf("unexpected records %v", records)
+	}
+
+	fl.Info("online")
+	records = tfs.waitRecords(t, 2)
+	if len(records) != 2 || records[1]["message"] != "online" {
+		t.Errorf("unexpected records %v", records)
+	}
+
+	if stats = fl.(*fluentLane).Stats(); stats.Spool.Replayed != 1 || stats.Spool.Pending != 0 {
+		t.Errorf("unexpected stats %+v", stats.Spool)
+	}
+}
@@ -0,0 +1,111 @@
+package lane
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+type (
+	// Rewrites the captured event text before it is compared with a golden
+	// file, to mask details that change from run to run
+	GoldenNormalizer func(text string) string
+)
+
+var (
+	goldenTimePattern    = regexp.MustCompile(`\d{4}[-/]\d{2}[-/]\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?`)
+	goldenUuidPattern    = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+	goldenUlidPattern    = regexp.MustCompile(`\b[0-9A-HJKMNP-TV-Z]{26}\b`)
+	goldenShortPattern   = regexp.MustCompile(`\{[0-9A-Za-z]{10}\}`)
+	goldenPointerPattern = regexp.MustCompile(`0x[0-9a-f]{6,}`)
+)
+
+// Replaces timestamps, such as "2024/03/04 05:06:07" or RFC 3339 times, with
+// "<time>"
+func MaskTimestamps(text string) string {
+	return goldenTimePattern.ReplaceAllString(text, "<time>")
+}
+
+// Replaces UUID and ULID lane IDs with "<lane>", as well as the 10 character
+// lane IDs shown in braces in log lane output
+func MaskLaneIds(text string) string {
+	text = goldenUuidPattern.ReplaceAllString(text, "<lane>")
+	text = goldenUlidPattern.ReplaceAllString(text, "<lane>")
+	return goldenShortPattern.ReplaceAllString(text, "{<lane>}")
+}
+
+// Replaces pointers and other long hex values, such as "0xc000012345", with
+// "0x<ptr>"
+func MaskPointers(text string) string {
+	return goldenPointerPattern.ReplaceAllString(text, "0x<ptr>")
+}
+
+// Makes a normalizer that replaces the matches of [pattern] with [repl],
+// which can refer to submatches as in regexp.ReplaceAllString()
+func MaskPattern(pattern *regexp.Regexp, repl string) GoldenNormalizer {
+	return func(text string) string {
+		return pattern.ReplaceAllString(text, repl)
+	}
+}
+
+// Renders the captured events in the golden file form, one "LEVEL\tmessage"
+// per event
+func (tl *testingLane) goldenText() string {
+	text := tl.EventsToString()
+	if text != "" {
+		text += "\n"
+	}
+	return text
+}
+
+func (tl *testingLane) WriteGolden(path string) error {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, []byte(tl.goldenText()), 0644)
+}
+
+func (tl *testingLane) VerifyGolden(t TestingT, path string, normalizers ...GoldenNormalizer) bool {
+	t.Helper()
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			t.Errorf("golden file %s does not exist; create it with WriteGolden()", path)
+		} else {
+			t.Errorf("golden file %s: %v", path, err)
+		}
+		return false
+	}
+
+	expected := strings.ReplaceAll(string(raw), "\r\n", "\n")
+	actual := tl.goldenText()
+	for _, normalize := range normalizers {
+		expected = normalize(expected)
+		actual = normalize(actual)
+	}
+	if expected == actual {
+		return true
+	}
+
+	expectedLines := strings.Split(expected, "\n")
+	actualLines := strings.Split(actual, "\n")
+	for i := 0; i < max(len(expectedLines), len(actualLines)); i++ {
+		var e, a string
+		if i < len(expectedLines) {
+			e = expectedLines[i]
+		}
+		if i < len(actualLines) {
+			a = actualLines[i]
+		}
+		if e != a {
+			t.Errorf("events differ from golden file %s at line %d:\n  expected: %q\n  actual:   %q", path, i+1, e, a)
+			break
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package lane
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestGoldenRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "testdata", "flow.golden")
+
+	tl := NewTestingLane(nil)
+	tl.Info("starting")
+	tl.Warnf("retry %d", 2)
+	if err := tl.WriteGolden(path); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(raw) != "INFO\tstarting\nWARN\tretry 2\n" {
+		t.Errorf("unexpected golden file %q", raw)
+	}
+
+	if !tl.VerifyGolden(t, path) {
+		t.Error("the events don't match their own golden file")
+	}
+}
+
+func TestGoldenNormalizers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flow.golden")
+
+	first := NewTestingLane(nil)
+	first.Infof("request %s at 2024-03-04T05:06:07.123Z from %p", first.LaneId(), first)
+	first.Info("order 41")
+	if err := first.WriteGolden(path); err != nil {
+		t.Fatal(err)
+	}
+
+	second := NewTestingLane(nil)
+	second.Infof("request %s at 2025-01-02T03:04:05.9+01:00 from %p", second.LaneId(), &testFailureRecorder{})
+	second.Info("order 42")
+
+	var tfr testFailureRecorder
+	if second.VerifyGolden(&tfr, path, MaskTimestamps, MaskLaneIds, MaskPointers) {
+		t.Error("the order numbers should differ")
+	}
+	if len(tfr.failures) != 1 || !strings.Contains(tfr.failures[0], "at line 2") {
+		t.Errorf("unexpected failures %v", tfr.failures)
+	}
+
+	orders := MaskPattern(regexp.MustCompile(`order \d+`), "order <n>")
+	if !second.VerifyGolden(t, path, MaskTimestamps, MaskLaneIds, MaskPointers, orders) {
+		t.Error("the normalized events should match")
+	}
+}
+
+func TestGoldenMasks(t *testing.T) {
+	text := MaskLaneIds("{a1b2c3d4e5} 01HQ3Z8J4YB2N7XK5V6W9T0RSD 6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	if text != "{<lane>} <lane> <lane>" {
+		t.Errorf("unexpected lane ID mask %q", text)
+	}
+	if text = MaskTimestamps("2024/03/04 05:06:07 INFO"); text != "<time> INFO" {
+		t.Errorf("unexpected time mask %q", text)
+	}
+	if text = MaskPointers("at 0xc000012345 (0x1f)"); text != "at 0x<ptr> (0x1f)" {
+		t.Errorf("unexpected pointer mask %q", text)
+	}
+}
+
+func TestGoldenMissing(t *testing.T) {
+	tl := NewTestingLane(nil)
+	var tfr testFailureRecorder
+	if tl.VerifyGolden(&tfr, filepath.Join(t.TempDir(), "missing.golden")) {
+		t.Error("a missing golden file can't match")
+	}
+	if len(tfr.failures) != 1 || !strings.Contains(tfr.failures[0], "WriteGolden") {
+		t.Errorf("unexpected failures %v", tfr.failures)
+	}
+}
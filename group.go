@@ -0,0 +1,57 @@
+package lane
+
+import (
+	"context"
+	"sync"
+)
+
+type (
+	// A collection of tasks working on subtasks of the same request, in the
+	// manner of errgroup.Group, where each task logs to its own derived lane
+	Group struct {
+		l      Lane
+		cancel context.CancelCauseFunc
+		wg     sync.WaitGroup
+		once   sync.Once
+		err    error
+	}
+)
+
+// Makes a group of tasks along with a lane derived from [l] for the group,
+// mirroring errgroup.WithContext(). The group lane's context is canceled when
+// a task first fails, with the task's error as the cause, or when Wait()
+// returns.
+func NewGroup(l Lane) (*Group, Lane) {
+	gl, cancel := l.DeriveWithCancelCause()
+	return &Group{l: gl, cancel: cancel}, gl
+}
+
+// Runs [fn] in a new goroutine with a lane derived from the group lane. The
+// first task to return an error cancels the group, and each failure is logged
+// to the group lane at ERROR with the lane ID of the task that failed. The
+// task lane is closed when [fn] returns.
+func (g *Group) Go(fn func(l Lane) error) {
+	tl := g.l.Derive()
+	g.wg.Add(1)
+
+	go func() {
+		defer g.wg.Done()
+		defer tl.Close()
+
+		if err := fn(tl); err != nil {
+			g.l.Errorf("task %s failed: %v", tl.LaneId(), err)
+			g.once.Do(func() {
+				g.err = err
+				g.cancel(err)
+			})
+		}
+	}()
+}
+
+// Waits for the tasks to finish, then returns the first error of a task, if
+// any.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.cancel(g.err)
+	return g.err
+}
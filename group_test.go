@@ -0,0 +1,72 @@
+package lane
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestGroupFailure(t *testing.T) {
+	tl := NewTestingLane(nil)
+	tl.WantDescendantEvents(true)
+	g, gl := NewGroup(tl)
+
+	errTask := errors.New("task failed")
+	var failedId string
+	g.Go(func(l Lane) error {
+		failedId = l.LaneId()
+		return errTask
+	})
+	g.Go(func(l Lane) error {
+		<-l.Done()
+		if context.Cause(l) != errTask {
+			return fmt.Errorf("unexpected cause %v", context.Cause(l))
+		}
+		return nil
+	})
+
+	if err := g.Wait(); err != errTask {
+		t.Errorf("unexpected error %v", err)
+	}
+	if context.Cause(gl) != errTask {
+		t.Error("the group lane was not canceled with the task error")
+	}
+	if !tl.VerifyEventText(fmt.Sprintf("ERROR\ttask %s failed: task failed", failedId)) {
+		t.Errorf("unexpected events:\n%s", tl.EventsToString())
+	}
+}
+
+func TestGroupSuccess(t *testing.T) {
+	tl := NewTestingLane(nil)
+	g, gl := NewGroup(tl)
+
+	ids := make(chan string, 3)
+	for range 3 {
+		g.Go(func(l Lane) error {
+			if l.Parent().LaneId() != gl.LaneId() {
+				return errors.New("the task lane is not derived from the group lane")
+			}
+			ids <- l.LaneId()
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	close(ids)
+	seen := map[string]bool{}
+	for id := range ids {
+		seen[id] = true
+	}
+	if len(seen) != 3 {
+		t.Error("the tasks did not get their own lanes")
+	}
+	if gl.Err() != context.Canceled {
+		t.Error("Wait() did not cancel the group lane")
+	}
+	if tl.EventCount() != 0 {
+		t.Errorf("unexpected events:\n%s", tl.EventsToString())
+	}
+}
@@ -0,0 +1,372 @@
+package lane
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+type (
+	// Settings of an httpBatchSender, filled in by the lane that makes it
+	httpBatchConfig struct {
+		endpoint     string
+		headers      map[string]string // includes the content type and authorization
+		gzip         bool              // compress the request bodies
+		batchSize    int               // maximum records per request, defaults to 100
+		maxBodyBytes int               // maximum request body before compression, or 0 for no limit
+		maxRecBytes  int               // maximum encoded record, which is shortened or dropped beyond it, or 0 for no limit
+		queueSize    int               // records held while sending, defaults to 10000
+		maxRetries   int               // attempts to send a batch, defaults to 3
+		backoff      Backoff           // delays between the attempts to send a batch
+		timeout      time.Duration     // per request, defaults to 10 seconds
+		client       *http.Client      // defaults to a client with the timeout
+		spool        *SpoolConfig
+		encodeRecord func(rec *Record) []byte      // encodes a record for a batch
+		encodeBody   func(records [][]byte) []byte // makes a request body from encoded records
+	}
+
+	// A network lane that delivers its records with an httpBatchSender
+	httpBatchLane struct {
+		LogLane
+		sender *httpBatchSender
+		owner  bool
+	}
+
+	// Background delivery of encoded records in batches with HTTP POST
+	// requests, shared by a network lane and its derivations. A batch that
+	// can't be sent after the retries is spooled, if there is a spool, or
+	// dropped. A batch the server rejects as invalid is dropped.
+	httpBatchSender struct {
+		cfg        httpBatchConfig
+		ctx        context.Context // canceled when a close gives up waiting
+		cancel     context.CancelFunc
+		records    chan []byte
+		batchBytes int // limit on the records of a batch, less the body's separators, or 0 for none
+		done       chan struct{}
+		mu         sync.RWMutex
+		closed     bool
+		spool      *diskSpool
+		counters   laneCounters
+	}
+)
+
+// Makes a lane that sends its records as configured by [cfg]. Close the lane
+// to flush the queue.
+func newHTTPBatchLane(ctx OptionalContext, cfg httpBatchConfig, opts ...LaneOption) Lane {
+	sender := newHTTPBatchSender(cfg)
+
+	createFn := func(parentLane Lane) (newLane Lane, ll LogLane, writer *log.Logger, err error) {
+		newLane, ll, writer = createHTTPBatchLane(sender)
+		return
+	}
+
+	l, _ := NewEmbeddedLogLane(createFn, ctx, opts...)
+	l.(*httpBatchLane).owner = true
+	return l
+}
+
+func createHTTPBatchLane(sender *httpBatchSender) (newLane Lane, ll LogLane, writer *log.Logger) {
+	hl := httpBatchLane{sender: sender}
+	ll = AllocEmbeddedLogLane()
+	hl.LogLane = ll
+	newLane = &hl
+	writer = log.New(io.Discard, "", 0)
+	return
+}
+
+func (hl *httpBatchLane) receiveRecord(rec *Record) {
+	if data := hl.sender.encode(rec); data != nil {
+		hl.sender.enqueue(data)
+	}
+}
+
+// Provides the lane's operational counters, including those of the sender
+func (hl *httpBatchLane) Stats() (stats LaneStats) {
+	stats = hl.LogLane.Stats()
+	stats.addDelivery(&hl.sender.counters)
+	if hl.sender.spool != nil {
+		stats.Spool = hl.sender.spool.stats()
+	}
+	return
+}
+
+// Flushes the queued records, if this lane started the sender. Returns the
+// context error if [ctx] is done before the records are sent; the records not
+// yet sent are then spooled, if there is a spool, or dropped.
+func (hl *httpBatchLane) CloseWithContext(ctx context.Context) (err error) {
+	err = hl.LogLane.CloseWithContext(ctx)
+	if hl.owner {
+		err = errors.Join(err, hl.sender.close(ctx))
+	}
+	return
+}
+
+func (hl *httpBatchLane) lengthConstraint(level LaneLogLevel) int {
+	if lc, ok := hl.LogLane.(lengthConstrainer); ok {
+		return lc.lengthConstraint(level)
+	}
+	return 0
+}
+
+func (hl *httpBatchLane) treeInfo() laneTreeInfo {
+	if tr, ok := hl.LogLane.(treeReporter); ok {
+		return tr.treeInfo()
+	}
+	return laneTreeInfo{}
+}
+
+func newHTTPBatchSender(cfg httpBatchConfig) *httpBatchSender {
+	if cfg.batchSize <= 0 {
+		cfg.batchSize = 100
+	}
+	if cfg.queueSize <= 0 {
+		cfg.queueSize = 10000
+	}
+	if cfg.maxRetries <= 0 {
+		cfg.maxRetries = 3
+	}
+	cfg.backoff.MaxAttempts = cfg.maxRetries
+	if cfg.timeout <= 0 {
+		cfg.timeout = 10 * time.Second
+	}
+	if cfg.client == nil {
+		cfg.client = &http.Client{Timeout: cfg.timeout}
+	}
+
+	hs := &httpBatchSender{
+		cfg:     cfg,
+		records: make(chan []byte, cfg.queueSize),
+		done:    make(chan struct{}),
+	}
+	hs.ctx, hs.cancel = context.WithCancel(context.Background())
+	if cfg.maxBodyBytes > 0 {
+		hs.batchBytes = max(cfg.maxBodyBytes-cfg.batchSize-2, 1)
+	}
+	if cfg.spool != nil {
+		hs.spool = newDiskSpool(*cfg.spool)
+	}
+	go hs.run()
+	return hs
+}
+
+// Encodes [rec], shortening its message to the record size limit. A record
+// too large without its message is dropped.
+func (hs *httpBatchSender) encode(rec *Record) []byte {
+	data := hs.cfg.encodeRecord(rec)
+	limit := hs.cfg.maxRecBytes
+	if hs.batchBytes > 0 && (limit <= 0 || limit > hs.batchBytes) {
+		// a record must fit in a batch by itself
+		limit = hs.batchBytes
+	}
+	if limit <= 0 || len(data) <= limit {
+		return data
+	}
+
+	fitted := *rec
+	fitted.Stack = nil
+	for {
+		excess := len(data) - limit
+		if excess <= 0 {
+			return data
+		}
+		message := strings.TrimSuffix(fitted.Message, elisionMark)
+		if message == "" {
+			hs.counters.setError(fmt.Errorf("a record of %d bytes exceeds the limit of %d", len(data), limit))
+			hs.counters.dropped.Add(1)
+			return nil
+		}
+		cut := max(len(message)-excess, 0)
+		for cut > 0 && !utf8.RuneStart(message[cut]) {
+			cut--
+		}
+		fitted.Message = message[:cut] + elisionMark
+		data = hs.cfg.encodeRecord(&fitted)
+	}
+}
+
+func (hs *httpBatchSender) enqueue(rec []byte) {
+	hs.mu.RLock()
+	defer hs.mu.RUnlock()
+
+	if hs.closed {
+		return
+	}
+	select {
+	case hs.records <- rec:
+	default:
+		// the queue is full; logging must not block
+		hs.counters.dropped.Add(1)
+	}
+}
+
+func (hs *httpBatchSender) close(ctx context.Context) error {
+	hs.mu.Lock()
+	if !hs.closed {
+		hs.closed = true
+		close(hs.records)
+	}
+	hs.mu.Unlock()
+
+	select {
+	case <-hs.done:
+		return nil
+	case <-ctx.Done():
+		// stop the retries; the records not sent are spooled or dropped
+		hs.cancel()
+		<-hs.done
+		return ctx.Err()
+	}
+}
+
+// Worker that sends the queued records in batches until the queue is closed
+func (hs *httpBatchSender) run() {
+	defer close(hs.done)
+
+	var retry <-chan time.Time
+	if hs.spool != nil {
+		defer hs.spool.close()
+		ticker := time.NewTicker(hs.spool.cfg.RetryInterval)
+		defer ticker.Stop()
+		retry = ticker.C
+	}
+
+	// a record that didn't fit in the previous batch
+	var carried []byte
+
+	for {
+		rec := carried
+		carried = nil
+		if rec == nil {
+			var more bool
+			select {
+			case rec, more = <-hs.records:
+			case <-retry:
+				hs.replaySpool()
+				continue
+			}
+			if !more {
+				if hs.spool != nil && hs.ctx.Err() == nil {
+					// what can't be delivered now stays on disk for the next run
+					hs.replaySpool()
+				}
+				return
+			}
+		}
+
+		batch := [][]byte{rec}
+		size := len(rec)
+	fill:
+		for len(batch) < hs.cfg.batchSize {
+			select {
+			case rec, more := <-hs.records:
+				if !more {
+					break fill
+				}
+				if hs.batchBytes > 0 && size+len(rec) > hs.batchBytes {
+					carried = rec
+					break fill
+				}
+				batch = append(batch, rec)
+				size += len(rec)
+			default:
+				break fill
+			}
+		}
+
+		hs.deliver(batch)
+	}
+}
+
+// Sends a batch of encoded records, spooling them if they can't be sent
+func (hs *httpBatchSender) deliver(records [][]byte) {
+	if hs.spool != nil && (hs.ctx.Err() != nil || hs.spool.pending() && hs.replaySpool() != nil) {
+		// keep the order: new records wait behind the spooled ones
+		hs.spool.append(records)
+		return
+	}
+
+	err := hs.ctx.Err()
+	if err == nil {
+		body := hs.cfg.encodeBody(records)
+		err = hs.cfg.backoff.Retry(hs.ctx, func(attempt int) error {
+			return hs.send(body)
+		})
+	}
+	if err != nil {
+		hs.counters.setError(err)
+		if IsPermanent(err) {
+			// the server won't take the batch however often it is sent
+			hs.counters.dropped.Add(int64(len(records)))
+		} else if hs.spool != nil {
+			hs.spool.append(records)
+		} else {
+			// without a spool, the batch is dropped after the retries are used up
+			hs.counters.dropped.Add(int64(len(records)))
+		}
+	}
+}
+
+// Sends the spooled records, making one attempt per batch. A batch the server
+// rejects is dropped, so that it doesn't hold up the batches after it.
+func (hs *httpBatchSender) replaySpool() error {
+	return hs.spool.replay(hs.cfg.batchSize, hs.batchBytes, func(records [][]byte) error {
+		err := hs.send(hs.cfg.encodeBody(records))
+		if IsPermanent(err) {
+			hs.counters.setError(err)
+			hs.counters.dropped.Add(int64(len(records)))
+			return nil
+		}
+		return err
+	})
+}
+
+func (hs *httpBatchSender) send(body []byte) error {
+	if hs.cfg.gzip {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write(body)
+		zw.Close()
+		body = buf.Bytes()
+	}
+
+	req, err := http.NewRequestWithContext(hs.ctx, http.MethodPost, hs.cfg.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range hs.cfg.headers {
+		req.Header.Set(k, v)
+	}
+	if hs.cfg.gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	resp, err := hs.cfg.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 300 {
+		err = fmt.Errorf("%s responded %s", req.URL.Host, resp.Status)
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+			// the request itself is at fault, such as malformed, unauthorized or too large
+			return Permanent(err)
+		}
+		if delay, valid := ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); valid {
+			err = RetryAfter(err, delay)
+		}
+		return err
+	}
+	hs.counters.bytes.Add(int64(len(body)))
+	return nil
+}
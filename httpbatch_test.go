@@ -0,0 +1,243 @@
+package lane
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type (
+	// Records the requests posted to a fake log intake
+	testIntakeServer struct {
+		*httptest.Server
+		mu       sync.Mutex
+		status   int // the response status, or 0 for 200
+		requests []testIntakeRequest
+	}
+
+	testIntakeRequest struct {
+		path   string
+		header http.Header
+		body   []byte // decompressed
+	}
+)
+
+func newTestIntakeServer() *testIntakeServer {
+	tis := &testIntakeServer{}
+	tis.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			body = zr
+		}
+		data, _ := io.ReadAll(body)
+
+		tis.mu.Lock()
+		defer tis.mu.Unlock()
+		if tis.status != 0 {
+			w.WriteHeader(tis.status)
+			return
+		}
+		tis.requests = append(tis.requests, testIntakeRequest{path: r.URL.Path, header: r.Header, body: data})
+	}))
+	return tis
+}
+
+func (tis *testIntakeServer) setStatus(status int) {
+	tis.mu.Lock()
+	defer tis.mu.Unlock()
+	tis.status = status
+}
+
+// Waits for [n] accepted requests, providing those received
+func (tis *testIntakeServer) waitRequests(t *testing.T, n int) []testIntakeRequest {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		tis.mu.Lock()
+		requests := append([]testIntakeRequest{}, tis.requests...)
+		tis.mu.Unlock()
+		if len(requests) >= n || time.Now().After(deadline) {
+			return requests
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func testBatchConfig(url string) httpBatchConfig {
+	return httpBatchConfig{
+		endpoint:   url,
+		headers:    map[string]string{"Content-Type": "text/plain"},
+		batchSize:  10,
+		queueSize:  100,
+		maxRetries: 2,
+		backoff:    Backoff{Initial: time.Millisecond},
+		client:     http.DefaultClient,
+		encodeBody: func(records [][]byte) []byte { return joinTestRecords(records) },
+	}
+}
+
+func joinTestRecords(records [][]byte) (body []byte) {
+	for _, rec := range records {
+		body = append(append(body, rec...), '\n')
+	}
+	return
+}
+
+func TestHTTPBatchSender(t *testing.T) {
+	tis := newTestIntakeServer()
+	defer tis.Close()
+
+	cfg := testBatchConfig(tis.URL + "/intake")
+	cfg.gzip = true
+	hs := newHTTPBatchSender(cfg)
+	hs.enqueue([]byte("one"))
+	hs.enqueue([]byte("two"))
+	if err := hs.close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var body string
+	for _, req := range tis.waitRequests(t, 1) {
+		if req.path != "/intake" || req.header.Get("Content-Type") != "text/plain" || req.header.Get("Content-Encoding") != "gzip" {
+			t.Errorf("unexpected request %+v", req)
+		}
+		body += string(req.body)
+	}
+	if body != "one\ntwo\n" {
+		t.Errorf("unexpected body %q", body)
+	}
+	if hs.counters.bytes.Load() == 0 {
+		t.Error("expected the bytes sent to be counted")
+	}
+
+	hs.enqueue([]byte("after close"))
+	if hs.counters.dropped.Load() != 0 {
+		t.Error("a record sent after close was counted as dropped")
+	}
+}
+
+func TestHTTPBatchSenderFailure(t *testing.T) {
+	tis := newTestIntakeServer()
+	defer tis.Close()
+	tis.setStatus(http.StatusServiceUnavailable)
+
+	hs := newHTTPBatchSender(testBatchConfig(tis.URL))
+	hs.enqueue([]byte("lost"))
+	hs.close(context.Background())
+
+	stats := hs.counters.snapshot()
+	if stats.Dropped != 1 || stats.LastError == nil {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestHTTPBatchSenderSpool(t *testing.T) {
+	tis := newTestIntakeServer()
+	defer tis.Close()
+	tis.setStatus(http.StatusServiceUnavailable)
+
+	cfg := testBatchConfig(tis.URL)
+	cfg.spool = &SpoolConfig{Dir: t.TempDir(), RetryInterval: 10 * time.Millisecond}
+	hs := newHTTPBatchSender(cfg)
+	defer hs.close(context.Background())
+
+	hs.enqueue([]byte("while offline"))
+	for deadline := time.Now().Add(5 * time.Second); hs.spool.stats().Spooled == 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the spooled record is replayed when the intake accepts requests again
+	tis.setStatus(0)
+	requests := tis.waitRequests(t, 1)
+	if len(requests) != 1 || string(requests[0].body) != "while offline\n" {
+		t.Fatalf("unexpected requests %v", requests)
+	}
+}
+
+func TestHTTPBatchSenderRejected(t *testing.T) {
+	tis := newTestIntakeServer()
+	defer tis.Close()
+	tis.setStatus(http.StatusBadRequest)
+
+	cfg := testBatchConfig(tis.URL)
+	cfg.backoff = Backoff{Initial: time.Hour}
+	cfg.spool = &SpoolConfig{Dir: t.TempDir(), RetryInterval: 10 * time.Millisecond}
+	hs := newHTTPBatchSender(cfg)
+	defer hs.close(context.Background())
+
+	// a rejected batch is dropped at once rather than retried or spooled
+	hs.enqueue([]byte("rejected"))
+	for deadline := time.Now().Add(5 * time.Second); hs.counters.dropped.Load() == 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	stats := hs.counters.snapshot()
+	if stats.Dropped != 1 || !IsPermanent(stats.LastError) || hs.spool.stats().Spooled != 0 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	// and doesn't hold up the records after it
+	tis.setStatus(0)
+	hs.enqueue([]byte("accepted"))
+	requests := tis.waitRequests(t, 1)
+	if len(requests) != 1 || string(requests[0].body) != "accepted\n" {
+		t.Fatalf("unexpected requests %v", requests)
+	}
+}
+
+func TestHTTPBatchSenderCloseContext(t *testing.T) {
+	tis := newTestIntakeServer()
+	defer tis.Close()
+	tis.setStatus(http.StatusServiceUnavailable)
+
+	cfg := testBatchConfig(tis.URL)
+	cfg.backoff = Backoff{Initial: time.Hour}
+	cfg.spool = &SpoolConfig{Dir: t.TempDir(), RetryInterval: time.Hour}
+	hs := newHTTPBatchSender(cfg)
+
+	hs.enqueue([]byte("in flight"))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// the close stops the retries and spools the batch
+	start := time.Now()
+	if err := hs.close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("unexpected error %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("the close waited out the backoff")
+	}
+	if stats := hs.spool.stats(); stats.Spooled != 1 {
+		t.Errorf("expected the batch in flight to be spooled, have %+v", stats)
+	}
+}
+
+func TestHTTPBatchSenderRecordLimit(t *testing.T) {
+	cfg := testBatchConfig("http://localhost")
+	cfg.maxRecBytes = 10
+	cfg.encodeRecord = func(rec *Record) []byte { return []byte(rec.Message) }
+	hs := newHTTPBatchSender(cfg)
+	defer hs.close(context.Background())
+
+	if data := hs.encode(&Record{Message: "short"}); string(data) != "short" {
+		t.Errorf("unexpected record %q", data)
+	}
+	if data := hs.encode(&Record{Message: "0123456789é123"}); string(data) != "0123456…" {
+		t.Errorf("unexpected shortened record %q", data)
+	}
+
+	// a record over the limit without its message is dropped
+	hs.cfg.encodeRecord = func(rec *Record) []byte { return []byte("fields over the limit " + rec.Message) }
+	if data := hs.encode(&Record{Message: "lost"}); data != nil || hs.counters.dropped.Load() != 1 {
+		t.Errorf("expected the record to be dropped, have %q", data)
+	}
+}
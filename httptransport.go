@@ -0,0 +1,105 @@
+package lane
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+type (
+	// An http.RoundTripper that logs requests on the lane of their context
+	httpTransport struct {
+		base http.RoundTripper
+	}
+)
+
+// The header carrying the journey ID of an outbound request
+const JourneyIdHeader = "X-Journey-Id"
+
+// Makes an http.RoundTripper that logs each request on the lane of the
+// request's context, found with FromContext(). The method, URL (without a
+// password), status and duration are logged at DEBUG. A failure is logged at
+// ERROR, or WARN if the request was canceled, with a classification such as
+// "timeout", "dns" or "connection refused".
+//
+// The lane's journey ID is sent in the JourneyIdHeader, and its W3C trace
+// parent in the traceparent header, unless the request already has them.
+// Requests without a lane are passed to [base] unchanged. A nil [base] uses
+// http.DefaultTransport.
+func NewHTTPTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &httpTransport{base: base}
+}
+
+func (ht *httpTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	l, found := FromContext(req.Context())
+	if !found {
+		return ht.base.RoundTrip(req)
+	}
+
+	journeyId := l.JourneyId()
+	traceParent := l.TraceParent()
+	if (journeyId != "" && req.Header.Get(JourneyIdHeader) == "") || (traceParent != "" && req.Header.Get("traceparent") == "") {
+		// a round tripper must not modify the caller's request
+		req = req.Clone(req.Context())
+		if journeyId != "" && req.Header.Get(JourneyIdHeader) == "" {
+			req.Header.Set(JourneyIdHeader, journeyId)
+		}
+		if traceParent != "" && req.Header.Get("traceparent") == "" {
+			req.Header.Set("traceparent", traceParent)
+		}
+	}
+
+	start := time.Now()
+	resp, err := ht.base.RoundTrip(req)
+	elapsed := time.Since(start)
+
+	if err != nil {
+		kind := classifyHTTPError(err)
+		if kind == "canceled" {
+			l.Warnf("http %s %s canceled after %v: %v", req.Method, req.URL.Redacted(), elapsed, err)
+		} else {
+			l.Errorf("http %s %s failed after %v (%s): %v", req.Method, req.URL.Redacted(), elapsed, kind, err)
+		}
+		return nil, err
+	}
+
+	l.Debugf("http %s %s %d took %v", req.Method, req.URL.Redacted(), resp.StatusCode, elapsed)
+	return resp, nil
+}
+
+// Names the kind of failure of an HTTP request
+func classifyHTTPError(err error) string {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var certErr *tls.CertificateVerificationError
+	var headerErr tls.RecordHeaderError
+	var unknownAuthErr x509.UnknownAuthorityError
+	var hostErr x509.HostnameError
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.As(err, &dnsErr):
+		return "dns"
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "connection refused"
+	case errors.Is(err, syscall.ECONNRESET):
+		return "connection reset"
+	case errors.As(err, &certErr), errors.As(err, &headerErr), errors.As(err, &unknownAuthErr), errors.As(err, &hostErr):
+		return "tls"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	default:
+		return "transport"
+	}
+}
@@ -0,0 +1,75 @@
+package lane
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestIndent(t *testing.T) {
+	for _, l := range []Lane{NewTestingLane(nil), NewLogLane(nil), NewNullLane(nil)} {
+		tl := NewTestingLane(nil)
+		l.AddTee(tl)
+
+		step := l.Indent()
+		step.Info("step")
+		step.Indent().Info("substep")
+		step.Derive().Info("derived")
+		l.Info("done")
+
+		events := tl.Events()
+		if len(events) != 4 || events[0].Depth != 1 || events[1].Depth != 2 || events[2].Depth != 1 || events[3].Depth != 0 {
+			t.Errorf("%T: unexpected events: %+v", l, events)
+		}
+		if l.(laneInternal).LaneProps().indent != 0 {
+			t.Errorf("%T: the indent changed the parent", l)
+		}
+	}
+}
+
+func TestIndentPrefix(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	ll := NewLogLane(nil)
+	ll.(LogLane).SetFlagsMask(log.Ldate | log.Ltime)
+	ll.Info("outer")
+	ll.Indent().Category("db").Indent().Info("inner")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], "} outer") || !strings.HasSuffix(lines[1], "} [db]     inner") {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+}
+
+func TestIndentRecord(t *testing.T) {
+	ll := NewLogLane(nil).(LogLane)
+	var sink testRecordSink
+	ll.AddRecordSink(&sink)
+	ll.Indent().Indent().Warn("nested")
+
+	recs := sink.records
+	if len(recs) != 1 || recs[0].Depth != 2 {
+		t.Fatalf("unexpected records %+v", recs)
+	}
+
+	for _, enc := range []Encoder{JSONEncoder{}, CBOREncoder{}, LogfmtEncoder{}} {
+		data := enc.AppendRecord(nil, &recs[0])
+		if _, ok := enc.(CBOREncoder); ok {
+			rec, err := NewCBORDecoder(bytes.NewReader(data)).Decode()
+			if err != nil || rec.Depth != 2 {
+				t.Errorf("unexpected decoding %+v %v", rec, err)
+			}
+		} else if !bytes.Contains(data, []byte(`"depth":2`)) && !bytes.Contains(data, []byte("depth=2")) {
+			t.Errorf("%T: the depth is missing from %q", enc, data)
+		}
+	}
+
+	text := TextEncoder{}.AppendRecord(nil, &recs[0])
+	if !strings.Contains(string(text), "}     nested") {
+		t.Errorf("unexpected text %q", text)
+	}
+}
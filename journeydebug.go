@@ -0,0 +1,83 @@
+package lane
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// A temporary log level for the lanes of a journey
+	journeyDebug struct {
+		level   LaneLogLevel
+		expires time.Time
+	}
+)
+
+var (
+	journeyDebugMu sync.RWMutex
+	journeyDebugs  = map[string]journeyDebug{}
+
+	// the number of entries in journeyDebugs, so that logging can skip the
+	// lookup when no journey is being debugged
+	journeyDebugCount atomic.Int32
+)
+
+// Lowers the effective log level to [level] for [ttl], for every lane whose
+// journey ID is [journeyId], such as to TRACE the requests of a single user
+// in production. Log lanes keep the first 10 characters of a journey ID,
+// which also match. A lane's own level applies again once the time expires
+// or DisableJourneyDebug() is called.
+//
+// Messages received as a tee are checked with the sender's journey ID.
+func EnableJourneyDebug(journeyId string, level LaneLogLevel, ttl time.Duration) {
+	if journeyId == "" {
+		return
+	}
+	entry := journeyDebug{level: level, expires: time.Now().Add(ttl)}
+
+	journeyDebugMu.Lock()
+	defer journeyDebugMu.Unlock()
+	journeyDebugs[journeyId] = entry
+	if len(journeyId) > 10 {
+		journeyDebugs[journeyId[:10]] = entry
+	}
+	journeyDebugCount.Store(int32(len(journeyDebugs)))
+}
+
+// Restores the lanes' own log level for the journey ID before its
+// EnableJourneyDebug() time expires.
+func DisableJourneyDebug(journeyId string) {
+	journeyDebugMu.Lock()
+	defer journeyDebugMu.Unlock()
+	delete(journeyDebugs, journeyId)
+	if len(journeyId) > 10 {
+		delete(journeyDebugs, journeyId[:10])
+	}
+	journeyDebugCount.Store(int32(len(journeyDebugs)))
+}
+
+// Checks if a message at [level] is enabled by a journey debug setting
+func journeyDebugEnabled(journeyId string, level LaneLogLevel) bool {
+	if journeyDebugCount.Load() == 0 || journeyId == "" {
+		return false
+	}
+
+	journeyDebugMu.RLock()
+	entry, found := journeyDebugs[journeyId]
+	journeyDebugMu.RUnlock()
+	if !found {
+		return false
+	}
+
+	if time.Now().After(entry.expires) {
+		journeyDebugMu.Lock()
+		if current, found := journeyDebugs[journeyId]; found && current == entry {
+			delete(journeyDebugs, journeyId)
+			journeyDebugCount.Store(int32(len(journeyDebugs)))
+		}
+		journeyDebugMu.Unlock()
+		return false
+	}
+	return level >= entry.level
+}
@@ -0,0 +1,99 @@
+package lane
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJourneyDebugTestingLane(t *testing.T) {
+	tl := NewTestingLane(nil)
+	tl.SetLogLevel(LogLevelWarn)
+	tl.SetJourneyId("user-1234-request")
+
+	other := NewTestingLane(nil)
+	other.SetLogLevel(LogLevelWarn)
+	other.SetJourneyId("user-9999")
+
+	EnableJourneyDebug("user-1234-request", LogLevelDebug, time.Minute)
+	defer DisableJourneyDebug("user-1234-request")
+
+	tl.Trace("still hidden")
+	tl.Debug("now visible")
+	other.Debug("other journey")
+
+	if !tl.VerifyEventText("DEBUG\tnow visible") {
+		t.Errorf("unexpected events:\n%s", tl.EventsToString())
+	}
+	if other.EventCount() != 0 {
+		t.Errorf("unexpected events:\n%s", other.EventsToString())
+	}
+
+	DisableJourneyDebug("user-1234-request")
+	tl.Debug("hidden again")
+	if tl.EventCount() != 1 {
+		t.Errorf("unexpected events:\n%s", tl.EventsToString())
+	}
+}
+
+func TestJourneyDebugLogLane(t *testing.T) {
+	skipInRelease(t)
+
+	var buf bytes.Buffer
+	ll := NewLogLane(nil).(LogLane)
+	ll.AddRawWriter(&buf)
+	ll.SetLogLevel(LogLevelError)
+	ll.SetJourneyId("0123456789abcdef")
+
+	EnableJourneyDebug("0123456789abcdef", LogLevelTrace, time.Minute)
+	ll.Tracef("trace %d", 1)
+	DisableJourneyDebug("0123456789abcdef")
+	ll.Trace("trace 2")
+
+	text := buf.String()
+	if !strings.Contains(text, "trace 1") || strings.Contains(text, "trace 2") {
+		t.Errorf("unexpected output %q", text)
+	}
+	if journeyDebugCount.Load() != 0 {
+		t.Error("the journey debug settings were not removed")
+	}
+}
+
+func TestJourneyDebugExpires(t *testing.T) {
+	tl := NewTestingLane(nil)
+	tl.SetLogLevel(LogLevelInfo)
+	tl.SetJourneyId("short")
+
+	EnableJourneyDebug("short", LogLevelTrace, 10*time.Millisecond)
+	tl.Trace("visible")
+	time.Sleep(20 * time.Millisecond)
+	tl.Trace("expired")
+
+	if !tl.VerifyEventText("TRACE\tvisible") {
+		t.Errorf("unexpected events:\n%s", tl.EventsToString())
+	}
+	if journeyDebugCount.Load() != 0 {
+		t.Error("the expired setting was not removed")
+	}
+}
+
+func TestJourneyDebugTee(t *testing.T) {
+	var buf bytes.Buffer
+	ll := NewLogLane(nil).(LogLane)
+	ll.SetLogLevel(LogLevelError)
+
+	tee := NewLogLane(nil).(LogLane)
+	tee.AddRawWriter(&buf)
+	tee.SetLogLevel(LogLevelError)
+	ll.AddTee(tee)
+	ll.SetJourneyId("teed")
+
+	EnableJourneyDebug("teed", LogLevelInfo, time.Minute)
+	defer DisableJourneyDebug("teed")
+	ll.Info("to the tee")
+
+	if !strings.Contains(buf.String(), "to the tee") {
+		t.Errorf("unexpected tee output %q", buf.String())
+	}
+}
@@ -2,6 +2,7 @@ package lane
 
 import (
 	"context"
+	"io"
 	"log"
 	"time"
 )
@@ -41,6 +42,17 @@ type (
 		// Once set, log messages will include this ID along with the lane ID.
 		SetJourneyId(id string)
 
+		// Assigns the journey ID from a W3C traceparent header value. The full trace ID
+		// becomes the journey ID, without the usual truncation, and the parent span ID is
+		// retained as the TraceParentSpanKey metadata value. A later SetJourneyId() ends
+		// the trace context and removes the TraceParentSpanKey value.
+		SetTraceParent(tp string) error
+
+		// Provides a W3C traceparent header value for outbound requests, carrying the trace
+		// ID and a span ID derived from this lane's ID, or an empty string if no trace
+		// parent was set.
+		TraceParent() string
+
 		// Controls the log filtering
 		SetLogLevel(newLevel LaneLogLevel) (priorLevel LaneLogLevel)
 
@@ -50,6 +62,16 @@ type (
 		// Gets a lane metadata value (even if the lane type does not log it)
 		GetMetadata(key string) string
 
+		// Removes a lane metadata value
+		DeleteMetadata(key string)
+
+		// Provides a copy of all of the lane's metadata values
+		MetadataMap() map[string]string
+
+		// Selects what metadata lanes derived from this one start with: none,
+		// a copy, or the same values shared with this lane.
+		SetMetadataInheritance(mode MetadataInheritance) (prior MetadataInheritance)
+
 		// Trace, intended for checkpoint information. Messages formated with fmt.Sprint().
 		Trace(args ...any)
 		// Trace, intended for checkpoint information. Messages formated with fmt.Sprintf().
@@ -85,6 +107,26 @@ type (
 		// Error, intended for application faults that alert or explain unwanted conditions. Object [obj] is converted to JSON, including private fields, and concatenated to [message].
 		ErrorObject(message string, obj any)
 
+		// Info, logged only the first time [key] is used on this lane. See InfoOnceGlobal() for once
+		// per process. Messages formated with fmt.Sprint().
+		InfoOnce(key string, args ...any)
+
+		// Warn, logged only the first time [key] is used on this lane. See WarnOnceGlobal() for once
+		// per process. Messages formated with fmt.Sprint().
+		WarnOnce(key string, args ...any)
+
+		// Warn, logged at most once per [period] for [key], such as within a loop calling a failing
+		// dependency. The next message logged reports how many were suppressed. Messages formated with fmt.Sprint().
+		WarnRate(key string, period time.Duration, args ...any)
+		// Warn, logged at most once per [period] for [key]. Messages formated with fmt.Sprintf().
+		WarnRatef(key string, period time.Duration, format string, args ...any)
+
+		// Error, logged at most once per [period] for [key], such as within a loop calling a failing
+		// dependency. The next message logged reports how many were suppressed. Messages formated with fmt.Sprint().
+		ErrorRate(key string, period time.Duration, args ...any)
+		// Error, logged at most once per [period] for [key]. Messages formated with fmt.Sprintf().
+		ErrorRatef(key string, period time.Duration, format string, args ...any)
+
 		// Severe error, intended for details about why an application will soon terminate. Messages formated with fmt.Sprint().
 		PreFatal(args ...any)
 		// Severe error, intended for details about why an application will soon terminate. Messages formated with fmt.Sprintf().
@@ -108,9 +150,65 @@ type (
 		// Set a limit on the message length, or less than 1 for no limit.
 		SetLengthConstraint(maxLength int) int
 
+		// Set a limit on the message length for a single level, overriding the lane-wide limit.
+		// Zero applies the lane-wide limit, and [LengthUnconstrained] removes the limit.
+		SetLevelLengthConstraint(level LaneLogLevel, maxLength int) (prior int)
+
+		// Selects which part of a message exceeding the length constraint is kept.
+		SetTruncationMode(mode TruncationMode) (prior TruncationMode)
+
+		// Replaces the settings used by the object logging functions, returning the prior settings.
+		SetObjectOptions(opt LogObjectOpt) (prior LogObjectOpt)
+
+		// Provides the settings used by the object logging functions.
+		ObjectOptions() LogObjectOpt
+
+		// Selects how the object logging functions name and render fields, returning the prior encoding.
+		SetObjectEncoding(encoding ObjectEncoding) (prior ObjectEncoding)
+
 		// Exposes access to the underlying log object.
 		Logger() *log.Logger
-		Close()
+
+		// Provides an output that logs each line written to it at [level], such
+		// as for the Stdout of an exec.Cmd or a library's verbose output. A
+		// level above ERROR is logged at ERROR. Close the writer to also log
+		// text that doesn't end with a line break.
+		WriterAt(level LaneLogLevel) io.WriteCloser
+
+		// Releases the lane's resources, after delivering any queued or buffered output. Closing
+		// a lane more than once has no further effect.
+		Close() error
+
+		// Same as Close, but stops waiting for queued output to be delivered once [ctx] is done.
+		CloseWithContext(ctx context.Context) error
+
+		// Registers a function called each time this lane or a later descendant is derived.
+		// Call [unregister] to stop calling it.
+		OnDerive(hook DeriveHook) (unregister func())
+
+		// Registers a function called once when this lane or a later descendant is closed.
+		// Call [unregister] to stop calling it.
+		OnClose(hook CloseHook) (unregister func())
+
+		// Registers a function called with the record of each message at or above [level]
+		// logged by this lane or a later descendant.
+		OnLevel(level LaneLogLevel, hook LevelHook)
+
+		// Assigns a descriptive name to the lane, for diagnostics.
+		SetName(name string)
+
+		// Provides the name assigned by SetName.
+		Name() string
+
+		// Turns on tracking of lanes derived from this lane, for Descendants() and DumpTree().
+		TrackDescendants(enable bool) (prior bool)
+
+		// Provides the live tracked descendants of the lane.
+		Descendants() []Lane
+
+		// Blocks until every tracked descendant that can be cancelled on its own, such as a lane
+		// made by DeriveWithCancel(), is cancelled or closed, or until [ctx] is done.
+		WaitForDescendants(ctx context.Context) error
 
 		// Makes a lane for a child activity that needs its own correlation ID. For example a server will derive a new lane for each client connection.
 		Derive() Lane
@@ -139,12 +237,52 @@ type (
 		// The [cause] argument provides an error to use for timeout expiration.
 		DeriveWithTimeoutCause(duration time.Duration, cause error) (Lane, context.CancelFunc)
 
+		// Makes a lane for a child activity that needs its own correlation ID, with a context that
+		// times out after [maxTimeout], or at the lane's deadline if that is sooner. The bound that
+		// applied is logged at DEBUG on the derived lane.
+		DeriveWithMaxTimeout(maxTimeout time.Duration) (Lane, context.CancelFunc)
+
 		// Used to maintain the lane configuration while changing the context.
 		DeriveReplaceContext(ctx OptionalContext) Lane
 
+		// Provides a lane derived from this one whose messages carry [category], such as
+		// "http", so that filters and sinks can select a subsystem's messages. The category
+		// follows the IDs in the text output, and lanes derived from the new lane inherit it.
+		Category(category string) Lane
+
+		// Provides a lane derived from this one whose messages are nested one
+		// level deeper, for readable traces of hierarchical operations. The text
+		// output indents the messages by two spaces per level, and records carry
+		// the level in Depth. Lanes derived from the new lane inherit the level,
+		// while this lane keeps its own.
+		Indent() Lane
+
+		// Captures the lane's level, stack trace, caller info, length constraint, object
+		// option, CR mode and flags mask settings, and its metadata tags.
+		ConfigSnapshot() LaneConfigState
+
+		// Restores settings captured by ConfigSnapshot(), such as after temporarily raising the
+		// verbosity, or to reconcile a derived lane after a configuration reload. Metadata
+		// keys that are not in [state] are removed. Settings the lane type doesn't have are
+		// ignored.
+		ApplyConfig(state LaneConfigState)
+
+		// Starts a timer that logs a warning and calls [onTimeout], if not nil, when [d] elapses
+		// before [stop] is called. Unlike DeriveWithTimeout(), the context isn't canceled, which
+		// suits SLO monitoring. The timer ends if the lane's context is done. [stop] reports
+		// whether it stopped the timer before it fired.
+		WithSoftTimeout(d time.Duration, onTimeout func(l Lane)) (stop func() bool)
+
 		// Turns on stack trace logging.
 		EnableStackTrace(level LaneLogLevel, enable bool) (wasEnabled bool)
 
+		// Turns on stack trace logging, limited to [maxFrames] callers for the level, or
+		// less than 1 to use the limit of SetMaxStackFrames().
+		EnableStackTraceDepth(level LaneLogLevel, maxFrames int) (priorFrames int)
+
+		// Annotates each message with the file:line and function of the code that logged it.
+		SetCallerInfo(enable bool) (prior bool)
+
 		// AddTee attaches a receiver lane to the sender lane. Log messages from the sender lane are
 		// forwarded to the receiver lane [l], but retain the sender lane's lane ID and journey ID
 		// instead of the receiver's IDs.
@@ -161,6 +299,13 @@ type (
 		// the test version of Panic doesn't return.
 		SetPanicHandler(handler Panic)
 
+		// Sets a function called when the lane or a later descendant fails to write a message,
+		// such as when the disk is full. A nil handler removes it.
+		SetErrorHandler(handler ErrorHandler)
+
+		// Provides the most recent failure to write or deliver a message, or nil.
+		LastError() error
+
 		// Gets the parent lane, or untyped nil if no parent.
 		Parent() Lane
 	}
@@ -196,12 +341,45 @@ type (
 
 		LogStackTrimInternal(props loggingProperties, message string, skippedCallers int)
 
+		// Logs an object, passing the original object rather than its
+		// rendering to the tees when the source lane's LogObjectOpt has
+		// TeeObjects set. The object is rendered with the lane's settings.
+		ObjectInternal(props loggingProperties, level LaneLogLevel, message string, obj any)
+
+		// Logs the rendered [text] of an object, passing the original
+		// object to the tees
+		objectTextInternal(props loggingProperties, level LaneLogLevel, text, message string, obj any)
+
 		OnPanic()
+
+		// Sets the category of the lane's messages and the lanes derived from it
+		setCategory(category string)
+
+		// Sets the nesting level of the lane's messages and the lanes derived from it
+		setIndent(depth int)
+
+		// Checks for a tracked descendant that WaitForDescendants() waits for
+		hasPendingDescendants(done <-chan struct{}) bool
+
+		// Logs a record captured earlier, such as by a ring buffer lane, at its
+		// original level and time regardless of the lane's level, and passes it
+		// to the tees
+		replayRecord(rec *Record)
+
+		// Checks if the lane was closed, so that senders drop it as a tee
+		isClosed() bool
+
+		// Reports true only the first time it is called on the lane, so that
+		// dropping it as a closed tee is logged once
+		firstDropNotice() bool
 	}
 
 	loggingProperties struct {
 		laneId    string
 		journeyId string
+		category  string
+		parentId  string // the parent lane's ID, when the lane shows it in the output
+		indent    int    // the nesting level, from Indent()
 	}
 
 	teeHandler func(props loggingProperties, receiver laneInternal)
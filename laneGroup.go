@@ -0,0 +1,341 @@
+package lane
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+)
+
+type (
+	// A lane that broadcasts each message to all of its member lanes, such as
+	// a console lane and a disk lane, when no member is the primary output. The
+	// members receive the messages with the group's lane ID and journey ID, as
+	// a tee would. The settings of the group, such as the log level, length
+	// constraints and metadata, are applied to every member, and the group
+	// has its own tees.
+	//
+	// Lanes derived from a group are groups of lanes derived from its members.
+	// Closing a group closes its members.
+	LaneGroup interface {
+		Lane
+
+		// Provides the member lanes.
+		Members() []Lane
+	}
+
+	laneGroup struct {
+		LogLane
+		ll      *logLane
+		members []Lane
+	}
+)
+
+// Makes a lane that broadcasts to [members]. A member panic is recorded as a
+// tee failure in the group's Stats(), and LastError() combines the errors of
+// the group and its members.
+func NewLaneGroup(ctx OptionalContext, members []Lane, opts ...LaneOption) LaneGroup {
+	for _, m := range members {
+		if _, ok := m.(laneInternal); !ok {
+			panic(fmt.Sprintf("lane group member %T is not a lane of this package", m))
+		}
+	}
+
+	createFn := func(parentLane Lane) (newLane Lane, ll LogLane, writer *log.Logger, err error) {
+		derived := members
+		if parent, ok := parentLane.(*laneGroup); ok {
+			derived = make([]Lane, 0, len(parent.members))
+			for _, m := range parent.members {
+				derived = append(derived, m.Derive())
+			}
+		}
+		newLane, ll, writer = createLaneGroup(derived)
+		return
+	}
+
+	l, _ := NewEmbeddedLogLane(createFn, ctx, opts...)
+	return l.(LaneGroup)
+}
+
+func createLaneGroup(members []Lane) (newLane Lane, ll LogLane, writer *log.Logger) {
+	g := laneGroup{members: append([]Lane{}, members...)}
+	ll = AllocEmbeddedLogLane()
+	g.LogLane = ll
+	g.ll = ll.(*logLane)
+	newLane = &g
+	writer = log.New(io.Discard, "", 0)
+	return
+}
+
+// The group doesn't have output of its own
+func (g *laneGroup) receiveRecord(rec *Record) {
+}
+
+func (g *laneGroup) Members() []Lane {
+	return append([]Lane{}, g.members...)
+}
+
+// Sends a message to the members and the group's tees
+func (g *laneGroup) broadcast(props loggingProperties, level LaneLogLevel, logger teeHandler) {
+	for _, m := range g.members {
+		g.ll.callTee(props, level, m, logger)
+	}
+	g.ll.tee(props, level, logger)
+}
+
+func (g *laneGroup) SetJourneyId(id string) {
+	g.LogLane.SetJourneyId(id)
+	for _, m := range g.members {
+		m.SetJourneyId(id)
+	}
+}
+
+func (g *laneGroup) SetLogLevel(newLevel LaneLogLevel) (priorLevel LaneLogLevel) {
+	priorLevel = g.LogLane.SetLogLevel(newLevel)
+	for _, m := range g.members {
+		m.SetLogLevel(newLevel)
+	}
+	return
+}
+
+func (g *laneGroup) SetMetadata(key, val string) {
+	g.LogLane.SetMetadata(key, val)
+	for _, m := range g.members {
+		m.SetMetadata(key, val)
+	}
+}
+
+func (g *laneGroup) DeleteMetadata(key string) {
+	g.LogLane.DeleteMetadata(key)
+	for _, m := range g.members {
+		m.DeleteMetadata(key)
+	}
+}
+
+func (g *laneGroup) SetMetadataInheritance(mode MetadataInheritance) (prior MetadataInheritance) {
+	prior = g.LogLane.SetMetadataInheritance(mode)
+	for _, m := range g.members {
+		m.SetMetadataInheritance(mode)
+	}
+	return
+}
+
+func (g *laneGroup) SetLengthConstraint(maxLength int) int {
+	prior := g.LogLane.SetLengthConstraint(maxLength)
+	for _, m := range g.members {
+		m.SetLengthConstraint(maxLength)
+	}
+	return prior
+}
+
+func (g *laneGroup) SetLevelLengthConstraint(level LaneLogLevel, maxLength int) (prior int) {
+	prior = g.LogLane.SetLevelLengthConstraint(level, maxLength)
+	for _, m := range g.members {
+		m.SetLevelLengthConstraint(level, maxLength)
+	}
+	return
+}
+
+func (g *laneGroup) SetTruncationMode(mode TruncationMode) (prior TruncationMode) {
+	prior = g.LogLane.SetTruncationMode(mode)
+	for _, m := range g.members {
+		m.SetTruncationMode(mode)
+	}
+	return
+}
+
+func (g *laneGroup) SetObjectOptions(opt LogObjectOpt) (prior LogObjectOpt) {
+	prior = g.LogLane.SetObjectOptions(opt)
+	for _, m := range g.members {
+		m.SetObjectOptions(opt)
+	}
+	return
+}
+
+func (g *laneGroup) SetObjectEncoding(encoding ObjectEncoding) (prior ObjectEncoding) {
+	prior = g.LogLane.SetObjectEncoding(encoding)
+	for _, m := range g.members {
+		m.SetObjectEncoding(encoding)
+	}
+	return
+}
+
+func (g *laneGroup) EnableStackTrace(level LaneLogLevel, enable bool) (wasEnabled bool) {
+	wasEnabled = g.LogLane.EnableStackTrace(level, enable)
+	for _, m := range g.members {
+		m.EnableStackTrace(level, enable)
+	}
+	return
+}
+
+func (g *laneGroup) EnableStackTraceDepth(level LaneLogLevel, maxFrames int) (priorFrames int) {
+	priorFrames = g.LogLane.EnableStackTraceDepth(level, maxFrames)
+	for _, m := range g.members {
+		m.EnableStackTraceDepth(level, maxFrames)
+	}
+	return
+}
+
+func (g *laneGroup) SetCallerInfo(enable bool) (prior bool) {
+	prior = g.LogLane.SetCallerInfo(enable)
+	for _, m := range g.members {
+		m.SetCallerInfo(enable)
+	}
+	return
+}
+
+func (g *laneGroup) SetErrorHandler(handler ErrorHandler) {
+	g.LogLane.SetErrorHandler(handler)
+	for _, m := range g.members {
+		m.SetErrorHandler(handler)
+	}
+}
+
+func (g *laneGroup) LastError() error {
+	errs := []error{g.LogLane.LastError()}
+	for _, m := range g.members {
+		errs = append(errs, m.LastError())
+	}
+	return errors.Join(errs...)
+}
+
+func (g *laneGroup) CloseWithContext(ctx context.Context) error {
+	errs := []error{g.LogLane.CloseWithContext(ctx)}
+	for _, m := range g.members {
+		errs = append(errs, m.CloseWithContext(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+func (g *laneGroup) treeInfo() laneTreeInfo {
+	if tr, ok := g.LogLane.(treeReporter); ok {
+		return tr.treeInfo()
+	}
+	return laneTreeInfo{}
+}
+
+func (g *laneGroup) Trace(args ...any) { g.TraceInternal(g.LaneProps(), args...) }
+func (g *laneGroup) Tracef(format string, args ...any) {
+	g.TracefInternal(g.LaneProps(), format, args...)
+}
+func (g *laneGroup) TraceObject(message string, obj any) {
+	LogObject(g, LogLevelTrace, message, obj)
+}
+func (g *laneGroup) Debug(args ...any) { g.DebugInternal(g.LaneProps(), args...) }
+func (g *laneGroup) Debugf(format string, args ...any) {
+	g.DebugfInternal(g.LaneProps(), format, args...)
+}
+func (g *laneGroup) DebugObject(message string, obj any) {
+	LogObject(g, LogLevelDebug, message, obj)
+}
+func (g *laneGroup) Info(args ...any) { g.InfoInternal(g.LaneProps(), args...) }
+func (g *laneGroup) Infof(format string, args ...any) {
+	g.InfofInternal(g.LaneProps(), format, args...)
+}
+func (g *laneGroup) InfoObject(message string, obj any) {
+	LogObject(g, LogLevelInfo, message, obj)
+}
+func (g *laneGroup) Warn(args ...any) { g.WarnInternal(g.LaneProps(), args...) }
+func (g *laneGroup) Warnf(format string, args ...any) {
+	g.WarnfInternal(g.LaneProps(), format, args...)
+}
+func (g *laneGroup) WarnObject(message string, obj any) {
+	LogObject(g, LogLevelWarn, message, obj)
+}
+func (g *laneGroup) Error(args ...any) { g.ErrorInternal(g.LaneProps(), args...) }
+func (g *laneGroup) Errorf(format string, args ...any) {
+	g.ErrorfInternal(g.LaneProps(), format, args...)
+}
+func (g *laneGroup) ErrorObject(message string, obj any) {
+	LogObject(g, LogLevelError, message, obj)
+}
+func (g *laneGroup) PreFatal(args ...any) { g.PreFatalInternal(g.LaneProps(), args...) }
+func (g *laneGroup) PreFatalf(format string, args ...any) {
+	g.PreFatalfInternal(g.LaneProps(), format, args...)
+}
+func (g *laneGroup) PreFatalObject(message string, obj any) {
+	LogObject(g, logLevelPreFatal, message, obj)
+}
+func (g *laneGroup) Fatal(args ...any) {
+	g.FatalInternal(g.LaneProps(), fatalArgs(g, args)...)
+	g.OnPanic()
+}
+func (g *laneGroup) Fatalf(format string, args ...any) {
+	format, args = fatalfArgs(g, format, args)
+	g.FatalfInternal(g.LaneProps(), format, args...)
+	g.OnPanic()
+}
+func (g *laneGroup) FatalObject(message string, obj any) {
+	LogObject(g, LogLevelFatal, annotateFatal(g, message), obj)
+}
+
+func (g *laneGroup) LogStack(message string) {
+	g.LogStackTrim(message, 0)
+}
+
+func (g *laneGroup) LogStackTrim(message string, skippedCallers int) {
+	g.LogStackTrimInternal(g.LaneProps(), message, skippedCallers)
+}
+
+func (g *laneGroup) TraceInternal(props loggingProperties, args ...any) {
+	g.broadcast(props, LogLevelTrace, func(teeProps loggingProperties, li laneInternal) { li.TraceInternal(teeProps, args...) })
+}
+func (g *laneGroup) TracefInternal(props loggingProperties, format string, args ...any) {
+	g.broadcast(props, LogLevelTrace, func(teeProps loggingProperties, li laneInternal) { li.TracefInternal(teeProps, format, args...) })
+}
+func (g *laneGroup) DebugInternal(props loggingProperties, args ...any) {
+	g.broadcast(props, LogLevelDebug, func(teeProps loggingProperties, li laneInternal) { li.DebugInternal(teeProps, args...) })
+}
+func (g *laneGroup) DebugfInternal(props loggingProperties, format string, args ...any) {
+	g.broadcast(props, LogLevelDebug, func(teeProps loggingProperties, li laneInternal) { li.DebugfInternal(teeProps, format, args...) })
+}
+func (g *laneGroup) InfoInternal(props loggingProperties, args ...any) {
+	g.broadcast(props, LogLevelInfo, func(teeProps loggingProperties, li laneInternal) { li.InfoInternal(teeProps, args...) })
+}
+func (g *laneGroup) InfofInternal(props loggingProperties, format string, args ...any) {
+	g.broadcast(props, LogLevelInfo, func(teeProps loggingProperties, li laneInternal) { li.InfofInternal(teeProps, format, args...) })
+}
+func (g *laneGroup) WarnInternal(props loggingProperties, args ...any) {
+	g.broadcast(props, LogLevelWarn, func(teeProps loggingProperties, li laneInternal) { li.WarnInternal(teeProps, args...) })
+}
+func (g *laneGroup) WarnfInternal(props loggingProperties, format string, args ...any) {
+	g.broadcast(props, LogLevelWarn, func(teeProps loggingProperties, li laneInternal) { li.WarnfInternal(teeProps, format, args...) })
+}
+func (g *laneGroup) ErrorInternal(props loggingProperties, args ...any) {
+	g.broadcast(props, LogLevelError, func(teeProps loggingProperties, li laneInternal) { li.ErrorInternal(teeProps, args...) })
+}
+func (g *laneGroup) ErrorfInternal(props loggingProperties, format string, args ...any) {
+	g.broadcast(props, LogLevelError, func(teeProps loggingProperties, li laneInternal) { li.ErrorfInternal(teeProps, format, args...) })
+}
+func (g *laneGroup) PreFatalInternal(props loggingProperties, args ...any) {
+	g.broadcast(props, logLevelPreFatal, func(teeProps loggingProperties, li laneInternal) { li.PreFatalInternal(teeProps, args...) })
+}
+func (g *laneGroup) PreFatalfInternal(props loggingProperties, format string, args ...any) {
+	g.broadcast(props, logLevelPreFatal, func(teeProps loggingProperties, li laneInternal) { li.PreFatalfInternal(teeProps, format, args...) })
+}
+func (g *laneGroup) FatalInternal(props loggingProperties, args ...any) {
+	g.PreFatalInternal(props, args...)
+	// panic will occur in a moment in the externally called Fatal
+}
+func (g *laneGroup) FatalfInternal(props loggingProperties, format string, args ...any) {
+	g.PreFatalfInternal(props, format, args...)
+	// panic will occur in a moment in the externally called Fatalf
+}
+
+func (g *laneGroup) LogStackTrimInternal(props loggingProperties, message string, skippedCallers int) {
+	g.broadcast(props, LogLevelStack, func(teeProps loggingProperties, li laneInternal) {
+		li.LogStackTrimInternal(teeProps, message, skippedCallers)
+	})
+}
+
+func (g *laneGroup) ObjectInternal(props loggingProperties, level LaneLogLevel, message string, obj any) {
+	if level == logLevelPreFatal {
+		level = LogLevelFatal
+	}
+	g.broadcast(props, level, func(teeProps loggingProperties, li laneInternal) { li.ObjectInternal(teeProps, level, message, obj) })
+}
+func (g *laneGroup) objectTextInternal(props loggingProperties, level LaneLogLevel, text, message string, obj any) {
+	// the members render the object with their own settings
+	g.ObjectInternal(props, level, message, obj)
+}
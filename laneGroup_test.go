@@ -0,0 +1,183 @@
+package lane
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLaneGroupBroadcast(t *testing.T) {
+	tl1 := NewTestingLane(nil)
+	tl2 := NewTestingLane(nil)
+	g := NewLaneGroup(nil, []Lane{tl1, tl2})
+
+	g.SetLogLevel(LogLevelInfo)
+	g.Debug("hidden")
+	g.Info("hello")
+	g.Warnf("count %d", 3)
+	g.ErrorObject("obj", map[string]int{"a": 1})
+
+	expected := "INFO\thello\nWARN\tcount 3\nERROR\tobj: {\"a\":1}"
+	for i, tl := range []TestingLane{tl1, tl2} {
+		if !tl.VerifyEventText(expected) {
+			t.Errorf("member %d events:\n%s", i, tl.EventsToString())
+		}
+		for _, e := range tl.Events() {
+			if e.Id != g.LaneId() {
+				t.Errorf("member %d received lane ID %s, expected the group's", i, e.Id)
+			}
+		}
+	}
+
+	if members := g.Members(); len(members) != 2 || members[0] != tl1 || members[1] != tl2 {
+		t.Error("unexpected members")
+	}
+}
+
+func TestLaneGroupTeeObjects(t *testing.T) {
+	tl1 := NewTestingLane(nil)
+	tl2 := NewTestingLane(nil)
+	g := NewLaneGroup(nil, []Lane{tl1, tl2})
+	g.SetObjectOptions(LogObjectOpt{TeeObjects: true})
+	tee := NewTestingLane(nil)
+	g.AddTee(tee)
+
+	g.WarnObject("obj", []string{"a"})
+
+	for i, tl := range []TestingLane{tl1, tl2, tee} {
+		events := tl.Events()
+		if len(events) != 1 || events[0].Message != `obj: ["a"]` || events[0].Object == nil {
+			t.Errorf("lane %d events:\n%s", i, tl.EventsToString())
+		}
+	}
+}
+
+func TestLaneGroupSettings(t *testing.T) {
+	tl := NewTestingLane(nil)
+	ll := NewLogLane(nil)
+	g := NewLaneGroup(nil, []Lane{tl, ll})
+
+	g.SetLogLevel(LogLevelWarn)
+	g.SetMetadata("tenant", "acme")
+	g.SetLengthConstraint(12)
+	g.EnableStackTrace(LogLevelError, true)
+
+	for _, m := range g.Members() {
+		if m.SetLogLevel(LogLevelWarn) != LogLevelWarn {
+			t.Errorf("%T: the level was not applied", m)
+		}
+		if m.GetMetadata("tenant") != "acme" {
+			t.Errorf("%T: the metadata was not applied", m)
+		}
+		if m.SetLengthConstraint(12) != 12 {
+			t.Errorf("%T: the length constraint was not applied", m)
+		}
+		if !m.EnableStackTrace(LogLevelError, true) {
+			t.Errorf("%T: the stack trace setting was not applied", m)
+		}
+	}
+}
+
+func TestLaneGroupDerive(t *testing.T) {
+	tl := NewTestingLane(nil)
+	tl.WantDescendantEvents(true)
+	g := NewLaneGroup(nil, []Lane{tl})
+
+	var closed []string
+	g.OnClose(func(l Lane) { closed = append(closed, l.LaneId()) })
+
+	child, cancel := g.DeriveWithCancel()
+	defer cancel()
+	cg, ok := child.(LaneGroup)
+	if !ok || len(cg.Members()) != 1 || cg.Members()[0].Parent() != tl {
+		t.Fatal("the derived lane is not a group of derived members")
+	}
+	if child.Parent().LaneId() != g.LaneId() {
+		t.Error("unexpected parent")
+	}
+
+	child.Info("from the child")
+	if !tl.VerifyEventText("INFO\tfrom the child") || tl.Events()[0].Id != child.LaneId() {
+		t.Errorf("unexpected events:\n%s", tl.EventsToString())
+	}
+
+	child.Close()
+	if len(closed) != 1 || closed[0] != child.LaneId() {
+		t.Errorf("unexpected close hook calls %v", closed)
+	}
+}
+
+func TestLaneGroupTee(t *testing.T) {
+	member := NewTestingLane(nil)
+	tee := NewTestingLane(nil)
+	g := NewLaneGroup(nil, []Lane{member})
+	g.AddTee(tee)
+
+	g.Info("both")
+	if !member.VerifyEventText("INFO\tboth") || !tee.VerifyEventText("INFO\tboth") {
+		t.Error("expected the member and the tee to receive the message")
+	}
+	if len(g.Tees()) != 1 {
+		t.Error("the members are not tees")
+	}
+}
+
+type panicLane struct {
+	TestingLane
+}
+
+func (pl *panicLane) InfoInternal(props loggingProperties, args ...any) {
+	panic("member failure")
+}
+
+func TestLaneGroupErrors(t *testing.T) {
+	tl := NewTestingLane(nil)
+	failing := &panicLane{TestingLane: NewTestingLane(nil)}
+	g := NewLaneGroup(nil, []Lane{failing, tl})
+
+	g.Info("survives")
+	if !tl.VerifyEventText("INFO\tsurvives") {
+		t.Error("a failing member stopped the broadcast")
+	}
+	if err := g.LastError(); err == nil || !strings.Contains(err.Error(), "member failure") {
+		t.Errorf("unexpected error %v", err)
+	}
+	if StatsOf(g).TeeFailures != 1 {
+		t.Error("the member failure was not counted")
+	}
+
+	if err := g.Close(); err != nil {
+		t.Errorf("unexpected close error %v", err)
+	}
+}
+
+func TestLaneGroupCloseErrors(t *testing.T) {
+	failing := &closeFailLane{TestingLane: NewTestingLane(nil)}
+	g := NewLaneGroup(nil, []Lane{failing, NewNullLane(nil)})
+	if err := g.Close(); !errors.Is(err, errCloseFailed) {
+		t.Errorf("unexpected close error %v", err)
+	}
+}
+
+var errCloseFailed = errors.New("close failed")
+
+type closeFailLane struct {
+	TestingLane
+}
+
+func (cfl *closeFailLane) CloseWithContext(ctx context.Context) error {
+	return errCloseFailed
+}
+
+func TestLaneGroupRateLimited(t *testing.T) {
+	tl := NewTestingLane(nil)
+	g := NewLaneGroup(nil, []Lane{tl})
+
+	g.WarnRatef("key", time.Hour, "warning %d", 1)
+	g.WarnRatef("key", time.Hour, "warning %d", 2)
+	if !tl.VerifyEventText("WARN\twarning 1") {
+		t.Errorf("unexpected events:\n%s", tl.EventsToString())
+	}
+}
@@ -2,6 +2,7 @@ package lane
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"errors"
 	"fmt"
@@ -104,6 +105,30 @@ func TestLane(t *testing.T) {
 	}
 }
 
+func TestMetadataInheritanceModes(t *testing.T) {
+	for _, l := range []Lane{NewTestingLane(nil), NewLogLane(nil), NewNullLane(nil)} {
+		l.SetMetadata("tenant", "acme")
+		l.SetMetadataInheritance(MetadataCopy)
+
+		copied := l.Derive()
+		replaced := l.DeriveReplaceContext(nil)
+		l.SetMetadata("later", "x")
+		if copied.GetMetadata("tenant") != "acme" || replaced.GetMetadata("tenant") != "acme" {
+			t.Errorf("%T: the metadata was not copied", l)
+		}
+		if copied.GetMetadata("later") != "" {
+			t.Errorf("%T: the copy must not see later changes", l)
+		}
+
+		l.SetMetadataInheritance(MetadataShared)
+		shared := l.Derive().Derive()
+		l.DeleteMetadata("tenant")
+		if shared.GetMetadata("tenant") != "" || shared.GetMetadata("later") != "x" {
+			t.Errorf("%T: unexpected shared metadata %v", l, shared.MetadataMap())
+		}
+	}
+}
+
 func TestTestingLaneJourneyId(t *testing.T) {
 	tl := NewTestingLane(nil)
 	id := uuid.New().String()
@@ -128,6 +153,94 @@ func TestTestingLaneJourneyIdDerived(t *testing.T) {
 	}
 }
 
+func TestTestingLaneEventsAccess(t *testing.T) {
+	tl := NewTestingLane(nil)
+
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 25 {
+				tl.Infof("worker %d message %d", i, j)
+				_ = tl.Events()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if tl.EventCount() != 100 {
+		t.Errorf("expected 100 events, got %d", tl.EventCount())
+	}
+
+	events := tl.Events()
+	events[0].Message = "changed"
+	if tl.Contains("changed") {
+		t.Error("expected a copy of the events")
+	}
+
+	tl.Reset()
+	if tl.EventCount() != 0 || len(tl.Events()) != 0 {
+		t.Error("expected no events after reset")
+	}
+	tl.Warn("next phase")
+	if !tl.VerifyEventText("WARN\tnext phase") {
+		t.Error("unexpected events after reset")
+	}
+}
+
+func TestTestingLaneMaxEvents(t *testing.T) {
+	tl := NewTestingLane(nil)
+	if prior := tl.SetMaxEvents(3, DropOldest); prior != 0 {
+		t.Errorf("unexpected prior limit %d", prior)
+	}
+	for i := range 5 {
+		tl.Infof("message %d", i)
+	}
+	if !tl.VerifyEventText("INFO\tmessage 2\nINFO\tmessage 3\nINFO\tmessage 4") || tl.DroppedEvents() != 2 {
+		t.Errorf("unexpected events (dropped %d):\n%s", tl.DroppedEvents(), tl.EventsToString())
+	}
+
+	// the limit is inherited, and applies to each lane's own capture
+	child := tl.Derive().(TestingLane)
+	child.SetMaxEvents(2, DropNewest)
+	for i := range 4 {
+		child.Infof("child %d", i)
+	}
+	if !child.VerifyEventText("INFO\tchild 0\nINFO\tchild 1") || child.DroppedEvents() != 2 {
+		t.Errorf("unexpected child events (dropped %d):\n%s", child.DroppedEvents(), child.EventsToString())
+	}
+	if grandchild := child.Derive().(TestingLane); grandchild.SetMaxEvents(0, DropOldest) != 2 {
+		t.Error("the limit was not inherited")
+	}
+
+	// lowering the limit discards the oldest events
+	tl.SetMaxEvents(1, DropOldest)
+	if !tl.VerifyEventText("INFO\tmessage 4") || tl.DroppedEvents() != 4 {
+		t.Errorf("unexpected events (dropped %d):\n%s", tl.DroppedEvents(), tl.EventsToString())
+	}
+
+	tl.Reset()
+	if tl.DroppedEvents() != 0 {
+		t.Error("reset did not clear the dropped count")
+	}
+}
+
+func TestTestingLaneMaxEventsFailTest(t *testing.T) {
+	tl := NewTestingLane(nil)
+	tl.SetMaxEvents(1, FailTest)
+	tl.Info("first")
+
+	defer func() {
+		r := recover()
+		if r == nil || !strings.Contains(fmt.Sprint(r), "exceeded 1 events with: second") {
+			t.Errorf("unexpected panic %v", r)
+		}
+	}()
+	tl.Info("second")
+	t.Error("the limit did not fail the test")
+}
+
 func TestLaneSetLevel(t *testing.T) {
 	tl := NewTestingLane(context.Background())
 
@@ -584,7 +697,7 @@ func TestTestingLaneConstrainedLengthObject(t *testing.T) {
 	tl.InfoObject("bigMap", bigMap)
 
 	ptl := tl.(*testingLane)
-	for _, e := range ptl.Events {
+	for _, e := range ptl.Events() {
 		if len(e.Message) > 22 { // UTF-8 length 20
 			t.Errorf("message %s length %d too long", e.Message, len(e.Message))
 		}
@@ -601,7 +714,7 @@ func TestTestingLaneConstrainedLengthTee(t *testing.T) {
 	tl.LogStack("stack")
 
 	ptl := tl.(*testingLane)
-	for _, e := range ptl.Events {
+	for _, e := range ptl.Events() {
 		if len(e.Message) > 12 { // UTF-8 length 10
 			t.Errorf("message %s length %d too long", e.Message, len(e.Message))
 		}
@@ -609,7 +722,7 @@ func TestTestingLaneConstrainedLengthTee(t *testing.T) {
 
 	ptl = tl2.(*testingLane)
 	exceeded := false
-	for _, e := range ptl.Events {
+	for _, e := range ptl.Events() {
 		if len(e.Message) > 12 {
 			exceeded = true
 		}
@@ -1055,6 +1168,42 @@ func TestLaneDerivedCaptureChild(t *testing.T) {
 	}
 }
 
+func TestLaneDerivedCaptureFiltered(t *testing.T) {
+	ptl := NewTestingLane(nil)
+	ptl.WantDescendantEvents(true, DescendantLevel(LogLevelWarn))
+	child := ptl.Derive().(TestingLane)
+	grandchild := child.Derive()
+
+	ptl.Debug("parent debug")
+	child.Info("child info")
+	child.Warn("child warn")
+	grandchild.Debug("grandchild debug")
+	grandchild.Error("grandchild error")
+
+	if !ptl.VerifyEventText("DEBUG\tparent debug\nWARN\tchild warn\nERROR\tgrandchild error") {
+		t.Errorf("unexpected events:\n%s", ptl.EventsToString())
+	}
+	if !child.VerifyEventText("INFO\tchild info\nWARN\tchild warn\nERROR\tgrandchild error") {
+		t.Errorf("unexpected child events:\n%s", child.EventsToString())
+	}
+
+	ptl.Reset()
+	ptl.WantDescendantEvents(true, DescendantLevel(LogLevelInfo), DescendantMatch(regexp.MustCompile(`^order \d+$`)))
+	child.Info("order 12")
+	child.Info("order twelve")
+	child.Debug("order 13")
+	if !ptl.VerifyEventText("INFO\torder 12") {
+		t.Errorf("unexpected events:\n%s", ptl.EventsToString())
+	}
+
+	ptl.Reset()
+	ptl.WantDescendantEvents(true)
+	child.Debug("anything")
+	if !ptl.VerifyEventText("DEBUG\tanything") {
+		t.Errorf("unexpected events:\n%s", ptl.EventsToString())
+	}
+}
+
 func TestLaneDerivedCaptureGrandchild(t *testing.T) {
 	gptl := NewTestingLane(context.Background())
 	ptl := gptl.Derive().(TestingLane)
@@ -1163,6 +1312,68 @@ func TestLogLaneJourneyIdDerived(t *testing.T) {
 	}
 }
 
+func TestLogLaneLogsWithoutLock(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	ll := NewLogLane(nil)
+	tl := NewTestingLane(nil)
+	ll.AddTee(tl)
+
+	// a lane reconfigured by another goroutine doesn't hold up logging
+	inner := ll.(*logLane)
+	inner.mu.Lock()
+	done := make(chan struct{})
+	go func() {
+		ll.Info("logged")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Error("logging waited for the lane's lock")
+	}
+	inner.mu.Unlock()
+
+	if !strings.Contains(buf.String(), "logged") || !tl.VerifyEventText("INFO\tlogged") {
+		t.Errorf("unexpected output %q, events:\n%s", buf.String(), tl.EventsToString())
+	}
+}
+
+func TestLogLaneConcurrentChanges(t *testing.T) {
+	skipInRelease(t)
+
+	ll := NewLogLane(nil)
+	ll.SetLogLevel(LogLevelInfo)
+	tl := NewTestingLane(nil)
+	ll.AddTee(tl)
+
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 50 {
+				ll.Debugf("worker %d message %d", i, j)
+			}
+		}()
+	}
+	for j := range 50 {
+		extra := NewNullLane(nil)
+		ll.AddTee(extra)
+		ll.SetJourneyId(fmt.Sprint("journey", j))
+		ll.(LogLane).SetFlagsMask(j % 2)
+		ll.(LogLane).SetLineEnding(LineEnding(j % 2))
+		ll.RemoveTee(extra)
+	}
+	wg.Wait()
+
+	if tl.EventCount() != 200 || len(ll.Tees()) != 1 {
+		t.Errorf("expected 200 events and 1 tee, got %d and %d", tl.EventCount(), len(ll.Tees()))
+	}
+}
+
 func TestLogLaneSetLevel(t *testing.T) {
 	ll := NewLogLane(context.Background())
 
@@ -1796,7 +2007,7 @@ func TestConstrainedLengthTee(t *testing.T) {
 
 	ptl := tl.(*testingLane)
 	exceeded := false
-	for _, e := range ptl.Events {
+	for _, e := range ptl.Events() {
 		if len(e.Message) > 10 {
 			exceeded = true
 		}
@@ -1897,6 +2108,8 @@ func TestLogLaneConstrainedLengthInherit(t *testing.T) {
 }
 
 func TestLogLaneVerifyText(t *testing.T) {
+	skipInRelease(t)
+
 	var buf bytes.Buffer
 	log.SetOutput(&buf)
 	defer func() { log.SetOutput(os.Stderr) }()
@@ -1933,6 +2146,8 @@ ERROR {GUID} testing 1213`
 }
 
 func TestLogLaneVerifyTextCrLf(t *testing.T) {
+	skipInRelease(t)
+
 	var buf bytes.Buffer
 	log.SetOutput(&buf)
 	defer func() { log.SetOutput(os.Stderr) }()
@@ -1972,6 +2187,8 @@ func TestLogLaneVerifyTextCrLf(t *testing.T) {
 }
 
 func TestLogLaneVerifyTextFilterTrace(t *testing.T) {
+	skipInRelease(t)
+
 	var buf bytes.Buffer
 	log.SetOutput(&buf)
 	defer func() { log.SetOutput(os.Stderr) }()
@@ -2128,6 +2345,8 @@ func TestLogLaneVerifyTextFilterFatal(t *testing.T) {
 }
 
 func TestLogLaneVerifyCancel(t *testing.T) {
+	skipInRelease(t)
+
 	var buf bytes.Buffer
 	log.SetOutput(&buf)
 	defer func() { log.SetOutput(os.Stderr) }()
@@ -2156,6 +2375,8 @@ func TestLogLaneVerifyCancel(t *testing.T) {
 }
 
 func TestLogLaneVerifyTimeout(t *testing.T) {
+	skipInRelease(t)
+
 	var buf bytes.Buffer
 	log.SetOutput(&buf)
 	defer func() { log.SetOutput(os.Stderr) }()
@@ -2184,6 +2405,8 @@ func TestLogLaneVerifyTimeout(t *testing.T) {
 }
 
 func TestLogLaneVerifyDeadline(t *testing.T) {
+	skipInRelease(t)
+
 	var buf bytes.Buffer
 	log.SetOutput(&buf)
 	defer func() { log.SetOutput(os.Stderr) }()
@@ -2835,6 +3058,216 @@ func TestDiskLane(t *testing.T) {
 	os.Remove("test.log")
 }
 
+func TestDiskLaneSharedFile(t *testing.T) {
+	path := t.TempDir() + "/shared.log"
+	dl, err := NewDiskLane(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dl2 := dl.Derive()
+	dl3 := dl2.Derive()
+
+	// the file stays open while any derived lane is open
+	dl.Close()
+	dl2.Close()
+	dl3.Info("after parent close")
+	if dl3.LastError() != nil {
+		t.Errorf("unexpected error %v", dl3.LastError())
+	}
+
+	dl3.Close()
+	dl3.Info("after last close")
+	if !errors.Is(dl3.LastError(), errDiskFileClosed) {
+		t.Errorf("expected closed file error, got %v", dl3.LastError())
+	}
+
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := string(bytes); !strings.Contains(text, "after parent close\n") || strings.Contains(text, "after last close") {
+		t.Errorf("incorrect contents of disk log file: %s", text)
+	}
+}
+
+func TestDiskLaneReopenFile(t *testing.T) {
+	path := t.TempDir() + "/rotate.log"
+	dl, err := NewDiskLane(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dl.Close()
+
+	child := dl.Derive()
+	defer child.Close()
+
+	dl.Info("before rotation")
+	if err = os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err = dl.(DiskLane).ReopenFile(); err != nil {
+		t.Fatal(err)
+	}
+	child.Info("after rotation")
+
+	rotated, _ := os.ReadFile(path + ".1")
+	current, _ := os.ReadFile(path)
+	if !strings.Contains(string(rotated), "before rotation") || strings.Contains(string(rotated), "after rotation") {
+		t.Errorf("incorrect contents of rotated file: %s", rotated)
+	}
+	if !strings.Contains(string(current), "after rotation") {
+		t.Errorf("incorrect contents of reopened file: %s", current)
+	}
+}
+
+func TestDiskLaneWriteBuffer(t *testing.T) {
+	path := t.TempDir() + "/buffered.log"
+	dl, err := NewDiskLane(context.Background(), path, WithWriteBuffer(4096, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fileText := func() string {
+		bytes, _ := os.ReadFile(path)
+		return string(bytes)
+	}
+
+	dl.Info("buffered")
+	if strings.Contains(fileText(), "buffered") {
+		t.Error("expected the message to be buffered")
+	}
+	if err = dl.(DiskLane).Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(fileText(), "buffered") {
+		t.Error("expected the message to be flushed")
+	}
+
+	// a fatal error is flushed before the panic handler
+	child := dl.Derive()
+	child.SetPanicHandler(func() {
+		if !strings.Contains(fileText(), "the end") {
+			t.Error("expected the fatal message to be flushed")
+		}
+	})
+	child.Fatal("the end")
+
+	dl.Info("closing")
+	dl.Close()
+	if !strings.Contains(fileText(), "closing") {
+		t.Error("expected the message to be flushed on close")
+	}
+	child.Close()
+}
+
+func TestDiskLaneFlushInterval(t *testing.T) {
+	path := t.TempDir() + "/interval.log"
+	dl, err := NewDiskLane(context.Background(), path, WithWriteBuffer(4096, 10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dl.Close()
+
+	dl.Info("eventually")
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		bytes, _ := os.ReadFile(path)
+		if strings.Contains(string(bytes), "eventually") {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Error("expected the periodic flush")
+}
+
+func readGzipLog(t *testing.T, path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a flushed stream that isn't complete yet ends unexpectedly
+	bytes, err := io.ReadAll(gz)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		t.Fatal(err)
+	}
+	return string(bytes)
+}
+
+func TestGzipLane(t *testing.T) {
+	skipInRelease(t)
+
+	path := t.TempDir() + "/compressed.log.gz"
+	gl, err := NewGzipLane(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	child := gl.Derive()
+	for i := range 100 {
+		child.Debugf("repetitive debug message %d", i)
+	}
+	gl.Close()
+
+	// readable once flushed, before the stream is complete
+	if err = gl.(DiskLane).Flush(); err != nil {
+		t.Fatal(err)
+	}
+	child.Close()
+
+	text := readGzipLog(t, path)
+	if strings.Count(text, "repetitive debug message") != 100 || !strings.Contains(text, "message 99\n") {
+		t.Errorf("incorrect contents of gzip log file: %s", text)
+	}
+
+	info, _ := os.Stat(path)
+	if info.Size()*4 > int64(len(text)) {
+		t.Errorf("expected compression, got %d bytes for %d", info.Size(), len(text))
+	}
+
+	// appending adds a gzip member
+	gl, err = NewGzipLane(context.Background(), path, WithWriteBuffer(1024, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gl.Info("appended")
+	gl.Close()
+
+	text = readGzipLog(t, path)
+	if !strings.Contains(text, "message 99\n") || !strings.HasSuffix(text, "appended\n") {
+		t.Errorf("incorrect contents of appended gzip log file: %s", text)
+	}
+}
+
+func TestGzipLaneReopen(t *testing.T) {
+	path := t.TempDir() + "/rotate.log.gz"
+	gl, err := NewGzipLane(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gl.Close()
+
+	gl.Info("first file")
+	os.Rename(path, path+".1")
+	if err = gl.(DiskLane).ReopenFile(); err != nil {
+		t.Fatal(err)
+	}
+	gl.Info("second file")
+	gl.(DiskLane).Flush()
+
+	if text := readGzipLog(t, path+".1"); !strings.Contains(text, "first file") {
+		t.Errorf("incorrect contents of rotated file: %s", text)
+	}
+	if text := readGzipLog(t, path); !strings.Contains(text, "second file") || strings.Contains(text, "first file") {
+		t.Errorf("incorrect contents of reopened file: %s", text)
+	}
+}
+
 func TestDiskLaneStack(t *testing.T) {
 	os.Remove("test.log")
 
@@ -3249,3 +3682,193 @@ func TestNullLaneParent(t *testing.T) {
 		t.Error("root parent not nil")
 	}
 }
+
+func TestNullLaneStrict(t *testing.T) {
+	nl := NewNullLane(nil, StrictNull(LogLevelError))
+	nl.Warn("allowed")
+	nl.Infof("allowed %d", 1)
+
+	defer func() {
+		r := recover()
+		if r == nil || !strings.Contains(fmt.Sprint(r), "ERROR message: unexpected 2") {
+			t.Errorf("unexpected panic %v", r)
+		}
+	}()
+	nl.Derive().Errorf("unexpected %d", 2)
+	t.Error("expected a panic")
+}
+
+func TestNullLaneStrictFunc(t *testing.T) {
+	var received []Record
+	nl := NewNullLane(nil, StrictNullFunc(LogLevelWarn, func(rec Record) {
+		received = append(received, rec)
+	}))
+	nl.SetJourneyId("trip")
+	nl.Info("ignored")
+	nl.Warn("first")
+
+	// messages sent by a tee are asserted too
+	ll := NewLogLane(nil)
+	ll.SetLogLevel(LogLevelFatal)
+	ll.AddTee(nl)
+	ll.Error("second")
+
+	if len(received) != 2 {
+		t.Fatalf("unexpected records %+v", received)
+	}
+	if received[0].Message != "first" || received[0].Level != LogLevelWarn || received[0].LaneId != nl.LaneId() || received[0].JourneyId != "trip" {
+		t.Errorf("unexpected record %+v", received[0])
+	}
+	if received[1].Message != "second" || received[1].LaneId != ll.LaneId() {
+		t.Errorf("unexpected record %+v", received[1])
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	for _, l := range []Lane{NewTestingLane(nil), NewLogLane(nil), NewNullLane(nil), NewRingBufferLane(nil, 4)} {
+		ctx, cancel := context.WithTimeout(context.WithValue(l, ParentLaneIdKey, "x"), time.Minute)
+		if found, ok := FromContext(ctx); !ok || found != l {
+			t.Errorf("%T: the lane was not found", l)
+		}
+		if found, ok := FromContext(l); !ok || found != l {
+			t.Errorf("%T: expected the lane itself", l)
+		}
+		cancel()
+	}
+
+	if _, found := FromContext(context.Background()); found {
+		t.Error("unexpected lane")
+	}
+	if _, found := FromContext(nil); found {
+		t.Error("unexpected lane")
+	}
+}
+
+func TestConstructorSettings(t *testing.T) {
+	for _, create := range []func(opts ...LaneOption) Lane{
+		func(opts ...LaneOption) Lane { return NewTestingLane(nil, opts...) },
+		func(opts ...LaneOption) Lane { return NewLogLane(nil, opts...) },
+		func(opts ...LaneOption) Lane { return NewNullLane(nil, opts...) },
+		func(opts ...LaneOption) Lane { return NewRingBufferLane(nil, 4, opts...) },
+	} {
+		tee1 := NewTestingLane(nil)
+		tee2 := NewTestingLane(nil)
+		l := create(WithLevel(LogLevelWarn), WithJourneyId("j1"), WithTee(tee1), WithTee(tee2), WithMaxLength(10))
+
+		state := l.ConfigSnapshot()
+		if state.Level != LogLevelWarn || state.MaxLength != 10 || l.JourneyId() != "j1" {
+			t.Errorf("%T: the settings were not made: %+v", l, state)
+		}
+		tees := l.Tees()
+		if len(tees) != 2 || tees[0] != tee1 || tees[1] != tee2 {
+			t.Errorf("%T: unexpected tees %v", l, tees)
+		}
+	}
+
+	tl := NewTestingLane(nil, WithLevel(LogLevelWarn), WithMaxLength(10))
+	tl.Info("not logged")
+	tl.Warn("a long warning")
+	if !tl.VerifyEventText("WARN\ta long wa…") {
+		t.Errorf("unexpected events:\n%s", tl.EventsToString())
+	}
+}
+
+func TestConstructorWithCR(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer func() { log.SetOutput(os.Stderr) }()
+
+	NewLogLane(nil, WithCR()).Info("with cr")
+	NewTestingLane(nil, WithCR()).Info("ignored")
+
+	if !strings.HasSuffix(buf.String(), "with cr\r\n") {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+}
+
+func TestFatalContextCause(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer func() { log.SetOutput(os.Stderr) }()
+
+	for _, l := range []Lane{NewTestingLane(nil), NewLogLane(nil), NewNullLane(nil)} {
+		tl := NewTestingLane(nil)
+		l.AddTee(tl)
+		l.SetPanicHandler(func() {})
+
+		l.Fatal("still running")
+		child, cancel := l.DeriveWithTimeoutCause(0, errors.New("request budget exhausted"))
+		child.Fatal("query failed")
+		child.Fatalf("query %d failed", 2)
+		child.FatalObject("state", map[string]int{"n": 1})
+		cancel()
+		canceled, cancelCause := l.DeriveWithCancelCause()
+		cancelCause(nil)
+		canceled.Fatal("interrupted")
+
+		expected := "FATAL\tstill running\n" +
+			"FATAL\tquery failed (context deadline exceeded, cause: request budget exhausted)\n" +
+			"FATAL\tquery 2 failed (context deadline exceeded, cause: request budget exhausted)\n" +
+			"FATAL\tstate (context deadline exceeded, cause: request budget exhausted): {\"n\":1}\n" +
+			"FATAL\tinterrupted (context canceled)"
+		if !tl.VerifyEventText(expected) {
+			t.Errorf("%T: unexpected events:\n%s", l, tl.EventsToString())
+		}
+	}
+}
+
+func TestFatalContextCauseStack(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer func() { log.SetOutput(os.Stderr) }()
+
+	ll := NewLogLane(nil, WithEncoder(JSONEncoder{}))
+	ll.SetPanicHandler(func() {})
+	ll.EnableStackTrace(LogLevelFatal, true)
+
+	ctx, cancel := context.WithCancelCause(ll)
+	cancel(errors.New("shutting down"))
+	ll.DeriveReplaceContext(ctx).Fatal("failed")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], `"level":"STACK","laneId"`) ||
+		!strings.Contains(lines[1], `"message":"context canceled, cause: shutting down"`) {
+		t.Errorf("unexpected output:\n%s", buf.String())
+	}
+}
+
+func TestDeriveWithMaxTimeout(t *testing.T) {
+	skipInRelease(t)
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	for _, l := range []Lane{NewTestingLane(nil), NewLogLane(nil), NewNullLane(nil)} {
+		tl := NewTestingLane(nil)
+		l.AddTee(tl)
+
+		child, cancel := l.DeriveWithMaxTimeout(time.Minute)
+		defer cancel()
+		deadline, ok := child.Deadline()
+		if !ok || time.Until(deadline) > time.Minute {
+			t.Errorf("%T: unexpected deadline %v", l, deadline)
+		}
+
+		short, cancelShort := l.DeriveWithTimeout(time.Hour)
+		defer cancelShort()
+		parentDeadline, _ := short.Deadline()
+		grandchild, cancelGrandchild := short.DeriveWithMaxTimeout(2 * time.Hour)
+		defer cancelGrandchild()
+		if deadline, _ := grandchild.Deadline(); !deadline.Equal(parentDeadline) {
+			t.Errorf("%T: expected the parent's deadline, got %v", l, deadline)
+		}
+
+		events := tl.Events()
+		if len(events) != 2 || events[0].Message != "deadline: the maximum timeout 1m0s applies" ||
+			!strings.HasPrefix(events[1].Message, "deadline: the parent's remaining ") ||
+			!strings.HasSuffix(events[1].Message, " is within the maximum timeout 2h0m0s") {
+			t.Errorf("%T: unexpected events:\n%s", l, tl.EventsToString())
+		}
+	}
+}
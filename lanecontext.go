@@ -0,0 +1,82 @@
+package lane
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type (
+	// Context key that lanes answer with themselves
+	laneContextKey struct{}
+)
+
+// Finds the lane that [ctx] is, or that [ctx] was derived from, such as with
+// context.WithTimeout(), so that code receiving only a context, such as a
+// database driver or an HTTP transport, can log to the caller's lane.
+func FromContext(ctx context.Context) (l Lane, found bool) {
+	if ctx == nil {
+		return
+	}
+	if l, found = ctx.(Lane); found {
+		return
+	}
+	l, found = ctx.Value(laneContextKey{}).(Lane)
+	return
+}
+
+// Describes why [ctx] is done, such as "context deadline exceeded", with the
+// cause given to its cancel function, or provides "" if it isn't done
+func contextDoneReason(ctx context.Context) string {
+	err := ctx.Err()
+	if err == nil {
+		return ""
+	}
+	reason := err.Error()
+	if cause := context.Cause(ctx); cause != nil && cause != err {
+		reason += ", cause: " + cause.Error()
+	}
+	return reason
+}
+
+// Appends the reason the context is done to a fatal message, because a
+// post-mortem usually needs to know if a timeout led to the failure
+func annotateFatal(ctx context.Context, text string) string {
+	if reason := contextDoneReason(ctx); reason != "" {
+		return text + " (" + reason + ")"
+	}
+	return text
+}
+
+// Provides the arguments of Fatal() with the context annotation
+func fatalArgs(ctx context.Context, args []any) []any {
+	if ctx.Err() == nil {
+		return args
+	}
+	return []any{annotateFatal(ctx, sprint(args...))}
+}
+
+// Provides the arguments of Fatalf() with the context annotation
+func fatalfArgs(ctx context.Context, format string, args []any) (string, []any) {
+	if ctx.Err() == nil {
+		return format, args
+	}
+	return "%s", []any{annotateFatal(ctx, fmt.Sprintf(format, args...))}
+}
+
+// Derives a lane from [l] whose deadline is the earlier of [l]'s deadline
+// and [maxTimeout] from now, logging the bound that applied at DEBUG on the
+// derived lane
+func deriveWithMaxTimeout(l Lane, maxTimeout time.Duration) (Lane, context.CancelFunc) {
+	if deadline, ok := l.Deadline(); ok {
+		if remaining := time.Until(deadline); remaining <= maxTimeout {
+			child, cancel := l.DeriveWithDeadline(deadline)
+			child.Debugf("deadline: the parent's remaining %v is within the maximum timeout %v", remaining, maxTimeout)
+			return child, cancel
+		}
+	}
+
+	child, cancel := l.DeriveWithTimeout(maxTimeout)
+	child.Debugf("deadline: the maximum timeout %v applies", maxTimeout)
+	return child, cancel
+}
@@ -0,0 +1,123 @@
+package lane
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type (
+	// Function that makes a new lane ID
+	LaneIdGenerator func() string
+
+	LaneIdFormat int
+)
+
+const (
+	// 36 character UUID, ex: 0a3d2c1e-7a4b-4fd0-9b1e-6c2f1a8e9d35 (the default)
+	LaneIdUuid LaneIdFormat = iota
+	// 10 hex characters, matching the width shown in log lane output
+	LaneIdShort
+	// 26 character ULID, lexically sortable by creation time
+	LaneIdUlid
+	// 10 digit monotonic counter starting at 1, for deterministic tests
+	LaneIdCounter
+)
+
+var (
+	laneIdMu  sync.Mutex
+	laneIdGen LaneIdGenerator = UuidLaneId
+)
+
+const crockfordBase32 = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// Replaces the package-level lane ID generator, used by lanes that are not
+// constructed with WithLaneIdFormat. Passing nil restores the UUID generator.
+// Returns the prior generator.
+func SetLaneIdGenerator(gen LaneIdGenerator) (prior LaneIdGenerator) {
+	laneIdMu.Lock()
+	defer laneIdMu.Unlock()
+
+	prior = laneIdGen
+	if gen == nil {
+		gen = UuidLaneId
+	}
+	laneIdGen = gen
+	return
+}
+
+// Makes a random UUID lane ID.
+func UuidLaneId() string {
+	return uuid.New().String()
+}
+
+// Makes a random 10 hex character lane ID.
+func ShortLaneId() string {
+	var b [5]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// Makes a ULID lane ID (48-bit millisecond timestamp and 80 random bits, in
+// Crockford base32).
+func UlidLaneId() string {
+	var b [16]byte
+	ms := uint64(time.Now().UnixMilli())
+	for i := 5; i >= 0; i-- {
+		b[i] = byte(ms)
+		ms >>= 8
+	}
+	rand.Read(b[6:])
+
+	// 128 bits encoded as 26 characters, the first character holding 3 bits
+	var out [26]byte
+	hi := uint64(b[0])<<56 | uint64(b[1])<<48 | uint64(b[2])<<40 | uint64(b[3])<<32 |
+		uint64(b[4])<<24 | uint64(b[5])<<16 | uint64(b[6])<<8 | uint64(b[7])
+	lo := uint64(b[8])<<56 | uint64(b[9])<<48 | uint64(b[10])<<40 | uint64(b[11])<<32 |
+		uint64(b[12])<<24 | uint64(b[13])<<16 | uint64(b[14])<<8 | uint64(b[15])
+	for i := 25; i >= 0; i-- {
+		out[i] = crockfordBase32[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// Makes a generator of sequential lane IDs "0000000001", "0000000002", etc.
+func NewCounterLaneIdGenerator() LaneIdGenerator {
+	var counter atomic.Int64
+	return func() string {
+		return fmt.Sprintf("%010d", counter.Add(1))
+	}
+}
+
+func (format LaneIdFormat) generator() LaneIdGenerator {
+	switch format {
+	case LaneIdUuid:
+		return UuidLaneId
+	case LaneIdShort:
+		return ShortLaneId
+	case LaneIdUlid:
+		return UlidLaneId
+	case LaneIdCounter:
+		return NewCounterLaneIdGenerator()
+	default:
+		panic("invalid lane id format")
+	}
+}
+
+// Makes a lane ID with the lane's generator, or the package-level generator
+// if the lane doesn't have one.
+func makeLaneId(gen LaneIdGenerator) string {
+	if gen == nil {
+		laneIdMu.Lock()
+		gen = laneIdGen
+		laneIdMu.Unlock()
+	}
+	return gen()
+}
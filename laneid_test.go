@@ -0,0 +1,98 @@
+package lane
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestLaneIdFormats(t *testing.T) {
+	ulidExp := regexp.MustCompile(`^[0-9A-HJKMNP-TV-Z]{26}$`)
+	shortExp := regexp.MustCompile(`^[0-9a-f]{10}$`)
+
+	if len(UuidLaneId()) != 36 {
+		t.Error("wrong uuid length")
+	}
+	if id := ShortLaneId(); !shortExp.MatchString(id) {
+		t.Errorf("wrong short id %s", id)
+	}
+	if id := UlidLaneId(); !ulidExp.MatchString(id) {
+		t.Errorf("wrong ulid %s", id)
+	}
+
+	gen := NewCounterLaneIdGenerator()
+	if gen() != "0000000001" || gen() != "0000000002" {
+		t.Error("counter not sequential")
+	}
+}
+
+func TestLaneIdFormatOption(t *testing.T) {
+	tl := NewTestingLane(context.Background(), WithLaneIdFormat(LaneIdCounter))
+	tl2 := tl.Derive()
+	tl3, cancel := tl2.DeriveWithCancel()
+	defer cancel()
+
+	if tl.LaneId() != "0000000001" || tl2.LaneId() != "0000000002" || tl3.LaneId() != "0000000003" {
+		t.Errorf("unexpected ids %s %s %s", tl.LaneId(), tl2.LaneId(), tl3.LaneId())
+	}
+
+	ll := NewLogLane(nil, WithLaneIdFormat(LaneIdCounter))
+	ll2 := ll.Derive()
+	if ll.LaneId() != "0000000001" || ll2.LaneId() != "0000000002" {
+		t.Errorf("unexpected log lane ids %s %s", ll.LaneId(), ll2.LaneId())
+	}
+
+	nl := NewNullLane(nil, WithLaneIdFormat(LaneIdShort))
+	nl2 := nl.DeriveReplaceContext(context.Background())
+	if len(nl.LaneId()) != 10 || len(nl2.LaneId()) != 10 {
+		t.Error("unexpected null lane ids")
+	}
+}
+
+func TestSetLaneIdGenerator(t *testing.T) {
+	prior := SetLaneIdGenerator(NewCounterLaneIdGenerator())
+	defer SetLaneIdGenerator(prior)
+
+	tl := NewTestingLane(nil)
+	nl := NewNullLane(nil)
+	if tl.LaneId() != "0000000001" || nl.LaneId() != "0000000002" {
+		t.Errorf("unexpected ids %s %s", tl.LaneId(), nl.LaneId())
+	}
+
+	// a constructor option takes precedence
+	ll := NewLogLane(nil, WithLaneIdFormat(LaneIdUlid))
+	if len(ll.LaneId()) != 26 {
+		t.Errorf("unexpected id %s", ll.LaneId())
+	}
+
+	SetLaneIdGenerator(nil)
+	if len(NewNullLane(nil).LaneId()) != 36 {
+		t.Error("uuid generator not restored")
+	}
+}
+
+func TestLaneIdOutput(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	ll := NewLogLane(context.Background(), WithLaneIdFormat(LaneIdUlid))
+	child := ll.Derive()
+	child.(LogLane).SetParentIdOutput(true)
+	child.Info("ulid")
+
+	ul := NewLogLane(context.Background())
+	ul.Info("uuid")
+
+	output := buf.String()
+	if !strings.Contains(output, "{"+ll.LaneId()+"→"+child.LaneId()+"} ulid") {
+		t.Errorf("full ulid not logged: %s", output)
+	}
+	if !strings.Contains(output, "{"+ul.LaneId()[26:]+"} uuid") {
+		t.Errorf("uuid not trimmed: %s", output)
+	}
+}
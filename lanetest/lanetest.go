@@ -0,0 +1,402 @@
+// Package lanetest verifies that a lane implementation behaves like the lanes
+// of the lane package.
+package lanetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jimsnab/go-lane"
+)
+
+type (
+	conformanceKey string
+)
+
+// Runs the conformance checks as subtests of [t]. The [factory] makes a new
+// root lane of the implementation for each check; the lanes are closed when
+// the check ends.
+//
+// The checks cover derivation, tees, log levels, stack trace settings,
+// metadata, length constraint inheritance, lifecycle hooks and context
+// behavior. Messages are observed through a tee, so the lane's own output
+// format is not checked.
+func RunLaneConformanceTests(t *testing.T, factory func() lane.Lane) {
+	checks := []struct {
+		name  string
+		check func(t *testing.T, l lane.Lane)
+	}{
+		{"LaneId", checkLaneId},
+		{"Derive", checkDerive},
+		{"JourneyId", checkJourneyId},
+		{"Tees", checkTees},
+		{"TeeLevels", checkTeeLevels},
+		{"LogLevel", checkLogLevel},
+		{"StackTrace", checkStackTrace},
+		{"Metadata", checkMetadata},
+		{"MetadataInheritance", checkMetadataInheritance},
+		{"Constraints", checkConstraints},
+		{"Lifecycle", checkLifecycle},
+		{"CancelContext", checkCancelContext},
+		{"DeadlineContext", checkDeadlineContext},
+		{"ReplaceContext", checkReplaceContext},
+	}
+
+	for _, c := range checks {
+		t.Run(c.name, func(t *testing.T) {
+			l := factory()
+			if l == nil {
+				t.Fatal("factory returned a nil lane")
+			}
+			t.Cleanup(func() { l.Close() })
+			c.check(t, l)
+		})
+	}
+}
+
+// Makes a tee that retains the messages it receives along with the lane IDs
+// they were sent with
+func newRecorder() lane.RingBufferLane {
+	return lane.NewRingBufferLane(nil, 64)
+}
+
+func checkLaneId(t *testing.T, l lane.Lane) {
+	if l.LaneId() == "" {
+		t.Fatal("empty lane ID")
+	}
+	if l.LaneId() != l.LaneId() {
+		t.Error("lane ID is not stable")
+	}
+	if l.Parent() != nil {
+		t.Error("a root lane must not have a parent")
+	}
+}
+
+func checkDerive(t *testing.T, l lane.Lane) {
+	derivations := map[string]func() lane.Lane{
+		"Derive":              l.Derive,
+		"DeriveWithoutCancel": l.DeriveWithoutCancel,
+		"DeriveWithCancel": func() lane.Lane {
+			child, cancel := l.DeriveWithCancel()
+			t.Cleanup(cancel)
+			return child
+		},
+		"DeriveWithTimeout": func() lane.Lane {
+			child, cancel := l.DeriveWithTimeout(time.Hour)
+			t.Cleanup(cancel)
+			return child
+		},
+	}
+
+	for name, derive := range derivations {
+		child := derive()
+		if child == nil {
+			t.Errorf("%s returned nil", name)
+			continue
+		}
+		if child.LaneId() == "" || child.LaneId() == l.LaneId() {
+			t.Errorf("%s: the derived lane needs its own ID", name)
+		}
+		if child.Parent() == nil || child.Parent().LaneId() != l.LaneId() {
+			t.Errorf("%s: the derived lane's parent is not the lane it was derived from", name)
+		}
+		child.Close()
+	}
+}
+
+func checkJourneyId(t *testing.T, l lane.Lane) {
+	l.SetJourneyId("journey")
+	if l.JourneyId() != "journey" {
+		t.Errorf("expected journey ID %q, got %q", "journey", l.JourneyId())
+	}
+
+	child := l.Derive()
+	defer child.Close()
+	if child.JourneyId() != "journey" {
+		t.Errorf("the derived lane did not inherit the journey ID, got %q", child.JourneyId())
+	}
+}
+
+func checkTees(t *testing.T, l lane.Lane) {
+	rec := newRecorder()
+	l.AddTee(rec)
+	if tees := l.Tees(); len(tees) != 1 || tees[0].LaneId() != rec.LaneId() {
+		t.Fatalf("unexpected tee list %v", tees)
+	}
+
+	l.SetJourneyId("trip")
+	l.Info("info message")
+	l.Warnf("warn %d", 1)
+	child := l.Derive()
+	defer child.Close()
+	child.Error("from the child")
+
+	events := rec.Events()
+	expected := []struct {
+		level   lane.LaneLogLevel
+		message string
+		laneId  string
+	}{
+		{lane.LogLevelInfo, "info message", l.LaneId()},
+		{lane.LogLevelWarn, "warn 1", l.LaneId()},
+		{lane.LogLevelError, "from the child", child.LaneId()},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d messages through the tee, got %d: %v", len(expected), len(events), events)
+	}
+	for i, e := range expected {
+		actual := events[i]
+		if actual.Level != e.level || actual.Message != e.message {
+			t.Errorf("message %d: expected %q at level %d, got %q at level %d", i, e.message, e.level, actual.Message, actual.Level)
+		}
+		if actual.LaneId != e.laneId {
+			t.Errorf("message %d: the tee must receive the sender's lane ID", i)
+		}
+		if actual.JourneyId != "trip" {
+			t.Errorf("message %d: the tee must receive the sender's journey ID", i)
+		}
+	}
+
+	l.RemoveTee(rec)
+	l.Info("after removal")
+	if len(l.Tees()) != 0 || len(rec.Events()) != len(expected) {
+		t.Error("the tee received a message after it was removed")
+	}
+}
+
+func checkTeeLevels(t *testing.T, l lane.Lane) {
+	// the sender's level filters its own output, not what it sends to tees
+	l.SetLogLevel(lane.LogLevelError)
+	rec := newRecorder()
+	l.AddTee(rec)
+
+	l.Trace("trace")
+	l.Debugf("debug %s", "f")
+	l.Info("info")
+
+	// a lane_release build may strip the trace and debug messages
+	events := rec.Events()
+	if len(events) != 3 && !(lane.ReleaseBuild && len(events) == 1 && events[0].Message == "info") {
+		t.Errorf("expected the tee to receive every message, got %v", events)
+	}
+}
+
+func checkLogLevel(t *testing.T, l lane.Lane) {
+	l.SetLogLevel(lane.LogLevelWarn)
+	if prior := l.SetLogLevel(lane.LogLevelDebug); prior != lane.LogLevelWarn {
+		t.Errorf("SetLogLevel returned prior level %d, expected %d", prior, lane.LogLevelWarn)
+	}
+
+	child := l.Derive()
+	defer child.Close()
+	if prior := child.SetLogLevel(lane.LogLevelInfo); prior != lane.LogLevelDebug {
+		t.Errorf("the derived lane did not inherit the log level, got %d", prior)
+	}
+}
+
+func checkStackTrace(t *testing.T, l lane.Lane) {
+	if l.EnableStackTrace(lane.LogLevelError, true) {
+		t.Error("stack traces should be off for ERROR by default")
+	}
+	if !l.EnableStackTrace(lane.LogLevelError, true) {
+		t.Error("EnableStackTrace did not report the prior setting")
+	}
+	l.EnableStackTraceDepth(lane.LogLevelWarn, 3)
+
+	child := l.Derive()
+	defer child.Close()
+	if !child.EnableStackTrace(lane.LogLevelError, true) {
+		t.Error("the derived lane did not inherit the stack trace setting")
+	}
+	if !child.EnableStackTrace(lane.LogLevelWarn, true) || child.EnableStackTraceDepth(lane.LogLevelWarn, 0) != 3 {
+		t.Error("the derived lane did not inherit the stack trace depth")
+	}
+
+	rec := newRecorder()
+	l.AddTee(rec)
+	l.LogStack("where")
+	found := false
+	for _, e := range rec.Events() {
+		if e.Level == lane.LogLevelStack && e.Message == "where" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("the tee did not receive the stack trace")
+	}
+}
+
+func checkMetadata(t *testing.T, l lane.Lane) {
+	rec := newRecorder()
+	l.AddTee(rec)
+
+	l.SetMetadata("key", "value")
+	if l.GetMetadata("key") != "value" {
+		t.Errorf("expected metadata %q, got %q", "value", l.GetMetadata("key"))
+	}
+	if l.GetMetadata("missing") != "" {
+		t.Error("unset metadata must be empty")
+	}
+	if rec.GetMetadata("key") != "value" {
+		t.Error("the metadata was not passed to the tee")
+	}
+	if md := l.MetadataMap(); len(md) != 1 || md["key"] != "value" {
+		t.Errorf("unexpected metadata map %v", md)
+	}
+
+	l.DeleteMetadata("key")
+	if l.GetMetadata("key") != "" {
+		t.Error("the metadata was not deleted")
+	}
+	if rec.GetMetadata("key") != "" {
+		t.Error("the metadata was not deleted from the tee")
+	}
+}
+
+func checkMetadataInheritance(t *testing.T, l lane.Lane) {
+	l.SetMetadata("key", "value")
+
+	none := l.Derive()
+	defer none.Close()
+	if none.GetMetadata("key") != "" {
+		t.Error("a derived lane must start without metadata by default")
+	}
+
+	if prior := l.SetMetadataInheritance(lane.MetadataCopy); prior != lane.MetadataNone {
+		t.Errorf("unexpected prior inheritance %d", prior)
+	}
+	copied := l.Derive()
+	defer copied.Close()
+	copied.SetMetadata("key", "copy")
+	if copied.GetMetadata("key") != "copy" || l.GetMetadata("key") != "value" {
+		t.Error("the derived lane did not get a separate copy of the metadata")
+	}
+
+	l.SetMetadataInheritance(lane.MetadataShared)
+	shared := l.Derive()
+	defer shared.Close()
+	shared.SetMetadata("added", "shared")
+	if l.GetMetadata("added") != "shared" {
+		t.Error("the derived lane does not share the metadata")
+	}
+	if mode := shared.SetMetadataInheritance(lane.MetadataNone); mode != lane.MetadataShared {
+		t.Errorf("the derived lane did not inherit the mode, got %d", mode)
+	}
+}
+
+func checkConstraints(t *testing.T, l lane.Lane) {
+	l.SetLengthConstraint(20)
+	l.SetLevelLengthConstraint(lane.LogLevelDebug, 40)
+	l.SetTruncationMode(lane.TruncateMiddle)
+
+	child := l.Derive()
+	defer child.Close()
+	if prior := child.SetLengthConstraint(0); prior != 20 {
+		t.Errorf("the derived lane did not inherit the length constraint, got %d", prior)
+	}
+	if prior := child.SetLevelLengthConstraint(lane.LogLevelDebug, 0); prior != 40 {
+		t.Errorf("the derived lane did not inherit the level length constraint, got %d", prior)
+	}
+	if mode := child.SetTruncationMode(lane.TruncateEnd); mode != lane.TruncateMiddle {
+		t.Errorf("the derived lane did not inherit the truncation mode, got %d", mode)
+	}
+}
+
+func checkLifecycle(t *testing.T, l lane.Lane) {
+	var derived, closed []string
+	l.OnDerive(func(parent, child lane.Lane) { derived = append(derived, child.LaneId()) })
+	l.OnClose(func(closing lane.Lane) { closed = append(closed, closing.LaneId()) })
+
+	child := l.Derive()
+	grandchild := child.Derive()
+	if len(derived) != 2 || derived[0] != child.LaneId() || derived[1] != grandchild.LaneId() {
+		t.Errorf("unexpected derive hook calls %v", derived)
+	}
+
+	if err := grandchild.Close(); err != nil {
+		t.Errorf("close failed: %v", err)
+	}
+	grandchild.Close()
+	child.Close()
+	if len(closed) != 2 || closed[0] != grandchild.LaneId() || closed[1] != child.LaneId() {
+		t.Errorf("the close hook must be called once per lane, got %v", closed)
+	}
+}
+
+func checkCancelContext(t *testing.T, l lane.Lane) {
+	child, cancel := l.DeriveWithCancel()
+	defer child.Close()
+	cause, cancelCause := l.DeriveWithCancelCause()
+	defer cause.Close()
+	detached := child.DeriveWithoutCancel()
+	defer detached.Close()
+
+	cancel()
+	cancelCause(context.DeadlineExceeded)
+	select {
+	case <-child.Done():
+	case <-time.After(time.Second):
+		t.Fatal("the derived lane was not canceled")
+	}
+	if child.Err() != context.Canceled {
+		t.Errorf("unexpected error %v", child.Err())
+	}
+	if context.Cause(cause) != context.DeadlineExceeded {
+		t.Errorf("unexpected cause %v", context.Cause(cause))
+	}
+	if l.Err() != nil {
+		t.Error("canceling a derived lane canceled its parent")
+	}
+	if detached.Err() != nil {
+		t.Error("a lane made by DeriveWithoutCancel was canceled")
+	}
+}
+
+func checkDeadlineContext(t *testing.T, l lane.Lane) {
+	child, cancel := l.DeriveWithTimeout(time.Millisecond)
+	defer cancel()
+	defer child.Close()
+
+	deadline := time.Now().Add(time.Hour)
+	later, cancelLater := l.DeriveWithDeadline(deadline)
+	defer cancelLater()
+	defer later.Close()
+
+	select {
+	case <-child.Done():
+	case <-time.After(time.Second):
+		t.Fatal("the timeout did not expire")
+	}
+	if child.Err() != context.DeadlineExceeded {
+		t.Errorf("unexpected error %v", child.Err())
+	}
+	if d, ok := later.Deadline(); !ok || !d.Equal(deadline) {
+		t.Errorf("unexpected deadline %v", d)
+	}
+}
+
+func checkReplaceContext(t *testing.T, l lane.Lane) {
+	key := conformanceKey("key")
+	ctx := context.WithValue(context.Background(), key, "value")
+	l.SetLogLevel(lane.LogLevelWarn)
+
+	replaced := l.DeriveReplaceContext(ctx)
+	defer replaced.Close()
+	if replaced.Value(key) != "value" {
+		t.Error("the replacement context's values are not available")
+	}
+	if replaced.LaneId() == "" || replaced.LaneId() == l.LaneId() {
+		t.Error("the lane with the replaced context needs its own ID")
+	}
+	if prior := replaced.SetLogLevel(lane.LogLevelInfo); prior != lane.LogLevelWarn {
+		t.Errorf("the lane configuration was not kept, got level %d", prior)
+	}
+
+	// a nil context is accepted
+	background := l.DeriveReplaceContext(nil)
+	defer background.Close()
+	if background.Value(key) != nil {
+		t.Error("unexpected value in the background context")
+	}
+}
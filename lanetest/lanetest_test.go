@@ -0,0 +1,51 @@
+package lanetest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jimsnab/go-lane"
+)
+
+func TestLogLaneConformance(t *testing.T) {
+	RunLaneConformanceTests(t, func() lane.Lane { return lane.NewLogLane(nil) })
+}
+
+func TestTestingLaneConformance(t *testing.T) {
+	RunLaneConformanceTests(t, func() lane.Lane { return lane.NewTestingLane(nil) })
+}
+
+func TestNullLaneConformance(t *testing.T) {
+	RunLaneConformanceTests(t, func() lane.Lane { return lane.NewNullLane(nil) })
+}
+
+func TestDiskLaneConformance(t *testing.T) {
+	dir := t.TempDir()
+	RunLaneConformanceTests(t, func() lane.Lane {
+		dl, err := lane.NewDiskLane(nil, filepath.Join(dir, "conformance.log"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return dl
+	})
+}
+
+func TestRingBufferLaneConformance(t *testing.T) {
+	RunLaneConformanceTests(t, func() lane.Lane { return lane.NewRingBufferLane(nil, 16) })
+}
+
+func TestLaneGroupConformance(t *testing.T) {
+	RunLaneConformanceTests(t, func() lane.Lane {
+		return lane.NewLaneGroup(nil, []lane.Lane{lane.NewTestingLane(nil), lane.NewNullLane(nil)})
+	})
+}
+
+func TestExprFilterLaneConformance(t *testing.T) {
+	RunLaneConformanceTests(t, func() lane.Lane {
+		l, err := lane.NewExprFilterLane(lane.NewNullLane(nil), "level >= trace")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return l
+	})
+}
@@ -0,0 +1,95 @@
+package lane
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+type (
+	// The subset of testing.TB used by the test helpers, such as VerifyNoLeaks
+	TestingT interface {
+		Helper()
+		Errorf(format string, args ...any)
+		Cleanup(func())
+	}
+
+	// A cancelable lane observed by VerifyNoLeaks
+	trackedLane struct {
+		l      Lane
+		site   string
+		closed bool
+	}
+)
+
+const lanePackagePrefix = "github.com/jimsnab/go-lane."
+
+// Fails the test if a cancelable lane derived from [root] during the test is
+// neither cancelled nor closed by the time the test ends. Call it before the
+// lanes are derived; the check runs as a test cleanup.
+//
+// A lane is cancelable when its context can be done independently of its
+// parent, such as a lane made by DeriveWithCancel() or DeriveWithTimeout().
+func VerifyNoLeaks(t TestingT, root Lane) {
+	t.Helper()
+
+	var mu sync.Mutex
+	tracked := map[string]*trackedLane{}
+	order := []string{}
+
+	stopDerive := root.OnDerive(func(parent, child Lane) {
+		done := child.Done()
+		if done == nil || done == parent.Done() {
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		tracked[child.LaneId()] = &trackedLane{l: child, site: deriveCallSite()}
+		order = append(order, child.LaneId())
+	})
+
+	stopClose := root.OnClose(func(l Lane) {
+		mu.Lock()
+		defer mu.Unlock()
+		if tl := tracked[l.LaneId()]; tl != nil {
+			tl.closed = true
+		}
+	})
+
+	t.Cleanup(func() {
+		t.Helper()
+
+		// a shared root must not accumulate the hooks of each test
+		stopDerive()
+		stopClose()
+
+		mu.Lock()
+		defer mu.Unlock()
+		for _, id := range order {
+			tl := tracked[id]
+			if !tl.closed && tl.l.Err() == nil {
+				t.Errorf("lane %s derived at %s was never cancelled or closed", id, tl.site)
+			}
+		}
+		tracked = nil
+		order = nil
+	})
+}
+
+// Finds the first caller outside of the lane package
+func deriveCallSite() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, lanePackagePrefix) || strings.HasSuffix(frame.File, "_test.go") {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return "(unknown)"
+		}
+	}
+}
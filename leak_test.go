@@ -0,0 +1,99 @@
+package lane
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type (
+	// Captures the results of VerifyNoLeaks
+	leakRecorder struct {
+		errors   []string
+		cleanups []func()
+	}
+)
+
+func (lr *leakRecorder) Helper() {}
+
+func (lr *leakRecorder) Errorf(format string, args ...any) {
+	lr.errors = append(lr.errors, fmt.Sprintf(format, args...))
+}
+
+func (lr *leakRecorder) Cleanup(fn func()) {
+	lr.cleanups = append(lr.cleanups, fn)
+}
+
+func (lr *leakRecorder) finish() {
+	for i := len(lr.cleanups) - 1; i >= 0; i-- {
+		lr.cleanups[i]()
+	}
+}
+
+func testVerifyNoLeaks(t *testing.T, root Lane) {
+	var lr leakRecorder
+	VerifyNoLeaks(&lr, root)
+
+	// not cancelable
+	l1 := root.Derive()
+	l1.DeriveWithoutCancel()
+
+	// cancelled, closed or expired
+	_, cancel := root.DeriveWithCancel()
+	cancel()
+	l3, _ := l1.DeriveWithCancelCause()
+	l3.Close()
+	l4, _ := root.DeriveWithTimeout(time.Millisecond)
+	<-l4.Done()
+
+	// leaked
+	leaked, _ := l1.DeriveWithDeadline(time.Now().Add(time.Hour))
+
+	lr.finish()
+	if len(lr.errors) != 1 {
+		t.Fatalf("expected one leak, got %v", lr.errors)
+	}
+	if !strings.Contains(lr.errors[0], leaked.LaneId()) || !strings.Contains(lr.errors[0], "leak_test.go:") {
+		t.Errorf("unexpected leak report %s", lr.errors[0])
+	}
+}
+
+func TestVerifyNoLeaksLogLane(t *testing.T) {
+	testVerifyNoLeaks(t, NewLogLane(nil))
+}
+
+func TestVerifyNoLeaksTestingLane(t *testing.T) {
+	testVerifyNoLeaks(t, NewTestingLane(nil))
+}
+
+func TestVerifyNoLeaksNullLane(t *testing.T) {
+	testVerifyNoLeaks(t, NewNullLane(nil))
+}
+
+func TestVerifyNoLeaksSharedRoot(t *testing.T) {
+	root := NewTestingLane(nil)
+	for range 3 {
+		var lr leakRecorder
+		VerifyNoLeaks(&lr, root)
+		root.DeriveWithCancel()
+		lr.finish()
+		if len(lr.errors) != 1 {
+			t.Errorf("expected one leak, got %v", lr.errors)
+		}
+	}
+
+	ls := &root.(*testingLane).lifecycleStore
+	if len(ls.deriveHooks) != 0 || len(ls.closeHooks) != 0 {
+		t.Errorf("hooks remain on the root: %d %d", len(ls.deriveHooks), len(ls.closeHooks))
+	}
+}
+
+func TestVerifyNoLeaksClean(t *testing.T) {
+	root := NewTestingLane(nil)
+	VerifyNoLeaks(t, root)
+
+	l, cancel := root.DeriveWithCancel()
+	defer cancel()
+	l.Info("working")
+}
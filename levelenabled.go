@@ -0,0 +1,28 @@
+package lane
+
+// Checks if a message at [level] could be logged by [l], so that the caller
+// can skip preparing it. Lanes that can't tell cheaply report true.
+//
+// In a build with the lane_release tag, trace and debug levels are never
+// enabled, and because ReleaseBuild is a constant, the compiler removes a
+// block guarded with a constant level along with its argument evaluation:
+//
+//	if lane.IsLevelEnabled(l, lane.LogLevelDebug) {
+//		l.Debugf("state %s", expensiveDump())
+//	}
+func IsLevelEnabled(l Lane, level LaneLogLevel) bool {
+	return !strippedLevel(level) && !laneDiscards(l, level)
+}
+
+// not inlined, to keep IsLevelEnabled() within the inlining budget
+//
+//go:noinline
+func laneDiscards(l Lane, level LaneLogLevel) bool {
+	ld, ok := l.(levelDiscarder)
+	return ok && ld.discards(level)
+}
+
+// Checks if [level] is removed from a release build
+func strippedLevel(level LaneLogLevel) bool {
+	return ReleaseBuild && level < LogLevelInfo
+}
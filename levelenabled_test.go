@@ -0,0 +1,25 @@
+package lane
+
+import "testing"
+
+func TestIsLevelEnabled(t *testing.T) {
+	ll := NewLogLane(nil)
+	ll.SetLogLevel(LogLevelWarn)
+	if IsLevelEnabled(ll, LogLevelInfo) || !IsLevelEnabled(ll, LogLevelWarn) {
+		t.Error("unexpected log lane levels")
+	}
+
+	ll.AddTee(NewTestingLane(nil))
+	if IsLevelEnabled(ll, LogLevelTrace) == ReleaseBuild {
+		t.Error("a tee should enable all levels of a debug build")
+	}
+
+	nl := NewNullLane(nil)
+	if IsLevelEnabled(nl, LogLevelError) {
+		t.Error("a null lane without a tee should discard")
+	}
+
+	if !IsLevelEnabled(NewTestingLane(nil), LogLevelInfo) {
+		t.Error("a testing lane should report enabled")
+	}
+}
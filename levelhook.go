@@ -0,0 +1,105 @@
+package lane
+
+import "sync"
+
+type (
+	// Called with each message logged at or above the level it was registered for
+	LevelHook func(rec Record)
+
+	levelHookEntry struct {
+		level LaneLogLevel
+		hook  LevelHook
+	}
+)
+
+// Registers a function that is called synchronously with the record of each
+// message at or above [level] that this lane or one of its future descendants
+// logs, such as to page on the first FATAL. Messages below the lane's log
+// level and stack traces are not passed to the hook. Wrap [hook] with
+// NewAsyncLevelHook() to keep slow work off the logging goroutine.
+func (ls *lifecycleStore) OnLevel(level LaneLogLevel, hook LevelHook) {
+	ls.hookMu.Lock()
+	defer ls.hookMu.Unlock()
+
+	// copy on write, so that lanes sharing the prior list are not affected
+	hooks := make([]levelHookEntry, 0, len(ls.levelHooks)+1)
+	ls.levelHooks = append(append(hooks, ls.levelHooks...), levelHookEntry{level: level, hook: hook})
+	ls.storeHookFloor()
+}
+
+// Caches one more than the lowest hooked level, for a check without the lock.
+// The caller holds hookMu.
+func (ls *lifecycleStore) storeHookFloor() {
+	floor := int32(0)
+	for _, h := range ls.levelHooks {
+		if floor == 0 || int32(h.level)+1 < floor {
+			floor = int32(h.level) + 1
+		}
+	}
+	ls.hookFloor.Store(floor)
+}
+
+// Checks if a hook wants messages at [level]
+func (ls *lifecycleStore) levelHooked(level LaneLogLevel) bool {
+	floor := ls.hookFloor.Load()
+	return floor != 0 && level <= LogLevelFatal && int32(level)+1 >= floor
+}
+
+// Passes [rec] to the hooks registered for its level
+func (ls *lifecycleStore) runLevelHooks(rec *Record) {
+	if rec == nil || !ls.levelHooked(rec.Level) {
+		return
+	}
+
+	ls.hookMu.Lock()
+	hooks := ls.levelHooks
+	ls.hookMu.Unlock()
+
+	for _, h := range hooks {
+		if rec.Level >= h.level {
+			h.hook(*rec)
+		}
+	}
+}
+
+// Makes a level hook that queues the records for [hook], which is called on
+// a goroutine of its own. When [queueSize] records are waiting, further
+// records are dropped. Call [stop] to deliver the queued records and end the
+// goroutine; records arriving after that are dropped.
+func NewAsyncLevelHook(hook LevelHook, queueSize int) (async LevelHook, stop func()) {
+	queue := make(chan Record, max(queueSize, 1))
+	done := make(chan struct{})
+	var mu sync.RWMutex
+	var stopped bool
+
+	go func() {
+		defer close(done)
+		for rec := range queue {
+			hook(rec)
+		}
+	}()
+
+	async = func(rec Record) {
+		mu.RLock()
+		defer mu.RUnlock()
+		if stopped {
+			return
+		}
+		select {
+		case queue <- rec:
+		default:
+		}
+	}
+
+	var once sync.Once
+	stop = func() {
+		once.Do(func() {
+			mu.Lock()
+			stopped = true
+			close(queue)
+			mu.Unlock()
+		})
+		<-done
+	}
+	return
+}
@@ -0,0 +1,85 @@
+package lane
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestOnLevel(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	for _, l := range []Lane{NewTestingLane(nil), NewLogLane(nil), NewNullLane(nil)} {
+		var recs []Record
+		l.OnLevel(LogLevelWarn, func(rec Record) {
+			recs = append(recs, rec)
+		})
+		l.SetMetadata("tenant", "acme")
+
+		l.Info("not hooked")
+		l.Warn("warning")
+		l.Derive().Errorf("child %d", 1)
+		l.Category("db").Error("query failed")
+		l.SetLogLevel(LogLevelFatal)
+		l.Error("below the lane level")
+
+		if len(recs) != 3 || recs[0].Message != "warning" || recs[0].Level != LogLevelWarn || recs[1].Message != "child 1" || recs[2].Category != "db" {
+			t.Errorf("%T: unexpected records %+v", l, recs)
+		} else if recs[0].LaneId != l.LaneId() || recs[1].LaneId == l.LaneId() {
+			t.Errorf("%T: unexpected lane IDs", l)
+		} else if _, isNull := l.(*nullLane); !isNull && recs[0].Fields["tenant"] != "acme" {
+			t.Errorf("%T: missing metadata", l)
+		}
+	}
+}
+
+func TestOnLevelMultiple(t *testing.T) {
+	tl := NewTestingLane(nil)
+	var errors, fatals int
+	tl.OnLevel(LogLevelError, func(rec Record) { errors++ })
+	child := tl.Derive()
+	tl.OnLevel(LogLevelFatal, func(rec Record) { fatals++ })
+	tl.SetPanicHandler(func() {})
+	child.SetPanicHandler(func() {})
+
+	tl.Error("error")
+	tl.Fatal("fatal")
+	child.Fatal("not hooked for fatal")
+
+	if errors != 3 || fatals != 1 {
+		t.Errorf("unexpected hook calls %d %d", errors, fatals)
+	}
+}
+
+func TestAsyncLevelHook(t *testing.T) {
+	var mu sync.Mutex
+	var messages []string
+	release := make(chan struct{})
+	hook, stop := NewAsyncLevelHook(func(rec Record) {
+		<-release
+		mu.Lock()
+		messages = append(messages, rec.Message)
+		mu.Unlock()
+	}, 2)
+
+	ll := NewLogLane(nil)
+	ll.SetLogLevel(LogLevelFatal)
+	ll.OnLevel(LogLevelTrace, hook)
+	hook(Record{Message: "1"})
+	hook(Record{Message: "2"})
+	hook(Record{Message: "3"})
+	hook(Record{Message: "4"})
+	close(release)
+	stop()
+	hook(Record{Message: "after stop"})
+	stop()
+
+	// the hook goroutine may take the first record before the queue fills
+	if len(messages) < 2 || len(messages) > 3 || messages[0] != "1" || messages[1] != "2" {
+		t.Errorf("unexpected messages %v", messages)
+	}
+}
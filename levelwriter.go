@@ -0,0 +1,68 @@
+package lane
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+type (
+	// Output that logs each line written to it to a lane at a fixed level
+	levelWriter struct {
+		mu      sync.Mutex
+		l       Lane
+		level   LaneLogLevel
+		partial []byte // the text written after the last line break
+	}
+)
+
+// the length at which a line without a line break is logged anyway, so
+// that output without line breaks doesn't accumulate
+const levelWriterMaxLine = 64 * 1024
+
+// Makes the writer of WriterAt()
+func newLevelWriter(l Lane, level LaneLogLevel) io.WriteCloser {
+	return &levelWriter{l: l, level: level}
+}
+
+func (lw *levelWriter) Write(p []byte) (n int, err error) {
+	if ld, ok := lw.l.(levelDiscarder); ok && ld.discards(lw.level) {
+		return len(p), nil
+	}
+
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+
+	text := p
+	for {
+		end := bytes.IndexByte(text, '\n')
+		if end < 0 {
+			break
+		}
+		lw.partial = append(lw.partial, text[:end]...)
+		lw.logPartial()
+		text = text[end+1:]
+	}
+	lw.partial = append(lw.partial, text...)
+	if len(lw.partial) >= levelWriterMaxLine {
+		lw.logPartial()
+	}
+	return len(p), nil
+}
+
+// Logs the text that follows the last line break, if there is any
+func (lw *levelWriter) Close() error {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+
+	if len(lw.partial) > 0 {
+		lw.logPartial()
+	}
+	return nil
+}
+
+// Logs the accumulated line. The caller holds lw.mu.
+func (lw *levelWriter) logPartial() {
+	logAtLevel(lw.l, lw.level, string(bytes.TrimSuffix(lw.partial, []byte{'\r'})))
+	lw.partial = lw.partial[:0]
+}
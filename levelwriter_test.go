@@ -0,0 +1,76 @@
+package lane
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestWriterAt(t *testing.T) {
+	skipInRelease(t)
+
+	for _, l := range []Lane{NewTestingLane(nil), NewLogLane(nil), NewNullLane(nil)} {
+		tl := NewTestingLane(nil)
+		l.AddTee(tl)
+
+		w := l.WriterAt(LogLevelDebug)
+		fmt.Fprint(w, "first line\nsecond ")
+		fmt.Fprint(w, "line\r\nunterminated")
+		if !tl.VerifyEventText("DEBUG\tfirst line\nDEBUG\tsecond line") {
+			t.Errorf("%T: unexpected events:\n%s", l, tl.EventsToString())
+		}
+
+		w.Close()
+		if !tl.VerifyEventText("DEBUG\tfirst line\nDEBUG\tsecond line\nDEBUG\tunterminated") {
+			t.Errorf("%T: unexpected events after close:\n%s", l, tl.EventsToString())
+		}
+	}
+}
+
+func TestWriterAtLevel(t *testing.T) {
+	tl := NewTestingLane(nil)
+	tl.SetLogLevel(LogLevelInfo)
+
+	fmt.Fprintln(tl.WriterAt(LogLevelTrace), "not logged")
+	fmt.Fprintln(tl.WriterAt(LogLevelWarn), "warning")
+	fmt.Fprintln(tl.WriterAt(LogLevelFatal), "not fatal")
+
+	if !tl.VerifyEventText("WARN\twarning\nERROR\tnot fatal") {
+		t.Errorf("unexpected events:\n%s", tl.EventsToString())
+	}
+}
+
+func TestWriterAtLongLine(t *testing.T) {
+	tl := NewTestingLane(nil)
+	w := tl.WriterAt(LogLevelInfo)
+	w.Write([]byte(strings.Repeat("x", levelWriterMaxLine+1)))
+
+	events := tl.Events()
+	if len(events) != 1 || len(events[0].Message) != levelWriterMaxLine+1 {
+		t.Errorf("expected the long line to be logged, got %d events", len(events))
+	}
+}
+
+func TestWriterAtCommand(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no shell")
+	}
+
+	tl := NewTestingLane(nil)
+	stdout := tl.WriterAt(LogLevelInfo)
+	stderr := tl.WriterAt(LogLevelWarn)
+	cmd := exec.Command(sh, "-c", "echo out; echo err 1>&2")
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err = cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	stdout.Close()
+	stderr.Close()
+
+	if !tl.Contains("out") || !tl.FindEventText("WARN\terr") {
+		t.Errorf("unexpected events:\n%s", tl.EventsToString())
+	}
+}
@@ -0,0 +1,173 @@
+package lane
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+type (
+	// Called after a lane is derived, before the child is returned to the caller
+	DeriveHook func(parent, child Lane)
+
+	// Called once when a lane is closed
+	CloseHook func(l Lane)
+
+	// A registered hook, which is nil once unregistered, including in the
+	// hook lists that descendants inherited
+	hookEntry[F any] struct {
+		fn atomic.Pointer[F]
+	}
+
+	// Common implementation of the lane lifecycle hooks. Hooks are inherited
+	// by lanes derived after the hook is registered.
+	lifecycleStore struct {
+		hookMu      sync.Mutex
+		deriveHooks []*hookEntry[DeriveHook]
+		closeHooks  []*hookEntry[CloseHook]
+		levelHooks  []levelHookEntry
+		hookFloor   atomic.Int32 // see storeHookFloor()
+		closed      atomic.Bool
+		dropNoticed atomic.Bool // set when a sender reports dropping the closed lane as a tee
+		teeClose    bool        // set by WithTeeClose on the constructed lane, not inherited
+	}
+)
+
+// Registers a function that is called each time this lane or one of its
+// future descendants is derived. Call [unregister] to stop calling it.
+func (ls *lifecycleStore) OnDerive(hook DeriveHook) (unregister func()) {
+	return addHook(&ls.hookMu, &ls.deriveHooks, hook)
+}
+
+// Registers a function that is called when this lane or one of its future
+// descendants is closed. Call [unregister] to stop calling it.
+func (ls *lifecycleStore) OnClose(hook CloseHook) (unregister func()) {
+	return addHook(&ls.hookMu, &ls.closeHooks, hook)
+}
+
+// Appends [hook] to the list, providing the function that removes it. The
+// hook is also cleared, so that the lists inherited by descendants no longer
+// call it or keep what it refers to.
+func addHook[F any](mu *sync.Mutex, list *[]*hookEntry[F], hook F) (unregister func()) {
+	entry := &hookEntry[F]{}
+	entry.fn.Store(&hook)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	// copy on write, so that lanes sharing the prior list are not affected
+	hooks := make([]*hookEntry[F], 0, len(*list)+1)
+	*list = append(append(hooks, *list...), entry)
+
+	return func() {
+		entry.fn.Store(nil)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if i := slices.Index(*list, entry); i >= 0 {
+			*list = slices.Delete(slices.Clone(*list), i, i+1)
+		}
+	}
+}
+
+// Gives a derived lane the hooks of its parent
+func (ls *lifecycleStore) inheritHooks(parent *lifecycleStore) {
+	parent.hookMu.Lock()
+	deriveHooks := parent.deriveHooks
+	closeHooks := parent.closeHooks
+	levelHooks := parent.levelHooks
+	parent.hookMu.Unlock()
+
+	ls.hookMu.Lock()
+	ls.deriveHooks = deriveHooks
+	ls.closeHooks = closeHooks
+	ls.levelHooks = levelHooks
+	ls.storeHookFloor()
+	ls.hookMu.Unlock()
+}
+
+func (ls *lifecycleStore) runDeriveHooks(parent, child Lane) {
+	ls.hookMu.Lock()
+	hooks := ls.deriveHooks
+	ls.hookMu.Unlock()
+
+	for _, hook := range hooks {
+		if fn := hook.fn.Load(); fn != nil {
+			(*fn)(parent, child)
+		}
+	}
+}
+
+// Runs the close hooks the first time the lane is closed, reporting true if
+// this is the first time
+func (ls *lifecycleStore) runCloseHooks(l Lane) (first bool) {
+	if ls.closed.Swap(true) {
+		return
+	}
+	first = true
+
+	ls.hookMu.Lock()
+	hooks := ls.closeHooks
+	ls.hookMu.Unlock()
+
+	for _, hook := range hooks {
+		if fn := hook.fn.Load(); fn != nil {
+			(*fn)(l)
+		}
+	}
+	return
+}
+
+// Closes the tees of a lane made with WithTeeClose
+func (ls *lifecycleStore) closeTees(ctx context.Context, tees []Lane) error {
+	if !ls.teeClose {
+		return nil
+	}
+
+	var errs []error
+	for _, t := range tees {
+		errs = append(errs, t.CloseWithContext(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+func (ls *lifecycleStore) isClosed() bool {
+	return ls.closed.Load()
+}
+
+func (ls *lifecycleStore) firstDropNotice() bool {
+	return ls.dropNoticed.CompareAndSwap(false, true)
+}
+
+// Separates the closed lanes from [tees], which a sender stops forwarding
+// to. The [open] list is [tees] itself if none are closed.
+func withoutClosedTees(tees []Lane) (open, closed []Lane) {
+	for i, t := range tees {
+		if !t.(laneInternal).isClosed() {
+			if closed != nil {
+				open = append(open, t)
+			}
+			continue
+		}
+		if closed == nil {
+			open = append([]Lane{}, tees[:i]...)
+		}
+		closed = append(closed, t)
+	}
+	if closed == nil {
+		open = tees
+	}
+	return
+}
+
+// Logs a warning on [l] for each of the [closed] tees it dropped, once for
+// each tee
+func reportClosedTees(l Lane, closed []Lane) {
+	for _, t := range closed {
+		if t.(laneInternal).firstDropNotice() {
+			l.Warnf("tee %s is closed and no longer receives messages", t.LaneId())
+		}
+	}
+}
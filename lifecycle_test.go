@@ -0,0 +1,258 @@
+package lane
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testLifecycleHooks(t *testing.T, root Lane) {
+	derived := 0
+	root.OnDerive(func(parent, child Lane) {
+		derived++
+		child.SetMetadata("tenant", parent.GetMetadata("tenant"))
+	})
+
+	closed := []string{}
+	root.OnClose(func(l Lane) {
+		closed = append(closed, l.LaneId())
+	})
+
+	root.SetMetadata("tenant", "acme")
+
+	l1 := root.Derive()
+	l2, cancel := l1.DeriveWithCancel()
+	defer cancel()
+	l3 := l2.DeriveReplaceContext(context.Background())
+
+	if derived != 3 {
+		t.Errorf("expected 3 derive calls, got %d", derived)
+	}
+	for _, l := range []Lane{l1, l2, l3} {
+		if l.GetMetadata("tenant") != "acme" {
+			t.Errorf("metadata not stamped on %s", l.LaneId())
+		}
+	}
+
+	// hooks registered on a child don't affect the parent
+	childHooks := 0
+	l1.OnDerive(func(parent, child Lane) {
+		childHooks++
+	})
+	root.Derive()
+	l1.Derive()
+	if derived != 5 || childHooks != 1 {
+		t.Errorf("unexpected counts %d %d", derived, childHooks)
+	}
+
+	l2.Close()
+	l2.Close()
+	root.Close()
+	if len(closed) != 2 || closed[0] != l2.LaneId() || closed[1] != root.LaneId() {
+		t.Errorf("unexpected close calls %v", closed)
+	}
+}
+
+func TestLifecycleHooksLogLane(t *testing.T) {
+	testLifecycleHooks(t, NewLogLane(nil))
+}
+
+func TestLifecycleHooksTestingLane(t *testing.T) {
+	testLifecycleHooks(t, NewTestingLane(nil))
+}
+
+func TestLifecycleHooksNullLane(t *testing.T) {
+	testLifecycleHooks(t, NewNullLane(nil))
+}
+
+func testLifecycleStore(l Lane) *lifecycleStore {
+	switch v := l.(type) {
+	case *logLane:
+		return &v.lifecycleStore
+	case *testingLane:
+		return &v.lifecycleStore
+	case *nullLane:
+		return &v.lifecycleStore
+	}
+	panic("unexpected lane type")
+}
+
+func TestLifecycleHooksUnregister(t *testing.T) {
+	for _, root := range []Lane{NewTestingLane(nil), NewLogLane(nil), NewNullLane(nil)} {
+		derived, closed := 0, 0
+		stopDerive := root.OnDerive(func(parent, child Lane) { derived++ })
+		stopClose := root.OnClose(func(l Lane) { closed++ })
+
+		child := root.Derive()
+		stopDerive()
+		stopClose()
+		stopDerive()
+
+		// also removed from the descendant that copied the hooks
+		root.Derive()
+		child.Derive().Close()
+		root.Close()
+
+		if derived != 1 || closed != 0 {
+			t.Errorf("%T: unexpected counts %d %d", root, derived, closed)
+		}
+		if len(testLifecycleStore(root).deriveHooks) != 0 {
+			t.Errorf("%T: hook not removed", root)
+		}
+	}
+}
+
+func TestLifecycleHooksDiskLane(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hooks.log")
+	dl, err := NewDiskLane(nil, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var hookParent, hookChild Lane
+	dl.OnDerive(func(parent, child Lane) {
+		hookParent = parent
+		hookChild = child
+	})
+	var closed Lane
+	dl.OnClose(func(l Lane) {
+		closed = l
+	})
+
+	child := dl.Derive()
+	if hookParent != dl || hookChild != child {
+		t.Error("expected outer lanes passed to derive hook")
+	}
+	if _, ok := hookChild.(*diskLane); !ok {
+		t.Error("expected disk lane child")
+	}
+
+	child.Close()
+	if closed != child {
+		t.Error("expected outer lane passed to close hook")
+	}
+	dl.Close()
+
+	if _, err := os.Stat(path); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCloseTees(t *testing.T) {
+	makers := map[string]func(opts ...LaneOption) Lane{
+		"log":     func(opts ...LaneOption) Lane { return NewLogLane(nil, opts...) },
+		"testing": func(opts ...LaneOption) Lane { return NewTestingLane(nil, opts...) },
+		"null":    func(opts ...LaneOption) Lane { return NewNullLane(nil, opts...) },
+	}
+
+	for name, maker := range makers {
+		var closer io.Closer = maker()
+		if closer.Close() != nil || closer.Close() != nil {
+			t.Errorf("%s: unexpected close error", name)
+		}
+
+		root := maker(WithTeeClose())
+		tee := NewTestingLane(nil)
+		teeClosed := 0
+		tee.OnClose(func(l Lane) { teeClosed++ })
+		root.AddTee(tee)
+
+		// a derived lane shares the tee but doesn't close it
+		root.Derive().Close()
+		if teeClosed != 0 {
+			t.Errorf("%s: tee closed by derived lane", name)
+		}
+
+		root.Close()
+		root.Close()
+		if teeClosed != 1 {
+			t.Errorf("%s: expected the tee to be closed once, got %d", name, teeClosed)
+		}
+
+		// without the option, tees stay open
+		other := maker()
+		tee2 := NewTestingLane(nil)
+		tee2Closed := false
+		tee2.OnClose(func(l Lane) { tee2Closed = true })
+		other.AddTee(tee2)
+		other.Close()
+		if tee2Closed {
+			t.Errorf("%s: tee closed without the option", name)
+		}
+	}
+}
+
+func TestCloseDiskLaneTwice(t *testing.T) {
+	dl, err := NewDiskLane(nil, filepath.Join(t.TempDir(), "close.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	child := dl.Derive()
+
+	if err = child.Close(); err != nil {
+		t.Error(err)
+	}
+	if err = dl.Close(); err != nil {
+		t.Error(err)
+	}
+	if err = dl.Close(); err != nil {
+		t.Errorf("expected a second close to succeed, got %v", err)
+	}
+}
+
+func TestCloseWithContextTimeout(t *testing.T) {
+	tfs := newTestFluentServer(t)
+	defer tfs.listener.Close()
+	tfs.mu.Lock()
+	tfs.noAck = true
+	tfs.mu.Unlock()
+
+	fl := NewFluentLane(nil, FluentConfig{
+		Addr:       tfs.listener.Addr().String(),
+		RequireAck: true,
+		AckTimeout: time.Second,
+		MaxRetries: 1,
+	})
+	fl.Info("never acknowledged")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := fl.CloseWithContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a deadline error, got %v", err)
+	}
+	if time.Since(start) >= time.Second {
+		t.Error("close waited for the ack timeout")
+	}
+}
+
+func TestClosedTeeDropped(t *testing.T) {
+	for _, l := range []Lane{NewTestingLane(nil), NewLogLane(nil, WithLevel(LogLevelError)), NewNullLane(nil)} {
+		closedTee := NewTestingLane(nil)
+		tl := NewTestingLane(nil)
+		l.AddTee(closedTee)
+		l.AddTee(tl)
+		child := l.Derive()
+
+		l.Info("before")
+		closedTee.Close()
+		l.Info("after")
+		child.Info("from the child")
+
+		if !closedTee.VerifyEventText("INFO\tbefore") {
+			t.Errorf("%T: unexpected closed tee events:\n%s", l, closedTee.EventsToString())
+		}
+		expected := "INFO\tbefore\nINFO\tafter\nWARN\ttee " + closedTee.LaneId() + " is closed and no longer receives messages\nINFO\tfrom the child"
+		if !tl.VerifyEventText(expected) {
+			t.Errorf("%T: unexpected events:\n%s", l, tl.EventsToString())
+		}
+		if len(l.Tees()) != 1 || len(child.Tees()) != 1 {
+			t.Errorf("%T: the closed tee wasn't removed", l)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package lane
+
+import (
+	"runtime"
+	"strings"
+)
+
+type (
+	// Selects how a log lane ends the lines of its text output
+	LineEnding int
+)
+
+const (
+	// Lines end with \n
+	LineEndingLF LineEnding = iota
+	// Lines end with \r\n, such as for a terminal that needs them
+	LineEndingCRLF
+	// Lines end with \r\n on Windows, and \n elsewhere
+	LineEndingPlatform
+)
+
+// Provides what goes before each \n of the output
+func (le LineEnding) cr() string {
+	if le == LineEndingCRLF || (le == LineEndingPlatform && runtime.GOOS == "windows") {
+		return "\r"
+	}
+	return ""
+}
+
+// Ends every line of [msg] with [cr] and \n, except for the final \n, which
+// the output logger adds
+func endLines(msg string, cr string) string {
+	if cr == "" {
+		return msg
+	}
+	msg = strings.TrimSuffix(strings.ReplaceAll(msg, "\r\n", "\n"), "\n")
+	return strings.ReplaceAll(msg, "\n", cr+"\n") + cr
+}
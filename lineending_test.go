@@ -0,0 +1,63 @@
+package lane
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestEndLines(t *testing.T) {
+	cases := []struct {
+		msg, cr, expected string
+	}{
+		{"one", "", "one"},
+		{"one", "\r", "one\r"},
+		{"one\ntwo", "\r", "one\r\ntwo\r"},
+		{"one\r\ntwo\n", "\r", "one\r\ntwo\r"},
+		{"one\ntwo", "", "one\ntwo"},
+	}
+	for _, c := range cases {
+		if actual := endLines(c.msg, c.cr); actual != c.expected {
+			t.Errorf("%q: got %q, expected %q", c.msg, actual, c.expected)
+		}
+	}
+}
+
+func TestLineEnding(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	ll := NewLogLane(nil, WithLineEnding(LineEndingCRLF)).(LogLane)
+	ll.EnableStackTrace(LogLevelError, true)
+	ll.Info("first\nsecond")
+	ll.Derive().Error("failed")
+	ll.Logger().Print("from the logger")
+
+	output := buf.String()
+	if strings.Count(output, "\n") < 4 || strings.Count(output, "\n") != strings.Count(output, "\r\n") {
+		t.Errorf("unexpected line endings %q", output)
+	}
+
+	if prior := ll.SetLineEnding(LineEndingLF); prior != LineEndingCRLF {
+		t.Errorf("unexpected prior line ending %d", prior)
+	}
+	buf.Reset()
+	ll.Info("plain")
+	if strings.Contains(buf.String(), "\r") {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+
+	ll.SetLineEnding(LineEndingPlatform)
+	buf.Reset()
+	ll.Info("platform")
+	if strings.HasSuffix(buf.String(), "\r\n") != (runtime.GOOS == "windows") {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+	if ll.AddCR(false) != (runtime.GOOS == "windows") {
+		t.Error("unexpected prior CR setting")
+	}
+}
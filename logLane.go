@@ -5,8 +5,8 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"log"
-	"runtime"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -18,26 +18,120 @@ type (
 		Lane
 		laneInternal
 		AddCR(shouldAdd bool) (prior bool)
+
+		// Selects the line endings of the text output, including stack traces,
+		// for this lane and lanes derived from it afterward.
+		SetLineEnding(ending LineEnding) (prior LineEnding)
 		SetFlagsMask(mask int) (prior int)
+
+		// Selects how the text output writes a message that has line breaks,
+		// for this lane and lanes derived from it afterward.
+		SetMultiLinePolicy(policy MultiLinePolicy) (prior MultiLinePolicy)
+
+		// Renders the timestamps of the output in [loc], with the time zone,
+		// for this lane and lanes derived from it afterward. A nil location
+		// restores the timestamps of the log package.
+		SetTimeLocation(loc *time.Location) (prior *time.Location)
+
+		// Shows the ID of the lane's parent in its output, as {parent→child},
+		// and in the ParentId of its records, for this lane and lanes derived
+		// from it afterward, so that derivation chains can be reconstructed
+		// from the log.
+		SetParentIdOutput(enable bool) (prior bool)
+
+		// Limits the depth of Derive() chains from this lane, and lanes derived
+		// from it afterward, to [depth] levels below the root, or 0 for no
+		// limit. See WithMaxDepth().
+		SetMaxDepth(depth int) (prior int)
+
+		// Limits the time the lane, and lanes derived from it afterward, wait
+		// for a tee to take a message, or 0 to wait as long as it takes. See
+		// WithTeeTimeout().
+		SetTeeTimeout(timeout time.Duration) (prior time.Duration)
+		Stats() LaneStats
+
+		// Renders the output with [enc] instead of as text lines, for this lane
+		// and lanes derived from it afterward. A nil encoder restores the text
+		// output. Lane types that don't write output ignore the encoder.
+		SetEncoder(enc Encoder) (prior Encoder)
+
+		// Also writes the exact formatted output to [w], such as an in-memory
+		// buffer for a crash report, without the work of a tee lane. Lanes
+		// derived from this lane share its raw writers.
+		AddRawWriter(w io.Writer)
+
+		// Stops writing the formatted output to [w].
+		RemoveRawWriter(w io.Writer)
+
+		// Also hands each output record to [sink], such as for machine-readable
+		// JSON alongside the text output, without the formatting work of a tee
+		// lane. Lanes derived from this lane share its record sinks.
+		AddRecordSink(sink RecordSink)
+
+		// Stops handing the output records to [sink].
+		RemoveRecordSink(sink RecordSink)
+	}
+
+	// Implemented by a lane type embedding a log lane to receive log records
+	// instead of formatted output. With WithTeeTimeout(), receiveRecord() may
+	// run on a worker goroutine rather than that of the logging call.
+	laneEventSink interface {
+		receiveRecord(rec *Record)
 	}
 
 	logLane struct {
 		context.Context
 		MetadataStore
+		objectOptStore
+		lengthConstraintStore
+		lifecycleStore
+		laneTreeStore
+		errorStore
+		stackTraceStore
+		callerInfoStore
+		logLimitStore
 		wlog         *log.Logger // wrapper log to capture caller's logging intent without sending to output
 		writer       *log.Logger // the log instance used for output
 		level        int32
-		cr           string
-		stackTrace   []atomic.Bool
-		mu           sync.Mutex
-		tees         []Lane
+		lineEnding   atomic.Int32 // a LineEnding
+		multiLine    atomic.Int32
+		mu           sync.Mutex // serializes changes; logging reads the atomic snapshots
+		tees         atomic.Pointer[[]Lane]
+		props        atomic.Pointer[loggingProperties]
 		journeyId    string
+		traceCtx     traceContext
+		category     string
+		parentId     string
+		showParent   bool
+		indent       int
 		onPanic      Panic
-		logMask      int
+		logMask      atomic.Int32
 		outer        Lane
 		parent       *logLane
+		depth        int          // the number of ancestors
+		maxDepth     atomic.Int32 // the limit of depth for Derive(), or 0
+		depthLogged  *atomic.Bool // shared by the lane tree, to report the limit once
+		plain        bool         // made by Derive(), sharing its parent's context
+		sibling      bool         // made with the settings of a lane as its sibling, at the depth limit
 		onCreateLane OnCreateLane
-		maxLength    atomic.Int32
+		idGen        LaneIdGenerator
+		clock        Clock
+		location     atomic.Pointer[time.Location]
+		sink         laneEventSink
+		gate         levelGate
+		levelParser  LevelParser
+		crashDump    *CrashDumpOptions
+		counters     *laneCounters
+		teeTimeout   atomic.Int64 // a time.Duration
+		busyTees     *busyTeeSet
+		encoder      atomic.Pointer[Encoder]
+		raw          *rawWriterSet
+		records      *recordSinkSet
+	}
+
+	// Implemented by an output that buffers
+	outputFlusher interface {
+		Flush() error
 	}
 
 	wrappedLogWriter struct {
@@ -72,8 +166,8 @@ func isLogCrLf() bool {
 	return buf.Bytes()[0] == '\r'
 }
 
-func NewLogLane(ctx OptionalContext) Lane {
-	l, _ := deriveLogLane(nil, ctx, nil, createLogLane)
+func NewLogLane(ctx OptionalContext, opts ...LaneOption) Lane {
+	l, _ := NewEmbeddedLogLane(createLogLane, ctx, opts...)
 	return l
 }
 
@@ -82,14 +176,26 @@ func NewLogLane(ctx OptionalContext) Lane {
 //   - onCreate creates a new instance of the outer lane and provides the embedded log lane.
 //   - startingCtx provides an optional context instance, to start a lane from a pre-existing
 //     context
-func NewEmbeddedLogLane(onCreate OnCreateLane, startingCtx OptionalContext) (l Lane, err error) {
+//   - opts provides optional constructor settings
+func NewEmbeddedLogLane(onCreate OnCreateLane, startingCtx OptionalContext, opts ...LaneOption) (l Lane, err error) {
 	laneOuter, embedded, writer, err := onCreate(nil)
 	if err != nil {
 		return
 	}
 
+	lo := applyLaneOptions(opts)
 	ll := embedded.(*logLane)
+	ll.idGen = lo.idGen
+	ll.clock = lo.clock
+	ll.levelParser = lo.levelParser
+	ll.crashDump = lo.crashDump
+	ll.teeClose = lo.teeClose
+	ll.setDiagnosticHandler(lo.diagnostics)
+	if lo.encoder != nil {
+		ll.encoder.Store(&lo.encoder)
+	}
 	ll.initialize(laneOuter, nil, startingCtx, nil, onCreate, writer)
+	lo.applySettings(laneOuter)
 	l = laneOuter
 	return
 }
@@ -112,17 +218,29 @@ func AllocEmbeddedLogLane() LogLane {
 
 // Convenience wrapper that makes a new logLane object and calls initialize() on it
 func deriveLogLane(parent *logLane, startingCtx context.Context, contextCallback deriveContext, createLane OnCreateLane) (l Lane, err error) {
+	return deriveLogLaneAs(parent, false, startingCtx, contextCallback, createLane)
+}
+
+// Makes a new logLane with the settings of [parent], placed under it, or
+// beside it if [sibling] is true
+func deriveLogLaneAs(parent *logLane, sibling bool, startingCtx context.Context, contextCallback deriveContext, createLane OnCreateLane) (l Lane, err error) {
 	var parentOuter Lane
 	if parent != nil {
 		parentOuter = parent.outer
+		parent.exceedsMaxDepth()
 	}
 	childOuter, child, writer, err := createLane(parentOuter)
 	if err != nil {
 		return
 	}
 	derived := child.(*logLane)
+	derived.sibling = sibling
 	derived.initialize(childOuter, parent, startingCtx, contextCallback, createLane, writer)
 
+	if parent != nil {
+		parent.runDeriveHooks(parentOuter, childOuter)
+	}
+
 	l = childOuter
 	return
 }
@@ -133,38 +251,83 @@ func (ll *logLane) initialize(laneOuter Lane, pll *logLane, startingCtx context.
 		startingCtx = context.Background()
 	}
 
-	ll.stackTrace = make([]atomic.Bool, int(LogLevelStack+1))
 	ll.EnableStackTrace(LogLevelStack, true)
 	ll.onCreateLane = onCreate // keep this reference so that future Derive() calls can invoke it
 	ll.outer = laneOuter
-	ll.parent = pll
+	ll.sink, _ = laneOuter.(laneEventSink)
+	ll.gate, _ = laneOuter.(levelGate)
 	ll.SetPanicHandler(nil)
 
+	// the settings come from pll, and the lane is placed under pll, or beside
+	// it when deriving a sibling
+	up := pll
+	if ll.sibling {
+		up = pll.parent
+	}
+	ll.parent = up
+
+	if pll != nil {
+		ll.counters = pll.counters
+		ll.busyTees = pll.busyTees
+		ll.teeTimeout.Store(pll.teeTimeout.Load())
+		ll.raw = pll.raw
+		ll.records = pll.records
+		ll.depth = up.depth + 1
+		ll.maxDepth.Store(pll.maxDepth.Load())
+		ll.depthLogged = pll.depthLogged
+		ll.plain = contextCallback == nil && startingCtx == context.Context(up)
+	} else {
+		ll.counters = &laneCounters{}
+		ll.busyTees = &busyTeeSet{}
+		ll.raw = &rawWriterSet{}
+		ll.records = &recordSinkSet{}
+		ll.depthLogged = &atomic.Bool{}
+	}
+
 	// make a logging instance that ultimately does logging via the lane
 	wlw := wrappedLogWriter{outer: laneOuter, ll: ll}
 	if writer == nil {
-		ll.writer = log.Default()
+		ll.writer = log.New(stdWriter{}, "", 0)
 	} else {
 		ll.writer = writer
 	}
+	if _, counted := ll.writer.Writer().(*countingWriter); !counted {
+		output := &rawTeeWriter{w: ll.writer.Writer(), set: ll.raw}
+		ll.writer.SetOutput(&countingWriter{w: output, counters: ll.counters})
+	}
 	ll.wlog = log.New(&wlw, "", 0)
 
 	if pll != nil {
 		ll.journeyId = pll.journeyId
-		ll.tees = pll.tees
-		ll.cr = pll.cr
+		ll.traceCtx = pll.traceCtx
+		ll.category = pll.category
+		ll.parentId = up.LaneId()
+		ll.showParent = pll.showParent
+		ll.indent = pll.indent
+		ll.tees.Store(pll.tees.Load())
+		ll.lineEnding.Store(pll.lineEnding.Load())
+		ll.multiLine.Store(pll.multiLine.Load())
 		ll.SetLogLevel(LaneLogLevel(atomic.LoadInt32(&pll.level)))
 		ll.wlog.SetFlags(pll.wlog.Flags())
 		ll.wlog.SetPrefix(pll.wlog.Prefix())
 		ll.onPanic = pll.onPanic
+		ll.idGen = pll.idGen
+		ll.clock = pll.clock
+		ll.location.Store(pll.location.Load())
+		ll.levelParser = pll.levelParser
+		ll.crashDump = pll.crashDump
+		ll.encoder.Store(pll.encoder.Load())
 		copyConfigToDerivation(ll, pll)
+		ll.inheritHooks(&pll.lifecycleStore)
+		ll.inheritErrorHandler(&pll.errorStore)
+		ll.inheritMetadata(&pll.MetadataStore)
+		ll.inheritLimits(&pll.logLimitStore)
 	} else {
 		ll.wlog.SetFlags(log.LstdFlags)
-		ll.tees = []Lane{}
-		ll.cr = ""
+		ll.tees.Store(&[]Lane{})
 	}
 
-	id := makeLaneId()
+	id := makeLaneId(ll.idGen)
 
 	// The context must have the correlation ID value set. The caller might also
 	// want another context feature such as WithCancel or WithDeadline. This requires
@@ -172,7 +335,7 @@ func (ll *logLane) initialize(laneOuter Lane, pll *logLane, startingCtx context.
 	var newCtx context.Context
 
 	if pll != nil {
-		newCtx = context.WithValue(context.WithValue(startingCtx, LogLaneIdKey, id), ParentLaneIdKey, pll.LaneId())
+		newCtx = context.WithValue(context.WithValue(startingCtx, LogLaneIdKey, id), ParentLaneIdKey, up.LaneId())
 	} else {
 		newCtx = context.WithValue(startingCtx, LogLaneIdKey, id)
 	}
@@ -181,23 +344,41 @@ func (ll *logLane) initialize(laneOuter Lane, pll *logLane, startingCtx context.
 	} else {
 		ll.Context = newCtx
 	}
+
+	ll.mu.Lock()
+	ll.publishProps()
+	ll.mu.Unlock()
+
+	if pll != nil {
+		ll.attachTree(laneOuter, &up.laneTreeStore, ll.now())
+	} else {
+		ll.attachTree(laneOuter, nil, ll.now())
+	}
 }
 
 func (ll *logLane) AddCR(shouldAdd bool) (prior bool) {
-	ll.mu.Lock()
-	prior = (ll.cr != "")
+	ending := LineEndingLF
 	if shouldAdd {
-		ll.cr = "\r"
-	} else {
-		ll.cr = ""
+		ending = LineEndingCRLF
 	}
-	ll.mu.Unlock()
-	return
+	return ll.SetLineEnding(ending).cr() != ""
+}
+
+func (ll *logLane) SetLineEnding(ending LineEnding) (prior LineEnding) {
+	return LineEnding(ll.lineEnding.Swap(int32(ending)))
+}
+
+func (ll *logLane) SetTimeLocation(loc *time.Location) (prior *time.Location) {
+	return ll.location.Swap(loc)
+}
+
+func (ll *logLane) SetMultiLinePolicy(policy MultiLinePolicy) (prior MultiLinePolicy) {
+	return MultiLinePolicy(ll.multiLine.Swap(int32(policy)))
 }
 
 // For cases where \r\n line endings are required (ex: vscode terminal)
-func NewLogLaneWithCR(ctx OptionalContext) Lane {
-	ll, _ := deriveLogLane(nil, ctx, nil, createLogLane)
+func NewLogLaneWithCR(ctx OptionalContext, opts ...LaneOption) Lane {
+	ll := NewLogLane(ctx, opts...)
 	if !isLogCrLf() {
 		p := ll.(LogLane)
 		p.AddCR(true)
@@ -208,13 +389,33 @@ func NewLogLaneWithCR(ctx OptionalContext) Lane {
 // Adds an ID to the log message(s)
 func (ll *logLane) SetJourneyId(id string) {
 	ll.mu.Lock()
-	defer ll.mu.Unlock()
-
 	if len(id) > 10 {
 		ll.journeyId = id[:10]
 	} else {
 		ll.journeyId = id
 	}
+	traced := ll.traceCtx != traceContext{}
+	ll.traceCtx = traceContext{}
+	ll.publishProps()
+	ll.mu.Unlock()
+
+	endTraceParent(ll.outer, traced)
+}
+
+func (ll *logLane) SetTraceParent(tp string) error {
+	return setTraceParent(ll.outer, tp, func(tc traceContext) {
+		ll.mu.Lock()
+		defer ll.mu.Unlock()
+		ll.traceCtx = tc
+		ll.journeyId = tc.traceId
+		ll.publishProps()
+	})
+}
+
+func (ll *logLane) TraceParent() string {
+	ll.mu.Lock()
+	defer ll.mu.Unlock()
+	return ll.traceCtx.render(ll.LaneId())
 }
 
 func sprint(args ...any) string {
@@ -231,85 +432,272 @@ func (ll *logLane) SetLogLevel(newLevel LaneLogLevel) (priorLevel LaneLogLevel)
 	return
 }
 
-func (ll *logLane) shouldLog(level LaneLogLevel) bool {
-	if atomic.LoadInt32(&ll.level) <= int32(level) {
-		// the log wrapper is exposed to the client, so ensure changes
-		// made to prefix and flags are copied into the instance
-		// generating the output
-		ll.writer.SetPrefix(ll.wlog.Prefix())
-		ll.writer.SetFlags(ll.wlog.Flags() &^ ll.logMask)
-		return true
+func (ll *logLane) ConfigSnapshot() (state LaneConfigState) {
+	state = captureConfig(ll.outer, LaneLogLevel(atomic.LoadInt32(&ll.level)), &ll.stackTraceStore, &ll.callerInfoStore, &ll.lengthConstraintStore)
+
+	state.LineEnding = LineEnding(ll.lineEnding.Load())
+	state.MultiLine = MultiLinePolicy(ll.multiLine.Load())
+	state.FlagsMask = int(ll.logMask.Load())
+	return
+}
+
+func (ll *logLane) ApplyConfig(state LaneConfigState) {
+	applyConfig(ll.outer, state)
+}
+
+func (ll *logLane) shouldLog(props loggingProperties, level LaneLogLevel) bool {
+	if ll.gate != nil && !ll.gate.admits(level) {
+		return false
 	}
+	return atomic.LoadInt32(&ll.level) <= int32(level) || journeyDebugEnabled(props.journeyId, level)
+}
 
-	return false
+// Provides the current time from the lane's clock
+func (ll *logLane) now() time.Time {
+	if ll.clock != nil {
+		return ll.clock.Now()
+	}
+	return time.Now()
 }
 
-func (ll *logLane) tee(props loggingProperties, logger teeHandler) {
-	ll.mu.Lock()
-	defer ll.mu.Unlock()
+// Sends the formatted message to the output, after the prefix and timestamp
+// of the lane's logger. A write failure is reported to the error handler
+// along with the message [text] it was for.
+func (ll *logLane) print(props loggingProperties, level LaneLogLevel, text string, msg string) {
+	ll.printAt(ll.now(), props, level, text, msg)
+}
 
-	for _, t := range ll.tees {
-		receiver := t.(laneInternal)
-		logger(props, receiver)
+// Same as print(), with the timestamp of [t]
+func (ll *logLane) printAt(t time.Time, props loggingProperties, level LaneLogLevel, text string, msg string) {
+	msg = ll.header(t) + msg
+	msg = endLines(msg, LineEnding(ll.lineEnding.Load()).cr())
+	if err := ll.writer.Output(1, msg); err != nil {
+		ll.reportError(err, Record{Time: t, LaneId: props.laneId, JourneyId: props.journeyId, Category: props.category, ParentId: props.parentId, Level: level, Message: text})
 	}
 }
 
+// Renders the prefix and timestamp the way the log package would, according
+// to the settings of the lane's logger. The output logger itself has no flags
+// or prefix, as other goroutines write through it at the same time.
+func (ll *logLane) header(t time.Time) string {
+	flags := ll.wlog.Flags() &^ int(ll.logMask.Load())
+	prefix := ll.wlog.Prefix()
+
+	var ts string
+	if loc := ll.location.Load(); loc != nil {
+		ts = formatLogTimeIn(t, flags, loc)
+	} else {
+		ts = formatLogTime(t, flags)
+	}
+
+	if flags&log.Lmsgprefix != 0 {
+		return ts + prefix
+	}
+	return prefix + ts
+}
+
+// Checks if a message at [level] would neither be logged nor sent to a tee,
+// so that the caller can skip all of the message preparation work.
+func (ll *logLane) discards(level LaneLogLevel) bool {
+	if strippedLevel(level) {
+		return true
+	}
+	if atomic.LoadInt32(&ll.level) <= int32(level) || len(*ll.tees.Load()) != 0 {
+		return false
+	}
+	return journeyDebugCount.Load() == 0 || !journeyDebugEnabled(ll.JourneyId(), level)
+}
+
+// Sends a message at [level] to the tees. The tees are called without the
+// lane's lock, from a snapshot of the list, so a slow tee doesn't block the
+// other users of the lane.
+func (ll *logLane) tee(props loggingProperties, level LaneLogLevel, logger teeHandler) {
+	tees, closed := withoutClosedTees(*ll.tees.Load())
+	if closed != nil {
+		// the list may have changed since the snapshot
+		ll.mu.Lock()
+		current, closedSince := withoutClosedTees(*ll.tees.Load())
+		ll.tees.Store(&current)
+		ll.mu.Unlock()
+		closed = append(closed, closedSince...)
+	}
+
+	for _, t := range tees {
+		ll.callTee(props, level, t, logger)
+	}
+	reportClosedTees(ll.outer, closed)
+}
+
 func (ll *logLane) printMsg(props loggingProperties, level LaneLogLevel, prefix string, teeFn teeHandler, args ...any) {
-	if ll.shouldLog(level) {
-		msg := fmt.Sprintf("%s %s", props.getMessagePrefix(prefix), sprint(args...))
-		if ll.cr != "" {
-			msg = strings.ReplaceAll(msg, "\r\n", "\n")
-			msg = strings.ReplaceAll(msg, "\n", ll.cr+"\n")
-			if !strings.Contains(msg, ll.cr) {
-				msg += ll.cr
-			}
-		}
-		ll.writer.Print(msg)
+	if ll.shouldLog(props, level) {
+		ll.emit(props, level, prefix, ll.constrainLevel(level, sprint(args...)))
 		ll.logStackIf(props, level, "", 0)
 	}
-	ll.tee(props, teeFn)
+	ll.tee(props, level, teeFn)
 }
 
-func (ll *logLane) Constrain(text string) string {
-	maxLen := ll.maxLength.Load()
-	if maxLen > 0 && len(text) > int(maxLen) {
-		text = text[:maxLen-1] + "\u2026"
+func (ll *logLane) printfMsg(props loggingProperties, level LaneLogLevel, prefix string, teeFn teeHandler, formatStr string, args ...any) {
+	if ll.shouldLog(props, level) {
+		ll.emit(props, level, prefix, ll.constrainLevel(level, fmt.Sprintf(formatStr, args...)))
+		ll.logStackIf(props, level, "", 0)
 	}
-	return text
+	ll.tee(props, level, teeFn)
 }
 
-func (ll *logLane) printfMsg(props loggingProperties, level LaneLogLevel, prefix string, teeFn teeHandler, formatStr string, args ...any) {
-	if ll.shouldLog(level) {
-		text := ll.Constrain(fmt.Sprintf(formatStr, args...))
-
-		msg := fmt.Sprintf("%s %s", props.getMessagePrefix(prefix), text)
-		if ll.cr != "" {
-			msg = strings.ReplaceAll(msg, "\r\n", "\n")
-			msg = strings.ReplaceAll(msg, "\n", ll.cr+"\n")
-			if !strings.Contains(msg, ll.cr) {
-				msg += ll.cr
+// Sends a message to the event sink or encoder, or formats it for the output,
+// unless a suppression rule mutes it
+func (ll *logLane) emit(props loggingProperties, level LaneLogLevel, prefix string, text string) {
+	if suppressionActive() {
+		if suppressed, audit := checkSuppression(level, text); suppressed {
+			if audit != "" {
+				ll.emitUnsuppressed(props, LogLevelWarn, "WARN", audit)
 			}
+			return
 		}
-		ll.writer.Print(msg)
-		ll.logStackIf(props, level, "", 0)
 	}
-	ll.tee(props, teeFn)
+	ll.emitUnsuppressed(props, level, prefix, text)
+}
+
+func (ll *logLane) emitUnsuppressed(props loggingProperties, level LaneLogLevel, prefix string, text string) {
+	ll.counters.countEvent(level)
+	caller := ll.callerInfo()
+	var rec *Record
+	if ll.sink != nil || ll.encoder.Load() != nil || ll.records.active() || ll.levelHooked(level) {
+		r := ll.makeRecord(props, level, text)
+		r.Caller = caller
+		rec = &r
+	}
+	if ll.sink != nil || ll.encoder.Load() != nil {
+		ll.deliver(rec)
+		ll.sendRecord(rec)
+		return
+	}
+
+	head := props.getMessagePrefix(prefix)
+	if caller != "" {
+		head = fmt.Sprintf("%s %s:", head, caller)
+	}
+	lines := MultiLinePolicy(ll.multiLine.Load()).apply(text)
+	if len(lines) == 1 {
+		ll.print(props, level, text, head+" "+lines[0])
+	} else {
+		id := multiLineRecordId.Add(1)
+		for i, line := range lines {
+			ll.print(props, level, text, fmt.Sprintf("%s rec=%d %d/%d %s", head, id, i+1, len(lines), line))
+		}
+	}
+	ll.sendRecord(rec)
+}
+
+func (ll *logLane) makeRecord(props loggingProperties, level LaneLogLevel, text string) Record {
+	t := ll.now()
+	if loc := ll.location.Load(); loc != nil {
+		t = t.In(loc)
+	}
+	rec := Record{
+		Time:      t,
+		LaneId:    props.laneId,
+		JourneyId: props.journeyId,
+		Category:  props.category,
+		ParentId:  props.parentId,
+		Depth:     props.indent,
+		Level:     level,
+		Message:   text,
+	}
+	if fields := ll.MetadataMap(); len(fields) > 0 {
+		rec.Fields = fields
+	}
+	return rec
+}
+
+// Hands a record to the record sinks and level hooks, if there are any
+func (ll *logLane) sendRecord(rec *Record) {
+	if rec != nil && ll.records.active() {
+		ll.records.send(rec)
+	}
+	ll.runLevelHooks(rec)
+}
+
+// Hands a record to the event sink, or writes it with the lane's encoder
+func (ll *logLane) deliver(rec *Record) {
+	if ll.sink != nil {
+		ll.sink.receiveRecord(rec)
+		return
+	}
+
+	enc := ll.encoder.Load()
+	if _, err := ll.writer.Writer().Write((*enc).AppendRecord(nil, rec)); err != nil {
+		ll.reportError(err, *rec)
+	}
 }
 
 func (ll *logLane) LaneProps() loggingProperties {
-	ll.mu.Lock()
-	defer ll.mu.Unlock()
-	return loggingProperties{
+	return *ll.props.Load()
+}
+
+// Replaces the snapshot of the logging properties read by LaneProps() after
+// a change to them. The caller holds ll.mu.
+func (ll *logLane) publishProps() {
+	ll.props.Store(&loggingProperties{
 		laneId:    ll.LaneId(),
 		journeyId: ll.journeyId,
+		category:  ll.category,
+		parentId:  ll.shownParentId(),
+		indent:    ll.indent,
+	})
+}
+
+// Provides the parent lane's ID if it is shown in the output. The caller
+// holds ll.mu.
+func (ll *logLane) shownParentId() string {
+	if ll.showParent {
+		return ll.parentId
 	}
+	return ""
+}
+
+func (ll *logLane) SetParentIdOutput(enable bool) (prior bool) {
+	ll.mu.Lock()
+	defer ll.mu.Unlock()
+	prior = ll.showParent
+	ll.showParent = enable
+	ll.publishProps()
+	return
+}
+
+func (ll *logLane) Category(category string) Lane {
+	return deriveCategory(ll.outer, category)
+}
+
+func (ll *logLane) setCategory(category string) {
+	ll.mu.Lock()
+	defer ll.mu.Unlock()
+	ll.category = category
+	ll.publishProps()
+}
+
+func (ll *logLane) Indent() Lane {
+	return deriveIndent(ll.outer)
+}
+
+func (ll *logLane) setIndent(depth int) {
+	ll.mu.Lock()
+	defer ll.mu.Unlock()
+	ll.indent = depth
+	ll.publishProps()
 }
 
 func (ll *logLane) Trace(args ...any) {
+	if ll.discards(LogLevelTrace) {
+		return
+	}
 	ll.TraceInternal(ll.LaneProps(), args...)
 }
 
 func (ll *logLane) Tracef(format string, args ...any) {
+	if ll.discards(LogLevelTrace) {
+		return
+	}
 	ll.TracefInternal(ll.LaneProps(), format, args...)
 }
 
@@ -318,10 +706,16 @@ func (ll *logLane) TraceObject(message string, obj any) {
 }
 
 func (ll *logLane) Debug(args ...any) {
+	if ll.discards(LogLevelDebug) {
+		return
+	}
 	ll.DebugInternal(ll.LaneProps(), args...)
 }
 
 func (ll *logLane) Debugf(format string, args ...any) {
+	if ll.discards(LogLevelDebug) {
+		return
+	}
 	ll.DebugfInternal(ll.LaneProps(), format, args...)
 }
 
@@ -330,10 +724,16 @@ func (ll *logLane) DebugObject(message string, obj any) {
 }
 
 func (ll *logLane) Info(args ...any) {
+	if ll.discards(LogLevelInfo) {
+		return
+	}
 	ll.InfoInternal(ll.LaneProps(), args...)
 }
 
 func (ll *logLane) Infof(format string, args ...any) {
+	if ll.discards(LogLevelInfo) {
+		return
+	}
 	ll.InfofInternal(ll.LaneProps(), format, args...)
 }
 
@@ -342,10 +742,16 @@ func (ll *logLane) InfoObject(message string, obj any) {
 }
 
 func (ll *logLane) Warn(args ...any) {
+	if ll.discards(LogLevelWarn) {
+		return
+	}
 	ll.WarnInternal(ll.LaneProps(), args...)
 }
 
 func (ll *logLane) Warnf(format string, args ...any) {
+	if ll.discards(LogLevelWarn) {
+		return
+	}
 	ll.WarnfInternal(ll.LaneProps(), format, args...)
 }
 
@@ -354,10 +760,16 @@ func (ll *logLane) WarnObject(message string, obj any) {
 }
 
 func (ll *logLane) Error(args ...any) {
+	if ll.discards(LogLevelError) {
+		return
+	}
 	ll.ErrorInternal(ll.LaneProps(), args...)
 }
 
 func (ll *logLane) Errorf(format string, args ...any) {
+	if ll.discards(LogLevelError) {
+		return
+	}
 	ll.ErrorfInternal(ll.LaneProps(), format, args...)
 }
 
@@ -365,11 +777,41 @@ func (ll *logLane) ErrorObject(message string, obj any) {
 	LogObject(ll, LogLevelError, message, obj)
 }
 
+func (ll *logLane) InfoOnce(key string, args ...any) {
+	logOnce(ll.outer, LogLevelInfo, key, ll.firstUse, func() string { return sprint(args...) })
+}
+
+func (ll *logLane) WarnOnce(key string, args ...any) {
+	logOnce(ll.outer, LogLevelWarn, key, ll.firstUse, func() string { return sprint(args...) })
+}
+
+func (ll *logLane) WarnRate(key string, period time.Duration, args ...any) {
+	logRateLimited(ll.outer, &ll.logLimitStore, LogLevelWarn, key, period, ll.now(), func() string { return sprint(args...) })
+}
+
+func (ll *logLane) WarnRatef(key string, period time.Duration, format string, args ...any) {
+	logRateLimited(ll.outer, &ll.logLimitStore, LogLevelWarn, key, period, ll.now(), func() string { return fmt.Sprintf(format, args...) })
+}
+
+func (ll *logLane) ErrorRate(key string, period time.Duration, args ...any) {
+	logRateLimited(ll.outer, &ll.logLimitStore, LogLevelError, key, period, ll.now(), func() string { return sprint(args...) })
+}
+
+func (ll *logLane) ErrorRatef(key string, period time.Duration, format string, args ...any) {
+	logRateLimited(ll.outer, &ll.logLimitStore, LogLevelError, key, period, ll.now(), func() string { return fmt.Sprintf(format, args...) })
+}
+
 func (ll *logLane) PreFatal(args ...any) {
+	if ll.discards(LogLevelFatal) {
+		return
+	}
 	ll.PreFatalInternal(ll.LaneProps(), args...)
 }
 
 func (ll *logLane) PreFatalf(format string, args ...any) {
+	if ll.discards(LogLevelFatal) {
+		return
+	}
 	ll.PreFatalfInternal(ll.LaneProps(), format, args...)
 }
 
@@ -378,39 +820,75 @@ func (ll *logLane) PreFatalObject(message string, obj any) {
 }
 
 func (ll *logLane) Fatal(args ...any) {
+	args = fatalArgs(ll, args)
 	ll.FatalInternal(ll.LaneProps(), args...)
+	ll.writeCrashReport(sprint(args...))
 	ll.onPanic()
 }
 
 func (ll *logLane) Fatalf(format string, args ...any) {
+	format, args = fatalfArgs(ll, format, args)
 	ll.FatalfInternal(ll.LaneProps(), format, args...)
+	ll.writeCrashReport(fmt.Sprintf(format, args...))
 	ll.onPanic()
 }
 
 func (ll *logLane) FatalObject(message string, obj any) {
+	message = annotateFatal(ll, message)
 	ll.PreFatalObject(message, obj)
+	ll.writeCrashReport(message)
 	ll.onPanic()
 }
 
 func (ll *logLane) logStackIf(props loggingProperties, level LaneLogLevel, message string, skipCallers int) {
-	if ll.stackTrace[level].Load() && level != LogLevelStack {
-		ll.logStack(props, message, skipCallers)
+	if ll.stackEnabled(level) && level != LogLevelStack {
+		if level == LogLevelFatal && message == "" {
+			// the stack record of a fatal error carries the reason the context is done
+			message = contextDoneReason(ll)
+		}
+		ll.logStack(props, level, message, skipCallers)
 	}
 }
 
-func (ll *logLane) logStack(props loggingProperties, message string, skipCallers int) {
-	buf := make([]byte, 16384)
-	n := runtime.Stack(buf, false)
-	lines := cleanStack(buf[:n], skipCallers)
+// Logs the stack, limited to the stack trace depth of [level]
+func (ll *logLane) logStack(props loggingProperties, level LaneLogLevel, message string, skipCallers int) {
+	lines, goroutineId := captureStack(skipCallers, ll.stackDepth(level))
+	if stackHeaders.Load() {
+		lines = append([]string{stackHeader(goroutineId, props)}, lines...)
+	}
+	if message != "" {
+		ll.counters.countEvent(LogLevelStack)
+	}
+	for range lines {
+		ll.counters.countEvent(LogLevelStack)
+	}
+
+	var rec *Record
+	if ll.sink != nil || ll.encoder.Load() != nil || ll.records.active() {
+		r := ll.makeRecord(props, LogLevelStack, ll.constrainLevel(LogLevelStack, message))
+		r.Stack = make([]string, 0, len(lines))
+		for _, line := range lines {
+			r.Stack = append(r.Stack, ll.constrainLevel(LogLevelStack, line))
+		}
+		rec = &r
+	}
+	if ll.sink != nil || ll.encoder.Load() != nil {
+		ll.deliver(rec)
+		ll.sendRecord(rec)
+		return
+	}
 
 	if message != "" {
-		ll.writer.Printf("%s %s%s", props.getMessagePrefix("STACK"), ll.Constrain(message), ll.cr)
+		text := ll.constrainLevel(LogLevelStack, message)
+		ll.print(props, LogLevelStack, text, fmt.Sprintf("%s %s", props.getMessagePrefix("STACK"), text))
 	}
 
 	// each has two lines (the function name on one line, followed by source info on the next line)
 	for _, line := range lines {
-		ll.writer.Printf("%s %s%s", props.getMessagePrefix("STACK"), ll.Constrain(line), ll.cr)
+		text := ll.constrainLevel(LogLevelStack, line)
+		ll.print(props, LogLevelStack, text, fmt.Sprintf("%s %s", props.getMessagePrefix("STACK"), text))
 	}
+	ll.sendRecord(rec)
 }
 
 func (ll *logLane) LogStack(message string) {
@@ -421,24 +899,45 @@ func (ll *logLane) LogStackTrim(message string, skippedCallers int) {
 	ll.LogStackTrimInternal(ll.LaneProps(), message, skippedCallers)
 }
 
-func (ll *logLane) SetLengthConstraint(maxLength int) int {
-	old := ll.maxLength.Load()
-	if maxLength > 1 {
-		ll.maxLength.Store(int32(maxLength))
-	} else {
-		ll.maxLength.Store(0)
+func (ll *logLane) Logger() *log.Logger {
+	return ll.wlog
+}
+
+func (ll *logLane) WriterAt(level LaneLogLevel) io.WriteCloser {
+	return newLevelWriter(ll.outer, level)
+}
+
+func (ll *logLane) Close() error {
+	return ll.outer.CloseWithContext(context.Background())
+}
+
+func (ll *logLane) CloseWithContext(ctx context.Context) error {
+	ll.detachTree(ll.outer)
+	if ll.runCloseHooks(ll.outer) {
+		return ll.closeTees(ctx, ll.Tees())
 	}
-	return int(old)
+	return nil
 }
 
-func (ll *logLane) Logger() *log.Logger {
-	return ll.wlog
+// Provides the counters shared by the lane and its derivations
+func (ll *logLane) Stats() LaneStats {
+	return ll.counters.snapshot()
 }
 
-func (ll *logLane) Close() {
+// Provides the most recent failure of the lane or its derivations, including
+// the delivery failures of an outer lane type
+func (ll *logLane) LastError() error {
+	return StatsOf(ll.outer).LastError
+}
+
+func (ll *logLane) treeInfo() laneTreeInfo {
+	return ll.makeTreeInfo(LaneLogLevel(atomic.LoadInt32(&ll.level)))
 }
 
 func (ll *logLane) Derive() Lane {
+	if ll.exceedsMaxDepth() && ll.plain {
+		return ll.deriveSibling()
+	}
 	l, err := deriveLogLane(ll, ll, nil, ll.onCreateLane)
 	if err != nil {
 		l.Fatal(err)
@@ -541,6 +1040,10 @@ func (ll *logLane) DeriveWithTimeoutCause(duration time.Duration, cause error) (
 	return l, cancelFn
 }
 
+func (ll *logLane) DeriveWithMaxTimeout(maxTimeout time.Duration) (Lane, context.CancelFunc) {
+	return deriveWithMaxTimeout(ll.outer, maxTimeout)
+}
+
 func (ll *logLane) DeriveReplaceContext(ctx OptionalContext) Lane {
 	if ctx == nil {
 		ctx = context.Background()
@@ -556,48 +1059,61 @@ func (ll *logLane) DeriveReplaceContext(ctx OptionalContext) Lane {
 	return l
 }
 
+func (ll *logLane) WithSoftTimeout(d time.Duration, onTimeout func(l Lane)) (stop func() bool) {
+	return startSoftTimeout(ll.outer, d, onTimeout)
+}
+
 func (ll *logLane) LaneId() string {
 	return ll.Value(LogLaneIdKey).(string)
 }
 
-func (ll *logLane) JourneyId() string {
-	ll.mu.Lock()
-	defer ll.mu.Unlock()
-	return ll.journeyId
+// Provides the context value for [key], and the lane itself for FromContext()
+func (ll *logLane) Value(key any) any {
+	if key == (laneContextKey{}) {
+		return ll.outer
+	}
+	return ll.Context.Value(key)
 }
 
-func (ll *logLane) EnableStackTrace(level LaneLogLevel, enable bool) bool {
-	return ll.stackTrace[level].Swap(enable)
+func (ll *logLane) JourneyId() string {
+	return ll.props.Load().journeyId
 }
 
+// The tee list is copied on write, as it is read without the lock and
+// derived lanes share it
 func (ll *logLane) AddTee(l Lane) {
 	ll.mu.Lock()
-	for _, t := range ll.tees {
+	defer ll.mu.Unlock()
+
+	current := *ll.tees.Load()
+	for _, t := range current {
 		if t.LaneId() == l.LaneId() {
 			// can't create a cyclical tee
 			panic("tee points to itself")
 		}
 	}
-	ll.tees = append(ll.tees, l)
-	ll.mu.Unlock()
+	tees := append(append(make([]Lane, 0, len(current)+1), current...), l)
+	ll.tees.Store(&tees)
 }
 
 func (ll *logLane) RemoveTee(l Lane) {
 	ll.mu.Lock()
-	for i, t := range ll.tees {
+	defer ll.mu.Unlock()
+
+	current := *ll.tees.Load()
+	for i, t := range current {
 		if t.LaneId() == l.LaneId() {
-			ll.tees = append(ll.tees[:i], ll.tees[i+1:]...)
+			tees := append(append([]Lane{}, current[:i]...), current[i+1:]...)
+			ll.tees.Store(&tees)
 			break
 		}
 	}
-	ll.mu.Unlock()
 }
 
 func (ll *logLane) Tees() []Lane {
-	ll.mu.Lock()
-	defer ll.mu.Unlock()
-	tees := make([]Lane, len(ll.tees))
-	copy(tees, ll.tees)
+	current := *ll.tees.Load()
+	tees := make([]Lane, len(current))
+	copy(tees, current)
 	return tees
 }
 
@@ -612,11 +1128,17 @@ func (ll *logLane) SetPanicHandler(handler Panic) {
 }
 
 func (ll *logLane) SetFlagsMask(mask int) (prior int) {
-	ll.mu.Lock()
-	defer ll.mu.Unlock()
+	return int(ll.logMask.Swap(int32(mask)))
+}
 
-	prior = ll.logMask
-	ll.logMask = mask
+func (ll *logLane) SetEncoder(enc Encoder) (prior Encoder) {
+	var p *Encoder
+	if enc != nil {
+		p = &enc
+	}
+	if old := ll.encoder.Swap(p); old != nil {
+		prior = *old
+	}
 	return
 }
 
@@ -647,11 +1169,16 @@ func (wlw *wrappedLogWriter) Write(p []byte) (n int, err error) {
 			cuts--
 		}
 	}
-	wlw.outer.Info(text)
+	level, text := wlw.ll.parseLevel(strings.TrimSuffix(text, "\n"))
+	logAtLevel(wlw.outer, level, text)
 
 	return len(p), nil
 }
 
+func (ll *logLane) parseLevel(text string) (level LaneLogLevel, message string) {
+	return ll.levelParser.parse(text)
+}
+
 func (ll *logLane) Parent() Lane {
 	if ll.parent != nil {
 		return ll.parent
@@ -701,10 +1228,38 @@ func (ll *logLane) ErrorfInternal(props loggingProperties, format string, args .
 
 func (ll *logLane) PreFatalInternal(props loggingProperties, args ...any) {
 	ll.printMsg(ll.LaneProps(), LogLevelFatal, "FATAL", func(teeProps loggingProperties, li laneInternal) { li.PreFatalInternal(teeProps, args...) }, args...)
+	ll.flush()
 }
 
 func (ll *logLane) PreFatalfInternal(props loggingProperties, format string, args ...any) {
 	ll.printfMsg(ll.LaneProps(), LogLevelFatal, "FATAL", func(teeProps loggingProperties, li laneInternal) { li.PreFatalfInternal(teeProps, format, args...) }, format, args...)
+	ll.flush()
+}
+
+func (ll *logLane) ObjectInternal(props loggingProperties, level LaneLogLevel, message string, obj any) {
+	var text string
+	if ll.shouldLog(props, level) {
+		text = renderObjectText(props, ll.outer.(laneInternal), level, message, obj, ll.ObjectOptions())
+	}
+	ll.objectTextInternal(props, level, text, message, obj)
+}
+
+func (ll *logLane) objectTextInternal(props loggingProperties, level LaneLogLevel, text, message string, obj any) {
+	if level == logLevelPreFatal {
+		level = LogLevelFatal
+	}
+	teeFn := func(teeProps loggingProperties, li laneInternal) { li.ObjectInternal(teeProps, level, message, obj) }
+	ll.printMsg(props, level, levelNames[level], teeFn, text)
+	if level == LogLevelFatal {
+		ll.flush()
+	}
+}
+
+// Writes buffered output, so that it isn't lost when the process terminates
+func (ll *logLane) flush() {
+	if of, ok := ll.writer.Writer().(outputFlusher); ok {
+		of.Flush()
+	}
 }
 
 func (ll *logLane) FatalInternal(props loggingProperties, args ...any) {
@@ -717,11 +1272,39 @@ func (ll *logLane) FatalfInternal(props loggingProperties, format string, args .
 	// panic will happen in a moment on the externally called Fatalf()
 }
 
+func (ll *logLane) replayRecord(rec *Record) {
+	props := rec.props()
+	ll.counters.countEvent(rec.Level)
+
+	if ll.sink != nil || ll.encoder.Load() != nil {
+		r := *rec
+		ll.deliver(&r)
+		ll.sendRecord(&r)
+	} else {
+		if rec.Level != LogLevelStack || rec.Message != "" {
+			head := props.getMessagePrefix(levelNames[rec.Level])
+			if rec.Caller != "" {
+				head = fmt.Sprintf("%s %s:", head, rec.Caller)
+			}
+			ll.printAt(rec.Time, props, rec.Level, rec.Message, head+" "+rec.Message)
+		}
+		for _, line := range rec.Stack {
+			ll.printAt(rec.Time, props, LogLevelStack, line, props.getMessagePrefix("STACK")+" "+line)
+		}
+		r := *rec
+		ll.sendRecord(&r)
+	}
+
+	ll.tee(props, rec.Level, func(teeProps loggingProperties, li laneInternal) {
+		li.replayRecord(rec)
+	})
+}
+
 func (ll *logLane) LogStackTrimInternal(props loggingProperties, message string, skippedCallers int) {
-	if ll.shouldLog(LogLevelStack) {
-		ll.logStack(props, message, skippedCallers)
+	if ll.shouldLog(props, LogLevelStack) {
+		ll.logStack(props, LogLevelStack, message, skippedCallers)
 	}
-	ll.tee(props, func(teeProps loggingProperties, li laneInternal) {
+	ll.tee(props, LogLevelStack, func(teeProps loggingProperties, li laneInternal) {
 		li.LogStackTrimInternal(teeProps, message, skippedCallers)
 	})
 }
@@ -0,0 +1,90 @@
+package lane
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type (
+	// Renders records as logfmt lines, such as
+	//
+	//	ts=2024-03-04T05:06:07Z level=info lane=abc msg="hello world"
+	//
+	// with a journey key when a journey ID is set, a category key when the
+	// lane has a category, a parent key when the lane shows its parent's ID,
+	// a depth key when the lane is indented, and a caller key when caller info is enabled, followed by the fields in key order, and the stack
+	// lines as one quoted value.
+	LogfmtEncoder struct{}
+)
+
+func (LogfmtEncoder) AppendRecord(buf []byte, rec *Record) []byte {
+	buf = append(buf, "ts="...)
+	buf = rec.Time.AppendFormat(buf, time.RFC3339Nano)
+	buf = appendLogfmtPair(buf, "level", strings.ToLower(levelNames[rec.Level]))
+	buf = appendLogfmtPair(buf, "lane", rec.LaneId)
+	if rec.JourneyId != "" {
+		buf = appendLogfmtPair(buf, "journey", rec.JourneyId)
+	}
+	if rec.Category != "" {
+		buf = appendLogfmtPair(buf, "category", rec.Category)
+	}
+	if rec.ParentId != "" {
+		buf = appendLogfmtPair(buf, "parent", rec.ParentId)
+	}
+	if rec.Depth != 0 {
+		buf = appendLogfmtPair(buf, "depth", strconv.Itoa(rec.Depth))
+	}
+	buf = appendLogfmtPair(buf, "msg", rec.Message)
+	if rec.Caller != "" {
+		buf = appendLogfmtPair(buf, "caller", rec.Caller)
+	}
+
+	keys := make([]string, 0, len(rec.Fields))
+	for k := range rec.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		buf = appendLogfmtPair(buf, logfmtKey(k), rec.Fields[k])
+	}
+
+	if len(rec.Stack) > 0 {
+		buf = appendLogfmtPair(buf, "stack", strings.Join(rec.Stack, "\n"))
+	}
+	return append(buf, '\n')
+}
+
+func appendLogfmtPair(buf []byte, key, value string) []byte {
+	buf = append(buf, ' ')
+	buf = append(buf, key...)
+	buf = append(buf, '=')
+	if logfmtNeedsQuotes(value) {
+		return strconv.AppendQuote(buf, value)
+	}
+	return append(buf, value...)
+}
+
+func logfmtNeedsQuotes(value string) bool {
+	if value == "" {
+		return true
+	}
+	return strings.IndexFunc(value, func(r rune) bool {
+		return r <= ' ' || r == '=' || r == '"' || r == '\\' || !unicode.IsPrint(r)
+	}) >= 0
+}
+
+// Replaces the characters that can't appear in a logfmt key
+func logfmtKey(key string) string {
+	if key == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || !unicode.IsPrint(r) {
+			return '_'
+		}
+		return r
+	}, key)
+}
@@ -0,0 +1,51 @@
+package lane
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLogfmtEncoder(t *testing.T) {
+	rec := testRecord()
+	actual := string(LogfmtEncoder{}.AppendRecord(nil, rec))
+	expected := "ts=2024-03-04T05:06:07.000008Z level=warn lane=abc journey=j1 msg=careful\n"
+	if actual != expected {
+		t.Errorf("unexpected logfmt %q", actual)
+	}
+
+	rec.JourneyId = ""
+	rec.Message = `say "hi" a=b`
+	rec.Fields = map[string]string{"user id": "u1", "empty": "", "path": `c:\tmp`}
+	actual = string(LogfmtEncoder{}.AppendRecord(nil, rec))
+	expected = `ts=2024-03-04T05:06:07.000008Z level=warn lane=abc msg="say \"hi\" a=b" empty="" path="c:\\tmp" user_id=u1` + "\n"
+	if actual != expected {
+		t.Errorf("unexpected logfmt %q", actual)
+	}
+
+	rec.Level = LogLevelStack
+	rec.Message = "here"
+	rec.Fields = nil
+	rec.Stack = []string{"main()", "\tmain.go:1"}
+	actual = string(LogfmtEncoder{}.AppendRecord(nil, rec))
+	expected = `ts=2024-03-04T05:06:07.000008Z level=stack lane=abc msg=here stack="main()\n\tmain.go:1"` + "\n"
+	if actual != expected {
+		t.Errorf("unexpected logfmt %q", actual)
+	}
+}
+
+func TestLogLaneLogfmt(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	ll := NewLogLane(nil, WithEncoder(LogfmtEncoder{}))
+	ll.Info("hello world")
+
+	expected := " level=info lane=" + ll.LaneId() + ` msg="hello world"` + "\n"
+	if !strings.HasPrefix(buf.String(), "ts=") || !strings.HasSuffix(buf.String(), expected) {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+}
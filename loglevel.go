@@ -0,0 +1,55 @@
+package lane
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrInvalidLogLevel = errors.New("invalid log level")
+
+// Provides the name of the level as it appears in log output, such as "WARN"
+func (level LaneLogLevel) String() string {
+	if level < LogLevelTrace || level >= logLevelMax {
+		return fmt.Sprintf("LaneLogLevel(%d)", int(level))
+	}
+	return levelNames[level]
+}
+
+// Converts a level name, such as "warn" or "WARN", to its level, so that a
+// level can be read from a flag, an environment variable or a config file.
+func ParseLogLevel(name string) (level LaneLogLevel, err error) {
+	level, found := parseLevelName(name)
+	if !found || level == logLevelPreFatal {
+		err = fmt.Errorf("%w: %q", ErrInvalidLogLevel, name)
+	}
+	return
+}
+
+// Renders the level as its lowercase name, such as "warn", for config files
+// and flag.TextVar().
+func (level LaneLogLevel) MarshalText() ([]byte, error) {
+	if level < LogLevelTrace || level >= logLevelMax || level == logLevelPreFatal {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidLogLevel, int(level))
+	}
+	return []byte(strings.ToLower(levelNames[level])), nil
+}
+
+// Sets the level from its name, in any case, as ParseLogLevel() does.
+func (level *LaneLogLevel) UnmarshalText(text []byte) (err error) {
+	parsed, err := ParseLogLevel(string(text))
+	if err == nil {
+		*level = parsed
+	}
+	return
+}
+
+// Converts a level name such as "info" to its level
+func parseLevelName(name string) (level LaneLogLevel, found bool) {
+	for i, levelName := range levelNames {
+		if strings.EqualFold(name, levelName) {
+			return LaneLogLevel(i), true
+		}
+	}
+	return
+}
@@ -0,0 +1,57 @@
+package lane
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"testing"
+)
+
+func TestLogLevelString(t *testing.T) {
+	if s := fmt.Sprint(LogLevelWarn, LogLevelStack); s != "WARN STACK" {
+		t.Errorf("unexpected text %q", s)
+	}
+	if s := LaneLogLevel(42).String(); s != "LaneLogLevel(42)" {
+		t.Errorf("unexpected text %q", s)
+	}
+}
+
+func TestParseLogLevel(t *testing.T) {
+	for _, name := range []string{"warn", "WARN", "Warn"} {
+		if level, err := ParseLogLevel(name); err != nil || level != LogLevelWarn {
+			t.Errorf("%s: unexpected %v %v", name, level, err)
+		}
+	}
+	for _, name := range []string{"", "warning", "prefatal"} {
+		if _, err := ParseLogLevel(name); !errors.Is(err, ErrInvalidLogLevel) {
+			t.Errorf("%q: unexpected error %v", name, err)
+		}
+	}
+}
+
+func TestLogLevelText(t *testing.T) {
+	var config struct {
+		Level LaneLogLevel `json:"level"`
+	}
+	if err := json.Unmarshal([]byte(`{"level":"Debug"}`), &config); err != nil || config.Level != LogLevelDebug {
+		t.Fatalf("unexpected %v %v", config.Level, err)
+	}
+	raw, err := json.Marshal(config)
+	if err != nil || string(raw) != `{"level":"debug"}` {
+		t.Errorf("unexpected %s %v", raw, err)
+	}
+	if err = json.Unmarshal([]byte(`{"level":"loud"}`), &config); !errors.Is(err, ErrInvalidLogLevel) || config.Level != LogLevelDebug {
+		t.Errorf("unexpected %v %v", config.Level, err)
+	}
+	if _, err = logLevelPreFatal.MarshalText(); !errors.Is(err, ErrInvalidLogLevel) {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	level := LogLevelInfo
+	fs.TextVar(&level, "log-level", LogLevelInfo, "the log level")
+	if err = fs.Parse([]string{"--log-level", "error"}); err != nil || level != LogLevelError {
+		t.Errorf("unexpected %v %v", level, err)
+	}
+}
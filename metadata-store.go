@@ -7,31 +7,67 @@ type (
 		SetOwner(l Lane)
 		SetMetadata(key, value string)
 		GetMetadata(key string) string
+		DeleteMetadata(key string)
 		MetadataMap() map[string]string
+		SetMetadataInheritance(mode MetadataInheritance) (prior MetadataInheritance)
 	}
 
+	// Selects what metadata a derived lane starts with
+	MetadataInheritance int32
+
 	// Common implementation of metadata
 	MetadataStore struct {
-		mu       sync.Mutex
-		l        Lane
-		metadata map[string]string
+		mu          sync.Mutex
+		l           Lane
+		inheritance MetadataInheritance
+		values      *metadataValues
+	}
+
+	// The metadata values, which lanes using MetadataShared have in common
+	metadataValues struct {
+		mu sync.Mutex
+		m  map[string]string
 	}
 )
 
+const (
+	// A derived lane starts without metadata
+	MetadataNone MetadataInheritance = iota
+
+	// A derived lane starts with a copy of the metadata, and later changes
+	// made to either lane aren't seen by the other
+	MetadataCopy
+
+	// A derived lane uses the same metadata values, so a change made to
+	// either lane is seen by both
+	MetadataShared
+)
+
 // Used in lane object creation to link the metadata interface to the owning lane
 func (ms *MetadataStore) SetOwner(l Lane) {
 	ms.l = l
 }
 
-// Sets the lane's metadata value, overwriting a prior value if one was set
-func (ms *MetadataStore) SetMetadata(key, value string) {
+// Provides the metadata values, allocating them on first use
+func (ms *MetadataStore) data() *metadataValues {
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
 
-	if ms.metadata == nil {
-		ms.metadata = map[string]string{}
+	if ms.values == nil {
+		ms.values = &metadataValues{}
+	}
+	return ms.values
+}
+
+// Sets the lane's metadata value, overwriting a prior value if one was set
+func (ms *MetadataStore) SetMetadata(key, value string) {
+	md := ms.data()
+	md.mu.Lock()
+	if md.m == nil {
+		md.m = map[string]string{}
 	}
-	ms.metadata[key] = value
+	md.m[key] = value
+	md.mu.Unlock()
 
 	tees := ms.l.Tees()
 	for _, tee := range tees {
@@ -41,21 +77,67 @@ func (ms *MetadataStore) SetMetadata(key, value string) {
 
 // Retrieves the lane's metadata value if it is set
 func (ms *MetadataStore) GetMetadata(key string) string {
-	ms.mu.Lock()
-	defer ms.mu.Unlock()
+	md := ms.data()
+	md.mu.Lock()
+	defer md.mu.Unlock()
 
-	return ms.metadata[key]
+	return md.m[key]
+}
+
+// Removes the lane's metadata value, and the value of its tees
+func (ms *MetadataStore) DeleteMetadata(key string) {
+	md := ms.data()
+	md.mu.Lock()
+	delete(md.m, key)
+	md.mu.Unlock()
+
+	tees := ms.l.Tees()
+	for _, tee := range tees {
+		tee.DeleteMetadata(key)
+	}
 }
 
 // Returns a copy of the metadata map
 func (ms *MetadataStore) MetadataMap() map[string]string {
+	md := ms.data()
+	md.mu.Lock()
+	defer md.mu.Unlock()
+
+	m := make(map[string]string, len(md.m))
+	for k, v := range md.m {
+		m[k] = v
+	}
+
+	return m
+}
+
+// Selects what metadata lanes derived from this one start with. The derived
+// lanes also take on the mode.
+func (ms *MetadataStore) SetMetadataInheritance(mode MetadataInheritance) (prior MetadataInheritance) {
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
 
-	md := make(map[string]string, len(ms.metadata))
-	for k, v := range ms.metadata {
-		md[k] = v
+	prior = ms.inheritance
+	ms.inheritance = mode
+	return
+}
+
+// Applies the inheritance mode of the [parent] store to a newly derived lane
+func (ms *MetadataStore) inheritMetadata(parent *MetadataStore) {
+	parent.mu.Lock()
+	mode := parent.inheritance
+	parent.mu.Unlock()
+
+	var values *metadataValues
+	switch mode {
+	case MetadataCopy:
+		values = &metadataValues{m: parent.MetadataMap()}
+	case MetadataShared:
+		values = parent.data()
 	}
 
-	return md
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.inheritance = mode
+	ms.values = values
 }
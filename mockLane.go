@@ -0,0 +1,441 @@
+package lane
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// A testing lane that also verifies logging calls with mock expectations,
+	// for unit tests that only need to check that a call such as Errorf was
+	// made with certain arguments. Lanes derived from it share its calls and
+	// expectations.
+	//
+	// Calls are named by the logging method, such as "Error", "Errorf",
+	// "ErrorObject" or "LogStack". The arguments of a formatted call are the
+	// format followed by its arguments. Calls received as a tee are recorded
+	// the same way.
+	MockLane interface {
+		TestingLane
+
+		// Expects a call to [method] with [args], which can include MockAnything.
+		// The call must be made at least once unless changed with Times().
+		On(method string, args ...any) *MockCall
+
+		// Reports a test error for each expectation that wasn't met. It is also
+		// called when the test ends.
+		AssertExpectations() bool
+
+		// Checks that a call to [method] with [args] was made, reporting a test
+		// error if not.
+		AssertCalled(method string, args ...any) bool
+
+		// Checks that no call to [method] with [args] was made, reporting a test
+		// error if one was.
+		AssertNotCalled(method string, args ...any) bool
+
+		// Provides the calls made so far, oldest first.
+		Calls() []MockInvocation
+	}
+
+	// An expected call of a mock lane
+	MockCall struct {
+		method string
+		args   []any
+		times  int // -1 for at least once
+		run    func(args ...any)
+		calls  int
+	}
+
+	// A call made to a mock lane
+	MockInvocation struct {
+		Method string
+		Args   []any
+	}
+
+	mockLane struct {
+		TestingLane
+		m *mockRecorder
+	}
+
+	// Calls and expectations shared by a mock lane and its derivations
+	mockRecorder struct {
+		mu       sync.Mutex
+		t        TestingT
+		expected []*MockCall
+		calls    []MockInvocation
+	}
+
+	mockAnything struct{}
+)
+
+// Argument that matches any value in an expectation
+var MockAnything = mockAnything{}
+
+// The method recorded for an object received from a tee source, by level
+var objectLogMethods = [logLevelMax]string{"TraceObject", "DebugObject", "InfoObject", "WarnObject", "ErrorObject", "FatalObject", "FatalObject", "LogStack"}
+
+// Makes a mock lane that reports unmet expectations to [t] when the test ends
+func NewMockLane(t TestingT, opts ...LaneOption) MockLane {
+	m := &mockRecorder{t: t}
+	ml := &mockLane{TestingLane: NewTestingLane(nil, opts...), m: m}
+	t.Cleanup(func() { ml.AssertExpectations() })
+	return ml
+}
+
+// Requires exactly [n] matching calls; zero requires that no matching call is made
+func (mc *MockCall) Times(n int) *MockCall {
+	mc.times = n
+	return mc
+}
+
+// Calls [fn] with the arguments of each matching call
+func (mc *MockCall) Run(fn func(args ...any)) *MockCall {
+	mc.run = fn
+	return mc
+}
+
+func (mc *MockCall) String() string {
+	return mockCallString(mc.method, mc.args)
+}
+
+func (mc *MockCall) matches(method string, args []any) bool {
+	if mc.method != method || len(mc.args) != len(args) {
+		return false
+	}
+	for i, arg := range mc.args {
+		if arg != MockAnything && !reflect.DeepEqual(arg, args[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func mockCallString(method string, args []any) string {
+	parts := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == MockAnything {
+			parts = append(parts, "<anything>")
+		} else {
+			parts = append(parts, fmt.Sprintf("%#v", arg))
+		}
+	}
+	return fmt.Sprintf("%s(%s)", method, strings.Join(parts, ", "))
+}
+
+func (m *mockRecorder) record(method string, args ...any) {
+	m.mu.Lock()
+	m.calls = append(m.calls, MockInvocation{Method: method, Args: args})
+	var run []func(args ...any)
+	for _, mc := range m.expected {
+		if mc.matches(method, args) {
+			mc.calls++
+			if mc.run != nil {
+				run = append(run, mc.run)
+			}
+		}
+	}
+	m.mu.Unlock()
+
+	for _, fn := range run {
+		fn(args...)
+	}
+}
+
+func (ml *mockLane) On(method string, args ...any) *MockCall {
+	mc := &MockCall{method: method, args: args, times: -1}
+	ml.m.mu.Lock()
+	defer ml.m.mu.Unlock()
+	ml.m.expected = append(ml.m.expected, mc)
+	return mc
+}
+
+func (ml *mockLane) AssertExpectations() bool {
+	ml.m.t.Helper()
+	ml.m.mu.Lock()
+	defer ml.m.mu.Unlock()
+
+	ok := true
+	for _, mc := range ml.m.expected {
+		if mc.times < 0 && mc.calls == 0 {
+			ml.m.t.Errorf("expected call %s was not made", mc)
+			ok = false
+		} else if mc.times >= 0 && mc.calls != mc.times {
+			ml.m.t.Errorf("expected call %s %d times, made %d times", mc, mc.times, mc.calls)
+			ok = false
+		}
+	}
+	return ok
+}
+
+func (ml *mockLane) AssertCalled(method string, args ...any) bool {
+	ml.m.t.Helper()
+	if !ml.called(method, args) {
+		ml.m.t.Errorf("expected call %s was not made", mockCallString(method, args))
+		return false
+	}
+	return true
+}
+
+func (ml *mockLane) AssertNotCalled(method string, args ...any) bool {
+	ml.m.t.Helper()
+	if ml.called(method, args) {
+		ml.m.t.Errorf("unexpected call %s", mockCallString(method, args))
+		return false
+	}
+	return true
+}
+
+func (ml *mockLane) called(method string, args []any) bool {
+	mc := MockCall{method: method, args: args}
+	ml.m.mu.Lock()
+	defer ml.m.mu.Unlock()
+	for _, call := range ml.m.calls {
+		if mc.matches(call.Method, call.Args) {
+			return true
+		}
+	}
+	return false
+}
+
+func (ml *mockLane) Calls() []MockInvocation {
+	ml.m.mu.Lock()
+	defer ml.m.mu.Unlock()
+	return append([]MockInvocation{}, ml.m.calls...)
+}
+
+// Wraps a lane derived from the inner testing lane
+func (ml *mockLane) wrap(l Lane) Lane {
+	return &mockLane{TestingLane: l.(TestingLane), m: ml.m}
+}
+
+func (ml *mockLane) Derive() Lane {
+	return ml.wrap(ml.TestingLane.Derive())
+}
+
+func (ml *mockLane) DeriveWithCancel() (Lane, context.CancelFunc) {
+	l, cancel := ml.TestingLane.DeriveWithCancel()
+	return ml.wrap(l), cancel
+}
+
+func (ml *mockLane) DeriveWithCancelCause() (Lane, context.CancelCauseFunc) {
+	l, cancel := ml.TestingLane.DeriveWithCancelCause()
+	return ml.wrap(l), cancel
+}
+
+func (ml *mockLane) DeriveWithoutCancel() Lane {
+	return ml.wrap(ml.TestingLane.DeriveWithoutCancel())
+}
+
+func (ml *mockLane) DeriveWithDeadline(deadline time.Time) (Lane, context.CancelFunc) {
+	l, cancel := ml.TestingLane.DeriveWithDeadline(deadline)
+	return ml.wrap(l), cancel
+}
+
+func (ml *mockLane) DeriveWithDeadlineCause(deadline time.Time, cause error) (Lane, context.CancelFunc) {
+	l, cancel := ml.TestingLane.DeriveWithDeadlineCause(deadline, cause)
+	return ml.wrap(l), cancel
+}
+
+func (ml *mockLane) DeriveWithTimeout(duration time.Duration) (Lane, context.CancelFunc) {
+	l, cancel := ml.TestingLane.DeriveWithTimeout(duration)
+	return ml.wrap(l), cancel
+}
+
+func (ml *mockLane) DeriveWithTimeoutCause(duration time.Duration, cause error) (Lane, context.CancelFunc) {
+	l, cancel := ml.TestingLane.DeriveWithTimeoutCause(duration, cause)
+	return ml.wrap(l), cancel
+}
+
+func (ml *mockLane) DeriveReplaceContext(ctx OptionalContext) Lane {
+	return ml.wrap(ml.TestingLane.DeriveReplaceContext(ctx))
+}
+
+func (ml *mockLane) Trace(args ...any) {
+	ml.m.record("Trace", args...)
+	ml.TestingLane.Trace(args...)
+}
+
+func (ml *mockLane) Tracef(format string, args ...any) {
+	ml.m.record("Tracef", append([]any{format}, args...)...)
+	ml.TestingLane.Tracef(format, args...)
+}
+
+func (ml *mockLane) TraceObject(message string, obj any) {
+	ml.m.record("TraceObject", message, obj)
+	ml.TestingLane.TraceObject(message, obj)
+}
+
+func (ml *mockLane) Debug(args ...any) {
+	ml.m.record("Debug", args...)
+	ml.TestingLane.Debug(args...)
+}
+
+func (ml *mockLane) Debugf(format string, args ...any) {
+	ml.m.record("Debugf", append([]any{format}, args...)...)
+	ml.TestingLane.Debugf(format, args...)
+}
+
+func (ml *mockLane) DebugObject(message string, obj any) {
+	ml.m.record("DebugObject", message, obj)
+	ml.TestingLane.DebugObject(message, obj)
+}
+
+func (ml *mockLane) Info(args ...any) {
+	ml.m.record("Info", args...)
+	ml.TestingLane.Info(args...)
+}
+
+func (ml *mockLane) Infof(format string, args ...any) {
+	ml.m.record("Infof", append([]any{format}, args...)...)
+	ml.TestingLane.Infof(format, args...)
+}
+
+func (ml *mockLane) InfoObject(message string, obj any) {
+	ml.m.record("InfoObject", message, obj)
+	ml.TestingLane.InfoObject(message, obj)
+}
+
+func (ml *mockLane) Warn(args ...any) {
+	ml.m.record("Warn", args...)
+	ml.TestingLane.Warn(args...)
+}
+
+func (ml *mockLane) Warnf(format string, args ...any) {
+	ml.m.record("Warnf", append([]any{format}, args...)...)
+	ml.TestingLane.Warnf(format, args...)
+}
+
+func (ml *mockLane) WarnObject(message string, obj any) {
+	ml.m.record("WarnObject", message, obj)
+	ml.TestingLane.WarnObject(message, obj)
+}
+
+func (ml *mockLane) Error(args ...any) {
+	ml.m.record("Error", args...)
+	ml.TestingLane.Error(args...)
+}
+
+func (ml *mockLane) Errorf(format string, args ...any) {
+	ml.m.record("Errorf", append([]any{format}, args...)...)
+	ml.TestingLane.Errorf(format, args...)
+}
+
+func (ml *mockLane) ErrorObject(message string, obj any) {
+	ml.m.record("ErrorObject", message, obj)
+	ml.TestingLane.ErrorObject(message, obj)
+}
+
+func (ml *mockLane) PreFatal(args ...any) {
+	ml.m.record("PreFatal", args...)
+	ml.TestingLane.PreFatal(args...)
+}
+
+func (ml *mockLane) PreFatalf(format string, args ...any) {
+	ml.m.record("PreFatalf", append([]any{format}, args...)...)
+	ml.TestingLane.PreFatalf(format, args...)
+}
+
+func (ml *mockLane) PreFatalObject(message string, obj any) {
+	ml.m.record("PreFatalObject", message, obj)
+	ml.TestingLane.PreFatalObject(message, obj)
+}
+
+func (ml *mockLane) Fatal(args ...any) {
+	ml.m.record("Fatal", args...)
+	ml.TestingLane.Fatal(args...)
+}
+
+func (ml *mockLane) Fatalf(format string, args ...any) {
+	ml.m.record("Fatalf", append([]any{format}, args...)...)
+	ml.TestingLane.Fatalf(format, args...)
+}
+
+func (ml *mockLane) FatalObject(message string, obj any) {
+	ml.m.record("FatalObject", message, obj)
+	ml.TestingLane.FatalObject(message, obj)
+}
+
+func (ml *mockLane) LogStack(message string) {
+	ml.m.record("LogStack", message)
+	ml.TestingLane.LogStack(message)
+}
+
+func (ml *mockLane) LogStackTrim(message string, skippedCallers int) {
+	ml.m.record("LogStackTrim", message, skippedCallers)
+	ml.TestingLane.LogStackTrim(message, skippedCallers)
+}
+
+func (ml *mockLane) TraceInternal(props loggingProperties, args ...any) {
+	ml.m.record("Trace", args...)
+	ml.TestingLane.TraceInternal(props, args...)
+}
+
+func (ml *mockLane) TracefInternal(props loggingProperties, format string, args ...any) {
+	ml.m.record("Tracef", append([]any{format}, args...)...)
+	ml.TestingLane.TracefInternal(props, format, args...)
+}
+
+func (ml *mockLane) DebugInternal(props loggingProperties, args ...any) {
+	ml.m.record("Debug", args...)
+	ml.TestingLane.DebugInternal(props, args...)
+}
+
+func (ml *mockLane) DebugfInternal(props loggingProperties, format string, args ...any) {
+	ml.m.record("Debugf", append([]any{format}, args...)...)
+	ml.TestingLane.DebugfInternal(props, format, args...)
+}
+
+func (ml *mockLane) InfoInternal(props loggingProperties, args ...any) {
+	ml.m.record("Info", args...)
+	ml.TestingLane.InfoInternal(props, args...)
+}
+
+func (ml *mockLane) InfofInternal(props loggingProperties, format string, args ...any) {
+	ml.m.record("Infof", append([]any{format}, args...)...)
+	ml.TestingLane.InfofInternal(props, format, args...)
+}
+
+func (ml *mockLane) WarnInternal(props loggingProperties, args ...any) {
+	ml.m.record("Warn", args...)
+	ml.TestingLane.WarnInternal(props, args...)
+}
+
+func (ml *mockLane) WarnfInternal(props loggingProperties, format string, args ...any) {
+	ml.m.record("Warnf", append([]any{format}, args...)...)
+	ml.TestingLane.WarnfInternal(props, format, args...)
+}
+
+func (ml *mockLane) ErrorInternal(props loggingProperties, args ...any) {
+	ml.m.record("Error", args...)
+	ml.TestingLane.ErrorInternal(props, args...)
+}
+
+func (ml *mockLane) ErrorfInternal(props loggingProperties, format string, args ...any) {
+	ml.m.record("Errorf", append([]any{format}, args...)...)
+	ml.TestingLane.ErrorfInternal(props, format, args...)
+}
+
+func (ml *mockLane) PreFatalInternal(props loggingProperties, args ...any) {
+	ml.m.record("PreFatal", args...)
+	ml.TestingLane.PreFatalInternal(props, args...)
+}
+
+func (ml *mockLane) PreFatalfInternal(props loggingProperties, format string, args ...any) {
+	ml.m.record("PreFatalf", append([]any{format}, args...)...)
+	ml.TestingLane.PreFatalfInternal(props, format, args...)
+}
+
+func (ml *mockLane) LogStackTrimInternal(props loggingProperties, message string, skippedCallers int) {
+	ml.m.record("LogStackTrim", message, skippedCallers)
+	ml.TestingLane.LogStackTrimInternal(props, message, skippedCallers)
+}
+
+func (ml *mockLane) ObjectInternal(props loggingProperties, level LaneLogLevel, message string, obj any) {
+	ml.m.record(objectLogMethods[level], message, obj)
+	ml.TestingLane.ObjectInternal(props, level, message, obj)
+}
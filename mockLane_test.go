@@ -0,0 +1,106 @@
+package lane
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type (
+	// Captures the failures reported to a TestingT
+	testFailureRecorder struct {
+		failures []string
+		cleanups []func()
+	}
+)
+
+func (tfr *testFailureRecorder) Helper() {}
+
+func (tfr *testFailureRecorder) Errorf(format string, args ...any) {
+	tfr.failures = append(tfr.failures, fmt.Sprintf(format, args...))
+}
+
+func (tfr *testFailureRecorder) Cleanup(fn func()) {
+	tfr.cleanups = append(tfr.cleanups, fn)
+}
+
+func (tfr *testFailureRecorder) finish() {
+	for i := len(tfr.cleanups) - 1; i >= 0; i-- {
+		tfr.cleanups[i]()
+	}
+}
+
+func TestMockLaneExpectations(t *testing.T) {
+	ml := NewMockLane(t)
+	ml.On("Errorf", "failed to open %s: %v", "config.json", MockAnything)
+	ml.On("Info", "ready").Times(2)
+
+	ml.Info("ready")
+	ml.Errorf("failed to open %s: %v", "config.json", fmt.Errorf("denied"))
+	child := ml.Derive()
+	child.Info("ready")
+	child.Warn("not expected, but allowed")
+
+	ml.AssertCalled("Warn", "not expected, but allowed")
+	ml.AssertNotCalled("Error", MockAnything)
+
+	// the calls are still captured as test events
+	if !ml.VerifyEventText("INFO\tready\nERROR\tfailed to open config.json: denied") {
+		t.Errorf("unexpected events:\n%s", ml.EventsToString())
+	}
+	if len(ml.Calls()) != 4 {
+		t.Errorf("unexpected calls %v", ml.Calls())
+	}
+}
+
+func TestMockLaneUnmetExpectations(t *testing.T) {
+	tfr := &testFailureRecorder{}
+	ml := NewMockLane(tfr)
+	ml.On("Error", "expected")
+	ml.On("Warnf", "count %d", 1).Times(2)
+	ml.Warnf("count %d", 1)
+
+	ml.AssertCalled("Info", MockAnything)
+	ml.Info("now")
+	ml.AssertNotCalled("Info", "now")
+
+	tfr.finish()
+	expected := []string{
+		`expected call Info(<anything>) was not made`,
+		`unexpected call Info("now")`,
+		`expected call Error("expected") was not made`,
+		`expected call Warnf("count %d", 1) 2 times, made 1 times`,
+	}
+	if strings.Join(tfr.failures, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected failures:\n%s", strings.Join(tfr.failures, "\n"))
+	}
+}
+
+func TestMockLaneTee(t *testing.T) {
+	var received []any
+	ml := NewMockLane(t)
+	ml.On("ErrorObject", "bad input", MockAnything).Times(0)
+	ml.On("Errorf", "code %d", 42).Run(func(args ...any) { received = args })
+
+	ll := NewLogLane(nil)
+	ll.AddTee(ml)
+	ll.Errorf("code %d", 42)
+
+	if len(received) != 2 || received[1] != 42 {
+		t.Errorf("unexpected arguments %v", received)
+	}
+}
+
+func TestMockLaneReceivesTeeObjects(t *testing.T) {
+	ml := NewMockLane(t)
+
+	tl := NewTestingLane(nil)
+	tl.SetObjectOptions(LogObjectOpt{TeeObjects: true})
+	tl.AddTee(ml)
+	tl.InfoObject("payload", 7)
+
+	ml.AssertCalled("InfoObject", "payload", 7)
+	if events := ml.Events(); len(events) != 1 || events[0].Object != 7 {
+		t.Errorf("unexpected events:\n%s", ml.EventsToString())
+	}
+}
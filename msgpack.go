@@ -0,0 +1,300 @@
+package lane
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+type (
+	// A MessagePack extension value
+	msgpackExt struct {
+		typ  int8
+		data []byte
+	}
+)
+
+var errMsgpackType = errors.New("unsupported msgpack type")
+
+// Appends the MessagePack encoding of v, which is made of nil, bool,
+// integers, float64, string, []byte, []any, map[string]any and msgpackExt.
+// Map keys are written in sorted order.
+func appendMsgpack(b []byte, v any) []byte {
+	switch t := v.(type) {
+	case nil:
+		return append(b, 0xc0)
+	case bool:
+		if t {
+			return append(b, 0xc3)
+		}
+		return append(b, 0xc2)
+	case int:
+		return appendMsgpackInt(b, int64(t))
+	case int64:
+		return appendMsgpackInt(b, t)
+	case uint64:
+		if t > math.MaxInt64 {
+			return binary.BigEndian.AppendUint64(append(b, 0xcf), t)
+		}
+		return appendMsgpackInt(b, int64(t))
+	case float64:
+		return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(t))
+	case string:
+		return append(appendMsgpackLength(b, len(t), 0xa0, 31, 0xd9, 0xda, 0xdb), t...)
+	case []byte:
+		return append(appendMsgpackLength(b, len(t), 0, -1, 0xc4, 0xc5, 0xc6), t...)
+	case []any:
+		b = appendMsgpackLength(b, len(t), 0x90, 15, 0, 0xdc, 0xdd)
+		for _, elem := range t {
+			b = appendMsgpack(b, elem)
+		}
+		return b
+	case map[string]any:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		b = appendMsgpackLength(b, len(t), 0x80, 15, 0, 0xde, 0xdf)
+		for _, k := range keys {
+			b = appendMsgpack(b, k)
+			b = appendMsgpack(b, t[k])
+		}
+		return b
+	case msgpackExt:
+		switch len(t.data) {
+		case 1:
+			b = append(b, 0xd4)
+		case 2:
+			b = append(b, 0xd5)
+		case 4:
+			b = append(b, 0xd6)
+		case 8:
+			b = append(b, 0xd7)
+		case 16:
+			b = append(b, 0xd8)
+		default:
+			b = appendMsgpackLength(b, len(t.data), 0, -1, 0xc7, 0xc8, 0xc9)
+		}
+		return append(append(b, byte(t.typ)), t.data...)
+	default:
+		panic(fmt.Sprintf("%v: %T", errMsgpackType, v))
+	}
+}
+
+func appendMsgpackInt(b []byte, n int64) []byte {
+	switch {
+	case n >= 0 && n <= 127:
+		return append(b, byte(n))
+	case n < 0 && n >= -32:
+		return append(b, byte(n))
+	case n >= math.MinInt8 && n <= math.MaxInt8:
+		return append(b, 0xd0, byte(n))
+	case n >= math.MinInt16 && n <= math.MaxInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(n))
+	case n >= math.MinInt32 && n <= math.MaxInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(n))
+	}
+}
+
+// Appends a length header, using the fixed form when the length is at most
+// fixMax, then the 8, 16 or 32 bit forms (a zero code skips a form)
+func appendMsgpackLength(b []byte, n int, fixCode byte, fixMax int, code8, code16, code32 byte) []byte {
+	switch {
+	case n <= fixMax:
+		return append(b, fixCode|byte(n))
+	case code8 != 0 && n <= math.MaxUint8:
+		return append(b, code8, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, code16), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, code32), uint32(n))
+	}
+}
+
+// Reads one MessagePack value. Maps are decoded as map[string]any when
+// their keys are strings, or map[any]any otherwise.
+func readMsgpack(r *bufio.Reader) (v any, err error) {
+	code, err := r.ReadByte()
+	if err != nil {
+		return
+	}
+
+	switch {
+	case code <= 0x7f:
+		return int64(code), nil
+	case code >= 0xe0:
+		return int64(int8(code)), nil
+	case code&0xf0 == 0x80:
+		return readMsgpackMap(r, int(code&0x0f))
+	case code&0xf0 == 0x90:
+		return readMsgpackArray(r, int(code&0x0f))
+	case code&0xe0 == 0xa0:
+		return readMsgpackString(r, int(code&0x1f))
+	}
+
+	switch code {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		var n int
+		if n, err = readMsgpackUint(r, 1<<(code-0xc4)); err != nil {
+			return
+		}
+		return readMsgpackBytes(r, n)
+	case 0xc7, 0xc8, 0xc9:
+		var n int
+		if n, err = readMsgpackUint(r, 1<<(code-0xc7)); err != nil {
+			return
+		}
+		return readMsgpackExt(r, n)
+	case 0xca:
+		var bits int
+		if bits, err = readMsgpackUint(r, 4); err != nil {
+			return
+		}
+		return float64(math.Float32frombits(uint32(bits))), nil
+	case 0xcb:
+		var raw []byte
+		if raw, err = readMsgpackBytes(r, 8); err != nil {
+			return
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(raw)), nil
+	case 0xcc, 0xcd, 0xce:
+		var n int
+		n, err = readMsgpackUint(r, 1<<(code-0xcc))
+		return int64(n), err
+	case 0xcf:
+		var raw []byte
+		if raw, err = readMsgpackBytes(r, 8); err != nil {
+			return
+		}
+		return binary.BigEndian.Uint64(raw), nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (code - 0xd0)
+		var raw []byte
+		if raw, err = readMsgpackBytes(r, size); err != nil {
+			return
+		}
+		switch size {
+		case 1:
+			return int64(int8(raw[0])), nil
+		case 2:
+			return int64(int16(binary.BigEndian.Uint16(raw))), nil
+		case 4:
+			return int64(int32(binary.BigEndian.Uint32(raw))), nil
+		default:
+			return int64(binary.BigEndian.Uint64(raw)), nil
+		}
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return readMsgpackExt(r, 1<<(code-0xd4))
+	case 0xd9, 0xda, 0xdb:
+		var n int
+		if n, err = readMsgpackUint(r, 1<<(code-0xd9)); err != nil {
+			return
+		}
+		return readMsgpackString(r, n)
+	case 0xdc, 0xdd:
+		var n int
+		if n, err = readMsgpackUint(r, 2<<(code-0xdc)); err != nil {
+			return
+		}
+		return readMsgpackArray(r, n)
+	case 0xde, 0xdf:
+		var n int
+		if n, err = readMsgpackUint(r, 2<<(code-0xde)); err != nil {
+			return
+		}
+		return readMsgpackMap(r, n)
+	}
+
+	return nil, errMsgpackType
+}
+
+func readMsgpackUint(r *bufio.Reader, size int) (n int, err error) {
+	raw, err := readMsgpackBytes(r, size)
+	if err != nil {
+		return
+	}
+	for _, by := range raw {
+		n = n<<8 | int(by)
+	}
+	return
+}
+
+func readMsgpackBytes(r *bufio.Reader, n int) (raw []byte, err error) {
+	raw = make([]byte, n)
+	_, err = io.ReadFull(r, raw)
+	return
+}
+
+func readMsgpackString(r *bufio.Reader, n int) (s string, err error) {
+	raw, err := readMsgpackBytes(r, n)
+	return string(raw), err
+}
+
+func readMsgpackExt(r *bufio.Reader, n int) (ext msgpackExt, err error) {
+	typ, err := r.ReadByte()
+	if err != nil {
+		return
+	}
+	ext.typ = int8(typ)
+	ext.data, err = readMsgpackBytes(r, n)
+	return
+}
+
+func readMsgpackArray(r *bufio.Reader, n int) (list []any, err error) {
+	list = make([]any, 0, n)
+	for range n {
+		var elem any
+		if elem, err = readMsgpack(r); err != nil {
+			return
+		}
+		list = append(list, elem)
+	}
+	return
+}
+
+func readMsgpackMap(r *bufio.Reader, n int) (m any, err error) {
+	keys := make([]any, 0, n)
+	values := make([]any, 0, n)
+	allStrings := true
+	for range n {
+		var k, v any
+		if k, err = readMsgpack(r); err != nil {
+			return
+		}
+		if v, err = readMsgpack(r); err != nil {
+			return
+		}
+		_, isString := k.(string)
+		allStrings = allStrings && isString
+		keys = append(keys, k)
+		values = append(values, v)
+	}
+
+	if allStrings {
+		sm := make(map[string]any, n)
+		for i, k := range keys {
+			sm[k.(string)] = values[i]
+		}
+		return sm, nil
+	}
+
+	am := make(map[any]any, n)
+	for i, k := range keys {
+		am[k] = values[i]
+	}
+	return am, nil
+}
@@ -0,0 +1,79 @@
+package lane
+
+import (
+	"bufio"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMsgpackEncoding(t *testing.T) {
+	cases := []struct {
+		v        any
+		expected []byte
+	}{
+		{nil, []byte{0xc0}},
+		{true, []byte{0xc3}},
+		{5, []byte{0x05}},
+		{-3, []byte{0xfd}},
+		{200, []byte{0xd1, 0x00, 0xc8}},
+		{-200, []byte{0xd1, 0xff, 0x38}},
+		{70000, []byte{0xd2, 0x00, 0x01, 0x11, 0x70}},
+		{"hi", []byte{0xa2, 'h', 'i'}},
+		{[]byte{1, 2}, []byte{0xc4, 0x02, 0x01, 0x02}},
+		{[]any{1, "a"}, []byte{0x92, 0x01, 0xa1, 'a'}},
+		{map[string]any{"b": 2, "a": 1}, []byte{0x82, 0xa1, 'a', 0x01, 0xa1, 'b', 0x02}},
+		{msgpackExt{typ: 0, data: []byte{1, 2, 3, 4, 5, 6, 7, 8}}, []byte{0xd7, 0x00, 1, 2, 3, 4, 5, 6, 7, 8}},
+	}
+
+	for _, c := range cases {
+		actual := appendMsgpack(nil, c.v)
+		if !bytes.Equal(actual, c.expected) {
+			t.Errorf("encoding %v: got %x, expected %x", c.v, actual, c.expected)
+		}
+	}
+}
+
+func TestMsgpackRoundTrip(t *testing.T) {
+	values := []any{
+		nil,
+		false,
+		int64(0),
+		int64(-32),
+		int64(-33),
+		int64(1 << 40),
+		uint64(1 << 63),
+		1.5,
+		"",
+		strings.Repeat("x", 40),
+		strings.Repeat("y", 300),
+		[]byte(strings.Repeat("z", 70000)),
+		[]any{int64(1), "two", []any{}},
+		map[string]any{"k": map[string]any{"n": nil}},
+		msgpackExt{typ: 5, data: []byte{1, 2, 3}},
+	}
+
+	for _, v := range values {
+		raw := appendMsgpack(nil, v)
+		decoded, err := readMsgpack(bufio.NewReader(bytes.NewReader(raw)))
+		if err != nil {
+			t.Errorf("decoding %v: %v", v, err)
+			continue
+		}
+		if !reflect.DeepEqual(decoded, v) {
+			t.Errorf("round trip of %v: got %v", v, decoded)
+		}
+	}
+}
+
+func TestMsgpackDecodeNonStringKeys(t *testing.T) {
+	raw := []byte{0x81, 0x01, 0xa3, 'o', 'n', 'e'}
+	decoded, err := readMsgpack(bufio.NewReader(bytes.NewReader(raw)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, map[any]any{int64(1): "one"}) {
+		t.Errorf("unexpected %v", decoded)
+	}
+}
@@ -0,0 +1,54 @@
+package lane
+
+import (
+	"strings"
+	"sync/atomic"
+)
+
+type (
+	// Selects how a log lane writes a message that has line breaks, for
+	// shippers that read the output one line at a time
+	MultiLinePolicy int
+)
+
+const (
+	// The message is written as is, over several lines
+	MultiLineRaw MultiLinePolicy = iota
+	// Line breaks are written as \n and \r, and backslashes as \\
+	MultiLineEscape
+	// The lines are joined into one line, separated by " | "
+	MultiLineFold
+	// Each line is written as its own output line, and the lines share a
+	// record ID, such as "INFO {id} rec=17 2/3 second line"
+	MultiLineSplit
+)
+
+// the source of the record IDs of split messages
+var multiLineRecordId atomic.Uint64
+
+var multiLineEscaper = strings.NewReplacer(`\`, `\\`, "\r", `\r`, "\n", `\n`)
+
+// Applies the policy to [text], providing the output lines
+func (mlp MultiLinePolicy) apply(text string) []string {
+	if mlp == MultiLineRaw || !strings.ContainsAny(text, "\r\n") {
+		return []string{text}
+	}
+
+	switch mlp {
+	case MultiLineEscape:
+		return []string{multiLineEscaper.Replace(text)}
+	case MultiLineFold:
+		return []string{strings.Join(splitLines(text), " | ")}
+	case MultiLineSplit:
+		return splitLines(text)
+	}
+	return []string{text}
+}
+
+// Breaks [text] into its lines, without the line endings, ignoring a final
+// line ending
+func splitLines(text string) []string {
+	text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\r", "\n")
+	text = strings.TrimRight(text, "\n")
+	return strings.Split(text, "\n")
+}
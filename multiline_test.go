@@ -0,0 +1,62 @@
+package lane
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMultiLinePolicyApply(t *testing.T) {
+	cases := []struct {
+		policy   MultiLinePolicy
+		text     string
+		expected []string
+	}{
+		{MultiLineRaw, "one\ntwo", []string{"one\ntwo"}},
+		{MultiLineEscape, `C:\dir`, []string{`C:\dir`}},
+		{MultiLineEscape, "one\r\ntwo\\", []string{`one\r\ntwo\\`}},
+		{MultiLineFold, "one\r\ntwo\nthree\n", []string{"one | two | three"}},
+		{MultiLineSplit, "one\ntwo\rthree\n\n", []string{"one", "two", "three"}},
+		{MultiLineSplit, "one", []string{"one"}},
+	}
+	for _, c := range cases {
+		if lines := c.policy.apply(c.text); !reflect.DeepEqual(lines, c.expected) {
+			t.Errorf("%d %q: got %q, expected %q", c.policy, c.text, lines, c.expected)
+		}
+	}
+}
+
+func TestMultiLinePolicy(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	ll := NewLogLane(nil, WithMultiLinePolicy(MultiLineEscape)).(LogLane)
+	ll.SetFlagsMask(log.Ldate | log.Ltime)
+	ll.Info("first\nsecond")
+	if !strings.HasSuffix(buf.String(), `} first\nsecond`+"\n") {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+
+	if prior := ll.SetMultiLinePolicy(MultiLineSplit); prior != MultiLineEscape {
+		t.Errorf("unexpected prior policy %d", prior)
+	}
+	child := ll.Derive()
+	if state := child.ConfigSnapshot(); state.MultiLine != MultiLineSplit {
+		t.Errorf("unexpected policy %d", state.MultiLine)
+	}
+
+	buf.Reset()
+	child.Warn("first\nsecond")
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+	id, _, _ := strings.Cut(strings.SplitN(lines[0], " rec=", 2)[1], " ")
+	if !strings.Contains(lines[0], "WARN {") || !strings.HasSuffix(lines[0], " rec="+id+" 1/2 first") || !strings.HasSuffix(lines[1], " rec="+id+" 2/2 second") {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+}
@@ -2,6 +2,8 @@ package lane
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"log"
 	"sync"
 	"sync/atomic"
@@ -14,15 +16,33 @@ type (
 	nullLane struct {
 		context.Context
 		MetadataStore
-		wlog       *log.Logger
-		level      int32
-		stackTrace []atomic.Bool
-		mu         sync.Mutex
-		tees       []Lane
-		onPanic    Panic
-		journeyId  string
-		parent     Lane
-		maxLength  atomic.Int32
+		objectOptStore
+		lengthConstraintStore
+		lifecycleStore
+		laneTreeStore
+		errorStore
+		stackTraceStore
+		callerInfoStore
+		logLimitStore
+		wlog      *log.Logger
+		level     int32
+		mu        sync.Mutex
+		tees      []Lane
+		teeCount  atomic.Int32
+		onPanic   Panic
+		journeyId string
+		traceCtx  traceContext
+		category  string
+		indent    int
+		parent    Lane
+		idGen     LaneIdGenerator
+		strict    *strictNull
+	}
+
+	// Assertion settings of a strict null lane, shared with its derivations
+	strictNull struct {
+		level LaneLogLevel
+		fn    func(rec Record)
 	}
 
 	wrappedNullWriter struct {
@@ -32,37 +52,83 @@ type (
 	nullContext string
 )
 
-func NewNullLane(ctx OptionalContext) Lane {
-	return deriveNullLane(nil, ctx, []Lane{}, nil)
+func NewNullLane(ctx OptionalContext, opts ...LaneOption) Lane {
+	lo := applyLaneOptions(opts)
+	nl := deriveNullLane(nil, ctx, []Lane{}, nil, lo.idGen)
+	nl.(*nullLane).teeClose = lo.teeClose
+	nl.(*nullLane).strict = lo.strict
+	nl.(*nullLane).setDiagnosticHandler(lo.diagnostics)
+	lo.applySettings(nl)
+	return nl
 }
 
-func deriveNullLane(parent Lane, ctx context.Context, tees []Lane, onPanic Panic) Lane {
+func deriveNullLane(parent Lane, ctx context.Context, tees []Lane, onPanic Panic, idGen LaneIdGenerator) Lane {
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
 	nl := nullLane{
-		stackTrace: make([]atomic.Bool, logLevelMax),
-		tees:       tees,
-		parent:     parent,
+		tees:   tees,
+		parent: parent,
+		idGen:  idGen,
 	}
+	nl.teeCount.Store(int32(len(tees)))
 	nl.SetPanicHandler(onPanic)
 	nl.SetOwner(&nl)
 
 	wnw := wrappedNullWriter{nl: &nl}
 	nl.wlog = log.New(&wnw, "", 0)
 
-	nl.Context = context.WithValue(ctx, null_lane_id, makeLaneId())
+	nl.Context = context.WithValue(ctx, null_lane_id, makeLaneId(idGen))
+
+	if pnl, ok := parent.(*nullLane); ok {
+		pnl.mu.Lock()
+		nl.traceCtx = pnl.traceCtx
+		nl.category = pnl.category
+		nl.indent = pnl.indent
+		pnl.mu.Unlock()
+		nl.strict = pnl.strict
+	}
 
 	copyConfigToDerivation(&nl, parent)
+
+	if pnl, ok := parent.(*nullLane); ok {
+		nl.attachTree(&nl, &pnl.laneTreeStore, time.Now())
+		nl.inheritHooks(&pnl.lifecycleStore)
+		nl.inheritErrorHandler(&pnl.errorStore)
+		nl.inheritMetadata(&pnl.MetadataStore)
+		nl.inheritLimits(&pnl.logLimitStore)
+		pnl.runDeriveHooks(pnl, &nl)
+	} else {
+		nl.attachTree(&nl, nil, time.Now())
+	}
 	return &nl
 }
 
 func (nl *nullLane) SetJourneyId(id string) {
 	nl.mu.Lock()
-	defer nl.mu.Unlock()
 	nl.journeyId = id
+	traced := nl.traceCtx != traceContext{}
+	nl.traceCtx = traceContext{}
+	nl.mu.Unlock()
 	// null lane does not format a log message, so the correlation ID is ignored
+
+	endTraceParent(nl, traced)
+}
+
+func (nl *nullLane) SetTraceParent(tp string) error {
+	return setTraceParent(nl, tp, func(tc traceContext) {
+		nl.mu.Lock()
+		defer nl.mu.Unlock()
+		nl.traceCtx = tc
+		nl.journeyId = tc.traceId
+	})
+}
+
+func (nl *nullLane) TraceParent() string {
+	nl.mu.Lock()
+	defer nl.mu.Unlock()
+	return nl.traceCtx.render(nl.LaneId())
 }
 
 func (nl *nullLane) SetLogLevel(newLevel LaneLogLevel) (priorLevel LaneLogLevel) {
@@ -71,11 +137,71 @@ func (nl *nullLane) SetLogLevel(newLevel LaneLogLevel) (priorLevel LaneLogLevel)
 	return
 }
 
+func (nl *nullLane) ConfigSnapshot() LaneConfigState {
+	return captureConfig(nl, LaneLogLevel(atomic.LoadInt32(&nl.level)), &nl.stackTraceStore, &nl.callerInfoStore, &nl.lengthConstraintStore)
+}
+
+func (nl *nullLane) ApplyConfig(state LaneConfigState) {
+	applyConfig(nl, state)
+}
+
+// A null lane only does work for a message when it has a tee, when the
+// message level is asserted, or when a level hook wants the message
+func (nl *nullLane) discards(level LaneLogLevel) bool {
+	if strippedLevel(level) {
+		return true
+	}
+	return nl.teeCount.Load() == 0 && (nl.strict == nil || level < nl.strict.level) && !nl.levelHooked(level)
+}
+
+// Passes a message at the lane's level to the level hooks, and panics or
+// calls the strict handler if a message at [level] is asserted
+func (nl *nullLane) assertLevel(props loggingProperties, level LaneLogLevel, text func() string) {
+	asserted := nl.strict != nil && level >= nl.strict.level
+	hooked := nl.levelHooked(level) && level >= LaneLogLevel(atomic.LoadInt32(&nl.level))
+	if !asserted && !hooked {
+		return
+	}
+
+	rec := Record{
+		Time:      time.Now(),
+		LaneId:    props.laneId,
+		JourneyId: props.journeyId,
+		Category:  props.category,
+		Depth:     props.indent,
+		Level:     level,
+		Message:   text(),
+	}
+	if hooked {
+		nl.runLevelHooks(&rec)
+	}
+	if !asserted {
+		return
+	}
+	if nl.strict.fn == nil {
+		panic(fmt.Sprintf("null lane received %s message: %s", levelNames[level], rec.Message))
+	}
+	nl.strict.fn(rec)
+}
+
 func (nl *nullLane) tee(props loggingProperties, logger teeHandler) {
+	if nl.teeCount.Load() == 0 {
+		return
+	}
+
+	var closed []Lane
+	defer func() { reportClosedTees(nl, closed) }()
+
 	nl.mu.Lock()
 	defer nl.mu.Unlock()
 
-	for _, t := range nl.tees {
+	var tees []Lane
+	tees, closed = withoutClosedTees(nl.tees)
+	if closed != nil {
+		nl.tees = tees
+		nl.teeCount.Store(int32(len(tees)))
+	}
+	for _, t := range tees {
 		receiver := t.(laneInternal)
 		logger(props, receiver)
 	}
@@ -87,58 +213,144 @@ func (nl *nullLane) LaneProps() loggingProperties {
 	return loggingProperties{
 		laneId:    nl.LaneId(),
 		journeyId: nl.journeyId,
+		category:  nl.category,
+		indent:    nl.indent,
 	}
 }
 
-func (nl *nullLane) Trace(args ...any) { nl.TraceInternal(nl.LaneProps(), args...) }
+func (nl *nullLane) Category(category string) Lane {
+	return deriveCategory(nl, category)
+}
+
+func (nl *nullLane) setCategory(category string) {
+	nl.mu.Lock()
+	defer nl.mu.Unlock()
+	nl.category = category
+}
+
+func (nl *nullLane) Indent() Lane {
+	return deriveIndent(nl)
+}
+
+func (nl *nullLane) setIndent(depth int) {
+	nl.mu.Lock()
+	defer nl.mu.Unlock()
+	nl.indent = depth
+}
+
+func (nl *nullLane) Trace(args ...any) {
+	if !nl.discards(LogLevelTrace) {
+		nl.TraceInternal(nl.LaneProps(), args...)
+	}
+}
 func (nl *nullLane) Tracef(format string, args ...any) {
-	nl.TracefInternal(nl.LaneProps(), format, args...)
+	if !nl.discards(LogLevelTrace) {
+		nl.TracefInternal(nl.LaneProps(), format, args...)
+	}
 }
 func (nl *nullLane) TraceObject(message string, obj any) {
 	LogObject(nl, LogLevelTrace, message, obj)
 }
-func (nl *nullLane) Debug(args ...any) { nl.DebugInternal(nl.LaneProps(), args...) }
+func (nl *nullLane) Debug(args ...any) {
+	if !nl.discards(LogLevelDebug) {
+		nl.DebugInternal(nl.LaneProps(), args...)
+	}
+}
 func (nl *nullLane) Debugf(format string, args ...any) {
-	nl.DebugfInternal(nl.LaneProps(), format, args...)
+	if !nl.discards(LogLevelDebug) {
+		nl.DebugfInternal(nl.LaneProps(), format, args...)
+	}
 }
 func (nl *nullLane) DebugObject(message string, obj any) {
 	LogObject(nl, LogLevelDebug, message, obj)
 }
-func (nl *nullLane) Info(args ...any) { nl.InfoInternal(nl.LaneProps(), args...) }
+func (nl *nullLane) Info(args ...any) {
+	if !nl.discards(LogLevelInfo) {
+		nl.InfoInternal(nl.LaneProps(), args...)
+	}
+}
 func (nl *nullLane) Infof(format string, args ...any) {
-	nl.InfofInternal(nl.LaneProps(), format, args...)
+	if !nl.discards(LogLevelInfo) {
+		nl.InfofInternal(nl.LaneProps(), format, args...)
+	}
 }
 func (nl *nullLane) InfoObject(message string, obj any) {
 	LogObject(nl, LogLevelInfo, message, obj)
 }
-func (nl *nullLane) Warn(args ...any) { nl.WarnInternal(nl.LaneProps(), args...) }
+func (nl *nullLane) Warn(args ...any) {
+	if !nl.discards(LogLevelWarn) {
+		nl.WarnInternal(nl.LaneProps(), args...)
+	}
+}
 func (nl *nullLane) Warnf(format string, args ...any) {
-	nl.WarnfInternal(nl.LaneProps(), format, args...)
+	if !nl.discards(LogLevelWarn) {
+		nl.WarnfInternal(nl.LaneProps(), format, args...)
+	}
 }
 func (nl *nullLane) WarnObject(message string, obj any) {
 	LogObject(nl, LogLevelWarn, message, obj)
 }
-func (nl *nullLane) Error(args ...any) { nl.ErrorInternal(nl.LaneProps(), args...) }
+func (nl *nullLane) Error(args ...any) {
+	if !nl.discards(LogLevelError) {
+		nl.ErrorInternal(nl.LaneProps(), args...)
+	}
+}
 func (nl *nullLane) Errorf(format string, args ...any) {
-	nl.ErrorfInternal(nl.LaneProps(), format, args...)
+	if !nl.discards(LogLevelError) {
+		nl.ErrorfInternal(nl.LaneProps(), format, args...)
+	}
 }
 func (nl *nullLane) ErrorObject(message string, obj any) {
 	LogObject(nl, LogLevelError, message, obj)
 }
-func (nl *nullLane) PreFatal(args ...any) { nl.PreFatalInternal(nl.LaneProps(), args...) }
+
+func (nl *nullLane) InfoOnce(key string, args ...any) {
+	logOnce(nl, LogLevelInfo, key, nl.firstUse, func() string { return sprint(args...) })
+}
+
+func (nl *nullLane) WarnOnce(key string, args ...any) {
+	logOnce(nl, LogLevelWarn, key, nl.firstUse, func() string { return sprint(args...) })
+}
+
+func (nl *nullLane) WarnRate(key string, period time.Duration, args ...any) {
+	logRateLimited(nl, &nl.logLimitStore, LogLevelWarn, key, period, time.Now(), func() string { return sprint(args...) })
+}
+
+func (nl *nullLane) WarnRatef(key string, period time.Duration, format string, args ...any) {
+	logRateLimited(nl, &nl.logLimitStore, LogLevelWarn, key, period, time.Now(), func() string { return fmt.Sprintf(format, args...) })
+}
+
+func (nl *nullLane) ErrorRate(key string, period time.Duration, args ...any) {
+	logRateLimited(nl, &nl.logLimitStore, LogLevelError, key, period, time.Now(), func() string { return sprint(args...) })
+}
+
+func (nl *nullLane) ErrorRatef(key string, period time.Duration, format string, args ...any) {
+	logRateLimited(nl, &nl.logLimitStore, LogLevelError, key, period, time.Now(), func() string { return fmt.Sprintf(format, args...) })
+}
+func (nl *nullLane) PreFatal(args ...any) {
+	if !nl.discards(LogLevelFatal) {
+		nl.PreFatalInternal(nl.LaneProps(), args...)
+	}
+}
 func (nl *nullLane) PreFatalf(format string, args ...any) {
-	nl.PreFatalfInternal(nl.LaneProps(), format, args...)
+	if !nl.discards(LogLevelFatal) {
+		nl.PreFatalfInternal(nl.LaneProps(), format, args...)
+	}
 }
 func (nl *nullLane) PreFatalObject(message string, obj any) {
 	LogObject(nl, logLevelPreFatal, message, obj)
 }
-func (nl *nullLane) Fatal(args ...any) { nl.FatalInternal(nl.LaneProps(), args...); nl.onPanic() }
+func (nl *nullLane) Fatal(args ...any) {
+	nl.FatalInternal(nl.LaneProps(), fatalArgs(nl, args)...)
+	nl.onPanic()
+}
 func (nl *nullLane) Fatalf(format string, args ...any) {
+	format, args = fatalfArgs(nl, format, args)
 	nl.FatalfInternal(nl.LaneProps(), format, args...)
 	nl.onPanic()
 }
 func (nl *nullLane) FatalObject(message string, obj any) {
-	LogObject(nl, LogLevelFatal, message, obj)
+	LogObject(nl, LogLevelFatal, annotateFatal(nl, message), obj)
 }
 
 func (nl *nullLane) LogStack(message string) {
@@ -149,33 +361,32 @@ func (nl *nullLane) LogStackTrim(message string, skippedCallers int) {
 	nl.LogStackTrimInternal(nl.LaneProps(), message, skippedCallers)
 }
 
-func (nl *nullLane) SetLengthConstraint(maxLength int) int {
-	old := nl.maxLength.Load()
-	if maxLength > 1 {
-		nl.maxLength.Store(int32(maxLength))
-	} else {
-		nl.maxLength.Store(0)
-	}
-	return int(old)
+func (nl *nullLane) Logger() *log.Logger {
+	return nl.wlog
 }
 
-func (nl *nullLane) Constrain(text string) string {
-	maxLen := nl.maxLength.Load()
-	if maxLen > 0 && len(text) > int(maxLen) {
-		text = text[:maxLen-1] + "\u2026"
-	}
-	return text
+func (nl *nullLane) WriterAt(level LaneLogLevel) io.WriteCloser {
+	return newLevelWriter(nl, level)
 }
 
-func (nl *nullLane) Logger() *log.Logger {
-	return nl.wlog
+func (nl *nullLane) Close() error {
+	return nl.CloseWithContext(context.Background())
+}
+
+func (nl *nullLane) CloseWithContext(ctx context.Context) error {
+	nl.detachTree(nl)
+	if nl.runCloseHooks(nl) {
+		return nl.closeTees(ctx, nl.Tees())
+	}
+	return nil
 }
 
-func (nl *nullLane) Close() {
+func (nl *nullLane) treeInfo() laneTreeInfo {
+	return nl.makeTreeInfo(LaneLogLevel(atomic.LoadInt32(&nl.level)))
 }
 
 func (nl *nullLane) Derive() Lane {
-	l := deriveNullLane(nl, context.WithValue(nl.Context, ParentLaneIdKey, nl.LaneId()), nl.tees, nl.onPanic)
+	l := deriveNullLane(nl, context.WithValue(nl.Context, ParentLaneIdKey, nl.LaneId()), nl.tees, nl.onPanic, nl.idGen)
 	l.SetLogLevel(LaneLogLevel(atomic.LoadInt32(&nl.level)))
 	l.SetJourneyId(nl.journeyId)
 	return l
@@ -183,68 +394,79 @@ func (nl *nullLane) Derive() Lane {
 
 func (nl *nullLane) DeriveWithCancel() (Lane, context.CancelFunc) {
 	childCtx, cancelFn := context.WithCancel(context.WithValue(nl.Context, ParentLaneIdKey, nl.LaneId()))
-	l := deriveNullLane(nl, childCtx, nl.tees, nl.onPanic)
+	l := deriveNullLane(nl, childCtx, nl.tees, nl.onPanic, nl.idGen)
 	l.SetLogLevel(LaneLogLevel(atomic.LoadInt32(&nl.level)))
 	return l, cancelFn
 }
 
 func (nl *nullLane) DeriveWithCancelCause() (Lane, context.CancelCauseFunc) {
 	childCtx, cancelFn := context.WithCancelCause(context.WithValue(nl.Context, ParentLaneIdKey, nl.LaneId()))
-	l := deriveNullLane(nl, childCtx, nl.tees, nl.onPanic)
+	l := deriveNullLane(nl, childCtx, nl.tees, nl.onPanic, nl.idGen)
 	l.SetLogLevel(LaneLogLevel(atomic.LoadInt32(&nl.level)))
 	return l, cancelFn
 }
 
 func (nl *nullLane) DeriveWithoutCancel() Lane {
 	childCtx := context.WithoutCancel(context.WithValue(nl.Context, ParentLaneIdKey, nl.LaneId()))
-	l := deriveNullLane(nl, childCtx, nl.tees, nl.onPanic)
+	l := deriveNullLane(nl, childCtx, nl.tees, nl.onPanic, nl.idGen)
 	l.SetLogLevel(LaneLogLevel(atomic.LoadInt32(&nl.level)))
 	return l
 }
 
 func (nl *nullLane) DeriveWithDeadline(deadline time.Time) (Lane, context.CancelFunc) {
 	childCtx, cancelFn := context.WithDeadline(context.WithValue(nl.Context, ParentLaneIdKey, nl.LaneId()), deadline)
-	l := deriveNullLane(nl, childCtx, nl.tees, nl.onPanic)
+	l := deriveNullLane(nl, childCtx, nl.tees, nl.onPanic, nl.idGen)
 	l.SetLogLevel(LaneLogLevel(atomic.LoadInt32(&nl.level)))
 	return l, cancelFn
 }
 
 func (nl *nullLane) DeriveWithDeadlineCause(deadline time.Time, cause error) (Lane, context.CancelFunc) {
 	childCtx, cancelFn := context.WithDeadlineCause(context.WithValue(nl.Context, ParentLaneIdKey, nl.LaneId()), deadline, cause)
-	l := deriveNullLane(nl, childCtx, nl.tees, nl.onPanic)
+	l := deriveNullLane(nl, childCtx, nl.tees, nl.onPanic, nl.idGen)
 	l.SetLogLevel(LaneLogLevel(atomic.LoadInt32(&nl.level)))
 	return l, cancelFn
 }
 
 func (nl *nullLane) DeriveWithTimeout(duration time.Duration) (Lane, context.CancelFunc) {
 	childCtx, cancelFn := context.WithTimeout(context.WithValue(nl.Context, ParentLaneIdKey, nl.LaneId()), duration)
-	l := deriveNullLane(nl, childCtx, nl.tees, nl.onPanic)
+	l := deriveNullLane(nl, childCtx, nl.tees, nl.onPanic, nl.idGen)
 	l.SetLogLevel(LaneLogLevel(atomic.LoadInt32(&nl.level)))
 	return l, cancelFn
 }
 
 func (nl *nullLane) DeriveWithTimeoutCause(duration time.Duration, cause error) (Lane, context.CancelFunc) {
 	childCtx, cancelFn := context.WithTimeoutCause(context.WithValue(nl.Context, ParentLaneIdKey, nl.LaneId()), duration, cause)
-	l := deriveNullLane(nl, childCtx, nl.tees, nl.onPanic)
+	l := deriveNullLane(nl, childCtx, nl.tees, nl.onPanic, nl.idGen)
 	l.SetLogLevel(LaneLogLevel(atomic.LoadInt32(&nl.level)))
 	return l, cancelFn
 }
 
+func (nl *nullLane) DeriveWithMaxTimeout(maxTimeout time.Duration) (Lane, context.CancelFunc) {
+	return deriveWithMaxTimeout(nl, maxTimeout)
+}
+
 func (nl *nullLane) DeriveReplaceContext(ctx OptionalContext) Lane {
-	l := deriveNullLane(nl, ctx, append([]Lane{}, nl.tees...), nil)
+	l := deriveNullLane(nl, ctx, append([]Lane{}, nl.tees...), nil, nl.idGen)
 	l.SetLogLevel(LaneLogLevel(atomic.LoadInt32(&nl.level)))
 	return l
 }
 
-func (nl *nullLane) EnableStackTrace(level LaneLogLevel, enable bool) bool {
-	// the last value should work as if the setting does something
-	return nl.stackTrace[level].Swap(enable)
+func (nl *nullLane) WithSoftTimeout(d time.Duration, onTimeout func(l Lane)) (stop func() bool) {
+	return startSoftTimeout(nl, d, onTimeout)
 }
 
 func (nl *nullLane) LaneId() string {
 	return nl.Value(null_lane_id).(string)
 }
 
+// Provides the context value for [key], and the lane itself for FromContext()
+func (nl *nullLane) Value(key any) any {
+	if key == (laneContextKey{}) {
+		return nl
+	}
+	return nl.Context.Value(key)
+}
+
 func (nl *nullLane) JourneyId() string {
 	nl.mu.Lock()
 	defer nl.mu.Unlock()
@@ -254,6 +476,7 @@ func (nl *nullLane) JourneyId() string {
 func (nl *nullLane) AddTee(l Lane) {
 	nl.mu.Lock()
 	nl.tees = append(nl.tees, l)
+	nl.teeCount.Store(int32(len(nl.tees)))
 	nl.mu.Unlock()
 }
 
@@ -262,6 +485,7 @@ func (nl *nullLane) RemoveTee(l Lane) {
 	for i, t := range nl.tees {
 		if t.LaneId() == l.LaneId() {
 			nl.tees = append(nl.tees[:i], nl.tees[i+1:]...)
+			nl.teeCount.Store(int32(len(nl.tees)))
 			break
 		}
 	}
@@ -286,6 +510,11 @@ func (nl *nullLane) SetPanicHandler(handler Panic) {
 	nl.onPanic = handler
 }
 
+// A null lane doesn't write, so it has no errors
+func (nl *nullLane) LastError() error {
+	return nil
+}
+
 func (wnw *wrappedNullWriter) Write(p []byte) (n int, err error) {
 	return len(p), nil
 }
@@ -298,41 +527,68 @@ func (nl *nullLane) Parent() Lane {
 }
 
 func (nl *nullLane) TraceInternal(props loggingProperties, args ...any) {
+	nl.assertLevel(props, LogLevelTrace, func() string { return sprint(args...) })
 	nl.tee(props, func(teeProps loggingProperties, li laneInternal) { li.TraceInternal(teeProps, args...) })
 }
 func (nl *nullLane) TracefInternal(props loggingProperties, format string, args ...any) {
+	nl.assertLevel(props, LogLevelTrace, func() string { return fmt.Sprintf(format, args...) })
 	nl.tee(props, func(teeProps loggingProperties, li laneInternal) { li.TracefInternal(teeProps, format, args...) })
 }
 func (nl *nullLane) DebugInternal(props loggingProperties, args ...any) {
+	nl.assertLevel(props, LogLevelDebug, func() string { return sprint(args...) })
 	nl.tee(props, func(teeProps loggingProperties, li laneInternal) { li.DebugInternal(teeProps, args...) })
 }
 func (nl *nullLane) DebugfInternal(props loggingProperties, format string, args ...any) {
+	nl.assertLevel(props, LogLevelDebug, func() string { return fmt.Sprintf(format, args...) })
 	nl.tee(props, func(teeProps loggingProperties, li laneInternal) { li.DebugfInternal(teeProps, format, args...) })
 }
 func (nl *nullLane) InfoInternal(props loggingProperties, args ...any) {
+	nl.assertLevel(props, LogLevelInfo, func() string { return sprint(args...) })
 	nl.tee(props, func(teeProps loggingProperties, li laneInternal) { li.InfoInternal(teeProps, args...) })
 }
 func (nl *nullLane) InfofInternal(props loggingProperties, format string, args ...any) {
+	nl.assertLevel(props, LogLevelInfo, func() string { return fmt.Sprintf(format, args...) })
 	nl.tee(props, func(teeProps loggingProperties, li laneInternal) { li.InfofInternal(teeProps, format, args...) })
 }
 func (nl *nullLane) WarnInternal(props loggingProperties, args ...any) {
+	nl.assertLevel(props, LogLevelWarn, func() string { return sprint(args...) })
 	nl.tee(props, func(teeProps loggingProperties, li laneInternal) { li.WarnInternal(teeProps, args...) })
 }
 func (nl *nullLane) WarnfInternal(props loggingProperties, format string, args ...any) {
+	nl.assertLevel(props, LogLevelWarn, func() string { return fmt.Sprintf(format, args...) })
 	nl.tee(props, func(teeProps loggingProperties, li laneInternal) { li.WarnfInternal(teeProps, format, args...) })
 }
 func (nl *nullLane) ErrorInternal(props loggingProperties, args ...any) {
+	nl.assertLevel(props, LogLevelError, func() string { return sprint(args...) })
 	nl.tee(props, func(teeProps loggingProperties, li laneInternal) { li.ErrorInternal(teeProps, args...) })
 }
 func (nl *nullLane) ErrorfInternal(props loggingProperties, format string, args ...any) {
+	nl.assertLevel(props, LogLevelError, func() string { return fmt.Sprintf(format, args...) })
 	nl.tee(props, func(teeProps loggingProperties, li laneInternal) { li.ErrorfInternal(teeProps, format, args...) })
 }
 func (nl *nullLane) PreFatalInternal(props loggingProperties, args ...any) {
+	nl.assertLevel(props, LogLevelFatal, func() string { return sprint(args...) })
 	nl.tee(props, func(teeProps loggingProperties, li laneInternal) { li.PreFatalInternal(teeProps, args...) })
 }
 func (nl *nullLane) PreFatalfInternal(props loggingProperties, format string, args ...any) {
+	nl.assertLevel(props, LogLevelFatal, func() string { return fmt.Sprintf(format, args...) })
 	nl.tee(props, func(teeProps loggingProperties, li laneInternal) { li.PreFatalfInternal(teeProps, format, args...) })
 }
+func (nl *nullLane) ObjectInternal(props loggingProperties, level LaneLogLevel, message string, obj any) {
+	nl.objectTextInternal(props, level, "", message, obj)
+}
+func (nl *nullLane) objectTextInternal(props loggingProperties, level LaneLogLevel, text, message string, obj any) {
+	if level == logLevelPreFatal {
+		level = LogLevelFatal
+	}
+	nl.assertLevel(props, level, func() string {
+		if text == "" {
+			text = renderObjectText(props, nl, level, message, obj, nl.ObjectOptions())
+		}
+		return text
+	})
+	nl.tee(props, func(teeProps loggingProperties, li laneInternal) { li.ObjectInternal(teeProps, level, message, obj) })
+}
 func (nl *nullLane) FatalInternal(props loggingProperties, args ...any) {
 	nl.PreFatalInternal(props, args...)
 	// panic will occur in a moment in the externally called Fatalf
@@ -342,6 +598,10 @@ func (nl *nullLane) FatalfInternal(props loggingProperties, format string, args
 	// panic will occur in a moment in the externally called Fatalf
 }
 
+func (nl *nullLane) replayRecord(rec *Record) {
+	nl.tee(rec.props(), func(teeProps loggingProperties, li laneInternal) { li.replayRecord(rec) })
+}
+
 func (nl *nullLane) LogStackTrimInternal(props loggingProperties, message string, skippedCallers int) {
 	nl.tee(nl.LaneProps(), func(teeProps loggingProperties, li laneInternal) {
 		li.LogStackTrimInternal(teeProps, message, skippedCallers)
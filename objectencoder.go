@@ -0,0 +1,187 @@
+package lane
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+type (
+	// Implemented by types that control their own representation in object
+	// logging. The returned value is captured in place of the original.
+	ObjectMarshaler interface {
+		MarshalLaneObject() any
+	}
+
+	objectEncoder func(val reflect.Value) any
+)
+
+var (
+	errorType           = reflect.TypeFor[error]()
+	stringerType        = reflect.TypeFor[fmt.Stringer]()
+	objectEncoders      sync.Map // reflect.Type -> objectEncoder
+	objectMarshalerType = reflect.TypeFor[ObjectMarshaler]()
+)
+
+// Registers a function that provides the representation of type T in object
+// logging, such as rendering time.Time as RFC3339 or redacting a password
+// field. The returned value is captured in place of the original. Registering
+// a nil function removes the encoder for T.
+func RegisterObjectEncoder[T any](fn func(v T) any) {
+	t := reflect.TypeFor[T]()
+	if fn == nil {
+		objectEncoders.Delete(t)
+	} else {
+		objectEncoders.Store(t, objectEncoder(func(val reflect.Value) any {
+			return fn(val.Interface().(T))
+		}))
+	}
+
+	// type plans depend on the encoders
+	typePlans.Range(func(key, value any) bool {
+		typePlans.Delete(key)
+		return true
+	})
+}
+
+// Checks if the type has a registered encoder or implements ObjectMarshaler
+func hasCustomEncoding(t reflect.Type) bool {
+	if _, found := objectEncoders.Load(t); found {
+		return true
+	}
+	return t.Implements(objectMarshalerType) || reflect.PointerTo(t).Implements(objectMarshalerType)
+}
+
+// Finds the custom encoding of a value, if any
+func (cs *captureState) customValue(val reflect.Value) (custom any, found bool) {
+	if !val.IsValid() || !val.CanInterface() {
+		return
+	}
+
+	t := val.Type()
+	if enc, registered := objectEncoders.Load(t); registered {
+		return enc.(objectEncoder)(val), true
+	}
+
+	if t.Implements(objectMarshalerType) {
+		if (t.Kind() == reflect.Pointer || t.Kind() == reflect.Interface) && val.IsNil() {
+			return
+		}
+		return val.Interface().(ObjectMarshaler).MarshalLaneObject(), true
+	}
+
+	if val.CanAddr() && reflect.PointerTo(t).Implements(objectMarshalerType) {
+		return val.Addr().Interface().(ObjectMarshaler).MarshalLaneObject(), true
+	}
+
+	return
+}
+
+// Captures the value provided by a custom encoding
+func (cs *captureState) captureCustom(val reflect.Value, custom any, depth int) any {
+	cv := reflect.ValueOf(custom)
+	if cv.IsValid() {
+		base := val.Type()
+		for base.Kind() == reflect.Pointer {
+			base = base.Elem()
+		}
+
+		// the encoder returned the same type or a pointer to it, such as a
+		// redacted copy, so don't encode again, which would never end
+		same := cv
+		for same.Type() != base && same.Kind() == reflect.Pointer && !same.IsNil() {
+			same = same.Elem()
+		}
+		if same.Type() == base {
+			return cs.kindValue(same, depth)
+		}
+		if same.Kind() == reflect.Pointer && same.IsNil() && same.Type().Elem() == base {
+			return nil
+		}
+	}
+	return cs.innerValue(cv, depth)
+}
+
+// Renders a value by its json.Marshaler, error or fmt.Stringer interface,
+// when the capture is honoring json conventions
+func (cs *captureState) jsonTagsValue(val reflect.Value) (rendered any, found bool) {
+	if cs.opt.Encoding != EncodingJSONTags || !val.IsValid() || !val.CanInterface() {
+		return
+	}
+
+	t := val.Type()
+	if (t.Kind() == reflect.Pointer || t.Kind() == reflect.Interface) && val.IsNil() {
+		return
+	}
+
+	var v any
+	switch {
+	case t.Implements(jsonMarshalerType), t.Implements(errorType), t.Implements(stringerType):
+		v = val.Interface()
+	case val.CanAddr() && (reflect.PointerTo(t).Implements(jsonMarshalerType) ||
+		reflect.PointerTo(t).Implements(errorType) || reflect.PointerTo(t).Implements(stringerType)):
+		v = val.Addr().Interface()
+	default:
+		return
+	}
+
+	switch iv := v.(type) {
+	case json.Marshaler:
+		raw, err := iv.MarshalJSON()
+		if err != nil || !json.Valid(raw) {
+			return
+		}
+		return json.RawMessage(raw), true
+	case error:
+		return iv.Error(), true
+	case fmt.Stringer:
+		return iv.String(), true
+	}
+	return
+}
+
+// Renders a value by its encoding.TextMarshaler interface, such as for
+// time.Time, net.IP or big.Int
+func (cs *captureState) textValue(val reflect.Value) (rendered string, found bool) {
+	if !val.IsValid() || !val.CanInterface() {
+		return
+	}
+
+	t := val.Type()
+	if (t.Kind() == reflect.Pointer || t.Kind() == reflect.Interface) && val.IsNil() {
+		return
+	}
+
+	var tm encoding.TextMarshaler
+	switch {
+	case t.Implements(textMarshalerType):
+		tm = val.Interface().(encoding.TextMarshaler)
+	case val.CanAddr() && reflect.PointerTo(t).Implements(textMarshalerType):
+		tm = val.Addr().Interface().(encoding.TextMarshaler)
+	default:
+		return
+	}
+
+	text, err := tm.MarshalText()
+	if err != nil {
+		return
+	}
+	return string(text), true
+}
+
+// Implements the encoding/json definition of an empty value for omitempty
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
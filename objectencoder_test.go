@@ -0,0 +1,169 @@
+package lane
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type (
+	testCredentials struct {
+		User     string
+		password string
+	}
+
+	testPtrMarshaler struct {
+		value int
+	}
+
+	testSameType struct {
+		Value int
+	}
+
+	testRedacted struct {
+		User     string
+		Password string
+	}
+
+	testHasTime struct {
+		When time.Time
+	}
+)
+
+func (tc testCredentials) MarshalLaneObject() any {
+	return map[string]string{"User": tc.User, "password": "***"}
+}
+
+func (tpm *testPtrMarshaler) MarshalLaneObject() any {
+	return tpm.value * 10
+}
+
+func (tst testSameType) MarshalLaneObject() any {
+	return testSameType{Value: tst.Value + 1}
+}
+
+func (tr testRedacted) MarshalLaneObject() any {
+	c := tr
+	c.Password = "***"
+	return &c
+}
+
+func TestLogObjectMarshaler(t *testing.T) {
+	l := NewLogLane(nil)
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer func() { log.SetOutput(os.Stderr) }()
+
+	creds := testCredentials{User: "bob", password: "secret"}
+	l.InfoObject("creds", creds)
+	l.InfoObject("nested", map[string]any{"login": &creds})
+	l.InfoObject("ptr", &testPtrMarshaler{value: 4})
+	l.InfoObject("field", struct{ inner testPtrMarshaler }{testPtrMarshaler{value: 5}})
+	l.InfoObject("same", testSameType{Value: 1})
+	l.InfoObject("redacted", testRedacted{User: "bob", Password: "secret"})
+	l.InfoObject("redacted ptr", &testRedacted{User: "bob", Password: "secret"})
+
+	var nilCreds *testPtrMarshaler
+	l.InfoObject("nil", nilCreds)
+
+	testExpectedStdout(t, &buf, []string{
+		`creds: {"User":"bob","password":"***"}`,
+		`nested: {"login":{"User":"bob","password":"***"}}`,
+		`ptr: 40`,
+		`field: {"inner":50}`,
+		`same: {"Value":2}`,
+		`redacted: {"Password":"***","User":"bob"}`,
+		`redacted ptr: {"Password":"***","User":"bob"}`,
+		`nil: null`,
+	})
+}
+
+func TestRegisterObjectEncoder(t *testing.T) {
+	RegisterObjectEncoder(func(v time.Time) any { return v.UTC().Format(time.RFC3339) })
+	defer RegisterObjectEncoder[time.Time](nil)
+
+	l := NewLogLane(nil)
+	l.SetObjectOptions(LogObjectOpt{DirectJSON: true})
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer func() { log.SetOutput(os.Stderr) }()
+
+	when := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	l.InfoObject("time", when)
+	l.InfoObject("struct", testHasTime{When: when})
+
+	testExpectedStdout(t, &buf, []string{
+		`time: "2024-05-06T07:08:09Z"`,
+		`struct: {"When":"2024-05-06T07:08:09Z"}`,
+	})
+
+	if planFor(reflect.TypeOf(testHasTime{})).exportedOnly {
+		t.Error("custom encoding should prevent direct json")
+	}
+}
+
+func TestUnregisterObjectEncoder(t *testing.T) {
+	RegisterObjectEncoder(func(v testStruct) any { return "custom" })
+	if CaptureObject(testStruct{}) != "custom" {
+		t.Error("encoder not used")
+	}
+
+	RegisterObjectEncoder[testStruct](nil)
+	if _, is := CaptureObject(testStruct{}).(map[string]any); !is {
+		t.Error("encoder not removed")
+	}
+}
+
+type (
+	testJsonBase struct {
+		Id string `json:"id"`
+	}
+
+	testJsonTagged struct {
+		testJsonBase
+		Name     string        `json:"name"`
+		Optional string        `json:"optional,omitempty"`
+		Hidden   string        `json:"-"`
+		Plain    int           `json:",omitempty"`
+		Err      error         `json:"err"`
+		Dur      time.Duration `json:"dur"`
+		private  int
+	}
+)
+
+func TestLogObjectJsonTags(t *testing.T) {
+	l := NewLogLane(nil)
+	prior := l.SetObjectEncoding(EncodingJSONTags)
+	if prior != EncodingReflection {
+		t.Error("wrong prior encoding")
+	}
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer func() { log.SetOutput(os.Stderr) }()
+
+	obj := testJsonTagged{
+		testJsonBase: testJsonBase{Id: "x1"},
+		Name:         "n",
+		Hidden:       "h",
+		Err:          os.ErrNotExist,
+		Dur:          time.Second,
+		private:      7,
+	}
+	l.InfoObject("tagged", obj)
+	l.InfoObject("time", time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC))
+
+	l.SetObjectEncoding(EncodingReflection)
+	l.InfoObject("reflect", testJsonBase{Id: "x2"})
+
+	testExpectedStdout(t, &buf, []string{
+		`tagged: {"dur":"1s","err":"file does not exist","id":"x1","name":"n","private":7}`,
+		`time: "2024-05-06T07:08:09Z"`,
+		`reflect: {"Id":"x2"}`,
+	})
+}
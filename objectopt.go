@@ -0,0 +1,245 @@
+package lane
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+type (
+	// Selects how object fields are named and rendered
+	ObjectEncoding int
+
+	// Selects how byte slices and arrays are rendered
+	BytesRendering int
+
+	// Selects the order of struct fields in rendered objects
+	FieldOrder int
+
+	// Settings for object logging (TraceObject, InfoObject, etc.)
+	LogObjectOpt struct {
+		// Use encoding/json directly when the object's type has only exported
+		// fields, instead of capturing the object via reflection. The capture
+		// is still used when a depth, element, omit or bytes setting applies.
+		DirectJSON bool
+
+		// Maximum nesting of structs, maps, slices and arrays, or 0 for no limit.
+		MaxDepth int
+
+		// Maximum number of slice, array or map elements, or 0 for no limit.
+		// A map keeps the keys that sort first, except that a map of more
+		// than 1000 entries keeps the first it iterates, which vary.
+		MaxElements int
+
+		// Maximum size of the rendered JSON, or 0 for no limit.
+		MaxBytes int
+
+		// Struct field names and map keys to leave out.
+		OmitFields []string
+
+		// Selects reflection naming (the default) or json tag naming.
+		Encoding ObjectEncoding
+
+		// Selects how byte slices and arrays are rendered.
+		Bytes BytesRendering
+
+		// With BytesDefault rendering, binary data longer than this is rendered
+		// as a hex dump instead of base64, or 0 for no threshold.
+		Base64MaxLength int
+
+		// The number of bytes shown in a hex dump, or 0 for the default of 32.
+		HexDumpLength int
+
+		// Selects sorted (the default) or declaration order of struct fields.
+		Fields FieldOrder
+
+		// Passes the original object to tees instead of the rendered text, so
+		// that a tee such as a testing lane can keep the structured payload.
+		// Each tee renders the object with its own settings.
+		TeeObjects bool
+	}
+
+	// Common implementation of the lane-level object logging settings
+	objectOptStore struct {
+		opt atomic.Pointer[LogObjectOpt]
+	}
+)
+
+const (
+	// Objects are captured with Go field names, including private fields
+	EncodingReflection ObjectEncoding = iota
+	// Exported fields are named by their json tags, and types implementing
+	// json.Marshaler, error or fmt.Stringer are rendered by those interfaces;
+	// everything else falls back to reflection
+	EncodingJSONTags
+)
+
+const (
+	// ASCII text is rendered as a string, short binary data as an array of
+	// numbers, and long binary data as base64
+	BytesDefault BytesRendering = iota
+	// Rendered as a length and a truncated hex dump, ex: "len=2048 0x000102…"
+	BytesHex
+)
+
+const (
+	// Struct fields are sorted by name, like map keys
+	FieldsSorted FieldOrder = iota
+	// Struct fields are rendered in their declaration order, like encoding/json
+	FieldsDeclared
+)
+
+const defaultHexDumpLength = 32
+
+// Replaces the lane's object logging settings, returning the prior settings
+func (oos *objectOptStore) SetObjectOptions(opt LogObjectOpt) (prior LogObjectOpt) {
+	old := oos.opt.Swap(&opt)
+	if old != nil {
+		prior = *old
+	}
+	return
+}
+
+// Changes the encoding of the lane's object logging settings, returning the prior encoding
+func (oos *objectOptStore) SetObjectEncoding(encoding ObjectEncoding) (prior ObjectEncoding) {
+	for {
+		old := oos.opt.Load()
+		var opt LogObjectOpt
+		if old != nil {
+			opt = *old
+		}
+		prior = opt.Encoding
+		opt.Encoding = encoding
+		if oos.opt.CompareAndSwap(old, &opt) {
+			return
+		}
+	}
+}
+
+// Provides the lane's object logging settings
+func (oos *objectOptStore) ObjectOptions() (opt LogObjectOpt) {
+	p := oos.opt.Load()
+	if p != nil {
+		opt = *p
+	}
+	return
+}
+
+// Converts an object to JSON according to the object logging settings
+func encodeObject(obj any, opt LogObjectOpt) (raw []byte, err error) {
+	defer func() {
+		// a marshaler or encoder of the application must not crash the logging call
+		if r := recover(); r != nil {
+			raw = nil
+			err = fmt.Errorf("object rendering panicked: %v", r)
+		}
+	}()
+
+	if opt.DirectJSON && !opt.hasCaptureLimits() && obj != nil && planFor(reflect.TypeOf(obj)).exportedOnly {
+		raw, err = json.Marshal(obj)
+		// on error, fall back to the reflection capture
+	}
+
+	if raw == nil || err != nil {
+		o := CaptureObjectWithOpt(obj, opt)
+		raw, err = json.Marshal(&o)
+		if err != nil {
+			return
+		}
+	}
+
+	if opt.MaxBytes > 0 && len(raw) > opt.MaxBytes {
+		// keep the output valid JSON by rendering the head as a string,
+		// without splitting a multi-byte UTF-8 sequence
+		cut := opt.MaxBytes
+		for cut > 0 && !utf8.RuneStart(raw[cut]) {
+			cut--
+		}
+		raw, err = json.Marshal(string(raw[:cut]) + "\u2026")
+	}
+	return
+}
+
+// Checks for settings that the reflection capture must apply
+func (opt LogObjectOpt) hasCaptureLimits() bool {
+	return opt.MaxDepth > 0 || opt.MaxElements > 0 || len(opt.OmitFields) > 0 ||
+		opt.Bytes != BytesDefault || opt.Base64MaxLength > 0 || opt.HexDumpLength > 0
+}
+
+// Renders a hex dump of the first [maxBytes] of data that has [length]
+// bytes, given the [head] of the data
+func hexDump(head []byte, length int, maxBytes int) string {
+	if maxBytes < 1 {
+		maxBytes = defaultHexDumpLength
+	}
+
+	var sb strings.Builder
+	sb.WriteString("len=")
+	sb.WriteString(strconv.Itoa(length))
+	sb.WriteString(" 0x")
+	if length > maxBytes {
+		sb.WriteString(hex.EncodeToString(head[:maxBytes]))
+		sb.WriteString("\u2026")
+	} else {
+		sb.WriteString(hex.EncodeToString(head))
+	}
+	return sb.String()
+}
+
+// Applies the byte rendering settings to a byte slice or array
+func (cs *captureState) bytesValue(val reflect.Value) (rendered any, handled bool) {
+	if val.Type().Elem().Kind() != reflect.Uint8 {
+		return
+	}
+	if cs.opt.Bytes == BytesDefault && cs.opt.Base64MaxLength <= 0 {
+		return
+	}
+
+	length := val.Len()
+	if cs.opt.Bytes == BytesDefault {
+		if length <= cs.opt.Base64MaxLength || (length < asciiTextMaxLength && isAsciiText(copyBytes(val, length))) {
+			return
+		}
+	}
+
+	// only the part shown is copied
+	maxBytes := cs.opt.HexDumpLength
+	if maxBytes < 1 {
+		maxBytes = defaultHexDumpLength
+	}
+	return hexDump(copyBytes(val, min(length, maxBytes)), length, maxBytes), true
+}
+
+// Copies the first [n] bytes of a byte slice or array
+func copyBytes(val reflect.Value, n int) []byte {
+	if val.Kind() == reflect.Slice {
+		return slices.Clone(val.Slice(0, n).Bytes())
+	}
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(val.Index(i).Uint())
+	}
+	return data
+}
+
+// the length at which byte data is no longer rendered as text
+const asciiTextMaxLength = 1000
+
+func isAsciiText(data []byte) bool {
+	if len(data) >= asciiTextMaxLength {
+		// long text is rendered as base64 by default
+		return false
+	}
+	for _, by := range data {
+		if (by < 32 && by != '\n' && by != '\r' && by != '\t') || by > 126 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,266 @@
+package lane
+
+import "time"
+
+type (
+	// Optional settings for lane constructors
+	LaneOption func(o *laneOptions)
+
+	laneOptions struct {
+		idGen         LaneIdGenerator
+		clock         Clock
+		teeClose      bool
+		bufferSize    int
+		flushInterval time.Duration
+		compress      bool
+		encoder       Encoder
+		strict        *strictNull
+		diagnostics   ErrorHandler
+		diskSpace     *DiskSpaceGuard
+		levelParser   LevelParser
+		crashDump     *CrashDumpOptions
+
+		// settings made on the new lane before it is returned
+		level      *LaneLogLevel
+		journeyId  *string
+		tees       []Lane
+		lineEnding *LineEnding
+		multiLine  *MultiLinePolicy
+		location   *time.Location
+		showParent bool
+		maxDepth   int
+		teeTimeout time.Duration
+		maxLength  int
+	}
+)
+
+// Collects the constructor options
+func applyLaneOptions(opts []LaneOption) *laneOptions {
+	lo := &laneOptions{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(lo)
+		}
+	}
+	return lo
+}
+
+// Makes the settings of the options on the new lane [l], before it is
+// returned, so that they are in place for its first message
+func (lo *laneOptions) applySettings(l Lane) {
+	if lo.level != nil {
+		l.SetLogLevel(*lo.level)
+	}
+	if lo.journeyId != nil {
+		l.SetJourneyId(*lo.journeyId)
+	}
+	for _, tee := range lo.tees {
+		l.AddTee(tee)
+	}
+	if lo.lineEnding != nil {
+		if ll, ok := l.(LogLane); ok {
+			ll.SetLineEnding(*lo.lineEnding)
+		}
+	}
+	if lo.multiLine != nil {
+		if ll, ok := l.(LogLane); ok {
+			ll.SetMultiLinePolicy(*lo.multiLine)
+		}
+	}
+	if lo.location != nil {
+		if ll, ok := l.(LogLane); ok {
+			ll.SetTimeLocation(lo.location)
+		}
+	}
+	if lo.showParent {
+		if ll, ok := l.(LogLane); ok {
+			ll.SetParentIdOutput(true)
+		}
+	}
+	if lo.maxDepth != 0 {
+		if ll, ok := l.(LogLane); ok {
+			ll.SetMaxDepth(lo.maxDepth)
+		}
+	}
+	if lo.teeTimeout != 0 {
+		if ll, ok := l.(LogLane); ok {
+			ll.SetTeeTimeout(lo.teeTimeout)
+		}
+	}
+	if lo.maxLength != 0 {
+		l.SetLengthConstraint(lo.maxLength)
+	}
+}
+
+// Sets the log level of the new lane, as with SetLogLevel().
+func WithLevel(level LaneLogLevel) LaneOption {
+	return func(o *laneOptions) {
+		o.level = &level
+	}
+}
+
+// Sets the journey ID of the new lane, as with SetJourneyId().
+func WithJourneyId(id string) LaneOption {
+	return func(o *laneOptions) {
+		o.journeyId = &id
+	}
+}
+
+// Attaches [tee] to the new lane, as with AddTee(). The option can be given
+// more than once.
+func WithTee(tee Lane) LaneOption {
+	return func(o *laneOptions) {
+		o.tees = append(o.tees, tee)
+	}
+}
+
+// Ends the lines of a log lane type with \r\n, as NewLogLaneWithCR() does.
+// Other lane types ignore it.
+func WithCR() LaneOption {
+	return WithLineEnding(LineEndingCRLF)
+}
+
+// Selects the line endings of a log lane type's text output, as with
+// SetLineEnding(). Other lane types ignore it.
+func WithLineEnding(ending LineEnding) LaneOption {
+	return func(o *laneOptions) {
+		o.lineEnding = &ending
+	}
+}
+
+// Renders the timestamps of a log lane type in [loc], with the time zone, as
+// with SetTimeLocation(). Other lane types ignore it.
+func WithTimeLocation(loc *time.Location) LaneOption {
+	return func(o *laneOptions) {
+		o.location = loc
+	}
+}
+
+// Shows the parent lane's ID in the output of a log lane type's derived
+// lanes, as with SetParentIdOutput(). Other lane types ignore it.
+func WithParentIdOutput() LaneOption {
+	return func(o *laneOptions) {
+		o.showParent = true
+	}
+}
+
+// Selects how a log lane type's text output writes a message that has line
+// breaks, as with SetMultiLinePolicy(). Other lane types ignore it.
+func WithMultiLinePolicy(policy MultiLinePolicy) LaneOption {
+	return func(o *laneOptions) {
+		o.multiLine = &policy
+	}
+}
+
+// Limits the length of the new lane's messages, as with
+// SetLengthConstraint().
+func WithMaxLength(maxLength int) LaneOption {
+	return func(o *laneOptions) {
+		o.maxLength = maxLength
+	}
+}
+
+// Selects the lane ID format for the new lane and all of its derivations.
+func WithLaneIdFormat(format LaneIdFormat) LaneOption {
+	return func(o *laneOptions) {
+		o.idGen = format.generator()
+	}
+}
+
+// Supplies the lane ID generator for the new lane and all of its derivations.
+func WithIdSource(gen LaneIdGenerator) LaneOption {
+	return func(o *laneOptions) {
+		o.idGen = gen
+	}
+}
+
+// Supplies the clock used for log timestamps, for the new lane and all of its
+// derivations.
+func WithClock(clock Clock) LaneOption {
+	return func(o *laneOptions) {
+		o.clock = clock
+	}
+}
+
+// Makes the new lane close its tees when it is closed. Lanes derived from it
+// share the tees, but don't close them.
+func WithTeeClose() LaneOption {
+	return func(o *laneOptions) {
+		o.teeClose = true
+	}
+}
+
+// Buffers the output of a disk lane, writing it to the file when [size] bytes
+// accumulate, every [flushInterval] (one second if zero), on Flush(), and
+// when a lane is closed or logs a fatal error. Other lane types ignore it.
+func WithWriteBuffer(size int, flushInterval time.Duration) LaneOption {
+	return func(o *laneOptions) {
+		o.bufferSize = size
+		o.flushInterval = flushInterval
+	}
+}
+
+// Renders the output of the new lane and its derivations with [enc] instead
+// of as text lines.
+func WithEncoder(enc Encoder) LaneOption {
+	return func(o *laneOptions) {
+		o.encoder = enc
+	}
+}
+
+// Monitors the free space of a disk lane's filesystem, writing only WARN and
+// above when it falls below [guard].LowBytes, and nothing when it falls below
+// [guard].CriticalBytes, so that logging can't fill the disk. Each change is
+// logged once, and writing resumes when space is freed. Other lane types
+// ignore it.
+func WithDiskSpaceGuard(guard DiskSpaceGuard) LaneOption {
+	return func(o *laneOptions) {
+		o.diskSpace = &guard
+	}
+}
+
+// Finds the level of text written to the Logger() of the new lane and its
+// derivations with [parser] instead of ParseLevelPrefix(), such as for the
+// prefixes of a third-party library. Null lanes ignore it.
+func WithLevelParser(parser LevelParser) LaneOption {
+	return func(o *laneOptions) {
+		o.levelParser = parser
+	}
+}
+
+// Makes a null lane panic when a message at [level] or above is logged to it,
+// to catch unexpected logging in tests. Other lane types ignore it.
+func StrictNull(level LaneLogLevel) LaneOption {
+	return StrictNullFunc(level, nil)
+}
+
+// Makes a null lane call [fn] when a message at [level] or above is logged to
+// it, or panic if [fn] is nil. Other lane types ignore it.
+func StrictNullFunc(level LaneLogLevel, fn func(rec Record)) LaneOption {
+	return func(o *laneOptions) {
+		o.strict = &strictNull{level: level, fn: fn}
+	}
+}
+
+// Routes the internal errors of the new lane and its derivations, such as an
+// object that can't be rendered by the object logging functions, to
+// [handler]. The logging call still completes, with a fallback rendering.
+func WithDiagnostics(handler ErrorHandler) LaneOption {
+	return func(o *laneOptions) {
+		o.diagnostics = handler
+	}
+}
+
+// Internal option to carry a lane ID generator into a replacement lane
+func withLaneIdGenerator(gen LaneIdGenerator) LaneOption {
+	return func(o *laneOptions) {
+		o.idGen = gen
+	}
+}
+
+// Internal option to make a disk lane write gzip compressed output
+func withCompression() LaneOption {
+	return func(o *laneOptions) {
+		o.compress = true
+	}
+}
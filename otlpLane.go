@@ -0,0 +1,161 @@
+package lane
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// Settings for a lane that exports log records to an OpenTelemetry
+	// collector with OTLP/HTTP, in the JSON encoding
+	OtlpConfig struct {
+		Endpoint           string            // defaults to "http://localhost:4318/v1/logs"
+		Headers            map[string]string // added to each request, such as an authorization header
+		ServiceName        string            // the service.name resource attribute, when set
+		ResourceAttributes map[string]string // more attributes of the resource, such as "deployment.environment"
+		Gzip               bool              // compress the requests
+		BatchSize          int               // maximum records per request, defaults to 100
+		QueueSize          int               // records held while sending, defaults to 10000
+		MaxRetries         int               // attempts to send a batch before dropping it, defaults to 3
+		Backoff            Backoff           // delays between the attempts to send a batch; MaxRetries sets the attempts
+		Timeout            time.Duration     // per request, defaults to 10 seconds
+		Client             *http.Client      // defaults to a client with the timeout
+		Spool              *SpoolConfig      // holds undeliverable records on disk for replay, when set
+	}
+
+	otlpLogRecord struct {
+		TimeUnixNano         string          `json:"timeUnixNano"`
+		ObservedTimeUnixNano string          `json:"observedTimeUnixNano"`
+		SeverityNumber       int             `json:"severityNumber"`
+		SeverityText         string          `json:"severityText"`
+		Body                 otlpAnyValue    `json:"body"`
+		Attributes           []otlpAttribute `json:"attributes,omitempty"`
+	}
+
+	otlpAttribute struct {
+		Key   string       `json:"key"`
+		Value otlpAnyValue `json:"value"`
+	}
+
+	otlpAnyValue struct {
+		StringValue string `json:"stringValue"`
+	}
+)
+
+// The OpenTelemetry severity numbers of the lane levels
+var otlpSeverities = [logLevelMax]int{
+	LogLevelTrace:    1,
+	LogLevelDebug:    5,
+	LogLevelInfo:     9,
+	LogLevelWarn:     13,
+	LogLevelError:    17,
+	logLevelPreFatal: 21,
+	LogLevelFatal:    21,
+	LogLevelStack:    9,
+}
+
+// Makes a lane that exports its log records to an OpenTelemetry collector,
+// such as for a collector that isn't fed by a log file. Each record becomes
+// an OTLP LogRecord with the level as its severity, and the lane ID, journey
+// ID, category, parent lane ID, caller and metadata as attributes. Records
+// are queued and sent in batches in the background, so logging does not wait
+// for the network. Close the lane to flush the queue.
+func NewOtlpLogLane(ctx OptionalContext, cfg OtlpConfig, opts ...LaneOption) Lane {
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "http://localhost:4318/v1/logs"
+	}
+
+	headers := map[string]string{"Content-Type": "application/json"}
+	for k, v := range cfg.Headers {
+		headers[k] = v
+	}
+
+	resource := map[string]string{}
+	for k, v := range cfg.ResourceAttributes {
+		resource[k] = v
+	}
+	if cfg.ServiceName != "" {
+		resource["service.name"] = cfg.ServiceName
+	}
+	resourceJson, _ := json.Marshal(map[string]any{"attributes": otlpAttributes(resource)})
+
+	return newHTTPBatchLane(ctx, httpBatchConfig{
+		endpoint:   cfg.Endpoint,
+		headers:    headers,
+		gzip:       cfg.Gzip,
+		batchSize:  cfg.BatchSize,
+		queueSize:  cfg.QueueSize,
+		maxRetries: cfg.MaxRetries,
+		backoff:    cfg.Backoff,
+		timeout:    cfg.Timeout,
+		client:     cfg.Client,
+		spool:      cfg.Spool,
+		encodeRecord: func(rec *Record) []byte {
+			return encodeOtlpLogRecord(rec, time.Now())
+		},
+		encodeBody: func(records [][]byte) []byte {
+			return encodeOtlpRequest(resourceJson, records)
+		},
+	}, opts...)
+}
+
+// Encodes [rec] as the JSON of an OTLP LogRecord, observed at [observed]
+func encodeOtlpLogRecord(rec *Record, observed time.Time) []byte {
+	attrs := map[string]string{"lane.id": rec.LaneId}
+	if rec.JourneyId != "" {
+		attrs["lane.journey_id"] = rec.JourneyId
+	}
+	if rec.Category != "" {
+		attrs["lane.category"] = rec.Category
+	}
+	if rec.ParentId != "" {
+		attrs["lane.parent_id"] = rec.ParentId
+	}
+	if rec.Caller != "" {
+		attrs["code.caller"] = rec.Caller
+	}
+	for k, v := range rec.Fields {
+		attrs[k] = v
+	}
+
+	if len(rec.Stack) > 0 {
+		attrs["exception.stacktrace"] = strings.Join(rec.Stack, "\n")
+	}
+
+	data, _ := json.Marshal(otlpLogRecord{
+		TimeUnixNano:         strconv.FormatInt(rec.Time.UnixNano(), 10),
+		ObservedTimeUnixNano: strconv.FormatInt(observed.UnixNano(), 10),
+		SeverityNumber:       otlpSeverities[rec.Level],
+		SeverityText:         levelNames[rec.Level],
+		Body:                 otlpAnyValue{StringValue: rec.Message},
+		Attributes:           otlpAttributes(attrs),
+	})
+	return data
+}
+
+// Makes the attribute list of [m], in key order
+func otlpAttributes(m map[string]string) []otlpAttribute {
+	attrs := make([]otlpAttribute, 0, len(m))
+	for k, v := range m {
+		attrs = append(attrs, otlpAttribute{Key: k, Value: otlpAnyValue{StringValue: v}})
+	}
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i].Key < attrs[j].Key })
+	return attrs
+}
+
+// Makes the body of an export request holding the encoded log [records]
+func encodeOtlpRequest(resourceJson []byte, records [][]byte) []byte {
+	buf := append([]byte(`{"resourceLogs":[{"resource":`), resourceJson...)
+	buf = append(buf, `,"scopeLogs":[{"scope":{"name":"go-lane"},"logRecords":[`...)
+	for i, rec := range records {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, rec...)
+	}
+	return append(buf, "]}]}]}"...)
+}
@@ -0,0 +1,110 @@
+package lane
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+type (
+	// The parts of an OTLP export request that the tests check
+	testOtlpRequest struct {
+		ResourceLogs []struct {
+			Resource struct {
+				Attributes []otlpAttribute `json:"attributes"`
+			} `json:"resource"`
+			ScopeLogs []struct {
+				LogRecords []otlpLogRecord `json:"logRecords"`
+			} `json:"scopeLogs"`
+		} `json:"resourceLogs"`
+	}
+)
+
+func testOtlpAttribute(attrs []otlpAttribute, key string) string {
+	for _, attr := range attrs {
+		if attr.Key == key {
+			return attr.Value.StringValue
+		}
+	}
+	return ""
+}
+
+func TestOtlpLogLane(t *testing.T) {
+	tis := newTestIntakeServer()
+	defer tis.Close()
+
+	ol := NewOtlpLogLane(nil, OtlpConfig{
+		Endpoint:           tis.URL + "/v1/logs",
+		Headers:            map[string]string{"Authorization": "Bearer token"},
+		ServiceName:        "checkout",
+		ResourceAttributes: map[string]string{"deployment.environment": "test"},
+		Gzip:               true,
+	})
+	ol.SetJourneyId("trip")
+	ol.SetMetadata("tenant", "acme")
+	ol.Category("db").Warn("slow query")
+	ol.Close()
+
+	requests := tis.waitRequests(t, 1)
+	if len(requests) != 1 || requests[0].path != "/v1/logs" || requests[0].header.Get("Authorization") != "Bearer token" || requests[0].header.Get("Content-Type") != "application/json" {
+		t.Fatalf("unexpected requests %+v", requests)
+	}
+
+	var req testOtlpRequest
+	if err := json.Unmarshal(requests[0].body, &req); err != nil {
+		t.Fatal(err)
+	}
+	if len(req.ResourceLogs) != 1 || len(req.ResourceLogs[0].ScopeLogs) != 1 || len(req.ResourceLogs[0].ScopeLogs[0].LogRecords) != 1 {
+		t.Fatalf("unexpected request %s", requests[0].body)
+	}
+	resource := req.ResourceLogs[0].Resource.Attributes
+	if testOtlpAttribute(resource, "service.name") != "checkout" || testOtlpAttribute(resource, "deployment.environment") != "test" {
+		t.Errorf("unexpected resource %+v", resource)
+	}
+
+	rec := req.ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+	if rec.SeverityNumber != 13 || rec.SeverityText != "WARN" || rec.Body.StringValue != "slow query" {
+		t.Errorf("unexpected record %+v", rec)
+	}
+	if testOtlpAttribute(rec.Attributes, "lane.journey_id") != "trip" || testOtlpAttribute(rec.Attributes, "lane.category") != "db" || testOtlpAttribute(rec.Attributes, "lane.id") == "" {
+		t.Errorf("unexpected attributes %+v", rec.Attributes)
+	}
+}
+
+func TestOtlpLogRecord(t *testing.T) {
+	rec := Record{
+		Time:   time.Unix(1700000000, 5),
+		LaneId: "lane",
+		Level:  LogLevelStack,
+		Fields: map[string]string{"b": "2", "a": "1"},
+		Stack:  []string{"main.main()", "\tmain.go:10"},
+	}
+
+	var decoded otlpLogRecord
+	if err := json.Unmarshal(encodeOtlpLogRecord(&rec, time.Unix(1700000001, 0)), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.TimeUnixNano != "1700000000000000005" || decoded.ObservedTimeUnixNano != "1700000001000000000" {
+		t.Errorf("unexpected times %+v", decoded)
+	}
+	keys := ""
+	for _, attr := range decoded.Attributes {
+		keys += attr.Key + " "
+	}
+	if keys != "a b exception.stacktrace lane.id " {
+		t.Errorf("unexpected attributes %+v", decoded.Attributes)
+	}
+}
+
+func TestOtlpLogLaneStats(t *testing.T) {
+	tis := newTestIntakeServer()
+	defer tis.Close()
+
+	ol := NewOtlpLogLane(nil, OtlpConfig{Endpoint: tis.URL})
+	ol.Info("counted")
+	ol.Close()
+
+	if stats := StatsOf(ol); stats.BytesWritten == 0 || stats.Events[LogLevelInfo] != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
@@ -0,0 +1,60 @@
+package lane
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParentIdOutput(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	ll := NewLogLane(nil, WithParentIdOutput())
+	ll.SetJourneyId("trip")
+	var rec testRecordSink
+	ll.(LogLane).AddRecordSink(&rec)
+
+	ll.Info("root")
+	child := ll.Derive()
+	child.Info("child")
+	grandchild := child.Derive()
+	grandchild.Info("grandchild")
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	expected := []string{
+		"INFO {trip:" + trimLaneId(ll.LaneId()) + "} root",
+		"INFO {trip:" + trimLaneId(ll.LaneId()) + "→" + trimLaneId(child.LaneId()) + "} child",
+		"INFO {trip:" + trimLaneId(child.LaneId()) + "→" + trimLaneId(grandchild.LaneId()) + "} grandchild",
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+	for i, line := range lines {
+		if !strings.HasSuffix(line, expected[i]) {
+			t.Errorf("expected %q, got %q", expected[i], line)
+		}
+	}
+
+	if len(rec.records) != 3 || rec.records[0].ParentId != "" || rec.records[2].ParentId != child.LaneId() {
+		t.Errorf("unexpected records %v", rec.records)
+	}
+	if text := string(LogfmtEncoder{}.AppendRecord(nil, &rec.records[1])); !strings.Contains(text, " parent="+ll.LaneId()+" ") {
+		t.Errorf("unexpected logfmt %q", text)
+	}
+	if text := string(JSONEncoder{}.AppendRecord(nil, &rec.records[1])); !strings.Contains(text, `"parentLaneId":"`+ll.LaneId()+`"`) {
+		t.Errorf("unexpected json %q", text)
+	}
+
+	if prior := child.(LogLane).SetParentIdOutput(false); !prior {
+		t.Error("the setting wasn't inherited")
+	}
+	buf.Reset()
+	child.Info("hidden")
+	if strings.Contains(buf.String(), "→") {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+}
@@ -0,0 +1,162 @@
+package lane
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+type (
+	// Common implementation of rate limited and once-only logging. The rate
+	// limits are shared by a lane and the lanes derived from it, while the
+	// once-only keys belong to the lane.
+	logLimitStore struct {
+		limitMu sync.Mutex
+		rates   *rateLimits
+		once    map[string]struct{}
+	}
+
+	rateLimits struct {
+		mu      sync.Mutex
+		windows map[string]*rateWindow
+	}
+
+	// The logging of a rate limited key
+	rateWindow struct {
+		next       time.Time
+		suppressed int
+	}
+)
+
+// the number of keys at which expired windows are discarded
+const rateLimitPruneSize = 1024
+
+// the keys of InfoOnceGlobal() and WarnOnceGlobal()
+var globalOnce sync.Map
+
+// Gives a derived lane the rate limits of its parent
+func (lls *logLimitStore) inheritLimits(parent *logLimitStore) {
+	rates := parent.rateLimits()
+
+	lls.limitMu.Lock()
+	defer lls.limitMu.Unlock()
+	lls.rates = rates
+}
+
+// Provides the rate limits, allocating them on first use
+func (lls *logLimitStore) rateLimits() *rateLimits {
+	lls.limitMu.Lock()
+	defer lls.limitMu.Unlock()
+
+	if lls.rates == nil {
+		lls.rates = &rateLimits{windows: map[string]*rateWindow{}}
+	}
+	return lls.rates
+}
+
+// Checks if a message for [key] can be logged at [now], providing the
+// number of messages suppressed since the last one that was logged
+func (rl *rateLimits) allow(key string, period time.Duration, now time.Time) (allowed bool, suppressed int) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	w := rl.windows[key]
+	if w == nil {
+		if len(rl.windows) >= rateLimitPruneSize {
+			rl.prune(now)
+		}
+		w = &rateWindow{}
+		rl.windows[key] = w
+	}
+
+	if now.Before(w.next) {
+		w.suppressed++
+		return
+	}
+
+	allowed = true
+	suppressed = w.suppressed
+	w.suppressed = 0
+	w.next = now.Add(period)
+	return
+}
+
+// Discards the keys that have no suppressed messages and are no longer
+// limiting, so that many distinct keys don't accumulate
+func (rl *rateLimits) prune(now time.Time) {
+	for key, w := range rl.windows {
+		if w.suppressed == 0 && !now.Before(w.next) {
+			delete(rl.windows, key)
+		}
+	}
+}
+
+// Logs the [text] to [l] at [level], at most once per [period] for [key].
+// The message that follows suppressed messages reports how many there were.
+func logRateLimited(l Lane, lls *logLimitStore, level LaneLogLevel, key string, period time.Duration, now time.Time, text func() string) {
+	if ld, ok := l.(levelDiscarder); ok && ld.discards(level) {
+		return
+	}
+
+	allowed, suppressed := lls.rateLimits().allow(key, period, now)
+	if !allowed {
+		return
+	}
+
+	msg := text()
+	if suppressed > 0 {
+		msg = fmt.Sprintf("%s (%d similar messages suppressed)", msg, suppressed)
+	}
+
+	li := l.(laneInternal)
+	logTextInternal(li.LaneProps(), li, level, msg)
+}
+
+// Checks if [key] is used for the first time on the lane
+func (lls *logLimitStore) firstUse(key string) bool {
+	lls.limitMu.Lock()
+	defer lls.limitMu.Unlock()
+
+	if _, used := lls.once[key]; used {
+		return false
+	}
+	if lls.once == nil {
+		lls.once = map[string]struct{}{}
+	}
+	lls.once[key] = struct{}{}
+	return true
+}
+
+// Logs the [text] to [l] at [level] if [firstUse] reports that [key] hasn't
+// been logged before. A message at a discarded level doesn't use the key.
+func logOnce(l Lane, level LaneLogLevel, key string, firstUse func(key string) bool, text func() string) {
+	if ld, ok := l.(levelDiscarder); ok && ld.discards(level) {
+		return
+	}
+	if !firstUse(key) {
+		return
+	}
+
+	li := l.(laneInternal)
+	logTextInternal(li.LaneProps(), li, level, text())
+}
+
+func globalFirstUse(key string) bool {
+	_, used := globalOnce.LoadOrStore(key, struct{}{})
+	return !used
+}
+
+// Logs an informational message to [l] only the first time [key] is used in
+// the process, such as for a deprecation notice logged by every request lane.
+// The keys are shared with WarnOnceGlobal(). Messages formated with
+// fmt.Sprint().
+func InfoOnceGlobal(l Lane, key string, args ...any) {
+	logOnce(l, LogLevelInfo, key, globalFirstUse, func() string { return sprint(args...) })
+}
+
+// Logs a warning to [l] only the first time [key] is used in the process,
+// such as for a configuration warning logged by every request lane. The keys
+// are shared with InfoOnceGlobal(). Messages formated with fmt.Sprint().
+func WarnOnceGlobal(l Lane, key string, args ...any) {
+	logOnce(l, LogLevelWarn, key, globalFirstUse, func() string { return sprint(args...) })
+}
@@ -0,0 +1,112 @@
+package lane
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRateLimitedLogging(t *testing.T) {
+	tl := NewTestingLane(nil)
+
+	for i := range 5 {
+		tl.ErrorRatef("db", time.Hour, "query %d failed", i)
+	}
+	tl.WarnRate("cache", time.Hour, "cache", "miss")
+	tl.WarnRate("cache", time.Hour, "cache", "miss")
+
+	if !tl.VerifyEventText("ERROR\tquery 0 failed\nWARN\tcache miss") {
+		t.Errorf("unexpected events:\n%s", tl.EventsToString())
+	}
+}
+
+func TestRateLimitedSummary(t *testing.T) {
+	clock := NewFixedClock(time.Date(2024, 4, 9, 11, 37, 37, 0, time.UTC))
+	ll := NewLogLane(nil, WithClock(clock))
+	tl := NewTestingLane(nil)
+	ll.AddTee(tl)
+
+	ll.ErrorRate("dep", time.Second, "dependency down")
+	ll.ErrorRate("dep", time.Second, "dependency down")
+	ll.ErrorRate("dep", time.Second, "dependency down")
+	clock.Advance(time.Second)
+	ll.ErrorRate("dep", time.Second, "dependency down")
+
+	if !tl.VerifyEventText("ERROR\tdependency down\nERROR\tdependency down (2 similar messages suppressed)") {
+		t.Errorf("unexpected events:\n%s", tl.EventsToString())
+	}
+}
+
+func TestRateLimitedSharedByDerivations(t *testing.T) {
+	for _, l := range []Lane{NewTestingLane(nil), NewLogLane(nil), NewNullLane(nil)} {
+		tl := NewTestingLane(nil)
+		l.AddTee(tl)
+
+		l.WarnRate("key", time.Hour, "first")
+		l.Derive().WarnRate("key", time.Hour, "from a child")
+		l.DeriveReplaceContext(nil).WarnRatef("key", time.Hour, "from %s", "a replacement")
+
+		if !tl.VerifyEventText("WARN\tfirst") {
+			t.Errorf("%T: unexpected events:\n%s", l, tl.EventsToString())
+		}
+	}
+}
+
+func TestRateLimitedDiscarded(t *testing.T) {
+	ll := NewLogLane(nil)
+	ll.SetLogLevel(LogLevelFatal)
+	called := false
+	logRateLimited(ll, &ll.(*logLane).logLimitStore, LogLevelError, "k", time.Hour, time.Now(), func() string {
+		called = true
+		return ""
+	})
+	if called {
+		t.Error("the message was prepared for a discarded level")
+	}
+}
+
+func TestRateLimitsPrune(t *testing.T) {
+	rl := rateLimits{windows: map[string]*rateWindow{}}
+	now := time.Now()
+	for i := range rateLimitPruneSize {
+		rl.allow(fmt.Sprint(i), time.Millisecond, now)
+	}
+	rl.allow("suppressing", time.Hour, now)
+	rl.allow("suppressing", time.Hour, now)
+	rl.allow("new", time.Hour, now.Add(time.Second))
+
+	if len(rl.windows) != 2 {
+		t.Errorf("expected the expired keys to be pruned, have %d", len(rl.windows))
+	}
+}
+
+func TestLogOnce(t *testing.T) {
+	for _, l := range []Lane{NewTestingLane(nil), NewLogLane(nil), NewNullLane(nil)} {
+		tl := NewTestingLane(nil)
+		l.AddTee(tl)
+
+		l.InfoOnce("notice", "deprecated", "option")
+		l.InfoOnce("notice", "again")
+		l.WarnOnce("config", "bad config")
+		l.WarnOnce("config", "bad config")
+		l.Derive().WarnOnce("config", "child config")
+
+		if !tl.VerifyEventText("INFO\tdeprecated option\nWARN\tbad config\nWARN\tchild config") {
+			t.Errorf("%T: unexpected events:\n%s", l, tl.EventsToString())
+		}
+	}
+}
+
+func TestLogOnceGlobal(t *testing.T) {
+	tl := NewTestingLane(nil)
+	tl.WantDescendantEvents(true)
+
+	for range 3 {
+		child := tl.Derive()
+		InfoOnceGlobal(child, "TestLogOnceGlobal-info", "info once")
+		WarnOnceGlobal(child, "TestLogOnceGlobal-warn", "warn once")
+	}
+	if !tl.VerifyEventText("INFO\tinfo once\nWARN\twarn once") {
+		t.Errorf("unexpected events:\n%s", tl.EventsToString())
+	}
+}
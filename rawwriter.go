@@ -0,0 +1,70 @@
+package lane
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+type (
+	// The raw writers of a log lane, shared by the lanes derived from it
+	rawWriterSet struct {
+		mu      sync.Mutex
+		writers []io.Writer
+		count   atomic.Int32
+	}
+
+	// Output writer that also sends the bytes to the raw writers
+	rawTeeWriter struct {
+		w   io.Writer
+		set *rawWriterSet
+	}
+)
+
+// Also writes the formatted output of the lane to [w]. The lanes derived from
+// the lane, before or after, share its raw writers.
+//
+// Each output write goes to the lane's output first, and then to the raw
+// writers in the order they were added. Writes are serialized, so every raw
+// writer receives the output in the same order. A raw writer's errors are
+// ignored, and it must not retain the bytes it is given.
+func (ll *logLane) AddRawWriter(w io.Writer) {
+	ll.raw.mu.Lock()
+	defer ll.raw.mu.Unlock()
+	ll.raw.writers = append(ll.raw.writers, w)
+	ll.raw.count.Store(int32(len(ll.raw.writers)))
+}
+
+// Stops writing the lane's output to [w]
+func (ll *logLane) RemoveRawWriter(w io.Writer) {
+	ll.raw.mu.Lock()
+	defer ll.raw.mu.Unlock()
+	for i, rw := range ll.raw.writers {
+		if rw == w {
+			ll.raw.writers = append(ll.raw.writers[:i:i], ll.raw.writers[i+1:]...)
+			break
+		}
+	}
+	ll.raw.count.Store(int32(len(ll.raw.writers)))
+}
+
+func (rtw *rawTeeWriter) Write(p []byte) (n int, err error) {
+	if rtw.set.count.Load() == 0 {
+		return rtw.w.Write(p)
+	}
+
+	rtw.set.mu.Lock()
+	defer rtw.set.mu.Unlock()
+	n, err = rtw.w.Write(p)
+	for _, w := range rtw.set.writers {
+		w.Write(p)
+	}
+	return
+}
+
+func (rtw *rawTeeWriter) Flush() error {
+	if of, ok := rtw.w.(outputFlusher); ok {
+		return of.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package lane
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestLogLaneRawWriter(t *testing.T) {
+	var output bytes.Buffer
+	log.SetOutput(&output)
+	defer log.SetOutput(os.Stderr)
+
+	ll := NewLogLane(nil).(LogLane)
+	child := ll.Derive()
+
+	var first, second bytes.Buffer
+	ll.AddRawWriter(&first)
+	ll.Info("before second")
+	child.(LogLane).AddRawWriter(&second)
+	child.Warn("from the child")
+	ll.RemoveRawWriter(&first)
+	ll.Error("after removal")
+
+	lines := strings.SplitAfter(output.String(), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("unexpected output %q", output.String())
+	}
+	if first.String() != lines[0]+lines[1] {
+		t.Errorf("unexpected first raw output %q", first.String())
+	}
+	if second.String() != lines[1]+lines[2] {
+		t.Errorf("unexpected second raw output %q", second.String())
+	}
+}
+
+func TestLogLaneRawWriterOrder(t *testing.T) {
+	var output bytes.Buffer
+	log.SetOutput(&output)
+	defer log.SetOutput(os.Stderr)
+
+	ll := NewLogLane(nil).(LogLane)
+	var raw bytes.Buffer
+	ll.AddRawWriter(&raw)
+
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l := ll.Derive()
+			for j := range 50 {
+				l.Infof("goroutine %d message %d", i, j)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if raw.String() != output.String() || strings.Count(raw.String(), "\n") != 200 {
+		t.Error("the raw writer did not receive the output in the same order")
+	}
+}
+
+func TestDiskLaneRawWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "raw.log")
+	dl, err := NewDiskLane(nil, path, WithEncoder(JSONEncoder{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var raw bytes.Buffer
+	dl.(LogLane).AddRawWriter(&raw)
+	dl.Info("encoded")
+	dl.Close()
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw.String() != string(content) || !strings.Contains(raw.String(), `"message":"encoded"`) {
+		t.Errorf("unexpected raw output %q", raw.String())
+	}
+}
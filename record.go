@@ -0,0 +1,222 @@
+package lane
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+type (
+	// A log message as it is delivered to an output
+	Record struct {
+		Time      time.Time
+		LaneId    string
+		JourneyId string
+		Category  string // the category of the lane, from Category(), or ""
+		ParentId  string // the ID of the lane's parent, if the lane shows it with SetParentIdOutput()
+		Depth     int    // the nesting level of the lane, from Indent()
+		Level     LaneLogLevel
+		Message   string
+		Caller    string            // file:line and function of the logging call, if SetCallerInfo() is enabled
+		Fields    map[string]string // the lane's metadata, nil if it has none
+		Stack     []string          // the lines of a stack trace, for LogLevelStack
+	}
+
+	// Renders records for a lane's output. A lane with an encoder writes the
+	// encoded bytes of each record in place of its usual text line.
+	Encoder interface {
+		// Appends the encoded record to [buf], including its terminator.
+		AppendRecord(buf []byte, rec *Record) []byte
+	}
+
+	// Renders records the way a log lane normally does, as "LEVEL {id} message"
+	// lines preceded by a timestamp selected by [Flags], which takes the log
+	// package's Ldate, Ltime, Lmicroseconds and LUTC flags. Fields are not
+	// included.
+	TextEncoder struct {
+		Flags int
+	}
+
+	// Renders records as one JSON object per line
+	JSONEncoder struct{}
+
+	// Renders records as a sequence of CBOR maps (RFC 8742), with the same
+	// keys as the JSON encoder and the time as a tagged RFC 3339 string
+	CBOREncoder struct{}
+
+	// Reads the records written by a CBOREncoder, such as from a disk lane's
+	// file or a stream server lane's "format=cbor" stream
+	CBORDecoder struct {
+		r *bufio.Reader
+	}
+
+	jsonRecord struct {
+		Time      time.Time         `json:"time"`
+		Level     string            `json:"level"`
+		LaneId    string            `json:"laneId"`
+		JourneyId string            `json:"journeyId,omitempty"`
+		Category  string            `json:"category,omitempty"`
+		ParentId  string            `json:"parentLaneId,omitempty"`
+		Depth     int               `json:"depth,omitempty"`
+		Message   string            `json:"message"`
+		Caller    string            `json:"caller,omitempty"`
+		Fields    map[string]string `json:"fields,omitempty"`
+		Stack     []string          `json:"stack,omitempty"`
+	}
+)
+
+// Provides the properties of the lane that logged the record
+func (rec *Record) props() loggingProperties {
+	return loggingProperties{laneId: rec.LaneId, journeyId: rec.JourneyId, category: rec.Category, parentId: rec.ParentId, indent: rec.Depth}
+}
+
+func (te TextEncoder) AppendRecord(buf []byte, rec *Record) []byte {
+	props := rec.props()
+	timestamp := formatLogTime(rec.Time, te.Flags)
+
+	appendLine := func(buf []byte, level LaneLogLevel, text string) []byte {
+		buf = append(buf, timestamp...)
+		buf = append(buf, props.getMessagePrefix(levelNames[level])...)
+		buf = append(buf, ' ')
+		buf = append(buf, text...)
+		return append(buf, '\n')
+	}
+
+	if rec.Caller != "" {
+		buf = appendLine(buf, rec.Level, rec.Caller+": "+rec.Message)
+	} else if rec.Level != LogLevelStack || rec.Message != "" {
+		buf = appendLine(buf, rec.Level, rec.Message)
+	}
+	for _, line := range rec.Stack {
+		buf = appendLine(buf, LogLevelStack, line)
+	}
+	return buf
+}
+
+func (JSONEncoder) AppendRecord(buf []byte, rec *Record) []byte {
+	data, err := json.Marshal(jsonRecord{
+		Time:      rec.Time,
+		Level:     levelNames[rec.Level],
+		LaneId:    rec.LaneId,
+		JourneyId: rec.JourneyId,
+		Category:  rec.Category,
+		ParentId:  rec.ParentId,
+		Depth:     rec.Depth,
+		Message:   rec.Message,
+		Caller:    rec.Caller,
+		Fields:    rec.Fields,
+		Stack:     rec.Stack,
+	})
+	if err != nil {
+		// the record is made of strings, so this is not expected
+		return buf
+	}
+	return append(append(buf, data...), '\n')
+}
+
+var errCborRecord = errors.New("invalid cbor record")
+
+func (CBOREncoder) AppendRecord(buf []byte, rec *Record) []byte {
+	m := map[string]any{
+		"time":    cborTag{num: 0, v: rec.Time.Format(time.RFC3339Nano)},
+		"level":   levelNames[rec.Level],
+		"laneId":  rec.LaneId,
+		"message": rec.Message,
+	}
+	if rec.JourneyId != "" {
+		m["journeyId"] = rec.JourneyId
+	}
+	if rec.Category != "" {
+		m["category"] = rec.Category
+	}
+	if rec.ParentId != "" {
+		m["parentLaneId"] = rec.ParentId
+	}
+	if rec.Depth != 0 {
+		m["depth"] = rec.Depth
+	}
+	if rec.Caller != "" {
+		m["caller"] = rec.Caller
+	}
+	if len(rec.Fields) > 0 {
+		fields := make(map[string]any, len(rec.Fields))
+		for k, v := range rec.Fields {
+			fields[k] = v
+		}
+		m["fields"] = fields
+	}
+	if len(rec.Stack) > 0 {
+		m["stack"] = rec.Stack
+	}
+	return appendCbor(buf, m)
+}
+
+// Makes a decoder of the CBOR records in [r]
+func NewCBORDecoder(r io.Reader) *CBORDecoder {
+	return &CBORDecoder{r: bufio.NewReader(r)}
+}
+
+// Reads the next record, returning io.EOF after the last one. The record's
+// fields are nil when it has none.
+func (cd *CBORDecoder) Decode() (rec *Record, err error) {
+	v, err := readCbor(cd.r)
+	if err != nil {
+		if errors.Is(err, errCborType) {
+			err = fmt.Errorf("%w: %w", errCborRecord, err)
+		}
+		return
+	}
+
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, errCborRecord
+	}
+
+	rec = &Record{}
+	text := func(key string) string {
+		s, _ := m[key].(string)
+		return s
+	}
+
+	if tag, ok := m["time"].(cborTag); ok {
+		if s, ok := tag.v.(string); ok && tag.num == 0 {
+			rec.Time, err = time.Parse(time.RFC3339Nano, s)
+		}
+	}
+	if err != nil || rec.Time.IsZero() {
+		return nil, fmt.Errorf("%w: bad time %v", errCborRecord, m["time"])
+	}
+
+	var found bool
+	if rec.Level, found = parseLevelName(text("level")); !found {
+		return nil, fmt.Errorf("%w: bad level %v", errCborRecord, m["level"])
+	}
+
+	rec.LaneId = text("laneId")
+	rec.JourneyId = text("journeyId")
+	rec.Category = text("category")
+	rec.ParentId = text("parentLaneId")
+	if depth, ok := m["depth"].(int64); ok {
+		rec.Depth = int(depth)
+	}
+	rec.Message = text("message")
+	rec.Caller = text("caller")
+
+	if fields, ok := m["fields"].(map[string]any); ok {
+		rec.Fields = make(map[string]string, len(fields))
+		for k, fv := range fields {
+			rec.Fields[k], _ = fv.(string)
+		}
+	}
+	if stack, ok := m["stack"].([]any); ok {
+		rec.Stack = make([]string, 0, len(stack))
+		for _, line := range stack {
+			s, _ := line.(string)
+			rec.Stack = append(rec.Stack, s)
+		}
+	}
+	return
+}
@@ -0,0 +1,215 @@
+package lane
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testRecord() *Record {
+	return &Record{
+		Time:      time.Date(2024, 3, 4, 5, 6, 7, 8000, time.UTC),
+		LaneId:    "abc",
+		JourneyId: "j1",
+		Level:     LogLevelWarn,
+		Message:   "careful",
+	}
+}
+
+func TestTextEncoder(t *testing.T) {
+	rec := testRecord()
+	actual := string(TextEncoder{Flags: log.LstdFlags | log.LUTC}.AppendRecord(nil, rec))
+	if actual != "2024/03/04 05:06:07 WARN {j1:abc} careful\n" {
+		t.Errorf("unexpected text %q", actual)
+	}
+
+	rec.Level = LogLevelStack
+	rec.Message = ""
+	rec.Stack = []string{"main()", "  main.go:1"}
+	actual = string(TextEncoder{}.AppendRecord(nil, rec))
+	if actual != "STACK {j1:abc} main()\nSTACK {j1:abc}   main.go:1\n" {
+		t.Errorf("unexpected text %q", actual)
+	}
+}
+
+func TestJSONEncoder(t *testing.T) {
+	rec := testRecord()
+	actual := string(JSONEncoder{}.AppendRecord(nil, rec))
+	expected := `{"time":"2024-03-04T05:06:07.000008Z","level":"WARN","laneId":"abc","journeyId":"j1","message":"careful"}` + "\n"
+	if actual != expected {
+		t.Errorf("unexpected json %s", actual)
+	}
+
+	rec.JourneyId = ""
+	rec.Fields = map[string]string{"user": "u1"}
+	rec.Stack = []string{"main()"}
+	actual = string(JSONEncoder{}.AppendRecord(nil, rec))
+	expected = `{"time":"2024-03-04T05:06:07.000008Z","level":"WARN","laneId":"abc","message":"careful","fields":{"user":"u1"},"stack":["main()"]}` + "\n"
+	if actual != expected {
+		t.Errorf("unexpected json %s", actual)
+	}
+}
+
+func TestCBOREncoder(t *testing.T) {
+	rec := testRecord()
+	rec.Fields = map[string]string{"k": "v"}
+
+	actual := CBOREncoder{}.AppendRecord([]byte{0xff}, rec)
+	expected := appendCbor([]byte{0xff}, map[string]any{
+		"time":      cborTag{num: 0, v: "2024-03-04T05:06:07.000008Z"},
+		"level":     "WARN",
+		"laneId":    "abc",
+		"journeyId": "j1",
+		"message":   "careful",
+		"fields":    map[string]any{"k": "v"},
+	})
+	if !bytes.Equal(actual, expected) {
+		t.Errorf("got %x, expected %x", actual, expected)
+	}
+
+	// the map header and the first key, which sorts first
+	if !bytes.HasPrefix(actual, []byte{0xff, 0xa6, 0x66, 'f', 'i', 'e', 'l', 'd', 's'}) {
+		t.Errorf("unexpected encoding %x", actual)
+	}
+}
+
+func TestCBORDecoder(t *testing.T) {
+	first := testRecord()
+	second := testRecord()
+	second.Level = LogLevelStack
+	second.JourneyId = ""
+	second.Caller = "main.go:1 main.main"
+	second.Fields = map[string]string{"k": "v"}
+	second.Stack = []string{"main()", "  main.go:1"}
+
+	data := CBOREncoder{}.AppendRecord(nil, first)
+	data = CBOREncoder{}.AppendRecord(data, second)
+
+	cd := NewCBORDecoder(bytes.NewReader(data))
+	for _, expected := range []*Record{first, second} {
+		rec, err := cd.Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(rec, expected) {
+			t.Errorf("got %+v, expected %+v", rec, expected)
+		}
+	}
+	if _, err := cd.Decode(); err != io.EOF {
+		t.Errorf("expected EOF, got %v", err)
+	}
+
+	for _, bad := range [][]byte{
+		appendCbor(nil, "not a map"),
+		appendCbor(nil, map[string]any{"level": "WARN"}),
+		appendCbor(nil, map[string]any{"time": cborTag{num: 0, v: "2024-03-04T05:06:07Z"}, "level": "LOUD"}),
+		{0xa1, 0x81, 0x01, 0x01}, // an array key
+	} {
+		if _, err := NewCBORDecoder(bytes.NewReader(bad)).Decode(); !errors.Is(err, errCborRecord) {
+			t.Errorf("%x: unexpected error %v", bad, err)
+		}
+	}
+}
+
+func TestLogLaneEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	ll := NewLogLane(nil, WithEncoder(JSONEncoder{}))
+	ll.SetMetadata("service", "api")
+	ll.Info("hello")
+	child := ll.Derive()
+	child.Warnf("count %d", 2)
+	ll.LogStack("here")
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 records, got:\n%s", buf.String())
+	}
+
+	var rec jsonRecord
+	if err := json.Unmarshal([]byte(lines[0]), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Level != "INFO" || rec.Message != "hello" || rec.LaneId != ll.LaneId() || rec.Fields["service"] != "api" {
+		t.Errorf("unexpected record %+v", rec)
+	}
+
+	// the derived lane inherits the encoder, but not the metadata
+	rec = jsonRecord{}
+	if err := json.Unmarshal([]byte(lines[1]), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Level != "WARN" || rec.Message != "count 2" || rec.LaneId != child.LaneId() || rec.Fields != nil {
+		t.Errorf("unexpected record %+v", rec)
+	}
+
+	rec = jsonRecord{}
+	if err := json.Unmarshal([]byte(lines[2]), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Level != "STACK" || rec.Message != "here" || len(rec.Stack) == 0 {
+		t.Errorf("unexpected record %+v", rec)
+	}
+
+	// removing the encoder restores the text output
+	buf.Reset()
+	prior := ll.(LogLane).SetEncoder(nil)
+	ll.Info("plain")
+	if _, ok := prior.(JSONEncoder); !ok || !strings.Contains(buf.String(), "INFO {"+ll.LaneId()[len(ll.LaneId())-10:]+"} plain") {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+}
+
+func TestDiskLaneEncoder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "encoded.log")
+	dl, err := NewDiskLane(nil, path, WithEncoder(TextEncoder{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dl.SetJourneyId("trip")
+	dl.Error("failed")
+	dl.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "ERROR {trip:" + trimLaneId(dl.LaneId()) + "} failed\n"
+	if string(data) != expected {
+		t.Errorf("expected %q, got %q", expected, string(data))
+	}
+	if StatsOf(dl).BytesWritten != int64(len(data)) {
+		t.Errorf("unexpected stats %+v", StatsOf(dl))
+	}
+}
+
+func TestEncoderWriteError(t *testing.T) {
+	f, err := os.OpenFile("/dev/full", os.O_WRONLY, 0)
+	if err != nil {
+		t.Skip("no /dev/full on this platform")
+	}
+	defer f.Close()
+	log.SetOutput(f)
+	defer log.SetOutput(os.Stderr)
+
+	var failed Record
+	ll := NewLogLane(nil, WithEncoder(JSONEncoder{}))
+	ll.SetErrorHandler(func(err error, record Record) {
+		failed = record
+	})
+	ll.Info("lost")
+
+	if failed.Message != "lost" || ll.LastError() == nil {
+		t.Errorf("expected the write error to be reported, got %+v", failed)
+	}
+}
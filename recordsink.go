@@ -0,0 +1,86 @@
+package lane
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+type (
+	// Receives the records a log lane outputs, alongside its usual output
+	RecordSink interface {
+		// Handles a record. The record is shared with the other sinks and
+		// must not be modified.
+		WriteRecord(rec *Record)
+	}
+
+	// The record sinks of a log lane, shared by the lanes derived from it
+	recordSinkSet struct {
+		mu    sync.Mutex
+		sinks []RecordSink
+		count atomic.Int32
+	}
+
+	// A record sink that writes encoded records
+	encodedSink struct {
+		mu  sync.Mutex
+		w   io.Writer
+		enc Encoder
+		buf []byte
+	}
+)
+
+// Also hands each record of the lane's output to [sink], such as one writing
+// JSON for a log shipper while the lane writes text for people, so that the
+// message is formatted once instead of by two tee lanes. The lanes derived
+// from the lane, before or after, share its record sinks.
+//
+// A record sink is called in the logging goroutine, after the record is
+// written to the lane's output.
+func (ll *logLane) AddRecordSink(sink RecordSink) {
+	ll.records.mu.Lock()
+	defer ll.records.mu.Unlock()
+	ll.records.sinks = append(ll.records.sinks, sink)
+	ll.records.count.Store(int32(len(ll.records.sinks)))
+}
+
+// Stops handing the lane's records to [sink]
+func (ll *logLane) RemoveRecordSink(sink RecordSink) {
+	ll.records.mu.Lock()
+	defer ll.records.mu.Unlock()
+	for i, rs := range ll.records.sinks {
+		if rs == sink {
+			ll.records.sinks = append(ll.records.sinks[:i:i], ll.records.sinks[i+1:]...)
+			break
+		}
+	}
+	ll.records.count.Store(int32(len(ll.records.sinks)))
+}
+
+func (rss *recordSinkSet) active() bool {
+	return rss.count.Load() != 0
+}
+
+func (rss *recordSinkSet) send(rec *Record) {
+	rss.mu.Lock()
+	sinks := rss.sinks
+	rss.mu.Unlock()
+
+	for _, sink := range sinks {
+		sink.WriteRecord(rec)
+	}
+}
+
+// Makes a record sink that writes the records encoded by [enc] to [w], such
+// as a JSONEncoder writing to a log shipper's connection. Write errors are
+// ignored.
+func NewEncodedSink(w io.Writer, enc Encoder) RecordSink {
+	return &encodedSink{w: w, enc: enc}
+}
+
+func (es *encodedSink) WriteRecord(rec *Record) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	es.buf = es.enc.AppendRecord(es.buf[:0], rec)
+	es.w.Write(es.buf)
+}
@@ -0,0 +1,68 @@
+package lane
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testRecordSink struct {
+	records []Record
+}
+
+func (trs *testRecordSink) WriteRecord(rec *Record) {
+	trs.records = append(trs.records, *rec)
+}
+
+func TestLogLaneRecordSink(t *testing.T) {
+	var output bytes.Buffer
+	log.SetOutput(&output)
+	defer log.SetOutput(os.Stderr)
+
+	clock := NewFixedClock(time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC))
+	ll := NewLogLane(nil, WithClock(clock), WithLaneIdFormat(LaneIdCounter)).(LogLane)
+	var jsonOut bytes.Buffer
+	sink := NewEncodedSink(&jsonOut, JSONEncoder{})
+	ll.AddRecordSink(sink)
+	ll.SetLogLevel(LogLevelDebug)
+
+	ll.SetMetadata("user", "u1")
+	ll.Info("hello")
+	ll.Trace("not logged")
+	ll.Derive().Warn("from the child")
+	ll.RemoveRecordSink(sink)
+	ll.Error("after removal")
+
+	if lines := strings.Split(strings.TrimSpace(output.String()), "\n"); len(lines) != 3 || !strings.HasSuffix(lines[0], "INFO {0000000001} hello") {
+		t.Errorf("unexpected text output %q", output.String())
+	}
+	expected := `{"time":"2024-03-04T05:06:07Z","level":"INFO","laneId":"0000000001","message":"hello","fields":{"user":"u1"}}` + "\n" +
+		`{"time":"2024-03-04T05:06:07Z","level":"WARN","laneId":"0000000002","message":"from the child"}` + "\n"
+	if jsonOut.String() != expected {
+		t.Errorf("unexpected json output %s", jsonOut.String())
+	}
+}
+
+func TestLogLaneRecordSinkStack(t *testing.T) {
+	for _, enc := range []Encoder{nil, JSONEncoder{}} {
+		var output bytes.Buffer
+		log.SetOutput(&output)
+
+		ll := NewLogLane(nil, WithEncoder(enc)).(LogLane)
+		sink := &testRecordSink{}
+		ll.AddRecordSink(sink)
+		ll.EnableStackTrace(LogLevelError, true)
+		ll.Error("failed")
+
+		if len(sink.records) != 2 || sink.records[0].Message != "failed" || sink.records[1].Level != LogLevelStack || len(sink.records[1].Stack) == 0 {
+			t.Errorf("%T: unexpected records %+v", enc, sink.records)
+		}
+		if output.Len() == 0 {
+			t.Errorf("%T: the lane's output is missing", enc)
+		}
+	}
+	log.SetOutput(os.Stderr)
+}
@@ -0,0 +1,7 @@
+//go:build lane_release
+
+package lane
+
+// Set when the package is built with the lane_release tag, which strips
+// trace and debug messages from log lanes and null lanes
+const ReleaseBuild = true
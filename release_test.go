@@ -0,0 +1,23 @@
+//go:build lane_release
+
+package lane
+
+import "testing"
+
+func TestReleaseStripsVerbose(t *testing.T) {
+	for _, l := range []Lane{NewLogLane(nil), NewNullLane(nil)} {
+		tl := NewTestingLane(nil)
+		l.AddTee(tl)
+
+		l.Trace("stripped")
+		l.Debugf("stripped %d", 1)
+		l.Info("kept")
+
+		if !tl.VerifyEventText("INFO\tkept") {
+			t.Errorf("%T: unexpected events:\n%s", l, tl.EventsToString())
+		}
+		if IsLevelEnabled(l, LogLevelDebug) {
+			t.Errorf("%T: debug is enabled", l)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package lane
+
+import (
+	"time"
+)
+
+type (
+	// Settings for Retry()
+	RetryPolicy struct {
+		Backoff
+		Operation string // names the operation in the log messages, defaults to "operation"
+	}
+)
+
+// Calls [fn] until it succeeds, following the [policy] backoff, with the
+// lane's context ending the retries. Each retry is logged to [l] at DEBUG
+// with the delay before it, and the final failure is logged at ERROR with the
+// number of attempts and the time spent. The [attempt] passed to [fn] counts
+// from 1. Returns the last error of [fn], joined with the context error if
+// the lane's context ended the retries.
+func Retry(l Lane, policy RetryPolicy, fn func(attempt int, l Lane) error) error {
+	operation := policy.Operation
+	if operation == "" {
+		operation = "operation"
+	}
+
+	start := time.Now()
+	attempts := 0
+	err := policy.Backoff.retry(l, func(attempt int) error {
+		attempts = attempt
+		return fn(attempt, l)
+	}, func(attempt int, err error, delay time.Duration) {
+		l.Debugf("%s attempt %d failed: %v; retrying in %v", operation, attempt, err, delay)
+	})
+
+	if err != nil {
+		l.Errorf("%s failed after %d attempts in %v: %v", operation, attempts, time.Since(start).Round(time.Millisecond), err)
+	}
+	return err
+}
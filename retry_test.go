@@ -0,0 +1,68 @@
+package lane
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryLogging(t *testing.T) {
+	tl := NewTestingLane(nil)
+	errFail := errors.New("unavailable")
+	policy := RetryPolicy{Backoff: Backoff{Initial: time.Millisecond, Jitter: -1, MaxAttempts: 3}, Operation: "fetch"}
+
+	err := Retry(tl, policy, func(attempt int, l Lane) error {
+		if l != tl {
+			t.Error("expected the lane passed to Retry")
+		}
+		return errFail
+	})
+	if !errors.Is(err, errFail) {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	events := tl.Events()
+	if len(events) != 3 {
+		t.Fatalf("unexpected events:\n%s", tl.EventsToString())
+	}
+	if events[0].Level != "DEBUG" || events[0].Message != "fetch attempt 1 failed: unavailable; retrying in 1ms" {
+		t.Errorf("unexpected retry event %v", events[0])
+	}
+	if events[1].Message != "fetch attempt 2 failed: unavailable; retrying in 2ms" {
+		t.Errorf("unexpected retry event %v", events[1])
+	}
+	if events[2].Level != "ERROR" || !strings.HasPrefix(events[2].Message, "fetch failed after 3 attempts in ") {
+		t.Errorf("unexpected failure event %v", events[2])
+	}
+}
+
+func TestRetrySuccess(t *testing.T) {
+	tl := NewTestingLane(nil)
+	err := Retry(tl, RetryPolicy{Backoff: Backoff{Initial: time.Millisecond}}, func(attempt int, l Lane) error {
+		if attempt == 1 {
+			return errors.New("first try")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if events := tl.Events(); len(events) != 1 || !strings.HasPrefix(events[0].Message, "operation attempt 1 failed: first try") {
+		t.Errorf("unexpected events:\n%s", tl.EventsToString())
+	}
+}
+
+func TestRetryCanceled(t *testing.T) {
+	tl := NewTestingLane(nil)
+	l, cancel := tl.DeriveWithTimeout(20 * time.Millisecond)
+	defer cancel()
+
+	err := Retry(l, RetryPolicy{Backoff: Backoff{Initial: time.Hour}}, func(attempt int, l Lane) error {
+		return errors.New("down")
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,228 @@
+package lane
+
+import (
+	"io"
+	"log"
+	"sync"
+)
+
+type (
+	// A lane that retains its most recent log events in memory instead of
+	// writing them out. Lanes derived from it share the same buffer.
+	RingBufferLane interface {
+		Lane
+
+		// Provides a copy of the retained events, oldest first.
+		Events() []RingEvent
+
+		// Logs the retained events to another lane, oldest first, keeping their
+		// original lane and journey IDs. The events remain in the buffer.
+		Replay(to Lane)
+
+		// Discards the retained events.
+		Clear()
+
+		// Installs a policy that flushes retained events to a target lane when a
+		// severe message is logged, or removes the policy if nil.
+		SetEscalationPolicy(policy *EscalationPolicy)
+	}
+
+	// Flushes the detail that preceded a severe message. When a message at
+	// [Trigger] or above is received, the retained events below [Below] that
+	// were logged by the same lane are removed from the buffer and delivered
+	// to [Target] at their original level and time, regardless of the level
+	// of [Target]. The triggering message itself is not sent.
+	EscalationPolicy struct {
+		Target   Lane
+		Trigger  LaneLogLevel
+		Below    LaneLogLevel
+		AllLanes bool // flush the events of every lane, not only the triggering lane
+	}
+
+	// A log event retained by a ring buffer lane
+	RingEvent = Record
+
+	ringBufferLane struct {
+		LogLane
+		ring *eventRing
+	}
+
+	// Fixed capacity event storage, shared by a ring buffer lane and its derivations
+	eventRing struct {
+		mu     sync.Mutex
+		events []RingEvent
+		next   int
+		full   bool
+		policy *EscalationPolicy
+	}
+)
+
+// Makes a lane that keeps the last [capacity] events. Attach it as a tee at
+// LogLevelTrace to have full detail available when something goes wrong.
+func NewRingBufferLane(ctx OptionalContext, capacity int, opts ...LaneOption) RingBufferLane {
+	if capacity < 1 {
+		panic("ring buffer capacity must be at least 1")
+	}
+	ring := &eventRing{events: make([]RingEvent, capacity)}
+
+	createFn := func(parentLane Lane) (newLane Lane, ll LogLane, writer *log.Logger, err error) {
+		newLane, ll, writer = createRingBufferLane(ring)
+		return
+	}
+
+	l, _ := NewEmbeddedLogLane(createFn, ctx, opts...)
+	return l.(RingBufferLane)
+}
+
+// Makes the policy that sends retained TRACE and DEBUG events to [target]
+// when an ERROR or more severe message is logged.
+func NewEscalationPolicy(target Lane) *EscalationPolicy {
+	return &EscalationPolicy{
+		Target:  target,
+		Trigger: LogLevelError,
+		Below:   LogLevelInfo,
+	}
+}
+
+func createRingBufferLane(ring *eventRing) (newLane Lane, ll LogLane, writer *log.Logger) {
+	rbl := ringBufferLane{ring: ring}
+	ll = AllocEmbeddedLogLane()
+	rbl.LogLane = ll
+	newLane = &rbl
+	writer = log.New(io.Discard, "", 0)
+	return
+}
+
+func (rbl *ringBufferLane) receiveRecord(rec *Record) {
+	policy := rbl.ring.add(*rec)
+
+	if policy != nil && rec.Level >= policy.Trigger && rec.Level != LogLevelStack {
+		escalated := rbl.ring.take(func(e *RingEvent) bool {
+			return e.Level < policy.Below && (policy.AllLanes || e.LaneId == rec.LaneId)
+		})
+		if len(escalated) > 0 && policy.Target != nil {
+			// the detail is delivered even though the target's level would
+			// filter it, as it was removed from the ring
+			li := policy.Target.(laneInternal)
+			for i := range escalated {
+				li.replayRecord(&escalated[i])
+			}
+		}
+	}
+}
+
+func (rbl *ringBufferLane) Events() []RingEvent {
+	return rbl.ring.snapshot()
+}
+
+func (rbl *ringBufferLane) Replay(to Lane) {
+	replayEvents(rbl.ring.snapshot(), to)
+}
+
+func (rbl *ringBufferLane) Clear() {
+	rbl.ring.clear()
+}
+
+func (rbl *ringBufferLane) SetEscalationPolicy(policy *EscalationPolicy) {
+	var p *EscalationPolicy
+	if policy != nil {
+		copied := *policy
+		p = &copied
+	}
+
+	rbl.ring.mu.Lock()
+	defer rbl.ring.mu.Unlock()
+	rbl.ring.policy = p
+}
+
+func (rbl *ringBufferLane) lengthConstraint(level LaneLogLevel) int {
+	if lc, ok := rbl.LogLane.(lengthConstrainer); ok {
+		return lc.lengthConstraint(level)
+	}
+	return 0
+}
+
+func (rbl *ringBufferLane) treeInfo() laneTreeInfo {
+	if tr, ok := rbl.LogLane.(treeReporter); ok {
+		return tr.treeInfo()
+	}
+	return laneTreeInfo{}
+}
+
+// Sends events to a lane as if they were logged by their original lanes.
+// A stack trace is sent at LogLevelTrace, its message followed by its lines.
+func replayEvents(events []RingEvent, to Lane) {
+	li := to.(laneInternal)
+	for _, e := range events {
+		props := e.props()
+		if e.Level != LogLevelStack {
+			logTextInternal(props, li, e.Level, e.Message)
+			continue
+		}
+		if e.Message != "" {
+			logTextInternal(props, li, LogLevelTrace, e.Message)
+		}
+		for _, line := range e.Stack {
+			logTextInternal(props, li, LogLevelTrace, line)
+		}
+	}
+}
+
+// Stores an event, returning the escalation policy in effect
+func (er *eventRing) add(e RingEvent) (policy *EscalationPolicy) {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+
+	er.events[er.next] = e
+	er.next++
+	if er.next == len(er.events) {
+		er.next = 0
+		er.full = true
+	}
+	return er.policy
+}
+
+func (er *eventRing) snapshot() []RingEvent {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+	return er.ordered()
+}
+
+// Provides the retained events oldest first; the caller must hold the lock
+func (er *eventRing) ordered() []RingEvent {
+	if !er.full {
+		return append([]RingEvent{}, er.events[:er.next]...)
+	}
+	return append(append([]RingEvent{}, er.events[er.next:]...), er.events[:er.next]...)
+}
+
+// Removes and returns the events selected by [match], oldest first
+func (er *eventRing) take(match func(e *RingEvent) bool) (taken []RingEvent) {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+
+	var kept []RingEvent
+	for _, e := range er.ordered() {
+		if match(&e) {
+			taken = append(taken, e)
+		} else {
+			kept = append(kept, e)
+		}
+	}
+
+	if len(taken) > 0 {
+		clear(er.events)
+		er.next = copy(er.events, kept)
+		er.full = false
+	}
+	return
+}
+
+func (er *eventRing) clear() {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+
+	clear(er.events)
+	er.next = 0
+	er.full = false
+}
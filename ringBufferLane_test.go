@@ -0,0 +1,196 @@
+package lane
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRingBufferLaneRetainsLast(t *testing.T) {
+	skipInRelease(t)
+
+	rbl := NewRingBufferLane(nil, 3)
+
+	rbl.Trace("one")
+	rbl.Debugf("%s", "two")
+	rbl.Info("three")
+	rbl.WarnObject("four", map[string]int{"a": 1})
+
+	events := rbl.Events()
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(events))
+	}
+	if events[0].Message != "two" || events[0].Level != LogLevelDebug ||
+		events[1].Message != "three" || events[1].Level != LogLevelInfo ||
+		events[2].Message != `four: {"a":1}` || events[2].Level != LogLevelWarn {
+		t.Errorf("unexpected events %v", events)
+	}
+	for _, e := range events {
+		if e.LaneId != rbl.LaneId() {
+			t.Errorf("wrong lane id %s", e.LaneId)
+		}
+	}
+
+	rbl.Clear()
+	if len(rbl.Events()) != 0 {
+		t.Error("expected empty buffer")
+	}
+}
+
+func TestRingBufferLaneTee(t *testing.T) {
+	skipInRelease(t)
+
+	clock := NewFixedClock(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	ll := NewLogLane(nil)
+	ll.SetLogLevel(LogLevelError)
+	rbl := NewRingBufferLane(nil, 10, WithClock(clock))
+	ll.AddTee(rbl)
+
+	child := ll.Derive()
+	child.SetJourneyId("journey")
+	child.Trace("detail")
+	ll.Error("failure")
+
+	events := rbl.Events()
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	if events[0].LaneId != child.LaneId() || events[0].JourneyId != "journey" || events[0].Message != "detail" {
+		t.Errorf("unexpected event %v", events[0])
+	}
+	if events[1].LaneId != ll.LaneId() || !events[1].Time.Equal(clock.Now()) {
+		t.Errorf("unexpected event %v", events[1])
+	}
+}
+
+func TestRingBufferLaneReplay(t *testing.T) {
+	rbl := NewRingBufferLane(nil, 10)
+	child := rbl.Derive()
+	rbl.Info("first")
+	child.Errorf("second %d", 2)
+	rbl.LogStack("where")
+
+	tl := NewTestingLane(nil)
+	rbl.Replay(tl)
+
+	// the stack lines follow the stack message
+	events := tl.EventsToString()
+	if !strings.HasPrefix(events, "INFO\tfirst\nERROR\tsecond 2\nTRACE\twhere\nTRACE\t") {
+		t.Errorf("unexpected replay:\n%s", events)
+	}
+
+	ptl := tl.(*testingLane)
+	if ptl.Events()[1].Id != child.LaneId() {
+		t.Error("expected original lane id")
+	}
+
+	if len(rbl.Events()) == 0 {
+		t.Error("replay should not remove events")
+	}
+}
+
+func TestRingBufferLaneEscalation(t *testing.T) {
+	skipInRelease(t)
+
+	target := NewTestingLane(nil)
+
+	rbl := NewRingBufferLane(nil, 10)
+	rbl.SetEscalationPolicy(NewEscalationPolicy(target))
+
+	other := rbl.Derive()
+	rbl.Trace("step 1")
+	other.Debug("other step")
+	rbl.Debug("step 2")
+	rbl.Info("progress")
+	rbl.Error("failed")
+
+	if !target.VerifyEventText("TRACE\tstep 1\nDEBUG\tstep 2") {
+		t.Errorf("unexpected escalation:\n%s", target.EventsToString())
+	}
+
+	// flushed events are removed from the buffer
+	events := rbl.Events()
+	if len(events) != 3 || events[0].Message != "other step" || events[1].Message != "progress" || events[2].Message != "failed" {
+		t.Errorf("unexpected remaining events %v", events)
+	}
+
+	// nothing more to flush for this lane
+	rbl.Error("failed again")
+	if len(target.Events()) != 2 {
+		t.Error("unexpected second flush")
+	}
+
+	rbl.SetEscalationPolicy(&EscalationPolicy{Target: target, Trigger: LogLevelWarn, Below: LogLevelWarn, AllLanes: true})
+	rbl.Warn("warning")
+	if !target.VerifyEventText("TRACE\tstep 1\nDEBUG\tstep 2\nDEBUG\tother step\nINFO\tprogress") {
+		t.Errorf("unexpected escalation:\n%s", target.EventsToString())
+	}
+
+	rbl.SetEscalationPolicy(nil)
+	rbl.Trace("quiet")
+	rbl.Error("no policy")
+	if len(target.Events()) != 4 {
+		t.Error("unexpected flush without a policy")
+	}
+}
+
+func TestRingBufferLaneEscalationLevel(t *testing.T) {
+	skipInRelease(t)
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	target := NewLogLane(nil)
+	target.SetLogLevel(LogLevelInfo)
+	var rec testRecordSink
+	target.(LogLane).AddRecordSink(&rec)
+
+	clock := NewFixedClock(time.Date(2024, 4, 9, 11, 37, 37, 0, time.Local))
+	rbl := NewRingBufferLane(nil, 10, WithClock(clock))
+	rbl.SetEscalationPolicy(NewEscalationPolicy(target))
+
+	rbl.Debug("detail")
+	clock.Advance(time.Hour)
+	rbl.Error("failed")
+
+	if buf.String() != "2024/04/09 11:37:37 DEBUG {"+trimLaneId(rbl.LaneId())+"} detail\n" {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+	if len(rec.records) != 1 || rec.records[0].Level != LogLevelDebug || !rec.records[0].Time.Equal(time.Date(2024, 4, 9, 11, 37, 37, 0, time.Local)) {
+		t.Errorf("unexpected records %v", rec.records)
+	}
+	if len(rbl.Events()) != 1 {
+		t.Errorf("unexpected remaining events %v", rbl.Events())
+	}
+}
+
+func TestRingBufferLaneEscalationTee(t *testing.T) {
+	skipInRelease(t)
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer func() { log.SetOutput(os.Stderr) }()
+
+	disk := NewTestingLane(nil)
+
+	ll := NewLogLane(nil)
+	ll.SetLogLevel(LogLevelInfo)
+
+	rbl := NewRingBufferLane(nil, 100)
+	rbl.SetEscalationPolicy(NewEscalationPolicy(disk))
+	ll.AddTee(rbl)
+
+	ll.Trace("detail")
+	ll.Error("boom")
+
+	if !disk.VerifyEventText("TRACE\tdetail") {
+		t.Errorf("unexpected escalation:\n%s", disk.EventsToString())
+	}
+	if strings.Contains(buf.String(), "detail") {
+		t.Error("trace should not reach the log lane output")
+	}
+}
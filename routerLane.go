@@ -0,0 +1,106 @@
+package lane
+
+import (
+	"fmt"
+	"io"
+	"log"
+)
+
+type (
+	// A rule of a router lane, sending the messages logged with all of the
+	// metadata values of [Match], such as {"tenant": "acme"}, to [Target]. A
+	// rule without Match values matches every message.
+	RouteRule struct {
+		Match  map[string]string
+		Target Lane
+	}
+
+	routerLane struct {
+		LogLane
+		rules    []RouteRule
+		fallback Lane
+	}
+)
+
+// Makes a lane that sends each message to the targets of the [rules] that
+// match the lane's metadata, so that a single root lane can fan messages out
+// to per-tenant disk files or per-subsystem indices. A message matching no
+// rule is sent to [fallback], or dropped if [fallback] is nil.
+//
+// The targets receive the messages with the router lane's IDs, as a tee
+// would. A stack trace is passed on at LogLevelTrace, one message per line.
+// The tees of the router lane receive all messages. Lanes derived from the
+// router lane share its rules and targets, and usually set the metadata that
+// selects them:
+//
+//	tl := router.Derive()
+//	tl.SetMetadata("tenant", "acme")
+//
+// The targets aren't closed with the router lane.
+func NewRouterLane(ctx OptionalContext, rules []RouteRule, fallback Lane, opts ...LaneOption) Lane {
+	for _, r := range rules {
+		if _, ok := r.Target.(laneInternal); !ok {
+			panic(fmt.Sprintf("router target %T is not a lane of this package", r.Target))
+		}
+	}
+	if fallback != nil {
+		if _, ok := fallback.(laneInternal); !ok {
+			panic(fmt.Sprintf("router fallback %T is not a lane of this package", fallback))
+		}
+	}
+	rules = append([]RouteRule{}, rules...)
+
+	createFn := func(parentLane Lane) (newLane Lane, ll LogLane, writer *log.Logger, err error) {
+		newLane, ll, writer = createRouterLane(rules, fallback)
+		return
+	}
+
+	l, _ := NewEmbeddedLogLane(createFn, ctx, opts...)
+	return l
+}
+
+func createRouterLane(rules []RouteRule, fallback Lane) (newLane Lane, ll LogLane, writer *log.Logger) {
+	rl := routerLane{rules: rules, fallback: fallback}
+	ll = AllocEmbeddedLogLane()
+	rl.LogLane = ll
+	newLane = &rl
+	writer = log.New(io.Discard, "", 0)
+	return
+}
+
+func (rl *routerLane) receiveRecord(rec *Record) {
+	routed := false
+	for _, r := range rl.rules {
+		if r.matches(rec.Fields) {
+			replayEvents([]RingEvent{*rec}, r.Target)
+			routed = true
+		}
+	}
+	if !routed && rl.fallback != nil {
+		replayEvents([]RingEvent{*rec}, rl.fallback)
+	}
+}
+
+// Checks if the metadata [fields] have all of the rule's values
+func (r *RouteRule) matches(fields map[string]string) bool {
+	for key, val := range r.Match {
+		if v, found := fields[key]; !found || v != val {
+			return false
+		}
+	}
+	return true
+}
+
+func (rl *routerLane) lengthConstraint(level LaneLogLevel) int {
+	if lc, ok := rl.LogLane.(lengthConstrainer); ok {
+		return lc.lengthConstraint(level)
+	}
+	return 0
+}
+
+func (rl *routerLane) treeInfo() laneTreeInfo {
+	if tr, ok := rl.LogLane.(treeReporter); ok {
+		return tr.treeInfo()
+	}
+	return laneTreeInfo{}
+}
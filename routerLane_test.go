@@ -0,0 +1,74 @@
+package lane
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRouterLane(t *testing.T) {
+	acme := NewTestingLane(nil)
+	globex := NewTestingLane(nil)
+	billing := NewTestingLane(nil)
+	other := NewTestingLane(nil)
+	tee := NewTestingLane(nil)
+
+	router := NewRouterLane(nil, []RouteRule{
+		{Match: map[string]string{"tenant": "acme"}, Target: acme},
+		{Match: map[string]string{"tenant": "globex"}, Target: globex},
+		{Match: map[string]string{"subsystem": "billing"}, Target: billing},
+	}, other)
+	router.AddTee(tee)
+
+	router.Info("no tenant")
+
+	al := router.Derive()
+	al.SetMetadata("tenant", "acme")
+	al.Warn("acme warning")
+
+	gl := router.Derive()
+	gl.SetMetadata("tenant", "globex")
+	gl.SetMetadata("subsystem", "billing")
+	gl.Errorf("globex %s", "invoice")
+
+	expected := []struct {
+		tl   TestingLane
+		text string
+	}{
+		{acme, "WARN\tacme warning"},
+		{globex, "ERROR\tglobex invoice"},
+		{billing, "ERROR\tglobex invoice"},
+		{other, "INFO\tno tenant"},
+		{tee, "INFO\tno tenant\nWARN\tacme warning\nERROR\tglobex invoice"},
+	}
+	for i, e := range expected {
+		if !e.tl.VerifyEventText(e.text) {
+			t.Errorf("target %d events:\n%s", i, e.tl.EventsToString())
+		}
+	}
+	if events := acme.Events(); len(events) != 1 || events[0].Id != al.LaneId() {
+		t.Error("expected the router lane's ID")
+	}
+}
+
+func TestRouterLaneDrops(t *testing.T) {
+	tl := NewTestingLane(nil)
+	router := NewRouterLane(nil, []RouteRule{{Match: map[string]string{"tenant": "acme"}, Target: tl}}, nil)
+
+	router.Info("unrouted")
+	router.SetMetadata("tenant", "acme")
+	router.LogStack("trace")
+
+	events := tl.Events()
+	if len(events) < 2 || events[0].Message != "trace" || !strings.Contains(tl.EventsToString(), "TestRouterLaneDrops") {
+		t.Errorf("unexpected events:\n%s", tl.EventsToString())
+	}
+}
+
+func TestRouterLaneTarget(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	NewRouterLane(nil, []RouteRule{{Target: nil}}, nil)
+}
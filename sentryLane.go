@@ -0,0 +1,454 @@
+package lane
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type (
+	// Settings for a lane that reports errors to a Sentry-compatible service
+	SentryOptions struct {
+		Environment    string
+		Release        string
+		ServerName     string
+		MaxBreadcrumbs int           // recent lower level messages sent with an error, defaults to 20
+		QueueSize      int           // errors held while sending, defaults to 100
+		Timeout        time.Duration // per request, defaults to 10 seconds
+		Client         *http.Client  // defaults to a client with the timeout
+		Spool          *SpoolConfig  // holds undeliverable reports on disk for replay, when set
+	}
+
+	sentryLane struct {
+		LogLane
+		reporter *sentryReporter
+		owner    bool
+	}
+
+	// Delivers events in the background, shared by a sentry lane and its derivations
+	sentryReporter struct {
+		opt         SentryOptions
+		endpoint    string
+		auth        string
+		events      chan []byte
+		done        chan struct{}
+		mu          sync.RWMutex
+		closed      bool
+		crumbMu     sync.Mutex
+		breadcrumbs []sentryBreadcrumb
+		spool       *diskSpool
+		counters    laneCounters
+	}
+
+	sentryBreadcrumb struct {
+		Timestamp float64 `json:"timestamp"`
+		Level     string  `json:"level"`
+		Message   string  `json:"message"`
+		laneId    string
+	}
+
+	sentryFrame struct {
+		Function string `json:"function"`
+		Module   string `json:"module,omitempty"`
+		Filename string `json:"filename"`
+		AbsPath  string `json:"abs_path"`
+		Lineno   int    `json:"lineno"`
+		InApp    bool   `json:"in_app"`
+	}
+)
+
+// Makes a lane that reports ERROR and FATAL messages to the Sentry project
+// identified by [dsn], such as "https://key@o0.ingest.sentry.io/123". It is
+// meant to be attached as a tee. Each report includes the stack of the
+// logging call, the lane metadata as tags, and the lane's recent INFO and WARN
+// messages as breadcrumbs.
+func NewSentryLane(ctx OptionalContext, dsn string, opt SentryOptions, opts ...LaneOption) (l Lane, err error) {
+	reporter, err := newSentryReporter(dsn, opt)
+	if err != nil {
+		return
+	}
+
+	createFn := func(parentLane Lane) (newLane Lane, ll LogLane, writer *log.Logger, err error) {
+		newLane, ll, writer = createSentryLane(reporter)
+		return
+	}
+
+	if l, err = NewEmbeddedLogLane(createFn, ctx, opts...); err != nil {
+		return
+	}
+	l.(*sentryLane).owner = true
+	l.SetLogLevel(LogLevelInfo)
+	return
+}
+
+func createSentryLane(reporter *sentryReporter) (newLane Lane, ll LogLane, writer *log.Logger) {
+	sl := sentryLane{reporter: reporter}
+	ll = AllocEmbeddedLogLane()
+	sl.LogLane = ll
+	newLane = &sl
+	writer = log.New(io.Discard, "", 0)
+	return
+}
+
+func (sl *sentryLane) receiveRecord(rec *Record) {
+	switch rec.Level {
+	case LogLevelError, LogLevelFatal, logLevelPreFatal:
+		props := rec.props()
+		event := sl.reporter.makeEvent(rec.Time, props, rec.Level, rec.Message, rec.Fields, sentryStack())
+		if rec.Level == LogLevelError {
+			sl.reporter.enqueue(event)
+		} else {
+			// the process is about to end
+			sl.reporter.deliver(event)
+		}
+	case LogLevelStack:
+		// the stack is captured with the error
+	default:
+		sl.reporter.addBreadcrumb(rec.Time, rec.LaneId, rec.Level, rec.Message)
+	}
+}
+
+// Provides the lane's operational counters, including those of the reporter
+func (sl *sentryLane) Stats() (stats LaneStats) {
+	stats = sl.LogLane.Stats()
+	stats.addDelivery(&sl.reporter.counters)
+	if sl.reporter.spool != nil {
+		stats.Spool = sl.reporter.spool.stats()
+	}
+	return
+}
+
+// Flushes the queued reports, if this lane started the reporter. Returns the
+// context error if [ctx] is done before the reports are sent.
+func (sl *sentryLane) CloseWithContext(ctx context.Context) (err error) {
+	err = sl.LogLane.CloseWithContext(ctx)
+	if sl.owner {
+		err = errors.Join(err, sl.reporter.close(ctx))
+	}
+	return
+}
+
+func (sl *sentryLane) lengthConstraint(level LaneLogLevel) int {
+	if lc, ok := sl.LogLane.(lengthConstrainer); ok {
+		return lc.lengthConstraint(level)
+	}
+	return 0
+}
+
+func (sl *sentryLane) treeInfo() laneTreeInfo {
+	if tr, ok := sl.LogLane.(treeReporter); ok {
+		return tr.treeInfo()
+	}
+	return laneTreeInfo{}
+}
+
+func newSentryReporter(dsn string, opt SentryOptions) (sr *sentryReporter, err error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return
+	}
+	if u.User == nil || u.User.Username() == "" || u.Host == "" {
+		err = fmt.Errorf("invalid sentry dsn %q", dsn)
+		return
+	}
+
+	projectPath, projectId := path.Split(strings.TrimSuffix(u.Path, "/"))
+	if projectId == "" {
+		err = fmt.Errorf("sentry dsn %q has no project id", dsn)
+		return
+	}
+
+	if opt.MaxBreadcrumbs <= 0 {
+		opt.MaxBreadcrumbs = 20
+	}
+	if opt.QueueSize <= 0 {
+		opt.QueueSize = 100
+	}
+	if opt.Timeout <= 0 {
+		opt.Timeout = 10 * time.Second
+	}
+	if opt.Client == nil {
+		opt.Client = &http.Client{Timeout: opt.Timeout}
+	}
+
+	sr = &sentryReporter{
+		opt:      opt,
+		endpoint: fmt.Sprintf("%s://%s%sapi/%s/envelope/", u.Scheme, u.Host, projectPath, projectId),
+		auth:     fmt.Sprintf("Sentry sentry_version=7, sentry_client=go-lane/1.0, sentry_key=%s", u.User.Username()),
+		events:   make(chan []byte, opt.QueueSize),
+		done:     make(chan struct{}),
+	}
+	if opt.Spool != nil {
+		sr.spool = newDiskSpool(*opt.Spool)
+	}
+	go sr.run()
+	return
+}
+
+func (sr *sentryReporter) addBreadcrumb(t time.Time, laneId string, level LaneLogLevel, text string) {
+	sr.crumbMu.Lock()
+	defer sr.crumbMu.Unlock()
+
+	if len(sr.breadcrumbs) >= sr.opt.MaxBreadcrumbs {
+		sr.breadcrumbs = append(sr.breadcrumbs[:0], sr.breadcrumbs[1:]...)
+	}
+	sr.breadcrumbs = append(sr.breadcrumbs, sentryBreadcrumb{
+		Timestamp: sentryTimestamp(t),
+		Level:     sentryLevel(level),
+		Message:   text,
+		laneId:    laneId,
+	})
+}
+
+// Provides the breadcrumbs logged by the lane
+func (sr *sentryReporter) laneBreadcrumbs(laneId string) (crumbs []sentryBreadcrumb) {
+	sr.crumbMu.Lock()
+	defer sr.crumbMu.Unlock()
+
+	for _, crumb := range sr.breadcrumbs {
+		if crumb.laneId == laneId {
+			crumbs = append(crumbs, crumb)
+		}
+	}
+	return
+}
+
+// Makes the envelope holding one event
+func (sr *sentryReporter) makeEvent(t time.Time, props loggingProperties, level LaneLogLevel, text string, metadata map[string]string, frames []sentryFrame) []byte {
+	eventId := strings.ReplaceAll(uuid.New().String(), "-", "")
+
+	tags := map[string]string{"lane_id": props.laneId}
+	if props.journeyId != "" {
+		tags["journey_id"] = props.journeyId
+	}
+	if props.category != "" {
+		tags["category"] = props.category
+	}
+	if props.parentId != "" {
+		tags["parent_lane_id"] = props.parentId
+	}
+	for k, v := range metadata {
+		tags[k] = v
+	}
+
+	event := map[string]any{
+		"event_id":  eventId,
+		"timestamp": sentryTimestamp(t),
+		"level":     sentryLevel(level),
+		"platform":  "go",
+		"logger":    "lane",
+		"message":   map[string]any{"formatted": text},
+		"tags":      tags,
+	}
+	if sr.opt.Environment != "" {
+		event["environment"] = sr.opt.Environment
+	}
+	if sr.opt.Release != "" {
+		event["release"] = sr.opt.Release
+	}
+	if sr.opt.ServerName != "" {
+		event["server_name"] = sr.opt.ServerName
+	}
+	if crumbs := sr.laneBreadcrumbs(props.laneId); len(crumbs) > 0 {
+		event["breadcrumbs"] = map[string]any{"values": crumbs}
+	}
+	if len(frames) > 0 {
+		event["threads"] = map[string]any{
+			"values": []any{map[string]any{
+				"current":    true,
+				"crashed":    level != LogLevelError,
+				"stacktrace": map[string]any{"frames": frames},
+			}},
+		}
+	}
+
+	header, _ := json.Marshal(map[string]any{"event_id": eventId, "sent_at": t.UTC().Format(time.RFC3339Nano)})
+	body, _ := json.Marshal(event)
+
+	var envelope bytes.Buffer
+	envelope.Write(header)
+	envelope.WriteString("\n{\"type\":\"event\"}\n")
+	envelope.Write(body)
+	envelope.WriteString("\n")
+	return envelope.Bytes()
+}
+
+func (sr *sentryReporter) enqueue(envelope []byte) {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+
+	if sr.closed {
+		return
+	}
+	select {
+	case sr.events <- envelope:
+	default:
+		// the queue is full; logging must not block
+		sr.counters.dropped.Add(1)
+	}
+}
+
+func (sr *sentryReporter) close(ctx context.Context) error {
+	sr.mu.Lock()
+	if !sr.closed {
+		sr.closed = true
+		close(sr.events)
+	}
+	sr.mu.Unlock()
+
+	select {
+	case <-sr.done:
+		return nil
+	case <-ctx.Done():
+		// the reporter finishes in the background
+		return ctx.Err()
+	}
+}
+
+// Worker that sends the queued events until the queue is closed
+func (sr *sentryReporter) run() {
+	defer close(sr.done)
+
+	var retry <-chan time.Time
+	if sr.spool != nil {
+		defer sr.spool.close()
+		ticker := time.NewTicker(sr.spool.cfg.RetryInterval)
+		defer ticker.Stop()
+		retry = ticker.C
+	}
+
+	for {
+		select {
+		case envelope, more := <-sr.events:
+			if !more {
+				if sr.spool != nil {
+					// what can't be delivered now stays on disk for the next run
+					sr.replaySpool()
+				}
+				return
+			}
+			if sr.spool != nil && sr.spool.pending() {
+				sr.replaySpool()
+			}
+			sr.deliver(envelope)
+		case <-retry:
+			sr.replaySpool()
+		}
+	}
+}
+
+// Sends an envelope, spooling it if it can't be sent
+func (sr *sentryReporter) deliver(envelope []byte) {
+	if err := sr.send(envelope); err != nil {
+		sr.counters.setError(err)
+		if sr.spool != nil {
+			sr.spool.append([][]byte{envelope})
+		} else {
+			sr.counters.dropped.Add(1)
+		}
+	}
+}
+
+func (sr *sentryReporter) replaySpool() error {
+	return sr.spool.replay(1, 0, func(envelopes [][]byte) error {
+		return sr.send(envelopes[0])
+	})
+}
+
+func (sr *sentryReporter) send(envelope []byte) error {
+	req, err := http.NewRequest(http.MethodPost, sr.endpoint, bytes.NewReader(envelope))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", sr.auth)
+
+	resp, err := sr.opt.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("sentry responded %s", resp.Status)
+	}
+	sr.counters.bytes.Add(int64(len(envelope)))
+	return nil
+}
+
+// Captures the caller's stack, oldest frame first, omitting the lane package
+func sentryStack() (frames []sentryFrame) {
+	pcs := make([]uintptr, 64)
+	pcs = pcs[:runtime.Callers(2, pcs)]
+	if origin := findTeeOrigin(); origin != nil {
+		// a tee on a worker goroutine
+		pcs = origin.pcs
+	}
+	iter := runtime.CallersFrames(pcs)
+	for {
+		frame, more := iter.Next()
+		if !strings.HasPrefix(frame.Function, lanePackagePrefix) || strings.HasSuffix(frame.File, "_test.go") {
+			module, function := splitFunctionName(frame.Function)
+			frames = append(frames, sentryFrame{
+				Function: function,
+				Module:   module,
+				Filename: path.Base(frame.File),
+				AbsPath:  frame.File,
+				Lineno:   frame.Line,
+				InApp:    !strings.HasPrefix(frame.Function, "runtime.") && !strings.HasPrefix(frame.Function, "testing."),
+			})
+		}
+		if !more {
+			break
+		}
+	}
+
+	// sentry lists the outermost frame first
+	for i, j := 0, len(frames)-1; i < j; i, j = i+1, j-1 {
+		frames[i], frames[j] = frames[j], frames[i]
+	}
+	return
+}
+
+// Splits "github.com/a/b.(*T).Fn" into "github.com/a/b" and "(*T).Fn"
+func splitFunctionName(name string) (module, function string) {
+	slash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[slash+1:], ".")
+	if dot < 0 {
+		return "", name
+	}
+	return name[:slash+1+dot], name[slash+2+dot:]
+}
+
+func sentryLevel(level LaneLogLevel) string {
+	switch level {
+	case LogLevelTrace, LogLevelDebug:
+		return "debug"
+	case LogLevelWarn:
+		return "warning"
+	case LogLevelError:
+		return "error"
+	case LogLevelFatal, logLevelPreFatal:
+		return "fatal"
+	default:
+		return "info"
+	}
+}
+
+func sentryTimestamp(t time.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Second)
+}
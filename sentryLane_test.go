@@ -0,0 +1,149 @@
+package lane
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type (
+	// Records the events posted to a fake sentry endpoint
+	testSentryServer struct {
+		*httptest.Server
+		mu     sync.Mutex
+		paths  []string
+		auths  []string
+		events []map[string]any
+	}
+)
+
+func newTestSentryServer() *testSentryServer {
+	tss := &testSentryServer{}
+	tss.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		scanner := bufio.NewScanner(bytes.NewReader(body))
+		scanner.Buffer(nil, 1<<20)
+		var lines []string
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+
+		var event map[string]any
+		if len(lines) == 3 && lines[1] == `{"type":"event"}` {
+			json.Unmarshal([]byte(lines[2]), &event)
+		}
+
+		tss.mu.Lock()
+		tss.paths = append(tss.paths, r.URL.Path)
+		tss.auths = append(tss.auths, r.Header.Get("X-Sentry-Auth"))
+		tss.events = append(tss.events, event)
+		tss.mu.Unlock()
+	}))
+	return tss
+}
+
+func (tss *testSentryServer) dsn() string {
+	return strings.Replace(tss.URL, "http://", "http://publickey@", 1) + "/42"
+}
+
+func TestSentryLaneDsn(t *testing.T) {
+	for _, dsn := range []string{"http://host/42", "http://key@/42", "http://key@host/", "::"} {
+		if _, err := NewSentryLane(nil, dsn, SentryOptions{}); err == nil {
+			t.Errorf("expected error for %s", dsn)
+		}
+	}
+
+	l, err := NewSentryLane(nil, "https://key@example.com/prefix/7", SentryOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if endpoint := l.(*sentryLane).reporter.endpoint; endpoint != "https://example.com/prefix/api/7/envelope/" {
+		t.Errorf("unexpected endpoint %s", endpoint)
+	}
+}
+
+func TestSentryLaneTee(t *testing.T) {
+	tss := newTestSentryServer()
+	defer tss.Close()
+
+	sl, err := NewSentryLane(nil, tss.dsn(), SentryOptions{Environment: "test", Release: "1.2.3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tl := NewTestingLane(nil)
+	tl.AddTee(sl)
+	tl.SetJourneyId("journey")
+	tl.SetMetadata("tenant", "acme")
+
+	tl.Trace("not a breadcrumb")
+	tl.Info("loading")
+	tl.Warn("slow")
+	tl.Errorf("failed %d", 7)
+	sl.Close()
+
+	tss.mu.Lock()
+	defer tss.mu.Unlock()
+
+	if len(tss.events) != 1 {
+		t.Fatalf("expected one event, got %d", len(tss.events))
+	}
+	if tss.paths[0] != "/api/42/envelope/" || !strings.Contains(tss.auths[0], "sentry_key=publickey") {
+		t.Errorf("unexpected request %s %s", tss.paths[0], tss.auths[0])
+	}
+
+	event := tss.events[0]
+	if event["level"] != "error" || event["environment"] != "test" || event["release"] != "1.2.3" {
+		t.Errorf("unexpected event %v", event)
+	}
+	if event["message"].(map[string]any)["formatted"] != "failed 7" {
+		t.Errorf("unexpected message %v", event["message"])
+	}
+
+	tags := event["tags"].(map[string]any)
+	if tags["lane_id"] != tl.LaneId() || tags["journey_id"] != "journey" || tags["tenant"] != "acme" {
+		t.Errorf("unexpected tags %v", tags)
+	}
+
+	crumbs := event["breadcrumbs"].(map[string]any)["values"].([]any)
+	if len(crumbs) != 2 || crumbs[0].(map[string]any)["message"] != "loading" || crumbs[1].(map[string]any)["level"] != "warning" {
+		t.Errorf("unexpected breadcrumbs %v", crumbs)
+	}
+
+	thread := event["threads"].(map[string]any)["values"].([]any)[0].(map[string]any)
+	frames := thread["stacktrace"].(map[string]any)["frames"].([]any)
+	last := frames[len(frames)-1].(map[string]any)
+	if last["function"] != "TestSentryLaneTee" || last["filename"] != "sentryLane_test.go" {
+		t.Errorf("unexpected innermost frame %v", last)
+	}
+}
+
+func TestSentryLaneFatal(t *testing.T) {
+	tss := newTestSentryServer()
+	defer tss.Close()
+
+	sl, err := NewSentryLane(nil, tss.dsn(), SentryOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sl.Close()
+
+	tl := NewTestingLane(nil)
+	tl.AddTee(sl)
+	tl.SetPanicHandler(func() {})
+	tl.Fatal("the end")
+
+	// fatal errors are sent before the logging call returns
+	tss.mu.Lock()
+	defer tss.mu.Unlock()
+	if len(tss.events) != 1 || tss.events[0]["level"] != "fatal" {
+		t.Errorf("unexpected events %v", tss.events)
+	}
+}
@@ -0,0 +1,129 @@
+package lane
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+)
+
+// Logs a diagnostics dump to [root] at INFO each time the process receives
+// one of [sigs], such as syscall.SIGUSR1, as a lightweight alternative to
+// attaching a debugger to a production process. The dump holds the stacks
+// of all goroutines, the lane tree of [root] (see DumpTree()), the stats of
+// each lane in the tree and of their tees, and the contents of the ring
+// buffer lanes among them.
+//
+// Call [stop] to stop handling the signals. Handling also stops when the
+// root lane's context is done.
+func EnableSignalDump(root Lane, sigs ...os.Signal) (stop func()) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ch:
+				logSignalDump(root)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	stop = func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+	context.AfterFunc(root, stop)
+	return
+}
+
+func logSignalDump(root Lane) {
+	lanes := dumpLanes(root)
+
+	var sb strings.Builder
+	sb.WriteString("diagnostics dump\n")
+	fmt.Fprintf(&sb, "\nlane tree:\n%s", DumpTree(root))
+
+	sb.WriteString("\nlane stats:\n")
+	for _, l := range lanes {
+		if sr, ok := l.(LaneStatsReporter); ok {
+			fmt.Fprintf(&sb, "%s %s\n", l.LaneId(), formatStats(sr.Stats()))
+		}
+	}
+
+	enc := TextEncoder{Flags: log.Ldate | log.Ltime | log.Lmicroseconds}
+	var buf []byte
+	for _, l := range lanes {
+		ring, ok := l.(RingBufferLane)
+		if !ok {
+			continue
+		}
+		records := ring.Events()
+		fmt.Fprintf(&sb, "\nring buffer %s (%d):\n", l.LaneId(), len(records))
+		for i := range records {
+			buf = enc.AppendRecord(buf[:0], &records[i])
+			sb.Write(buf)
+		}
+	}
+
+	fmt.Fprintf(&sb, "\ngoroutines:\n%s", allGoroutineStacks())
+	root.Info(sb.String())
+}
+
+// Lists [root], its tracked descendants and the tees of all of them, each
+// lane once, in the order found
+func dumpLanes(root Lane) (lanes []Lane) {
+	seen := map[Lane]bool{}
+	var add func(l Lane)
+	add = func(l Lane) {
+		if seen[l] {
+			return
+		}
+		seen[l] = true
+		lanes = append(lanes, l)
+		for _, tee := range l.Tees() {
+			add(tee)
+		}
+	}
+
+	add(root)
+	for _, l := range root.Descendants() {
+		add(l)
+	}
+	return
+}
+
+// Renders the nonzero counters of [stats] on one line
+func formatStats(stats LaneStats) string {
+	var parts []string
+	for level, n := range stats.Events {
+		if n != 0 {
+			parts = append(parts, fmt.Sprintf("%s=%d", strings.ToLower(levelNames[level]), n))
+		}
+	}
+	if stats.BytesWritten != 0 {
+		parts = append(parts, fmt.Sprintf("bytes=%d", stats.BytesWritten))
+	}
+	if stats.Dropped != 0 {
+		parts = append(parts, fmt.Sprintf("dropped=%d", stats.Dropped))
+	}
+	if stats.TeeFailures != 0 {
+		parts = append(parts, fmt.Sprintf("tee_failures=%d", stats.TeeFailures))
+	}
+	if stats.LastError != nil {
+		parts = append(parts, fmt.Sprintf("last_error=%q", stats.LastError.Error()))
+	}
+	if len(parts) == 0 {
+		return "idle"
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,72 @@
+//go:build linux || darwin || freebsd
+
+package lane
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSignalDump(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	ring := NewRingBufferLane(nil, 10)
+	ll := NewLogLane(nil, WithTee(ring))
+	ll.TrackDescendants(true)
+	tl := NewTestingLane(nil)
+	ll.AddTee(tl)
+
+	ll.Warn("disk slow")
+	child := ll.Derive()
+	child.SetName("worker")
+
+	stop := EnableSignalDump(ll, syscall.SIGUSR1)
+	defer stop()
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(tl.Events()) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("the dump wasn't logged")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	e := tl.Events()[1]
+	if e.Level != "INFO" {
+		t.Errorf("unexpected level %s", e.Level)
+	}
+	for _, expected := range []string{
+		"diagnostics dump\n",
+		"\nlane tree:\n" + ll.LaneId() + " ",
+		"  " + child.LaneId() + ` "worker"`,
+		"\nlane stats:\n" + ll.LaneId() + " warn=1 bytes=",
+		"\nring buffer " + ring.LaneId() + " (1):\n",
+		"} disk slow\n",
+		"\ngoroutines:\ngoroutine ",
+	} {
+		if !strings.Contains(e.Message, expected) {
+			t.Errorf("the dump is missing %q:\n%s", expected, e.Message)
+		}
+	}
+}
+
+func TestSignalDumpStop(t *testing.T) {
+	tl := NewTestingLane(nil)
+	stop := EnableSignalDump(tl, syscall.SIGUSR1)
+	stop()
+	stop()
+
+	l, cancel := tl.DeriveWithCancel()
+	EnableSignalDump(l, syscall.SIGUSR1)
+	cancel()
+}
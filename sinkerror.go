@@ -0,0 +1,60 @@
+package lane
+
+import (
+	"sync/atomic"
+)
+
+type (
+	// Called when a lane fails to write a message to its output
+	ErrorHandler func(err error, record Record)
+
+	// Common implementation of the write error handler, which is inherited
+	// by lanes derived after the handler is set, and of the diagnostic
+	// handler set by WithDiagnostics().
+	errorStore struct {
+		onError      atomic.Pointer[ErrorHandler]
+		onDiagnostic atomic.Pointer[ErrorHandler]
+	}
+
+	// Implemented by lanes that report their internal errors
+	diagnosticReporter interface {
+		reportDiagnostic(err error, rec Record)
+	}
+)
+
+// Sets the function called when the lane or a later descendant fails to
+// write a message. A nil handler removes it.
+func (es *errorStore) SetErrorHandler(handler ErrorHandler) {
+	if handler == nil {
+		es.onError.Store(nil)
+	} else {
+		es.onError.Store(&handler)
+	}
+}
+
+// Gives a derived lane the error handler of its parent
+func (es *errorStore) inheritErrorHandler(parent *errorStore) {
+	es.onError.Store(parent.onError.Load())
+	es.onDiagnostic.Store(parent.onDiagnostic.Load())
+}
+
+// Sets the handler of the WithDiagnostics() option
+func (es *errorStore) setDiagnosticHandler(handler ErrorHandler) {
+	if handler != nil {
+		es.onDiagnostic.Store(&handler)
+	}
+}
+
+func (es *errorStore) reportError(err error, rec Record) {
+	if handler := es.onError.Load(); handler != nil {
+		(*handler)(err, rec)
+	}
+}
+
+// Reports an internal error of the lane, such as an object that can't be
+// rendered, which the lane has worked around
+func (es *errorStore) reportDiagnostic(err error, rec Record) {
+	if handler := es.onDiagnostic.Load(); handler != nil {
+		(*handler)(err, rec)
+	}
+}
@@ -0,0 +1,85 @@
+package lane
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+)
+
+func TestErrorHandlerDiskFull(t *testing.T) {
+	if _, err := os.Stat("/dev/full"); err != nil {
+		t.Skip("requires /dev/full")
+	}
+
+	dl, err := NewDiskLane(nil, "/dev/full")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dl.Close()
+
+	var mu sync.Mutex
+	var records []Record
+	var lastErr error
+	dl.SetErrorHandler(func(err error, record Record) {
+		mu.Lock()
+		defer mu.Unlock()
+		records = append(records, record)
+		lastErr = err
+	})
+
+	// the handler is inherited
+	child := dl.Derive()
+	child.SetJourneyId("j1")
+	child.Warnf("lost %d", 1)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(records) != 1 || !errors.Is(lastErr, syscall.ENOSPC) {
+		t.Fatalf("unexpected errors %v %v", records, lastErr)
+	}
+	rec := records[0]
+	if rec.LaneId != child.LaneId() || rec.JourneyId != "j1" || rec.Level != LogLevelWarn || rec.Message != "lost 1" || rec.Time.IsZero() {
+		t.Errorf("unexpected record %+v", rec)
+	}
+
+	if !errors.Is(dl.LastError(), syscall.ENOSPC) || !errors.Is(child.LastError(), syscall.ENOSPC) {
+		t.Errorf("unexpected last error %v", dl.LastError())
+	}
+	if StatsOf(dl).Dropped != 1 {
+		t.Errorf("unexpected stats %+v", StatsOf(dl))
+	}
+}
+
+func TestErrorHandlerRemoved(t *testing.T) {
+	if _, err := os.Stat("/dev/full"); err != nil {
+		t.Skip("requires /dev/full")
+	}
+
+	dl, err := NewDiskLane(nil, "/dev/full")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dl.Close()
+
+	calls := 0
+	dl.SetErrorHandler(func(err error, record Record) { calls++ })
+	dl.Info("reported")
+	dl.SetErrorHandler(nil)
+	dl.Info("not reported")
+
+	if calls != 1 || dl.LastError() == nil {
+		t.Errorf("unexpected calls %d", calls)
+	}
+}
+
+func TestErrorHandlerNoErrors(t *testing.T) {
+	for _, l := range []Lane{NewTestingLane(nil), NewNullLane(nil), NewLogLane(nil)} {
+		l.SetErrorHandler(func(err error, record Record) { t.Errorf("unexpected error %v", err) })
+		l.Derive().Trace("ok")
+		if l.LastError() != nil {
+			t.Errorf("unexpected last error %v", l.LastError())
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package lane
+
+import (
+	"context"
+	"time"
+)
+
+// Starts the soft timeout of [l]. When [d] elapses before [stop] is called,
+// a warning is logged to [l] and [onTimeout] is called, but the lane's context
+// isn't canceled. The timer ends without a warning if the context is done.
+func startSoftTimeout(l Lane, d time.Duration, onTimeout func(l Lane)) (stop func() bool) {
+	timer := time.AfterFunc(d, func() {
+		l.Warnf("soft timeout of %v exceeded", d)
+		if onTimeout != nil {
+			onTimeout(l)
+		}
+	})
+	stopOnDone := context.AfterFunc(l, func() { timer.Stop() })
+
+	return func() bool {
+		stopOnDone()
+		return timer.Stop()
+	}
+}
@@ -0,0 +1,63 @@
+package lane
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSoftTimeout(t *testing.T) {
+	for _, l := range []Lane{NewTestingLane(nil), NewLogLane(nil), NewNullLane(nil)} {
+		tl := NewTestingLane(nil)
+		l.AddTee(tl)
+
+		fired := make(chan Lane, 1)
+		stop := l.WithSoftTimeout(time.Millisecond, func(l Lane) { fired <- l })
+
+		select {
+		case received := <-fired:
+			if received != l {
+				t.Errorf("%T: the callback received another lane", l)
+			}
+		case <-time.After(time.Minute):
+			t.Fatalf("%T: the soft timeout didn't fire", l)
+		}
+
+		if stop() {
+			t.Errorf("%T: stopped a timer that fired", l)
+		}
+		if l.Err() != nil {
+			t.Errorf("%T: the context was canceled", l)
+		}
+		if !tl.VerifyEventText("WARN\tsoft timeout of 1ms exceeded") {
+			t.Errorf("%T: unexpected events:\n%s", l, tl.EventsToString())
+		}
+	}
+}
+
+func TestSoftTimeoutStopped(t *testing.T) {
+	tl := NewTestingLane(nil)
+	stop := tl.WithSoftTimeout(time.Hour, func(l Lane) { t.Error("the soft timeout fired") })
+	if !stop() {
+		t.Error("the timer wasn't stopped")
+	}
+	if stop() {
+		t.Error("the timer was stopped twice")
+	}
+}
+
+func TestSoftTimeoutContextDone(t *testing.T) {
+	tl := NewTestingLane(nil)
+	tl.WantDescendantEvents(true)
+	l, cancel := tl.DeriveWithCancel()
+
+	stop := l.WithSoftTimeout(20*time.Millisecond, func(l Lane) { t.Error("the soft timeout fired") })
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+
+	if stop() {
+		t.Error("the timer wasn't stopped by the context")
+	}
+	if len(tl.Events()) != 0 {
+		t.Errorf("unexpected events:\n%s", tl.EventsToString())
+	}
+}
@@ -0,0 +1,135 @@
+package lane
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type (
+	// Settings for a lane that sends log records to a Splunk HTTP Event
+	// Collector
+	SplunkConfig struct {
+		URL        string        // the collector, such as "https://splunk:8088", required
+		Token      string        // the HEC token, required
+		Index      string        // the index of each event, or "" for the token's default
+		Source     string        // the source of each event, when set
+		SourceType string        // the sourcetype of each event, defaults to "_json"
+		Host       string        // the host of each event, when set
+		Gzip       bool          // compress the requests
+		BatchSize  int           // maximum records per request, defaults to 100
+		MaxBytes   int           // maximum request size before compression, defaults to 1 MB, the collector's max_content_length
+		QueueSize  int           // records held while sending, defaults to 10000
+		MaxRetries int           // attempts to send a batch before dropping it, defaults to 3
+		Backoff    Backoff       // delays between the attempts to send a batch; MaxRetries sets the attempts
+		Timeout    time.Duration // per request, defaults to 10 seconds
+		Client     *http.Client  // defaults to a client with the timeout
+		Spool      *SpoolConfig  // holds undeliverable records on disk for replay, when set
+	}
+
+	splunkEvent struct {
+		Time       float64           `json:"time"`
+		Host       string            `json:"host,omitempty"`
+		Source     string            `json:"source,omitempty"`
+		SourceType string            `json:"sourcetype"`
+		Index      string            `json:"index,omitempty"`
+		Event      splunkEventBody   `json:"event"`
+		Fields     map[string]string `json:"fields,omitempty"`
+	}
+
+	splunkEventBody struct {
+		Message   string   `json:"message"`
+		Level     string   `json:"level"`
+		LaneId    string   `json:"lane_id"`
+		JourneyId string   `json:"journey_id,omitempty"`
+		Category  string   `json:"category,omitempty"`
+		ParentId  string   `json:"parent_lane_id,omitempty"`
+		Caller    string   `json:"caller,omitempty"`
+		Stack     []string `json:"stack,omitempty"`
+	}
+)
+
+// Makes a lane that sends its log records to a Splunk HTTP Event Collector.
+// Each record is an event holding the message, level, lane ID, journey ID,
+// category, parent lane ID and caller, with the metadata as indexed fields.
+// Records are queued and sent in batches in the background, so logging does
+// not wait for the network. Close the lane to flush the queue.
+//
+// Batches are kept within SplunkConfig.MaxBytes. An event over it has its
+// message shortened to fit, or is dropped if it can't.
+func NewSplunkLane(ctx OptionalContext, cfg SplunkConfig, opts ...LaneOption) (l Lane, err error) {
+	if cfg.Token == "" {
+		err = errors.New("splunk lane requires a HEC token")
+		return
+	}
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return
+	}
+	if u.Host == "" {
+		err = errors.New("splunk lane requires the collector URL")
+		return
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/services/collector/event"
+	}
+	if cfg.SourceType == "" {
+		cfg.SourceType = "_json"
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = 1 << 20
+	}
+
+	l = newHTTPBatchLane(ctx, httpBatchConfig{
+		endpoint:     u.String(),
+		headers:      map[string]string{"Content-Type": "application/json", "Authorization": "Splunk " + cfg.Token},
+		gzip:         cfg.Gzip,
+		batchSize:    cfg.BatchSize,
+		maxBodyBytes: cfg.MaxBytes,
+		queueSize:    cfg.QueueSize,
+		maxRetries:   cfg.MaxRetries,
+		backoff:      cfg.Backoff,
+		timeout:      cfg.Timeout,
+		client:       cfg.Client,
+		spool:        cfg.Spool,
+		encodeRecord: func(rec *Record) []byte {
+			return encodeSplunkEvent(rec, &cfg)
+		},
+		encodeBody: encodeSplunkBatch,
+	}, opts...)
+	return
+}
+
+// Encodes [rec] as a HEC event
+func encodeSplunkEvent(rec *Record, cfg *SplunkConfig) []byte {
+	data, _ := json.Marshal(splunkEvent{
+		Time:       float64(rec.Time.UnixMicro()) / 1e6,
+		Host:       cfg.Host,
+		Source:     cfg.Source,
+		SourceType: cfg.SourceType,
+		Index:      cfg.Index,
+		Event: splunkEventBody{
+			Message:   rec.Message,
+			Level:     levelNames[rec.Level],
+			LaneId:    rec.LaneId,
+			JourneyId: rec.JourneyId,
+			Category:  rec.Category,
+			ParentId:  rec.ParentId,
+			Caller:    rec.Caller,
+			Stack:     rec.Stack,
+		},
+		Fields: rec.Fields,
+	})
+	return data
+}
+
+// Makes a HEC batch, which is the events one after another
+func encodeSplunkBatch(records [][]byte) []byte {
+	var buf []byte
+	for _, rec := range records {
+		buf = append(append(buf, rec...), '\n')
+	}
+	return buf
+}
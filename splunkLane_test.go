@@ -0,0 +1,112 @@
+package lane
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSplunkLane(t *testing.T) {
+	tis := newTestIntakeServer()
+	defer tis.Close()
+
+	sl, err := NewSplunkLane(nil, SplunkConfig{
+		URL:    tis.URL,
+		Token:  "token",
+		Index:  "main",
+		Source: "checkout",
+		Host:   "web-1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sl.SetMetadata("tenant", "acme")
+	sl.Category("db").Warn("slow query")
+	sl.Info("done")
+	sl.Close()
+
+	var events []splunkEvent
+	for _, req := range tis.waitRequests(t, 1) {
+		if req.path != "/services/collector/event" || req.header.Get("Authorization") != "Splunk token" {
+			t.Errorf("unexpected request %+v", req)
+		}
+		dec := json.NewDecoder(bytes.NewReader(req.body))
+		for dec.More() {
+			var event splunkEvent
+			if err = dec.Decode(&event); err != nil {
+				t.Fatal(err)
+			}
+			events = append(events, event)
+		}
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("unexpected events %+v", events)
+	}
+	first := events[0]
+	if first.Event.Message != "slow query" || first.Event.Level != "WARN" || first.Event.Category != "db" || first.Event.LaneId == "" {
+		t.Errorf("unexpected event %+v", first)
+	}
+	if first.Index != "main" || first.Source != "checkout" || first.SourceType != "_json" || first.Host != "web-1" || first.Time == 0 {
+		t.Errorf("unexpected event metadata %+v", first)
+	}
+	if events[1].Fields["tenant"] != "acme" {
+		t.Errorf("unexpected fields %+v", events[1].Fields)
+	}
+}
+
+func TestSplunkLaneConfig(t *testing.T) {
+	for _, cfg := range []SplunkConfig{{URL: "https://splunk:8088"}, {Token: "token"}, {URL: "::", Token: "token"}} {
+		if _, err := NewSplunkLane(nil, cfg); err == nil {
+			t.Errorf("expected an error for %+v", cfg)
+		}
+	}
+
+	sl, err := NewSplunkLane(nil, SplunkConfig{URL: "https://splunk:8088/services/collector/raw", Token: "token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sl.Close()
+	if endpoint := sl.(*httpBatchLane).sender.cfg.endpoint; endpoint != "https://splunk:8088/services/collector/raw" {
+		t.Errorf("unexpected endpoint %s", endpoint)
+	}
+}
+
+func TestSplunkLaneMaxBytes(t *testing.T) {
+	tis := newTestIntakeServer()
+	defer tis.Close()
+
+	sl, err := NewSplunkLane(nil, SplunkConfig{URL: tis.URL, Token: "token", MaxBytes: 600})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 10 {
+		sl.Info(strings.Repeat("x", 100))
+	}
+	sl.Info(strings.Repeat("y", 1000))
+	sl.Close()
+
+	// the events are split over requests within the limit, and the large
+	// one is shortened to fit
+	var events []splunkEvent
+	for _, req := range tis.waitRequests(t, 3) {
+		if len(req.body) > 600 {
+			t.Errorf("request of %d bytes is over the limit", len(req.body))
+		}
+		dec := json.NewDecoder(bytes.NewReader(req.body))
+		for dec.More() {
+			var event splunkEvent
+			if err = dec.Decode(&event); err != nil {
+				t.Fatal(err)
+			}
+			events = append(events, event)
+		}
+	}
+	if len(events) != 11 {
+		t.Fatalf("expected 11 events, have %d", len(events))
+	}
+	if last := events[10].Event.Message; !strings.HasPrefix(last, "yyy") || !strings.HasSuffix(last, "…") || len(last) >= 1000 {
+		t.Errorf("unexpected shortened message %q", last)
+	}
+}
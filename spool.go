@@ -0,0 +1,329 @@
+package lane
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+type (
+	// Settings for holding records on disk while a network lane can't
+	// deliver them. The records are replayed, oldest first, once delivery
+	// succeeds again - including after the process restarts.
+	SpoolConfig struct {
+		Dir           string        // directory of the spool files, which should be unique to the lane
+		MaxBytes      int64         // the oldest records are discarded beyond this size, defaults to 64 MiB
+		FileBytes     int64         // a new spool file is started beyond this size, defaults to 4 MiB
+		RetryInterval time.Duration // how often delivery of spooled records is attempted, defaults to 5 seconds
+	}
+
+	// Spool activity
+	SpoolStats struct {
+		Files    int   // spool files on disk
+		Bytes    int64 // total size of the spool files
+		Pending  int64 // records waiting to be replayed
+		Spooled  int64 // records written to the spool
+		Replayed int64 // records delivered from the spool
+		Dropped  int64 // records lost to the size cap or a disk error
+	}
+
+	// Bounded write-ahead store of undeliverable records. Replay is expected
+	// to run on one goroutine; the other operations are safe from any.
+	diskSpool struct {
+		cfg      SpoolConfig
+		mu       sync.Mutex
+		files    []*spoolFile // oldest first
+		w        *os.File     // the newest file, open for append
+		nextSeq  uint64
+		spooled  int64
+		replayed int64
+		dropped  int64
+	}
+
+	spoolFile struct {
+		path     string
+		size     int64
+		records  int64
+		offset   int64 // replay read position
+		consumed int64 // records replayed
+	}
+)
+
+const spoolFilePattern = "spool-%016d.dat"
+
+// Makes a spool, picking up any records left in the directory by a prior run
+func newDiskSpool(cfg SpoolConfig) *diskSpool {
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = 64 * 1024 * 1024
+	}
+	if cfg.FileBytes <= 0 {
+		cfg.FileBytes = 4 * 1024 * 1024
+	}
+	if cfg.FileBytes > cfg.MaxBytes {
+		cfg.FileBytes = cfg.MaxBytes
+	}
+	if cfg.RetryInterval <= 0 {
+		cfg.RetryInterval = 5 * time.Second
+	}
+
+	ds := &diskSpool{cfg: cfg}
+	ds.scan()
+	return ds
+}
+
+func (ds *diskSpool) scan() {
+	entries, err := os.ReadDir(ds.cfg.Dir)
+	if err != nil {
+		return
+	}
+
+	seqs := []uint64{}
+	for _, entry := range entries {
+		var seq uint64
+		if _, err := fmt.Sscanf(entry.Name(), spoolFilePattern, &seq); err == nil && !entry.IsDir() {
+			seqs = append(seqs, seq)
+		}
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+
+	for _, seq := range seqs {
+		sf := &spoolFile{path: filepath.Join(ds.cfg.Dir, fmt.Sprintf(spoolFilePattern, seq))}
+		if sf.count() && sf.records > 0 {
+			ds.files = append(ds.files, sf)
+		} else {
+			os.Remove(sf.path)
+		}
+		ds.nextSeq = seq + 1
+	}
+}
+
+// Determines the number of whole records in the file, trimming a partial
+// record left by an interrupted write
+func (sf *spoolFile) count() bool {
+	f, err := os.OpenFile(sf.path, os.O_RDWR, 0)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	var header [4]byte
+	for {
+		if _, err = f.ReadAt(header[:], sf.size); err != nil {
+			break
+		}
+		next := sf.size + 4 + int64(binary.BigEndian.Uint32(header[:]))
+		if next > info.Size() {
+			break
+		}
+		sf.size = next
+		sf.records++
+	}
+	return f.Truncate(sf.size) == nil
+}
+
+// Reports true if records are waiting to be replayed
+func (ds *diskSpool) pending() bool {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	return len(ds.files) > 0
+}
+
+// Writes records to the end of the spool, discarding the oldest records if
+// the spool grows beyond its size cap
+func (ds *diskSpool) append(records [][]byte) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	for _, rec := range records {
+		if err := ds.write(rec); err != nil {
+			ds.dropped++
+			continue
+		}
+		ds.spooled++
+	}
+
+	var total int64
+	for _, sf := range ds.files {
+		total += sf.size
+	}
+	for total > ds.cfg.MaxBytes && len(ds.files) > 0 {
+		total -= ds.files[0].size
+		ds.dropped += ds.files[0].records - ds.files[0].consumed
+		ds.removeOldest()
+	}
+}
+
+func (ds *diskSpool) write(rec []byte) (err error) {
+	if ds.w == nil || ds.files[len(ds.files)-1].size >= ds.cfg.FileBytes {
+		if err = ds.rotate(); err != nil {
+			return
+		}
+	}
+
+	data := binary.BigEndian.AppendUint32(make([]byte, 0, 4+len(rec)), uint32(len(rec)))
+	data = append(data, rec...)
+
+	sf := ds.files[len(ds.files)-1]
+	if _, err = ds.w.Write(data); err != nil {
+		// don't leave a partial record behind
+		ds.w.Truncate(sf.size)
+		return
+	}
+	sf.size += int64(len(data))
+	sf.records++
+	return
+}
+
+// Starts a new spool file
+func (ds *diskSpool) rotate() (err error) {
+	if ds.w != nil {
+		ds.w.Close()
+		ds.w = nil
+	}
+
+	if err = os.MkdirAll(ds.cfg.Dir, 0755); err != nil {
+		return
+	}
+
+	path := filepath.Join(ds.cfg.Dir, fmt.Sprintf(spoolFilePattern, ds.nextSeq))
+	if ds.w, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644); err != nil {
+		return
+	}
+	ds.nextSeq++
+	ds.files = append(ds.files, &spoolFile{path: path})
+	return
+}
+
+func (ds *diskSpool) removeOldest() {
+	if len(ds.files) == 1 && ds.w != nil {
+		ds.w.Close()
+		ds.w = nil
+	}
+	os.Remove(ds.files[0].path)
+	ds.files = ds.files[1:]
+}
+
+// Delivers the spooled records, oldest first, in batches of up to [batchSize]
+// records and [batchBytes] bytes, if not zero, until the spool is empty or
+// [send] fails. A batch is removed from the spool only after it is sent, so
+// records can be delivered twice if the process ends in between.
+func (ds *diskSpool) replay(batchSize int, batchBytes int, send func(records [][]byte) error) error {
+	for {
+		sf, batch, next := ds.read(batchSize, batchBytes)
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := send(batch); err != nil {
+			return err
+		}
+		ds.advance(sf, len(batch), next)
+	}
+}
+
+// Reads the next batch of the oldest file, and the file position after it
+func (ds *diskSpool) read(batchSize int, batchBytes int) (sf *spoolFile, batch [][]byte, next int64) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	for len(ds.files) > 0 {
+		sf = ds.files[0]
+		f, err := os.Open(sf.path)
+		if err == nil {
+			r := io.NewSectionReader(f, sf.offset, sf.size-sf.offset)
+			batch, err = readSpoolRecords(r, batchSize, batchBytes)
+			f.Close()
+		}
+		if err == nil && len(batch) > 0 {
+			next = sf.offset
+			for _, rec := range batch {
+				next += 4 + int64(len(rec))
+			}
+			return
+		}
+
+		// the file is unreadable or used up
+		ds.dropped += sf.records - sf.consumed
+		ds.removeOldest()
+		batch = nil
+	}
+	return
+}
+
+func readSpoolRecords(r io.Reader, batchSize int, batchBytes int) (batch [][]byte, err error) {
+	var header [4]byte
+	size := 0
+	for len(batch) < batchSize {
+		if _, err = io.ReadFull(r, header[:]); err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return
+		}
+		length := int(binary.BigEndian.Uint32(header[:]))
+		if batchBytes > 0 && len(batch) > 0 && size+length > batchBytes {
+			// the record starts the next batch
+			return
+		}
+		size += length
+		rec := make([]byte, length)
+		if _, err = io.ReadFull(r, rec); err != nil {
+			return
+		}
+		batch = append(batch, rec)
+	}
+	return
+}
+
+// Moves past a replayed batch, removing the oldest file once it's used up
+func (ds *diskSpool) advance(sf *spoolFile, count int, next int64) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	if len(ds.files) == 0 || ds.files[0] != sf {
+		// the size cap discarded the file while the batch was sent
+		return
+	}
+	ds.replayed += int64(count)
+	sf.offset = next
+	sf.consumed += int64(count)
+	if sf.consumed >= sf.records {
+		ds.removeOldest()
+	}
+}
+
+func (ds *diskSpool) stats() (stats SpoolStats) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	stats.Files = len(ds.files)
+	for _, sf := range ds.files {
+		stats.Bytes += sf.size
+		stats.Pending += sf.records - sf.consumed
+	}
+	stats.Spooled = ds.spooled
+	stats.Replayed = ds.replayed
+	stats.Dropped = ds.dropped
+	return
+}
+
+// Closes the open spool file; the records left on disk are replayed by the
+// next spool opened on the directory
+func (ds *diskSpool) close() {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	if ds.w != nil {
+		ds.w.Close()
+		ds.w = nil
+	}
+}
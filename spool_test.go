@@ -0,0 +1,129 @@
+package lane
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func testSpoolRecords(prefix string, count int) (records [][]byte) {
+	for i := range count {
+		records = append(records, []byte(fmt.Sprintf("%s%d", prefix, i)))
+	}
+	return
+}
+
+func TestSpoolReplay(t *testing.T) {
+	ds := newDiskSpool(SpoolConfig{Dir: t.TempDir(), FileBytes: 20})
+	defer ds.close()
+
+	if ds.pending() {
+		t.Fatal("new spool has records")
+	}
+
+	ds.append(testSpoolRecords("rec", 5))
+	stats := ds.stats()
+	if stats.Files != 2 || stats.Pending != 5 || stats.Spooled != 5 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	// a failed send keeps the records
+	failure := errors.New("offline")
+	err := ds.replay(2, 0, func(records [][]byte) error { return failure })
+	if err != failure || ds.stats().Pending != 5 {
+		t.Fatalf("unexpected replay result %v %+v", err, ds.stats())
+	}
+
+	var replayed []string
+	err = ds.replay(2, 0, func(records [][]byte) error {
+		for _, rec := range records {
+			replayed = append(replayed, string(rec))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(replayed) != "[rec0 rec1 rec2 rec3 rec4]" {
+		t.Errorf("unexpected replay %v", replayed)
+	}
+
+	stats = ds.stats()
+	if ds.pending() || stats.Files != 0 || stats.Replayed != 5 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestSpoolReplayBytes(t *testing.T) {
+	ds := newDiskSpool(SpoolConfig{Dir: t.TempDir()})
+	defer ds.close()
+
+	// batches stop before the record that would take them over 10 bytes
+	ds.append([][]byte{[]byte("four"), []byte("four"), []byte("four"), []byte("eleven bytes")})
+	var sizes []int
+	ds.replay(10, 10, func(records [][]byte) error {
+		sizes = append(sizes, len(records))
+		return nil
+	})
+	if fmt.Sprint(sizes) != "[2 1 1]" {
+		t.Errorf("unexpected batch sizes %v", sizes)
+	}
+}
+
+func TestSpoolSizeCap(t *testing.T) {
+	// each record takes 8 bytes on disk
+	ds := newDiskSpool(SpoolConfig{Dir: t.TempDir(), MaxBytes: 32, FileBytes: 16})
+	defer ds.close()
+
+	ds.append(testSpoolRecords("rec", 6))
+	stats := ds.stats()
+	if stats.Pending != 4 || stats.Dropped != 2 || stats.Bytes != 32 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	var first string
+	ds.replay(1, 0, func(records [][]byte) error {
+		first = string(records[0])
+		return errors.New("stop")
+	})
+	if first != "rec2" {
+		t.Errorf("expected the oldest records to be discarded, got %s", first)
+	}
+}
+
+func TestSpoolReopen(t *testing.T) {
+	dir := t.TempDir()
+	ds := newDiskSpool(SpoolConfig{Dir: dir, FileBytes: 20})
+	ds.append(testSpoolRecords("a", 3))
+	ds.replay(1, 0, func(records [][]byte) error { return nil })
+	ds.append(testSpoolRecords("b", 2))
+	ds.close()
+
+	// simulate a crash during a write
+	f, err := os.OpenFile(ds.files[len(ds.files)-1].path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{0, 0, 0, 9, 'x'})
+	f.Close()
+
+	ds2 := newDiskSpool(SpoolConfig{Dir: dir})
+	defer ds2.close()
+
+	var replayed []string
+	ds2.replay(10, 0, func(records [][]byte) error {
+		for _, rec := range records {
+			replayed = append(replayed, string(rec))
+		}
+		return nil
+	})
+	if fmt.Sprint(replayed) != "[b0 b1]" {
+		t.Errorf("unexpected replay %v", replayed)
+	}
+
+	ds2.append(testSpoolRecords("c", 1))
+	if stats := ds2.stats(); stats.Files != 1 || stats.Pending != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
@@ -0,0 +1,387 @@
+package lane
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"sync"
+)
+
+type (
+	// Settings for a lane that stores log records in a SQLite database
+	SqliteConfig struct {
+		DriverName string // the database/sql driver, defaults to "sqlite" or "sqlite3", whichever is registered
+		MaxRows    int64  // the oldest records are deleted beyond this count, defaults to 100000
+		MaxBytes   int64  // the oldest records are deleted beyond this many bytes of message and fields text, 0 for no limit
+		BatchSize  int    // maximum records per transaction, defaults to 100
+		QueueSize  int    // records held while writing, defaults to 10000
+	}
+
+	sqliteLane struct {
+		LogLane
+		writer *sqliteWriter
+		owner  bool
+	}
+
+	// Background insertion of records, shared by a sqlite lane and its derivations
+	sqliteWriter struct {
+		cfg      SqliteConfig
+		db       *sql.DB
+		records  chan Record
+		done     chan struct{}
+		mu       sync.RWMutex
+		closed   bool
+		counters laneCounters
+
+		// with MaxBytes, the size of each stored row, oldest first, and
+		// their total; only the worker goroutine uses these
+		rows        []sqliteRow
+		storedBytes int64
+	}
+
+	sqliteRow struct {
+		id   int64
+		size int64
+	}
+)
+
+var ErrNoSqliteDriver = errors.New("no sqlite database/sql driver is registered")
+
+// the layout of the time column, which sorts in time order
+const sqliteTimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+var sqliteSchema = []string{
+	`CREATE TABLE IF NOT EXISTS lane_log (
+		id INTEGER PRIMARY KEY,
+		time TEXT NOT NULL,
+		level TEXT NOT NULL,
+		lane TEXT NOT NULL,
+		journey TEXT,
+		category TEXT,
+		message TEXT NOT NULL,
+		fields TEXT
+	)`,
+	`CREATE INDEX IF NOT EXISTS lane_log_time ON lane_log (time)`,
+	`CREATE INDEX IF NOT EXISTS lane_log_level ON lane_log (level)`,
+	`CREATE INDEX IF NOT EXISTS lane_log_lane ON lane_log (lane)`,
+	`CREATE INDEX IF NOT EXISTS lane_log_journey ON lane_log (journey)`,
+}
+
+// Makes a lane that stores its log records in the lane_log table of the
+// SQLite database at [dbPath], for querying the history with SQL on a device
+// that has no log pipeline. The table has the time (UTC, as RFC 3339 text),
+// level, lane ID, journey ID, category, message and the metadata as a JSON
+// object, and is indexed by time, level, lane and journey. The oldest records
+// are deleted beyond SqliteConfig.MaxRows, and beyond SqliteConfig.MaxBytes of
+// message and fields text if it is set. The database file is larger than
+// MaxBytes by the row and index overhead, and SQLite reuses the space of the
+// deleted rows rather than shrinking the file. The MaxBytes total is loaded
+// when the lane opens, so rows deleted by another connection are counted
+// until the lane is reopened.
+//
+// This package doesn't depend on a SQLite driver; the program imports one,
+// such as modernc.org/sqlite or github.com/mattn/go-sqlite3. Records are
+// written in the background in batches, so logging does not wait for the
+// disk. Close the lane to flush the queue and close the database.
+func NewSqliteLane(ctx OptionalContext, dbPath string, cfg SqliteConfig, opts ...LaneOption) (l Lane, err error) {
+	writer, err := newSqliteWriter(dbPath, cfg)
+	if err != nil {
+		return
+	}
+
+	createFn := func(parentLane Lane) (newLane Lane, ll LogLane, writer2 *log.Logger, err error) {
+		newLane, ll, writer2 = createSqliteLane(writer)
+		return
+	}
+
+	if l, err = NewEmbeddedLogLane(createFn, ctx, opts...); err != nil {
+		writer.close(context.Background())
+		return
+	}
+	l.(*sqliteLane).owner = true
+	return
+}
+
+func createSqliteLane(writer *sqliteWriter) (newLane Lane, ll LogLane, w *log.Logger) {
+	sl := sqliteLane{writer: writer}
+	ll = AllocEmbeddedLogLane()
+	sl.LogLane = ll
+	newLane = &sl
+	w = log.New(io.Discard, "", 0)
+	return
+}
+
+func (sl *sqliteLane) receiveRecord(rec *Record) {
+	sl.writer.enqueue(*rec)
+}
+
+// Provides the lane's operational counters, including those of the writer
+func (sl *sqliteLane) Stats() (stats LaneStats) {
+	stats = sl.LogLane.Stats()
+	stats.addDelivery(&sl.writer.counters)
+	return
+}
+
+// Writes the queued records and closes the database, if this lane started
+// the writer. Returns the context error if [ctx] is done before the records
+// are written.
+func (sl *sqliteLane) CloseWithContext(ctx context.Context) (err error) {
+	err = sl.LogLane.CloseWithContext(ctx)
+	if sl.owner {
+		err = errors.Join(err, sl.writer.close(ctx))
+	}
+	return
+}
+
+func (sl *sqliteLane) lengthConstraint(level LaneLogLevel) int {
+	if lc, ok := sl.LogLane.(lengthConstrainer); ok {
+		return lc.lengthConstraint(level)
+	}
+	return 0
+}
+
+func (sl *sqliteLane) treeInfo() laneTreeInfo {
+	if tr, ok := sl.LogLane.(treeReporter); ok {
+		return tr.treeInfo()
+	}
+	return laneTreeInfo{}
+}
+
+func newSqliteWriter(dbPath string, cfg SqliteConfig) (sw *sqliteWriter, err error) {
+	if cfg.DriverName == "" {
+		drivers := sql.Drivers()
+		for _, name := range []string{"sqlite", "sqlite3"} {
+			if slices.Contains(drivers, name) {
+				cfg.DriverName = name
+				break
+			}
+		}
+		if cfg.DriverName == "" {
+			err = ErrNoSqliteDriver
+			return
+		}
+	}
+	if cfg.MaxRows <= 0 {
+		cfg.MaxRows = 100000
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 10000
+	}
+
+	db, err := sql.Open(cfg.DriverName, dbPath)
+	if err != nil {
+		return
+	}
+	// SQLite allows one writer at a time
+	db.SetMaxOpenConns(1)
+
+	for _, stmt := range sqliteSchema {
+		if _, err = db.Exec(stmt); err != nil {
+			db.Close()
+			err = fmt.Errorf("sqlite lane schema: %w", err)
+			return
+		}
+	}
+
+	sw = &sqliteWriter{
+		cfg:     cfg,
+		db:      db,
+		records: make(chan Record, cfg.QueueSize),
+		done:    make(chan struct{}),
+	}
+	if cfg.MaxBytes > 0 {
+		if err = sw.loadRowSizes(); err != nil {
+			db.Close()
+			err = fmt.Errorf("sqlite lane row sizes: %w", err)
+			return
+		}
+	}
+	go sw.run()
+	return
+}
+
+func (sw *sqliteWriter) enqueue(rec Record) {
+	sw.mu.RLock()
+	defer sw.mu.RUnlock()
+
+	if sw.closed {
+		return
+	}
+	select {
+	case sw.records <- rec:
+	default:
+		// the queue is full; logging must not block
+		sw.counters.dropped.Add(1)
+	}
+}
+
+func (sw *sqliteWriter) close(ctx context.Context) error {
+	sw.mu.Lock()
+	if !sw.closed {
+		sw.closed = true
+		close(sw.records)
+	}
+	sw.mu.Unlock()
+
+	select {
+	case <-sw.done:
+		return nil
+	case <-ctx.Done():
+		// the writer finishes in the background
+		return ctx.Err()
+	}
+}
+
+// Reads the size of the rows already stored, for the byte limit
+func (sw *sqliteWriter) loadRowSizes() (err error) {
+	rows, err := sw.db.Query(`SELECT id, length(CAST(message AS BLOB)) + IFNULL(length(CAST(fields AS BLOB)), 0) FROM lane_log ORDER BY id`)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row sqliteRow
+		if err = rows.Scan(&row.id, &row.size); err != nil {
+			return
+		}
+		sw.rows = append(sw.rows, row)
+		sw.storedBytes += row.size
+	}
+	return rows.Err()
+}
+
+// Worker that inserts the queued records in batches until the queue is closed
+func (sw *sqliteWriter) run() {
+	defer close(sw.done)
+	defer sw.db.Close()
+
+	for rec := range sw.records {
+		batch := []Record{rec}
+	fill:
+		for len(batch) < sw.cfg.BatchSize {
+			select {
+			case rec, more := <-sw.records:
+				if !more {
+					break fill
+				}
+				batch = append(batch, rec)
+			default:
+				break fill
+			}
+		}
+
+		if err := sw.insert(batch); err != nil {
+			sw.counters.setError(err)
+			sw.counters.dropped.Add(int64(len(batch)))
+		}
+	}
+}
+
+// Inserts a batch of records in one transaction, deleting the oldest records
+// beyond the row and byte limits
+func (sw *sqliteWriter) insert(batch []Record) (err error) {
+	tx, err := sw.db.Begin()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	stmt, err := tx.Prepare(`INSERT INTO lane_log (time, level, lane, journey, category, message, fields) VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return
+	}
+	defer stmt.Close()
+
+	var size int64
+	rows := sw.rows
+	stored := sw.storedBytes
+	for i := range batch {
+		rec := &batch[i]
+		var fields string
+		if len(rec.Fields) > 0 {
+			data, merr := json.Marshal(rec.Fields)
+			if merr != nil {
+				// the record is stored without its metadata
+				sw.counters.setError(fmt.Errorf("sqlite lane fields: %w", merr))
+			} else {
+				fields = string(data)
+			}
+		}
+		message := rec.Message
+		for _, line := range rec.Stack {
+			message += "\n" + line
+		}
+
+		args := []any{rec.Time.UTC().Format(sqliteTimeLayout), levelNames[rec.Level], rec.LaneId, nullableText(rec.JourneyId), nullableText(rec.Category), message, nullableText(fields)}
+		var result sql.Result
+		if result, err = stmt.Exec(args...); err != nil {
+			return
+		}
+		size += int64(len(message))
+
+		if sw.cfg.MaxBytes > 0 {
+			row := sqliteRow{size: int64(len(message) + len(fields))}
+			if row.id, err = result.LastInsertId(); err != nil {
+				return
+			}
+			rows = append(rows, row)
+			stored += row.size
+		}
+	}
+
+	// by rank rather than by ID, since IDs have gaps after a rollback or a
+	// delete by another connection
+	if _, err = tx.Exec(`DELETE FROM lane_log WHERE id <= (SELECT id FROM lane_log ORDER BY id DESC LIMIT 1 OFFSET ?)`, sw.cfg.MaxRows); err != nil {
+		return
+	}
+
+	if sw.cfg.MaxBytes > 0 {
+		// skip the rows deleted by the row limit, then delete the oldest rows
+		// beyond the byte limit, keeping at least the newest
+		kept := 0
+		for int64(len(rows)-kept) > sw.cfg.MaxRows {
+			stored -= rows[kept].size
+			kept++
+		}
+		cut := kept
+		for cut < len(rows)-1 && stored > sw.cfg.MaxBytes {
+			stored -= rows[cut].size
+			cut++
+		}
+		if cut > kept {
+			if _, err = tx.Exec(`DELETE FROM lane_log WHERE id <= ?`, rows[cut-1].id); err != nil {
+				return
+			}
+		}
+		rows = rows[cut:]
+	}
+
+	if err = tx.Commit(); err != nil {
+		return
+	}
+	sw.counters.bytes.Add(size)
+	sw.rows = rows
+	sw.storedBytes = stored
+	return
+}
+
+// Provides NULL for an empty value, so that queries can use IS NULL
+func nullableText(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
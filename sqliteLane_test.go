@@ -0,0 +1,302 @@
+package lane
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type (
+	// A database/sql driver that records the statements executed on each
+	// database name, in place of a SQLite driver
+	testSqliteDriver struct{}
+	testSqliteConn   struct{ db *testSqliteDb }
+	testSqliteStmt   struct {
+		db    *testSqliteDb
+		query string
+	}
+
+	testSqliteDb struct {
+		mu       sync.Mutex
+		execs    []testSqliteExec
+		failExec string           // statements starting with this fail
+		rows     [][]driver.Value // the result of any query
+		lastId   int64
+	}
+
+	testSqliteRows struct {
+		rows [][]driver.Value
+	}
+
+	// the last inserted row ID
+	testSqliteResult int64
+
+	testSqliteExec struct {
+		query string
+		args  []driver.Value
+	}
+)
+
+var testSqliteDbs sync.Map
+
+func init() {
+	sql.Register("lane-test-sqlite", testSqliteDriver{})
+}
+
+func newTestSqliteDb(t *testing.T) (name string, db *testSqliteDb) {
+	name = t.Name()
+	db = &testSqliteDb{}
+	testSqliteDbs.Store(name, db)
+	return
+}
+
+func (testSqliteDriver) Open(name string) (driver.Conn, error) {
+	db, ok := testSqliteDbs.Load(name)
+	if !ok {
+		return nil, errors.New("no such database")
+	}
+	return &testSqliteConn{db: db.(*testSqliteDb)}, nil
+}
+
+func (tc *testSqliteConn) Prepare(query string) (driver.Stmt, error) {
+	return &testSqliteStmt{db: tc.db, query: query}, nil
+}
+func (tc *testSqliteConn) Close() error              { return nil }
+func (tc *testSqliteConn) Begin() (driver.Tx, error) { return tc, nil }
+func (tc *testSqliteConn) Commit() error             { return nil }
+func (tc *testSqliteConn) Rollback() error           { return nil }
+
+func (ts *testSqliteStmt) Close() error  { return nil }
+func (ts *testSqliteStmt) NumInput() int { return -1 }
+func (ts *testSqliteStmt) Exec(args []driver.Value) (driver.Result, error) {
+	ts.db.mu.Lock()
+	defer ts.db.mu.Unlock()
+
+	if ts.db.failExec != "" && strings.HasPrefix(ts.query, ts.db.failExec) {
+		return nil, errors.New("disk I/O error")
+	}
+	ts.db.execs = append(ts.db.execs, testSqliteExec{query: ts.query, args: args})
+	if strings.HasPrefix(ts.query, "INSERT") {
+		ts.db.lastId++
+	}
+	return testSqliteResult(ts.db.lastId), nil
+}
+func (ts *testSqliteStmt) Query(args []driver.Value) (driver.Rows, error) {
+	ts.db.mu.Lock()
+	defer ts.db.mu.Unlock()
+	return &testSqliteRows{rows: ts.db.rows}, nil
+}
+
+func (tr testSqliteResult) LastInsertId() (int64, error) { return int64(tr), nil }
+func (tr testSqliteResult) RowsAffected() (int64, error) { return 1, nil }
+
+func (tr *testSqliteRows) Columns() []string { return []string{"id", "size"} }
+func (tr *testSqliteRows) Close() error      { return nil }
+func (tr *testSqliteRows) Next(dest []driver.Value) error {
+	if len(tr.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, tr.rows[0])
+	tr.rows = tr.rows[1:]
+	return nil
+}
+
+// Provides the statements executed that start with [prefix]
+func (db *testSqliteDb) find(prefix string) (execs []testSqliteExec) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, exec := range db.execs {
+		if strings.HasPrefix(exec.query, prefix) {
+			execs = append(execs, exec)
+		}
+	}
+	return
+}
+
+func TestSqliteLane(t *testing.T) {
+	name, db := newTestSqliteDb(t)
+	l, err := NewSqliteLane(nil, name, SqliteConfig{DriverName: "lane-test-sqlite"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(db.find("CREATE TABLE IF NOT EXISTS lane_log")) != 1 || len(db.find("CREATE INDEX")) != 4 {
+		t.Error("expected the table and its indexes")
+	}
+
+	l.SetJourneyId("journey")
+	l.Info("started")
+	l.Derive().Category("db").Warn("slow query")
+	l.SetMetadata("user", "alice")
+	l.Error("denied")
+	l.Close()
+
+	inserts := db.find("INSERT INTO lane_log")
+	if len(inserts) != 3 {
+		t.Fatalf("expected 3 inserts, have %d", len(inserts))
+	}
+
+	args := inserts[0].args
+	if _, err := time.Parse(sqliteTimeLayout, args[0].(string)); err != nil {
+		t.Errorf("unexpected time %v", args[0])
+	}
+	if args[1] != "INFO" || args[2] != l.LaneId() || args[3] != "journey" || args[4] != nil || args[5] != "started" || args[6] != nil {
+		t.Errorf("unexpected first record %v", args)
+	}
+
+	args = inserts[1].args
+	if args[1] != "WARN" || args[2] == l.LaneId() || args[4] != "db" || args[5] != "slow query" {
+		t.Errorf("unexpected second record %v", args)
+	}
+
+	args = inserts[2].args
+	if args[1] != "ERROR" || args[5] != "denied" || args[6] != `{"user":"alice"}` {
+		t.Errorf("unexpected third record %v", args)
+	}
+
+	prunes := db.find("DELETE FROM lane_log")
+	if len(prunes) == 0 || prunes[0].args[0] != int64(100000) {
+		t.Errorf("expected the default row limit, have %v", prunes)
+	}
+
+	stats := l.(LaneStatsReporter).Stats()
+	if stats.BytesWritten != int64(len("started")+len("slow query")+len("denied")) {
+		t.Errorf("unexpected bytes written %d", stats.BytesWritten)
+	}
+}
+
+func TestSqliteLanePrune(t *testing.T) {
+	name, db := newTestSqliteDb(t)
+	l, err := NewSqliteLane(nil, name, SqliteConfig{DriverName: "lane-test-sqlite", MaxRows: 50, BatchSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range 5 {
+		l.Info("record")
+	}
+	l.Close()
+
+	if len(db.find("INSERT INTO lane_log")) != 5 {
+		t.Error("expected every record to be inserted")
+	}
+	prunes := db.find("DELETE FROM lane_log")
+	if len(prunes) < 3 {
+		t.Errorf("expected a prune after each batch, have %d", len(prunes))
+	}
+	for _, prune := range prunes {
+		if prune.args[0] != int64(50) {
+			t.Errorf("unexpected row limit %v", prune.args[0])
+		}
+	}
+}
+
+func TestSqliteLaneFailure(t *testing.T) {
+	name, db := newTestSqliteDb(t)
+	db.failExec = "INSERT"
+	l, err := NewSqliteLane(nil, name, SqliteConfig{DriverName: "lane-test-sqlite"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l.Info("lost")
+	if err = l.(LogLane).CloseWithContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := l.(LaneStatsReporter).Stats()
+	if stats.Dropped != 1 || stats.LastError == nil || !strings.Contains(stats.LastError.Error(), "disk I/O error") {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestSqliteLaneSchemaFailure(t *testing.T) {
+	name, db := newTestSqliteDb(t)
+	db.failExec = "CREATE INDEX"
+	if _, err := NewSqliteLane(nil, name, SqliteConfig{DriverName: "lane-test-sqlite"}); err == nil || !strings.Contains(err.Error(), "schema") {
+		t.Errorf("expected a schema error, have %v", err)
+	}
+}
+
+func TestSqliteLaneNoDriver(t *testing.T) {
+	if _, err := NewSqliteLane(nil, "test.db", SqliteConfig{}); !errors.Is(err, ErrNoSqliteDriver) {
+		t.Errorf("expected ErrNoSqliteDriver, have %v", err)
+	}
+}
+
+func TestSqliteLaneMaxBytes(t *testing.T) {
+	name, db := newTestSqliteDb(t)
+	// rows already stored: 10 bytes each
+	db.rows = [][]driver.Value{{int64(1), int64(10)}, {int64(2), int64(10)}}
+	db.lastId = 2
+	l, err := NewSqliteLane(nil, name, SqliteConfig{DriverName: "lane-test-sqlite", MaxBytes: 30, BatchSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l.Info("0123456789")
+	l.Info("0123456789")
+	l.SetMetadata("k", "v") // the fields {"k":"v"} are 9 bytes
+	l.Info("0123456789")
+	l.Close()
+
+	var cutoffs []any
+	for _, prune := range db.find("DELETE FROM lane_log WHERE id <= ?") {
+		cutoffs = append(cutoffs, prune.args[0])
+	}
+	if !slices.Equal(cutoffs, []any{int64(1), int64(3)}) {
+		t.Errorf("unexpected byte limit cutoffs %v", cutoffs)
+	}
+}
+
+func TestSqliteLaneIdGaps(t *testing.T) {
+	name, db := newTestSqliteDb(t)
+	// rows already stored, with the IDs of a rolled back batch missing
+	db.rows = [][]driver.Value{{int64(1), int64(10)}, {int64(2), int64(10)}, {int64(10), int64(10)}}
+	db.lastId = 10
+	l, err := NewSqliteLane(nil, name, SqliteConfig{DriverName: "lane-test-sqlite", MaxRows: 3, MaxBytes: 25, BatchSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l.Info("0123456789")
+	l.Close()
+
+	prunes := db.find("DELETE FROM lane_log WHERE id <= (SELECT id FROM lane_log ORDER BY id DESC LIMIT 1 OFFSET ?)")
+	if len(prunes) != 1 || prunes[0].args[0] != int64(3) {
+		t.Errorf("unexpected row limit prunes %v", prunes)
+	}
+
+	// the row limit removes only row 1, so row 2 is over the byte limit
+	var cutoffs []any
+	for _, prune := range db.find("DELETE FROM lane_log WHERE id <= ?") {
+		cutoffs = append(cutoffs, prune.args[0])
+	}
+	if !slices.Equal(cutoffs, []any{int64(2)}) {
+		t.Errorf("unexpected byte limit cutoffs %v", cutoffs)
+	}
+}
+
+func TestSqliteLaneMaxBytesOversized(t *testing.T) {
+	name, db := newTestSqliteDb(t)
+	l, err := NewSqliteLane(nil, name, SqliteConfig{DriverName: "lane-test-sqlite", MaxBytes: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the newest row is kept even when it alone is over the limit
+	l.Info("larger than the limit")
+	l.Close()
+
+	if len(db.find("DELETE FROM lane_log WHERE id <= ?")) != 0 {
+		t.Error("unexpected byte limit prune")
+	}
+}
@@ -0,0 +1,333 @@
+// Package sqllog wraps a database/sql driver to log its queries to the lane
+// found in each query's context, so that slow queries and failures can be
+// correlated with the lane and journey of the request that made them.
+package sqllog
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jimsnab/go-lane"
+)
+
+type (
+	// Settings for the logging of a wrapped driver
+	Options struct {
+		Lane      lane.Lane                          // logs the queries whose context doesn't carry a lane, or nil to skip them
+		SlowQuery time.Duration                      // queries that take at least this long are logged at WARN, or zero to disable
+		FormatArg func(arg driver.NamedValue) string // renders an argument; by default only its type is logged, to keep values private
+	}
+
+	wrappedDriver struct {
+		driver.Driver
+		opts *Options
+	}
+
+	wrappedConnector struct {
+		base driver.Connector
+		drv  *wrappedDriver
+	}
+
+	// Connector for a driver that doesn't implement driver.DriverContext
+	dsnConnector struct {
+		dsn string
+		drv driver.Driver
+	}
+
+	wrappedConn struct {
+		driver.Conn
+		opts *Options
+	}
+
+	wrappedStmt struct {
+		driver.Stmt
+		conn  driver.Conn
+		query string
+		opts  *Options
+	}
+
+	wrappedTx struct {
+		driver.Tx
+		ctx  context.Context
+		opts *Options
+	}
+)
+
+var errNamedArgs = errors.New("sqllog: the driver does not support named arguments")
+
+// Wraps [d] so that its queries are logged, for use with sql.Register().
+//
+// Each query, statement execution, and transaction commit or rollback is
+// logged at DEBUG with its duration, on the lane that is, or was derived
+// into, the operation's context. A failure is logged at ERROR.
+func Wrap(d driver.Driver, opts Options) driver.Driver {
+	return &wrappedDriver{Driver: d, opts: &opts}
+}
+
+// Wraps [c] so that the queries of its connections are logged, for use with
+// sql.OpenDB(). See Wrap().
+func NewConnector(c driver.Connector, opts Options) driver.Connector {
+	return &wrappedConnector{base: c, drv: &wrappedDriver{Driver: c.Driver(), opts: &opts}}
+}
+
+func (wd *wrappedDriver) Open(name string) (driver.Conn, error) {
+	c, err := wd.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &wrappedConn{Conn: c, opts: wd.opts}, nil
+}
+
+func (wd *wrappedDriver) OpenConnector(name string) (driver.Connector, error) {
+	var base driver.Connector
+	if dc, ok := wd.Driver.(driver.DriverContext); ok {
+		var err error
+		if base, err = dc.OpenConnector(name); err != nil {
+			return nil, err
+		}
+	} else {
+		base = &dsnConnector{dsn: name, drv: wd.Driver}
+	}
+	return &wrappedConnector{base: base, drv: wd}, nil
+}
+
+func (wc *wrappedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	c, err := wc.base.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &wrappedConn{Conn: c, opts: wc.drv.opts}, nil
+}
+
+func (wc *wrappedConnector) Driver() driver.Driver {
+	return wc.drv
+}
+
+func (dc *dsnConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return dc.drv.Open(dc.dsn)
+}
+
+func (dc *dsnConnector) Driver() driver.Driver {
+	return dc.drv
+}
+
+func (wc *wrappedConn) PrepareContext(ctx context.Context, query string) (stmt driver.Stmt, err error) {
+	if cp, ok := wc.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = cp.PrepareContext(ctx, query)
+	} else {
+		stmt, err = wc.Conn.Prepare(query)
+	}
+	if err != nil {
+		wc.opts.log(ctx, "prepare", query, nil, time.Time{}, err)
+		return
+	}
+	return &wrappedStmt{Stmt: stmt, conn: wc.Conn, query: query, opts: wc.opts}, nil
+}
+
+func (wc *wrappedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (tx driver.Tx, err error) {
+	start := time.Now()
+	if cb, ok := wc.Conn.(driver.ConnBeginTx); ok {
+		tx, err = cb.BeginTx(ctx, opts)
+	} else if opts.Isolation != 0 || opts.ReadOnly {
+		err = errors.New("sqllog: the driver does not support transaction options")
+	} else {
+		tx, err = wc.Conn.Begin()
+	}
+	wc.opts.log(ctx, "begin", "", nil, start, err)
+	if err != nil {
+		return
+	}
+	return &wrappedTx{Tx: tx, ctx: ctx, opts: wc.opts}, nil
+}
+
+func (wc *wrappedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (rows driver.Rows, err error) {
+	start := time.Now()
+	if qc, ok := wc.Conn.(driver.QueryerContext); ok {
+		rows, err = qc.QueryContext(ctx, query, args)
+	} else if q, ok := wc.Conn.(driver.Queryer); ok {
+		var values []driver.Value
+		if values, err = namedValues(args); err == nil {
+			rows, err = q.Query(query, values)
+		}
+	} else {
+		// database/sql prepares a statement instead
+		return nil, driver.ErrSkip
+	}
+	wc.opts.log(ctx, "query", query, args, start, err)
+	return
+}
+
+func (wc *wrappedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (result driver.Result, err error) {
+	start := time.Now()
+	if ec, ok := wc.Conn.(driver.ExecerContext); ok {
+		result, err = ec.ExecContext(ctx, query, args)
+	} else if e, ok := wc.Conn.(driver.Execer); ok {
+		var values []driver.Value
+		if values, err = namedValues(args); err == nil {
+			result, err = e.Exec(query, values)
+		}
+	} else {
+		// database/sql prepares a statement instead
+		return nil, driver.ErrSkip
+	}
+	wc.opts.log(ctx, "exec", query, args, start, err)
+	return
+}
+
+func (wc *wrappedConn) Ping(ctx context.Context) error {
+	if p, ok := wc.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (wc *wrappedConn) ResetSession(ctx context.Context) error {
+	if sr, ok := wc.Conn.(driver.SessionResetter); ok {
+		return sr.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (wc *wrappedConn) IsValid() bool {
+	if v, ok := wc.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (wc *wrappedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nvc, ok := wc.Conn.(driver.NamedValueChecker); ok {
+		return nvc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func (ws *wrappedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (result driver.Result, err error) {
+	start := time.Now()
+	if ec, ok := ws.Stmt.(driver.StmtExecContext); ok {
+		result, err = ec.ExecContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValues(args); err == nil {
+			result, err = ws.Stmt.Exec(values)
+		}
+	}
+	ws.opts.log(ctx, "exec", ws.query, args, start, err)
+	return
+}
+
+func (ws *wrappedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (rows driver.Rows, err error) {
+	start := time.Now()
+	if qc, ok := ws.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = qc.QueryContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValues(args); err == nil {
+			rows, err = ws.Stmt.Query(values)
+		}
+	}
+	ws.opts.log(ctx, "query", ws.query, args, start, err)
+	return
+}
+
+// Checks an argument with the statement's checker, or else the connection's,
+// as database/sql does for an unwrapped driver
+func (ws *wrappedStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if nvc, ok := ws.Stmt.(driver.NamedValueChecker); ok {
+		return nvc.CheckNamedValue(nv)
+	}
+	if nvc, ok := ws.conn.(driver.NamedValueChecker); ok {
+		return nvc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func (ws *wrappedStmt) ColumnConverter(idx int) driver.ValueConverter {
+	if cc, ok := ws.Stmt.(driver.ColumnConverter); ok {
+		return cc.ColumnConverter(idx)
+	}
+	return driver.DefaultParameterConverter
+}
+
+func (wt *wrappedTx) Commit() error {
+	start := time.Now()
+	err := wt.Tx.Commit()
+	wt.opts.log(wt.ctx, "commit", "", nil, start, err)
+	return err
+}
+
+func (wt *wrappedTx) Rollback() error {
+	start := time.Now()
+	err := wt.Tx.Rollback()
+	wt.opts.log(wt.ctx, "rollback", "", nil, start, err)
+	return err
+}
+
+// Converts the arguments for the driver interfaces that predate named arguments
+func namedValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errNamedArgs
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
+
+// Logs an operation on the lane of [ctx], or on the fallback lane. A zero
+// [start] omits the duration.
+func (o *Options) log(ctx context.Context, op, query string, args []driver.NamedValue, start time.Time, err error) {
+	l, found := lane.FromContext(ctx)
+	if !found {
+		if l = o.Lane; l == nil {
+			return
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("sql ")
+	sb.WriteString(op)
+	if query != "" {
+		fmt.Fprintf(&sb, " %q", query)
+	}
+	if len(args) > 0 {
+		sb.WriteString(" args [")
+		for i, arg := range args {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(o.formatArg(arg))
+		}
+		sb.WriteString("]")
+	}
+
+	var elapsed time.Duration
+	if !start.IsZero() {
+		elapsed = time.Since(start)
+		fmt.Fprintf(&sb, " took %v", elapsed)
+	}
+
+	switch {
+	case err != nil:
+		l.Errorf("%s: %v", sb.String(), err)
+	case o.SlowQuery > 0 && elapsed >= o.SlowQuery:
+		l.Warnf("slow %s", sb.String())
+	default:
+		l.Debug(sb.String())
+	}
+}
+
+func (o *Options) formatArg(arg driver.NamedValue) string {
+	if o.FormatArg != nil {
+		return o.FormatArg(arg)
+	}
+	if arg.Value == nil {
+		return "nil"
+	}
+	return fmt.Sprintf("%T", arg.Value)
+}
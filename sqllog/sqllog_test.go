@@ -0,0 +1,165 @@
+package sqllog
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jimsnab/go-lane"
+)
+
+type (
+	fakeDriver struct{}
+	fakeConn   struct{}
+	fakeStmt   struct{ query string }
+	fakeTx     struct{}
+	fakeRows   struct{ done bool }
+	fakeResult struct{}
+
+	fakeConnector struct{}
+)
+
+var errFakeQuery = errors.New("syntax error")
+
+func (fakeDriver) Open(name string) (driver.Conn, error) { return fakeConn{}, nil }
+
+func (fakeConnector) Connect(ctx context.Context) (driver.Conn, error) { return fakeConn{}, nil }
+func (fakeConnector) Driver() driver.Driver                            { return fakeDriver{} }
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return &fakeStmt{query: query}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return fakeTx{}, nil }
+
+func (fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if strings.HasPrefix(query, "bad") {
+		return nil, errFakeQuery
+	}
+	return fakeResult{}, nil
+}
+
+func (fs *fakeStmt) Close() error  { return nil }
+func (fs *fakeStmt) NumInput() int { return -1 }
+func (fs *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return fakeResult{}, nil
+}
+func (fs *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if strings.HasPrefix(fs.query, "bad") {
+		return nil, errFakeQuery
+	}
+	return &fakeRows{}, nil
+}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+func (fr *fakeRows) Columns() []string { return []string{"n"} }
+func (fr *fakeRows) Close() error      { return nil }
+func (fr *fakeRows) Next(dest []driver.Value) error {
+	if fr.done {
+		return io.EOF
+	}
+	fr.done = true
+	dest[0] = int64(42)
+	return nil
+}
+
+func (fakeResult) LastInsertId() (int64, error) { return 0, nil }
+func (fakeResult) RowsAffected() (int64, error) { return 1, nil }
+
+func init() {
+	sql.Register("sqllog-fake", Wrap(fakeDriver{}, Options{}))
+}
+
+// Checks the events, ignoring the durations
+func verifyEvents(t *testing.T, tl lane.TestingLane, expected ...string) {
+	t.Helper()
+	events := tl.Events()
+	if len(events) != len(expected) {
+		t.Fatalf("unexpected events:\n%s", tl.EventsToString())
+	}
+	for i, e := range events {
+		text := e.Level + "\t" + e.Message
+		if !strings.HasPrefix(text, expected[i]) {
+			t.Errorf("expected %q, got %q", expected[i], text)
+		}
+	}
+}
+
+func TestLogQueries(t *testing.T) {
+	db, err := sql.Open("sqllog-fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	tl := lane.NewTestingLane(nil)
+	ctx, cancel := context.WithTimeout(tl, time.Minute)
+	defer cancel()
+
+	if _, err := db.ExecContext(ctx, "insert into t values (?, ?)", "secret", 5); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := db.QueryRowContext(tl, "select n from t").Scan(&n); err != nil || n != 42 {
+		t.Fatalf("unexpected result %d %v", n, err)
+	}
+	if _, err := db.ExecContext(tl, "bad statement"); !errors.Is(err, errFakeQuery) {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, err := db.QueryContext(tl, "bad query", 1); !errors.Is(err, errFakeQuery) {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	verifyEvents(t, tl,
+		"DEBUG\tsql exec \"insert into t values (?, ?)\" args [string, int64] took ",
+		"DEBUG\tsql query \"select n from t\" took ",
+		"ERROR\tsql exec \"bad statement\" took ",
+		"ERROR\tsql query \"bad query\" args [int64] took ",
+	)
+	if msg := tl.Events()[2].Message; !strings.HasSuffix(msg, ": syntax error") {
+		t.Errorf("the error is missing from %q", msg)
+	}
+	if tl.Contains("secret") {
+		t.Error("an argument value was logged")
+	}
+}
+
+func TestLogTransaction(t *testing.T) {
+	fallback := lane.NewTestingLane(nil)
+	db := sql.OpenDB(NewConnector(fakeConnector{}, Options{
+		Lane:      fallback,
+		SlowQuery: time.Nanosecond,
+		FormatArg: func(arg driver.NamedValue) string { return fmt.Sprint(arg.Value) },
+	}))
+	defer db.Close()
+
+	tl := lane.NewTestingLane(nil)
+	tx, err := db.BeginTx(tl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = tx.Exec("update t set n = ?", 7); err != nil {
+		t.Fatal(err)
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	verifyEvents(t, tl, "WARN\tslow sql begin took ", "WARN\tslow sql commit took ")
+	verifyEvents(t, fallback, "WARN\tslow sql exec \"update t set n = ?\" args [7] took ")
+}
+
+func TestNoLane(t *testing.T) {
+	db := sql.OpenDB(NewConnector(fakeConnector{}, Options{}))
+	defer db.Close()
+
+	if _, err := db.Exec("insert into t values (1)"); err != nil {
+		t.Fatal(err)
+	}
+}
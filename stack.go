@@ -0,0 +1,208 @@
+package lane
+
+import (
+	"path"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+type (
+	// Common implementation of the per-level stack trace settings
+	stackTraceStore struct {
+		enabled [logLevelMax]atomic.Bool
+		depth   [logLevelMax]atomic.Int32
+	}
+)
+
+const defaultMaxStackSize = 16384
+
+var (
+	maxStackSize   atomic.Int32
+	maxStackFrames atomic.Int32
+	skipStdlib     atomic.Bool
+	stackHeaders   atomic.Bool
+	stackBufPool   = sync.Pool{
+		New: func() any {
+			buf := make([]byte, maxStackSize.Load())
+			return &buf
+		},
+	}
+)
+
+func init() {
+	maxStackSize.Store(defaultMaxStackSize)
+}
+
+// Sets the size of the buffer used to capture a stack trace, which bounds
+// the amount of stack text logged. Values less than 1 restore the default
+// of 16 KB. Returns the prior size.
+func SetMaxStackSize(size int) (prior int) {
+	if size < 1 {
+		size = defaultMaxStackSize
+	}
+	return int(maxStackSize.Swap(int32(size)))
+}
+
+// Limits the number of callers logged in a stack trace, or less than 1 for no
+// limit. Returns the prior limit.
+func SetMaxStackFrames(frames int) (prior int) {
+	if frames < 1 {
+		frames = 0
+	}
+	return int(maxStackFrames.Swap(int32(frames)))
+}
+
+// Removes the frames of the Go runtime and standard library from logged stack
+// traces when [skip] is true, so that the application's callers stand out.
+// Returns the prior setting.
+func SetStackSkipStdlib(skip bool) (prior bool) {
+	return skipStdlib.Swap(skip)
+}
+
+// Starts each logged stack trace with a machine-parsable header line naming
+// the goroutine, lane and journey, such as "goroutine=7 lane=3f2a... journey=abc",
+// for use with ParseStackRecord(). Returns the prior setting.
+func SetStackHeader(enable bool) (prior bool) {
+	return stackHeaders.Swap(enable)
+}
+
+// Turns on stack trace logging for [level]
+func (sts *stackTraceStore) EnableStackTrace(level LaneLogLevel, enable bool) bool {
+	return sts.enabled[level].Swap(enable)
+}
+
+// Turns on stack trace logging for [level], logging at most [maxFrames]
+// callers, or less than 1 to use the limit of SetMaxStackFrames(). Returns
+// the prior limit of the level.
+func (sts *stackTraceStore) EnableStackTraceDepth(level LaneLogLevel, maxFrames int) (prior int) {
+	if maxFrames < 1 {
+		maxFrames = 0
+	}
+	prior = int(sts.depth[level].Swap(int32(maxFrames)))
+	sts.enabled[level].Store(true)
+	return
+}
+
+func (sts *stackTraceStore) stackEnabled(level LaneLogLevel) bool {
+	return sts.enabled[level].Load()
+}
+
+func (sts *stackTraceStore) stackDepth(level LaneLogLevel) int {
+	return int(sts.depth[level].Load())
+}
+
+// Captures the calling goroutine's stack, with the lane implementation and
+// [skipCallers] callers removed from the top, using a pooled buffer. At most
+// [maxFrames] callers are kept, or the SetMaxStackFrames() limit if zero.
+// Also provides the ID of the goroutine.
+func captureStack(skipCallers int, maxFrames int) (lines []string, goroutineId uint64) {
+	size := int(maxStackSize.Load())
+
+	bufPtr := stackBufPool.Get().(*[]byte)
+	if len(*bufPtr) != size {
+		buf := make([]byte, size)
+		bufPtr = &buf
+	}
+
+	stack := (*bufPtr)[:runtime.Stack(*bufPtr, false)]
+	if origin := findTeeOrigin(); origin != nil {
+		// a tee on a worker goroutine
+		stack = origin.stackText()
+	}
+	goroutineId = parseGoroutineId(stack)
+	lines = cleanStack(stack, skipCallers)
+	if skipStdlib.Load() {
+		lines = removeStdlibFrames(lines)
+	}
+
+	if maxFrames == 0 {
+		maxFrames = int(maxStackFrames.Load())
+	}
+	if maxFrames > 0 && len(lines) > maxFrames*2 {
+		// each frame has two lines
+		lines = lines[:maxFrames*2]
+	}
+
+	stackBufPool.Put(bufPtr)
+	return
+}
+
+// Removes the frames (function and source line pairs) of standard library packages
+func removeStdlibFrames(lines []string) (kept []string) {
+	for i := 0; i+1 < len(lines); i += 2 {
+		if !isStdlibFrame(lines[i], lines[i+1]) {
+			kept = append(kept, lines[i], lines[i+1])
+		}
+	}
+	return
+}
+
+// Checks a stack frame, given its function line such as
+// "net/http.(*conn).serve(...)" and its source line such as
+// "\t/usr/local/go/src/net/http/server.go:2009 +0x8ed", for a standard
+// library package, whose source is under GOROOT. A binary built with
+// -trimpath has no GOROOT in its paths; the package of the function is then
+// checked against the modules the binary was built from.
+func isStdlibFrame(funcLine, sourceLine string) bool {
+	file := strings.TrimSpace(sourceLine)
+	if space := strings.LastIndexByte(file, ' '); space >= 0 {
+		file = file[:space]
+	}
+	if root := stdlibSourceRoot(); root != "" && strings.HasPrefix(file, root) {
+		return true
+	}
+	if path.IsAbs(file) || filepath.IsAbs(file) {
+		return false
+	}
+
+	name := strings.TrimPrefix(funcLine, "created by ")
+	pkg := name
+	if slash := strings.LastIndexByte(pkg, '/'); slash >= 0 {
+		dot := strings.IndexByte(pkg[slash:], '.')
+		if dot >= 0 {
+			pkg = pkg[:slash+dot]
+		}
+	} else {
+		pkg, _, _ = strings.Cut(pkg, ".")
+	}
+	if pkg == "main" {
+		return false
+	}
+	for _, mod := range buildModulePaths() {
+		if pkg == mod || strings.HasPrefix(pkg, mod+"/") {
+			return false
+		}
+	}
+
+	first, _, _ := strings.Cut(pkg, "/")
+	return !strings.Contains(first, ".")
+}
+
+// Provides the directory of the standard library source, with a trailing
+// slash, or "" if the GOROOT isn't known
+var stdlibSourceRoot = sync.OnceValue(func() string {
+	root := runtime.GOROOT()
+	if root == "" {
+		return ""
+	}
+	return filepath.ToSlash(filepath.Join(root, "src")) + "/"
+})
+
+// Provides the paths of the main module and its dependencies
+var buildModulePaths = sync.OnceValue(func() (paths []string) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+	if info.Main.Path != "" {
+		paths = append(paths, info.Main.Path)
+	}
+	for _, dep := range info.Deps {
+		paths = append(paths, dep.Path)
+	}
+	return
+})
@@ -0,0 +1,156 @@
+package lane
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestLogLaneLogStackMaxFrames(t *testing.T) {
+	prior := SetMaxStackFrames(1)
+	defer SetMaxStackFrames(prior)
+
+	l := NewLogLane(context.Background())
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer func() { log.SetOutput(os.Stderr) }()
+
+	l.LogStack("")
+
+	expected := `STACK {GUID} {ANY}
+STACK {GUID} {ANY}`
+
+	verifyLogLaneEvents(t, l, expected, buf)
+}
+
+func TestTestingLaneStackMaxFrames(t *testing.T) {
+	prior := SetMaxStackFrames(2)
+	defer SetMaxStackFrames(prior)
+
+	tl := NewTestingLane(context.Background())
+	tl.EnableStackTrace(LogLevelError, true)
+	tl.Error("failure")
+
+	events := tl.Events()
+	if len(events) != 2 || events[1].Level != "STACK" {
+		t.Fatalf("unexpected events:\n%s", tl.EventsToString())
+	}
+	if strings.Count(events[1].Message, "\n") != 3 {
+		t.Errorf("wrong number of stack lines:\n%s", events[1].Message)
+	}
+	if !strings.Contains(events[1].Message, "TestTestingLaneStackMaxFrames") {
+		t.Errorf("stack doesn't start at the caller:\n%s", events[1].Message)
+	}
+}
+
+func TestMaxStackSize(t *testing.T) {
+	prior := SetMaxStackSize(100)
+	defer SetMaxStackSize(prior)
+
+	lines, _ := captureStack(0, 0)
+	if len(strings.Join(lines, "\n")) > 100 {
+		t.Error("stack capture not limited")
+	}
+
+	if SetMaxStackSize(0) != 100 {
+		t.Error("wrong prior size")
+	}
+	if SetMaxStackSize(100) != defaultMaxStackSize {
+		t.Error("default not restored")
+	}
+}
+
+func BenchmarkCaptureStack(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		captureStack(0, 0)
+	}
+}
+
+func TestStackTraceDepth(t *testing.T) {
+	tl := NewTestingLane(context.Background())
+	tl.EnableSingleLineStackTrace(true)
+	if prior := tl.EnableStackTraceDepth(LogLevelError, 1); prior != 0 {
+		t.Errorf("unexpected prior depth %d", prior)
+	}
+	tl.Error("failure")
+	tl.Warn("not traced")
+
+	events := tl.Events()
+	if len(events) != 3 || events[1].Level != "STACK" || strings.Count(events[1].Message, "\n") != 1 {
+		t.Fatalf("unexpected events:\n%s", tl.EventsToString())
+	}
+
+	// derived lanes keep the depth
+	child := tl.Derive()
+	if child.EnableStackTrace(LogLevelError, true) != true || child.EnableStackTraceDepth(LogLevelError, 0) != 1 {
+		t.Error("expected the derived lane to inherit the stack trace depth")
+	}
+}
+
+func TestLogLaneStackTraceDepth(t *testing.T) {
+	l := NewLogLane(context.Background())
+	l.EnableStackTraceDepth(LogLevelError, 1)
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer func() { log.SetOutput(os.Stderr) }()
+
+	l.Error("failure")
+
+	expected := `ERROR {GUID} failure
+STACK {GUID} {ANY}
+STACK {GUID} {ANY}`
+
+	verifyLogLaneEvents(t, l, expected, buf)
+}
+
+func TestStackSkipStdlib(t *testing.T) {
+	prior := SetStackSkipStdlib(true)
+	defer SetStackSkipStdlib(prior)
+
+	lines, _ := captureStack(0, 0)
+	if len(lines) == 0 || !strings.Contains(lines[0], "TestStackSkipStdlib") {
+		t.Fatalf("stack doesn't start at the caller:\n%s", strings.Join(lines, "\n"))
+	}
+	for _, line := range lines {
+		if strings.HasPrefix(line, "testing.") || strings.Contains(line, "created by testing.") {
+			t.Errorf("stdlib frame not removed:\n%s", strings.Join(lines, "\n"))
+		}
+	}
+}
+
+func TestIsStdlibFrame(t *testing.T) {
+	goroot := filepath.ToSlash(runtime.GOROOT())
+	cases := []struct {
+		funcLine, sourceLine string
+		expected             bool
+	}{
+		{"runtime.goexit({})", "\t" + goroot + "/src/runtime/asm_amd64.s:1700 +0x1", true},
+		{"testing.tRunner(0xc000007860, 0x5b8e28)", "\t" + goroot + "/src/testing/testing.go:1690 +0xf4", true},
+		{"net/http.(*conn).serve(0xc0001b6000, {0x7a1e40, 0xc0000a8000})", "\t" + goroot + "/src/net/http/server.go:2009 +0x8ed", true},
+		{"created by testing.(*T).Run in goroutine 1", "\t" + goroot + "/src/testing/testing.go:1743 +0x390", true},
+		{"main.main()", "\t/home/dev/app/main.go:12 +0x1d", false},
+		{"github.com/jimsnab/go-lane.TestIsStdlibFrame(0xc000007860)", "\t/home/dev/go-lane/stack_test.go:127 +0x25", false},
+		{"created by golang.org/x/sync/errgroup.(*Group).Go in goroutine 5", "\t/home/dev/go/pkg/mod/golang.org/x/sync/errgroup/errgroup.go:75 +0x96", false},
+
+		// a module path without a domain name
+		{"mymodule/internal/db.Query(0xc000010000)", "\t/home/dev/mymodule/internal/db/db.go:42 +0x3c", false},
+		{"internal/handlers.(*Server).Serve(0xc000010000)", "\t/srv/app/internal/handlers/server.go:8 +0x10", false},
+
+		// -trimpath
+		{"net/http.(*conn).serve(0xc0001b6000)", "\tnet/http/server.go:2009 +0x8ed", true},
+		{"github.com/jimsnab/go-lane.TestIsStdlibFrame(0xc000007860)", "\tgithub.com/jimsnab/go-lane/stack_test.go:127 +0x25", false},
+	}
+	for _, c := range cases {
+		if isStdlibFrame(c.funcLine, c.sourceLine) != c.expected {
+			t.Errorf("%s: expected %t", c.funcLine, c.expected)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package lane
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type (
+	// A logged stack trace, as reconstructed by ParseStackRecord()
+	StackRecord struct {
+		GoroutineId uint64
+		LaneId      string
+		JourneyId   string
+		Frames      []StackFrame // innermost caller first
+	}
+
+	// A caller in a stack trace
+	StackFrame struct {
+		Function  string // the package qualified function name, without arguments
+		File      string
+		Line      int
+		CreatedBy bool // the frame is the go statement that started the goroutine
+	}
+)
+
+var (
+	errStackHeaderMissing = errors.New("stack header not found")
+	errStackFrameInvalid  = errors.New("invalid stack frame")
+)
+
+// Makes the first stack line, such as "goroutine=7 lane=3f2a... journey=abc"
+func stackHeader(goroutineId uint64, props loggingProperties) string {
+	header := fmt.Sprintf("goroutine=%d lane=%s", goroutineId, props.laneId)
+	if props.journeyId != "" {
+		header += " journey=" + props.journeyId
+	}
+	return header
+}
+
+// Extracts the goroutine ID from the title of a runtime.Stack() capture,
+// such as "goroutine 7 [running]:"
+func parseGoroutineId(buf []byte) (id uint64) {
+	rest, found := bytes.CutPrefix(buf, []byte("goroutine "))
+	if !found {
+		return
+	}
+	if end := bytes.IndexByte(rest, ' '); end > 0 {
+		id, _ = strconv.ParseUint(string(rest[:end]), 10, 64)
+	}
+	return
+}
+
+// Reconstructs a stack trace logged with SetStackHeader(true) from its text,
+// which is the stack lines with or without the "STACK {id}" log prefix of each
+// line. The lines before the stack header, such as the LogStack() message,
+// are ignored.
+func ParseStackRecord(text string) (rec StackRecord, err error) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i := range lines {
+		lines[i] = trimStackLinePrefix(lines[i])
+	}
+
+	start := -1
+	for i, line := range lines {
+		if strings.HasPrefix(line, "goroutine=") {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		err = errStackHeaderMissing
+		return
+	}
+
+	for _, field := range strings.Fields(lines[start]) {
+		key, value, _ := strings.Cut(field, "=")
+		switch key {
+		case "goroutine":
+			if rec.GoroutineId, err = strconv.ParseUint(value, 10, 64); err != nil {
+				return
+			}
+		case "lane":
+			rec.LaneId = value
+		case "journey":
+			rec.JourneyId = value
+		}
+	}
+
+	frameLines := lines[start+1:]
+	for len(frameLines) > 0 && strings.TrimSpace(frameLines[len(frameLines)-1]) == "" {
+		frameLines = frameLines[:len(frameLines)-1]
+	}
+	for i := 0; i < len(frameLines); i += 2 {
+		if i+1 >= len(frameLines) {
+			err = fmt.Errorf("%w: %s", errStackFrameInvalid, frameLines[i])
+			return
+		}
+		var frame StackFrame
+		if frame, err = parseStackFrame(frameLines[i], frameLines[i+1]); err != nil {
+			return
+		}
+		rec.Frames = append(rec.Frames, frame)
+	}
+	return
+}
+
+// Removes the log prefix through "STACK {id} ", if present
+func trimStackLinePrefix(line string) string {
+	if start := strings.Index(line, "STACK {"); start >= 0 {
+		if end := strings.Index(line[start:], "} "); end >= 0 {
+			return line[start+end+2:]
+		}
+	}
+	return line
+}
+
+// Parses a function line, such as "main.run(0xc000012345)" or "created by
+// main.main in goroutine 1", and the source line that follows it, such as
+// "\t/src/main.go:12 +0x1d"
+func parseStackFrame(funcLine, sourceLine string) (frame StackFrame, err error) {
+	var name string
+	name, frame.CreatedBy = strings.CutPrefix(funcLine, "created by ")
+	if frame.CreatedBy {
+		name, _, _ = strings.Cut(name, " in goroutine ")
+	} else if strings.HasSuffix(name, ")") {
+		if open := strings.LastIndexByte(name, '('); open > 0 {
+			name = name[:open]
+		}
+	}
+	frame.Function = name
+
+	source := strings.TrimSpace(sourceLine)
+	if space := strings.LastIndex(source, " +0x"); space >= 0 {
+		source = source[:space]
+	}
+	colon := strings.LastIndexByte(source, ':')
+	if colon < 0 {
+		err = fmt.Errorf("%w: %s", errStackFrameInvalid, sourceLine)
+		return
+	}
+	frame.File = source[:colon]
+	if frame.Line, err = strconv.Atoi(source[colon+1:]); err != nil {
+		err = fmt.Errorf("%w: %s", errStackFrameInvalid, sourceLine)
+	}
+	return
+}
@@ -0,0 +1,86 @@
+package lane
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseStackRecord(t *testing.T) {
+	text := `2024/03/04 05:06:07 STACK {abc} where
+2024/03/04 05:06:07 STACK {abc} goroutine=7 lane=1234abc journey=trip
+2024/03/04 05:06:07 STACK {abc} main.run(0xc000012345, {0x5, 0x6})
+2024/03/04 05:06:07 STACK {abc} 	/src/app/main.go:12 +0x1d
+2024/03/04 05:06:07 STACK {abc} github.com/acme/svc.(*Server).handle(...)
+2024/03/04 05:06:07 STACK {abc} 	/src/svc/server.go:88
+2024/03/04 05:06:07 STACK {abc} created by main.main in goroutine 1
+2024/03/04 05:06:07 STACK {abc} 	/src/app/main.go:5 +0x25
+`
+
+	rec, err := ParseStackRecord(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.GoroutineId != 7 || rec.LaneId != "1234abc" || rec.JourneyId != "trip" || len(rec.Frames) != 3 {
+		t.Fatalf("unexpected record %+v", rec)
+	}
+
+	expected := []StackFrame{
+		{Function: "main.run", File: "/src/app/main.go", Line: 12},
+		{Function: "github.com/acme/svc.(*Server).handle", File: "/src/svc/server.go", Line: 88},
+		{Function: "main.main", File: "/src/app/main.go", Line: 5, CreatedBy: true},
+	}
+	for i, frame := range rec.Frames {
+		if frame != expected[i] {
+			t.Errorf("frame %d: expected %+v, got %+v", i, expected[i], frame)
+		}
+	}
+}
+
+func TestParseStackRecordErrors(t *testing.T) {
+	if _, err := ParseStackRecord("main.run()\n\t/src/main.go:1"); !errors.Is(err, errStackHeaderMissing) {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, err := ParseStackRecord("goroutine=1 lane=x\nmain.run()"); !errors.Is(err, errStackFrameInvalid) {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, err := ParseStackRecord("goroutine=1 lane=x\nmain.run()\n\tno line number"); !errors.Is(err, errStackFrameInvalid) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestLogLaneStackHeader(t *testing.T) {
+	prior := SetStackHeader(true)
+	defer SetStackHeader(prior)
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	ll := NewLogLane(nil)
+	ll.SetJourneyId("trip")
+	ll.LogStack("here")
+
+	rec, err := ParseStackRecord(buf.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.GoroutineId == 0 || rec.LaneId != ll.LaneId() || rec.JourneyId != "trip" {
+		t.Errorf("unexpected record %+v", rec)
+	}
+	if len(rec.Frames) == 0 || !strings.HasSuffix(rec.Frames[0].Function, "TestLogLaneStackHeader") {
+		t.Errorf("stack doesn't start at the caller: %+v", rec.Frames)
+	}
+}
+
+func TestParseGoroutineId(t *testing.T) {
+	if id := parseGoroutineId([]byte("goroutine 42 [running]:\nmain.main()")); id != 42 {
+		t.Errorf("unexpected id %d", id)
+	}
+	if id := parseGoroutineId([]byte("garbage")); id != 0 {
+		t.Errorf("unexpected id %d", id)
+	}
+}
@@ -0,0 +1,125 @@
+package lane
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+type (
+	// Operational counters of a lane. The counters of a derived lane are
+	// shared with the lane it was derived from.
+	LaneStats struct {
+		Events       [logLevelMax]int64 // messages logged, indexed by level
+		BytesWritten int64              // bytes written to the output or sent over the network
+		Dropped      int64              // messages lost to a write error or a full queue
+		TeeFailures  int64              // tee calls that panicked
+		LastError    error              // the most recent write, send or tee failure
+		Spool        SpoolStats         // activity of the disk spool, for lanes configured with one
+	}
+
+	// Implemented by lanes that keep operational counters
+	LaneStatsReporter interface {
+		Stats() LaneStats
+	}
+
+	laneCounters struct {
+		events      [logLevelMax]atomic.Int64
+		bytes       atomic.Int64
+		dropped     atomic.Int64
+		teeFailures atomic.Int64
+		lastErr     atomic.Pointer[error]
+	}
+
+	// Output writer that counts the bytes written
+	countingWriter struct {
+		w        io.Writer
+		counters *laneCounters
+	}
+
+	// Output writer that forwards to the standard logger's output
+	stdWriter struct{}
+)
+
+// serializes writes to the standard logger's output, as the standard logger would
+var stdWriterMu sync.Mutex
+
+// Provides the operational counters of [l], or zero values if the lane
+// doesn't keep them.
+func StatsOf(l Lane) (stats LaneStats) {
+	if sr, ok := l.(LaneStatsReporter); ok {
+		stats = sr.Stats()
+	}
+	return
+}
+
+func (lc *laneCounters) countEvent(level LaneLogLevel) {
+	if level >= LogLevelTrace && level < logLevelMax {
+		lc.events[level].Add(1)
+	}
+}
+
+func (lc *laneCounters) setError(err error) {
+	lc.lastErr.Store(&err)
+}
+
+func (lc *laneCounters) snapshot() (stats LaneStats) {
+	for level := range lc.events {
+		stats.Events[level] = lc.events[level].Load()
+	}
+	stats.BytesWritten = lc.bytes.Load()
+	stats.Dropped = lc.dropped.Load()
+	stats.TeeFailures = lc.teeFailures.Load()
+	if err := lc.lastErr.Load(); err != nil {
+		stats.LastError = *err
+	}
+	return
+}
+
+// Adds the delivery counters of a lane's background sender
+func (stats *LaneStats) addDelivery(lc *laneCounters) {
+	delivery := lc.snapshot()
+	stats.BytesWritten += delivery.BytesWritten
+	stats.Dropped += delivery.Dropped
+	if delivery.LastError != nil {
+		stats.LastError = delivery.LastError
+	}
+}
+
+// Calls a tee, counting a panic as a tee failure instead of letting it
+// interrupt the logging call, and providing it as an error
+func (lc *laneCounters) callTee(props loggingProperties, t Lane, logger teeHandler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			lc.teeFailures.Add(1)
+			err = fmt.Errorf("tee %s: %v", t.LaneId(), r)
+			lc.setError(err)
+		}
+	}()
+	logger(props, t.(laneInternal))
+	return
+}
+
+func (cw *countingWriter) Write(p []byte) (n int, err error) {
+	n, err = cw.w.Write(p)
+	cw.counters.bytes.Add(int64(n))
+	if err != nil {
+		cw.counters.dropped.Add(1)
+		cw.counters.setError(err)
+	}
+	return
+}
+
+func (cw *countingWriter) Flush() error {
+	if of, ok := cw.w.(outputFlusher); ok {
+		return of.Flush()
+	}
+	return nil
+}
+
+func (stdWriter) Write(p []byte) (n int, err error) {
+	stdWriterMu.Lock()
+	defer stdWriterMu.Unlock()
+	return stdLogOutput().Write(p)
+}
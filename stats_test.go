@@ -0,0 +1,172 @@
+package lane
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type (
+	// Tee that fails on every INFO message
+	testPanicLane struct {
+		*nullLane
+	}
+
+	// Tee that blocks every INFO message until released
+	testStallLane struct {
+		*nullLane
+		release chan struct{}
+	}
+)
+
+func (pl *testPanicLane) InfoInternal(props loggingProperties, args ...any) {
+	panic("tee is broken")
+}
+
+func (sl *testStallLane) InfoInternal(props loggingProperties, args ...any) {
+	<-sl.release
+}
+
+func TestStatsLogLane(t *testing.T) {
+	skipInRelease(t)
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	ll := NewLogLane(nil)
+	ll.SetLogLevel(LogLevelDebug)
+	child := ll.Derive()
+
+	ll.Trace("not logged")
+	ll.Debug("debug")
+	ll.Info("info")
+	child.Warn("warn")
+	child.Error("error")
+	ll.LogStack("")
+
+	stats := StatsOf(ll)
+	expected := [logLevelMax]int64{0, 1, 1, 1, 1, 0, 0, stats.Events[LogLevelStack]}
+	if stats.Events != expected || stats.Events[LogLevelStack] == 0 {
+		t.Errorf("unexpected events %v", stats.Events)
+	}
+	if stats.BytesWritten != int64(buf.Len()) {
+		t.Errorf("expected %d bytes, got %d", buf.Len(), stats.BytesWritten)
+	}
+	if stats.Dropped != 0 || stats.TeeFailures != 0 || stats.LastError != nil {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	// derived lanes share the counters
+	if StatsOf(child) != stats {
+		t.Error("expected the derived lane to report the same counters")
+	}
+}
+
+func TestStatsTeeFailure(t *testing.T) {
+	ll := NewLogLane(nil)
+	tl := NewTestingLane(nil)
+	pl := &testPanicLane{nullLane: NewNullLane(nil).(*nullLane)}
+	ll.AddTee(pl)
+	ll.AddTee(tl)
+
+	ll.Info("delivered anyway")
+	ll.Warn("not a failure")
+
+	stats := StatsOf(ll)
+	if stats.TeeFailures != 1 || stats.LastError == nil || !strings.Contains(stats.LastError.Error(), "tee is broken") {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if !tl.Contains("delivered anyway") {
+		t.Error("expected the other tee to receive the message")
+	}
+}
+
+func TestTeeFailureDiagnostic(t *testing.T) {
+	var diagnosed []Record
+	ll := NewLogLane(nil, WithDiagnostics(func(err error, rec Record) {
+		if strings.Contains(err.Error(), "tee is broken") {
+			diagnosed = append(diagnosed, rec)
+		}
+	}))
+	ll.AddTee(&testPanicLane{nullLane: NewNullLane(nil).(*nullLane)})
+
+	ll.Info("delivered anyway")
+
+	if len(diagnosed) != 1 || diagnosed[0].Level != LogLevelInfo || diagnosed[0].LaneId != ll.LaneId() {
+		t.Errorf("unexpected diagnostics %+v", diagnosed)
+	}
+}
+
+func TestTeeTimeout(t *testing.T) {
+	var diagnosed []error
+	ll := NewLogLane(nil, WithTeeTimeout(10*time.Millisecond), WithDiagnostics(func(err error, rec Record) {
+		diagnosed = append(diagnosed, err)
+	}))
+	sl := &testStallLane{nullLane: NewNullLane(nil).(*nullLane), release: make(chan struct{})}
+	tl := NewTestingLane(nil)
+	ll.AddTee(sl)
+	ll.AddTee(tl)
+
+	ll.Info("first")
+	ll.Derive().Info("second")
+	close(sl.release)
+
+	stats := StatsOf(ll)
+	if stats.TeeFailures != 1 || stats.Dropped != 1 || stats.LastError == nil || !strings.Contains(stats.LastError.Error(), "timed out") {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if len(diagnosed) != 1 {
+		t.Errorf("unexpected diagnostics %v", diagnosed)
+	}
+	if !tl.VerifyEventText("INFO\tfirst\nINFO\tsecond") {
+		t.Errorf("unexpected events:\n%s", tl.EventsToString())
+	}
+}
+
+func TestStatsDiskLane(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.log")
+	dl, err := NewDiskLane(nil, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dl.Info("parent")
+	child := dl.Derive()
+	child.Info("child")
+	child.Close()
+	dl.Close()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := StatsOf(dl)
+	if stats.BytesWritten != info.Size() || stats.Events[LogLevelInfo] != 2 {
+		t.Errorf("unexpected stats %+v for a file of %d bytes", stats, info.Size())
+	}
+}
+
+func TestStatsNetworkLane(t *testing.T) {
+	fl := NewFluentLane(nil, FluentConfig{Addr: "127.0.0.1:1", MaxRetries: 1, DialTimeout: time.Millisecond})
+	fl.Info("undeliverable")
+	fl.Close()
+
+	stats := StatsOf(fl)
+	if stats.Events[LogLevelInfo] != 1 || stats.Dropped != 1 || stats.LastError == nil || stats.BytesWritten != 0 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestStatsOfUnsupported(t *testing.T) {
+	tl := NewTestingLane(nil)
+	tl.Info("not counted")
+
+	if stats := StatsOf(tl); stats != (LaneStats{}) {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
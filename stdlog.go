@@ -0,0 +1,279 @@
+package lane
+
+import (
+	"context"
+	"io"
+	"log"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+)
+
+type (
+	// Finds the level of a line of text written to a lane's Logger(), or to
+	// the standard logger after HijackStandardLog(), providing the message
+	// without the level marker. Text without a level marker is logged at INFO.
+	LevelParser func(text string) (level LaneLogLevel, message string)
+
+	// Implemented by lanes that have a LevelParser
+	levelParsing interface {
+		parseLevel(text string) (level LaneLogLevel, message string)
+	}
+
+	// Output of the standard logger that forwards each line to a lane
+	stdLogWriter struct {
+		l Lane
+	}
+
+	// A slog handler that forwards records to a lane, rendering the
+	// attributes as logfmt pairs after the message
+	slogHandler struct {
+		l      Lane
+		attrs  []byte // pairs from WithAttrs()
+		prefix string // key prefix from WithGroup()
+	}
+)
+
+// The standard logger's output from before a hijack, used by the lanes that
+// write to the standard logger so their output doesn't loop back into a lane
+var stdOutput atomic.Pointer[io.Writer]
+
+// Level words recognized at the start of a standard log message
+var stdLogLevels = map[string]LaneLogLevel{
+	"TRACE":   LogLevelTrace,
+	"DEBUG":   LogLevelDebug,
+	"INFO":    LogLevelInfo,
+	"WARN":    LogLevelWarn,
+	"WARNING": LogLevelWarn,
+	"ERROR":   LogLevelError,
+	"ERR":     LogLevelError,
+	"FATAL":   LogLevelError,
+	"PANIC":   LogLevelError,
+}
+
+// The syslog priorities of sd-daemon "<N>" prefixes
+var syslogPriorityLevels = [8]LaneLogLevel{
+	LogLevelError, // emerg
+	LogLevelError, // alert
+	LogLevelError, // crit
+	LogLevelError, // err
+	LogLevelWarn,  // warning
+	LogLevelInfo,  // notice
+	LogLevelInfo,  // info
+	LogLevelDebug, // debug
+}
+
+// Redirects the output of the standard logger, log.Default(), into [l], so
+// that libraries logging with the log package are correlated with the lane.
+// The level of each message is found by the lane's LevelParser, which is
+// ParseLevelPrefix() unless set with WithLevelParser(). A "FATAL" or "PANIC"
+// message is logged at ERROR, as the log package terminates or panics on its
+// own.
+//
+// Lanes that write to the standard logger, such as a log lane, continue to
+// write to its original output. Call the returned function to restore the
+// standard logger.
+func HijackStandardLog(l Lane) (restore func()) {
+	std := log.Default()
+	priorFlags, priorPrefix := std.Flags(), std.Prefix()
+	restoreOutput := captureStdOutput()
+
+	std.SetOutput(&stdLogWriter{l: l})
+	std.SetFlags(0)
+	std.SetPrefix("")
+
+	return func() {
+		std.SetFlags(priorFlags)
+		std.SetPrefix(priorPrefix)
+		restoreOutput()
+	}
+}
+
+// Sets slog.Default() to a handler that logs into [l]. The slog levels map to
+// the nearest lane level, with levels below DEBUG logged at TRACE, and the
+// attributes follow the message as key=value pairs. As with
+// slog.SetDefault(), the standard logger is also redirected, without level
+// inference. Call the returned function to restore both.
+func HijackSlogDefault(l Lane) (restore func()) {
+	prior := slog.Default()
+	std := log.Default()
+	priorFlags, priorPrefix := std.Flags(), std.Prefix()
+	restoreOutput := captureStdOutput()
+
+	slog.SetDefault(slog.New(&slogHandler{l: l}))
+
+	return func() {
+		slog.SetDefault(prior)
+		std.SetFlags(priorFlags)
+		std.SetPrefix(priorPrefix)
+		restoreOutput()
+	}
+}
+
+// Sets the output used by the lanes that write to the standard logger to the
+// standard logger's current output, unless a hijack already did, and
+// provides the function that restores both
+func captureStdOutput() (restore func()) {
+	output := log.Writer()
+	priorStd := stdOutput.Load()
+	if priorStd == nil {
+		stdOutput.Store(&output)
+	}
+	return func() {
+		log.SetOutput(output)
+		stdOutput.Store(priorStd)
+	}
+}
+
+// Provides the output for lanes that write to the standard logger
+func stdLogOutput() io.Writer {
+	if w := stdOutput.Load(); w != nil {
+		return *w
+	}
+	return log.Writer()
+}
+
+func (slw *stdLogWriter) Write(p []byte) (n int, err error) {
+	logParsedText(slw.l, strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
+
+// Logs [text] to [l] at the level found by the lane's LevelParser
+func logParsedText(l Lane, text string) {
+	var level LaneLogLevel
+	if lp, ok := l.(levelParsing); ok {
+		level, text = lp.parseLevel(text)
+	} else {
+		level, text = ParseLevelPrefix(text)
+	}
+	logAtLevel(l, level, text)
+}
+
+// Applies [parser] to [text], or ParseLevelPrefix() if [parser] is nil
+func (parser LevelParser) parse(text string) (level LaneLogLevel, message string) {
+	if parser == nil {
+		return ParseLevelPrefix(text)
+	}
+	return parser(text)
+}
+
+// The default LevelParser, which recognizes an sd-daemon priority prefix such
+// as "<3>", used by programs logging to journald, and otherwise a leading
+// level word such as "ERROR:", "[warn]" or "DEBUG". A level word is recognized
+// in capitals, or in any case when it is marked as a level, so that a message
+// like "Error opening file" is left intact. Text without either is INFO.
+func ParseLevelPrefix(text string) (level LaneLogLevel, message string) {
+	if len(text) >= 3 && text[0] == '<' && text[2] == '>' && text[1] >= '0' && text[1] <= '7' {
+		return syslogPriorityLevels[text[1]-'0'], strings.TrimLeft(text[3:], " ")
+	}
+	return inferLogLevel(text)
+}
+
+// Separates a leading level word from the message. The word is recognized
+// in capitals, such as "WARN", or in any case when it is marked as a level,
+// such as "error:" or "[debug]", so that a message like "Error opening file"
+// is left intact. Messages without a level word are INFO.
+func inferLogLevel(text string) (level LaneLogLevel, message string) {
+	word, rest, _ := strings.Cut(text, " ")
+	marked := false
+	if inner, found := strings.CutPrefix(word, "["); found {
+		word, marked = strings.CutSuffix(inner, "]")
+	}
+	if trimmed, found := strings.CutSuffix(word, ":"); found {
+		word, marked = trimmed, true
+	}
+
+	level, found := stdLogLevels[strings.ToUpper(word)]
+	if !found || (!marked && word != strings.ToUpper(word)) {
+		return LogLevelInfo, text
+	}
+	rest = strings.TrimLeft(rest, " ")
+	if after, cut := strings.CutPrefix(rest, "- "); cut {
+		rest = after
+	}
+	return level, rest
+}
+
+// Logs [text] to [l] at [level], which must be TRACE through ERROR
+func logAtLevel(l Lane, level LaneLogLevel, text string) {
+	switch level {
+	case LogLevelTrace:
+		l.Trace(text)
+	case LogLevelDebug:
+		l.Debug(text)
+	case LogLevelInfo:
+		l.Info(text)
+	case LogLevelWarn:
+		l.Warn(text)
+	default:
+		l.Error(text)
+	}
+}
+
+// Maps a slog level to the nearest lane level at or below it
+func slogLaneLevel(level slog.Level) LaneLogLevel {
+	switch {
+	case level < slog.LevelDebug:
+		return LogLevelTrace
+	case level < slog.LevelInfo:
+		return LogLevelDebug
+	case level < slog.LevelWarn:
+		return LogLevelInfo
+	case level < slog.LevelError:
+		return LogLevelWarn
+	default:
+		return LogLevelError
+	}
+}
+
+func (sh *slogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	// the lane applies its own level
+	return true
+}
+
+func (sh *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	buf := append([]byte(r.Message), sh.attrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		buf = appendSlogAttr(buf, sh.prefix, a)
+		return true
+	})
+	logAtLevel(sh.l, slogLaneLevel(r.Level), string(buf))
+	return nil
+}
+
+func (sh *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h := *sh
+	h.attrs = append([]byte{}, sh.attrs...)
+	for _, a := range attrs {
+		h.attrs = appendSlogAttr(h.attrs, sh.prefix, a)
+	}
+	return &h
+}
+
+func (sh *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return sh
+	}
+	h := *sh
+	h.prefix = sh.prefix + name + "."
+	return &h
+}
+
+// Renders an attribute as " key=value", with group members as
+// " group.key=value", following the slog rule of omitting empty attributes
+func appendSlogAttr(buf []byte, prefix string, a slog.Attr) []byte {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return buf
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, member := range a.Value.Group() {
+			buf = appendSlogAttr(buf, prefix, member)
+		}
+		return buf
+	}
+	return appendLogfmtPair(buf, logfmtKey(prefix+a.Key), a.Value.String())
+}
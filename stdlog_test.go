@@ -0,0 +1,189 @@
+package lane
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestHijackStandardLog(t *testing.T) {
+	tl := NewTestingLane(nil)
+	restore := HijackStandardLog(tl)
+
+	log.Print("plain message")
+	log.Printf("ERROR: failed after %d tries", 3)
+	log.Println("[warn] disk low")
+	log.Print("debug: cache miss")
+	log.Print("WARNING - slow")
+	log.Print("Error opening file")
+	restore()
+	log.SetOutput(&bytes.Buffer{})
+	defer log.SetOutput(os.Stderr)
+	log.Print("after restore")
+
+	expected := "INFO\tplain message\n" +
+		"ERROR\tfailed after 3 tries\n" +
+		"WARN\tdisk low\n" +
+		"DEBUG\tcache miss\n" +
+		"WARN\tslow\n" +
+		"INFO\tError opening file"
+	if !tl.VerifyEventText(expected) {
+		t.Errorf("unexpected events:\n%s", tl.EventsToString())
+	}
+}
+
+func TestHijackStandardLogRestore(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	log.SetFlags(log.Lshortfile)
+	defer log.SetFlags(log.LstdFlags)
+
+	// a log lane writes to the original output instead of looping back
+	ll := NewLogLane(nil)
+	restore := HijackStandardLog(ll)
+	log.Print("ERROR: from a library")
+	restore()
+
+	if log.Writer() != &buf || log.Flags() != log.Lshortfile {
+		t.Error("the standard logger was not restored")
+	}
+	if text := buf.String(); strings.Count(text, "from a library") != 1 || !strings.Contains(text, "ERROR {") {
+		t.Errorf("unexpected output %q", text)
+	}
+}
+
+func TestHijackSlogDefault(t *testing.T) {
+	prior := slog.Default()
+	tl := NewTestingLane(nil)
+	restore := HijackSlogDefault(tl)
+
+	slog.Info("request done", "status", 200, "path", "/a b")
+	slog.Debug("details", slog.Group("req", "id", 7))
+	slog.Error("failed", "err", "timeout")
+	slog.Log(context.Background(), slog.LevelDebug-4, "very detailed")
+	slog.With("tenant", "acme").WithGroup("job").Warn("slow", "ms", 900)
+	log.Print("from the log package")
+	restore()
+
+	if slog.Default() != prior {
+		t.Error("the slog default was not restored")
+	}
+
+	expected := "INFO\trequest done status=200 path=\"/a b\"\n" +
+		"DEBUG\tdetails req.id=7\n" +
+		"ERROR\tfailed err=timeout\n" +
+		"TRACE\tvery detailed\n" +
+		"WARN\tslow tenant=acme job.ms=900\n" +
+		"INFO\tfrom the log package"
+	if !tl.VerifyEventText(expected) {
+		t.Errorf("unexpected events:\n%s", tl.EventsToString())
+	}
+}
+
+func TestInferLogLevel(t *testing.T) {
+	cases := []struct {
+		text    string
+		level   LaneLogLevel
+		message string
+	}{
+		{"FATAL: out of memory", LogLevelError, "out of memory"},
+		{"[Trace] step", LogLevelTrace, "step"},
+		{"INFO", LogLevelInfo, ""},
+		{"Debugging the thing", LogLevelInfo, "Debugging the thing"},
+		{"", LogLevelInfo, ""},
+	}
+	for _, c := range cases {
+		level, message := inferLogLevel(c.text)
+		if level != c.level || message != c.message {
+			t.Errorf("%q: got %d %q", c.text, level, message)
+		}
+	}
+}
+
+func TestParseLevelPrefix(t *testing.T) {
+	cases := []struct {
+		text    string
+		level   LaneLogLevel
+		message string
+	}{
+		{"<3>disk failed", LogLevelError, "disk failed"},
+		{"<0> panic", LogLevelError, "panic"},
+		{"<4>slow", LogLevelWarn, "slow"},
+		{"<5>notice", LogLevelInfo, "notice"},
+		{"<7>details", LogLevelDebug, "details"},
+		{"<8>not a priority", LogLevelInfo, "<8>not a priority"},
+		{"<3", LogLevelInfo, "<3"},
+		{"[ERROR] failed", LogLevelError, "failed"},
+		{"plain", LogLevelInfo, "plain"},
+	}
+	for _, c := range cases {
+		level, message := ParseLevelPrefix(c.text)
+		if level != c.level || message != c.message {
+			t.Errorf("%q: got %d %q", c.text, level, message)
+		}
+	}
+}
+
+func TestLoggerLevels(t *testing.T) {
+	skipInRelease(t)
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	tl := NewTestingLane(nil)
+	ll := NewLogLane(nil)
+	ll.AddTee(tl)
+
+	ll.Logger().Print("<4>journald warning")
+	ll.Logger().Print("[debug] library detail")
+	tl.Logger().Print("<3>from the testing lane")
+	ll.Derive().Logger().Print("plain")
+
+	expected := "WARN\tjournald warning\n" +
+		"DEBUG\tlibrary detail\n" +
+		"ERROR\tfrom the testing lane\n" +
+		"INFO\tplain"
+	if !tl.VerifyEventText(expected) {
+		t.Errorf("unexpected events:\n%s", tl.EventsToString())
+	}
+}
+
+func TestWithLevelParser(t *testing.T) {
+	skipInRelease(t)
+
+	parser := func(text string) (LaneLogLevel, string) {
+		if rest, found := strings.CutPrefix(text, "E "); found {
+			return LogLevelError, rest
+		}
+		return LogLevelTrace, text
+	}
+
+	for _, l := range []Lane{NewTestingLane(nil, WithLevelParser(parser)), NewLogLane(nil, WithLevelParser(parser))} {
+		var buf bytes.Buffer
+		log.SetOutput(&buf)
+
+		tl := NewTestingLane(nil)
+		l.AddTee(tl)
+		l.SetLogLevel(LogLevelTrace)
+
+		l.Logger().Print("E library failure")
+		l.Derive().Logger().Print("<3>not parsed")
+		restore := HijackStandardLog(l.DeriveReplaceContext(context.Background()))
+		log.Print("E from the log package")
+		restore()
+
+		expected := "ERROR\tlibrary failure\n" +
+			"TRACE\t<3>not parsed\n" +
+			"ERROR\tfrom the log package"
+		if !tl.VerifyEventText(expected) {
+			t.Errorf("%T: unexpected events:\n%s", l, tl.EventsToString())
+		}
+		log.SetOutput(os.Stderr)
+	}
+}
@@ -0,0 +1,387 @@
+package lane
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// A lane that publishes its log events to HTTP clients as a stream of
+	// server-sent events. Lanes derived from it publish to the same clients.
+	StreamServerLane interface {
+		Lane
+
+		// Provides the address the server is listening on.
+		Addr() string
+
+		// Provides the HTTP handler that streams the events, for mounting in
+		// another server.
+		Handler() http.Handler
+	}
+
+	// Settings of a stream server lane
+	StreamServerConfig struct {
+		Addr string // the listening address, such as "localhost:7777"
+
+		// Requires clients to send "Authorization: Bearer <token>", or "" to
+		// allow any client that can connect
+		Token string
+
+		// Decides if a client may connect, after the token check, or nil to
+		// allow it, such as to check a session cookie
+		Authorize func(r *http.Request) bool
+	}
+
+	// The JSON form of a streamed log event
+	StreamRecord struct {
+		Time      time.Time `json:"time"`
+		Level     string    `json:"level"`
+		LaneId    string    `json:"laneId"`
+		JourneyId string    `json:"journeyId,omitempty"`
+		Message   string    `json:"message"`
+		Caller    string    `json:"caller,omitempty"`
+		Stack     []string  `json:"stack,omitempty"`
+	}
+
+	streamServerLane struct {
+		LogLane
+		hub    *streamHub
+		server *http.Server // set only on the lane that started the server
+	}
+
+	// Distributes events to the connected clients
+	streamHub struct {
+		cfg      StreamServerConfig
+		mu       sync.Mutex
+		clients  map[*streamClient]struct{}
+		addr     string
+		counters laneCounters
+	}
+
+	streamClient struct {
+		filter  streamFilter
+		cbor    bool
+		records chan []byte
+	}
+
+	// Per-connection selection of the events to stream
+	streamFilter struct {
+		level     LaneLogLevel
+		match     *regexp.Regexp
+		journeyId string
+		laneId    string
+	}
+)
+
+// Number of events held for a slow client before events are dropped
+const streamClientBacklog = 256
+
+// Time a client has to send its request headers
+const streamReadHeaderTimeout = 10 * time.Second
+
+// Makes a lane that streams its log events to clients that connect to
+// [addr], such as "localhost:7777". Clients select events with query
+// parameters:
+//
+//   - level: the minimum level, such as "warn"
+//   - match: a regular expression the message must match
+//   - journey: a journey ID
+//   - lane: a lane ID
+//   - format: "cbor" for a compact binary stream of the records in place of
+//     server-sent events, read with a CBORDecoder
+//
+// For example, `curl -N 'http://localhost:7777/?journey=abc&level=debug'`.
+// Close the lane to stop the server.
+//
+// Any client that can connect receives the full log, including the journey
+// IDs, so bind [addr] to localhost, or use NewStreamServerLaneWithConfig()
+// to require a token.
+func NewStreamServerLane(ctx OptionalContext, addr string, opts ...LaneOption) (l StreamServerLane, err error) {
+	return NewStreamServerLaneWithConfig(ctx, StreamServerConfig{Addr: addr}, opts...)
+}
+
+// Same as NewStreamServerLane(), with the client authorization of [cfg]. A
+// request from a browser page of another origin is always refused, so that
+// a web site can't read the log of a server bound to localhost. Without a
+// token, a request must also name a loopback host or the listening address
+// in its Host header, so that a site can't reach the server through a DNS
+// name it rebinds to 127.0.0.1.
+func NewStreamServerLaneWithConfig(ctx OptionalContext, cfg StreamServerConfig, opts ...LaneOption) (l StreamServerLane, err error) {
+	hub := &streamHub{cfg: cfg, clients: map[*streamClient]struct{}{}}
+
+	listener, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		return
+	}
+	hub.addr = listener.Addr().String()
+
+	createFn := func(parentLane Lane) (newLane Lane, ll LogLane, writer *log.Logger, err error) {
+		newLane, ll, writer = createStreamServerLane(hub)
+		return
+	}
+
+	newLane, err := NewEmbeddedLogLane(createFn, ctx, opts...)
+	if err != nil {
+		listener.Close()
+		return
+	}
+
+	ssl := newLane.(*streamServerLane)
+	ssl.server = &http.Server{Handler: hub, ReadHeaderTimeout: streamReadHeaderTimeout}
+	go ssl.server.Serve(listener)
+
+	l = ssl
+	return
+}
+
+func createStreamServerLane(hub *streamHub) (newLane Lane, ll LogLane, writer *log.Logger) {
+	ssl := streamServerLane{hub: hub}
+	ll = AllocEmbeddedLogLane()
+	ssl.LogLane = ll
+	newLane = &ssl
+	writer = log.New(io.Discard, "", 0)
+	return
+}
+
+func (ssl *streamServerLane) receiveRecord(rec *Record) {
+	ssl.hub.publish(rec)
+}
+
+func (ssl *streamServerLane) Addr() string {
+	return ssl.hub.addr
+}
+
+func (ssl *streamServerLane) Handler() http.Handler {
+	return ssl.hub
+}
+
+// Stops the server if this lane started it.
+func (ssl *streamServerLane) CloseWithContext(ctx context.Context) (err error) {
+	err = ssl.LogLane.CloseWithContext(ctx)
+	if ssl.server != nil {
+		err = errors.Join(err, ssl.server.Close())
+	}
+	return
+}
+
+// Provides the lane's operational counters, including those of the clients
+func (ssl *streamServerLane) Stats() (stats LaneStats) {
+	stats = ssl.LogLane.Stats()
+	stats.addDelivery(&ssl.hub.counters)
+	return
+}
+
+func (ssl *streamServerLane) lengthConstraint(level LaneLogLevel) int {
+	if lc, ok := ssl.LogLane.(lengthConstrainer); ok {
+		return lc.lengthConstraint(level)
+	}
+	return 0
+}
+
+func (ssl *streamServerLane) treeInfo() laneTreeInfo {
+	if tr, ok := ssl.LogLane.(treeReporter); ok {
+		return tr.treeInfo()
+	}
+	return laneTreeInfo{}
+}
+
+func (hub *streamHub) publish(rec *Record) {
+	sr := StreamRecord{
+		Time:      rec.Time,
+		Level:     levelNames[rec.Level],
+		LaneId:    rec.LaneId,
+		JourneyId: rec.JourneyId,
+		Message:   rec.Message,
+		Caller:    rec.Caller,
+		Stack:     rec.Stack,
+	}
+
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	var jsonData, cborData []byte
+	for client := range hub.clients {
+		if !client.filter.matches(rec.Level, &sr) {
+			continue
+		}
+		var data []byte
+		if client.cbor {
+			if cborData == nil {
+				cborData = CBOREncoder{}.AppendRecord(nil, rec)
+			}
+			data = cborData
+		} else {
+			if jsonData == nil {
+				jsonData, _ = json.Marshal(sr)
+			}
+			data = jsonData
+		}
+		select {
+		case client.records <- data:
+		default:
+			// the client isn't keeping up; logging must not block
+			hub.counters.dropped.Add(1)
+		}
+	}
+}
+
+// Checks the request's origin, token and the authorization hook
+func (hub *streamHub) authorized(r *http.Request) (status int) {
+	if origin := r.Header.Get("Origin"); origin != "" {
+		if u, err := url.Parse(origin); err != nil || u.Host != r.Host {
+			return http.StatusForbidden
+		}
+	}
+
+	if hub.cfg.Token == "" {
+		if !hub.localHost(r.Host) {
+			return http.StatusForbidden
+		}
+	} else {
+		token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(token), []byte(hub.cfg.Token)) != 1 {
+			return http.StatusUnauthorized
+		}
+	}
+
+	if hub.cfg.Authorize != nil && !hub.cfg.Authorize(r) {
+		return http.StatusForbidden
+	}
+	return http.StatusOK
+}
+
+// Indicates whether [host], from a request's Host header, names a loopback
+// address or the listening address
+func (hub *streamHub) localHost(host string) bool {
+	if host == hub.addr {
+		return true
+	}
+	name, _, err := net.SplitHostPort(host)
+	if err != nil {
+		name = host
+	}
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	if name == "localhost" || strings.HasSuffix(name, ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(strings.Trim(name, "[]"))
+	return ip != nil && ip.IsLoopback()
+}
+
+func (hub *streamHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if status := hub.authorized(r); status != http.StatusOK {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	filter, err := parseStreamFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	client := &streamClient{filter: filter, records: make(chan []byte, streamClientBacklog)}
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "sse":
+	case "cbor":
+		client.cbor = true
+	default:
+		http.Error(w, fmt.Sprintf("invalid format %q", format), http.StatusBadRequest)
+		return
+	}
+
+	hub.mu.Lock()
+	hub.clients[client] = struct{}{}
+	hub.mu.Unlock()
+
+	defer func() {
+		hub.mu.Lock()
+		delete(hub.clients, client)
+		hub.mu.Unlock()
+	}()
+
+	if client.cbor {
+		w.Header().Set("Content-Type", "application/cbor-seq")
+	} else {
+		w.Header().Set("Content-Type", "text/event-stream")
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case data := <-client.records:
+			var n int
+			var err error
+			if client.cbor {
+				n, err = w.Write(data)
+			} else {
+				n, err = fmt.Fprintf(w, "data: %s\n\n", data)
+			}
+			hub.counters.bytes.Add(int64(n))
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func parseStreamFilter(r *http.Request) (filter streamFilter, err error) {
+	q := r.URL.Query()
+
+	if levelText := q.Get("level"); levelText != "" {
+		var found bool
+		if filter.level, found = parseLevelName(levelText); !found {
+			err = fmt.Errorf("invalid level %q", levelText)
+			return
+		}
+	}
+
+	if match := q.Get("match"); match != "" {
+		if filter.match, err = regexp.Compile(match); err != nil {
+			return
+		}
+	}
+
+	filter.journeyId = q.Get("journey")
+	filter.laneId = q.Get("lane")
+	return
+}
+
+func (filter *streamFilter) matches(level LaneLogLevel, rec *StreamRecord) bool {
+	if level < filter.level {
+		return false
+	}
+	if filter.journeyId != "" && rec.JourneyId != filter.journeyId {
+		return false
+	}
+	if filter.laneId != "" && rec.LaneId != filter.laneId {
+		return false
+	}
+	if filter.match != nil && !filter.match.MatchString(rec.Message) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,277 @@
+package lane
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Connects to the stream and provides the records it sends
+func testStreamConnect(t *testing.T, ssl StreamServerLane, query string) (records chan StreamRecord, stop func()) {
+	resp, err := http.Get("http://" + ssl.Addr() + "/?" + query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+
+	records = make(chan StreamRecord, 100)
+	go func() {
+		defer close(records)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			data, found := strings.CutPrefix(scanner.Text(), "data: ")
+			if !found {
+				continue
+			}
+			var rec StreamRecord
+			if err := json.Unmarshal([]byte(data), &rec); err == nil {
+				records <- rec
+			}
+		}
+	}()
+
+	stop = func() { resp.Body.Close() }
+	return
+}
+
+// Waits until the hub has the expected number of clients
+func testStreamWaitClients(t *testing.T, ssl StreamServerLane, count int) {
+	hub := ssl.(*streamServerLane).hub
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		hub.mu.Lock()
+		n := len(hub.clients)
+		hub.mu.Unlock()
+		if n == count {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("clients did not connect")
+}
+
+func testStreamNext(t *testing.T, records chan StreamRecord) StreamRecord {
+	select {
+	case rec := <-records:
+		return rec
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for record")
+	}
+	return StreamRecord{}
+}
+
+func TestStreamServerLane(t *testing.T) {
+	skipInRelease(t)
+
+	ssl, err := NewStreamServerLane(nil, "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ssl.Close()
+
+	all, stopAll := testStreamConnect(t, ssl, "")
+	defer stopAll()
+	warn, stopWarn := testStreamConnect(t, ssl, "level=warn")
+	defer stopWarn()
+	matched, stopMatched := testStreamConnect(t, ssl, "match=^order%20[0-9]%2B$&journey=j1")
+	defer stopMatched()
+	testStreamWaitClients(t, ssl, 3)
+
+	child := ssl.Derive()
+	child.SetJourneyId("j1")
+
+	ssl.Trace("starting")
+	child.Info("order 42")
+	child.Info("order abc")
+	ssl.Warnf("low %s", "disk")
+
+	rec := testStreamNext(t, all)
+	if rec.Level != "TRACE" || rec.Message != "starting" || rec.LaneId != ssl.LaneId() {
+		t.Errorf("unexpected record %v", rec)
+	}
+	rec = testStreamNext(t, all)
+	if rec.Level != "INFO" || rec.Message != "order 42" || rec.JourneyId != "j1" || rec.LaneId != child.LaneId() {
+		t.Errorf("unexpected record %v", rec)
+	}
+
+	rec = testStreamNext(t, warn)
+	if rec.Level != "WARN" || rec.Message != "low disk" {
+		t.Errorf("unexpected record %v", rec)
+	}
+
+	rec = testStreamNext(t, matched)
+	if rec.Message != "order 42" {
+		t.Errorf("unexpected record %v", rec)
+	}
+	select {
+	case rec = <-matched:
+		t.Errorf("unexpected record %v", rec)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestStreamServerLaneBadFilter(t *testing.T) {
+	ssl, err := NewStreamServerLane(nil, "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ssl.Close()
+
+	for _, query := range []string{"level=loud", "match=%5B", "format=xml"} {
+		resp, err := http.Get("http://" + ssl.Addr() + "/?" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("expected bad request for %s, got %d", query, resp.StatusCode)
+		}
+	}
+}
+
+func TestStreamServerLaneAuthorization(t *testing.T) {
+	ssl, err := NewStreamServerLaneWithConfig(nil, StreamServerConfig{
+		Addr:      "127.0.0.1:0",
+		Token:     "secret",
+		Authorize: func(r *http.Request) bool { return r.URL.Query().Get("lane") != "hidden" },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ssl.Close()
+
+	get := func(query string, headers map[string]string) int {
+		req, _ := http.NewRequest(http.MethodGet, "http://"+ssl.Addr()+"/?"+query, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	bearer := map[string]string{"Authorization": "Bearer secret"}
+	cases := []struct {
+		query   string
+		headers map[string]string
+		status  int
+	}{
+		{"", nil, http.StatusUnauthorized},
+		{"", map[string]string{"Authorization": "Bearer wrong"}, http.StatusUnauthorized},
+		{"lane=hidden", bearer, http.StatusForbidden},
+		{"", map[string]string{"Authorization": "Bearer secret", "Origin": "http://evil.example"}, http.StatusForbidden},
+		{"", map[string]string{"Authorization": "Bearer secret", "Origin": "http://" + ssl.Addr()}, http.StatusOK},
+		{"", bearer, http.StatusOK},
+	}
+	for _, c := range cases {
+		if status := get(c.query, c.headers); status != c.status {
+			t.Errorf("%q %v: expected %d, got %d", c.query, c.headers, c.status, status)
+		}
+	}
+}
+
+func TestStreamServerLaneHost(t *testing.T) {
+	ssl, err := NewStreamServerLane(nil, "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ssl.Close()
+	if ssl.(*streamServerLane).server.ReadHeaderTimeout == 0 {
+		t.Error("expected a header timeout")
+	}
+
+	_, port, _ := net.SplitHostPort(ssl.Addr())
+	cases := []struct {
+		host   string
+		status int
+	}{
+		{ssl.Addr(), http.StatusOK},
+		{"localhost:" + port, http.StatusOK},
+		{"[::1]:" + port, http.StatusOK},
+		// a DNS name rebound to 127.0.0.1
+		{"rebound.example:" + port, http.StatusForbidden},
+		{"10.1.2.3:" + port, http.StatusForbidden},
+	}
+	for _, c := range cases {
+		req, _ := http.NewRequest(http.MethodGet, "http://"+ssl.Addr()+"/?level=fatal", nil)
+		req.Host = c.host
+		ctx, cancel := context.WithCancel(context.Background())
+		resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != c.status {
+			t.Errorf("%s: expected %d, got %d", c.host, c.status, resp.StatusCode)
+		}
+		cancel()
+		resp.Body.Close()
+	}
+}
+
+func TestStreamServerLaneCBOR(t *testing.T) {
+	ssl, err := NewStreamServerLane(nil, "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ssl.Close()
+
+	resp, err := http.Get("http://" + ssl.Addr() + "/?format=cbor&level=info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "application/cbor-seq" {
+		t.Errorf("unexpected content type %q", ct)
+	}
+	testStreamWaitClients(t, ssl, 1)
+
+	ssl.SetMetadata("user", "u1")
+	ssl.Trace("not sent")
+	ssl.Infof("order %d", 1)
+	ssl.Error("failed")
+
+	cd := NewCBORDecoder(resp.Body)
+	for _, expected := range []string{"INFO\torder 1", "ERROR\tfailed"} {
+		rec, err := cd.Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text := levelNames[rec.Level] + "\t" + rec.Message; text != expected || rec.LaneId != ssl.LaneId() {
+			t.Errorf("unexpected record %+v", rec)
+		}
+		if rec.Fields["user"] != "u1" {
+			t.Errorf("the metadata is missing from %+v", rec)
+		}
+	}
+}
+
+func TestStreamServerLaneTee(t *testing.T) {
+	ssl, err := NewStreamServerLane(nil, "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ssl.Close()
+
+	records, stop := testStreamConnect(t, ssl, "")
+	defer stop()
+	testStreamWaitClients(t, ssl, 1)
+
+	tl := NewTestingLane(nil)
+	tl.AddTee(ssl)
+	tl.Error("teed")
+
+	rec := testStreamNext(t, records)
+	if rec.Level != "ERROR" || rec.Message != "teed" || rec.LaneId != tl.LaneId() {
+		t.Errorf("unexpected record %v", rec)
+	}
+}
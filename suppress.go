@@ -0,0 +1,139 @@
+package lane
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// Mutes the messages logged from a source location, such as a noisy log
+	// call in a third-party package. A rule needs a File or a Pattern; the
+	// fields that are set must all match.
+	SuppressRule struct {
+		File    string    // the source file, matched by its trailing path elements, such as "client.go" or "pkg/client.go"
+		Line    int       // the line in File, or 0 for any line
+		Pattern string    // a regular expression matched against the message text, or "" for any text
+		Until   time.Time // the time the rule expires, or zero for no expiration
+	}
+
+	// A rule in effect
+	suppressor struct {
+		rule      SuppressRule
+		pattern   *regexp.Regexp
+		activated atomic.Bool
+	}
+)
+
+var (
+	// the rules of SetSuppressionRules(), or nil when there are none
+	suppressors atomic.Pointer[[]*suppressor]
+
+	errSuppressRule = errors.New("a suppression rule needs a file or a pattern")
+)
+
+// Replaces the process-wide suppression rules, which mute the messages logged
+// from known noisy sites without changing any lane's log level. A nil or
+// empty [rules] removes them. Fatal messages and stack traces are never
+// suppressed. The first time a rule mutes a message, the lane that received
+// the message logs a WARN audit message naming the rule.
+//
+// The call site is found the same way as SetCallerInfo(), skipping the frames
+// of the lane implementation and the log package, and is cached per program
+// counter.
+func SetSuppressionRules(rules []SuppressRule) error {
+	if len(rules) == 0 {
+		suppressors.Store(nil)
+		return nil
+	}
+
+	list := make([]*suppressor, 0, len(rules))
+	for _, rule := range rules {
+		if rule.File == "" && rule.Pattern == "" {
+			return errSuppressRule
+		}
+		s := &suppressor{rule: rule}
+		if rule.Pattern != "" {
+			re, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return fmt.Errorf("suppression rule pattern: %w", err)
+			}
+			s.pattern = re
+		}
+		list = append(list, s)
+	}
+	suppressors.Store(&list)
+	return nil
+}
+
+// Checks if any suppression rules are set, so that the caller can skip
+// preparing the message text
+func suppressionActive() bool {
+	return suppressors.Load() != nil
+}
+
+// Checks if a message at [level] with [text] is muted by a suppression rule.
+// The [audit] text is provided the first time a rule mutes a message.
+func checkSuppression(level LaneLogLevel, text string) (suppressed bool, audit string) {
+	list := suppressors.Load()
+	if list == nil || level >= LogLevelFatal {
+		return
+	}
+
+	var site *callSite // found on first use
+	now := time.Now()
+	for _, s := range *list {
+		if !s.rule.Until.IsZero() && now.After(s.rule.Until) {
+			continue
+		}
+		if s.rule.File != "" {
+			if site == nil {
+				found := findCallSite()
+				site = &found
+			}
+			if !s.matchesSite(*site) {
+				continue
+			}
+		}
+		if s.pattern != nil && !s.pattern.MatchString(text) {
+			continue
+		}
+
+		if !s.activated.Swap(true) {
+			audit = "log suppression rule activated: " + s.rule.String()
+		}
+		suppressed = true
+		return
+	}
+	return
+}
+
+func (s *suppressor) matchesSite(site callSite) bool {
+	if s.rule.Line != 0 && s.rule.Line != site.line {
+		return false
+	}
+	return site.file == s.rule.File || strings.HasSuffix(site.file, "/"+s.rule.File)
+}
+
+// Describes the rule, such as `client.go:42 =~ "retrying" until 2024-05-01T00:00:00Z`
+func (rule SuppressRule) String() string {
+	var parts []string
+	if rule.File != "" {
+		site := rule.File
+		if rule.Line != 0 {
+			site += ":" + strconv.Itoa(rule.Line)
+		}
+		parts = append(parts, site)
+	}
+	if rule.Pattern != "" {
+		parts = append(parts, "=~ "+strconv.Quote(rule.Pattern))
+	}
+	if !rule.Until.IsZero() {
+		parts = append(parts, "until "+rule.Until.Format(time.RFC3339))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,78 @@
+package lane
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSuppressionRules(t *testing.T) {
+	defer SetSuppressionRules(nil)
+
+	tl := NewTestingLane(nil)
+	tl.SetPanicHandler(func() {})
+
+	_, _, line, _ := runtime.Caller(0)
+	err := SetSuppressionRules([]SuppressRule{
+		{File: "suppress_test.go", Line: line + 9},
+		{Pattern: "^retrying"},
+		{File: "go-lane/suppress_test.go", Pattern: "expired", Until: time.Now().Add(-time.Second)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tl.Warn("noisy site")
+	tl.Info("retrying in 1s")
+	tl.Infof("retrying in %ds", 2)
+	tl.Info("expired rule")
+	tl.Fatal("retrying is never suppressed when fatal")
+
+	expected := "WARN\tlog suppression rule activated: suppress_test.go:" + strconv.Itoa(line+9) + "\n" +
+		"WARN\tlog suppression rule activated: =~ \"^retrying\"\n" +
+		"INFO\texpired rule\n" +
+		"FATAL\tretrying is never suppressed when fatal"
+	if !tl.VerifyEventText(expected) {
+		t.Errorf("unexpected events:\n%s", tl.EventsToString())
+	}
+}
+
+func TestSuppressionRulesOutput(t *testing.T) {
+	defer SetSuppressionRules(nil)
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	if err := SetSuppressionRules([]SuppressRule{{File: "suppress_test.go", Pattern: "cache miss"}}); err != nil {
+		t.Fatal(err)
+	}
+	ll := NewLogLane(nil)
+	ll.Info("cache miss 1")
+	ll.Info("cache miss 2")
+	ll.Logger().Print("cache miss 3")
+	ll.Info("cache hit")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `WARN {`) || !strings.HasSuffix(lines[0], `} log suppression rule activated: suppress_test.go =~ "cache miss"`) || !strings.HasSuffix(lines[1], "cache hit") {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+}
+
+func TestSuppressionRuleErrors(t *testing.T) {
+	defer SetSuppressionRules(nil)
+
+	if err := SetSuppressionRules([]SuppressRule{{Line: 5}}); err != errSuppressRule {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := SetSuppressionRules([]SuppressRule{{Pattern: "("}}); err == nil {
+		t.Error("expected a pattern error")
+	}
+	if suppressionActive() {
+		t.Error("invalid rules were applied")
+	}
+}
@@ -12,6 +12,8 @@ import (
 )
 
 func TestTeeLog(t *testing.T) {
+	skipInRelease(t)
+
 	tl := NewTestingLane(context.Background())
 
 	ll := NewLogLane(context.Background())
@@ -65,6 +67,8 @@ func TestTeeLog(t *testing.T) {
 }
 
 func TestTeeLogDerive(t *testing.T) {
+	skipInRelease(t)
+
 	tl := NewTestingLane(context.Background())
 
 	ll := NewLogLane(context.Background())
@@ -116,6 +120,8 @@ func TestTeeLogDerive(t *testing.T) {
 }
 
 func TestTeeLogDouble(t *testing.T) {
+	skipInRelease(t)
+
 	tl1 := NewTestingLane(context.Background())
 	tl2 := NewTestingLane(context.Background())
 
@@ -171,6 +177,8 @@ func TestTeeLogDouble(t *testing.T) {
 }
 
 func TestTeeNull(t *testing.T) {
+	skipInRelease(t)
+
 	tl := NewTestingLane(context.Background())
 
 	nl := NewNullLane(context.Background())
@@ -224,6 +232,8 @@ func TestTeeNull(t *testing.T) {
 }
 
 func TestTeeNullDerive(t *testing.T) {
+	skipInRelease(t)
+
 	tl := NewTestingLane(context.Background())
 
 	nl := NewNullLane(context.Background())
@@ -275,6 +285,8 @@ func TestTeeNullDerive(t *testing.T) {
 }
 
 func TestTeeNullDouble(t *testing.T) {
+	skipInRelease(t)
+
 	tl1 := NewTestingLane(context.Background())
 	tl2 := NewTestingLane(context.Background())
 
@@ -432,6 +444,8 @@ func TestTeeTestDerive1(t *testing.T) {
 }
 
 func TestTeeTestDerive2(t *testing.T) {
+	skipInRelease(t)
+
 	tlv := NewTestingLane(context.Background())
 
 	tl := NewNullLane(context.Background())
@@ -491,6 +505,8 @@ func TestTeeTestDerive2(t *testing.T) {
 }
 
 func TestTeeTestDerive3(t *testing.T) {
+	skipInRelease(t)
+
 	tlv := NewTestingLane(context.Background())
 
 	tl := NewLogLane(context.Background())
@@ -550,6 +566,8 @@ func TestTeeTestDerive3(t *testing.T) {
 }
 
 func TestTeeTestDerive4(t *testing.T) {
+	skipInRelease(t)
+
 	tlv := NewTestingLane(context.Background())
 
 	tl, err := NewDiskLane(context.Background(), "test.log")
@@ -721,15 +739,15 @@ func TestTeeRetainLaneIds(t *testing.T) {
 	tl.Info("test2")
 
 	ptl := tl.(*testingLane)
-	if len(ptl.Events) != 2 {
+	if len(ptl.Events()) != 2 {
 		t.Fatal("expected 2 events")
 	}
 
-	if ptl.Events[0].Id != ll.LaneId() {
+	if ptl.Events()[0].Id != ll.LaneId() {
 		t.Error("wrong ID for event 1")
 	}
 
-	if ptl.Events[1].Id != tl.LaneId() {
+	if ptl.Events()[1].Id != tl.LaneId() {
 		t.Error("wrong ID for event 2")
 	}
 }
@@ -964,3 +982,50 @@ func TestLogLaneRetainIdsWithObjectLog(t *testing.T) {
 		t.Error("not the expected log output")
 	}
 }
+
+func TestTeeObjects(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer func() { log.SetOutput(os.Stderr) }()
+
+	obj := map[string]any{"ids": []int{1, 2, 3}}
+
+	ll := NewLogLane(nil)
+	ll.SetObjectOptions(LogObjectOpt{TeeObjects: true})
+	nl := NewNullLane(nil)
+	tl := NewTestingLane(nil)
+	tl.SetObjectOptions(LogObjectOpt{MaxElements: 1})
+	ll.AddTee(nl)
+	nl.AddTee(tl)
+
+	ll.InfoObject("payload", obj)
+	ll.Info("text")
+
+	events := tl.Events()
+	if len(events) != 2 || events[0].Message != `payload: {"ids":[1,"(2 more)"]}` || events[1].Message != "text" {
+		t.Fatalf("unexpected events:\n%s", tl.EventsToString())
+	}
+	if events[0].Object == nil || events[0].Object.(map[string]any)["ids"] == nil || events[1].Object != nil {
+		t.Errorf("the original object wasn't received: %v", events[0].Object)
+	}
+	if !strings.Contains(buf.String(), `INFO {`+trimLaneId(ll.LaneId())+`} payload: {"ids":[1,2,3]}`) {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+}
+
+func TestTeeObjectsDefault(t *testing.T) {
+	tl := NewTestingLane(nil)
+	tl2 := NewTestingLane(nil)
+	tl.AddTee(tl2)
+
+	tl.WarnObject("payload", []int{1})
+	LogObjectWithOpt(tl, LogLevelError, "per call", []int{2}, LogObjectOpt{TeeObjects: true})
+
+	events := tl2.Events()
+	if len(events) != 2 || events[0].Object != nil || events[1].Level != "ERROR" || events[1].Message != "per call: [2]" {
+		t.Fatalf("unexpected events:\n%s", tl2.EventsToString())
+	}
+	if a, is := events[1].Object.([]int); !is || a[0] != 2 {
+		t.Errorf("unexpected object %v", events[1].Object)
+	}
+}
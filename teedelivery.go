@@ -0,0 +1,170 @@
+package lane
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// The tees that are still taking a message after their timeout, shared
+	// by a lane and its derivations
+	busyTeeSet struct {
+		mu   sync.Mutex
+		busy map[Lane]struct{}
+	}
+
+	// The call stack of a logging call whose tee runs on a worker goroutine
+	teeOrigin struct {
+		pcs         []uintptr
+		goroutineId uint64
+	}
+)
+
+var (
+	// worker goroutine ID -> *teeOrigin, so that a tee capturing a stack or
+	// a caller finds the logging call instead of the worker
+	teeOrigins sync.Map
+
+	// the number of entries in teeOrigins, so that captures can skip the
+	// lookup when no tee runs on a worker
+	teeOriginCount atomic.Int32
+)
+
+// Limits the time a log lane type, and the lanes derived from it, wait for
+// a tee to take a message to [timeout], so that a slow tee, such as one
+// sending over a network, can't stall the logging call. A tee that takes
+// longer is left to finish in the background, and it is skipped, counting
+// the message as dropped, until it does. The timeout is reported as a tee
+// failure in Stats() and to the WithDiagnostics() handler. Other lane types
+// ignore it.
+//
+// With a timeout, the tees receive messages on a worker goroutine. The stack
+// traces and caller info they capture are those of the logging call.
+func WithTeeTimeout(timeout time.Duration) LaneOption {
+	return func(o *laneOptions) {
+		o.teeTimeout = timeout
+	}
+}
+
+func (ll *logLane) SetTeeTimeout(timeout time.Duration) (prior time.Duration) {
+	return time.Duration(ll.teeTimeout.Swap(int64(timeout)))
+}
+
+// Calls a tee with a message at [level], reporting a panic or a timeout to
+// the diagnostics handler
+func (ll *logLane) callTee(props loggingProperties, level LaneLogLevel, t Lane, logger teeHandler) {
+	timeout := time.Duration(ll.teeTimeout.Load())
+	if timeout <= 0 {
+		if err := ll.counters.callTee(props, t, logger); err != nil {
+			ll.reportTeeFailure(props, level, err)
+		}
+		return
+	}
+
+	if !ll.busyTees.claim(t) {
+		ll.counters.dropped.Add(1)
+		return
+	}
+
+	// the stack must be captured here, before the worker takes over
+	origin := captureTeeOrigin()
+
+	done := make(chan error, 1)
+	go func() {
+		id := currentGoroutineId()
+		teeOrigins.Store(id, origin)
+		teeOriginCount.Add(1)
+		defer func() {
+			teeOrigins.Delete(id)
+			teeOriginCount.Add(-1)
+		}()
+
+		err := ll.counters.callTee(props, t, logger)
+		ll.busyTees.release(t)
+		done <- err
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			ll.reportTeeFailure(props, level, err)
+		}
+	case <-timer.C:
+		err := fmt.Errorf("tee %s timed out after %v", t.LaneId(), timeout)
+		ll.counters.teeFailures.Add(1)
+		ll.counters.setError(err)
+		ll.reportTeeFailure(props, level, err)
+	}
+}
+
+func (ll *logLane) reportTeeFailure(props loggingProperties, level LaneLogLevel, err error) {
+	ll.reportDiagnostic(err, Record{Time: ll.now(), LaneId: props.laneId, JourneyId: props.journeyId, Category: props.category, ParentId: props.parentId, Level: level})
+}
+
+// Marks [t] as taking a message, reporting false if it is still taking an
+// earlier one
+func (bts *busyTeeSet) claim(t Lane) bool {
+	bts.mu.Lock()
+	defer bts.mu.Unlock()
+
+	if _, busy := bts.busy[t]; busy {
+		return false
+	}
+	if bts.busy == nil {
+		bts.busy = map[Lane]struct{}{}
+	}
+	bts.busy[t] = struct{}{}
+	return true
+}
+
+func (bts *busyTeeSet) release(t Lane) {
+	bts.mu.Lock()
+	defer bts.mu.Unlock()
+	delete(bts.busy, t)
+}
+
+// Captures the call stack of the logging call for a tee worker, or passes on
+// the origin of the current worker for a tee of a tee
+func captureTeeOrigin() *teeOrigin {
+	if origin := findTeeOrigin(); origin != nil {
+		return origin
+	}
+
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(3, pcs)
+	return &teeOrigin{pcs: pcs[:n], goroutineId: currentGoroutineId()}
+}
+
+// Provides the origin of the tee that runs on the current goroutine, or nil
+// if the goroutine isn't a tee worker
+func findTeeOrigin() *teeOrigin {
+	if teeOriginCount.Load() == 0 {
+		return nil
+	}
+	if origin, found := teeOrigins.Load(currentGoroutineId()); found {
+		return origin.(*teeOrigin)
+	}
+	return nil
+}
+
+// Renders the origin's stack in the form of runtime.Stack()
+func (to *teeOrigin) stackText() []byte {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "goroutine %d [running]:\n", to.goroutineId)
+	frames := runtime.CallersFrames(to.pcs)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&sb, "%s(...)\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return []byte(sb.String())
+}
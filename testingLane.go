@@ -3,8 +3,9 @@ package lane
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
-	"runtime"
+	"regexp"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -13,30 +14,58 @@ import (
 
 type (
 	LaneEvent struct {
-		Id      string
-		Level   string
-		Message string
+		Id        string
+		JourneyId string // the journey ID of the lane that logged the message
+		Level     string
+		Category  string // the category of the lane, from Category(), or ""
+		Depth     int    // the nesting level of the lane, from Indent()
+		Message   string
+		Caller    string // file:line and function of the logging call, if SetCallerInfo() is enabled
+		Object    any    // the original object of an object log made with TeeObjects
 	}
 
 	testingLane struct {
 		mu sync.Mutex
 		context.Context
 		MetadataStore
-		Events               []*LaneEvent
+		objectOptStore
+		lengthConstraintStore
+		lifecycleStore
+		laneTreeStore
+		errorStore
+		stackTraceStore
+		callerInfoStore
+		logLimitStore
+		events               []*LaneEvent
 		tlog                 *log.Logger
 		level                LaneLogLevel
-		stackTrace           []atomic.Bool
 		testingStack         atomic.Bool
 		tees                 []Lane
 		parent               *testingLane
 		wantDescendantEvents bool
+		descendantFilters    []DescendantFilter
+		maxEvents            int
+		limitPolicy          EventLimitPolicy
+		dropped              int64
 		onPanic              Panic
 		journeyId            string
-		maxLength            atomic.Int32
+		traceCtx             traceContext
+		category             string
+		indent               int
+		idGen                LaneIdGenerator
+		levelParser          LevelParser
 	}
 
 	testingLaneId string
 
+	// Selects the descendant events a testing lane captures, see
+	// WantDescendantEvents()
+	DescendantFilter func(level LaneLogLevel, message string) bool
+
+	// Selects what a testing lane does with a message that would exceed its
+	// SetMaxEvents() limit
+	EventLimitPolicy int32
+
 	testingLogWriter struct {
 		tl *testingLane
 	}
@@ -48,7 +77,8 @@ type (
 		// Renders all of the captured log messages into a single string.
 		EventsToString() string
 
-		// Checks for log messages to exactly match the specified events.
+		// Checks for log messages to exactly match the specified events, by
+		// level and message.
 		VerifyEvents(eventList []*LaneEvent) (match bool)
 
 		// Checks for log messages to match the specified events. Ignores
@@ -59,9 +89,20 @@ type (
 		// The descriptor is a simple format where log messages are separated
 		// by line breaks, and each line is "SEVERITY\tExpected message". The
 		// other details that get logged, such as timestamp and correlation ID,
-		// are ignored.
+		// are ignored, unless the line is "SEVERITY\t{laneId}\tExpected message"
+		// or "SEVERITY\t{journeyId:laneId}\tExpected message", where "*" in
+		// place of an ID matches any ID.
 		VerifyEventText(eventText string) (match bool)
 
+		// Same as VerifyEvents, but a mismatch is reported to [t] with a diff of
+		// the expected and captured events, starting with the first index that
+		// differs.
+		VerifyEventsT(t TestingT, eventList []*LaneEvent) (match bool)
+
+		// Same as VerifyEventText, but a mismatch is reported to [t] with a diff
+		// of the expected and captured events.
+		VerifyEventTextT(t TestingT, eventText string) (match bool)
+
 		// Similar to VerifyEventText, except that lines that do not match
 		// are ignored.
 		FindEventText(eventText string) (found bool)
@@ -69,8 +110,42 @@ type (
 		// Checks if the string occurs anywhere in the logged text
 		Contains(text string) (found bool)
 
+		// Provides a copy of the captured log messages, oldest first. Safe to
+		// call while other goroutines are logging.
+		Events() []LaneEvent
+
+		// Returns the number of captured log messages.
+		EventCount() int
+
+		// Discards the captured log messages and the dropped count, such as
+		// between test phases.
+		Reset()
+
+		// Limits the number of captured log messages to [n], or removes the
+		// limit if [n] is zero, so that a soak test doesn't exhaust memory.
+		// The [policy] selects what happens to a message over the limit.
+		// Captured messages over a new limit are discarded, oldest first.
+		// Derived lanes inherit the limit.
+		SetMaxEvents(n int, policy EventLimitPolicy) (prior int)
+
+		// Returns the number of log messages discarded by the SetMaxEvents()
+		// limit.
+		DroppedEvents() int64
+
+		// Writes the captured log messages to a golden file at [path], in the
+		// VerifyEventText() form, creating its directory if necessary.
+		WriteGolden(path string) error
+
+		// Compares the captured log messages with the golden file at [path],
+		// reporting the first difference to [t]. The [normalizers], such as
+		// MaskTimestamps, are applied to both sides before the comparison.
+		VerifyGolden(t TestingT, path string, normalizers ...GoldenNormalizer) (match bool)
+
 		// Controls whether to capture child lane activity (wanted=true) or not.
-		WantDescendantEvents(wanted bool) (prior bool)
+		// When [filters] are given, a descendant's message is captured only if
+		// every filter accepts it, such as DescendantLevel(LogLevelWarn) to
+		// keep a long test of a large lane tree from holding every message.
+		WantDescendantEvents(wanted bool, filters ...DescendantFilter) (prior bool)
 
 		// Retrieves metadata
 		GetMetadata(key string) string
@@ -83,19 +158,31 @@ type (
 
 const testing_lane_id testingLaneId = "testing_lane"
 
-func NewTestingLane(ctx OptionalContext) TestingLane {
-	return deriveTestingLane(ctx, nil, []Lane{})
+const (
+	DropOldest EventLimitPolicy = iota // discards the oldest captured message to make room
+	DropNewest                         // discards the new message
+	FailTest                           // panics, failing the test
+)
+
+func NewTestingLane(ctx OptionalContext, opts ...LaneOption) TestingLane {
+	lo := applyLaneOptions(opts)
+	tl := deriveTestingLane(ctx, nil, []Lane{}, lo.idGen)
+	tl.(*testingLane).teeClose = lo.teeClose
+	tl.(*testingLane).levelParser = lo.levelParser
+	tl.(*testingLane).setDiagnosticHandler(lo.diagnostics)
+	lo.applySettings(tl)
+	return tl
 }
 
-func deriveTestingLane(ctx context.Context, parent *testingLane, tees []Lane) TestingLane {
+func deriveTestingLane(ctx context.Context, parent *testingLane, tees []Lane, idGen LaneIdGenerator) TestingLane {
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
 	tl := testingLane{
-		stackTrace: make([]atomic.Bool, logLevelMax),
-		parent:     parent,
-		tees:       tees,
+		parent: parent,
+		tees:   tees,
+		idGen:  idGen,
 	}
 	tl.EnableStackTrace(LogLevelStack, true)
 	tl.SetPanicHandler(nil)
@@ -110,20 +197,57 @@ func deriveTestingLane(ctx context.Context, parent *testingLane, tees []Lane) Te
 	if parent != nil {
 		tl.onPanic = parent.onPanic
 		tl.wantDescendantEvents = parent.wantDescendantEvents
+		tl.descendantFilters = parent.descendantFilters
+		tl.maxEvents = parent.maxEvents
+		tl.limitPolicy = parent.limitPolicy
 		tl.journeyId = parent.journeyId
+		tl.traceCtx = parent.traceCtx
+		tl.category = parent.category
+		tl.indent = parent.indent
+		tl.levelParser = parent.levelParser
 	}
 
-	tl.Context = context.WithValue(ctx, testing_lane_id, makeLaneId())
+	tl.Context = context.WithValue(ctx, testing_lane_id, makeLaneId(idGen))
 
 	copyConfigToDerivation(&tl, parent)
+
+	if parent != nil {
+		tl.attachTree(&tl, &parent.laneTreeStore, time.Now())
+		tl.inheritHooks(&parent.lifecycleStore)
+		tl.inheritErrorHandler(&parent.errorStore)
+		tl.inheritMetadata(&parent.MetadataStore)
+		tl.inheritLimits(&parent.logLimitStore)
+		parent.runDeriveHooks(parent, &tl)
+	} else {
+		tl.attachTree(&tl, nil, time.Now())
+	}
 	return &tl
 }
 
 func (tl *testingLane) SetJourneyId(id string) {
 	tl.mu.Lock()
-	defer tl.mu.Unlock()
 	tl.journeyId = id
+	traced := tl.traceCtx != traceContext{}
+	tl.traceCtx = traceContext{}
+	tl.mu.Unlock()
 	// testing lane does not format a log message, so the correlation ID is ignored
+
+	endTraceParent(tl, traced)
+}
+
+func (tl *testingLane) SetTraceParent(tp string) error {
+	return setTraceParent(tl, tp, func(tc traceContext) {
+		tl.mu.Lock()
+		defer tl.mu.Unlock()
+		tl.traceCtx = tc
+		tl.journeyId = tc.traceId
+	})
+}
+
+func (tl *testingLane) TraceParent() string {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	return tl.traceCtx.render(tl.LaneId())
 }
 
 func (tl *testingLane) SetLogLevel(newLevel LaneLogLevel) (priorLevel LaneLogLevel) {
@@ -135,20 +259,37 @@ func (tl *testingLane) SetLogLevel(newLevel LaneLogLevel) (priorLevel LaneLogLev
 	return
 }
 
+func (tl *testingLane) ConfigSnapshot() LaneConfigState {
+	tl.mu.Lock()
+	level := tl.level
+	tl.mu.Unlock()
+
+	return captureConfig(tl, level, &tl.stackTraceStore, &tl.callerInfoStore, &tl.lengthConstraintStore)
+}
+
+func (tl *testingLane) ApplyConfig(state LaneConfigState) {
+	applyConfig(tl, state)
+}
+
 func (tl *testingLane) VerifyEvents(eventList []*LaneEvent) bool {
+	return tl.verifyEvents(eventList, false)
+}
+
+// Worker for VerifyEvents() and VerifyEventText(), which compares the IDs
+// that a descriptor sets when [withIds] is true
+func (tl *testingLane) verifyEvents(eventList []*LaneEvent, withIds bool) bool {
 	tl.mu.Lock()
 	defer tl.mu.Unlock()
 
-	if len(eventList) != len(tl.Events) {
+	if len(eventList) != len(tl.events) {
 		return false
 	}
 
 	for i := 0; i < len(eventList); i++ {
 		e1 := eventList[i]
-		e2 := tl.Events[i]
+		e2 := tl.events[i]
 
-		if e1.Level != e2.Level ||
-			e1.Message != e2.Message {
+		if !e1.matches(e2, withIds) {
 			return false
 		}
 	}
@@ -156,16 +297,33 @@ func (tl *testingLane) VerifyEvents(eventList []*LaneEvent) bool {
 	return true
 }
 
+// Checks if the captured event [e2] matches the expected event. With
+// [withIds], the Id and JourneyId are also compared when the expected event
+// has them, as set by a descriptor.
+func (e1 *LaneEvent) matches(e2 *LaneEvent, withIds bool) bool {
+	if e1.Level != e2.Level || e1.Message != e2.Message {
+		return false
+	}
+	return !withIds ||
+		(e1.Id == "" || e1.Id == e2.Id) &&
+			(e1.JourneyId == "" || e1.JourneyId == e2.JourneyId)
+}
+
 func (tl *testingLane) FindEvents(eventList []*LaneEvent) bool {
+	return tl.findEvents(eventList, false)
+}
+
+// Worker for FindEvents() and FindEventText()
+func (tl *testingLane) findEvents(eventList []*LaneEvent, withIds bool) bool {
 	tl.mu.Lock()
 	defer tl.mu.Unlock()
 
 	pos := 0
 	for _, e1 := range eventList {
 		found := false
-		for i := pos; i < len(tl.Events); i++ {
-			e2 := tl.Events[i]
-			if e1.Level == e2.Level && e1.Message == e2.Message {
+		for i := pos; i < len(tl.events); i++ {
+			e2 := tl.events[i]
+			if e1.matches(e2, withIds) {
 				pos = i + 1
 				found = true
 				break
@@ -181,9 +339,15 @@ func (tl *testingLane) FindEvents(eventList []*LaneEvent) bool {
 }
 
 // eventText specifies a list of events, separated by \n, and each
-// line must be in the form of <level>\t<message>. Actual \n or \t
-// can be specified by "\\n" or "\\t"
+// line must be in the form of <level>\t<message>, or
+// <level>\t{<ids>}\t<message> to check the lane ID and journey ID too.
+// Actual \n or \t can be specified by "\\n" or "\\t"
 func (tl *testingLane) VerifyEventText(eventText string) (match bool) {
+	return tl.verifyEvents(parseEventText(eventText), true)
+}
+
+// Makes the event list of a VerifyEventText() descriptor
+func parseEventText(eventText string) []*LaneEvent {
 	eventList := []*LaneEvent{}
 
 	if eventText != "" {
@@ -193,42 +357,57 @@ func (tl *testingLane) VerifyEventText(eventText string) (match bool) {
 				continue
 			}
 			parts := strings.Split(line, "\t")
+			var ids string
+			if len(parts) == 3 && strings.HasPrefix(parts[1], "{") && strings.HasSuffix(parts[1], "}") {
+				ids = parts[1]
+				parts = []string{parts[0], parts[2]}
+			}
 			if len(parts) != 2 {
 				panic(fmt.Sprintf("eventText line must have exactly one tab separator but has %d parts: %s", len(parts), line))
 			}
 			text := parts[1]
 			text = strings.ReplaceAll(text, "\\t", "\t")
 			text = strings.ReplaceAll(text, "\\n", "\n")
-			eventList = append(eventList, &LaneEvent{Level: parts[0], Message: text})
+			e := &LaneEvent{Level: parts[0], Message: text}
+			if ids != "" {
+				e.JourneyId, e.Id = parseEventIds(ids)
+			}
+			eventList = append(eventList, e)
 		}
 	}
-
-	return tl.VerifyEvents(eventList)
+	return eventList
 }
 
-// eventText specifies a list of events, separated by \n, and each
-// line must be in the form of <level>\t<message>.
-func (tl *testingLane) FindEventText(eventText string) (found bool) {
-	eventList := []*LaneEvent{}
-
-	if eventText != "" {
-		lines := strings.Split(eventText, "\n")
-		for _, line := range lines {
-			parts := strings.Split(line, "\t")
-			if len(parts) != 2 {
-				panic("eventText line must have exactly one tab separator")
-			}
-			eventList = append(eventList, &LaneEvent{Level: parts[0], Message: parts[1]})
-		}
+// Provides the journey ID and lane ID of a descriptor's "{laneId}" or
+// "{journeyId:laneId}", where "*" matches any ID
+func parseEventIds(ids string) (journeyId, laneId string) {
+	ids = ids[1 : len(ids)-1]
+	if colon := strings.LastIndexByte(ids, ':'); colon >= 0 {
+		journeyId, laneId = ids[:colon], ids[colon+1:]
+	} else {
+		laneId = ids
 	}
+	if journeyId == "*" {
+		journeyId = ""
+	}
+	if laneId == "*" {
+		laneId = ""
+	}
+	return
+}
 
-	return tl.FindEvents(eventList)
+// eventText specifies a list of events in the VerifyEventText() form.
+func (tl *testingLane) FindEventText(eventText string) (found bool) {
+	return tl.findEvents(parseEventText(eventText), true)
 }
 
 func (tl *testingLane) EventsToString() string {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+
 	var sb strings.Builder
 
-	for _, e := range tl.Events {
+	for _, e := range tl.events {
 		if sb.Len() > 0 {
 			sb.WriteRune('\n')
 		}
@@ -241,7 +420,10 @@ func (tl *testingLane) EventsToString() string {
 }
 
 func (tl *testingLane) Contains(text string) bool {
-	for _, e := range tl.Events {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+
+	for _, e := range tl.events {
 		if strings.Contains(e.Message, text) {
 			return true
 		}
@@ -250,63 +432,222 @@ func (tl *testingLane) Contains(text string) bool {
 	return false
 }
 
-func (tl *testingLane) WantDescendantEvents(wanted bool) bool {
+func (tl *testingLane) Events() []LaneEvent {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+
+	events := make([]LaneEvent, 0, len(tl.events))
+	for _, e := range tl.events {
+		events = append(events, *e)
+	}
+	return events
+}
+
+func (tl *testingLane) EventCount() int {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	return len(tl.events)
+}
+
+func (tl *testingLane) Reset() {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	tl.events = nil
+	tl.dropped = 0
+}
+
+func (tl *testingLane) SetMaxEvents(n int, policy EventLimitPolicy) (prior int) {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+
+	prior = tl.maxEvents
+	tl.maxEvents = max(n, 0)
+	tl.limitPolicy = policy
+
+	if tl.maxEvents > 0 && len(tl.events) > tl.maxEvents {
+		excess := len(tl.events) - tl.maxEvents
+		tl.events = append([]*LaneEvent{}, tl.events[excess:]...)
+		tl.dropped += int64(excess)
+	}
+	return
+}
+
+func (tl *testingLane) DroppedEvents() int64 {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	return tl.dropped
+}
+
+// Adds an event to the captured events, applying the event limit; the caller
+// must hold the lock
+func (tl *testingLane) captureEvent(le *LaneEvent) {
+	if tl.maxEvents > 0 && len(tl.events) >= tl.maxEvents {
+		switch tl.limitPolicy {
+		case DropNewest:
+			tl.dropped++
+			return
+		case FailTest:
+			panic(fmt.Sprintf("testing lane %s exceeded %d events with: %s", tl.LaneId(), tl.maxEvents, le.Message))
+		default:
+			tl.events[0] = nil
+			tl.events = tl.events[1:]
+			tl.dropped++
+		}
+	}
+	tl.events = append(tl.events, le)
+}
+
+func (tl *testingLane) WantDescendantEvents(wanted bool, filters ...DescendantFilter) bool {
 	tl.mu.Lock()
 	prior := tl.wantDescendantEvents
 	tl.wantDescendantEvents = wanted
+	tl.descendantFilters = nil
+	if wanted && len(filters) > 0 {
+		tl.descendantFilters = append([]DescendantFilter{}, filters...)
+	}
 	tl.mu.Unlock()
 
 	return prior
 }
 
+// Captures descendant messages at [level] or above
+func DescendantLevel(level LaneLogLevel) DescendantFilter {
+	return func(l LaneLogLevel, message string) bool {
+		return l >= level
+	}
+}
+
+// Captures descendant messages that match [pattern]
+func DescendantMatch(pattern *regexp.Regexp) DescendantFilter {
+	return func(l LaneLogLevel, message string) bool {
+		return pattern.MatchString(message)
+	}
+}
+
+func (tl *testingLane) wantDescendantEvent(level LaneLogLevel, message string) bool {
+	for _, filter := range tl.descendantFilters {
+		if !filter(level, message) {
+			return false
+		}
+	}
+	return true
+}
+
 func (tl *testingLane) recordLaneEvent(props loggingProperties, level LaneLogLevel, levelText string, format *string, args ...any) {
-	tl.recordLaneEventRecursive(props, true, level, levelText, format, args...)
+	var caller string
+	if level != LogLevelStack {
+		caller = tl.callerInfo()
+	}
+	if suppressionActive() && tl.suppressEvent(props, caller, level, eventMessage(format, args...)) {
+		return
+	}
+	tl.recordLaneEventRecursive(props, true, caller, level, levelText, nil, format, args...)
+	if tl.levelHooked(level) {
+		tl.runHooksForEvent(props, level, caller, eventMessage(format, args...))
+	}
+}
+
+// Records an object log's rendered [text] along with the original object
+func (tl *testingLane) recordObjectEvent(props loggingProperties, level LaneLogLevel, text string, obj any) {
+	caller := tl.callerInfo()
+	if suppressionActive() && tl.suppressEvent(props, caller, level, text) {
+		return
+	}
+	tl.recordLaneEventRecursive(props, true, caller, level, levelNames[level], obj, nil, text)
+	if tl.levelHooked(level) {
+		tl.runHooksForEvent(props, level, caller, text)
+	}
+}
+
+// Checks if a suppression rule mutes the message, recording the audit event
+// when the rule activates
+func (tl *testingLane) suppressEvent(props loggingProperties, caller string, level LaneLogLevel, text string) bool {
+	suppressed, audit := checkSuppression(level, text)
+	if audit != "" {
+		tl.recordLaneEventRecursive(props, true, caller, LogLevelWarn, "WARN", nil, nil, audit)
+	}
+	return suppressed
+}
+
+// Passes a message the lane logs at its level to the level hooks
+func (tl *testingLane) runHooksForEvent(props loggingProperties, level LaneLogLevel, caller, text string) {
+	tl.mu.Lock()
+	logged := level >= tl.level || journeyDebugEnabled(props.journeyId, level)
+	tl.mu.Unlock()
+	if !logged {
+		return
+	}
+
+	rec := Record{
+		Time:      time.Now(),
+		LaneId:    props.laneId,
+		JourneyId: props.journeyId,
+		Category:  props.category,
+		Depth:     props.indent,
+		Level:     level,
+		Message:   tl.constrainLevel(level, text),
+		Caller:    caller,
+	}
+	if fields := tl.MetadataMap(); len(fields) > 0 {
+		rec.Fields = fields
+	}
+	tl.runLevelHooks(&rec)
 }
 
-func (tl *testingLane) Constrain(msg string) string {
-	maxLen := tl.maxLength.Load()
-	if maxLen > 0 && len(msg) > int(maxLen) {
-		msg = msg[:maxLen-1] + "\u2026"
+// Formats the text of a testing lane event
+func eventMessage(format *string, args ...any) string {
+	if format != nil {
+		return fmt.Sprintf(*format, args...)
 	}
-	return msg
+	msg := fmt.Sprintln(args...) // use Sprintln because it matches log behavior wrt spaces between args
+	return msg[:len(msg)-1]      // remove \n
 }
 
 // Worker that adds the test event to the testing lane, and then passes it up to the parent,
 // where the parent decides to capture it as well, and then passes it up to the
 // grandparent, and so on.
-func (tl *testingLane) recordLaneEventRecursive(props loggingProperties, originator bool, level LaneLogLevel, levelText string, format *string, args ...any) {
+func (tl *testingLane) recordLaneEventRecursive(props loggingProperties, originator bool, caller string, level LaneLogLevel, levelText string, obj any, format *string, args ...any) {
 	tl.mu.Lock()
 	defer tl.mu.Unlock()
 
 	if originator || tl.wantDescendantEvents {
-		if level >= tl.level {
+		if level >= tl.level || journeyDebugEnabled(props.journeyId, level) {
 			le := LaneEvent{
-				Id:    props.laneId,
-				Level: levelText,
+				Id:        props.laneId,
+				JourneyId: props.journeyId,
+				Level:     levelText,
+				Category:  props.category,
+				Depth:     props.indent,
+				Caller:    caller,
+				Object:    obj,
+				Message:   eventMessage(format, args...),
 			}
 
-			if format == nil {
-				le.Message = fmt.Sprintln(args...)          // use Sprintln because it matches log behavior wrt spaces between args
-				le.Message = le.Message[:len(le.Message)-1] // remove \n
-			} else {
-				le.Message = fmt.Sprintf(*format, args...)
+			if originator || tl.wantDescendantEvent(level, le.Message) {
+				le.Message = tl.constrainLevel(level, le.Message)
+				tl.captureEvent(&le)
 			}
-
-			le.Message = tl.Constrain(le.Message)
-			tl.Events = append(tl.Events, &le)
 		}
 	}
 
 	if tl.parent != nil {
-		tl.parent.recordLaneEventRecursive(props, false, level, levelText, format, args...)
+		tl.parent.recordLaneEventRecursive(props, false, caller, level, levelText, obj, format, args...)
 	}
 }
 
 func (tl *testingLane) tee(props loggingProperties, logger teeHandler) {
+	var closed []Lane
+	defer func() { reportClosedTees(tl, closed) }()
+
 	tl.mu.Lock()
 	defer tl.mu.Unlock()
 
-	for _, t := range tl.tees {
+	var tees []Lane
+	tees, closed = withoutClosedTees(tl.tees)
+	if closed != nil {
+		tl.tees = tees
+	}
+	for _, t := range tees {
 		receiver := t.(laneInternal)
 		logger(props, receiver)
 	}
@@ -318,9 +659,31 @@ func (tl *testingLane) LaneProps() loggingProperties {
 	return loggingProperties{
 		laneId:    tl.LaneId(),
 		journeyId: tl.journeyId,
+		category:  tl.category,
+		indent:    tl.indent,
 	}
 }
 
+func (tl *testingLane) Category(category string) Lane {
+	return deriveCategory(tl, category)
+}
+
+func (tl *testingLane) setCategory(category string) {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	tl.category = category
+}
+
+func (tl *testingLane) Indent() Lane {
+	return deriveIndent(tl)
+}
+
+func (tl *testingLane) setIndent(depth int) {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	tl.indent = depth
+}
+
 func (tl *testingLane) Trace(args ...any) {
 	tl.TraceInternal(tl.LaneProps(), args...)
 }
@@ -383,6 +746,30 @@ func (tl *testingLane) ErrorObject(message string, obj any) {
 	LogObject(tl, LogLevelError, message, obj)
 }
 
+func (tl *testingLane) InfoOnce(key string, args ...any) {
+	logOnce(tl, LogLevelInfo, key, tl.firstUse, func() string { return sprint(args...) })
+}
+
+func (tl *testingLane) WarnOnce(key string, args ...any) {
+	logOnce(tl, LogLevelWarn, key, tl.firstUse, func() string { return sprint(args...) })
+}
+
+func (tl *testingLane) WarnRate(key string, period time.Duration, args ...any) {
+	logRateLimited(tl, &tl.logLimitStore, LogLevelWarn, key, period, time.Now(), func() string { return sprint(args...) })
+}
+
+func (tl *testingLane) WarnRatef(key string, period time.Duration, format string, args ...any) {
+	logRateLimited(tl, &tl.logLimitStore, LogLevelWarn, key, period, time.Now(), func() string { return fmt.Sprintf(format, args...) })
+}
+
+func (tl *testingLane) ErrorRate(key string, period time.Duration, args ...any) {
+	logRateLimited(tl, &tl.logLimitStore, LogLevelError, key, period, time.Now(), func() string { return sprint(args...) })
+}
+
+func (tl *testingLane) ErrorRatef(key string, period time.Duration, format string, args ...any) {
+	logRateLimited(tl, &tl.logLimitStore, LogLevelError, key, period, time.Now(), func() string { return fmt.Sprintf(format, args...) })
+}
+
 func (tl *testingLane) PreFatal(args ...any) {
 	tl.PreFatalInternal(tl.LaneProps(), args...)
 }
@@ -396,28 +783,27 @@ func (tl *testingLane) PreFatalObject(message string, obj any) {
 }
 
 func (tl *testingLane) Fatal(args ...any) {
-	tl.FatalInternal(tl.LaneProps(), args...)
+	tl.FatalInternal(tl.LaneProps(), fatalArgs(tl, args)...)
 	tl.onPanic()
 }
 
 func (tl *testingLane) Fatalf(format string, args ...any) {
+	format, args = fatalfArgs(tl, format, args)
 	tl.FatalfInternal(tl.LaneProps(), format, args...)
 	tl.onPanic()
 }
 
 func (tl *testingLane) FatalObject(message string, obj any) {
-	LogObject(tl, LogLevelFatal, message, obj)
+	LogObject(tl, LogLevelFatal, annotateFatal(tl, message), obj)
 }
 
 func (tl *testingLane) logTestingLaneStack(props loggingProperties, level LaneLogLevel, skippedCallers int) {
 	if tl.testingStack.Load() {
-		if tl.stackTrace[level].Load() {
+		if tl.stackEnabled(level) {
 			// When single event stack trace is enabled in the testing lane, record
 			// the stack as a single message, so that the test code has a predictable
 			// number of log events.
-			buf := make([]byte, 16384)
-			n := runtime.Stack(buf, false)
-			lines := cleanStack(buf[:n], skippedCallers)
+			lines, _ := captureStack(skippedCallers, tl.stackDepth(level))
 
 			filtered := strings.Join(lines, "\n")
 
@@ -433,16 +819,15 @@ func (tl *testingLane) logTestingLaneStack(props loggingProperties, level LaneLo
 
 func (tl *testingLane) logStackIf(props loggingProperties, level LaneLogLevel, message string, skippedCallers int) {
 
-	if tl.stackTrace[level].Load() {
+	if tl.stackEnabled(level) {
 		// skip lines: the first line (goroutine label), plus the LogStack() and logging API
-		tl.logStack(props, message, skippedCallers)
+		tl.logStack(props, level, message, skippedCallers)
 	}
 }
 
-func (tl *testingLane) logStack(props loggingProperties, message string, skippedCallers int) {
-	buf := make([]byte, 16384)
-	n := runtime.Stack(buf, false)
-	lines := cleanStack(buf[:n], skippedCallers)
+// Logs the stack, limited to the stack trace depth of [level]
+func (tl *testingLane) logStack(props loggingProperties, level LaneLogLevel, message string, skippedCallers int) {
+	lines, _ := captureStack(skippedCallers, tl.stackDepth(level))
 
 	// each has two lines (the function name on one line, followed by source info on the next line)
 	format := "%s"
@@ -463,25 +848,35 @@ func (tl *testingLane) LogStackTrim(message string, skippedCallers int) {
 	tl.LogStackTrimInternal(tl.LaneProps(), message, skippedCallers)
 }
 
-func (tl *testingLane) SetLengthConstraint(maxLength int) int {
-	old := tl.maxLength.Load()
-	if maxLength > 1 {
-		tl.maxLength.Store(int32(maxLength))
-	} else {
-		tl.maxLength.Store(0)
-	}
-	return int(old)
-}
-
 func (tl *testingLane) Logger() *log.Logger {
 	return tl.tlog
 }
 
-func (tl *testingLane) Close() {
+func (tl *testingLane) WriterAt(level LaneLogLevel) io.WriteCloser {
+	return newLevelWriter(tl, level)
+}
+
+func (tl *testingLane) Close() error {
+	return tl.CloseWithContext(context.Background())
+}
+
+func (tl *testingLane) CloseWithContext(ctx context.Context) error {
+	tl.detachTree(tl)
+	if tl.runCloseHooks(tl) {
+		return tl.closeTees(ctx, tl.Tees())
+	}
+	return nil
+}
+
+func (tl *testingLane) treeInfo() laneTreeInfo {
+	tl.mu.Lock()
+	level := tl.level
+	tl.mu.Unlock()
+	return tl.makeTreeInfo(level)
 }
 
 func (tl *testingLane) Derive() Lane {
-	l := deriveTestingLane(context.WithValue(tl.Context, ParentLaneIdKey, tl.LaneId()), tl, tl.tees)
+	l := deriveTestingLane(context.WithValue(tl.Context, ParentLaneIdKey, tl.LaneId()), tl, tl.tees, tl.idGen)
 
 	tl.mu.Lock()
 	defer tl.mu.Unlock()
@@ -492,7 +887,7 @@ func (tl *testingLane) Derive() Lane {
 
 func (tl *testingLane) DeriveWithCancel() (Lane, context.CancelFunc) {
 	childCtx, cancelFn := context.WithCancel(context.WithValue(tl.Context, ParentLaneIdKey, tl.LaneId()))
-	l := deriveTestingLane(childCtx, tl, tl.tees)
+	l := deriveTestingLane(childCtx, tl, tl.tees, tl.idGen)
 
 	tl.mu.Lock()
 	defer tl.mu.Unlock()
@@ -503,7 +898,7 @@ func (tl *testingLane) DeriveWithCancel() (Lane, context.CancelFunc) {
 
 func (tl *testingLane) DeriveWithCancelCause() (Lane, context.CancelCauseFunc) {
 	childCtx, cancelFn := context.WithCancelCause(context.WithValue(tl.Context, ParentLaneIdKey, tl.LaneId()))
-	l := deriveTestingLane(childCtx, tl, tl.tees)
+	l := deriveTestingLane(childCtx, tl, tl.tees, tl.idGen)
 
 	tl.mu.Lock()
 	defer tl.mu.Unlock()
@@ -514,7 +909,7 @@ func (tl *testingLane) DeriveWithCancelCause() (Lane, context.CancelCauseFunc) {
 
 func (tl *testingLane) DeriveWithoutCancel() Lane {
 	childCtx := context.WithoutCancel(context.WithValue(tl.Context, ParentLaneIdKey, tl.LaneId()))
-	l := deriveTestingLane(childCtx, tl, tl.tees)
+	l := deriveTestingLane(childCtx, tl, tl.tees, tl.idGen)
 
 	tl.mu.Lock()
 	defer tl.mu.Unlock()
@@ -525,7 +920,7 @@ func (tl *testingLane) DeriveWithoutCancel() Lane {
 
 func (tl *testingLane) DeriveWithDeadline(deadline time.Time) (Lane, context.CancelFunc) {
 	childCtx, cancelFn := context.WithDeadline(context.WithValue(tl.Context, ParentLaneIdKey, tl.LaneId()), deadline)
-	l := deriveTestingLane(childCtx, tl, tl.tees)
+	l := deriveTestingLane(childCtx, tl, tl.tees, tl.idGen)
 
 	tl.mu.Lock()
 	defer tl.mu.Unlock()
@@ -536,7 +931,7 @@ func (tl *testingLane) DeriveWithDeadline(deadline time.Time) (Lane, context.Can
 
 func (tl *testingLane) DeriveWithDeadlineCause(deadline time.Time, cause error) (Lane, context.CancelFunc) {
 	childCtx, cancelFn := context.WithDeadlineCause(context.WithValue(tl.Context, ParentLaneIdKey, tl.LaneId()), deadline, cause)
-	l := deriveTestingLane(childCtx, tl, tl.tees)
+	l := deriveTestingLane(childCtx, tl, tl.tees, tl.idGen)
 
 	tl.mu.Lock()
 	defer tl.mu.Unlock()
@@ -547,7 +942,7 @@ func (tl *testingLane) DeriveWithDeadlineCause(deadline time.Time, cause error)
 
 func (tl *testingLane) DeriveWithTimeout(duration time.Duration) (Lane, context.CancelFunc) {
 	childCtx, cancelFn := context.WithTimeout(context.WithValue(tl.Context, ParentLaneIdKey, tl.LaneId()), duration)
-	l := deriveTestingLane(childCtx, tl, tl.tees)
+	l := deriveTestingLane(childCtx, tl, tl.tees, tl.idGen)
 
 	tl.mu.Lock()
 	defer tl.mu.Unlock()
@@ -558,7 +953,7 @@ func (tl *testingLane) DeriveWithTimeout(duration time.Duration) (Lane, context.
 
 func (tl *testingLane) DeriveWithTimeoutCause(duration time.Duration, cause error) (Lane, context.CancelFunc) {
 	childCtx, cancelFn := context.WithTimeoutCause(context.WithValue(tl.Context, ParentLaneIdKey, tl.LaneId()), duration, cause)
-	l := deriveTestingLane(childCtx, tl, tl.tees)
+	l := deriveTestingLane(childCtx, tl, tl.tees, tl.idGen)
 
 	tl.mu.Lock()
 	defer tl.mu.Unlock()
@@ -567,24 +962,40 @@ func (tl *testingLane) DeriveWithTimeoutCause(duration time.Duration, cause erro
 	return l, cancelFn
 }
 
+func (tl *testingLane) DeriveWithMaxTimeout(maxTimeout time.Duration) (Lane, context.CancelFunc) {
+	return deriveWithMaxTimeout(tl, maxTimeout)
+}
+
 func (tl *testingLane) DeriveReplaceContext(ctx OptionalContext) Lane {
-	l := NewTestingLane(ctx)
-	l.WantDescendantEvents(tl.wantDescendantEvents)
+	l := NewTestingLane(ctx, withLaneIdGenerator(tl.idGen))
+	l.WantDescendantEvents(tl.wantDescendantEvents, tl.descendantFilters...)
 
 	tl.mu.Lock()
-	defer tl.mu.Unlock()
 	l.SetLogLevel(tl.level)
+	l.SetMaxEvents(tl.maxEvents, tl.limitPolicy)
 
 	for _, tee := range tl.tees {
 		l.AddTee(tee)
 	}
+	tl.mu.Unlock()
 
 	copyConfigToDerivation(l, tl)
+
+	child := l.(*testingLane)
+	child.levelParser = tl.levelParser
+	child.category = tl.category
+	child.indent = tl.indent
+	child.attachTree(child, &tl.laneTreeStore, time.Now())
+	child.inheritHooks(&tl.lifecycleStore)
+	child.inheritErrorHandler(&tl.errorStore)
+	child.inheritMetadata(&tl.MetadataStore)
+	child.inheritLimits(&tl.logLimitStore)
+	tl.runDeriveHooks(tl, child)
 	return l
 }
 
-func (tl *testingLane) EnableStackTrace(level LaneLogLevel, enable bool) bool {
-	return tl.stackTrace[level].Swap(enable)
+func (tl *testingLane) WithSoftTimeout(d time.Duration, onTimeout func(l Lane)) (stop func() bool) {
+	return startSoftTimeout(tl, d, onTimeout)
 }
 
 func (tl *testingLane) EnableSingleLineStackTrace(enable bool) bool {
@@ -595,6 +1006,14 @@ func (tl *testingLane) LaneId() string {
 	return tl.Value(testing_lane_id).(string)
 }
 
+// Provides the context value for [key], and the lane itself for FromContext()
+func (tl *testingLane) Value(key any) any {
+	if key == (laneContextKey{}) {
+		return tl
+	}
+	return tl.Context.Value(key)
+}
+
 func (tl *testingLane) JourneyId() string {
 	tl.mu.Lock()
 	defer tl.mu.Unlock()
@@ -636,6 +1055,11 @@ func (tl *testingLane) SetPanicHandler(handler Panic) {
 	tl.onPanic = handler
 }
 
+// A testing lane captures events in memory, so it has no write errors
+func (tl *testingLane) LastError() error {
+	return nil
+}
+
 func (tl *testingLane) Parent() Lane {
 	if tl.parent != nil {
 		return tl.parent
@@ -644,11 +1068,15 @@ func (tl *testingLane) Parent() Lane {
 }
 
 func (tlw *testingLogWriter) Write(p []byte) (n int, err error) {
-	text := strings.TrimSuffix(string(p), "\n")
-	tlw.tl.Info(text)
+	level, text := tlw.tl.parseLevel(strings.TrimSuffix(string(p), "\n"))
+	logAtLevel(tlw.tl, level, text)
 	return len(p), nil
 }
 
+func (tl *testingLane) parseLevel(text string) (level LaneLogLevel, message string) {
+	return tl.levelParser.parse(text)
+}
+
 func (tl *testingLane) TraceInternal(props loggingProperties, args ...any) {
 	tl.recordLaneEvent(props, LogLevelTrace, "TRACE", nil, args...)
 	tl.tee(props, func(teeProps loggingProperties, li laneInternal) { li.TraceInternal(teeProps, args...) })
@@ -721,6 +1149,32 @@ func (tl *testingLane) FatalfInternal(props loggingProperties, format string, ar
 	// panic occurs on the externally called Fatalf() in a moment
 }
 
+func (tl *testingLane) replayRecord(rec *Record) {
+	props := rec.props()
+	event := func(level, message string) *LaneEvent {
+		return &LaneEvent{
+			Id:        props.laneId,
+			JourneyId: props.journeyId,
+			Level:     level,
+			Category:  props.category,
+			Depth:     props.indent,
+			Caller:    rec.Caller,
+			Message:   message,
+		}
+	}
+
+	tl.mu.Lock()
+	if rec.Level != LogLevelStack || rec.Message != "" {
+		tl.captureEvent(event(levelNames[rec.Level], rec.Message))
+	}
+	for _, line := range rec.Stack {
+		tl.captureEvent(event("STACK", line))
+	}
+	tl.mu.Unlock()
+
+	tl.tee(props, func(teeProps loggingProperties, li laneInternal) { li.replayRecord(rec) })
+}
+
 func (tl *testingLane) LogStackTrimInternal(props loggingProperties, message string, skippedCallers int) {
 	tl.logStackIf(props, LogLevelStack, message, skippedCallers)
 	tl.tee(props, func(teeProps loggingProperties, li laneInternal) {
@@ -728,6 +1182,21 @@ func (tl *testingLane) LogStackTrimInternal(props loggingProperties, message str
 	})
 }
 
+func (tl *testingLane) ObjectInternal(props loggingProperties, level LaneLogLevel, message string, obj any) {
+	receiveObject(props, tl, level, message, obj, tl.ObjectOptions())
+}
+
+func (tl *testingLane) objectTextInternal(props loggingProperties, level LaneLogLevel, text, message string, obj any) {
+	if level == logLevelPreFatal {
+		level = LogLevelFatal
+	}
+	tl.recordObjectEvent(props, level, text, obj)
+	if level == LogLevelError {
+		tl.logTestingLaneStack(props, LogLevelError, 0)
+	}
+	tl.tee(props, func(teeProps loggingProperties, li laneInternal) { li.ObjectInternal(teeProps, level, message, obj) })
+}
+
 func (tl *testingLane) OnPanic() {
 	tl.onPanic()
 }
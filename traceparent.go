@@ -0,0 +1,109 @@
+package lane
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strings"
+)
+
+type (
+	// W3C trace context details retained by a lane
+	traceContext struct {
+		traceId      string
+		parentSpanId string
+		flags        string
+	}
+)
+
+// Metadata key holding the span ID parsed from an incoming traceparent header
+const TraceParentSpanKey = "trace_parent_span_id"
+
+var ErrInvalidTraceParent = errors.New("invalid traceparent")
+
+// Parses a W3C traceparent header value of the form
+// "version-traceid-parentid-flags", ex:
+// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func parseTraceParent(tp string) (tc traceContext, err error) {
+	parts := strings.Split(strings.TrimSpace(tp), "-")
+	if len(parts) < 4 {
+		err = ErrInvalidTraceParent
+		return
+	}
+
+	version := parts[0]
+	if !isLowerHex(version, 2) || version == "ff" || (version == "00" && len(parts) != 4) {
+		err = ErrInvalidTraceParent
+		return
+	}
+
+	if !isLowerHex(parts[1], 32) || isAllZeros(parts[1]) ||
+		!isLowerHex(parts[2], 16) || isAllZeros(parts[2]) ||
+		!isLowerHex(parts[3], 2) {
+		err = ErrInvalidTraceParent
+		return
+	}
+
+	tc.traceId = parts[1]
+	tc.parentSpanId = parts[2]
+	tc.flags = parts[3]
+	return
+}
+
+// Renders the traceparent header value for outbound requests, using the lane's
+// own span ID as the parent, or an empty string if no trace context is set.
+func (tc traceContext) render(laneId string) string {
+	if tc.traceId == "" {
+		return ""
+	}
+	return fmt.Sprintf("00-%s-%s-%s", tc.traceId, spanIdFromLaneId(laneId), tc.flags)
+}
+
+// Makes a stable 16 hex character span ID from a lane ID
+func spanIdFromLaneId(laneId string) string {
+	h := fnv.New64a()
+	h.Write([]byte(laneId))
+	sum := h.Sum64()
+	if sum == 0 {
+		sum = 1 // all zeros is an invalid span ID
+	}
+	return fmt.Sprintf("%016x", sum)
+}
+
+func isLowerHex(s string, length int) bool {
+	if len(s) != length {
+		return false
+	}
+	for _, ch := range s {
+		if (ch < '0' || ch > '9') && (ch < 'a' || ch > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func isAllZeros(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
+
+// Common implementation of SetTraceParent for the lane types. The [store]
+// function keeps the trace context and the full trace ID as the journey ID, so
+// that the log can be joined to the distributed trace.
+func setTraceParent(l Lane, tp string, store func(tc traceContext)) error {
+	tc, err := parseTraceParent(tp)
+	if err != nil {
+		return err
+	}
+
+	store(tc)
+	l.SetMetadata(TraceParentSpanKey, tc.parentSpanId)
+	return nil
+}
+
+// Removes the parent span of the trace context that SetJourneyId() ended, if
+// [traced], so that later records don't carry a span of another journey
+func endTraceParent(l Lane, traced bool) {
+	if traced {
+		l.DeleteMetadata(TraceParentSpanKey)
+	}
+}
@@ -0,0 +1,141 @@
+package lane
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+const testTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestParseTraceParent(t *testing.T) {
+	tc, err := parseTraceParent(testTraceParent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tc.traceId != "4bf92f3577b34da6a3ce929d0e0e4736" || tc.parentSpanId != "00f067aa0ba902b7" || tc.flags != "01" {
+		t.Errorf("unexpected parse result %+v", tc)
+	}
+
+	invalid := []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01",
+	}
+	for _, tp := range invalid {
+		if _, err := parseTraceParent(tp); err == nil {
+			t.Errorf("expected error for %q", tp)
+		}
+	}
+
+	// future versions may append fields
+	if _, err := parseTraceParent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"); err != nil {
+		t.Errorf("future version rejected: %v", err)
+	}
+}
+
+func TestTestingLaneTraceParent(t *testing.T) {
+	tl := NewTestingLane(context.Background())
+
+	if tl.TraceParent() != "" {
+		t.Error("expected empty trace parent")
+	}
+
+	if err := tl.SetTraceParent("bogus"); err != ErrInvalidTraceParent {
+		t.Errorf("expected invalid trace parent error, got %v", err)
+	}
+
+	if err := tl.SetTraceParent(testTraceParent); err != nil {
+		t.Fatal(err)
+	}
+
+	if tl.JourneyId() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("wrong journey id %s", tl.JourneyId())
+	}
+	if tl.GetMetadata(TraceParentSpanKey) != "00f067aa0ba902b7" {
+		t.Error("span id not retained")
+	}
+
+	tp := tl.TraceParent()
+	if !strings.HasPrefix(tp, "00-4bf92f3577b34da6a3ce929d0e0e4736-") || !strings.HasSuffix(tp, "-01") {
+		t.Errorf("wrong trace parent %s", tp)
+	}
+	if _, err := parseTraceParent(tp); err != nil {
+		t.Errorf("emitted trace parent doesn't parse: %v", err)
+	}
+
+	tl2 := tl.Derive()
+	tp2 := tl2.TraceParent()
+	if tp2 == "" || tp2 == tp || tp2[:36] != tp[:36] {
+		t.Errorf("derived trace parent incorrect: %s vs %s", tp2, tp)
+	}
+}
+
+func TestLogLaneTraceParent(t *testing.T) {
+	ll := NewLogLane(nil)
+
+	if err := ll.SetTraceParent(testTraceParent); err != nil {
+		t.Fatal(err)
+	}
+
+	if ll.JourneyId() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("wrong journey id %s", ll.JourneyId())
+	}
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer func() { log.SetOutput(os.Stderr) }()
+
+	ll2 := ll.Derive()
+	ll2.Info("test")
+
+	if !strings.Contains(buf.String(), "{4bf92f3577b34da6a3ce929d0e0e4736:") {
+		t.Error("journey id not logged")
+	}
+
+	if !strings.Contains(ll2.TraceParent(), "4bf92f3577b34da6a3ce929d0e0e4736") {
+		t.Error("derived lane lost trace id")
+	}
+}
+
+func TestNullLaneTraceParent(t *testing.T) {
+	nl := NewNullLane(nil)
+
+	if err := nl.SetTraceParent(testTraceParent); err != nil {
+		t.Fatal(err)
+	}
+
+	nl2, cancel := nl.DeriveWithCancel()
+	defer cancel()
+
+	if !strings.HasPrefix(nl2.TraceParent(), "00-4bf92f3577b34da6a3ce929d0e0e4736-") {
+		t.Error("derived lane lost trace id")
+	}
+}
+
+func TestSetJourneyIdEndsTraceParent(t *testing.T) {
+	for _, l := range []Lane{NewTestingLane(nil), NewLogLane(nil), NewNullLane(nil)} {
+		if err := l.SetTraceParent(testTraceParent); err != nil {
+			t.Fatal(err)
+		}
+		l.SetJourneyId("other")
+
+		if l.TraceParent() != "" {
+			t.Errorf("%T: stale trace parent %s", l, l.TraceParent())
+		}
+		if l.JourneyId() != "other" {
+			t.Errorf("%T: wrong journey id %s", l, l.JourneyId())
+		}
+		if _, found := l.MetadataMap()[TraceParentSpanKey]; found {
+			t.Errorf("%T: stale parent span %s", l, l.GetMetadata(TraceParentSpanKey))
+		}
+	}
+}
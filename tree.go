@@ -0,0 +1,246 @@
+package lane
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// Common implementation of lane names and the live lane hierarchy
+	laneTreeStore struct {
+		treeMu     sync.Mutex
+		self       Lane
+		name       string
+		created    time.Time
+		tracking   bool
+		parentTree *laneTreeStore
+		children   []Lane
+		removed    chan struct{} // closed when a descendant is removed, for WaitForDescendants()
+	}
+
+	// Details reported by a lane for DumpTree
+	laneTreeInfo struct {
+		level    LaneLogLevel
+		created  time.Time
+		children []Lane
+	}
+
+	// Implemented by lanes that participate in the lane hierarchy
+	treeReporter interface {
+		treeInfo() laneTreeInfo
+	}
+)
+
+var levelNames = [logLevelMax]string{"TRACE", "DEBUG", "INFO", "WARN", "ERROR", "FATAL", "PREFATAL", "STACK"}
+
+// Assigns a descriptive name to the lane, for diagnostics.
+func (lts *laneTreeStore) SetName(name string) {
+	lts.treeMu.Lock()
+	defer lts.treeMu.Unlock()
+	lts.name = name
+}
+
+// Provides the name assigned by SetName, or an empty string.
+func (lts *laneTreeStore) Name() string {
+	lts.treeMu.Lock()
+	defer lts.treeMu.Unlock()
+	return lts.name
+}
+
+// Turns on tracking of lanes derived from this lane, which is inherited by
+// the derived lanes. Tracked lanes are retained until they are closed or
+// their context is done.
+func (lts *laneTreeStore) TrackDescendants(enable bool) (prior bool) {
+	lts.treeMu.Lock()
+	defer lts.treeMu.Unlock()
+	prior = lts.tracking
+	lts.tracking = enable
+	return
+}
+
+// Provides the live tracked descendants of the lane, depth first, in the
+// order they were derived.
+func (lts *laneTreeStore) Descendants() (list []Lane) {
+	for _, child := range lts.liveChildren() {
+		list = append(list, child)
+		list = append(list, child.Descendants()...)
+	}
+	return
+}
+
+func (lts *laneTreeStore) liveChildren() []Lane {
+	lts.treeMu.Lock()
+	defer lts.treeMu.Unlock()
+	return append([]Lane{}, lts.children...)
+}
+
+// Records the creation of a lane, and links it to its parent when the
+// parent is tracking descendants. Must be called after the lane's context
+// is established.
+func (lts *laneTreeStore) attachTree(self Lane, parent *laneTreeStore, created time.Time) {
+	lts.treeMu.Lock()
+	lts.self = self
+	lts.treeMu.Unlock()
+	lts.created = created
+	if parent == nil {
+		return
+	}
+
+	parent.treeMu.Lock()
+	tracking := parent.tracking
+	if tracking {
+		parent.children = append(parent.children, self)
+	}
+	parent.treeMu.Unlock()
+
+	if !tracking {
+		return
+	}
+
+	lts.treeMu.Lock()
+	lts.tracking = true
+	lts.parentTree = parent
+	lts.treeMu.Unlock()
+
+	if self.Done() != nil {
+		context.AfterFunc(self, func() {
+			parent.removeChild(self)
+		})
+	}
+}
+
+// Unlinks a closed lane from its parent
+func (lts *laneTreeStore) detachTree(self Lane) {
+	lts.treeMu.Lock()
+	parent := lts.parentTree
+	lts.parentTree = nil
+	lts.treeMu.Unlock()
+
+	if parent != nil {
+		parent.removeChild(self)
+	}
+}
+
+func (lts *laneTreeStore) removeChild(child Lane) {
+	lts.treeMu.Lock()
+	for i, l := range lts.children {
+		if l == child {
+			lts.children = append(lts.children[:i:i], lts.children[i+1:]...)
+			break
+		}
+	}
+	lts.treeMu.Unlock()
+
+	lts.notifyRemoval()
+}
+
+// Wakes the WaitForDescendants() calls of the lane and its tracked ancestors
+func (lts *laneTreeStore) notifyRemoval() {
+	lts.treeMu.Lock()
+	removed := lts.removed
+	lts.removed = nil
+	parent := lts.parentTree
+	lts.treeMu.Unlock()
+
+	if removed != nil {
+		close(removed)
+	}
+	if parent != nil {
+		parent.notifyRemoval()
+	}
+}
+
+// Blocks until every tracked descendant whose context can be done on its
+// own, such as a lane made by DeriveWithCancel() or DeriveWithTimeout(), is
+// cancelled or closed, or until [ctx] is done, such as for a clean server
+// shutdown. TrackDescendants() must be turned on before the lanes are
+// derived. Descendants that share the context of their parent, such as a
+// lane made by Derive(), are not waited for.
+func (lts *laneTreeStore) WaitForDescendants(ctx context.Context) error {
+	for {
+		lts.treeMu.Lock()
+		if lts.removed == nil {
+			lts.removed = make(chan struct{})
+		}
+		removed := lts.removed
+		self := lts.self
+		lts.treeMu.Unlock()
+
+		if self == nil || !lts.hasPendingDescendants(self.Done()) {
+			return nil
+		}
+
+		select {
+		case <-removed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Checks for a live tracked descendant whose context can be done
+// independently of the lane, which has the [done] channel
+func (lts *laneTreeStore) hasPendingDescendants(done <-chan struct{}) bool {
+	for _, child := range lts.liveChildren() {
+		childDone := child.Done()
+		if childDone != done && child.Err() == nil {
+			return true
+		}
+		if child.(laneInternal).hasPendingDescendants(childDone) {
+			return true
+		}
+	}
+	return false
+}
+
+func (lts *laneTreeStore) makeTreeInfo(level LaneLogLevel) laneTreeInfo {
+	return laneTreeInfo{
+		level:    level,
+		created:  lts.created,
+		children: lts.liveChildren(),
+	}
+}
+
+// Renders the live hierarchy of [root] and its tracked descendants, one lane
+// per line, with the lane ID, name, log level, tee targets and creation time.
+func DumpTree(root Lane) string {
+	var sb strings.Builder
+	dumpTreeNode(&sb, root, 0)
+	return sb.String()
+}
+
+func dumpTreeNode(sb *strings.Builder, l Lane, depth int) {
+	sb.WriteString(strings.Repeat("  ", depth))
+	sb.WriteString(l.LaneId())
+	if name := l.Name(); name != "" {
+		fmt.Fprintf(sb, " %q", name)
+	}
+
+	var info laneTreeInfo
+	if tr, ok := l.(treeReporter); ok {
+		info = tr.treeInfo()
+		if info.level >= LogLevelTrace && info.level < logLevelMax {
+			fmt.Fprintf(sb, " level=%s", levelNames[info.level])
+		}
+	}
+
+	if tees := l.Tees(); len(tees) > 0 {
+		ids := make([]string, 0, len(tees))
+		for _, tee := range tees {
+			ids = append(ids, tee.LaneId())
+		}
+		fmt.Fprintf(sb, " tees=[%s]", strings.Join(ids, ","))
+	}
+
+	if !info.created.IsZero() {
+		fmt.Fprintf(sb, " created=%s", info.created.Format(time.RFC3339Nano))
+	}
+	sb.WriteString("\n")
+
+	for _, child := range info.children {
+		dumpTreeNode(sb, child, depth+1)
+	}
+}
@@ -0,0 +1,142 @@
+package lane
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testLaneTree(t *testing.T, root Lane) {
+	root.SetName("root")
+	if root.Name() != "root" {
+		t.Error("wrong name")
+	}
+
+	// not tracked
+	root.Derive()
+	if len(root.Descendants()) != 0 {
+		t.Error("expected no tracking")
+	}
+
+	if root.TrackDescendants(true) {
+		t.Error("expected tracking initially off")
+	}
+
+	l1 := root.Derive()
+	l1.SetName("request")
+	l2, cancel := l1.DeriveWithCancel()
+	l3 := root.Derive()
+
+	desc := root.Descendants()
+	if len(desc) != 3 || desc[0] != l1 || desc[1] != l2 || desc[2] != l3 {
+		t.Fatalf("unexpected descendants %v", desc)
+	}
+
+	dump := DumpTree(root)
+	lines := strings.Split(strings.TrimSuffix(dump, "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("unexpected dump:\n%s", dump)
+	}
+	if !strings.HasPrefix(lines[0], root.LaneId()+` "root" level=TRACE`) ||
+		!strings.HasPrefix(lines[1], "  "+l1.LaneId()+` "request"`) ||
+		!strings.HasPrefix(lines[2], "    "+l2.LaneId()+" ") ||
+		!strings.HasPrefix(lines[3], "  "+l3.LaneId()+" ") {
+		t.Errorf("unexpected dump:\n%s", dump)
+	}
+
+	// cancelled and closed lanes leave the tree
+	cancel()
+	l3.Close()
+	deadline := time.Now().Add(time.Second)
+	for len(root.Descendants()) != 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	desc = root.Descendants()
+	if len(desc) != 1 || desc[0] != l1 {
+		t.Errorf("unexpected descendants %v", desc)
+	}
+}
+
+func TestLaneTreeLogLane(t *testing.T) {
+	testLaneTree(t, NewLogLane(nil))
+}
+
+func TestLaneTreeTestingLane(t *testing.T) {
+	testLaneTree(t, NewTestingLane(nil))
+}
+
+func TestLaneTreeNullLane(t *testing.T) {
+	testLaneTree(t, NewNullLane(nil))
+}
+
+func TestDumpTreeDetails(t *testing.T) {
+	clock := NewFixedClock(time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC))
+	root := NewLogLane(nil, WithClock(clock), WithIdSource(NewCounterLaneIdGenerator()))
+	root.TrackDescendants(true)
+	root.SetLogLevel(LogLevelInfo)
+
+	tee := NewTestingLane(nil)
+	root.AddTee(tee)
+
+	clock.Advance(time.Second)
+	child := root.Derive()
+	child.SetName("worker")
+	child.SetLogLevel(LogLevelError)
+
+	expected := fmt.Sprintf("0000000001 level=INFO tees=[%s] created=2024-05-06T07:08:09Z\n"+
+		"  0000000002 \"worker\" level=ERROR tees=[%s] created=2024-05-06T07:08:10Z\n", tee.LaneId(), tee.LaneId())
+	if dump := DumpTree(root); dump != expected {
+		t.Errorf("unexpected dump:\n%s", dump)
+	}
+}
+
+func TestWaitForDescendants(t *testing.T) {
+	for _, root := range []Lane{NewTestingLane(nil), NewLogLane(nil), NewNullLane(nil)} {
+		root.TrackDescendants(true)
+		root.Derive() // not cancelable, so not waited for
+
+		request := root.Derive()
+		l1, cancel1 := request.DeriveWithCancel()
+		l2, cancel2 := l1.DeriveWithTimeout(time.Hour)
+		defer cancel2()
+		l3, cancel3 := root.DeriveWithCancel()
+		defer cancel3()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		if err := root.WaitForDescendants(ctx); err != context.DeadlineExceeded {
+			t.Errorf("%T: unexpected error %v", root, err)
+		}
+		cancel()
+
+		done := make(chan error, 1)
+		go func() {
+			done <- root.WaitForDescendants(context.Background())
+		}()
+
+		cancel1() // also ends l2
+		l3.Close()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("%T: unexpected error %v", root, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%T: the wait did not end", root)
+		}
+		if l2.Err() == nil {
+			t.Errorf("%T: expected l2 to be done", root)
+		}
+	}
+}
+
+func TestWaitForDescendantsUntracked(t *testing.T) {
+	root := NewLogLane(nil)
+	_, cancel := root.DeriveWithCancel()
+	defer cancel()
+
+	if err := root.WaitForDescendants(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
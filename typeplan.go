@@ -0,0 +1,153 @@
+package lane
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+type (
+	// Reflection details of a type, computed once and cached
+	typePlan struct {
+		fieldNames   []string
+		jsonFields   []jsonFieldPlan
+		exportedOnly bool // encoding/json renders the type without losing data
+	}
+
+	// How a struct field is named when json tags are honored
+	jsonFieldPlan struct {
+		exported  bool
+		name      string
+		omitEmpty bool
+		skip      bool
+		inline    bool // embedded struct without a tag name, its fields are promoted
+	}
+)
+
+var (
+	typePlans         sync.Map // reflect.Type -> *typePlan
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// Provides the cached plan for type [t]
+func planFor(t reflect.Type) *typePlan {
+	if plan, found := typePlans.Load(t); found {
+		return plan.(*typePlan)
+	}
+
+	plan := makeTypePlan(t, map[reflect.Type]bool{})
+	actual, _ := typePlans.LoadOrStore(t, plan)
+	return actual.(*typePlan)
+}
+
+func makeTypePlan(t reflect.Type, visiting map[reflect.Type]bool) *typePlan {
+	plan := &typePlan{}
+
+	if t.Kind() == reflect.Struct {
+		plan.fieldNames = make([]string, t.NumField())
+		plan.jsonFields = make([]jsonFieldPlan, t.NumField())
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			plan.fieldNames[i] = f.Name
+			plan.jsonFields[i] = makeJsonFieldPlan(f)
+		}
+	}
+
+	plan.exportedOnly = isExportedOnly(t, visiting)
+	return plan
+}
+
+// Determines if a type has only exported fields and kinds that encoding/json
+// can render, throughout the entire type graph.
+func isExportedOnly(t reflect.Type, visiting map[reflect.Type]bool) bool {
+	if inProgress, found := visiting[t]; found {
+		return inProgress // recursive type, decided by the outer evaluation
+	}
+	if plan, found := typePlans.Load(t); found {
+		return plan.(*typePlan).exportedOnly
+	}
+
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	if hasCustomEncoding(t) {
+		// encoding/json would bypass the custom encoding
+		return false
+	}
+
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.String:
+		return true
+
+	case reflect.Float32, reflect.Float64:
+		// NaN and Inf make encoding/json fail, which falls back to reflection
+		return true
+
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		return isExportedOnly(t.Elem(), visiting)
+
+	case reflect.Map:
+		kind := t.Key().Kind()
+		if kind != reflect.String && (kind < reflect.Int || kind > reflect.Uintptr) && !t.Key().Implements(textMarshalerType) {
+			return false
+		}
+		return isExportedOnly(t.Elem(), visiting)
+
+	case reflect.Struct:
+		if t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType) {
+			return true
+		}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() || !isExportedOnly(f.Type, visiting) {
+				return false
+			}
+		}
+		return true
+
+	default:
+		// interfaces are only known at run time, and other kinds aren't supported by encoding/json
+		return false
+	}
+}
+
+// Interprets the json tag of a struct field
+func makeJsonFieldPlan(f reflect.StructField) (jfp jsonFieldPlan) {
+	jfp.name = f.Name
+	jfp.exported = f.IsExported()
+
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		jfp.skip = true
+		return
+	}
+
+	name, opts, _ := strings.Cut(tag, ",")
+	if name != "" {
+		jfp.name = name
+	}
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		if opt == "omitempty" {
+			jfp.omitEmpty = true
+		}
+	}
+
+	if f.Anonymous && name == "" {
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct {
+			jfp.inline = true
+		}
+	}
+
+	return
+}
@@ -0,0 +1,116 @@
+package lane
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type (
+	testExported struct {
+		Name  string
+		Count int
+		Tags  []string `json:"tags"`
+		Child *testExported
+	}
+
+	testMixed struct {
+		Name  string
+		count int
+	}
+
+	testWithAny struct {
+		Value any
+	}
+)
+
+func TestTypePlanExportedOnly(t *testing.T) {
+	cases := []struct {
+		v        any
+		expected bool
+	}{
+		{testExported{}, true},
+		{&testExported{}, true},
+		{[]testExported{}, true},
+		{map[string]testExported{}, true},
+		{map[testStruct]int{}, false},
+		{testMixed{}, false},
+		{testWithAny{}, false},
+		{time.Time{}, true},
+		{make(chan int), false},
+		{1.5, true},
+		{complex(1, 2), false},
+	}
+
+	for _, c := range cases {
+		if planFor(reflect.TypeOf(c.v)).exportedOnly != c.expected {
+			t.Errorf("wrong exportedOnly for %T", c.v)
+		}
+	}
+
+	plan := planFor(reflect.TypeOf(testMixed{}))
+	if len(plan.fieldNames) != 2 || plan.fieldNames[0] != "Name" || plan.fieldNames[1] != "count" {
+		t.Errorf("wrong field names %v", plan.fieldNames)
+	}
+	if planFor(reflect.TypeOf(testMixed{})) != plan {
+		t.Error("plan not cached")
+	}
+}
+
+func TestLogObjectDirectJSON(t *testing.T) {
+	l := NewLogLane(nil)
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer func() { log.SetOutput(os.Stderr) }()
+
+	obj := testExported{Name: "a", Count: 1, Tags: []string{"x"}}
+
+	l.InfoObject("reflect", obj)
+
+	prior := l.SetObjectOptions(LogObjectOpt{DirectJSON: true})
+	if prior.DirectJSON {
+		t.Error("unexpected prior setting")
+	}
+
+	l.InfoObject("direct", obj)
+	l.InfoObject("mixed", testMixed{Name: "b", count: 2})
+
+	// derived lanes inherit the setting
+	l2 := l.Derive()
+	if !l2.ObjectOptions().DirectJSON {
+		t.Error("setting not inherited")
+	}
+
+	testExpectedStdout(t, &buf, []string{
+		`reflect: {"Child":null,"Count":1,"Name":"a","Tags":["x"]}`,
+		`direct: {"Name":"a","Count":1,"tags":["x"],"Child":null}`,
+		`mixed: {"Name":"b","count":2}`,
+	})
+}
+
+func BenchmarkInfoObjectReflect(b *testing.B) {
+	l := NewNullLane(nil)
+	l.AddTee(NewNullLane(nil))
+	obj := testExported{Name: "a", Count: 1, Tags: []string{"x", "y"}, Child: &testExported{Name: "b"}}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.InfoObject("bench", obj)
+	}
+}
+
+func BenchmarkInfoObjectDirectJSON(b *testing.B) {
+	l := NewNullLane(nil)
+	l.AddTee(NewNullLane(nil))
+	l.SetObjectOptions(LogObjectOpt{DirectJSON: true})
+	obj := testExported{Name: "a", Count: 1, Tags: []string{"x", "y"}, Child: &testExported{Name: "b"}}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.InfoObject("bench", obj)
+	}
+}
@@ -7,7 +7,9 @@ import (
 	"math"
 	"reflect"
 	"runtime"
+	"slices"
 	"strings"
+	"time"
 	"unsafe"
 
 	"github.com/google/uuid"
@@ -16,6 +18,42 @@ import (
 type (
 	asciiSequence []byte
 	recursionType int
+
+	// A captured struct that renders its fields in declaration order
+	objectFields []objectField
+	objectField  struct {
+		name  string
+		value any
+	}
+
+	// A map element chosen for capture, by its rendered key
+	mapEntry struct {
+		key   string
+		rk    reflect.Value
+		value reflect.Value
+	}
+
+	// Working state of an object capture
+	captureState struct {
+		addrs map[uintptr]recursionType
+		opt   LogObjectOpt
+		omit  map[string]struct{}
+
+		// the entries kept of the maps too large to sort, so that the address
+		// scan and the rendering visit the same ones
+		mapPicks map[unsafe.Pointer][]mapEntry
+	}
+
+	// Implemented by lanes that can tell cheaply that a message would be discarded
+	levelDiscarder interface {
+		discards(level LaneLogLevel) bool
+	}
+)
+
+const (
+	maxDepthText       = "(max depth)"
+	moreElementsFormat = "(%d more)"
+	moreElementsKey    = "\u2026"
 )
 
 const (
@@ -27,45 +65,84 @@ const (
 
 // Logs an entire object.
 func LogObject(l Lane, level LaneLogLevel, message string, obj any) {
+	LogObjectWithOpt(l, level, message, obj, l.ObjectOptions())
+}
+
+// Logs an entire object, using [opt] instead of the lane's object settings.
+func LogObjectWithOpt(l Lane, level LaneLogLevel, message string, obj any, opt LogObjectOpt) {
+	if ld, ok := l.(levelDiscarder); ok && level != LogLevelFatal && ld.discards(level) {
+		// skip the object capture
+		return
+	}
+
 	li := l.(laneInternal)
 
-	logObjectInternal(li.LaneProps(), li, level, message, obj)
+	logObjectInternal(li.LaneProps(), li, level, message, obj, opt)
 }
 
-func logObjectInternal(props loggingProperties, li laneInternal, level LaneLogLevel, message string, obj any) {
-	// Convert the entire object (public and private values) to public
-	o := CaptureObject(obj)
+func logObjectInternal(props loggingProperties, li laneInternal, level LaneLogLevel, message string, obj any, opt LogObjectOpt) {
+	text := renderObjectText(props, li, level, message, obj, opt)
+	if opt.TeeObjects {
+		li.objectTextInternal(props, level, text, message, obj)
+	} else {
+		logTextInternal(props, li, level, text)
+	}
+	if level == LogLevelFatal {
+		li.OnPanic()
+	}
+}
 
-	raw, err := json.Marshal(&o)
+// Renders the message text of an object log
+func renderObjectText(props loggingProperties, li laneInternal, level LaneLogLevel, message string, obj any, opt LogObjectOpt) string {
+	// Convert the entire object (public and private values) to public
+	raw, err := encodeObject(obj, opt)
 	if err != nil {
-		panic(err)
+		if dr, ok := li.(diagnosticReporter); ok {
+			dr.reportDiagnostic(err, Record{Time: time.Now(), LaneId: props.laneId, JourneyId: props.journeyId, Category: props.category, Level: level, Message: message})
+		}
+		return li.Constrain(fmt.Sprintf("%s: %#v (%v)", message, obj, err))
+	} else if lc, ok := li.(lengthConstrainer); ok {
+		return constrainObjectText(message, raw, lc.lengthConstraint(level))
 	}
-	enc := fmt.Sprintf("%s: %s", message, string(raw))
+	return li.Constrain(fmt.Sprintf("%s: %s", message, string(raw)))
+}
 
-	enc = li.Constrain(enc)
+// Logs an object received from a tee source, rendering it with the receiving
+// lane's object settings
+func receiveObject(props loggingProperties, li laneInternal, level LaneLogLevel, message string, obj any, opt LogObjectOpt) {
+	li.objectTextInternal(props, level, renderObjectText(props, li, level, message, obj, opt), message, obj)
+}
 
+// Sends prepared text to the lane at [level], without invoking the panic handler
+func logTextInternal(props loggingProperties, li laneInternal, level LaneLogLevel, text string) {
 	switch level {
 	case LogLevelTrace:
-		li.TraceInternal(props, enc)
+		li.TraceInternal(props, text)
 	case LogLevelDebug:
-		li.DebugInternal(props, enc)
+		li.DebugInternal(props, text)
 	case LogLevelInfo:
-		li.InfoInternal(props, enc)
+		li.InfoInternal(props, text)
 	case LogLevelWarn:
-		li.WarnInternal(props, enc)
+		li.WarnInternal(props, text)
 	case LogLevelError:
-		li.ErrorInternal(props, enc)
+		li.ErrorInternal(props, text)
 	case logLevelPreFatal:
-		li.PreFatalInternal(props, enc)
+		li.PreFatalInternal(props, text)
 	case LogLevelFatal:
-		li.FatalInternal(props, enc)
-		li.OnPanic()
+		li.FatalInternal(props, text)
 	default:
 		panic("invalid level argument")
 	}
 }
 
-func captureAddrs(val reflect.Value, addrs map[uintptr]recursionType) (showAddrs bool) {
+// Beyond this many entries, a map truncated by MaxElements keeps the entries
+// it iterates first, rather than the keys that sort first
+const mapSortLimit = 1000
+
+// Finds the pointers the capture will reach more than once, down to the depth
+// and element limits
+func (cs *captureState) captureAddrs(val reflect.Value, depth int) (showAddrs bool) {
+	addrs := cs.addrs
 	var addr uintptr
 	if val.Kind() == reflect.Pointer {
 		addr = val.Pointer()
@@ -92,33 +169,76 @@ func captureAddrs(val reflect.Value, addrs map[uintptr]recursionType) (showAddrs
 
 	switch val.Kind() {
 	case reflect.Interface, reflect.Pointer:
-		showAddrs = captureAddrs(val.Elem(), addrs) || showAddrs
+		showAddrs = cs.captureAddrs(val.Elem(), depth) || showAddrs
 
 	case reflect.Struct:
+		if cs.atDepthLimit(depth) {
+			break
+		}
 		for i := 0; i < val.NumField(); i++ {
 			f := val.Field(i)
-			showAddrs = captureAddrs(f, addrs) || showAddrs
+			showAddrs = cs.captureAddrs(f, depth+1) || showAddrs
 		}
 
 	case reflect.Array, reflect.Slice:
-		for i := 0; i < val.Len(); i++ {
-			showAddrs = captureAddrs(val.Index(i), addrs) || showAddrs
+		if cs.atDepthLimit(depth) {
+			break
+		}
+		for i := range cs.elementLimit(val.Len()) {
+			showAddrs = cs.captureAddrs(val.Index(i), depth+1) || showAddrs
 		}
 
 	case reflect.Map:
-		iter := val.MapRange()
-		for iter.Next() {
-			rk := iter.Key()
-			rv := iter.Value()
-			showAddrs = captureAddrs(rk, addrs) || showAddrs
-			showAddrs = captureAddrs(rv, addrs) || showAddrs
+		if cs.atDepthLimit(depth) {
+			break
+		}
+		entries, _ := cs.mapEntries(val)
+		for _, entry := range entries {
+			if _, isPointer := pointerKey(entry.rk); !isPointer {
+				showAddrs = cs.captureAddrs(entry.rk, depth+1) || showAddrs
+			}
+			showAddrs = cs.captureAddrs(entry.value, depth+1) || showAddrs
 		}
 	}
 
 	return
 }
 
-func innerValue(val reflect.Value, addrs map[uintptr]recursionType) (inner any) {
+// Provides the entries of a map that can be rendered: all of them, or for a
+// map over MaxElements and mapSortLimit, the first MaxElements iterated.
+// Indicates if the entries must be sorted and truncated to MaxElements.
+func (cs *captureState) mapEntries(val reflect.Value) (entries []mapEntry, truncate bool) {
+	length := val.Len()
+	if cs.opt.MaxElements <= 0 || length <= cs.opt.MaxElements {
+		entries = make([]mapEntry, 0, length)
+	} else if length <= mapSortLimit {
+		entries = make([]mapEntry, 0, length)
+		truncate = true
+	} else {
+		// iteration order is random, so pick the entries once per capture
+		ptr := val.UnsafePointer()
+		if picked, found := cs.mapPicks[ptr]; found {
+			return picked, false
+		}
+		entries = make([]mapEntry, 0, cs.opt.MaxElements)
+		defer func() {
+			if cs.mapPicks == nil {
+				cs.mapPicks = map[unsafe.Pointer][]mapEntry{}
+			}
+			cs.mapPicks[ptr] = entries
+		}()
+	}
+
+	iter := val.MapRange()
+	for iter.Next() && len(entries) < cap(entries) {
+		entries = append(entries, mapEntry{rk: iter.Key(), value: iter.Value()})
+	}
+	return
+}
+
+func (cs *captureState) innerValue(val reflect.Value, depth int) (inner any) {
+	addrs := cs.addrs
+
 	var pointerTarget uintptr
 	if addrs != nil {
 		if val.Kind() == reflect.Pointer {
@@ -144,6 +264,31 @@ func innerValue(val reflect.Value, addrs map[uintptr]recursionType) (inner any)
 		}
 	}
 
+	if custom, found := cs.customValue(val); found {
+		inner = cs.captureCustom(val, custom, depth)
+	} else if rendered, found := cs.jsonTagsValue(val); found {
+		inner = rendered
+	} else if text, found := cs.textValue(val); found {
+		inner = text
+	} else {
+		inner = cs.kindValue(val, depth)
+	}
+
+	if pointerTarget != 0 {
+		address := fmt.Sprintf("Address: %#x", pointerTarget)
+		switch m := inner.(type) {
+		case map[string]any:
+			m[""] = address
+		case objectFields:
+			inner = append(objectFields{{"", address}}, m...)
+		}
+	}
+
+	return
+}
+
+// Converts the value according to its kind
+func (cs *captureState) kindValue(val reflect.Value, depth int) (inner any) {
 	switch val.Kind() {
 	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
 		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
@@ -152,7 +297,8 @@ func innerValue(val reflect.Value, addrs map[uintptr]recursionType) (inner any)
 
 	case reflect.Float32, reflect.Float64:
 		f64 := val.Float()
-		if math.IsInf(f64, 0) {
+		if math.IsInf(f64, 0) || math.IsNaN(f64) {
+			// not representable in JSON
 			inner = fmt.Sprintf("%v", f64)
 		} else {
 			inner = val.Interface()
@@ -171,20 +317,83 @@ func innerValue(val reflect.Value, addrs map[uintptr]recursionType) (inner any)
 		inner = runtime.FuncForPC(val.Pointer()).Name()
 
 	case reflect.Struct:
-		m := map[string]any{}
+		if cs.atDepthLimit(depth) {
+			inner = maxDepthText
+			break
+		}
+		plan := planFor(val.Type())
+		var m map[string]any
+		var fields objectFields
+		if cs.opt.Fields == FieldsDeclared {
+			fields = make(objectFields, 0, len(plan.fieldNames))
+		} else {
+			m = make(map[string]any, len(plan.fieldNames))
+		}
 		val2 := reflect.New(val.Type()).Elem()
 		val2.Set(val)
-		for i := 0; i < val.NumField(); i++ {
+		for i, name := range plan.fieldNames {
 			rf := val2.Field(i)
 			rf = reflect.NewAt(rf.Type(), unsafe.Pointer(rf.UnsafeAddr())).Elem()
-			m[val.Type().Field(i).Name] = innerValue(rf, addrs)
+
+			if jf := plan.jsonFields[i]; cs.opt.Encoding == EncodingJSONTags && (jf.exported || jf.inline) {
+				if jf.skip || (jf.omitEmpty && isEmptyValue(rf)) {
+					continue
+				}
+				if jf.inline {
+					// promote the embedded struct's fields like encoding/json
+					switch em := cs.innerValue(rf, depth).(type) {
+					case map[string]any:
+						for k, v := range em {
+							if _, exists := m[k]; !exists {
+								m[k] = v
+							}
+						}
+						continue
+					case objectFields:
+						for _, f := range em {
+							if !fields.has(f.name) {
+								fields = append(fields, f)
+							}
+						}
+						continue
+					}
+				}
+				name = jf.name
+			}
+
+			if cs.isOmitted(name) {
+				continue
+			}
+			if fields != nil {
+				fields = append(fields, objectField{name, cs.innerValue(rf, depth+1)})
+			} else {
+				m[name] = cs.innerValue(rf, depth+1)
+			}
+		}
+		if fields != nil {
+			inner = fields
+		} else {
+			inner = m
 		}
-		inner = m
 
 	case reflect.Array, reflect.Slice:
-		a := []any{}
-		for i := 0; i < val.Len(); i++ {
-			a = append(a, innerValue(val.Index(i), addrs))
+		if cs.atDepthLimit(depth) {
+			inner = maxDepthText
+			break
+		}
+		if rendered, handled := cs.bytesValue(val); handled {
+			inner = rendered
+			break
+		}
+		length := val.Len()
+		limit := cs.elementLimit(length)
+		a := make([]any, 0, limit)
+		for i := 0; i < limit; i++ {
+			a = append(a, cs.innerValue(val.Index(i), depth+1))
+		}
+		var more string
+		if limit < length {
+			more = fmt.Sprintf(moreElementsFormat, length-limit)
 		}
 
 		// special case for byte array/slice: if the values are all ascii, render the bytes as runes
@@ -210,6 +419,9 @@ func innerValue(val reflect.Value, addrs map[uintptr]recursionType) (inner any)
 						seq = append(seq, by)
 					}
 					if runeable {
+						if more != "" {
+							seq = append(seq, more...)
+						}
 						inner = seq
 						break
 					}
@@ -222,28 +434,74 @@ func innerValue(val reflect.Value, addrs map[uintptr]recursionType) (inner any)
 					for _, item := range a {
 						bytes = append(bytes, item.(byte))
 					}
-					inner = base64.StdEncoding.EncodeToString(bytes)
+					inner = base64.StdEncoding.EncodeToString(bytes) + more
 					break
 				}
 			}
 		}
 
+		if more != "" {
+			a = append(a, more)
+		}
 		inner = a
 
 	case reflect.Map:
+		if cs.atDepthLimit(depth) {
+			inner = maxDepthText
+			break
+		}
+
+		// pick the keys before rendering any values, so that a large map
+		// costs only the elements that are kept
+		candidates, truncate := cs.mapEntries(val)
+		more := val.Len() - len(candidates)
+		entries := make([]mapEntry, 0, len(candidates))
+		for _, entry := range candidates {
+			entry.key = cs.mapKey(entry.rk, depth+1)
+			if cs.isOmitted(entry.key) {
+				continue
+			}
+			entries = append(entries, entry)
+		}
+
+		if truncate && len(entries) > cs.opt.MaxElements {
+			// keep the keys that sort first, so the output is stable
+			slices.SortFunc(entries, func(a, b mapEntry) int { return strings.Compare(a.key, b.key) })
+			more += len(entries) - cs.opt.MaxElements
+			entries = entries[:cs.opt.MaxElements]
+		}
+
 		// generalize map
-		m := map[string]any{}
+		m := make(map[string]any, len(entries))
+		var collisions map[string][]any
+
+		for _, entry := range entries {
+			key := entry.key
+			v := cs.innerValue(entry.value, depth+1)
+			if prior, exists := m[key]; exists {
+				// distinct keys with the same text, such as NaN floats
+				if collisions == nil {
+					collisions = map[string][]any{}
+				}
+				if collisions[key] == nil {
+					collisions[key] = []any{prior}
+				}
+				collisions[key] = append(collisions[key], v)
+				continue
+			}
+			m[key] = v
+		}
+		for key, values := range collisions {
+			disambiguateKeys(m, key, values)
+		}
 
-		iter := val.MapRange()
-		for iter.Next() {
-			rk := iter.Key()
-			rv := iter.Value()
-			m[fmt.Sprintf("%v", innerValue(rk, addrs))] = innerValue(rv, addrs)
+		if more > 0 {
+			m[moreElementsKey] = fmt.Sprintf(moreElementsFormat, more)
 		}
 		inner = m
 
 	case reflect.Interface, reflect.Pointer:
-		inner = innerValue(val.Elem(), addrs)
+		inner = cs.innerValue(val.Elem(), depth)
 
 	case reflect.UnsafePointer:
 		inner = fmt.Sprintf("(unsafe.Pointer: %#x)", val.Pointer())
@@ -256,24 +514,95 @@ func innerValue(val reflect.Value, addrs map[uintptr]recursionType) (inner any)
 		panic("can't process type combination " + val.Kind().String())
 	}
 
-	if pointerTarget != 0 {
-		m, is := inner.(map[string]any)
-		if is {
-			m[""] = fmt.Sprintf("Address: %#x", pointerTarget)
-		}
+	return
+}
+
+// Renders a map key as text. A key with a pointer is identified by its
+// address, like the map does, so that distinct keys don't collide and a key
+// referring to the map doesn't recurse.
+func (cs *captureState) mapKey(rk reflect.Value, depth int) string {
+	if text, found := cs.textValue(rk); found {
+		return text
 	}
+	if addr, isPointer := pointerKey(rk); isPointer {
+		return fmt.Sprintf("(pointer: %#x)", addr)
+	}
+	return fmt.Sprintf("%v", cs.innerValue(rk, depth))
+}
 
+// Provides the address of a map key that is a pointer
+func pointerKey(rk reflect.Value) (addr uintptr, isPointer bool) {
+	for rk.Kind() == reflect.Interface && !rk.IsNil() {
+		rk = rk.Elem()
+	}
+	switch rk.Kind() {
+	case reflect.Pointer, reflect.UnsafePointer, reflect.Chan:
+		return rk.Pointer(), true
+	}
 	return
 }
 
+// Stores the [values] of distinct map keys that render as [key]. The map's
+// iteration order is random, so the values are ordered by their rendering,
+// keeping the output stable.
+func disambiguateKeys(m map[string]any, key string, values []any) {
+	rendered := make([]string, len(values))
+	order := make([]int, len(values))
+	for i, v := range values {
+		rendered[i] = objToString(v)
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int { return strings.Compare(rendered[a], rendered[b]) })
+
+	m[key] = values[order[0]]
+	for n, i := range order[1:] {
+		m[fmt.Sprintf("%s (%d)", key, n+2)] = values[i]
+	}
+}
+
 // Converts an arbitrary object into a JSON-renderable object.
 func CaptureObject(obj any) (v any) {
-	addrs := map[uintptr]recursionType{}
+	return CaptureObjectWithOpt(obj, LogObjectOpt{})
+}
+
+// Converts an arbitrary object into a JSON-renderable object, applying the
+// limits specified in [opt].
+func CaptureObjectWithOpt(obj any, opt LogObjectOpt) (v any) {
+	cs := captureState{
+		addrs: map[uintptr]recursionType{},
+		opt:   opt,
+	}
+	if len(opt.OmitFields) > 0 {
+		cs.omit = make(map[string]struct{}, len(opt.OmitFields))
+		for _, name := range opt.OmitFields {
+			cs.omit[name] = struct{}{}
+		}
+	}
+
 	val := reflect.ValueOf(obj)
-	if !captureAddrs(val, addrs) {
-		addrs = nil
+	if !cs.captureAddrs(val, 0) {
+		cs.addrs = nil
+	}
+	return cs.innerValue(val, 0)
+}
+
+func (cs *captureState) atDepthLimit(depth int) bool {
+	return cs.opt.MaxDepth > 0 && depth >= cs.opt.MaxDepth
+}
+
+func (cs *captureState) elementLimit(length int) int {
+	if cs.opt.MaxElements > 0 && length > cs.opt.MaxElements {
+		return cs.opt.MaxElements
+	}
+	return length
+}
+
+func (cs *captureState) isOmitted(name string) bool {
+	if cs.omit == nil {
+		return false
 	}
-	return innerValue(val, addrs)
+	_, omitted := cs.omit[name]
+	return omitted
 }
 
 func (seq asciiSequence) MarshalJSON() ([]byte, error) {
@@ -294,17 +623,46 @@ func (seq asciiSequence) MarshalJSON() ([]byte, error) {
 	return []byte(sb.String()), nil
 }
 
+// Checks if the captured struct has a field [name]
+func (of objectFields) has(name string) bool {
+	return slices.ContainsFunc(of, func(f objectField) bool { return f.name == name })
+}
+
+func (of objectFields) MarshalJSON() ([]byte, error) {
+	buf := []byte{'{'}
+	for i, f := range of {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		name, err := json.Marshal(f.name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, name...)
+		buf = append(buf, ':')
+		buf = append(buf, value...)
+	}
+	return append(buf, '}'), nil
+}
+
+// Copies the settings of [src] to its derivation [dest]. The settings are
+// read without changing [src], which other goroutines may be logging to.
 func copyConfigToDerivation(dest, src Lane) {
 	if !isNil(src) {
+		state := src.ConfigSnapshot()
 		for i := LogLevelTrace; i < logLevelMax; i++ {
-			old := src.EnableStackTrace(i, false)
-			src.EnableStackTrace(i, old)
-			dest.EnableStackTrace(i, old)
+			dest.EnableStackTraceDepth(i, state.StackTraceDepth[i])
+			dest.EnableStackTrace(i, state.StackTrace[i])
+			dest.SetLevelLengthConstraint(i, state.LevelMaxLength[i])
 		}
-
-		oldMaxLen := src.SetLengthConstraint(0)
-		src.SetLengthConstraint(oldMaxLen)
-		dest.SetLengthConstraint(oldMaxLen)
+		dest.SetCallerInfo(state.CallerInfo)
+		dest.SetLengthConstraint(state.MaxLength)
+		dest.SetTruncationMode(state.TruncationMode)
+		dest.SetObjectOptions(state.ObjectOptions)
 	}
 }
 
@@ -325,25 +683,49 @@ func isNil(i any) bool {
 
 func (props loggingProperties) getMessagePrefix(level string) string {
 	id := trimLaneId(props.laneId)
+	if props.parentId != "" {
+		id = trimLaneId(props.parentId) + "→" + id
+	}
 
+	var prefix string
 	if props.journeyId != "" {
-		return fmt.Sprintf("%s {%s:%s}", level, props.journeyId, id)
+		prefix = fmt.Sprintf("%s {%s:%s}", level, props.journeyId, id)
 	} else {
-		return fmt.Sprintf("%s {%s}", level, id)
+		prefix = fmt.Sprintf("%s {%s}", level, id)
+	}
+	if props.category != "" {
+		prefix += " [" + props.category + "]"
+	}
+	if props.indent > 0 {
+		prefix += strings.Repeat("  ", props.indent)
 	}
+	return prefix
+}
+
+// Provides a lane derived from [l] with [category]
+func deriveCategory(l Lane, category string) Lane {
+	child := l.Derive()
+	child.(laneInternal).setCategory(category)
+	return child
 }
 
+// Provides a lane derived from [l] that is nested one level deeper
+func deriveIndent(l Lane) Lane {
+	depth := l.(laneInternal).LaneProps().indent
+	child := l.Derive()
+	child.(laneInternal).setIndent(depth + 1)
+	return child
+}
+
+// Shortens a lane ID in the default UUID format to its last 10 characters
+// for log output. IDs from other generators are printed in full.
 func trimLaneId(id string) string {
-	if len(id) > 10 {
+	if len(id) == 36 && uuid.Validate(id) == nil {
 		id = id[len(id)-10:]
 	}
 	return id
 }
 
-func makeLaneId() string {
-	return uuid.New().String()
-}
-
 func cleanStack(buf []byte, skipCallers int) (lines []string) {
 	full := strings.Split(strings.TrimSpace(string(buf)), "\n")
 
@@ -353,7 +735,7 @@ func cleanStack(buf []byte, skipCallers int) (lines []string) {
 	// next skip all of the go-lane implementation
 	for top < len(full) {
 		line := full[top]
-		if !strings.Contains(line, "go-lane.(*") {
+		if !strings.Contains(line, "go-lane.(*") && !strings.Contains(line, "go-lane.captureStack(") {
 			break
 		}
 		top += 2
@@ -6,7 +6,9 @@ import (
 	"log"
 	"math"
 	"os"
+	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
 	"testing"
 	"unsafe"
@@ -38,6 +40,15 @@ type (
 
 var objLineExp = regexp.MustCompile(`\d{4}\/\d\d\/\d\d \d\d:\d\d:\d\d [A-Z]+ \{[a-z0-9]{10}\} (.*)\n`)
 
+// Skips a test that verifies trace or debug output, which the lane_release
+// build tag strips from log lanes and null lanes
+func skipInRelease(t *testing.T) {
+	t.Helper()
+	if ReleaseBuild {
+		t.Skip("trace and debug messages are stripped by the lane_release tag")
+	}
+}
+
 func testExpectedStdout(t *testing.T, buf *bytes.Buffer, expected []string) {
 	capture := buf.String()
 
@@ -71,6 +82,8 @@ func testExpectedStdout(t *testing.T, buf *bytes.Buffer, expected []string) {
 }
 
 func TestLogObject(t *testing.T) {
+	skipInRelease(t)
+
 	l := NewLogLane(nil)
 
 	var buf bytes.Buffer
@@ -107,6 +120,8 @@ func TestLogObject(t *testing.T) {
 }
 
 func TestLogLaneObject(t *testing.T) {
+	skipInRelease(t)
+
 	l := NewLogLane(nil)
 	l2 := NewLogLane(nil)
 	l.AddTee(l2)
@@ -155,6 +170,8 @@ func TestLogLaneObject(t *testing.T) {
 }
 
 func TestNullLaneObject(t *testing.T) {
+	skipInRelease(t)
+
 	l := NewNullLane(nil)
 	l2 := NewLogLane(nil)
 	l.AddTee(l2)
@@ -590,6 +607,85 @@ func TestLogObjectInf(t *testing.T) {
 	})
 }
 
+type (
+	testSample struct {
+		Readings []float64
+		Stats    map[string]float32
+		peak     *float64
+	}
+
+	testNode struct {
+		name  string
+		links map[*testNode]int
+	}
+
+	testLabel struct {
+		code int
+	}
+)
+
+func (tl testLabel) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("label-%d", tl.code)), nil
+}
+
+func TestLogObjectNaN(t *testing.T) {
+	l := NewLogLane(nil)
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer func() { log.SetOutput(os.Stderr) }()
+
+	peak := math.Inf(1)
+	l.InfoObject("sample", testSample{
+		Readings: []float64{1.5, math.NaN(), math.Inf(-1)},
+		Stats:    map[string]float32{"mean": float32(math.NaN())},
+		peak:     &peak,
+	})
+
+	testExpectedStdout(t, &buf, []string{
+		`sample: {"Readings":[1.5,"NaN","-Inf"],"Stats":{"mean":"NaN"},"peak":"+Inf"}`,
+	})
+}
+
+func TestLogObjectPointerKeys(t *testing.T) {
+	a := &testNode{name: "a"}
+	b := &testNode{name: "b"}
+	a.links = map[*testNode]int{a: 1, b: 2}
+
+	v := CaptureObject(a).(map[string]any)
+	links := v["links"].(map[string]any)
+	if len(links) != 2 {
+		t.Fatalf("expected distinct keys, got %v", links)
+	}
+	if links[fmt.Sprintf("(pointer: %#x)", uintptr(unsafe.Pointer(b)))] != 2 {
+		t.Errorf("unexpected keys %v", links)
+	}
+
+	var nilKey *int
+	v2 := CaptureObject(map[any]string{nilKey: "nil", 3: "three"}).(map[string]any)
+	if v2["(pointer: 0x0)"] != "nil" || v2["3"] != "three" {
+		t.Errorf("unexpected capture %v", v2)
+	}
+}
+
+func TestLogObjectTextMarshaler(t *testing.T) {
+	l := NewLogLane(nil)
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer func() { log.SetOutput(os.Stderr) }()
+
+	l.InfoObject("label", testLabel{code: 7})
+	l.InfoObject("keys", map[testLabel]testLabel{{code: 1}: {code: 2}})
+	l.InfoObject("field", struct{ label *testLabel }{&testLabel{code: 3}})
+
+	testExpectedStdout(t, &buf, []string{
+		`label: "label-7"`,
+		`keys: {"label-1":"label-2"}`,
+		`field: {"label":"label-3"}`,
+	})
+}
+
 func TestLogObjectComplex(t *testing.T) {
 	l := NewLogLane(nil)
 
@@ -1168,11 +1264,11 @@ func TestTestingLaneLogStackDirectSingleEvent(t *testing.T) {
 
 	tl := l.(*testingLane)
 
-	if len(tl.Events) != 2 {
+	if len(tl.Events()) != 2 {
 		t.Fatal("wrong events")
 	}
 
-	stack := strings.Split(tl.Events[1].Message, "\n")
+	stack := strings.Split(tl.Events()[1].Message, "\n")
 	if len(stack) < 4 {
 		t.Fatal("insufficient stack")
 	}
@@ -1192,17 +1288,394 @@ func TestTestingLaneLogStackDirectMultiEvent(t *testing.T) {
 
 	tl := l.(*testingLane)
 
-	if len(tl.Events) < 5 {
+	if len(tl.Events()) < 5 {
 		t.Fatal("wrong events")
 	}
 
-	for i := 1; i < len(tl.Events); i++ {
-		if tl.Events[i].Level != "STACK" {
-			t.Errorf("unexpected level at %d: %s", i, tl.Events[i].Level)
+	for i := 1; i < len(tl.Events()); i++ {
+		if tl.Events()[i].Level != "STACK" {
+			t.Errorf("unexpected level at %d: %s", i, tl.Events()[i].Level)
 		}
 	}
 
-	if !strings.Contains(tl.Events[1].Message, "TestTestingLaneLogStackDirectMultiEvent") {
-		t.Errorf("unexpected top of stack: %s", tl.Events[1].Message)
+	if !strings.Contains(tl.Events()[1].Message, "TestTestingLaneLogStackDirectMultiEvent") {
+		t.Errorf("unexpected top of stack: %s", tl.Events()[1].Message)
+	}
+}
+
+func TestLogObjectMaxDepth(t *testing.T) {
+	l := NewLogLane(nil)
+	l.SetObjectOptions(LogObjectOpt{MaxDepth: 2})
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer func() { log.SetOutput(os.Stderr) }()
+
+	nested := map[string]any{"a": map[string]any{"b": map[string]any{"c": 1}}, "x": []any{[]int{1}}}
+	l.InfoObject("nested", nested)
+
+	testExpectedStdout(t, &buf, []string{
+		`nested: {"a":{"b":"(max depth)"},"x":["(max depth)"]}`,
+	})
+}
+
+func TestLogObjectMaxElements(t *testing.T) {
+	l := NewLogLane(nil)
+	l.SetObjectOptions(LogObjectOpt{MaxElements: 3})
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer func() { log.SetOutput(os.Stderr) }()
+
+	l.InfoObject("slice", []int{1, 2, 3, 4, 5})
+	l.InfoObject("map", map[string]int{"e": 5, "d": 4, "c": 3, "b": 2, "a": 1})
+	l.InfoObject("bytes", []byte("abcdef"))
+	l.InfoObject("short", []int{1, 2})
+
+	testExpectedStdout(t, &buf, []string{
+		`slice: [1,2,3,"(2 more)"]`,
+		`map: {"a":1,"b":2,"c":3,"…":"(2 more)"}`,
+		`bytes: "abc(3 more)"`,
+		`short: [1,2]`,
+	})
+}
+
+func TestLogObjectOmitFields(t *testing.T) {
+	l := NewLogLane(nil)
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer func() { log.SetOutput(os.Stderr) }()
+
+	opt := LogObjectOpt{OmitFields: []string{"b", "secret"}}
+	LogObjectWithOpt(l, LogLevelInfo, "struct", testStruct{a: 1, b: 2}, opt)
+	LogObjectWithOpt(l, LogLevelInfo, "map", map[string]string{"secret": "x", "user": "y"}, opt)
+
+	// the lane's settings are unaffected
+	l.InfoObject("plain", testStruct{a: 1, b: 2})
+
+	testExpectedStdout(t, &buf, []string{
+		`struct: {"a":1}`,
+		`map: {"user":"y"}`,
+		`plain: {"a":1,"b":2}`,
+	})
+}
+
+func TestLogObjectMaxBytes(t *testing.T) {
+	l := NewLogLane(nil)
+	l.SetObjectOptions(LogObjectOpt{MaxBytes: 10, DirectJSON: true})
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer func() { log.SetOutput(os.Stderr) }()
+
+	l.InfoObject("big", []int{1000, 2000, 3000, 4000})
+	l.InfoObject("small", []int{1})
+	l.InfoObject("utf8", []string{"aéééé"})
+
+	testExpectedStdout(t, &buf, []string{
+		`big: "[1000,2000…"`,
+		`small: [1]`,
+		`utf8: "[\"aééé…"`,
+	})
+}
+
+type testCountedValue struct {
+	calls *int
+}
+
+func (tcv testCountedValue) MarshalLaneObject() any {
+	*tcv.calls++
+	return "value"
+}
+
+func TestLogObjectMaxElementsMapCost(t *testing.T) {
+	var calls int
+	m := map[int]testCountedValue{}
+	for i := range 1000 {
+		m[i] = testCountedValue{calls: &calls}
+	}
+
+	v := CaptureObjectWithOpt(m, LogObjectOpt{MaxElements: 2})
+	if calls != 2 {
+		t.Errorf("expected only the kept values to be rendered, have %d", calls)
+	}
+	if objToString(v) != `{"0":"value","1":"value","…":"(998 more)"}` {
+		t.Errorf("unexpected capture %s", objToString(v))
+	}
+}
+
+type testCountedKey struct {
+	id    int
+	calls *int
+}
+
+func (tck testCountedKey) MarshalText() ([]byte, error) {
+	*tck.calls++
+	return []byte(strconv.Itoa(tck.id)), nil
+}
+
+func TestLogObjectMaxElementsLargeMap(t *testing.T) {
+	var calls int
+	m := map[testCountedKey]int{}
+	for i := range 5000 {
+		m[testCountedKey{id: i, calls: &calls}] = i
+	}
+
+	// a map too large to sort renders only the keys it keeps
+	v := CaptureObjectWithOpt(m, LogObjectOpt{MaxElements: 3})
+	if calls != 3 {
+		t.Errorf("expected only the kept keys to be rendered, have %d", calls)
+	}
+	if rendered := v.(map[string]any); len(rendered) != 4 || rendered[moreElementsKey] != "(4997 more)" {
+		t.Errorf("unexpected capture %s", objToString(v))
+	}
+}
+
+type testLargeMapNode struct {
+	Children map[int]*testLargeMapNode
+}
+
+func TestLogObjectMaxElementsLargeMapCycle(t *testing.T) {
+	root := &testLargeMapNode{Children: map[int]*testLargeMapNode{}}
+	for i := range 2000 {
+		root.Children[i] = root
+	}
+
+	// the address scan and the rendering keep the same entries, so the
+	// cycle is found
+	text := objToString(CaptureObjectWithOpt(root, LogObjectOpt{MaxElements: 2}))
+	if strings.Count(text, "(pointer: ") != 2 || !strings.Contains(text, "(1998 more)") {
+		t.Errorf("unexpected capture %s", text)
+	}
+}
+
+func TestLogObjectMaxDepthScan(t *testing.T) {
+	// a deep chain below the depth limit isn't scanned for addresses
+	type node struct{ Next []*node }
+	head := &node{}
+	for n, i := head, 0; i < 100000; i++ {
+		n.Next = []*node{{}}
+		n = n.Next[0]
+	}
+	cs := captureState{addrs: map[uintptr]recursionType{}, opt: LogObjectOpt{MaxDepth: 2}}
+	cs.captureAddrs(reflect.ValueOf(head), 0)
+	if len(cs.addrs) > 3 {
+		t.Errorf("expected the scan to stop at the depth limit, have %d addresses", len(cs.addrs))
+	}
+}
+
+type testFieldOrder struct {
+	Zone   string
+	id     int
+	Active bool
+	inner  testStruct
+}
+
+func TestLogObjectFieldOrder(t *testing.T) {
+	l := NewLogLane(nil)
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer func() { log.SetOutput(os.Stderr) }()
+
+	obj := testFieldOrder{Zone: "east", id: 7, Active: true, inner: testStruct{b: 2, a: 1}}
+	l.InfoObject("sorted", obj)
+	l.SetObjectOptions(LogObjectOpt{Fields: FieldsDeclared})
+	l.InfoObject("declared", obj)
+	l.InfoObject("map", map[string]any{"z": obj.inner, "a": 1})
+
+	n3 := testRecursive{name: "n3"}
+	n3.next = &n3
+	l.InfoObject("recursive", &n3)
+
+	testExpectedStdout(t, &buf, []string{
+		`sorted: {"Active":true,"Zone":"east","id":7,"inner":{"a":1,"b":2}}`,
+		`declared: {"Zone":"east","id":7,"Active":true,"inner":{"a":1,"b":2}}`,
+		`map: {"a":1,"z":{"a":1,"b":2}}`,
+		`recursive: {"":"Address: **addr**","name":"n3","next":"(pointer: **addr**)"}`,
+	})
+}
+
+func TestLogObjectStableOutput(t *testing.T) {
+	obj := map[string]any{
+		"mixed": map[any]int{1: 1, "1": 2, 1.0: 3},
+		"nan":   map[float64]string{math.NaN(): "b", math.NaN(): "a", math.NaN(): "c"},
+	}
+
+	first, err := encodeObject(obj, LogObjectOpt{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"mixed":{"1":1,"1 (2)":2,"1 (3)":3},"nan":{"NaN":"a","NaN (2)":"b","NaN (3)":"c"}}`
+	if string(first) != expected {
+		t.Errorf("unexpected rendering %s", first)
+	}
+
+	for range 50 {
+		raw, _ := encodeObject(obj, LogObjectOpt{})
+		if string(raw) != string(first) {
+			t.Fatalf("unstable rendering %s", raw)
+		}
+	}
+}
+
+func TestLogObjectLimitsInherited(t *testing.T) {
+	tl := NewTestingLane(nil)
+	tl.SetObjectOptions(LogObjectOpt{MaxElements: 1})
+
+	tl2 := tl.Derive()
+	tl2.InfoObject("list", []string{"a", "b"})
+
+	if !tl2.(TestingLane).VerifyEventText(`INFO	list: ["a","(1 more)"]`) {
+		t.Error(tl2.(TestingLane).EventsToString())
+	}
+}
+
+func TestLogObjectBytesHex(t *testing.T) {
+	l := NewLogLane(nil)
+	l.SetObjectOptions(LogObjectOpt{Bytes: BytesHex, HexDumpLength: 4})
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer func() { log.SetOutput(os.Stderr) }()
+
+	a := make([]byte, 2048)
+	for i := range 2048 {
+		a[i] = byte(i % 256)
+	}
+	l.InfoObject("array", a)
+	l.InfoObject("short", [3]byte{0xa, 0xb, 0xc})
+	l.InfoObject("text", []byte("hi"))
+
+	testExpectedStdout(t, &buf, []string{
+		`array: "len=2048 0x00010203…"`,
+		`short: "len=3 0x0a0b0c"`,
+		`text: "len=2 0x6869"`,
+	})
+}
+
+func TestLogObjectBytesBase64Threshold(t *testing.T) {
+	l := NewLogLane(nil)
+	l.SetObjectOptions(LogObjectOpt{Base64MaxLength: 1500})
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer func() { log.SetOutput(os.Stderr) }()
+
+	a := make([]byte, 2048)
+	l.InfoObject("large", a)
+	l.InfoObject("medium", a[:1200])
+	l.InfoObject("small", a[:2])
+	l.InfoObject("text", []byte("hi"))
+
+	testExpectedStdout(t, &buf, []string{
+		`large: "len=2048 0x0000000000000000000000000000000000000000000000000000000000000000…"`,
+		`medium: "` + strings.Repeat("A", 1600) + `"`,
+		`small: [0,0]`,
+		`text: "hi"`,
+	})
+}
+
+func TestLogObjectBytesDirectJSON(t *testing.T) {
+	l := NewLogLane(nil)
+	l.SetObjectOptions(LogObjectOpt{DirectJSON: true, Bytes: BytesHex, HexDumpLength: 2})
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer func() { log.SetOutput(os.Stderr) }()
+
+	type payload struct {
+		Data []byte
+	}
+	l.InfoObject("exported", payload{Data: []byte{1, 2, 3, 4}})
+
+	testExpectedStdout(t, &buf, []string{
+		`exported: {"Data":"len=4 0x0102…"}`,
+	})
+}
+
+type panickingMarshaler struct{}
+
+func (panickingMarshaler) MarshalLaneObject() any {
+	panic("marshaler failure")
+}
+
+func TestLogObjectFailureDegrades(t *testing.T) {
+	var diagnostics []string
+	tl := NewTestingLane(nil, WithDiagnostics(func(err error, rec Record) {
+		diagnostics = append(diagnostics, levelNames[rec.Level]+" "+rec.Message+": "+err.Error())
+	}))
+	child := tl.Derive().(TestingLane)
+
+	child.WarnObject("ratio", math.NaN())
+	child.ErrorObject("bad", panickingMarshaler{})
+
+	events := child.Events()
+	if len(events) != 2 || events[0].Message != `ratio: "NaN"` {
+		t.Errorf("unexpected events:\n%s", child.EventsToString())
+	}
+	if events[1].Level != "ERROR" || events[1].Message != "bad: lane.panickingMarshaler{} (object rendering panicked: marshaler failure)" {
+		t.Errorf("unexpected event %q", events[1].Message)
+	}
+
+	if len(diagnostics) != 1 || diagnostics[0] != "ERROR bad: object rendering panicked: marshaler failure" {
+		t.Errorf("unexpected diagnostics %v", diagnostics)
+	}
+
+	// without a diagnostic handler, the call still completes
+	NewLogLane(nil).InfoObject("ratio", math.Inf(1))
+}
+
+// A lane that fails the test when its settings are changed
+type testReadOnlyLane struct {
+	Lane
+	t *testing.T
+}
+
+func (trl testReadOnlyLane) EnableStackTrace(level LaneLogLevel, enable bool) bool {
+	trl.t.Error("EnableStackTrace called on the source lane")
+	return false
+}
+func (trl testReadOnlyLane) EnableStackTraceDepth(level LaneLogLevel, depth int) int {
+	trl.t.Error("EnableStackTraceDepth called on the source lane")
+	return 0
+}
+func (trl testReadOnlyLane) SetCallerInfo(enable bool) bool {
+	trl.t.Error("SetCallerInfo called on the source lane")
+	return false
+}
+func (trl testReadOnlyLane) SetLengthConstraint(maxLength int) int {
+	trl.t.Error("SetLengthConstraint called on the source lane")
+	return 0
+}
+func (trl testReadOnlyLane) SetLevelLengthConstraint(level LaneLogLevel, maxLength int) int {
+	trl.t.Error("SetLevelLengthConstraint called on the source lane")
+	return 0
+}
+func (trl testReadOnlyLane) SetTruncationMode(mode TruncationMode) TruncationMode {
+	trl.t.Error("SetTruncationMode called on the source lane")
+	return TruncateEnd
+}
+
+func TestDeriveKeepsParentConfig(t *testing.T) {
+	src := NewTestingLane(nil)
+	src.EnableStackTraceDepth(LogLevelWarn, 3)
+	src.EnableStackTrace(LogLevelWarn, true)
+	src.SetCallerInfo(true)
+	src.SetLengthConstraint(20)
+	src.SetLevelLengthConstraint(LogLevelDebug, LengthUnconstrained)
+	src.SetTruncationMode(TruncateMiddle)
+
+	// the settings are read without changing the source, which other
+	// goroutines may be logging to
+	dest := NewTestingLane(nil)
+	copyConfigToDerivation(dest, testReadOnlyLane{Lane: src, t: t})
+
+	want := src.ConfigSnapshot()
+	have := dest.ConfigSnapshot()
+	if have.StackTrace != want.StackTrace || have.StackTraceDepth != want.StackTraceDepth || !have.CallerInfo ||
+		have.MaxLength != 20 || have.LevelMaxLength != want.LevelMaxLength || have.TruncationMode != TruncateMiddle {
+		t.Errorf("unexpected settings %+v", have)
 	}
 }
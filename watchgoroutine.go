@@ -0,0 +1,110 @@
+package lane
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// The most memory used to find a watched goroutine's stack
+const maxAllStacksSize = 64 << 20
+
+// Watches the calling goroutine, such as a worker looping on a channel or a
+// select. If [heartbeat] elapses without a call to [beat], a warning with the
+// goroutine's current stack is logged to [l], so that a stuck worker shows
+// where it is blocked. After a warning, the next call to [beat] logs that the
+// goroutine resumed, and the watch continues.
+//
+// Call [stop] when the goroutine is done. The watch also ends when the lane's
+// context is done.
+func WatchGoroutine(l Lane, name string, heartbeat time.Duration) (beat func(), stop func()) {
+	id := currentGoroutineId()
+
+	var mu sync.Mutex
+	last := time.Now()
+	stalled := false
+	stopped := false
+
+	timer := time.AfterFunc(heartbeat, func() {
+		mu.Lock()
+		if stopped {
+			mu.Unlock()
+			return
+		}
+		stalled = true
+		idle := time.Since(last)
+		mu.Unlock()
+
+		l.Warnf("goroutine %s has had no heartbeat for %v\n%s", name, idle.Round(time.Millisecond), goroutineStack(id))
+	})
+	stopOnDone := context.AfterFunc(l, func() { timer.Stop() })
+
+	beat = func() {
+		mu.Lock()
+		if stopped {
+			mu.Unlock()
+			return
+		}
+		resumed := stalled
+		idle := time.Since(last)
+		stalled = false
+		last = time.Now()
+		timer.Reset(heartbeat)
+		mu.Unlock()
+
+		if resumed {
+			l.Infof("goroutine %s resumed after %v", name, idle.Round(time.Millisecond))
+		}
+	}
+
+	stop = func() {
+		mu.Lock()
+		defer mu.Unlock()
+		stopped = true
+		timer.Stop()
+		stopOnDone()
+	}
+	return
+}
+
+// Provides the ID of the calling goroutine
+func currentGoroutineId() uint64 {
+	var buf [64]byte
+	return parseGoroutineId(buf[:runtime.Stack(buf[:], false)])
+}
+
+// Provides the stacks of all goroutines, limited to maxAllStacksSize
+func allGoroutineStacks() []byte {
+	buf := make([]byte, 64*1024)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) || len(buf) >= maxAllStacksSize {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// Provides the current stack of goroutine [id], starting with its
+// "goroutine 7 [chan receive]:" title, or "" if it has exited
+func goroutineStack(id uint64) string {
+	buf := allGoroutineStacks()
+	title := []byte(fmt.Sprintf("goroutine %d [", id))
+	start := 0
+	if !bytes.HasPrefix(buf, title) {
+		start = bytes.Index(buf, append([]byte("\n"), title...))
+		if start < 0 {
+			return ""
+		}
+		start++
+	}
+
+	stack := buf[start:]
+	if end := bytes.Index(stack, []byte("\n\n")); end >= 0 {
+		stack = stack[:end]
+	}
+	return string(bytes.TrimRight(stack, "\n"))
+}
@@ -0,0 +1,80 @@
+package lane
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Waits until the testing lane has [count] events
+func testWaitEvents(t *testing.T, tl TestingLane, count int) []LaneEvent {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if events := tl.Events(); len(events) >= count {
+			return events
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for events:\n%s", tl.EventsToString())
+	return nil
+}
+
+func TestWatchGoroutine(t *testing.T) {
+	tl := NewTestingLane(nil)
+	work := make(chan int)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		beat, stop := WatchGoroutine(tl, "worker", 20*time.Millisecond)
+		defer stop()
+		for range work {
+			beat()
+		}
+	}()
+
+	work <- 1
+	events := testWaitEvents(t, tl, 1)
+	if events[0].Level != "WARN" || !strings.HasPrefix(events[0].Message, "goroutine worker has had no heartbeat for ") ||
+		!strings.Contains(events[0].Message, "[chan receive") || !strings.Contains(events[0].Message, "TestWatchGoroutine.func") {
+		t.Errorf("unexpected warning %q", events[0].Message)
+	}
+
+	work <- 2
+	events = testWaitEvents(t, tl, 2)
+	if events[1].Level != "INFO" || !strings.HasPrefix(events[1].Message, "goroutine worker resumed after ") {
+		t.Errorf("unexpected events:\n%s", tl.EventsToString())
+	}
+
+	close(work)
+	<-done
+	time.Sleep(50 * time.Millisecond)
+	if len(tl.Events()) > 3 {
+		t.Errorf("logged after the watch stopped:\n%s", tl.EventsToString())
+	}
+}
+
+func TestWatchGoroutineContextDone(t *testing.T) {
+	tl := NewTestingLane(nil)
+	tl.WantDescendantEvents(true)
+	l, cancel := tl.DeriveWithCancel()
+	_, stop := WatchGoroutine(l, "worker", 10*time.Millisecond)
+	defer stop()
+	cancel()
+
+	time.Sleep(50 * time.Millisecond)
+	if len(tl.Events()) != 0 {
+		t.Errorf("unexpected events:\n%s", tl.EventsToString())
+	}
+}
+
+func TestGoroutineStack(t *testing.T) {
+	stack := goroutineStack(currentGoroutineId())
+	if !strings.HasPrefix(stack, "goroutine ") || !strings.Contains(stack, "TestGoroutineStack") {
+		t.Errorf("unexpected stack %q", stack)
+	}
+	if goroutineStack(1<<62) != "" {
+		t.Error("expected no stack")
+	}
+}